@@ -0,0 +1,83 @@
+// Package main provides spinup-fakecloud, a local fake of the provider APIs
+// for developing and testing spinup without real cloud accounts.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/fakecloud"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8787", "address to listen on")
+	scenarioPath := flag.String("scenario", "", "JSON scenario file with offers, lifecycle and faults")
+	apiKey := flag.String("api-key", "", "only accept this API key (default: any non-empty key)")
+	flag.Parse()
+
+	if err := run(*addr, *scenarioPath, *apiKey); err != nil {
+		fmt.Fprintf(os.Stderr, "spinup-fakecloud: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(addr, scenarioPath, apiKey string) error {
+	var opts []fakecloud.Option
+	if scenarioPath != "" {
+		sc, err := fakecloud.LoadScenario(scenarioPath)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, fakecloud.WithScenario(sc))
+	}
+	if apiKey != "" {
+		opts = append(opts, fakecloud.WithAPIKey(apiKey))
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	root := "http://" + ln.Addr().String()
+
+	// Print the environment that points spinup at this fake cloud
+	fmt.Printf("# spinup-fakecloud listening on %s\n", root)
+	fmt.Println("# Point spinup at it with:")
+	for _, kv := range fakecloud.Env(root) {
+		fmt.Printf("export %s\n", kv)
+	}
+
+	srv := &http.Server{
+		Handler:           fakecloud.New(opts...),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
@@ -10,6 +10,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/tmeurs/spinup/internal/clock"
 	"github.com/tmeurs/spinup/internal/logging"
 )
 
@@ -59,6 +60,7 @@ type WebhookClient struct {
 	webhookURL string
 	httpClient *http.Client
 	logger     *logging.Logger
+	clock      clock.Clock
 }
 
 // WebhookOption is a functional option for configuring WebhookClient.
@@ -78,6 +80,13 @@ func WithLogger(logger *logging.Logger) WebhookOption {
 	}
 }
 
+// WithWebhookClock sets the clock alerts are timestamped with.
+func WithWebhookClock(c clock.Clock) WebhookOption {
+	return func(wc *WebhookClient) {
+		wc.clock = c
+	}
+}
+
 // NewWebhookClient creates a new WebhookClient with the given webhook URL.
 // Returns nil if webhookURL is empty (alerts will be silently ignored).
 func NewWebhookClient(webhookURL string, opts ...WebhookOption) *WebhookClient {
@@ -91,6 +100,7 @@ func NewWebhookClient(webhookURL string, opts ...WebhookOption) *WebhookClient {
 			Timeout: 10 * time.Second,
 		},
 		logger: logging.Get(),
+		clock:  clock.Real(),
 	}
 
 	for _, opt := range opts {
@@ -112,7 +122,7 @@ func (wc *WebhookClient) SendAlert(ctx context.Context, level Level, message str
 	payload := WebhookPayload{
 		Level:     level,
 		Message:   message,
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Timestamp: wc.clock.Now().UTC().Format(time.RFC3339),
 		Context:   alertCtx,
 	}
 
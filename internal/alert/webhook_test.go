@@ -7,6 +7,8 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/tmeurs/spinup/internal/clock"
 )
 
 func TestNewWebhookClient(t *testing.T) {
@@ -98,6 +100,26 @@ func TestWebhookClient_SendAlert(t *testing.T) {
 	}
 }
 
+func TestWebhookClient_SendAlert_Clock(t *testing.T) {
+	var receivedPayload WebhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&receivedPayload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cet := time.FixedZone("CET", 3600)
+	fake := clock.NewFake(time.Date(2026, 2, 2, 18, 0, 15, 0, cet))
+	client := NewWebhookClient(server.URL, WithWebhookClock(fake))
+
+	if err := client.SendAlert(context.Background(), LevelWarn, "test", Context{}); err != nil {
+		t.Fatalf("SendAlert returned error: %v", err)
+	}
+	if receivedPayload.Timestamp != "2026-02-02T17:00:15Z" {
+		t.Errorf("timestamp = %s, want the clock's time in UTC", receivedPayload.Timestamp)
+	}
+}
+
 func TestWebhookClient_SendAlert_NilClient(t *testing.T) {
 	var client *WebhookClient = nil
 	ctx := context.Background()
@@ -12,6 +12,7 @@ import (
 	"github.com/tmeurs/spinup/internal/config"
 	"github.com/tmeurs/spinup/internal/deploy"
 	"github.com/tmeurs/spinup/internal/logging"
+	"github.com/tmeurs/spinup/internal/offerexpr"
 )
 
 // RunCheapestDeploy executes the --cheapest non-interactive deployment flow.
@@ -23,13 +24,24 @@ import (
 //	      ✓ Selected: vast.ai A100 40GB EU-West @ €0.65/hr spot
 //
 // ...and so on.
-// When --output=json is set, it outputs JSON only at the end.
-func RunCheapestDeploy(modelName, providerName, gpuType, regionName string, preferSpot bool, timeoutStr string) error {
+// When --output=json is set, it outputs JSON only at the end; with
+// --output=ndjson every progress update is printed as an event.
+func RunCheapestDeploy(modelName, providerName, gpuType, regionName, filterStr string, preferSpot bool, timeoutStr string) error {
 	log := logging.Get()
 	jsonOutput := IsJSONOutput()
+	events := newEventWriter("deploy")
+	textOutput := !jsonOutput && events == nil
+
+	fail := func(err error) error {
+		if jsonOutput {
+			PrintJSONError(err)
+		}
+		events.failure(err)
+		return err
+	}
 
 	// Print header (skip in JSON mode)
-	if !jsonOutput {
+	if textOutput {
 		fmt.Printf("\nspinup %s - Starting deployment\n\n", Version)
 	}
 
@@ -41,36 +53,36 @@ func RunCheapestDeploy(modelName, providerName, gpuType, regionName string, pref
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigCh
-		if !jsonOutput {
+		if textOutput {
 			fmt.Println("\n\nInterrupted. Cleaning up...")
 		}
 		cancel()
 	}()
 
 	// Load configuration
-	cfg, warnings, err := config.LoadConfig("")
+	cfg, warnings, err := loadConfig()
 	if err != nil {
-		if jsonOutput {
-			PrintJSONError(fmt.Errorf("failed to load config: %w", err))
-		}
-		return fmt.Errorf("failed to load config: %w", err)
+		return fail(fmt.Errorf("failed to load config: %w", err))
 	}
 	for _, w := range warnings {
 		log.Warn().Msg(w)
+		events.warning(WarningInfo{Message: w})
 	}
 
 	// Validate that at least one provider is configured
 	if !cfg.HasAnyProvider() {
-		err := fmt.Errorf("no providers configured - run 'spinup init' first or set API keys in .env")
-		if jsonOutput {
-			PrintJSONError(err)
-		}
-		return err
+		return fail(fmt.Errorf("no providers configured - run 'spinup init' first or set API keys in .env"))
 	}
 
 	// Parse timeout
 	deadmanHours := parseTimeout(timeoutStr)
 
+	// Parse the offer filter; without one the config's OFFER_FILTER applies
+	offerFilter, err := offerexpr.Parse(filterStr)
+	if err != nil {
+		return fail(fmt.Errorf("invalid --filter: %w", err))
+	}
+
 	// Create deploy configuration
 	deployCfg := deploy.DefaultDeployConfig()
 	deployCfg.Model = modelName
@@ -78,41 +90,39 @@ func RunCheapestDeploy(modelName, providerName, gpuType, regionName string, pref
 	deployCfg.ProviderName = providerName
 	deployCfg.GPUType = gpuType
 	deployCfg.Region = regionName
+	deployCfg.Filter = offerFilter
 	deployCfg.DeadmanTimeoutHours = deadmanHours
+	deployCfg.MaxAttempts = maxAttempts
 
 	// Create state manager
-	stateManager, err := config.NewStateManager("")
+	stateManager, err := newSessionStateManager()
 	if err != nil {
-		if jsonOutput {
-			PrintJSONError(fmt.Errorf("failed to create state manager: %w", err))
-		}
-		return fmt.Errorf("failed to create state manager: %w", err)
+		return fail(fmt.Errorf("failed to create state manager: %w", err))
 	}
 
 	// Check if there's already an active instance
 	existingState, _ := stateManager.LoadState()
 	if existingState != nil && existingState.Instance != nil {
 		err := fmt.Errorf("an instance is already running (ID: %s). Use --stop first", existingState.Instance.ID)
-		if jsonOutput {
-			PrintJSONError(err)
+		if stateManager.Session() != config.DefaultSessionName {
+			err = fmt.Errorf("session %q already has an instance running (ID: %s). Use --stop --session %s first, or pick another --session",
+				stateManager.Session(), existingState.Instance.ID, stateManager.Session())
 		}
-		return err
+		return fail(err)
 	}
 
-	// Create deployer with progress callback (skip in JSON mode)
-	var progressCb func(deploy.DeployProgress)
-	if !jsonOutput {
-		progressCb = cheapestProgressCallback
+	// Refuse to start over an unfinished deployment's instance
+	if err := checkUnfinishedDeploy(stateManager); err != nil {
+		return fail(err)
 	}
+
+	// Create deployer with progress callback (skip in JSON mode)
 	deployer, err := deploy.NewDeployer(cfg, deployCfg,
-		deploy.WithProgressCallback(progressCb),
+		deploy.WithProgressCallback(deployProgressCallback(textOutput, events)),
 		deploy.WithStateManager(stateManager),
 	)
 	if err != nil {
-		if jsonOutput {
-			PrintJSONError(fmt.Errorf("failed to create deployer: %w", err))
-		}
-		return fmt.Errorf("failed to create deployer: %w", err)
+		return fail(fmt.Errorf("failed to create deployer: %w", err))
 	}
 
 	// Run deployment
@@ -126,22 +136,37 @@ func RunCheapestDeploy(modelName, providerName, gpuType, regionName string, pref
 
 	result, err := deployer.Deploy(ctx)
 	if err != nil {
-		if jsonOutput {
-			PrintJSONError(err)
-		}
-		return err
+		return fail(err)
 	}
 
+	// Hand the session over to the background supervisor
+	startSupervisorDaemon(stateManager)
+
 	// Print success summary
-	if jsonOutput {
-		printDeploymentSummaryJSON(result, deadmanHours)
-	} else {
-		printDeploymentSummary(result)
+	switch {
+	case jsonOutput:
+		printDeploymentSummaryJSON(result, deadmanHours, stateManager.Session())
+	case events != nil:
+		events.result(buildDeployOutput(result, deadmanHours, stateManager.Session()))
+	default:
+		printDeploymentSummary(result, stateManager.Session())
 	}
 
 	return nil
 }
 
+// deployProgressCallback returns the deployer progress callback for the
+// output format: text, NDJSON events, or none for JSON.
+func deployProgressCallback(textOutput bool, events *eventWriter) func(deploy.DeployProgress) {
+	switch {
+	case events != nil:
+		return events.deployProgress
+	case textOutput:
+		return cheapestProgressCallback
+	}
+	return nil
+}
+
 // cheapestProgressCallback handles progress updates from the deployer.
 // It formats output to match the PRD format.
 func cheapestProgressCallback(progress deploy.DeployProgress) {
@@ -151,7 +176,11 @@ func cheapestProgressCallback(progress deploy.DeployProgress) {
 	if progress.Completed {
 		// Print completed step with checkmark
 		fmt.Printf("[%d/%d] %s\n", stepNum, totalSteps, progress.Step.String())
-		fmt.Printf("      ✓ %s\n\n", progress.Message)
+		if progress.Detail != "" {
+			fmt.Printf("      ✓ %s (%s)\n\n", progress.Message, progress.Detail)
+		} else {
+			fmt.Printf("      ✓ %s\n\n", progress.Message)
+		}
 	} else if progress.Error != nil {
 		// Print error
 		fmt.Printf("[%d/%d] %s\n", stepNum, totalSteps, progress.Step.String())
@@ -168,10 +197,13 @@ func cheapestProgressCallback(progress deploy.DeployProgress) {
 }
 
 // printDeploymentSummary prints the final deployment summary.
-func printDeploymentSummary(result *deploy.DeployResult) {
+func printDeploymentSummary(result *deploy.DeployResult, sessionName string) {
 	fmt.Println("─────────────────────────────────────────────────────")
 	fmt.Println("DEPLOYMENT COMPLETE")
 	fmt.Println("─────────────────────────────────────────────────────")
+	if sessionName != config.DefaultSessionName {
+		fmt.Printf("  Session:     %s\n", sessionName)
+	}
 	fmt.Printf("  Provider:    %s\n", result.Provider.Name())
 	fmt.Printf("  GPU:         %s\n", result.SelectedOffer.GPU)
 	fmt.Printf("  Region:      %s\n", result.SelectedOffer.Region)
@@ -187,6 +219,40 @@ func printDeploymentSummary(result *deploy.DeployResult) {
 		hourlyRate = *result.SelectedOffer.SpotPrice
 	}
 	fmt.Printf("  Pricing:     €%.2f/hr (%s)\n", hourlyRate, priceType)
+	if result.SelectedScore != nil {
+		fmt.Printf("  Score:       %s\n", result.SelectedScore)
+	}
+
+	if len(result.ProviderReports) > 0 {
+		fmt.Println()
+		fmt.Println("PROVIDERS")
+		fmt.Println("─────────────────────────────────────────────────────")
+		for _, r := range result.ProviderReports {
+			if r.Cached {
+				fmt.Printf("  ✓ %-11s %3d offers  cached %s ago\n", r.Provider, r.OfferCount, formatDuration(time.Since(r.FetchedAt)))
+			} else if r.OK() {
+				fmt.Printf("  ✓ %-11s %3d offers  %s\n", r.Provider, r.OfferCount, formatLatency(r.Latency))
+			} else {
+				fmt.Printf("  ✗ %-11s %-10s  %s\n", r.Provider, r.ErrorCode, formatLatency(r.Latency))
+			}
+		}
+	}
+
+	if len(result.Attempts) > 1 {
+		fmt.Println()
+		fmt.Println("ATTEMPTS")
+		fmt.Println("─────────────────────────────────────────────────────")
+		for _, a := range result.Attempts {
+			if a.Succeeded() {
+				fmt.Printf("  ✓ %d. %s %s %s\n", a.Number, a.Provider, a.GPU, a.Region)
+				continue
+			}
+			fmt.Printf("  ✗ %d. %s %s %s: %v\n", a.Number, a.Provider, a.GPU, a.Region, a.Err)
+			if a.Terminated {
+				fmt.Printf("       instance %s terminated\n", a.InstanceID)
+			}
+		}
+	}
 
 	fmt.Println()
 	fmt.Println("CONNECT")
@@ -255,7 +321,12 @@ func parseTimeout(timeoutStr string) int {
 
 // printDeploymentSummaryJSON prints the deployment summary in JSON format.
 // Matches PRD Section 3.2 JSON format.
-func printDeploymentSummaryJSON(result *deploy.DeployResult, deadmanHours int) {
+func printDeploymentSummaryJSON(result *deploy.DeployResult, deadmanHours int, sessionName string) {
+	PrintJSON(buildDeployOutput(result, deadmanHours, sessionName))
+}
+
+// buildDeployOutput builds the JSON output of a completed deployment.
+func buildDeployOutput(result *deploy.DeployResult, deadmanHours int, sessionName string) DeployOutput {
 	// Determine pricing type and rate
 	instanceType := "on-demand"
 	hourlyRate := result.SelectedOffer.OnDemandPrice
@@ -283,7 +354,8 @@ func printDeploymentSummaryJSON(result *deploy.DeployResult, deadmanHours int) {
 	}
 
 	output := DeployOutput{
-		Status: "ready",
+		Status:  "ready",
+		Session: sessionName,
 		Instance: &DeployInstanceInfo{
 			ID:       result.Instance.ID,
 			Provider: result.Provider.Name(),
@@ -297,7 +369,10 @@ func printDeploymentSummaryJSON(result *deploy.DeployResult, deadmanHours int) {
 			Hourly:   hourlyRate,
 			Currency: "EUR",
 		},
-		Duration: formatDuration(result.Duration()),
+		Duration:  formatDuration(result.Duration()),
+		Providers: buildProviderFetchInfo(result.ProviderReports),
+		Attempts:  buildDeployAttemptInfo(result.Attempts),
+		Score:     buildOfferScoreInfo(result.SelectedScore),
 	}
 
 	// Add endpoint info if WireGuard is configured
@@ -317,5 +392,67 @@ func printDeploymentSummaryJSON(result *deploy.DeployResult, deadmanHours int) {
 		RemainingSeconds: remainingSeconds,
 	}
 
-	PrintJSON(output)
+	return output
+}
+
+// buildProviderFetchInfo converts offer fetch reports to their JSON representation.
+func buildProviderFetchInfo(reports []deploy.ProviderFetchReport) []ProviderFetchInfo {
+	var infos []ProviderFetchInfo
+	for _, r := range reports {
+		info := ProviderFetchInfo{
+			Provider:   r.Provider,
+			LatencyMs:  r.Latency.Milliseconds(),
+			OfferCount: r.OfferCount,
+			ErrorCode:  r.ErrorCode,
+			Cached:     r.Cached,
+		}
+		if r.Err != nil {
+			info.Error = r.Err.Error()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// formatLatency formats a request latency for display (e.g., "340ms", "2.1s").
+func formatLatency(d time.Duration) string {
+	if d < time.Second {
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	}
+	return fmt.Sprintf("%.1fs", d.Seconds())
+}
+
+// buildDeployAttemptInfo converts offer attempts to their JSON representation.
+func buildDeployAttemptInfo(attempts []deploy.DeployAttempt) []DeployAttemptInfo {
+	var infos []DeployAttemptInfo
+	for _, a := range attempts {
+		info := DeployAttemptInfo{
+			Number:     a.Number,
+			Provider:   a.Provider,
+			OfferID:    a.OfferID,
+			GPU:        a.GPU,
+			Region:     a.Region,
+			InstanceID: a.InstanceID,
+			Succeeded:  a.Succeeded(),
+			Terminated: a.Terminated,
+		}
+		if a.Err != nil {
+			info.FailedStep = a.FailedStep.String()
+			info.Error = a.Err.Error()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// buildOfferScoreInfo converts an offer score to its JSON representation.
+func buildOfferScoreInfo(score *deploy.OfferScore) *OfferScoreInfo {
+	if score == nil {
+		return nil
+	}
+	info := &OfferScoreInfo{Total: score.Total}
+	for _, f := range score.Factors {
+		info.Factors = append(info.Factors, ScoreFactorInfo{Name: f.Name, Value: f.Value, Weight: f.Weight})
+	}
+	return info
 }
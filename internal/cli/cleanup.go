@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/logging"
+)
+
+// cleanupCmd represents the cleanup command
+var cleanupCmd = &cobra.Command{
+	Use:   "cleanup",
+	Short: "Terminate instances left behind by unfinished deployments",
+	Long: `Terminate instances left behind by unfinished deployments.
+
+Every deployment writes a journal to the state directory before each step.
+If spinup was killed between creating an instance and finishing the
+deployment, the journal still records that instance. This command
+terminates it (with the same retries and billing verification as --stop),
+removes its WireGuard tunnel, and deletes the journal.
+
+Without --session, journals of all sessions are cleaned up.`,
+	Run: runCleanupCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(cleanupCmd)
+
+	cleanupCmd.Flags().StringVar(&output, "output", "text", "Output format: text, json")
+}
+
+func runCleanupCmd(cmd *cobra.Command, args []string) {
+	if err := RunCleanup(); err != nil {
+		logging.Error().Err(err).Msg("Cleanup failed")
+		if !IsJSONOutput() {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// RunCleanup terminates the instances recorded in unfinished deploy journals.
+// With --session only that session's journal is handled.
+func RunCleanup() error {
+	log := logging.Get()
+	jsonOutput := IsJSONOutput()
+
+	if !jsonOutput {
+		fmt.Printf("\nspinup %s - Cleaning up unfinished deployments\n\n", Version)
+	}
+
+	// Set up context with cancellation on SIGINT/SIGTERM
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		if !jsonOutput {
+			fmt.Println("\n\nInterrupted. Cleanup may be incomplete - run 'spinup cleanup' again.")
+		}
+		cancel()
+	}()
+
+	cfg, warnings, err := loadConfig()
+	if err != nil {
+		if jsonOutput {
+			PrintJSONError(fmt.Errorf("failed to load config: %w", err))
+		}
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	for _, w := range warnings {
+		log.Warn().Msg(w)
+	}
+
+	stateManager, err := newSessionStateManager()
+	if err != nil {
+		if jsonOutput {
+			PrintJSONError(fmt.Errorf("failed to create state manager: %w", err))
+		}
+		return fmt.Errorf("failed to create state manager: %w", err)
+	}
+
+	journals, err := loadCleanupJournals(stateManager)
+	if err != nil {
+		if jsonOutput {
+			PrintJSONError(err)
+		}
+		return err
+	}
+
+	if len(journals) == 0 {
+		if jsonOutput {
+			PrintJSON(CleanupOutput{Status: "nothing_to_clean"})
+		} else {
+			fmt.Println("No unfinished deployments to clean up.")
+		}
+		return nil
+	}
+
+	out := CleanupOutput{Status: "cleaned"}
+	var failed int
+	for _, journal := range journals {
+		info := cleanupJournal(ctx, cfg, stateManager, journal)
+		if info.Action == "error" {
+			failed++
+			out.Status = "error"
+		}
+		out.Journals = append(out.Journals, info)
+	}
+
+	if jsonOutput {
+		PrintJSON(out)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d unfinished deployments could not be cleaned up", failed, len(journals))
+	}
+	return nil
+}
+
+// loadCleanupJournals returns the journal of the --session session, or all
+// journals when no session was given.
+func loadCleanupJournals(stateManager *config.StateManager) ([]*deploy.DeployJournal, error) {
+	if session == "" {
+		return deploy.ListDeployJournals(stateManager)
+	}
+
+	journal, err := deploy.LoadDeployJournal(stateManager)
+	if err != nil || journal == nil {
+		return nil, err
+	}
+	return []*deploy.DeployJournal{journal}, nil
+}
+
+// cleanupJournal terminates one journal's instance and removes the journal.
+func cleanupJournal(ctx context.Context, cfg *config.Config, stateManager *config.StateManager, journal *deploy.DeployJournal) CleanupJournalInfo {
+	log := logging.Get()
+	jsonOutput := IsJSONOutput()
+
+	info := CleanupJournalInfo{
+		Session:           journal.Session,
+		InstanceID:        journal.InstanceID,
+		Provider:          journal.Provider,
+		LastCompletedStep: journal.LastCompletedStep.String(),
+	}
+	fail := func(err error) CleanupJournalInfo {
+		info.Action = "error"
+		info.Error = err.Error()
+		log.Error().Err(err).Str("session", journal.Session).Str("instance_id", journal.InstanceID).Msg("Cleanup failed")
+		if !jsonOutput {
+			fmt.Printf("      ✗ %v\n\n", err)
+		}
+		return info
+	}
+
+	if !jsonOutput {
+		if journal.HasInstance() {
+			fmt.Printf("Session %s: instance %s (%s), started %s\n\n", journal.Session, journal.InstanceID, journal.Provider,
+				journal.StartedAt.Local().Format("2006-01-02 15:04"))
+		} else {
+			fmt.Printf("Session %s: no instance was created\n\n", journal.Session)
+		}
+	}
+
+	sessionSM, err := stateManager.ForSession(journal.Session)
+	if err != nil {
+		return fail(err)
+	}
+
+	// The deployment finished and only the journal removal was missed; the
+	// instance belongs to the session now and is stopped with --stop.
+	if state, _ := sessionSM.LoadState(); state != nil && state.Instance != nil && state.Instance.ID == journal.InstanceID {
+		if err := journal.Remove(); err != nil {
+			return fail(err)
+		}
+		info.Action = "discarded"
+		if !jsonOutput {
+			fmt.Printf("      ✓ Deployment had completed, journal removed\n\n")
+		}
+		return info
+	}
+
+	var progressCb func(deploy.StopProgress)
+	var manualVerifCb func(*deploy.ManualVerification)
+	if !jsonOutput {
+		progressCb = stopProgressCallback
+		manualVerifCb = displayManualVerification
+	}
+	stopper, err := deploy.NewStopper(cfg, deploy.DefaultStopConfig(),
+		deploy.WithStopProgressCallback(progressCb),
+		deploy.WithStopStateManager(sessionSM),
+		deploy.WithManualVerificationCallback(manualVerifCb),
+	)
+	if err != nil {
+		return fail(fmt.Errorf("failed to create stopper: %w", err))
+	}
+
+	result, err := stopper.CleanupJournal(ctx, journal)
+	if result != nil {
+		info.BillingVerified = result.BillingVerified
+		info.ManualVerificationRequired = result.ManualVerificationRequired
+		info.ConsoleURL = result.ConsoleURL
+	}
+	if err != nil && !errors.Is(err, deploy.ErrBillingNotVerified) {
+		return fail(err)
+	}
+
+	if journal.HasInstance() {
+		info.Action = "terminated"
+	} else {
+		info.Action = "discarded"
+	}
+	if err != nil {
+		// Terminated and journal removed, but billing could not be confirmed
+		info.Error = err.Error()
+	}
+	return info
+}
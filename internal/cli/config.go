@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/logging"
+)
+
+// configEffective tracks if config show --effective was requested
+var configEffective bool
+
+// secretsPassphrase is the passphrase entered to unlock the secrets file.
+// It is kept for the rest of the process and handed to the supervisor
+// daemon, which can't prompt.
+var secretsPassphrase []byte
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the layered configuration",
+	Long: `Inspect the layered configuration.
+
+Settings are read from these layers, each overriding the ones before:
+
+  1. built-in defaults
+  2. the global config file, ~/.config/spinup/config.toml
+     ($XDG_CONFIG_HOME/spinup/config.toml if set)
+  3. the profile selected with --profile or SPINUP_PROFILE, a
+     [profiles.<name>] table in the global config file
+  4. the project .env file in the current directory
+  5. environment variables
+  6. flags
+
+Any setting can reference a secret instead of holding it, e.g.
+VAST_API_KEY=secret://vast. SECRET_BACKEND selects where secrets are read:
+an age-encrypted file (file, the default), a command such as 'pass show'
+(command), or SPINUP_SECRET_<NAME> variables (env). The secret store
+settings are not accepted from the project .env file.
+
+The global config file uses the .env variable names in lowercase, e.g.
+vast_api_key = "..." or preferred_regions = ["eu-west", "eu-central"].`,
+}
+
+// configShowCmd represents the config show command
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the configuration",
+	Long: `Show the configuration files in use and the settings they set.
+
+With --effective, every setting is listed, including defaults, with the
+layer its value came from. API keys and the WireGuard private key are
+masked; secret references are shown as they are written.`,
+	Run: runConfigShowCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configShowCmd)
+
+	configShowCmd.Flags().BoolVar(&configEffective, "effective", false, "List every setting with the origin of its value")
+	configShowCmd.Flags().StringVar(&output, "output", "text", "Output format: text, json")
+}
+
+// configOptions returns the options selecting the configuration layers
+// from the --profile and --state-dir flags.
+func configOptions() []config.LoadOption {
+	return []config.LoadOption{
+		config.WithProfile(profile),
+		config.WithFlag("STATE_DIR", stateDir, "--state-dir"),
+		config.WithPassphrase(promptPassphrase),
+	}
+}
+
+// promptPassphrase asks for the passphrase of the secrets file on the
+// terminal, twice if the file is new. It returns nil without a terminal.
+func promptPassphrase(confirm bool) ([]byte, error) {
+	if secretsPassphrase != nil {
+		return secretsPassphrase, nil
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) {
+		return nil, nil
+	}
+
+	passphrase, err := readPassphrase("Secrets passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if confirm {
+		again, err := readPassphrase("Confirm passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(passphrase, again) {
+			return nil, errors.New("passphrases don't match")
+		}
+	}
+
+	secretsPassphrase = passphrase
+	return passphrase, nil
+}
+
+// readPassphrase prompts on stderr and reads a line without echoing it.
+func readPassphrase(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err := term.ReadPassword(os.Stdin.Fd())
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return passphrase, nil
+}
+
+// loadConfig loads the layered configuration for the selected profile.
+func loadConfig() (*config.Config, []string, error) {
+	return config.Load(configOptions()...)
+}
+
+// newStateManager creates a state manager in the configured state
+// directory, creating the directory if needed.
+func newStateManager(opts ...config.StateManagerOption) (*config.StateManager, error) {
+	dir, err := config.ResolveStateDir(configOptions()...)
+	if err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create state directory: %w", err)
+		}
+	}
+	return config.NewStateManager(dir, opts...)
+}
+
+func runConfigShowCmd(cmd *cobra.Command, args []string) {
+	if err := RunConfigShow(configEffective); err != nil {
+		logging.Error().Err(err).Msg("Config show failed")
+		if IsJSONOutput() {
+			PrintJSONError(err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// RunConfigShow prints the configuration. Without effective, only settings
+// set by a file, the environment or a flag are shown.
+func RunConfigShow(effective bool) error {
+	cfg, warnings, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	out := ConfigOutput{
+		Profile:  cfg.Profile,
+		Files:    cfg.Files,
+		StateDir: cfg.StateDir,
+		Settings: make([]ConfigSettingInfo, 0, len(cfg.Effective)),
+	}
+	if out.Files == nil {
+		out.Files = []string{}
+	}
+	for _, v := range cfg.Effective {
+		if !effective && v.Origin.Scope == config.ScopeDefault {
+			continue
+		}
+		value := v.Value
+		if config.IsSecret(v.Key) && !config.IsSecretRef(value) {
+			value = maskSecret(value)
+		}
+		out.Settings = append(out.Settings, ConfigSettingInfo{
+			Key:    v.Key,
+			Value:  value,
+			Scope:  string(v.Origin.Scope),
+			Origin: v.Origin.String(),
+		})
+	}
+
+	if IsJSONOutput() {
+		PrintJSON(out)
+		return nil
+	}
+
+	for _, w := range warnings {
+		fmt.Printf("Warning: %s\n", w)
+	}
+	printConfig(out, effective)
+	return nil
+}
+
+// printConfig prints the configuration as text.
+func printConfig(out ConfigOutput, effective bool) {
+	profileName := out.Profile
+	if profileName == "" {
+		profileName = "(none)"
+	}
+	fmt.Printf("Profile:    %s\n", profileName)
+	if len(out.Files) == 0 {
+		fmt.Println("Files:      (none)")
+	}
+	for i, f := range out.Files {
+		label := ""
+		if i == 0 {
+			label = "Files:"
+		}
+		fmt.Printf("%-11s %s\n", label, f)
+	}
+	fmt.Printf("State dir:  %s\n\n", out.StateDir)
+
+	if len(out.Settings) == 0 {
+		fmt.Println("No settings configured. Run 'spinup init' to create a configuration.")
+		return
+	}
+
+	keyWidth, valueWidth := len("SETTING"), len("VALUE")
+	for _, s := range out.Settings {
+		keyWidth = max(keyWidth, len(s.Key))
+		valueWidth = max(valueWidth, len(s.Value))
+	}
+
+	if !effective {
+		for _, s := range out.Settings {
+			fmt.Printf("%-*s  %s\n", keyWidth, s.Key, s.Value)
+		}
+		return
+	}
+
+	fmt.Printf("%-*s  %-*s  %s\n", keyWidth, "SETTING", valueWidth, "VALUE", "ORIGIN")
+	for _, s := range out.Settings {
+		fmt.Printf("%-*s  %-*s  %s\n", keyWidth, s.Key, valueWidth, s.Value, s.Origin)
+	}
+}
+
+// maskSecret hides all but the last four characters of a credential.
+func maskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 8 {
+		return strings.Repeat("*", len(value))
+	}
+	return strings.Repeat("*", 8) + value[len(value)-4:]
+}
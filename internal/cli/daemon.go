@@ -0,0 +1,255 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tmeurs/spinup/internal/alert"
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/logging"
+)
+
+// daemonStopTimeout is how long --stop waits for the supervisor daemon to exit.
+const daemonStopTimeout = 5 * time.Second
+
+// daemonPassphraseStdin makes the daemon read the secrets passphrase from
+// stdin, where spawnDaemon hands it over.
+var daemonPassphraseStdin bool
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the background supervisor for the active instance",
+	Long: `Run the background supervisor for the active instance.
+
+The supervisor keeps the deadman switch alive with heartbeats, watches for
+spot interruptions, and records accumulated cost against the daily budget.
+It is started automatically after a successful deployment and exits when
+the instance is stopped with --stop.
+
+You normally don't need to run this command yourself.`,
+	Run: runDaemonCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+
+	daemonCmd.Flags().BoolVar(&daemonPassphraseStdin, "secrets-passphrase-stdin", false, "Read the secrets passphrase from stdin")
+	_ = daemonCmd.Flags().MarkHidden("secrets-passphrase-stdin")
+}
+
+func runDaemonCmd(cmd *cobra.Command, args []string) {
+	if err := RunDaemon(); err != nil {
+		logging.Error().Err(err).Msg("Supervisor daemon failed")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// RunDaemon runs the supervisor for the active session in the foreground
+// until it is signalled or the session is stopped.
+func RunDaemon() error {
+	log := logging.Get()
+
+	if daemonPassphraseStdin {
+		passphrase, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read secrets passphrase: %w", err)
+		}
+		if len(passphrase) > 0 {
+			secretsPassphrase = passphrase
+		}
+	}
+
+	cfg, warnings, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	for _, w := range warnings {
+		log.Warn().Msg(w)
+	}
+
+	stateManager, err := newSessionStateManager()
+	if err != nil {
+		return fmt.Errorf("failed to create state manager: %w", err)
+	}
+
+	// Refuse to run next to another live supervisor
+	pid, err := stateManager.ReadDaemonPID()
+	if err == nil && pid != os.Getpid() && processAlive(pid) {
+		return fmt.Errorf("supervisor daemon already running (PID %d)", pid)
+	}
+	if err := stateManager.WriteDaemonPID(os.Getpid()); err != nil {
+		return err
+	}
+	defer func() {
+		// Only clear the PID file if it still belongs to this process
+		if pid, err := stateManager.ReadDaemonPID(); err == nil && pid == os.Getpid() {
+			if err := stateManager.ClearDaemonPID(); err != nil {
+				log.Warn().Err(err).Msg("Failed to clear daemon PID file")
+			}
+		}
+	}()
+
+	dispatcher := alert.NewDispatcher(
+		alert.WithDispatcherWebhookClient(alert.NewWebhookClient(cfg.AlertWebhookURL)),
+		alert.WithTUIEnabled(false),
+	)
+
+	supervisor, err := deploy.NewSupervisor(cfg, stateManager, nil,
+		deploy.WithSupervisorDispatcher(dispatcher),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create supervisor: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+	go func() {
+		select {
+		case sig := <-sigCh:
+			log.Info().Str("signal", sig.String()).Msg("Supervisor daemon received signal")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	log.Info().Int("pid", os.Getpid()).Str("session", stateManager.Session()).Msg("Supervisor daemon started")
+
+	err = supervisor.Run(ctx)
+	if errors.Is(err, config.ErrNoActiveInstance) {
+		log.Info().Msg("No active instance, supervisor daemon exiting")
+		return nil
+	}
+	return err
+}
+
+// spawnDaemon starts the supervisor daemon as a detached background process
+// so it outlives the current CLI invocation. It is a no-op if a live daemon
+// is already recorded in the state directory.
+func spawnDaemon(stateManager *config.StateManager) (int, error) {
+	if pid, err := stateManager.ReadDaemonPID(); err == nil && processAlive(pid) {
+		return pid, nil
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("failed to locate spinup executable: %w", err)
+	}
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", os.DevNull, err)
+	}
+	defer devNull.Close()
+
+	// The daemon runs in this directory so it reads the same project .env,
+	// with the same profile and state directory
+	args := []string{"daemon", "--session", stateManager.Session(), "--state-dir", stateManager.StateDir()}
+	if profile != "" {
+		args = append(args, "--profile", profile)
+	}
+	daemon := exec.Command(executable, args...)
+	if cwd, err := os.Getwd(); err == nil {
+		daemon.Dir = cwd
+	} else {
+		daemon.Dir = stateManager.StateDir()
+	}
+	daemon.Stdin = devNull
+	daemon.Stdout = devNull
+	daemon.Stderr = devNull
+	daemon.SysProcAttr = daemonSysProcAttr()
+
+	// A passphrase entered for the secrets file lets the daemon unlock it
+	// too. It goes through a pipe rather than the environment, which other
+	// processes of the user can read.
+	var passphraseW *os.File
+	if secretsPassphrase != nil {
+		r, w, err := os.Pipe()
+		if err != nil {
+			return 0, fmt.Errorf("failed to create passphrase pipe: %w", err)
+		}
+		defer r.Close()
+		passphraseW = w
+		daemon.Stdin = r
+		daemon.Args = append(daemon.Args, "--secrets-passphrase-stdin")
+	}
+
+	if err := daemon.Start(); err != nil {
+		if passphraseW != nil {
+			passphraseW.Close()
+		}
+		return 0, fmt.Errorf("failed to start supervisor daemon: %w", err)
+	}
+	pid := daemon.Process.Pid
+
+	if passphraseW != nil {
+		_, err := passphraseW.Write(secretsPassphrase)
+		passphraseW.Close()
+		if err != nil {
+			logging.Warn().Err(err).Msg("Failed to hand the secrets passphrase to the supervisor daemon")
+		}
+	}
+
+	// Record the PID right away so an immediate --stop can find the daemon
+	if err := stateManager.WriteDaemonPID(pid); err != nil {
+		logging.Warn().Err(err).Msg("Failed to record supervisor daemon PID")
+	}
+	// Reap the daemon if it exits while this process is still running
+	go daemon.Wait()
+
+	logging.Info().Int("pid", pid).Msg("Supervisor daemon spawned")
+	return pid, nil
+}
+
+// startSupervisorDaemon spawns the supervisor daemon after a successful
+// deployment. Failure is logged but does not fail the deployment.
+func startSupervisorDaemon(stateManager *config.StateManager) {
+	if _, err := spawnDaemon(stateManager); err != nil {
+		logging.Warn().Err(err).Msg("Failed to start supervisor daemon - heartbeats will not be sent")
+	}
+}
+
+// stopDaemon signals the supervisor daemon to exit and waits briefly for it.
+// It is safe to call when no daemon is running.
+func stopDaemon(stateManager *config.StateManager) error {
+	pid, err := stateManager.ReadDaemonPID()
+	if errors.Is(err, config.ErrNoDaemon) {
+		return nil
+	}
+	if err != nil {
+		// Unreadable PID file - nothing we can signal
+		return stateManager.ClearDaemonPID()
+	}
+
+	if processAlive(pid) {
+		if err := terminateProcess(pid); err != nil {
+			logging.Warn().Err(err).Int("pid", pid).Msg("Failed to signal supervisor daemon")
+		}
+
+		deadline := time.Now().Add(daemonStopTimeout)
+		for processAlive(pid) && time.Now().Before(deadline) {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if processAlive(pid) {
+			logging.Warn().Int("pid", pid).Msg("Supervisor daemon did not exit in time")
+		} else {
+			logging.Info().Int("pid", pid).Msg("Supervisor daemon stopped")
+		}
+	}
+
+	return stateManager.ClearDaemonPID()
+}
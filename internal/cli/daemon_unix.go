@@ -0,0 +1,27 @@
+//go:build unix
+
+package cli
+
+import (
+	"syscall"
+)
+
+// daemonSysProcAttr detaches the daemon into its own session so it survives
+// the terminal that started it.
+func daemonSysProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// processAlive reports whether a process with the given PID exists.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// terminateProcess asks the process to shut down gracefully.
+func terminateProcess(pid int) error {
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
@@ -0,0 +1,195 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/logging"
+)
+
+// resume tracks if deploy --resume was requested
+var resume bool
+
+// deployCmd represents the deploy command
+var deployCmd = &cobra.Command{
+	Use:   "deploy",
+	Short: "Deploy the cheapest compatible instance, or resume an unfinished deployment",
+	Long: `Deploy the cheapest compatible instance without the interactive TUI.
+
+Without flags this is the same as 'spinup --cheapest'. Every deployment step
+is written to a journal in the state directory before it runs, so a
+deployment that was interrupted (crash, kill, closed terminal) can be
+continued with --resume instead of leaving a billed instance behind.
+
+Use 'spinup cleanup' to terminate an unfinished deployment instead.`,
+	Run: runDeployCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(deployCmd)
+
+	deployCmd.Flags().BoolVar(&resume, "resume", false, "Resume the unfinished deployment from its last completed step")
+	deployCmd.Flags().StringVar(&provider, "provider", "", "Force specific provider (vast, lambda, runpod, coreweave, paperspace)")
+	deployCmd.Flags().StringVar(&gpu, "gpu", "", "Force specific GPU type (a100-40, a100-80, a6000, h100)")
+	deployCmd.Flags().StringVar(&model, "model", "qwen2.5-coder:32b", "Model to deploy (e.g., qwen2.5-coder:32b)")
+	deployCmd.Flags().BoolVar(&spot, "spot", true, "Prefer spot instances")
+	deployCmd.Flags().BoolVar(&onDemand, "on-demand", false, "Force on-demand instances")
+	deployCmd.Flags().StringVar(&region, "region", "", "Preferred region (eu-west, us-east, etc.)")
+	deployCmd.Flags().StringVar(&filterExpr, "filter", "", "Offer filter expression, e.g. 'vram>=48 && provider!=paperspace' (default OFFER_FILTER)")
+	deployCmd.Flags().IntVar(&maxAttempts, "max-attempts", deploy.DefaultMaxAttempts, "Offers to try if instance creation fails or the instance doesn't boot")
+	deployCmd.Flags().StringVar(&output, "output", "text", "Output format: text, json, ndjson")
+	deployCmd.Flags().StringVar(&timeout, "timeout", "10h", "Deadman switch timeout")
+}
+
+func runDeployCmd(cmd *cobra.Command, args []string) {
+	var err error
+	if resume {
+		err = RunResumeDeploy()
+	} else {
+		// Determine spot preference: --on-demand overrides --spot
+		preferSpot := spot && !onDemand
+		err = RunCheapestDeploy(model, provider, gpu, region, filterExpr, preferSpot, timeout)
+	}
+	if err != nil {
+		logging.Error().Err(err).Msg("Deployment failed")
+		if GetOutputFormat() == OutputFormatText {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// RunResumeDeploy continues the session's unfinished deployment from its journal.
+// Output matches RunCheapestDeploy; steps that were already completed are
+// marked as resumed.
+func RunResumeDeploy() error {
+	log := logging.Get()
+	jsonOutput := IsJSONOutput()
+	events := newEventWriter("deploy")
+	textOutput := !jsonOutput && events == nil
+
+	fail := func(err error) error {
+		if jsonOutput {
+			PrintJSONError(err)
+		}
+		events.failure(err)
+		return err
+	}
+
+	if textOutput {
+		fmt.Printf("\nspinup %s - Resuming deployment\n\n", Version)
+	}
+
+	// Set up context with cancellation on SIGINT/SIGTERM
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		if textOutput {
+			fmt.Println("\n\nInterrupted. Cleaning up...")
+		}
+		cancel()
+	}()
+
+	cfg, warnings, err := loadConfig()
+	if err != nil {
+		return fail(fmt.Errorf("failed to load config: %w", err))
+	}
+	for _, w := range warnings {
+		log.Warn().Msg(w)
+		events.warning(WarningInfo{Message: w})
+	}
+
+	stateManager, err := newSessionStateManager()
+	if err != nil {
+		return fail(fmt.Errorf("failed to create state manager: %w", err))
+	}
+
+	journal, err := deploy.LoadDeployJournal(stateManager)
+	if err != nil {
+		return fail(err)
+	}
+	if journal == nil {
+		return fail(fmt.Errorf("no unfinished deployment to resume in session %q", stateManager.Session()))
+	}
+
+	// A journal left next to the state it produced only missed its removal
+	existingState, _ := stateManager.LoadState()
+	if existingState != nil && existingState.Instance != nil {
+		if existingState.Instance.ID == journal.InstanceID {
+			if err := journal.Remove(); err != nil {
+				return fail(err)
+			}
+			return fail(fmt.Errorf("deployment of instance %s already completed, nothing to resume", journal.InstanceID))
+		}
+		return fail(fmt.Errorf("session %q already has an instance running (ID: %s); run 'spinup cleanup' to discard the unfinished deployment",
+			stateManager.Session(), existingState.Instance.ID))
+	}
+
+	deployCfg := journal.DeployConfig()
+	deployer, err := deploy.NewDeployer(cfg, deployCfg,
+		deploy.WithProgressCallback(deployProgressCallback(textOutput, events)),
+		deploy.WithStateManager(stateManager),
+	)
+	if err != nil {
+		return fail(fmt.Errorf("failed to create deployer: %w", err))
+	}
+
+	log.Info().
+		Str("instance_id", journal.InstanceID).
+		Str("provider", journal.Provider).
+		Str("last_step", journal.LastCompletedStep.String()).
+		Msg("Resuming deployment")
+
+	result, err := deployer.Resume(ctx, journal)
+	if err != nil {
+		if errors.Is(err, deploy.ErrResumeInstanceGone) {
+			err = fmt.Errorf("%w - the journal was removed, run 'spinup deploy' to start over", err)
+		}
+		return fail(err)
+	}
+
+	// Hand the session over to the background supervisor
+	startSupervisorDaemon(stateManager)
+
+	switch {
+	case jsonOutput:
+		printDeploymentSummaryJSON(result, deployCfg.DeadmanTimeoutHours, stateManager.Session())
+	case events != nil:
+		events.result(buildDeployOutput(result, deployCfg.DeadmanTimeoutHours, stateManager.Session()))
+	default:
+		printDeploymentSummary(result, stateManager.Session())
+	}
+
+	return nil
+}
+
+// checkUnfinishedDeploy returns an error if the session has a journal recording
+// an instance that was created but never finished. A new deployment would
+// otherwise lose track of that (billed) instance.
+func checkUnfinishedDeploy(stateManager *config.StateManager) error {
+	journal, err := deploy.LoadDeployJournal(stateManager)
+	if err != nil {
+		return err
+	}
+	if !journal.HasInstance() {
+		return nil
+	}
+
+	sessionFlag := ""
+	if stateManager.Session() != config.DefaultSessionName {
+		sessionFlag = " --session " + stateManager.Session()
+	}
+	return fmt.Errorf("an unfinished deployment left instance %s (%s) running. Run 'spinup deploy --resume%s' to finish it or 'spinup cleanup%s' to terminate it",
+		journal.InstanceID, journal.Provider, sessionFlag, sessionFlag)
+}
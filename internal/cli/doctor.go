@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/doctor"
+	"github.com/tmeurs/spinup/internal/logging"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that this machine is ready to deploy",
+	Long: `Check that this machine is ready to deploy.
+
+Runs the checks a deployment depends on before any instance is rented:
+the .env file and its permissions, the configuration, the WireGuard key,
+the wg/ip (Linux) or wireguard-go/wg (macOS) tools, root or passwordless
+sudo, a leftover wg-spinup interface, other interfaces using the tunnel
+subnet 10.13.37.0/24, the state file, and the API key of every configured
+provider. Each problem comes with a hint on how to fix it.
+
+Exits with status 1 if any check fails.`,
+	Run: runDoctorCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().StringVar(&output, "output", "text", "Output format: text, json")
+}
+
+func runDoctorCmd(cmd *cobra.Command, args []string) {
+	if err := RunDoctor(); err != nil {
+		logging.Error().Err(err).Msg("Preflight checks failed")
+		if !IsJSONOutput() {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// RunDoctor runs the preflight checks and prints their outcome. It returns
+// an error if any check failed.
+func RunDoctor() error {
+	jsonOutput := IsJSONOutput()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if !jsonOutput {
+		fmt.Printf("\nspinup %s - Preflight checks\n\n", Version)
+	}
+
+	opts := []doctor.Option{
+		doctor.WithGlobalConfig(config.GlobalConfigPath()),
+		doctor.WithProfile(profile),
+	}
+	// An unknown profile is reported by the config check
+	if dir, err := config.ResolveStateDir(configOptions()...); err == nil {
+		opts = append(opts, doctor.WithStateDir(dir))
+	}
+
+	report := doctor.New(opts...).Run(ctx)
+
+	if jsonOutput {
+		PrintJSON(buildDoctorOutput(report))
+	} else {
+		printDoctorReport(report)
+	}
+
+	if failed := report.Count(doctor.StatusFail); failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// buildDoctorOutput converts a report to its JSON representation.
+func buildDoctorOutput(report *doctor.Report) DoctorOutput {
+	out := DoctorOutput{
+		Status: string(report.Status()),
+		Checks: make([]DoctorCheckInfo, 0, len(report.Checks)),
+	}
+	for _, c := range report.Checks {
+		out.Checks = append(out.Checks, DoctorCheckInfo{
+			Name:    c.Name,
+			Status:  string(c.Status),
+			Message: c.Message,
+			Hint:    c.Hint,
+		})
+	}
+	return out
+}
+
+// printDoctorReport prints one line per check, with the hints of problems
+// indented below.
+func printDoctorReport(report *doctor.Report) {
+	icons := map[doctor.Status]string{
+		doctor.StatusOK:   "✓",
+		doctor.StatusWarn: "⚠",
+		doctor.StatusFail: "✗",
+		doctor.StatusSkip: "-",
+	}
+
+	for _, c := range report.Checks {
+		fmt.Printf("  %s %-16s %s\n", icons[c.Status], c.Name, c.Message)
+		if c.Hint != "" {
+			fmt.Printf("    %-16s → %s\n", "", c.Hint)
+		}
+	}
+
+	fmt.Printf("\n%d passed, %d warning(s), %d failed",
+		report.Count(doctor.StatusOK), report.Count(doctor.StatusWarn), report.Count(doctor.StatusFail))
+	if skipped := report.Count(doctor.StatusSkip); skipped > 0 {
+		fmt.Printf(", %d skipped", skipped)
+	}
+	fmt.Println()
+	if report.Status() == doctor.StatusOK {
+		fmt.Println("Ready to deploy.")
+	}
+	fmt.Println()
+}
@@ -0,0 +1,270 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/logging"
+)
+
+// gcDryRun tracks if gc --dry-run was requested
+var gcDryRun bool
+
+// gcCmd represents the gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Find and terminate orphaned spinup instances at all providers",
+	Long: `Find and terminate orphaned spinup instances at all providers.
+
+Lists the instances at every configured provider and reports those labelled
+'spinup' that no local session or unfinished deployment owns - for example
+instances left behind by a deleted state file or another machine. Their
+running time and accrued cost are shown, and after confirmation they are
+terminated with the same retries and billing verification as --stop.
+
+Unfinished deployments are not touched; use 'spinup cleanup' for those.`,
+	Run: runGCCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(gcCmd)
+
+	gcCmd.Flags().BoolVarP(&yes, "yes", "y", false, "Terminate without asking for confirmation")
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "Only list orphaned instances, don't terminate them")
+	gcCmd.Flags().StringVar(&output, "output", "text", "Output format: text, json")
+}
+
+func runGCCmd(cmd *cobra.Command, args []string) {
+	if err := RunGC(); err != nil {
+		logging.Error().Err(err).Msg("Garbage collection failed")
+		if !IsJSONOutput() {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// RunGC finds orphaned instances and terminates them after confirmation.
+// In JSON mode nothing is terminated without --yes.
+func RunGC() error {
+	log := logging.Get()
+	jsonOutput := IsJSONOutput()
+
+	fail := func(err error) error {
+		if jsonOutput {
+			PrintJSONError(err)
+		}
+		return err
+	}
+
+	if !jsonOutput {
+		fmt.Printf("\nspinup %s - Looking for orphaned instances\n\n", Version)
+	}
+
+	// Set up context with cancellation on SIGINT/SIGTERM
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigCh
+		if !jsonOutput {
+			fmt.Println("\n\nInterrupted. Run 'spinup gc' again to check for remaining instances.")
+		}
+		cancel()
+	}()
+
+	cfg, warnings, err := loadConfig()
+	if err != nil {
+		return fail(fmt.Errorf("failed to load config: %w", err))
+	}
+	for _, w := range warnings {
+		log.Warn().Msg(w)
+	}
+
+	stateManager, err := newStateManager()
+	if err != nil {
+		return fail(fmt.Errorf("failed to create state manager: %w", err))
+	}
+
+	sweeper, err := deploy.NewSweeper(cfg, stateManager)
+	if err != nil {
+		return fail(fmt.Errorf("failed to create sweeper: %w", err))
+	}
+
+	result, err := sweeper.FindOrphans(ctx)
+	if err != nil {
+		return fail(err)
+	}
+
+	out := GCOutput{
+		Status:     "clean",
+		HourlyCost: result.HourlyCost(),
+		Currency:   "EUR",
+	}
+	for name, perr := range result.ProviderErrors {
+		if out.ProviderErrors == nil {
+			out.ProviderErrors = make(map[string]string)
+		}
+		out.ProviderErrors[name] = perr.Error()
+	}
+	for _, orphan := range result.Orphans {
+		out.Orphans = append(out.Orphans, buildGCOrphanInfo(orphan))
+	}
+
+	if !jsonOutput {
+		printGCProviderErrors(result)
+	}
+
+	if len(result.Orphans) == 0 {
+		if jsonOutput {
+			PrintJSON(out)
+		} else {
+			fmt.Printf("No orphaned instances found (%d providers checked).\n", result.ProvidersChecked)
+		}
+		return nil
+	}
+
+	out.Status = "found"
+	if !jsonOutput {
+		printGCOrphans(result)
+	}
+
+	if gcDryRun || (jsonOutput && !yes) {
+		if jsonOutput {
+			PrintJSON(out)
+		}
+		return nil
+	}
+
+	if !yes && !confirmGC(len(result.Orphans)) {
+		fmt.Println("Aborted, nothing was terminated.")
+		return nil
+	}
+
+	var progressCb func(deploy.StopProgress)
+	var manualVerifCb func(*deploy.ManualVerification)
+	if !jsonOutput {
+		progressCb = stopProgressCallback
+		manualVerifCb = displayManualVerification
+	}
+	stopper, err := deploy.NewStopper(cfg, deploy.DefaultStopConfig(),
+		deploy.WithStopProgressCallback(progressCb),
+		deploy.WithManualVerificationCallback(manualVerifCb),
+	)
+	if err != nil {
+		return fail(fmt.Errorf("failed to create stopper: %w", err))
+	}
+
+	out.Status = "terminated"
+	var failed int
+	for i, orphan := range result.Orphans {
+		if !jsonOutput {
+			fmt.Printf("\nInstance %s (%s):\n\n", orphan.Instance.ID, orphan.Client.Name())
+		}
+
+		info := &out.Orphans[i]
+		stopResult, err := stopper.TerminateOrphan(ctx, orphan)
+		if stopResult != nil {
+			info.BillingVerified = stopResult.BillingVerified
+		}
+		if err != nil && !errors.Is(err, deploy.ErrBillingNotVerified) {
+			failed++
+			out.Status = "error"
+			info.Action = "error"
+			info.Error = err.Error()
+			log.Error().Err(err).Str("provider", orphan.Client.Name()).Str("instance_id", orphan.Instance.ID).Msg("Failed to terminate orphaned instance")
+			if !jsonOutput {
+				fmt.Printf("      ✗ %v\n", err)
+			}
+			continue
+		}
+
+		info.Action = "terminated"
+		if err != nil {
+			info.Error = err.Error()
+		}
+	}
+
+	if jsonOutput {
+		PrintJSON(out)
+	} else {
+		fmt.Println()
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d orphaned instances could not be terminated", failed, len(result.Orphans))
+	}
+	return nil
+}
+
+// buildGCOrphanInfo converts an orphan to its JSON representation.
+func buildGCOrphanInfo(orphan deploy.OrphanedInstance) GCOrphanInfo {
+	info := GCOrphanInfo{
+		Provider:    orphan.Client.Name(),
+		InstanceID:  orphan.Instance.ID,
+		Name:        orphan.Instance.Name,
+		GPU:         orphan.Instance.GPU,
+		Region:      orphan.Instance.Region,
+		Status:      string(orphan.Instance.Status),
+		HourlyRate:  orphan.Instance.HourlyRate,
+		AccruedCost: orphan.AccruedCost,
+		Action:      "listed",
+	}
+	if orphan.Running > 0 {
+		info.Running = formatSessionDuration(orphan.Running)
+	}
+	return info
+}
+
+// printGCProviderErrors warns about providers whose instances could not be listed.
+func printGCProviderErrors(result *deploy.SweepResult) {
+	if len(result.ProviderErrors) == 0 {
+		return
+	}
+	for name, err := range result.ProviderErrors {
+		fmt.Printf("  ⚠ Could not list instances at %s: %v\n", name, err)
+	}
+	fmt.Println()
+}
+
+// printGCOrphans prints the orphaned instances with their running cost.
+func printGCOrphans(result *deploy.SweepResult) {
+	fmt.Printf("Found %d orphaned instance(s):\n\n", len(result.Orphans))
+	fmt.Printf("  %-11s %-14s %-16s %-12s %-9s %-9s %s\n", "PROVIDER", "ID", "GPU", "REGION", "RUNNING", "PRICE", "ACCRUED")
+
+	for _, o := range result.Orphans {
+		running, accrued := "unknown", "unknown"
+		if o.Running > 0 {
+			running = formatSessionDuration(o.Running)
+			accrued = fmt.Sprintf("€%.2f", o.AccruedCost)
+		}
+		fmt.Printf("  %-11s %-14s %-16s %-12s %-9s %-9s %s\n",
+			o.Client.Name(), o.Instance.ID, o.Instance.GPU, o.Instance.Region,
+			running, fmt.Sprintf("€%.2f/hr", o.Instance.HourlyRate), accrued)
+	}
+
+	fmt.Printf("\n  Total: €%.2f/hr\n\n", result.HourlyCost())
+}
+
+// confirmGC asks the user to confirm terminating the orphaned instances.
+func confirmGC(count int) bool {
+	fmt.Printf("Terminate %d instance(s)? [y/N] ", count)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		fmt.Println()
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -1,9 +1,33 @@
 package cli
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
 	"github.com/spf13/cobra"
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/logging"
+	"github.com/tmeurs/spinup/internal/models"
+	"github.com/tmeurs/spinup/internal/provider/registry"
+	"github.com/tmeurs/spinup/internal/ui"
+	"github.com/tmeurs/spinup/internal/wireguard"
+)
+
+// Flags of the non-interactive init
+var (
+	initProviders     []string
+	initAPIKeyStdin   bool
+	initGenerateWGKey bool
+	initDeadman       int
+	initForce         bool
+	initSecretBackend string
 )
 
 // initCmd represents the init command
@@ -18,19 +42,213 @@ This interactive setup wizard will guide you through:
 - Generating WireGuard keys
 - Setting default preferences
 
-The configuration will be saved to a .env file in the current directory.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("spinup init - Configuration wizard")
-		fmt.Println("")
-		fmt.Println("Interactive setup coming soon!")
-		fmt.Println("")
-		fmt.Println("This will help you configure:")
-		fmt.Println("  - Provider API keys (Vast.ai, Lambda Labs, RunPod, etc.)")
-		fmt.Println("  - WireGuard keys for secure tunneling")
-		fmt.Println("  - Default preferences (model tier, region, etc.)")
-	},
+The configuration will be saved to a .env file in the current directory
+with 0600 permissions. An existing .env is only replaced with --force.
+
+With --secret-backend, the API keys and the WireGuard private key are
+stored in a secret backend and the .env file references them, e.g.
+VAST_API_KEY=secret://vast. The file backend encrypts them with a
+passphrase to ~/.config/spinup/secrets.age; the command backend runs
+SECRET_STORE_COMMAND, e.g. "pass insert -m spinup/{name}". The backend
+settings are added to the global config file, as the .env file can't
+select the backend.
+
+With --provider, init runs without prompts, e.g. to provision dev containers
+and CI images. API keys are read from stdin with --api-key-stdin (one line
+per provider, in --provider order) or else from the provider's variable,
+e.g. VAST_API_KEY. Every key is validated before the .env is written:
+
+  echo "$KEY" | spinup init --provider vast --api-key-stdin \
+      --generate-wg-key --tier medium --deadman 10`,
+	Run: runInitCmd,
 }
 
 func init() {
 	rootCmd.AddCommand(initCmd)
+
+	initCmd.Flags().StringSliceVar(&initProviders, "provider", nil, "Providers to configure without prompts (vast, lambda, runpod, coreweave, paperspace)")
+	initCmd.Flags().BoolVar(&initAPIKeyStdin, "api-key-stdin", false, "Read API keys from stdin, one line per --provider")
+	initCmd.Flags().BoolVar(&initGenerateWGKey, "generate-wg-key", false, "Generate a WireGuard key pair (default: a new key pair per deployment)")
+	initCmd.Flags().StringVar(&tier, "tier", "medium", "Default model tier: small, medium, large")
+	initCmd.Flags().IntVar(&initDeadman, "deadman", 10, "Deadman switch timeout in hours (1-168)")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "Overwrite an existing .env file")
+	initCmd.Flags().StringVar(&initSecretBackend, "secret-backend", "", "Store keys in a secret backend instead of .env: file, command")
+}
+
+func runInitCmd(cmd *cobra.Command, args []string) {
+	var err error
+	if len(initProviders) > 0 {
+		err = RunInit()
+	} else {
+		err = runInitWizard(cmd)
+	}
+	if err != nil {
+		logging.Error().Err(err).Msg("Init failed")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runInitWizard runs the interactive setup wizard.
+func runInitWizard(cmd *cobra.Command) error {
+	for _, name := range []string{"api-key-stdin", "generate-wg-key", "tier", "deadman"} {
+		if cmd.Flags().Changed(name) {
+			return fmt.Errorf("--%s requires --provider", name)
+		}
+	}
+	if err := checkEnvFile(); err != nil {
+		return err
+	}
+	// Unlock the secret backend before the wizard takes over the terminal
+	secrets, secretsConfig, err := openInitSecrets()
+	if err != nil {
+		return err
+	}
+	return ui.RunInitWizard(secrets, secretsConfig)
+}
+
+// RunInit writes the .env file without prompts. It validates the API key of
+// every provider in --provider and fails without writing anything if one
+// is rejected.
+func RunInit() error {
+	if _, err := models.ParseTier(tier); err != nil {
+		return err
+	}
+	if initDeadman < 1 || initDeadman > 168 {
+		return fmt.Errorf("--deadman must be 1-168 hours, got %d", initDeadman)
+	}
+	for _, name := range initProviders {
+		if !registry.IsValidProviderName(name) {
+			return fmt.Errorf("unknown provider %q (valid: %s)", name, strings.Join(registry.AllProviderNames(), ", "))
+		}
+	}
+	if err := checkEnvFile(); err != nil {
+		return err
+	}
+
+	keys, err := readInitAPIKeys(os.Stdin)
+	if err != nil {
+		return err
+	}
+	secrets, secretsConfig, err := openInitSecrets()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	for _, name := range initProviders {
+		keyCtx, keyCancel := context.WithTimeout(ctx, ui.APIKeyValidationTimeout)
+		info, err := ui.ValidateAPIKey(keyCtx, registry.NewProviderWithKey, name, keys[name])
+		keyCancel()
+		if err != nil {
+			return fmt.Errorf("%s API key: %w", name, err)
+		}
+
+		msg := fmt.Sprintf("✓ %s: API key valid", name)
+		if info.Email != "" {
+			msg += " (" + info.Email + ")"
+		}
+		fmt.Println(msg)
+	}
+
+	env := &config.EnvFile{
+		APIKeys:             keys,
+		DefaultTier:         strings.ToLower(tier),
+		DeadmanTimeoutHours: initDeadman,
+		Secrets:             secrets,
+		SecretsConfig:       secretsConfig,
+	}
+	if initGenerateWGKey {
+		keyPair, err := wireguard.GenerateKeyPair()
+		if err != nil {
+			return fmt.Errorf("failed to generate WireGuard key pair: %w", err)
+		}
+		env.WireGuardPrivateKey = keyPair.PrivateKey
+		env.WireGuardPublicKey = keyPair.PublicKey
+		fmt.Printf("✓ WireGuard key pair generated (public key %s)\n", keyPair.PublicKey)
+	}
+
+	if err := env.Write(config.DefaultEnvPath, initForce); err != nil {
+		return err
+	}
+	if secrets != nil {
+		fmt.Printf("✓ Keys stored in %s, selected in %s\n", secrets, config.GlobalConfigPath())
+	}
+	fmt.Printf("✓ Configuration saved to %s\n", config.DefaultEnvPath)
+	return nil
+}
+
+// openInitSecrets opens the secret backend selected with --secret-backend,
+// configured by the global config file and the environment. It returns a
+// nil backend without --secret-backend.
+func openInitSecrets() (config.SecretBackend, config.SecretsConfig, error) {
+	if initSecretBackend == "" {
+		return nil, config.SecretsConfig{}, nil
+	}
+
+	// The project .env is about to be replaced, so it doesn't configure the backend
+	opts := append(configOptions(),
+		config.WithProjectConfig(""),
+		config.WithFlag("SECRET_BACKEND", initSecretBackend, "--secret-backend"),
+	)
+	cfg, _, err := config.Load(opts...)
+	if err != nil {
+		return nil, config.SecretsConfig{}, fmt.Errorf("failed to load config: %w", err)
+	}
+	if strings.EqualFold(cfg.Secrets.Backend, config.SecretBackendEnv) {
+		return nil, config.SecretsConfig{}, fmt.Errorf("the env secret backend is read-only: set %s<NAME> variables and reference them as secret://<name>", config.SecretEnvPrefix)
+	}
+
+	secrets, err := cfg.Secrets.Open(promptPassphrase)
+	if err != nil {
+		return nil, config.SecretsConfig{}, fmt.Errorf("failed to open secret backend: %w", err)
+	}
+	return secrets, cfg.Secrets, nil
+}
+
+// checkEnvFile returns an error if a .env file exists and --force wasn't given.
+func checkEnvFile() error {
+	if initForce {
+		return nil
+	}
+	if _, err := os.Stat(config.DefaultEnvPath); err == nil {
+		return fmt.Errorf("%w: %s (use --force to overwrite it)", config.ErrConfigFileExists, config.DefaultEnvPath)
+	}
+	return nil
+}
+
+// readInitAPIKeys returns the API key of each provider in --provider, read
+// from r with --api-key-stdin and from the environment otherwise.
+func readInitAPIKeys(r io.Reader) (map[string]string, error) {
+	keys := make(map[string]string, len(initProviders))
+
+	if !initAPIKeyStdin {
+		for _, name := range initProviders {
+			envVar := registry.GetProviderAPIKeyEnvVar(name)
+			key := strings.TrimSpace(os.Getenv(envVar))
+			if key == "" {
+				return nil, fmt.Errorf("no API key for %s: set %s or pass --api-key-stdin", name, envVar)
+			}
+			keys[name] = key
+		}
+		return keys, nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for _, name := range initProviders {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, fmt.Errorf("failed to read API keys from stdin: %w", err)
+			}
+			return nil, errors.New("stdin has fewer API keys than --provider lists providers")
+		}
+		key := strings.TrimSpace(scanner.Text())
+		if key == "" {
+			return nil, fmt.Errorf("empty API key for %s on stdin", name)
+		}
+		keys[name] = key
+	}
+	return keys, nil
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/tmeurs/spinup/internal/deploy"
 	"github.com/tmeurs/spinup/internal/logging"
 	"github.com/tmeurs/spinup/internal/models"
+	"github.com/tmeurs/spinup/internal/offerexpr"
 	providerPkg "github.com/tmeurs/spinup/internal/provider"
 	"github.com/tmeurs/spinup/internal/provider/registry"
 	"github.com/tmeurs/spinup/internal/ui"
@@ -49,9 +50,16 @@ func NewInteractiveModel(cfg *config.Config, stateManager *config.StateManager,
 		cfg:          cfg,
 		stateManager: stateManager,
 		deployCfg:    deployCfg,
+		// Init fetches offers
+		fetchingOffers: true,
 	}
-	// Start in provider select view
+	// Start in provider select view, filtered by --filter or OFFER_FILTER
 	m.SetView(ui.ViewProviderSelect)
+	if deployCfg != nil && deployCfg.Filter != nil {
+		m.SetOfferFilter(deployCfg.Filter)
+	} else if cfg != nil {
+		m.SetOfferFilter(cfg.DefaultFilter)
+	}
 	return m
 }
 
@@ -64,6 +72,7 @@ func (m *InteractiveModel) SetProgram(p *tea.Program) {
 func (m InteractiveModel) Init() tea.Cmd {
 	return tea.Batch(
 		m.Model.Init(),
+		m.loadCachedOffersCmd(),
 		m.fetchOffersCmd(),
 	)
 }
@@ -83,6 +92,15 @@ func (m InteractiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, cmd
 
 	case ui.OffersLoadedMsg:
+		if msg.Stale {
+			// Cached offers are only useful until the fetch completes
+			if !m.fetchingOffers {
+				return m, nil
+			}
+			baseModel, cmd := m.Model.Update(msg)
+			m.Model = baseModel.(ui.Model)
+			return m, cmd
+		}
 		// Forward to base model
 		m.fetchingOffers = false
 		m.lastFetchError = nil
@@ -169,6 +187,13 @@ func (m InteractiveModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 // handleKeyPress processes keyboard input for interactive mode.
 func (m InteractiveModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// The offer filter search box takes all keys
+	if m.IsSearching() {
+		baseModel, cmd := m.Model.Update(msg)
+		m.Model = baseModel.(ui.Model)
+		return m, cmd
+	}
+
 	switch msg.String() {
 	case "q", "ctrl+c":
 		return m, tea.Quit
@@ -202,7 +227,7 @@ func (m InteractiveModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "?":
 		// Show help
-		m.SetStatusMessage("Keys: q=quit, esc=back, ↑↓=navigate, Enter=select, r=refresh")
+		m.SetStatusMessage("Keys: q=quit, esc=back, ↑↓=navigate, Enter=select, /=filter, r=refresh")
 		return m, nil
 	}
 
@@ -238,43 +263,104 @@ func (m InteractiveModel) fetchOffersCmd() tea.Cmd {
 			return ui.OffersLoadErrorMsg{Err: fmt.Errorf("no providers configured: %w", err)}
 		}
 
-		// Build filter based on deploy config
-		filter := providerPkg.OfferFilter{}
-		if m.deployCfg != nil {
-			if m.deployCfg.GPUType != "" {
-				filter.GPUType = m.deployCfg.GPUType
-			}
-			if m.deployCfg.Region != "" {
-				filter.Region = m.deployCfg.Region
-			}
-			if !m.deployCfg.PreferSpot {
-				filter.OnDemandOnly = true
-			}
-		}
+		filter := m.offerFilter()
 
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
+		fetched := deploy.FetchOffers(ctx, providers, filter, deploy.DefaultProviderFetchTimeout)
+		reports := deploy.FetchReports(fetched)
+		m.storeOffers(fetched, filter)
+
 		var allOffers []providerPkg.Offer
-		for _, p := range providers {
-			offers, err := p.GetOffers(ctx, filter)
-			if err != nil {
-				log.Warn().Err(err).Str("provider", p.Name()).Msg("Failed to fetch offers")
-				continue
-			}
-			for _, o := range offers {
-				if o.Available {
-					allOffers = append(allOffers, o)
-				}
-			}
+		for _, f := range fetched {
+			allOffers = append(allOffers, f.Offers...)
 		}
 
 		if len(allOffers) == 0 {
-			return ui.OffersLoadErrorMsg{Err: fmt.Errorf("no offers available from any provider")}
+			return ui.OffersLoadErrorMsg{Err: fmt.Errorf("no offers available from any provider"), Reports: reports}
 		}
 
+		preferSpot := m.deployCfg == nil || m.deployCfg.PreferSpot
+		scores := deploy.ScoreOffers(deploy.NewDefaultOfferScorer(m.cfg, m.stateManager, preferSpot), allOffers)
+
 		log.Info().Int("count", len(allOffers)).Msg("Loaded offers")
-		return ui.OffersLoadedMsg{Offers: allOffers}
+		return ui.OffersLoadedMsg{Offers: allOffers, Reports: reports, Scores: scores}
+	}
+}
+
+// loadCachedOffersCmd creates a command that loads offers from the offer
+// cache, so they can be shown while fetchOffersCmd is running.
+// It yields no message if nothing is cached.
+func (m InteractiveModel) loadCachedOffersCmd() tea.Cmd {
+	return func() tea.Msg {
+		if m.stateManager == nil {
+			return nil
+		}
+
+		providers, err := registry.GetConfiguredProviders(m.cfg)
+		if err != nil {
+			return nil
+		}
+
+		cache, err := deploy.LoadOfferCache(m.stateManager, m.cfg.OfferCacheTTL)
+		if err != nil {
+			logging.Get().Debug().Err(err).Msg("Ignoring offer cache")
+			return nil
+		}
+
+		cached := cache.CachedOffers(providers, m.offerFilter())
+		var allOffers []providerPkg.Offer
+		for _, c := range cached {
+			allOffers = append(allOffers, c.Offers...)
+		}
+		if len(allOffers) == 0 {
+			return nil
+		}
+
+		preferSpot := m.deployCfg == nil || m.deployCfg.PreferSpot
+		scores := deploy.ScoreOffers(deploy.NewDefaultOfferScorer(m.cfg, m.stateManager, preferSpot), allOffers)
+
+		return ui.OffersLoadedMsg{
+			Offers:    allOffers,
+			Reports:   deploy.FetchReports(cached),
+			Scores:    scores,
+			Stale:     true,
+			FetchedAt: deploy.OldestFetch(cached),
+		}
+	}
+}
+
+// offerFilter returns the offer filter for the deploy config.
+func (m InteractiveModel) offerFilter() providerPkg.OfferFilter {
+	filter := providerPkg.OfferFilter{}
+	if m.deployCfg != nil {
+		if m.deployCfg.GPUType != "" {
+			filter.GPUType = m.deployCfg.GPUType
+		}
+		if m.deployCfg.Region != "" {
+			filter.Region = m.deployCfg.Region
+		}
+		if !m.deployCfg.PreferSpot {
+			filter.OnDemandOnly = true
+		}
+	}
+	return filter
+}
+
+// storeOffers saves fetched offers to the offer cache for the next launch.
+func (m InteractiveModel) storeOffers(fetched []deploy.ProviderOffers, filter providerPkg.OfferFilter) {
+	if m.stateManager == nil {
+		return
+	}
+
+	cache, err := deploy.LoadOfferCache(m.stateManager, m.cfg.OfferCacheTTL)
+	if err != nil {
+		logging.Get().Debug().Err(err).Msg("Starting a new offer cache")
+	}
+	cache.Store(fetched, filter)
+	if err := cache.Save(); err != nil {
+		logging.Get().Warn().Err(err).Msg("Failed to save offer cache")
 	}
 }
 
@@ -295,6 +381,12 @@ func (m InteractiveModel) startDeploymentCmd() tea.Cmd {
 			deployCfg.PreferSpot = m.deployCfg.PreferSpot
 			deployCfg.DeadmanTimeoutHours = m.deployCfg.DeadmanTimeoutHours
 		}
+		// Deploy with the filter the offer was picked under; a cleared
+		// filter must not fall back to OFFER_FILTER
+		deployCfg.Filter = m.GetOfferFilter()
+		if deployCfg.Filter == nil {
+			deployCfg.Filter = offerexpr.MatchAll()
+		}
 
 		return deployStartMsg{deployCfg: deployCfg}
 	}
@@ -337,6 +429,9 @@ func (m InteractiveModel) runDeploymentCmd(deployCfg *deploy.DeployConfig) tea.C
 			Dur("duration", result.Duration()).
 			Msg("Deployment complete")
 
+		// Hand the session over to the background supervisor
+		startSupervisorDaemon(m.stateManager)
+
 		// Convert to UI result
 		uiResult := ui.ResultFromDeployResult(result, deployCfg.DeadmanTimeoutHours)
 		return ui.DeployProgressCompleteMsg{Result: uiResult}
@@ -372,7 +467,7 @@ func CheckAndRunInteractive() (bool, error) {
 	log := logging.Get()
 
 	// Load configuration
-	cfg, warnings, err := config.LoadConfig("")
+	cfg, warnings, err := loadConfig()
 	if err != nil {
 		return false, fmt.Errorf("failed to load config: %w", err)
 	}
@@ -388,7 +483,7 @@ func CheckAndRunInteractive() (bool, error) {
 	}
 
 	// Create state manager
-	stateManager, err := config.NewStateManager("")
+	stateManager, err := newSessionStateManager()
 	if err != nil {
 		return false, fmt.Errorf("failed to create state manager: %w", err)
 	}
@@ -407,6 +502,11 @@ func CheckAndRunInteractive() (bool, error) {
 		return true, err
 	}
 
+	// Refuse to start over an unfinished deployment's instance
+	if err := checkUnfinishedDeploy(stateManager); err != nil {
+		return false, err
+	}
+
 	// No active instance - start interactive mode for deployment
 	deployCfg := deploy.DefaultDeployConfig()
 
@@ -428,6 +528,10 @@ func CheckAndRunInteractive() (bool, error) {
 	} else {
 		deployCfg.PreferSpot = spot
 	}
+	deployCfg.Filter, err = offerexpr.Parse(filterExpr)
+	if err != nil {
+		return false, fmt.Errorf("invalid --filter: %w", err)
+	}
 
 	err = RunInteractiveMode(cfg, stateManager, deployCfg)
 	return true, err
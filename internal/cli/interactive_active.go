@@ -309,6 +309,11 @@ func (m ActiveInstanceModel) runStopCmd() tea.Cmd {
 			return ui.StopProgressErrorMsg{Err: err}
 		}
 
+		// Stop the supervisor first so it no longer refreshes the deadman or writes state
+		if err := stopDaemon(m.stateManager); err != nil {
+			log.Warn().Err(err).Msg("Failed to stop supervisor daemon")
+		}
+
 		// Run stop process
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 		defer cancel()
@@ -346,6 +351,9 @@ func (m ActiveInstanceModel) runTestCmd() tea.Cmd {
 		// Set up verification options to also check Ollama
 		opts := wireguard.DefaultVerifyOptions()
 		opts.ServerIP = serverIP
+		if m.state != nil && m.state.WireGuard != nil && m.state.WireGuard.InterfaceName != "" {
+			opts.InterfaceName = m.state.WireGuard.InterfaceName
+		}
 		opts.CheckOllama = true
 		opts.Timeout = 10 * time.Second
 
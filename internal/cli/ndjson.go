@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/deploy"
+)
+
+// NDJSONSchemaVersion is the version of the --output=ndjson event schema.
+// It changes only when fields are removed or change meaning.
+const NDJSONSchemaVersion = 1
+
+// Event types of --output=ndjson.
+const (
+	EventTypeProgress  = "progress"  // a deploy or stop step started, completed or failed
+	EventTypeWarning   = "warning"   // something needs attention but didn't fail the command
+	EventTypeReconcile = "reconcile" // local state was checked against the provider
+	EventTypeStatus    = "status"    // session status, sent by status --watch
+	EventTypeResult    = "result"    // final summary, the --output=json document
+	EventTypeError     = "error"     // the command failed
+)
+
+// NDJSONEvent is one line of --output=ndjson. The payload field matching
+// Type is set; Result holds a DeployOutput or StopOutput.
+type NDJSONEvent struct {
+	Schema    int             `json:"schema"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"` // "deploy", "stop", "status"
+	Session   string          `json:"session,omitempty"`
+	Time      time.Time       `json:"time"`
+	Progress  *ProgressOutput `json:"progress,omitempty"`
+	Warning   *WarningInfo    `json:"warning,omitempty"`
+	Reconcile *ReconcileInfo  `json:"reconcile,omitempty"`
+	Status    *StatusOutput   `json:"status,omitempty"`
+	Result    any             `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// WarningInfo contains a warning for output.
+type WarningInfo struct {
+	Message    string `json:"message"`
+	Detail     string `json:"detail,omitempty"`
+	ConsoleURL string `json:"console_url,omitempty"`
+}
+
+// ReconcileInfo contains the result of checking local state against the
+// provider for output.
+type ReconcileInfo struct {
+	StateValid     bool   `json:"state_valid"`
+	StateCleaned   bool   `json:"state_cleaned"`
+	InstanceStatus string `json:"instance_status,omitempty"`
+	Warning        string `json:"warning,omitempty"`
+	Details        string `json:"details,omitempty"`
+}
+
+// eventWriter writes --output=ndjson events. A nil *eventWriter discards
+// events, so commands can call it unconditionally.
+type eventWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	command string
+	session string
+	now     func() time.Time
+}
+
+// newEventWriter returns an eventWriter for command if --output=ndjson is
+// set, nil otherwise.
+func newEventWriter(command string) *eventWriter {
+	if !IsNDJSONOutput() {
+		return nil
+	}
+	return openEventWriter(command)
+}
+
+// openEventWriter returns an eventWriter for command writing to stdout,
+// for commands with their own --output flag.
+func openEventWriter(command string) *eventWriter {
+	return &eventWriter{
+		w:       os.Stdout,
+		command: command,
+		session: selectedSessionName(),
+		now:     time.Now,
+	}
+}
+
+// selectedSessionName returns the session selected with --session.
+func selectedSessionName() string {
+	if session == "" {
+		return config.DefaultSessionName
+	}
+	return session
+}
+
+// write writes one event as a line of JSON.
+func (e *eventWriter) write(ev NDJSONEvent) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	ev.Schema = NDJSONSchemaVersion
+	ev.Command = e.command
+	if ev.Session == "" {
+		ev.Session = e.session
+	}
+	ev.Time = e.now().UTC()
+	data, err := json.Marshal(ev)
+	if err != nil {
+		data, _ = json.Marshal(NDJSONEvent{
+			Schema:  NDJSONSchemaVersion,
+			Type:    EventTypeError,
+			Command: e.command,
+			Session: e.session,
+			Time:    ev.Time,
+			Error:   "failed to marshal event: " + err.Error(),
+		})
+	}
+	e.w.Write(append(data, '\n'))
+}
+
+// deployProgress writes a deployment progress event.
+func (e *eventWriter) deployProgress(p deploy.DeployProgress) {
+	if e == nil {
+		return
+	}
+	progress := buildDeployProgressOutput(e.session, p)
+	e.write(NDJSONEvent{Type: EventTypeProgress, Progress: &progress})
+}
+
+// stopProgress writes a stop progress event.
+func (e *eventWriter) stopProgress(p deploy.StopProgress) {
+	if e == nil {
+		return
+	}
+	progress := buildStopProgressOutput(e.session, p)
+	e.write(NDJSONEvent{Type: EventTypeProgress, Progress: &progress})
+}
+
+// warning writes a warning event.
+func (e *eventWriter) warning(info WarningInfo) {
+	e.write(NDJSONEvent{Type: EventTypeWarning, Warning: &info})
+}
+
+// manualVerification writes a warning event for a stop whose billing has
+// to be verified in the provider's console.
+func (e *eventWriter) manualVerification(mv *deploy.ManualVerification) {
+	if mv == nil || !mv.Required {
+		return
+	}
+	e.warning(WarningInfo{
+		Message:    mv.WarningMessage,
+		Detail:     "instance " + mv.InstanceID + " at " + mv.Provider,
+		ConsoleURL: mv.ConsoleURL,
+	})
+}
+
+// reconcile writes a reconcile event.
+func (e *eventWriter) reconcile(r *deploy.ReconcileResult) {
+	if r == nil {
+		return
+	}
+	e.write(NDJSONEvent{Type: EventTypeReconcile, Reconcile: &ReconcileInfo{
+		StateValid:     r.StateValid,
+		StateCleaned:   r.StateCleaned,
+		InstanceStatus: string(r.InstanceStatus),
+		Warning:        r.Warning,
+		Details:        r.Details,
+	}})
+}
+
+// status writes a status event for the session s describes.
+func (e *eventWriter) status(s StatusOutput) {
+	e.write(NDJSONEvent{Type: EventTypeStatus, Session: s.Session, Status: &s})
+}
+
+// result writes the final summary event.
+func (e *eventWriter) result(v any) {
+	e.write(NDJSONEvent{Type: EventTypeResult, Result: v})
+}
+
+// failure writes an error event.
+func (e *eventWriter) failure(err error) {
+	e.write(NDJSONEvent{Type: EventTypeError, Error: err.Error()})
+}
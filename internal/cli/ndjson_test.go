@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/fakecloud"
+	"github.com/tmeurs/spinup/internal/models"
+	providerPkg "github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/mock"
+	"github.com/tmeurs/spinup/internal/provider/registry"
+)
+
+// eventFields are the top-level fields of an NDJSON event.
+var eventFields = []string{"schema", "type", "command", "session", "time", "progress", "warning", "reconcile", "status", "result", "error"}
+
+// progressFields are the fields of a progress payload.
+var progressFields = []string{"operation", "session", "step", "total_steps", "step_name", "message", "detail", "completed", "warning", "attempt", "error"}
+
+// payloadField is the field that holds each event type's payload.
+var payloadField = map[string]string{
+	EventTypeProgress:  "progress",
+	EventTypeWarning:   "warning",
+	EventTypeReconcile: "reconcile",
+	EventTypeStatus:    "status",
+	EventTypeResult:    "result",
+	EventTypeError:     "error",
+}
+
+// parseEvents checks that stream holds one JSON object per line and
+// returns the objects.
+func parseEvents(t *testing.T, stream []byte) []map[string]json.RawMessage {
+	t.Helper()
+
+	var events []map[string]json.RawMessage
+	s := bufio.NewScanner(bytes.NewReader(stream))
+	for s.Scan() {
+		line := s.Bytes()
+		var ev map[string]json.RawMessage
+		if err := json.Unmarshal(line, &ev); err != nil {
+			t.Fatalf("line %d is not a JSON object: %v\n%s", len(events)+1, err, line)
+		}
+		events = append(events, ev)
+	}
+	if len(events) == 0 {
+		t.Fatal("no events written")
+	}
+	if !bytes.HasSuffix(stream, []byte("\n")) {
+		t.Error("stream does not end with a newline")
+	}
+	return events
+}
+
+// checkFields reports fields of obj that are not in allowed.
+func checkFields(t *testing.T, what string, obj map[string]json.RawMessage, allowed []string) {
+	t.Helper()
+	for name := range obj {
+		found := false
+		for _, a := range allowed {
+			if name == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("%s has unknown field %q", what, name)
+		}
+	}
+}
+
+// checkEvent checks the fields every event of command must have.
+func checkEvent(t *testing.T, i int, ev map[string]json.RawMessage, command string) string {
+	t.Helper()
+
+	var head struct {
+		Schema  int       `json:"schema"`
+		Type    string    `json:"type"`
+		Command string    `json:"command"`
+		Session string    `json:"session"`
+		Time    time.Time `json:"time"`
+	}
+	raw, _ := json.Marshal(ev)
+	if err := json.Unmarshal(raw, &head); err != nil {
+		t.Fatalf("event %d: %v", i, err)
+	}
+
+	checkFields(t, "event", ev, eventFields)
+	if head.Schema != NDJSONSchemaVersion || head.Command != command || head.Session != config.DefaultSessionName || head.Time.IsZero() {
+		t.Errorf("event %d = schema %d, command %q, session %q, time %v", i, head.Schema, head.Command, head.Session, head.Time)
+	}
+
+	payload, ok := payloadField[head.Type]
+	if !ok {
+		t.Fatalf("event %d has unknown type %q", i, head.Type)
+	}
+	if _, ok := ev[payload]; !ok {
+		t.Errorf("%s event %d has no %q field", head.Type, i, payload)
+	}
+	for _, field := range payloadField {
+		if _, ok := ev[field]; ok && field != payload && head.Type != EventTypeStatus {
+			t.Errorf("%s event %d also has %q", head.Type, i, field)
+		}
+	}
+	return head.Type
+}
+
+// captureStdout returns what fn writes to stdout.
+func captureStdout(t *testing.T, fn func()) []byte {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- data
+	}()
+
+	fn()
+	w.Close()
+	return <-out
+}
+
+// useNDJSONOutput runs the CLI in a clean environment with
+// --output=ndjson and the given --max-attempts.
+func useNDJSONOutput(t *testing.T, attempts int) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home+"/.config")
+	t.Setenv("XDG_STATE_HOME", home+"/.local/state")
+	t.Setenv("SPINUP_PROFILE", "")
+	t.Chdir(t.TempDir())
+
+	prevOutput, prevAttempts, prevSession := output, maxAttempts, session
+	output, maxAttempts, session = string(OutputFormatNDJSON), attempts, ""
+	t.Cleanup(func() { output, maxAttempts, session = prevOutput, prevAttempts, prevSession })
+}
+
+func TestRunCheapestDeploy_NDJSON(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs a deployment against the fake cloud")
+	}
+	useNDJSONOutput(t, 1)
+
+	name := registry.ProviderLambda
+	fc := fakecloud.NewTestServer(t)
+	fc.SetLifecycle(name, fakecloud.Lifecycle{FailBoot: true})
+	t.Setenv("LAMBDA_API_KEY", "fakecloud")
+	t.Setenv("LAMBDA_API_URL", fc.BaseURL(name))
+
+	// The instance fails to boot, so the deployment ends before it needs a
+	// tunnel
+	var runErr error
+	stream := captureStdout(t, func() {
+		runErr = RunCheapestDeploy("qwen2.5-coder:7b", name, "", "", "", false, "10h")
+	})
+	if runErr == nil {
+		t.Fatal("RunCheapestDeploy() error = nil, want the boot failure")
+	}
+
+	events := parseEvents(t, stream)
+	var types []string
+	for i, ev := range events {
+		types = append(types, checkEvent(t, i, ev, "deploy"))
+	}
+
+	// Progress of every step up to the failed boot, then one terminal event
+	last := len(events) - 1
+	if types[last] != EventTypeError {
+		t.Fatalf("last event type = %q, want %q; types %v", types[last], EventTypeError, types)
+	}
+	var errMsg string
+	json.Unmarshal(events[last]["error"], &errMsg)
+	if errMsg != runErr.Error() {
+		t.Errorf("error event = %q, want %q", errMsg, runErr.Error())
+	}
+
+	steps := map[int]bool{}
+	for i, typ := range types[:last] {
+		switch typ {
+		case EventTypeResult, EventTypeError:
+			t.Errorf("terminal event %q at line %d of %d", typ, i+1, len(events))
+		case EventTypeProgress:
+			var progress map[string]json.RawMessage
+			json.Unmarshal(events[i]["progress"], &progress)
+			checkFields(t, "progress", progress, progressFields)
+
+			var p ProgressOutput
+			json.Unmarshal(events[i]["progress"], &p)
+			if p.Operation != "deploy" || p.Step < 1 || p.Step > p.TotalSteps || p.StepName == "" || p.Message == "" {
+				t.Errorf("progress event %d = %+v", i, p)
+			}
+			steps[p.Step] = true
+		}
+	}
+	for _, step := range []deploy.DeployStep{deploy.StepFetchPrices, deploy.StepSelectOffer, deploy.StepCreateInstance, deploy.StepWaitBoot} {
+		if !steps[int(step)] {
+			t.Errorf("no progress event for step %d (%s), got steps %v", step, step, steps)
+		}
+	}
+}
+
+func TestEventWriter_Result(t *testing.T) {
+	useNDJSONOutput(t, 1)
+
+	var buf bytes.Buffer
+	events := &eventWriter{
+		w:       &buf,
+		command: "deploy",
+		session: config.DefaultSessionName,
+		now:     func() time.Time { return time.Date(2024, 1, 1, 8, 0, 0, 0, time.FixedZone("CET", 3600)) },
+	}
+
+	model, err := models.GetModelByName("qwen2.5-coder:7b")
+	if err != nil {
+		t.Fatalf("GetModelByName() error = %v", err)
+	}
+	result := &deploy.DeployResult{
+		Provider:      mock.New(mock.WithName("vast")),
+		SelectedOffer: &providerPkg.Offer{OfferID: "offer-1", Provider: "vast", GPU: "A100", VRAM: 80, Region: "EU-West", OnDemandPrice: 1.5},
+		Instance:      &providerPkg.Instance{ID: "inst-1", Provider: "vast", GPU: "A100", Region: "EU-West"},
+		Model:         model,
+	}
+	events.deployProgress(deploy.DeployProgress{Step: deploy.StepFetchPrices, TotalSteps: 8, Message: "Fetching prices", Completed: true})
+	events.warning(WarningInfo{Message: "offer cache is stale"})
+	events.result(buildDeployOutput(result, 10, config.DefaultSessionName))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("wrote %d lines, want 3:\n%s", len(lines), buf.String())
+	}
+	parsed := parseEvents(t, buf.Bytes())
+	var types []string
+	for i, ev := range parsed {
+		types = append(types, checkEvent(t, i, ev, "deploy"))
+	}
+	if strings.Join(types, ",") != "progress,warning,result" {
+		t.Errorf("event types = %v, want progress, warning, result", types)
+	}
+
+	// Times are written in UTC
+	var first struct {
+		Time string `json:"time"`
+	}
+	json.Unmarshal([]byte(lines[0]), &first)
+	if first.Time != "2024-01-01T07:00:00Z" {
+		t.Errorf("time = %q, want 2024-01-01T07:00:00Z", first.Time)
+	}
+
+	// The result is the --output=json document
+	var got map[string]json.RawMessage
+	json.Unmarshal(parsed[2]["result"], &got)
+	var fields []string
+	for name := range got {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	for _, want := range []string{"cost", "deadman", "instance", "model", "session", "status"} {
+		if _, ok := got[want]; !ok {
+			t.Errorf("result has no %q field, got %v", want, fields)
+		}
+	}
+	var status string
+	json.Unmarshal(got["status"], &status)
+	if status != "ready" {
+		t.Errorf("result status = %q, want ready", status)
+	}
+}
@@ -0,0 +1,294 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/logging"
+	"github.com/tmeurs/spinup/internal/models"
+	providerPkg "github.com/tmeurs/spinup/internal/provider"
+)
+
+// Flags of the offers command
+var (
+	offersProvider string
+	offersGPU      string
+	offersMinVRAM  int
+	offersRegion   string
+	offersSpot     bool
+	offersMaxPrice float64
+	offersModel    string
+	offersFilter   string
+	offersSort     string
+	offersDesc     bool
+)
+
+// offerSortKeys are the values of offers --sort.
+var offerSortKeys = []string{"price", "on-demand", "spot", "vram", "gpu", "provider", "region"}
+
+// offersCmd represents the offers command
+var offersCmd = &cobra.Command{
+	Use:   "offers",
+	Short: "List GPU offers and prices at all providers",
+	Long: `List the GPU offers of every configured provider without deploying.
+
+Offers are fetched from all providers in parallel and can be narrowed down
+with --gpu, --min-vram, --region, --spot and --max-price. --model sets the
+minimum VRAM to what the model needs. --filter takes an expression over
+the offer fields (provider, gpu, region, vram, price, spot, on_demand,
+reliability, has_spot) and replaces OFFER_FILTER from the config.
+
+Offers are sorted by their lowest hourly price (spot if available, on-demand
+otherwise); use --sort to sort by another column and --desc to reverse.
+
+Use --output=json or --output=csv for scripts.`,
+	Example: `  spinup offers --model qwen2.5-coder:32b
+  spinup offers --gpu A100 --region eu-west --spot --sort spot
+  spinup offers --min-vram 80 --output csv > offers.csv
+  spinup offers --filter 'vram>=48 && (spot<0.9 || on_demand<1.4)'`,
+	Run: runOffersCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(offersCmd)
+
+	offersCmd.Flags().StringVar(&offersProvider, "provider", "", "Only list offers of this provider")
+	offersCmd.Flags().StringVar(&offersGPU, "gpu", "", "GPU type (e.g. A100, H100)")
+	offersCmd.Flags().IntVar(&offersMinVRAM, "min-vram", 0, "Minimum GPU memory in GB")
+	offersCmd.Flags().StringVar(&offersRegion, "region", "", "Region (eu-west, us-east, etc.)")
+	offersCmd.Flags().BoolVar(&offersSpot, "spot", false, "Only list offers with spot pricing")
+	offersCmd.Flags().Float64Var(&offersMaxPrice, "max-price", 0, "Maximum hourly price in EUR")
+	offersCmd.Flags().StringVar(&offersModel, "model", "", "Only list offers with enough VRAM for this model")
+	offersCmd.Flags().StringVar(&offersFilter, "filter", "", "Offer filter expression, e.g. 'vram>=48 && provider!=paperspace' (default OFFER_FILTER)")
+	offersCmd.Flags().StringVar(&offersSort, "sort", "price", "Sort by: "+strings.Join(offerSortKeys, ", "))
+	offersCmd.Flags().BoolVar(&offersDesc, "desc", false, "Sort in descending order")
+	offersCmd.Flags().StringVar(&output, "output", "text", "Output format: text, json, csv")
+}
+
+func runOffersCmd(cmd *cobra.Command, args []string) {
+	if err := RunOffers(); err != nil {
+		logging.Error().Err(err).Msg("Listing offers failed")
+		if !IsJSONOutput() {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// RunOffers fetches the offers matching the offers flags from all
+// configured providers and prints them.
+func RunOffers() error {
+	log := logging.Get()
+	jsonOutput := IsJSONOutput()
+
+	fail := func(err error) error {
+		if jsonOutput {
+			PrintJSONError(err)
+		}
+		return err
+	}
+
+	if output != "text" && output != "json" && output != "csv" {
+		return fail(fmt.Errorf("invalid --output %q: must be text, json or csv", output))
+	}
+	less, err := offerLess(offersSort)
+	if err != nil {
+		return fail(err)
+	}
+
+	filter := providerPkg.OfferFilter{
+		GPUType:        offersGPU,
+		MinVRAM:        offersMinVRAM,
+		Region:         offersRegion,
+		SpotOnly:       offersSpot,
+		MaxHourlyPrice: offersMaxPrice,
+	}
+	filter.MinVRAM, err = modelMinVRAM(offersModel, filter.MinVRAM)
+	if err != nil {
+		return fail(err)
+	}
+
+	// Set up context with cancellation on SIGINT/SIGTERM
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	cfg, warnings, err := loadConfig()
+	if err != nil {
+		return fail(fmt.Errorf("failed to load config: %w", err))
+	}
+	for _, w := range warnings {
+		log.Warn().Msg(w)
+	}
+
+	expr, err := resolveOfferFilter(cfg, offersFilter)
+	if err != nil {
+		return fail(err)
+	}
+
+	providers, err := offerProviders(cfg, offersProvider)
+	if err != nil {
+		return fail(err)
+	}
+
+	stateManager, err := newStateManager()
+	if err != nil {
+		return fail(fmt.Errorf("failed to create state manager: %w", err))
+	}
+	cache, err := deploy.LoadOfferCache(stateManager, cfg.OfferCacheTTL)
+	if err != nil {
+		log.Warn().Err(err).Msg("Ignoring offer cache")
+	}
+
+	results := cache.FetchOffers(ctx, providers, filter, deploy.DefaultProviderFetchTimeout)
+	out := buildOffersOutput(results, expr)
+	sort.SliceStable(out.Offers, func(i, j int) bool {
+		if offersDesc {
+			return less(out.Offers[j], out.Offers[i])
+		}
+		return less(out.Offers[i], out.Offers[j])
+	})
+
+	switch output {
+	case "json":
+		PrintJSON(out)
+	case "csv":
+		printOfferFetchErrors(os.Stderr, out.Providers)
+		if err := writeOffersCSV(os.Stdout, out.Offers); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+	default:
+		printOffersText(out)
+	}
+
+	// Some failing providers still give a usable list, none doesn't
+	if allFetchesFailed(out.Providers) {
+		return fmt.Errorf("no provider returned offers (%s)", deploy.SummarizeFetchErrors(deploy.FetchReports(results)))
+	}
+	return nil
+}
+
+// modelMinVRAM returns the minimum VRAM in GB of offers that can run
+// modelName, which is at least minVRAM. Without a model it returns minVRAM.
+func modelMinVRAM(modelName string, minVRAM int) (int, error) {
+	if modelName == "" {
+		return minVRAM, nil
+	}
+	m, err := models.GetModelByName(modelName)
+	if err != nil {
+		return 0, err
+	}
+	return max(minVRAM, m.VRAM), nil
+}
+
+// offerLess returns the ordering of offers for a --sort key.
+func offerLess(key string) (func(a, b OfferInfo) bool, error) {
+	switch key {
+	case "price":
+		return func(a, b OfferInfo) bool { return lowestOfferPrice(a) < lowestOfferPrice(b) }, nil
+	case "on-demand":
+		return func(a, b OfferInfo) bool { return a.OnDemandPrice < b.OnDemandPrice }, nil
+	case "spot":
+		// Offers without spot pricing sort last
+		return func(a, b OfferInfo) bool {
+			if a.SpotPrice == nil || b.SpotPrice == nil {
+				return a.SpotPrice != nil && b.SpotPrice == nil
+			}
+			return *a.SpotPrice < *b.SpotPrice
+		}, nil
+	case "vram":
+		return func(a, b OfferInfo) bool { return a.VRAM < b.VRAM }, nil
+	case "gpu":
+		return func(a, b OfferInfo) bool { return a.GPU < b.GPU }, nil
+	case "provider":
+		return func(a, b OfferInfo) bool { return a.Provider < b.Provider }, nil
+	case "region":
+		return func(a, b OfferInfo) bool { return a.Region < b.Region }, nil
+	}
+	return nil, fmt.Errorf("invalid --sort %q: must be one of %s", key, strings.Join(offerSortKeys, ", "))
+}
+
+// lowestOfferPrice returns the spot price of an offer if it has one,
+// the on-demand price otherwise.
+func lowestOfferPrice(o OfferInfo) float64 {
+	if o.SpotPrice != nil {
+		return *o.SpotPrice
+	}
+	return o.OnDemandPrice
+}
+
+// allFetchesFailed returns true if providers were asked and none returned offers.
+func allFetchesFailed(infos []ProviderFetchInfo) bool {
+	for _, info := range infos {
+		if info.Error == "" {
+			return false
+		}
+	}
+	return len(infos) > 0
+}
+
+// printOfferFetchErrors warns about providers whose offers could not be fetched.
+func printOfferFetchErrors(w io.Writer, infos []ProviderFetchInfo) {
+	for _, info := range infos {
+		if info.Error != "" {
+			fmt.Fprintf(w, "⚠ Could not fetch offers from %s: %s\n", info.Provider, info.Error)
+		}
+	}
+}
+
+// printOffersText prints the offers as a table.
+func printOffersText(out OffersOutput) {
+	printOfferFetchErrors(os.Stdout, out.Providers)
+
+	if len(out.Offers) == 0 {
+		fmt.Println("No offers match.")
+		return
+	}
+
+	fmt.Printf("%-11s %-20s %-5s %-12s %-10s %-10s %s\n", "PROVIDER", "GPU", "VRAM", "REGION", "ON-DEMAND", "SPOT", "OFFER")
+	for _, o := range out.Offers {
+		spotPrice := "-"
+		if o.SpotPrice != nil {
+			spotPrice = fmt.Sprintf("€%.2f", *o.SpotPrice)
+		}
+		fmt.Printf("%-11s %-20s %-5s %-12s %-10s %-10s %s\n",
+			o.Provider, o.GPU, fmt.Sprintf("%dGB", o.VRAM), o.Region,
+			fmt.Sprintf("€%.2f", o.OnDemandPrice), spotPrice, o.OfferID)
+	}
+	fmt.Printf("\n%d offer(s), prices per hour\n", len(out.Offers))
+}
+
+// writeOffersCSV writes the offers as CSV with a header row to out.
+// Offers without spot pricing have an empty spot_price.
+func writeOffersCSV(out io.Writer, offers []OfferInfo) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"provider", "offer_id", "gpu", "vram_gb", "region", "on_demand_price", "spot_price", "reliability", "currency"})
+	for _, o := range offers {
+		spotPrice := ""
+		if o.SpotPrice != nil {
+			spotPrice = strconv.FormatFloat(*o.SpotPrice, 'f', -1, 64)
+		}
+		w.Write([]string{
+			o.Provider,
+			o.OfferID,
+			o.GPU,
+			strconv.Itoa(o.VRAM),
+			o.Region,
+			strconv.FormatFloat(o.OnDemandPrice, 'f', -1, 64),
+			spotPrice,
+			strconv.FormatFloat(o.Reliability, 'f', -1, 64),
+			o.Currency,
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tmeurs/spinup/internal/models"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestModelMinVRAM(t *testing.T) {
+	tests := []struct {
+		name    string
+		model   string
+		minVRAM int
+		want    int
+		wantErr error
+	}{
+		{"no model", "", 0, 0, nil},
+		{"no model keeps min-vram", "", 24, 24, nil},
+		{"small model", "qwen2.5-coder:7b", 0, 8, nil},
+		{"medium model", "qwen2.5-coder:32b", 0, 35, nil},
+		{"large model", "deepseek-coder-v2:236b", 0, 120, nil},
+		{"min-vram above the model", "qwen2.5-coder:7b", 48, 48, nil},
+		{"min-vram below the model", "codellama:70b", 24, 40, nil},
+		{"unknown model", "llama3:8b", 0, 0, models.ErrModelNotFound},
+		{"name without tag", "qwen2.5-coder", 16, 0, models.ErrModelNotFound},
+		{"name is case sensitive", "Qwen2.5-Coder:7b", 0, 0, models.ErrModelNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := modelMinVRAM(tt.model, tt.minVRAM)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("modelMinVRAM(%q, %d) error = %v, want %v", tt.model, tt.minVRAM, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("modelMinVRAM(%q, %d) = %d, want %d", tt.model, tt.minVRAM, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteOffersCSV(t *testing.T) {
+	spot := func(price float64) *float64 { return &price }
+	offers := []OfferInfo{
+		{Provider: "vast", OfferID: "12345", GPU: "RTX 4090", VRAM: 24, Region: "EU-West", OnDemandPrice: 0.45, SpotPrice: spot(0.28), Reliability: 0.987, Currency: "EUR"},
+		{Provider: "lambda", OfferID: "gpu_1x_h100_pcie@us-east-1", GPU: "H100 80GB", VRAM: 80, Region: "US-East", OnDemandPrice: 2.49, Currency: "EUR"},
+		// Fields with a comma or quote are quoted
+		{Provider: "runpod", OfferID: "NVIDIA A100, 80GB", GPU: `A100 "SXM"`, VRAM: 80, Region: "EU-West", OnDemandPrice: 1.19, SpotPrice: spot(0.1), Currency: "EUR"},
+	}
+
+	var buf bytes.Buffer
+	if err := writeOffersCSV(&buf, offers); err != nil {
+		t.Fatalf("writeOffersCSV() error = %v", err)
+	}
+	checkGolden(t, "offers.csv", buf.Bytes())
+
+	buf.Reset()
+	if err := writeOffersCSV(&buf, nil); err != nil {
+		t.Fatalf("writeOffersCSV(no offers) error = %v", err)
+	}
+	checkGolden(t, "offers_empty.csv", buf.Bytes())
+}
+
+// checkGolden compares got to the golden file testdata/name, or rewrites
+// the file when the tests run with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatalf("failed to create testdata: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("failed to update %s: %v", path, err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file:\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
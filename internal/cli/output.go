@@ -11,14 +11,18 @@ import (
 type OutputFormat string
 
 const (
-	OutputFormatText OutputFormat = "text"
-	OutputFormatJSON OutputFormat = "json"
+	OutputFormatText   OutputFormat = "text"
+	OutputFormatJSON   OutputFormat = "json"
+	OutputFormatNDJSON OutputFormat = "ndjson"
 )
 
 // GetOutputFormat returns the current output format from the global flag.
 func GetOutputFormat() OutputFormat {
-	if output == "json" {
+	switch output {
+	case "json":
 		return OutputFormatJSON
+	case "ndjson":
+		return OutputFormatNDJSON
 	}
 	return OutputFormatText
 }
@@ -28,17 +32,63 @@ func IsJSONOutput() bool {
 	return output == "json"
 }
 
+// IsNDJSONOutput returns true if NDJSON event stream output is enabled.
+func IsNDJSONOutput() bool {
+	return output == "ndjson"
+}
+
 // DeployOutput represents the JSON output structure for deploy command.
 // Matches PRD Section 3.2 JSON format.
 type DeployOutput struct {
-	Status   string              `json:"status"` // "deploying", "ready", "error"
-	Instance *DeployInstanceInfo `json:"instance,omitempty"`
-	Model    string              `json:"model,omitempty"`
-	Endpoint *EndpointInfo       `json:"endpoint,omitempty"`
-	Cost     *CostInfo           `json:"cost,omitempty"`
-	Deadman  *DeadmanInfo        `json:"deadman,omitempty"`
-	Error    string              `json:"error,omitempty"`
-	Duration string              `json:"duration,omitempty"`
+	Status    string              `json:"status"` // "deploying", "ready", "error"
+	Session   string              `json:"session,omitempty"`
+	Instance  *DeployInstanceInfo `json:"instance,omitempty"`
+	Model     string              `json:"model,omitempty"`
+	Endpoint  *EndpointInfo       `json:"endpoint,omitempty"`
+	Cost      *CostInfo           `json:"cost,omitempty"`
+	Deadman   *DeadmanInfo        `json:"deadman,omitempty"`
+	Error     string              `json:"error,omitempty"`
+	Duration  string              `json:"duration,omitempty"`
+	Providers []ProviderFetchInfo `json:"providers,omitempty"`
+	Attempts  []DeployAttemptInfo `json:"attempts,omitempty"`
+	Score     *OfferScoreInfo     `json:"score,omitempty"`
+}
+
+// OfferScoreInfo describes why the deployed offer was ranked where it was.
+type OfferScoreInfo struct {
+	Total   float64           `json:"total"`
+	Factors []ScoreFactorInfo `json:"factors"`
+}
+
+// ScoreFactorInfo contains one weighted factor of an offer score.
+type ScoreFactorInfo struct {
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Weight float64 `json:"weight"`
+}
+
+// DeployAttemptInfo describes one offer that was tried during deployment.
+type DeployAttemptInfo struct {
+	Number     int    `json:"number"`
+	Provider   string `json:"provider"`
+	OfferID    string `json:"offer_id"`
+	GPU        string `json:"gpu,omitempty"`
+	Region     string `json:"region,omitempty"`
+	InstanceID string `json:"instance_id,omitempty"`
+	Succeeded  bool   `json:"succeeded"`
+	FailedStep string `json:"failed_step,omitempty"`
+	Terminated bool   `json:"terminated,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ProviderFetchInfo describes how fetching offers from one provider went.
+type ProviderFetchInfo struct {
+	Provider   string `json:"provider"`
+	LatencyMs  int64  `json:"latency_ms"`
+	OfferCount int    `json:"offer_count"`
+	ErrorCode  string `json:"error_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Cached     bool   `json:"cached,omitempty"`
 }
 
 // DeployInstanceInfo contains instance information for deploy output.
@@ -78,6 +128,7 @@ type DeadmanInfo struct {
 // StopOutput represents the JSON output structure for stop command.
 type StopOutput struct {
 	Status                     string   `json:"status"` // "stopped", "error", "manual_verification_required"
+	Session                    string   `json:"session,omitempty"`
 	InstanceID                 string   `json:"instance_id,omitempty"`
 	Provider                   string   `json:"provider,omitempty"`
 	BillingVerified            bool     `json:"billing_verified"`
@@ -89,6 +140,123 @@ type StopOutput struct {
 	Error                      string   `json:"error,omitempty"`
 }
 
+// ProgressOutput is one deploy or stop progress update, as streamed by serve.
+type ProgressOutput struct {
+	Operation  string `json:"operation"` // "deploy", "stop"
+	Session    string `json:"session,omitempty"`
+	Step       int    `json:"step"`
+	TotalSteps int    `json:"total_steps"`
+	StepName   string `json:"step_name"`
+	Message    string `json:"message"`
+	Detail     string `json:"detail,omitempty"`
+	Completed  bool   `json:"completed"`
+	Warning    bool   `json:"warning,omitempty"`
+	Attempt    int    `json:"attempt,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// OffersOutput represents the JSON output structure for an offer listing.
+type OffersOutput struct {
+	Offers    []OfferInfo         `json:"offers"`
+	Providers []ProviderFetchInfo `json:"providers,omitempty"`
+}
+
+// OfferInfo contains one offer for output. Prices are per hour.
+type OfferInfo struct {
+	Provider      string   `json:"provider"`
+	OfferID       string   `json:"offer_id"`
+	GPU           string   `json:"gpu"`
+	VRAM          int      `json:"vram_gb"`
+	Region        string   `json:"region"`
+	OnDemandPrice float64  `json:"on_demand_price"`
+	SpotPrice     *float64 `json:"spot_price,omitempty"`
+	Reliability   float64  `json:"reliability,omitempty"`
+	Currency      string   `json:"currency"`
+}
+
+// ModelInfo contains one supported model for output.
+type ModelInfo struct {
+	Name    string `json:"name"`
+	Params  string `json:"params"`
+	VRAM    int    `json:"vram_gb"`
+	Quality int    `json:"quality"`
+	Tier    string `json:"tier"`
+}
+
+// CleanupOutput represents the JSON output structure for the cleanup command.
+type CleanupOutput struct {
+	Status   string               `json:"status"` // "cleaned", "nothing_to_clean", "error"
+	Journals []CleanupJournalInfo `json:"journals,omitempty"`
+}
+
+// CleanupJournalInfo describes what cleanup did with one deploy journal.
+type CleanupJournalInfo struct {
+	Session                    string `json:"session"`
+	InstanceID                 string `json:"instance_id,omitempty"`
+	Provider                   string `json:"provider,omitempty"`
+	LastCompletedStep          string `json:"last_completed_step,omitempty"`
+	Action                     string `json:"action"` // "terminated", "discarded", "error"
+	BillingVerified            bool   `json:"billing_verified"`
+	ManualVerificationRequired bool   `json:"manual_verification_required,omitempty"`
+	ConsoleURL                 string `json:"console_url,omitempty"`
+	Error                      string `json:"error,omitempty"`
+}
+
+// GCOutput represents the JSON output structure for the gc command.
+type GCOutput struct {
+	Status         string            `json:"status"` // "clean", "found", "terminated", "error"
+	Orphans        []GCOrphanInfo    `json:"orphans,omitempty"`
+	HourlyCost     float64           `json:"hourly_cost"`
+	Currency       string            `json:"currency"`
+	ProviderErrors map[string]string `json:"provider_errors,omitempty"`
+}
+
+// GCOrphanInfo describes one orphaned instance and what gc did with it.
+type GCOrphanInfo struct {
+	Provider        string  `json:"provider"`
+	InstanceID      string  `json:"instance_id"`
+	Name            string  `json:"name,omitempty"`
+	GPU             string  `json:"gpu,omitempty"`
+	Region          string  `json:"region,omitempty"`
+	Status          string  `json:"status"`
+	HourlyRate      float64 `json:"hourly_rate"`
+	Running         string  `json:"running,omitempty"`
+	AccruedCost     float64 `json:"accrued_cost"`
+	Action          string  `json:"action"` // "listed", "terminated", "error"
+	BillingVerified bool    `json:"billing_verified"`
+	Error           string  `json:"error,omitempty"`
+}
+
+// DoctorOutput represents the JSON output structure for the doctor command.
+type DoctorOutput struct {
+	Status string            `json:"status"` // "ok", "warn", "fail"
+	Checks []DoctorCheckInfo `json:"checks"`
+}
+
+// DoctorCheckInfo describes the outcome of one preflight check.
+type DoctorCheckInfo struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"` // "ok", "warn", "fail", "skip"
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// ConfigOutput represents the JSON output structure for the config show command.
+type ConfigOutput struct {
+	Profile  string              `json:"profile,omitempty"`
+	Files    []string            `json:"files"`
+	StateDir string              `json:"state_dir"`
+	Settings []ConfigSettingInfo `json:"settings"`
+}
+
+// ConfigSettingInfo describes the value of one setting and where it came from.
+type ConfigSettingInfo struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Scope  string `json:"scope"` // "default", "global", "profile", "project", "env", "flag"
+	Origin string `json:"origin"`
+}
+
 // PrintJSON marshals and prints a value as JSON.
 func PrintJSON(v any) {
 	data, err := json.MarshalIndent(v, "", "  ")
@@ -6,6 +6,8 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/deploy"
 	"github.com/tmeurs/spinup/internal/logging"
 )
 
@@ -18,19 +20,24 @@ var (
 
 // Global flags
 var (
-	cheapest   bool
-	provider   string
-	gpu        string
-	model      string
-	tier       string
-	spot       bool
-	onDemand   bool
-	region     string
-	stop       bool
-	output     string
-	timeout    string
-	yes        bool
-	verbose    int
+	cheapest    bool
+	provider    string
+	gpu         string
+	model       string
+	tier        string
+	spot        bool
+	onDemand    bool
+	region      string
+	filterExpr  string
+	stop        bool
+	output      string
+	timeout     string
+	yes         bool
+	verbose     int
+	session     string
+	profile     string
+	stateDir    string
+	maxAttempts int
 )
 
 // showVersion tracks if --version was requested
@@ -64,6 +71,7 @@ sets up WireGuard tunnels, and guarantees cleanup.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		log := logging.Get()
 		jsonOutput := IsJSONOutput()
+		textOutput := GetOutputFormat() == OutputFormatText
 
 		// Handle --version flag
 		if showVersion {
@@ -84,7 +92,7 @@ sets up WireGuard tunnels, and guarantees cleanup.`,
 			err := RunStop()
 			if err != nil {
 				log.Error().Err(err).Msg("Stop failed")
-				if !jsonOutput {
+				if textOutput {
 					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				}
 				os.Exit(1)
@@ -96,10 +104,10 @@ sets up WireGuard tunnels, and guarantees cleanup.`,
 		if cheapest {
 			// Determine spot preference: --on-demand overrides --spot
 			preferSpot := spot && !onDemand
-			err := RunCheapestDeploy(model, provider, gpu, region, preferSpot, timeout)
+			err := RunCheapestDeploy(model, provider, gpu, region, filterExpr, preferSpot, timeout)
 			if err != nil {
 				log.Error().Err(err).Msg("Deployment failed")
-				if !jsonOutput {
+				if textOutput {
 					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				}
 				os.Exit(1)
@@ -108,8 +116,8 @@ sets up WireGuard tunnels, and guarantees cleanup.`,
 		}
 
 		// JSON output mode is incompatible with interactive TUI
-		if jsonOutput {
-			PrintJSONError(fmt.Errorf("--output=json requires --cheapest, --stop, or status subcommand"))
+		if !textOutput {
+			PrintJSONError(fmt.Errorf("--output=%s requires --cheapest, --stop, or status subcommand", output))
 			os.Exit(1)
 		}
 
@@ -145,12 +153,14 @@ func init() {
 	rootCmd.Flags().BoolVar(&spot, "spot", true, "Prefer spot instances")
 	rootCmd.Flags().BoolVar(&onDemand, "on-demand", false, "Force on-demand instances")
 	rootCmd.Flags().StringVar(&region, "region", "", "Preferred region (eu-west, us-east, etc.)")
+	rootCmd.Flags().StringVar(&filterExpr, "filter", "", "Offer filter expression, e.g. 'vram>=48 && provider!=paperspace' (default OFFER_FILTER)")
+	rootCmd.Flags().IntVar(&maxAttempts, "max-attempts", deploy.DefaultMaxAttempts, "Offers to try if instance creation fails or the instance doesn't boot")
 
 	// Control flags
 	rootCmd.Flags().BoolVar(&stop, "stop", false, "Stop running instance")
 
 	// Output flags
-	rootCmd.Flags().StringVar(&output, "output", "text", "Output format: text, json")
+	rootCmd.Flags().StringVar(&output, "output", "text", "Output format: text, json, ndjson")
 	rootCmd.Flags().StringVar(&timeout, "timeout", "10h", "Deadman switch timeout")
 
 	// Convenience flags
@@ -159,6 +169,18 @@ func init() {
 
 	// Version flag
 	rootCmd.Flags().BoolVar(&showVersion, "version", false, "Show version information")
+
+	// Session flag (shared with subcommands)
+	rootCmd.PersistentFlags().StringVar(&session, "session", "", "Named session to deploy, stop or show (default \"default\")")
+
+	// Configuration flags (shared with subcommands)
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Configuration profile from the global config file (default $SPINUP_PROFILE)")
+	rootCmd.PersistentFlags().StringVar(&stateDir, "state-dir", "", "Directory of the state file (default $STATE_DIR or ~/.local/state/spinup)")
+}
+
+// newSessionStateManager creates a state manager for the session selected with --session.
+func newSessionStateManager() (*config.StateManager, error) {
+	return newStateManager(config.WithSession(session))
 }
 
 // SetVersion sets the version information for the version command
@@ -0,0 +1,385 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/logging"
+	"github.com/tmeurs/spinup/internal/models"
+	"github.com/tmeurs/spinup/internal/offerexpr"
+	providerPkg "github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/registry"
+	"github.com/tmeurs/spinup/internal/server"
+)
+
+// serveAddr is the listen address of the serve command
+var serveAddr string
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a local HTTP API to deploy, stop and watch instances",
+	Long: `Serve a local HTTP API for editor plugins and other tools.
+
+The API only listens on loopback addresses. A new token is written to
+.spinup.serve.token in the state directory on every start; requests must
+send it as "Authorization: Bearer <token>".
+
+Endpoints (JSON bodies match --output=json of the commands):
+
+  GET  /v1/status   Session status
+  POST /v1/deploy   Start a deployment, e.g. {"model": "qwen2.5-coder:32b"}
+  POST /v1/stop     Stop the instance
+  GET  /v1/offers   Available offers (?model=&provider=&gpu=&region=&spot_only=&max_price=)
+  GET  /v1/models   Supported models
+  GET  /v1/events   Server-Sent Events: deploy_progress, deploy_result,
+                    stop_progress, stop_result
+
+Deployments run in the background; POST /v1/deploy returns 202 and the
+result arrives as a deploy_result event. Only one deploy or stop runs at a
+time. Use --session to serve a named session.
+
+The configuration is loaded, and the secrets file unlocked, once at
+start; restart the server to pick up changes.`,
+	Run: runServeCmd,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveAddr, "addr", server.DefaultAddr, "Loopback address to listen on")
+}
+
+func runServeCmd(cmd *cobra.Command, args []string) {
+	if err := RunServe(serveAddr); err != nil {
+		logging.Error().Err(err).Msg("Serve failed")
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// RunServe serves the HTTP API for the selected session on addr until
+// interrupted. A deployment still running then is canceled and cleaned up
+// before it returns.
+func RunServe(addr string) error {
+	log := logging.Get()
+
+	stateManager, err := newSessionStateManager()
+	if err != nil {
+		return fmt.Errorf("failed to create state manager: %w", err)
+	}
+
+	// Requests share this configuration: loading it per request could
+	// prompt for the passphrase while the terminal is not watched
+	cfg, warnings, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	for _, w := range warnings {
+		log.Warn().Msg(w)
+	}
+
+	token, err := server.GenerateToken()
+	if err != nil {
+		return err
+	}
+	tokenPath := stateManager.SessionFilePath("serve.token")
+	if err := os.WriteFile(tokenPath, []byte(token+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	defer os.Remove(tokenPath)
+
+	backend := &serveBackend{cfg: cfg, stateManager: stateManager}
+	srv, err := server.New(backend, token)
+	if err != nil {
+		return err
+	}
+	backend.publish = srv.Publish
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	log.Info().Str("addr", addr).Str("session", stateManager.Session()).Msg("Serving API")
+	fmt.Printf("spinup %s - Serving API on http://%s (session %q)\n", Version, addr, stateManager.Session())
+	fmt.Printf("Token: %s\n", tokenPath)
+
+	return srv.ListenAndServe(ctx, addr)
+}
+
+// serveBackend carries out API requests for one session, with the same
+// checks and JSON output as the equivalent commands.
+type serveBackend struct {
+	// cfg is loaded once when the server starts and only read afterwards
+	cfg          *config.Config
+	stateManager *config.StateManager
+	publish      func(event string, data any)
+}
+
+// Status returns the session status like 'spinup status --output=json'.
+func (b *serveBackend) Status(ctx context.Context) (any, error) {
+	state, err := b.stateManager.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	if state == nil || state.Instance == nil {
+		return StatusOutput{Status: "none_active", Session: b.stateManager.Session()}, nil
+	}
+	return buildStatusOutput(b.stateManager.Session(), state), nil
+}
+
+// Deploy checks the session like 'spinup deploy' and returns the deployment.
+func (b *serveBackend) Deploy(ctx context.Context, req server.DeployRequest) (server.Operation, error) {
+	if req.Model == "" {
+		return nil, fmt.Errorf("%w: model is required", server.ErrBadRequest)
+	}
+	if _, err := models.GetModelByName(req.Model); err != nil {
+		return nil, fmt.Errorf("%w: %v", server.ErrBadRequest, err)
+	}
+	if req.Provider != "" && !registry.IsValidProviderName(req.Provider) {
+		return nil, fmt.Errorf("%w: unknown provider %q", server.ErrBadRequest, req.Provider)
+	}
+
+	cfg := b.cfg
+	if !cfg.HasAnyProvider() {
+		return nil, fmt.Errorf("%w: no providers configured - run 'spinup init' first or set API keys in .env", server.ErrConflict)
+	}
+
+	existing, _ := b.stateManager.LoadState()
+	if existing != nil && existing.Instance != nil {
+		return nil, fmt.Errorf("%w: session %q already has an instance running (ID: %s)",
+			server.ErrConflict, b.stateManager.Session(), existing.Instance.ID)
+	}
+	if err := checkUnfinishedDeploy(b.stateManager); err != nil {
+		return nil, fmt.Errorf("%w: %v", server.ErrConflict, err)
+	}
+
+	filter, err := offerexpr.Parse(req.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid filter: %v", server.ErrBadRequest, err)
+	}
+
+	deployCfg := deploy.DefaultDeployConfig()
+	deployCfg.Model = req.Model
+	deployCfg.PreferSpot = !req.OnDemand
+	deployCfg.ProviderName = req.Provider
+	deployCfg.GPUType = req.GPU
+	deployCfg.Region = req.Region
+	deployCfg.Filter = filter
+	deployCfg.DeadmanTimeoutHours = cfg.DeadmanTimeoutHours
+	if req.Timeout != "" {
+		deployCfg.DeadmanTimeoutHours = parseTimeout(req.Timeout)
+	}
+	if req.MaxAttempts > 0 {
+		deployCfg.MaxAttempts = req.MaxAttempts
+	}
+
+	sessionName := b.stateManager.Session()
+	deployer, err := deploy.NewDeployer(cfg, deployCfg,
+		deploy.WithStateManager(b.stateManager),
+		deploy.WithProgressCallback(func(p deploy.DeployProgress) {
+			b.publish(server.EventDeployProgress, buildDeployProgressOutput(sessionName, p))
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deployer: %w", err)
+	}
+
+	return func(ctx context.Context) (any, error) {
+		logging.Info().
+			Str("model", req.Model).
+			Str("provider", req.Provider).
+			Str("session", sessionName).
+			Msg("Starting deployment requested through the API")
+
+		result, err := deployer.Deploy(ctx)
+		if err != nil {
+			return DeployOutput{Status: "error", Session: sessionName, Error: err.Error()}, err
+		}
+
+		// Hand the session over to the background supervisor
+		startSupervisorDaemon(b.stateManager)
+
+		return buildDeployOutput(result, deployCfg.DeadmanTimeoutHours, sessionName), nil
+	}, nil
+}
+
+// Stop stops the session's instance like 'spinup --stop --output=json'.
+func (b *serveBackend) Stop(ctx context.Context) (any, error) {
+	sessionName := b.stateManager.Session()
+	cfg := b.cfg
+
+	existing, err := b.stateManager.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	if existing == nil || existing.Instance == nil {
+		return StopOutput{Status: "no_active_instance", Session: sessionName}, nil
+	}
+
+	stopper, err := deploy.NewStopper(cfg, deploy.DefaultStopConfig(),
+		deploy.WithStopStateManager(b.stateManager),
+		deploy.WithStopProgressCallback(func(p deploy.StopProgress) {
+			b.publish(server.EventStopProgress, buildStopProgressOutput(sessionName, p))
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stopper: %w", err)
+	}
+
+	// Stop the supervisor first so it no longer refreshes the deadman or writes state
+	if err := stopDaemon(b.stateManager); err != nil {
+		logging.Warn().Err(err).Msg("Failed to stop supervisor daemon")
+	}
+
+	result, err := stopper.Stop(ctx)
+	if result != nil {
+		return buildStopOutput(result, err, sessionName), err
+	}
+	return nil, err
+}
+
+// Offers returns the available offers of the configured providers.
+func (b *serveBackend) Offers(ctx context.Context, req server.OffersRequest) (any, error) {
+	cfg := b.cfg
+
+	filter := providerPkg.OfferFilter{
+		GPUType:        req.GPU,
+		Region:         req.Region,
+		SpotOnly:       req.SpotOnly,
+		MaxHourlyPrice: req.MaxPrice,
+	}
+	if req.Model != "" {
+		m, err := models.GetModelByName(req.Model)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", server.ErrBadRequest, err)
+		}
+		filter.MinVRAM = m.VRAM
+	}
+
+	expr, err := resolveOfferFilter(cfg, req.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", server.ErrBadRequest, err)
+	}
+
+	providers, err := offerProviders(cfg, req.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", server.ErrBadRequest, err)
+	}
+
+	cache, err := deploy.LoadOfferCache(b.stateManager, cfg.OfferCacheTTL)
+	if err != nil {
+		logging.Warn().Err(err).Msg("Ignoring offer cache")
+	}
+	results := cache.FetchOffers(ctx, providers, filter, deploy.DefaultProviderFetchTimeout)
+	return buildOffersOutput(results, expr), nil
+}
+
+// Models returns the supported models.
+func (b *serveBackend) Models(ctx context.Context) (any, error) {
+	var infos []ModelInfo
+	for _, m := range models.GetAllModels() {
+		infos = append(infos, ModelInfo{
+			Name:    m.Name,
+			Params:  m.Params,
+			VRAM:    m.VRAM,
+			Quality: m.Quality,
+			Tier:    string(m.Tier),
+		})
+	}
+	return infos, nil
+}
+
+// offerProviders returns the configured provider with the given name, or all
+// configured providers if name is empty.
+func offerProviders(cfg *config.Config, name string) ([]providerPkg.Provider, error) {
+	if name == "" {
+		return registry.GetConfiguredProviders(cfg)
+	}
+	p, err := registry.GetProviderByName(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return []providerPkg.Provider{p}, nil
+}
+
+// resolveOfferFilter parses an offer filter expression, falling back to the
+// config's OFFER_FILTER if it is empty.
+func resolveOfferFilter(cfg *config.Config, expr string) (*offerexpr.Filter, error) {
+	if expr == "" {
+		return cfg.DefaultFilter, nil
+	}
+	filter, err := offerexpr.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	return filter, nil
+}
+
+// buildOffersOutput converts the fetched offers matching filter to their
+// JSON representation.
+func buildOffersOutput(results []deploy.ProviderOffers, filter *offerexpr.Filter) OffersOutput {
+	output := OffersOutput{
+		Offers:    []OfferInfo{},
+		Providers: buildProviderFetchInfo(deploy.FetchReports(results)),
+	}
+	for _, r := range results {
+		for _, o := range filter.Apply(r.Offers) {
+			output.Offers = append(output.Offers, OfferInfo{
+				Provider:      o.Provider,
+				OfferID:       o.OfferID,
+				GPU:           o.GPU,
+				VRAM:          o.VRAM,
+				Region:        o.Region,
+				OnDemandPrice: o.OnDemandPrice,
+				SpotPrice:     o.SpotPrice,
+				Reliability:   o.Reliability,
+				Currency:      "EUR",
+			})
+		}
+	}
+	return output
+}
+
+// buildDeployProgressOutput converts deployment progress to its JSON representation.
+func buildDeployProgressOutput(sessionName string, p deploy.DeployProgress) ProgressOutput {
+	output := ProgressOutput{
+		Operation:  "deploy",
+		Session:    sessionName,
+		Step:       int(p.Step),
+		TotalSteps: p.TotalSteps,
+		StepName:   p.Step.String(),
+		Message:    p.Message,
+		Detail:     p.Detail,
+		Completed:  p.Completed,
+		Attempt:    p.Attempt,
+	}
+	if p.Error != nil {
+		output.Error = p.Error.Error()
+	}
+	return output
+}
+
+// buildStopProgressOutput converts stop progress to its JSON representation.
+func buildStopProgressOutput(sessionName string, p deploy.StopProgress) ProgressOutput {
+	output := ProgressOutput{
+		Operation:  "stop",
+		Session:    sessionName,
+		Step:       int(p.Step),
+		TotalSteps: p.TotalSteps,
+		StepName:   p.Step.String(),
+		Message:    p.Message,
+		Detail:     p.Detail,
+		Completed:  p.Completed,
+		Warning:    p.Warning,
+	}
+	if p.Error != nil {
+		output.Error = p.Error.Error()
+	}
+	return output
+}
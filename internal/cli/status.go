@@ -1,19 +1,30 @@
 package cli
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/tmeurs/spinup/internal/clock"
 	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/logging"
 )
 
+// statusClock is the clock durations, costs and deadman times are reported at.
+var statusClock = clock.Real()
+
 // StatusOutput represents the JSON output structure for status command.
 // Matches PRD Section 3.2 JSON format.
 type StatusOutput struct {
 	Status   string               `json:"status"` // "ready", "loading", "none_active"
+	Session  string               `json:"session,omitempty"`
 	Instance *StatusInstanceInfo  `json:"instance,omitempty"`
 	Model    string               `json:"model,omitempty"`
 	Endpoint *StatusEndpointInfo  `json:"endpoint,omitempty"`
@@ -21,6 +32,12 @@ type StatusOutput struct {
 	Deadman  *StatusDeadmanInfo   `json:"deadman,omitempty"`
 }
 
+// StatusListOutput is the JSON output when several sessions are active
+// and no --session was given.
+type StatusListOutput struct {
+	Sessions []StatusOutput `json:"sessions"`
+}
+
 // StatusInstanceInfo contains instance information for status output.
 type StatusInstanceInfo struct {
 	ID       string `json:"id"`
@@ -66,32 +83,61 @@ If an instance is running, this will show:
 
 If no instance is running, it will indicate that.
 
-Use --output=json for machine-readable output.`,
+With several named sessions running, all of them are shown unless
+--session selects one.
+
+With --watch, the status of the selected session is refreshed every
+--interval until its instance is gone. The session is first checked
+against the provider, and stale local state is cleaned up.
+
+Use --output=json for machine-readable output, or --output=ndjson for
+one JSON event per line (status events, and with --watch a reconcile
+event first).`,
 	Run: runStatusCmd,
 }
 
 func runStatusCmd(cmd *cobra.Command, args []string) {
 	outputFormat, _ := cmd.Flags().GetString("output")
+	watch, _ := cmd.Flags().GetBool("watch")
+	interval, _ := cmd.Flags().GetDuration("interval")
+
+	// status has its own --output flag, so the events are opened here
+	var events *eventWriter
+	if outputFormat == "ndjson" {
+		events = openEventWriter("status")
+	}
+
+	if watch && outputFormat == "json" {
+		exitStatusError(outputFormat, events, "", errors.New("--watch requires --output=text or --output=ndjson"))
+	}
+	if watch && interval <= 0 {
+		exitStatusError(outputFormat, events, "", fmt.Errorf("invalid --interval %s: must be positive", interval))
+	}
 
 	// Load state
-	stateManager, err := config.NewStateManager("")
+	stateManager, err := newSessionStateManager()
 	if err != nil {
-		if outputFormat == "json" {
-			printStatusError(err)
-		} else {
-			fmt.Fprintf(os.Stderr, "Error: failed to initialize state manager: %v\n", err)
-		}
-		os.Exit(1)
+		exitStatusError(outputFormat, events, "failed to initialize state manager", err)
+	}
+
+	if watch {
+		runStatusWatch(stateManager, events, interval)
+		return
+	}
+	if events != nil {
+		printStatusEvents(stateManager, events)
+		return
+	}
+
+	// Without --session, show every active session
+	if session == "" {
+		runStatusAllSessions(stateManager, outputFormat)
+		return
 	}
 
 	state, err := stateManager.LoadState()
 	if err != nil {
-		if outputFormat == "json" {
-			printStatusError(err)
-		} else {
-			fmt.Fprintf(os.Stderr, "Error: failed to load state: %v\n", err)
-		}
-		os.Exit(1)
+		exitStatusError(outputFormat, events, "failed to load state", err)
 	}
 
 	// Check if there's an active instance
@@ -102,9 +148,142 @@ func runStatusCmd(cmd *cobra.Command, args []string) {
 
 	// Print status for active instance
 	if outputFormat == "json" {
-		printStatusJSON(state)
+		printStatusJSON(stateManager.Session(), state)
 	} else {
-		printStatusText(state)
+		printStatusText(stateManager.Session(), state)
+	}
+}
+
+// runStatusAllSessions prints the status of every active session.
+// A single session is printed exactly like a --session lookup.
+func runStatusAllSessions(stateManager *config.StateManager, outputFormat string) {
+	sessions, err := stateManager.ListSessions()
+	if err != nil {
+		exitStatusError(outputFormat, nil, "failed to load state", err)
+	}
+
+	names, _ := stateManager.SessionNames()
+	switch len(names) {
+	case 0:
+		printNoActiveInstance(outputFormat)
+	case 1:
+		if outputFormat == "json" {
+			printStatusJSON(names[0], sessions[names[0]])
+		} else {
+			printStatusText(names[0], sessions[names[0]])
+		}
+	default:
+		if outputFormat == "json" {
+			list := StatusListOutput{}
+			for _, name := range names {
+				list.Sessions = append(list.Sessions, buildStatusOutput(name, sessions[name]))
+			}
+			data, _ := json.MarshalIndent(list, "", "  ")
+			fmt.Println(string(data))
+			return
+		}
+		for i, name := range names {
+			if i > 0 {
+				fmt.Println("")
+			}
+			printStatusText(name, sessions[name])
+		}
+	}
+}
+
+// printStatusEvents prints one status event per session: the one selected
+// with --session, or every active one.
+func printStatusEvents(stateManager *config.StateManager, events *eventWriter) {
+	if session != "" {
+		state, err := stateManager.LoadState()
+		if err != nil {
+			exitStatusError("ndjson", events, "failed to load state", err)
+		}
+		events.status(sessionStatusOutput(stateManager.Session(), state))
+		return
+	}
+
+	sessions, err := stateManager.ListSessions()
+	if err != nil {
+		exitStatusError("ndjson", events, "failed to load state", err)
+	}
+	names, _ := stateManager.SessionNames()
+	if len(names) == 0 {
+		events.status(StatusOutput{Status: "none_active"})
+		return
+	}
+	for _, name := range names {
+		events.status(buildStatusOutput(name, sessions[name]))
+	}
+}
+
+// runStatusWatch reports the status of the selected session every interval
+// until its instance is gone or the command is interrupted. Local state is
+// reconciled against the provider before the first report.
+func runStatusWatch(stateManager *config.StateManager, events *eventWriter, interval time.Duration) {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	reconcileStatus(ctx, stateManager, events)
+
+	ticker := statusClock.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		state, err := stateManager.LoadState()
+		if err != nil {
+			exitStatusError("text", events, "failed to load state", err)
+		}
+
+		if events != nil {
+			events.status(sessionStatusOutput(stateManager.Session(), state))
+		} else {
+			// Redraw in place
+			fmt.Print("\033[H\033[2J")
+			if state == nil || state.Instance == nil {
+				printNoActiveInstance("text")
+			} else {
+				printStatusText(stateManager.Session(), state)
+				fmt.Printf("\nRefreshing every %s. Press Ctrl+C to exit.\n", interval)
+			}
+		}
+
+		if state == nil || state.Instance == nil {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// reconcileStatus checks the session's local state against the provider
+// and cleans it up if the instance no longer exists. Failures are reported
+// as warnings: the watch goes on with the local state.
+func reconcileStatus(ctx context.Context, stateManager *config.StateManager, events *eventWriter) {
+	log := logging.Get()
+
+	cfg, _, err := loadConfig()
+	if err != nil {
+		log.Warn().Err(err).Msg("Skipping state reconciliation")
+		events.warning(WarningInfo{Message: "Skipping state reconciliation: failed to load config", Detail: err.Error()})
+		return
+	}
+
+	result, err := deploy.ReconcileState(ctx, cfg, stateManager, nil)
+	if err != nil {
+		log.Warn().Err(err).Msg("State reconciliation failed")
+		events.warning(WarningInfo{Message: "State reconciliation failed", Detail: err.Error()})
+		return
+	}
+
+	if events != nil {
+		events.reconcile(result)
+	} else if result.Warning != "" {
+		fmt.Fprintln(os.Stderr, result.Warning)
 	}
 }
 
@@ -128,9 +307,17 @@ func printNoActiveInstance(outputFormat string) {
 }
 
 // printStatusJSON prints status in JSON format per PRD Section 3.2.
-func printStatusJSON(state *config.State) {
+func printStatusJSON(sessionName string, state *config.State) {
+	output := buildStatusOutput(sessionName, state)
+	data, _ := json.MarshalIndent(output, "", "  ")
+	fmt.Println(string(data))
+}
+
+// buildStatusOutput builds the JSON status for one session.
+func buildStatusOutput(sessionName string, state *config.State) StatusOutput {
 	output := StatusOutput{
-		Status: getStatusFromState(state),
+		Status:  getStatusFromState(state),
+		Session: sessionName,
 	}
 
 	// Instance info
@@ -160,7 +347,7 @@ func printStatusJSON(state *config.State) {
 
 	// Cost info
 	if state.Cost != nil {
-		accumulated := state.CalculateAccumulatedCost()
+		accumulated := state.AccumulatedCostAt(statusClock.Now())
 		output.Cost = &StatusCostInfo{
 			Hourly:      state.Cost.HourlyRate,
 			Accumulated: accumulated,
@@ -178,15 +365,28 @@ func printStatusJSON(state *config.State) {
 		}
 	}
 
-	data, _ := json.MarshalIndent(output, "", "  ")
-	fmt.Println(string(data))
+	return output
+}
+
+// sessionStatusOutput builds the status for one session, which may have
+// no active instance.
+func sessionStatusOutput(sessionName string, state *config.State) StatusOutput {
+	if state == nil || state.Instance == nil {
+		return StatusOutput{Status: "none_active", Session: sessionName}
+	}
+	return buildStatusOutput(sessionName, state)
 }
 
 // printStatusText prints status in text format per PRD Section 3.2.
-func printStatusText(state *config.State) {
+func printStatusText(sessionName string, state *config.State) {
 	fmt.Printf("spinup %s - Status\n", Version)
 	fmt.Println("")
 
+	// Session name (only shown for named sessions)
+	if sessionName != config.DefaultSessionName {
+		fmt.Printf("Session:      %s\n", sessionName)
+	}
+
 	// Instance status
 	fmt.Printf("Instance:     ● Active\n")
 
@@ -208,13 +408,13 @@ func printStatusText(state *config.State) {
 
 	// Running time
 	if state.Instance != nil {
-		duration := state.Instance.Duration()
+		duration := state.Instance.DurationAt(statusClock.Now())
 		fmt.Printf("Running:      %s\n", formatDuration(duration))
 	}
 
 	// Cost
 	if state.Cost != nil {
-		accumulated := state.CalculateAccumulatedCost()
+		accumulated := state.AccumulatedCostAt(statusClock.Now())
 		fmt.Printf("Cost so far:  %s%.2f\n", getCurrencySymbol(state.Cost.Currency), accumulated)
 	}
 
@@ -229,6 +429,24 @@ func printStatusText(state *config.State) {
 	}
 }
 
+// exitStatusError reports err in the selected output format and exits.
+// what describes the failed step for text and ndjson output.
+func exitStatusError(outputFormat string, events *eventWriter, what string, err error) {
+	if outputFormat == "json" {
+		printStatusError(err)
+		os.Exit(1)
+	}
+	if what != "" {
+		err = fmt.Errorf("%s: %w", what, err)
+	}
+	if events != nil {
+		events.failure(err)
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	os.Exit(1)
+}
+
 // printStatusError prints an error in JSON format.
 func printStatusError(err error) {
 	output := struct {
@@ -262,15 +480,7 @@ func getStatusFromState(state *config.State) string {
 
 // calculateDeadmanRemaining calculates the time remaining before deadman triggers.
 func calculateDeadmanRemaining(deadman *config.DeadmanState) time.Duration {
-	if deadman == nil {
-		return 0
-	}
-	deadline := deadman.LastHeartbeat.Add(time.Duration(deadman.TimeoutHours) * time.Hour)
-	remaining := time.Until(deadline)
-	if remaining < 0 {
-		return 0
-	}
-	return remaining
+	return deadman.RemainingAt(statusClock.Now())
 }
 
 // getCurrencySymbol returns the symbol for a currency code.
@@ -291,5 +501,7 @@ func init() {
 	rootCmd.AddCommand(statusCmd)
 
 	// Status command inherits --output flag from root, but we can also set it locally
-	statusCmd.Flags().String("output", "text", "Output format: text, json")
+	statusCmd.Flags().String("output", "text", "Output format: text, json, ndjson")
+	statusCmd.Flags().Bool("watch", false, "Refresh the status until the instance is gone")
+	statusCmd.Flags().Duration("interval", 5*time.Second, "Refresh interval for --watch")
 }
@@ -29,13 +29,24 @@ import (
 //	Session cost: €2.93
 //	Duration: 4h 28m
 //
-// When --output=json is set, it outputs JSON only at the end.
+// When --output=json is set, it outputs JSON only at the end; with
+// --output=ndjson every progress update is printed as an event.
 func RunStop() error {
 	log := logging.Get()
 	jsonOutput := IsJSONOutput()
+	events := newEventWriter("stop")
+	textOutput := !jsonOutput && events == nil
+
+	fail := func(err error) error {
+		if jsonOutput {
+			PrintJSONError(err)
+		}
+		events.failure(err)
+		return err
+	}
 
 	// Print header (skip in JSON mode)
-	if !jsonOutput {
+	if textOutput {
 		fmt.Printf("\nspinup %s - Stopping instance\n\n", Version)
 	}
 
@@ -47,48 +58,46 @@ func RunStop() error {
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-sigCh
-		if !jsonOutput {
+		if textOutput {
 			fmt.Println("\n\nInterrupted. Cleanup may be incomplete - please verify manually.")
 		}
 		cancel()
 	}()
 
 	// Load configuration
-	cfg, warnings, err := config.LoadConfig("")
+	cfg, warnings, err := loadConfig()
 	if err != nil {
-		if jsonOutput {
-			PrintJSONError(fmt.Errorf("failed to load config: %w", err))
-		}
-		return fmt.Errorf("failed to load config: %w", err)
+		return fail(fmt.Errorf("failed to load config: %w", err))
 	}
 	for _, w := range warnings {
 		log.Warn().Msg(w)
+		events.warning(WarningInfo{Message: w})
 	}
 
 	// Create state manager
-	stateManager, err := config.NewStateManager("")
+	stateManager, err := newSessionStateManager()
 	if err != nil {
-		if jsonOutput {
-			PrintJSONError(fmt.Errorf("failed to create state manager: %w", err))
-		}
-		return fmt.Errorf("failed to create state manager: %w", err)
+		return fail(fmt.Errorf("failed to create state manager: %w", err))
 	}
 
 	// Check if there's an active instance
 	existingState, err := stateManager.LoadState()
 	if err != nil {
-		if jsonOutput {
-			PrintJSONError(fmt.Errorf("failed to load state: %w", err))
-		}
-		return fmt.Errorf("failed to load state: %w", err)
+		return fail(fmt.Errorf("failed to load state: %w", err))
 	}
 	if existingState == nil || existingState.Instance == nil {
-		if jsonOutput {
-			output := StopOutput{
-				Status: "no_active_instance",
-			}
+		output := StopOutput{
+			Status:  "no_active_instance",
+			Session: stateManager.Session(),
+		}
+		switch {
+		case jsonOutput:
 			PrintJSON(output)
-		} else {
+		case events != nil:
+			events.result(output)
+		case stateManager.Session() != config.DefaultSessionName:
+			fmt.Printf("No active instance to stop in session %q.\n", stateManager.Session())
+		default:
 			fmt.Println("No active instance to stop.")
 		}
 		return nil
@@ -97,7 +106,11 @@ func RunStop() error {
 	// Create stopper with progress callback (skip progress in JSON mode)
 	var progressCb func(deploy.StopProgress)
 	var manualVerifCb func(*deploy.ManualVerification)
-	if !jsonOutput {
+	switch {
+	case events != nil:
+		progressCb = events.stopProgress
+		manualVerifCb = events.manualVerification
+	case textOutput:
 		progressCb = stopProgressCallback
 		manualVerifCb = displayManualVerification
 	}
@@ -107,10 +120,13 @@ func RunStop() error {
 		deploy.WithManualVerificationCallback(manualVerifCb),
 	)
 	if err != nil {
-		if jsonOutput {
-			PrintJSONError(fmt.Errorf("failed to create stopper: %w", err))
-		}
-		return fmt.Errorf("failed to create stopper: %w", err)
+		return fail(fmt.Errorf("failed to create stopper: %w", err))
+	}
+
+	// Stop the supervisor first so it no longer refreshes the deadman or writes state
+	if err := stopDaemon(stateManager); err != nil {
+		log.Warn().Err(err).Msg("Failed to stop supervisor daemon")
+		events.warning(WarningInfo{Message: "Failed to stop supervisor daemon", Detail: err.Error()})
 	}
 
 	// Run stop
@@ -122,22 +138,26 @@ func RunStop() error {
 	result, err := stopper.Stop(ctx)
 	if err != nil {
 		// Check if we got a result despite the error (e.g., billing not verified)
-		if result != nil {
-			if jsonOutput {
-				printStopSummaryJSON(result, err)
-			} else {
-				printStopSummary(result)
-			}
-		} else if jsonOutput {
-			PrintJSONError(err)
+		switch {
+		case result == nil:
+			return fail(err)
+		case jsonOutput:
+			printStopSummaryJSON(result, err, stateManager.Session())
+		case events != nil:
+			events.result(buildStopOutput(result, err, stateManager.Session()))
+		default:
+			printStopSummary(result)
 		}
 		return err
 	}
 
 	// Print success summary
-	if jsonOutput {
-		printStopSummaryJSON(result, nil)
-	} else {
+	switch {
+	case jsonOutput:
+		printStopSummaryJSON(result, nil, stateManager.Session())
+	case events != nil:
+		events.result(buildStopOutput(result, nil, stateManager.Session()))
+	default:
 		printStopSummary(result)
 	}
 
@@ -230,7 +250,12 @@ func formatSessionDuration(d time.Duration) string {
 }
 
 // printStopSummaryJSON prints the stop summary in JSON format.
-func printStopSummaryJSON(result *deploy.StopResult, stopErr error) {
+func printStopSummaryJSON(result *deploy.StopResult, stopErr error, sessionName string) {
+	PrintJSON(buildStopOutput(result, stopErr, sessionName))
+}
+
+// buildStopOutput builds the JSON output of a stop.
+func buildStopOutput(result *deploy.StopResult, stopErr error, sessionName string) StopOutput {
 	status := "stopped"
 	if result.ManualVerificationRequired {
 		status = "manual_verification_required"
@@ -241,6 +266,7 @@ func printStopSummaryJSON(result *deploy.StopResult, stopErr error) {
 
 	output := StopOutput{
 		Status:                     status,
+		Session:                    sessionName,
 		InstanceID:                 result.InstanceID,
 		Provider:                   result.Provider,
 		BillingVerified:            result.BillingVerified,
@@ -255,5 +281,5 @@ func printStopSummaryJSON(result *deploy.StopResult, stopErr error) {
 		output.Error = stopErr.Error()
 	}
 
-	PrintJSON(output)
+	return output
 }
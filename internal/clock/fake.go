@@ -0,0 +1,129 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves when told to. It is safe for concurrent
+// use, so a test can advance it while the code under test ticks in its own
+// goroutine.
+type Fake struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// NewFake returns a Fake set to now.
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t.
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// Until returns the fake duration until t.
+func (f *Fake) Until(t time.Time) time.Duration {
+	return t.Sub(f.Now())
+}
+
+// NewTicker returns a ticker that ticks whenever the fake time passes a
+// multiple of d after now.
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t := &fakeTicker{
+		fake:   f,
+		c:      make(chan time.Time, 1),
+		period: d,
+		next:   f.now.Add(d),
+	}
+	f.tickers = append(f.tickers, t)
+	f.cond.Broadcast()
+	return t
+}
+
+// Advance moves the fake time forward by d and fires the tickers that became
+// due. Like a real ticker, a ticker that is due several times while its
+// channel is full delivers a single tick.
+func (f *Fake) Advance(d time.Duration) {
+	f.Set(f.Now().Add(d))
+}
+
+// Set moves the fake time to t. Moving it backward fires no tickers.
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.now = t
+	for _, tk := range f.tickers {
+		if tk.next.After(t) {
+			continue
+		}
+		select {
+		case tk.c <- tk.next:
+		default:
+		}
+		for !tk.next.After(t) {
+			tk.next = tk.next.Add(tk.period)
+		}
+	}
+}
+
+// BlockUntil blocks until at least n tickers are running. Tests call it
+// before Advance so a goroutine under test has created its ticker first.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.tickers) < n {
+		f.cond.Wait()
+	}
+}
+
+// Tickers returns the number of running tickers.
+func (f *Fake) Tickers() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.tickers)
+}
+
+// fakeTicker is a ticker of a Fake.
+type fakeTicker struct {
+	fake   *Fake
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Stop() {
+	f := t.fake
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, tk := range f.tickers {
+		if tk == t {
+			f.tickers = append(f.tickers[:i], f.tickers[i+1:]...)
+			f.cond.Broadcast()
+			return
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestFake_Time(t *testing.T) {
+	f := NewFake(epoch)
+	start := f.Now()
+
+	f.Advance(10 * time.Hour)
+	if got := f.Since(start); got != 10*time.Hour {
+		t.Errorf("Since() = %v, want 10h", got)
+	}
+	if got := f.Until(start.Add(11 * time.Hour)); got != time.Hour {
+		t.Errorf("Until() = %v, want 1h", got)
+	}
+
+	f.Set(epoch)
+	if !f.Now().Equal(epoch) {
+		t.Errorf("Now() after Set = %v, want %v", f.Now(), epoch)
+	}
+}
+
+func TestFake_Ticker(t *testing.T) {
+	f := NewFake(epoch)
+	tk := f.NewTicker(time.Minute)
+
+	// receive reports the tick waiting on the ticker, if any.
+	receive := func() (time.Time, bool) {
+		select {
+		case at := <-tk.C():
+			return at, true
+		default:
+			return time.Time{}, false
+		}
+	}
+
+	f.Advance(59 * time.Second)
+	if _, ok := receive(); ok {
+		t.Error("ticked before the interval passed")
+	}
+
+	f.Advance(time.Second)
+	if at, ok := receive(); !ok || !at.Equal(epoch.Add(time.Minute)) {
+		t.Errorf("tick = %v, %v, want %v", at, ok, epoch.Add(time.Minute))
+	}
+
+	// Ticks due while nobody receives are dropped
+	f.Advance(5 * time.Minute)
+	if _, ok := receive(); !ok {
+		t.Error("no tick after 5 intervals")
+	}
+	if _, ok := receive(); ok {
+		t.Error("more than one tick delivered for one Advance")
+	}
+
+	// The schedule stays aligned to the start
+	f.Advance(30 * time.Second)
+	if _, ok := receive(); ok {
+		t.Error("ticked off schedule")
+	}
+	f.Advance(30 * time.Second)
+	if at, ok := receive(); !ok || !at.Equal(epoch.Add(7*time.Minute)) {
+		t.Errorf("tick = %v, %v, want %v", at, ok, epoch.Add(7*time.Minute))
+	}
+
+	tk.Stop()
+	f.Advance(time.Hour)
+	if _, ok := receive(); ok {
+		t.Error("stopped ticker ticked")
+	}
+	if n := f.Tickers(); n != 0 {
+		t.Errorf("Tickers() = %d after Stop, want 0", n)
+	}
+}
+
+func TestFake_BlockUntil(t *testing.T) {
+	f := NewFake(epoch)
+	ticked := make(chan struct{})
+
+	go func() {
+		tk := f.NewTicker(time.Hour)
+		defer tk.Stop()
+		<-tk.C()
+		close(ticked)
+	}()
+
+	f.BlockUntil(1)
+	f.Advance(time.Hour)
+	select {
+	case <-ticked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("goroutine didn't see the tick")
+	}
+}
+
+func TestOrReal(t *testing.T) {
+	f := NewFake(epoch)
+	if OrReal(f) != Clock(f) {
+		t.Error("OrReal(fake) didn't return the fake")
+	}
+	if _, ok := OrReal(nil).(realClock); !ok {
+		t.Error("OrReal(nil) isn't the system clock")
+	}
+}
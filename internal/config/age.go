@@ -0,0 +1,99 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// The secrets file is encrypted in the age v1 format (age-encryption.org/v1)
+// with a single scrypt recipient, so it can also be read and written with
+// 'age --passphrase'.
+
+const (
+	// defaultScryptLogN is the scrypt work factor of new files, as used by age.
+	defaultScryptLogN = 18
+	// maxScryptLogN bounds the work factor accepted from a file.
+	maxScryptLogN = 22
+)
+
+// ErrWrongPassphrase indicates the secrets file could not be decrypted with
+// the passphrase, or is corrupted.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted secrets file")
+
+// ageEncrypt encrypts plaintext to passphrase with scrypt work factor
+// 2^logN. Every call generates a new file key and salt.
+func ageEncrypt(plaintext, passphrase []byte, logN int) ([]byte, error) {
+	recipient, err := age.NewScryptRecipient(string(passphrase))
+	if err != nil {
+		return nil, err
+	}
+	recipient.SetWorkFactor(logN)
+
+	var out bytes.Buffer
+	w, err := age.Encrypt(&out, recipient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt secrets: %w", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, fmt.Errorf("failed to encrypt secrets: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encrypt secrets: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// ageDecrypt decrypts a file encrypted to a passphrase.
+func ageDecrypt(data, passphrase []byte) ([]byte, error) {
+	if err := checkAgeRecipients(data); err != nil {
+		return nil, err
+	}
+
+	identity, err := age.NewScryptIdentity(string(passphrase))
+	if err != nil {
+		return nil, err
+	}
+	identity.SetMaxWorkFactor(maxScryptLogN)
+
+	r, err := age.Decrypt(bytes.NewReader(data), identity)
+	if err != nil {
+		var noMatch *age.NoIdentityMatchError
+		if errors.As(err, &noMatch) {
+			return nil, ErrWrongPassphrase
+		}
+		return nil, fmt.Errorf("invalid secrets file: %w", err)
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		// The payload is authenticated chunk by chunk
+		return nil, fmt.Errorf("%w: %v", ErrWrongPassphrase, err)
+	}
+	return plaintext, nil
+}
+
+// checkAgeRecipients returns an error for an age file without a scrypt
+// recipient, which age would only report as not matching the passphrase.
+func checkAgeRecipients(data []byte) error {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	if !s.Scan() || s.Text() != "age-encryption.org/v1" {
+		return errors.New("invalid secrets file: not an age file")
+	}
+	for s.Scan() {
+		line := s.Text()
+		if strings.HasPrefix(line, "---") {
+			return nil
+		}
+		if args, ok := strings.CutPrefix(line, "-> "); ok {
+			if kind, _, _ := strings.Cut(args, " "); kind != "scrypt" {
+				return fmt.Errorf("invalid secrets file: %s recipients are not supported, encrypt it with a passphrase", kind)
+			}
+		}
+	}
+	return errors.New("invalid secrets file: truncated header")
+}
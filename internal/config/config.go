@@ -4,12 +4,13 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/tmeurs/spinup/internal/offerexpr"
 )
 
 // Config represents the application configuration loaded from .env file.
@@ -21,6 +22,14 @@ type Config struct {
 	CoreWeaveAPIKey  string
 	PaperspaceAPIKey string
 
+	// Provider API endpoints (optional, override the public APIs, e.g. to
+	// point spinup at a fake cloud)
+	VastAPIURL       string
+	LambdaAPIURL     string
+	RunPodAPIURL     string // GraphQL endpoint
+	CoreWeaveAPIURL  string
+	PaperspaceAPIURL string
+
 	// WireGuard configuration
 	WireGuardPrivateKey string
 	WireGuardPublicKey  string
@@ -31,11 +40,60 @@ type Config struct {
 	PreferSpot           bool
 	DeadmanTimeoutHours  int
 
+	// DeadmanTermination is how the deadman switch terminates an instance
+	// (DEADMAN_TERMINATION): scoped, relay, api-key or shutdown.
+	DeadmanTermination string
+
+	// WatchdogTimeout is how long an instance may be unreachable before the
+	// supervisor terminates it through the provider API (WATCHDOG_TIMEOUT).
+	// Zero disables the watchdog.
+	WatchdogTimeout time.Duration
+
 	// Alerting (optional)
 	AlertWebhookURL string
 	DailyBudgetEUR  float64
+
+	// Offer ranking
+	Scoring ScoringConfig
+
+	// Offer cache
+	OfferCacheTTL time.Duration // zero disables reuse of cached offers
+
+	// DefaultFilter is the OFFER_FILTER expression offers must match unless
+	// --filter is given (nil matches every offer)
+	DefaultFilter *offerexpr.Filter
+
+	// StateDir is the directory of the state file (STATE_DIR). Load
+	// defaults it to DefaultStateDir(); empty means the current directory.
+	StateDir string
+
+	// Secrets selects the secret backend that secret:// references are
+	// read from.
+	Secrets SecretsConfig
+
+	// Profile is the profile selected from the global config file, if any.
+	Profile string
+
+	// Files are the config files the configuration was loaded from,
+	// lowest precedence first.
+	Files []string
+
+	// Effective lists every setting the configuration was built from, in
+	// load order, with its value and origin.
+	Effective []Value
 }
 
+// DefaultOfferCacheTTL is how long fetched offers are reused by default.
+const DefaultOfferCacheTTL = 5 * time.Minute
+
+// DefaultWatchdogTimeout is how long an instance may be unreachable by
+// default before the supervisor terminates it.
+const DefaultWatchdogTimeout = time.Hour
+
+// MinWatchdogTimeout keeps the watchdog from firing on a couple of missed
+// heartbeats.
+const MinWatchdogTimeout = 10 * time.Minute
+
 // DefaultEnvPath is the default path for the .env file.
 const DefaultEnvPath = ".env"
 
@@ -48,48 +106,41 @@ var ErrInsecurePermissions = errors.New("configuration file has insecure permiss
 // ErrNoProviderConfigured indicates no provider API key was configured.
 var ErrNoProviderConfigured = errors.New("at least one provider API key must be configured")
 
-// LoadConfig loads configuration from the specified .env file path.
-// If path is empty, it uses DefaultEnvPath.
+// LoadConfig loads configuration from the specified .env file path and the
+// environment, which takes precedence. If path is empty, it uses
+// DefaultEnvPath. Unlike Load, it ignores the global config file and fails
+// if the .env file doesn't exist.
 // Returns the config and any warnings (e.g., permission issues) as a slice of strings.
 func LoadConfig(path string) (*Config, []string, error) {
 	if path == "" {
 		path = DefaultEnvPath
 	}
 
-	var warnings []string
-
 	// Check if file exists
 	absPath, err := filepath.Abs(path)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to resolve config path: %w", err)
 	}
 
-	info, err := os.Stat(absPath)
-	if err != nil {
+	if _, err := os.Stat(absPath); err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil, fmt.Errorf("%w: %s", ErrNoConfigFile, absPath)
 		}
 		return nil, nil, fmt.Errorf("failed to stat config file: %w", err)
 	}
 
-	// Check file permissions (Unix only)
-	mode := info.Mode().Perm()
-	if mode != 0o600 {
-		warnings = append(warnings, fmt.Sprintf(
-			"config file %s has permissions %04o, should be 0600 for security",
-			absPath, mode,
-		))
-	}
-
-	// Load the .env file
-	if err := godotenv.Load(absPath); err != nil {
-		return nil, nil, fmt.Errorf("failed to load config file: %w", err)
+	l := newLoader([]LoadOption{WithGlobalConfig(""), WithProjectConfig(absPath)})
+	l.profile = ""
+	r, warnings, err := l.resolver()
+	if err != nil {
+		return nil, warnings, err
 	}
 
-	cfg := &Config{}
-	if err := cfg.loadFromEnv(); err != nil {
+	cfg := &Config{Files: l.files}
+	if err := cfg.load(r); err != nil {
 		return nil, warnings, err
 	}
+	cfg.Effective = r.effective
 
 	return cfg, warnings, nil
 }
@@ -106,28 +157,72 @@ func LoadConfigFromEnv() (*Config, error) {
 
 // loadFromEnv populates the Config from environment variables.
 func (c *Config) loadFromEnv() error {
+	r := envResolver()
+	if err := c.load(r); err != nil {
+		return err
+	}
+	c.Effective = r.effective
+	return nil
+}
+
+// load populates the Config from the settings r resolves.
+func (c *Config) load(r *resolver) error {
+	// Secret backend
+	c.Secrets = loadSecretsConfig(r)
+
 	// Provider API Keys
-	c.VastAPIKey = os.Getenv("VAST_API_KEY")
-	c.LambdaAPIKey = os.Getenv("LAMBDA_API_KEY")
-	c.RunPodAPIKey = os.Getenv("RUNPOD_API_KEY")
-	c.CoreWeaveAPIKey = os.Getenv("COREWEAVE_API_KEY")
-	c.PaperspaceAPIKey = os.Getenv("PAPERSPACE_API_KEY")
+	c.VastAPIKey = r.get("VAST_API_KEY")
+	c.LambdaAPIKey = r.get("LAMBDA_API_KEY")
+	c.RunPodAPIKey = r.get("RUNPOD_API_KEY")
+	c.CoreWeaveAPIKey = r.get("COREWEAVE_API_KEY")
+	c.PaperspaceAPIKey = r.get("PAPERSPACE_API_KEY")
+
+	// Provider API endpoints
+	c.VastAPIURL = r.get("VAST_API_URL")
+	c.LambdaAPIURL = r.get("LAMBDA_API_URL")
+	c.RunPodAPIURL = r.get("RUNPOD_API_URL")
+	c.CoreWeaveAPIURL = r.get("COREWEAVE_API_URL")
+	c.PaperspaceAPIURL = r.get("PAPERSPACE_API_URL")
 
 	// WireGuard
-	c.WireGuardPrivateKey = os.Getenv("WIREGUARD_PRIVATE_KEY")
-	c.WireGuardPublicKey = os.Getenv("WIREGUARD_PUBLIC_KEY")
+	c.WireGuardPrivateKey = r.get("WIREGUARD_PRIVATE_KEY")
+	c.WireGuardPublicKey = r.get("WIREGUARD_PUBLIC_KEY")
 
 	// Preferences with defaults
-	c.DefaultTier = getEnvWithDefault("DEFAULT_TIER", "medium")
-	c.DefaultRegion = getEnvWithDefault("DEFAULT_REGION", "eu-west")
-	c.PreferSpot = getEnvBool("PREFER_SPOT", true)
-	c.DeadmanTimeoutHours = getEnvInt("DEADMAN_TIMEOUT_HOURS", 10)
+	c.DefaultTier = r.getWithDefault("DEFAULT_TIER", "medium")
+	c.DefaultRegion = r.getWithDefault("DEFAULT_REGION", "eu-west")
+	c.PreferSpot = r.getBool("PREFER_SPOT", true)
+	c.DeadmanTimeoutHours = r.getInt("DEADMAN_TIMEOUT_HOURS", 10)
+	c.DeadmanTermination = strings.ToLower(r.getWithDefault("DEADMAN_TERMINATION", "scoped"))
+	c.WatchdogTimeout = r.getDuration("WATCHDOG_TIMEOUT", DefaultWatchdogTimeout)
 
 	// Alerting
-	c.AlertWebhookURL = os.Getenv("ALERT_WEBHOOK_URL")
-	c.DailyBudgetEUR = getEnvFloat("DAILY_BUDGET_EUR", 20.0)
+	c.AlertWebhookURL = r.get("ALERT_WEBHOOK_URL")
+	c.DailyBudgetEUR = r.getFloat("DAILY_BUDGET_EUR", 20.0)
 
-	return nil
+	// Offer ranking
+	scoring, err := loadScoring(r)
+	if err != nil {
+		return err
+	}
+	c.Scoring = scoring
+
+	// Offer cache
+	c.OfferCacheTTL = r.getDuration("OFFER_CACHE_TTL", DefaultOfferCacheTTL)
+
+	// Offer filter
+	filter, err := offerexpr.Parse(r.get("OFFER_FILTER"))
+	if err != nil {
+		return fmt.Errorf("invalid OFFER_FILTER: %w", err)
+	}
+	c.DefaultFilter = filter
+
+	// State directory, defaulted by Load
+	if dir, ok := r.lookup("STATE_DIR"); ok {
+		c.StateDir = expandHome(dir)
+	}
+
+	return r.err
 }
 
 // Validate checks if the configuration is valid for operation.
@@ -136,6 +231,24 @@ func (c *Config) Validate() error {
 		return ErrNoProviderConfigured
 	}
 
+	// Validate provider API endpoints
+	endpoints := []struct{ name, value string }{
+		{"VAST_API_URL", c.VastAPIURL},
+		{"LAMBDA_API_URL", c.LambdaAPIURL},
+		{"RUNPOD_API_URL", c.RunPodAPIURL},
+		{"COREWEAVE_API_URL", c.CoreWeaveAPIURL},
+		{"PAPERSPACE_API_URL", c.PaperspaceAPIURL},
+	}
+	for _, e := range endpoints {
+		if e.value == "" {
+			continue
+		}
+		u, err := url.Parse(e.value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid %s: %q (must be an http or https URL)", e.name, e.value)
+		}
+	}
+
 	// Validate tier
 	tier := strings.ToLower(c.DefaultTier)
 	if tier != "small" && tier != "medium" && tier != "large" {
@@ -150,11 +263,33 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("DEADMAN_TIMEOUT_HOURS too large: %d (max 168 = 1 week)", c.DeadmanTimeoutHours)
 	}
 
+	// Validate deadman termination mode
+	switch c.DeadmanTermination {
+	case "", "scoped", "relay", "api-key", "shutdown":
+	default:
+		return fmt.Errorf("invalid DEADMAN_TERMINATION: %q (must be scoped, relay, api-key, or shutdown)", c.DeadmanTermination)
+	}
+
+	// Validate watchdog timeout
+	if c.WatchdogTimeout != 0 && c.WatchdogTimeout < MinWatchdogTimeout {
+		return fmt.Errorf("WATCHDOG_TIMEOUT must be 0 (disabled) or at least %s, got %s", MinWatchdogTimeout, c.WatchdogTimeout)
+	}
+
 	// Validate daily budget
 	if c.DailyBudgetEUR < 0 {
 		return fmt.Errorf("DAILY_BUDGET_EUR cannot be negative: %.2f", c.DailyBudgetEUR)
 	}
 
+	// Validate offer cache TTL
+	if c.OfferCacheTTL < 0 {
+		return fmt.Errorf("OFFER_CACHE_TTL cannot be negative: %s", c.OfferCacheTTL)
+	}
+
+	// Validate offer scoring
+	if err := c.Scoring.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -192,43 +327,3 @@ func (c *Config) ConfiguredProviders() []string {
 func (c *Config) HasWireGuardKeys() bool {
 	return c.WireGuardPrivateKey != "" && c.WireGuardPublicKey != ""
 }
-
-// Helper functions for environment variable parsing
-
-func getEnvWithDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
-
-func getEnvBool(key string, defaultValue bool) bool {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	value = strings.ToLower(strings.TrimSpace(value))
-	return value == "true" || value == "1" || value == "yes"
-}
-
-func getEnvInt(key string, defaultValue int) int {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	if i, err := strconv.Atoi(value); err == nil {
-		return i
-	}
-	return defaultValue
-}
-
-func getEnvFloat(key string, defaultValue float64) float64 {
-	value := os.Getenv(key)
-	if value == "" {
-		return defaultValue
-	}
-	if f, err := strconv.ParseFloat(value, 64); err == nil {
-		return f
-	}
-	return defaultValue
-}
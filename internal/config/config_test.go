@@ -0,0 +1,45 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigFromEnv_OfferFilter(t *testing.T) {
+	t.Setenv("OFFER_FILTER", `vram >= 48 && provider != "paperspace"`)
+
+	cfg, err := LoadConfigFromEnv()
+	if err != nil {
+		t.Fatalf("LoadConfigFromEnv() error = %v", err)
+	}
+	if cfg.DefaultFilter == nil {
+		t.Fatal("DefaultFilter = nil, want the OFFER_FILTER expression")
+	}
+	if got := cfg.DefaultFilter.String(); got != `vram >= 48 && provider != "paperspace"` {
+		t.Errorf("DefaultFilter = %q", got)
+	}
+}
+
+func TestLoadConfigFromEnv_NoOfferFilter(t *testing.T) {
+	t.Setenv("OFFER_FILTER", "")
+
+	cfg, err := LoadConfigFromEnv()
+	if err != nil {
+		t.Fatalf("LoadConfigFromEnv() error = %v", err)
+	}
+	if cfg.DefaultFilter != nil {
+		t.Errorf("DefaultFilter = %q, want nil", cfg.DefaultFilter)
+	}
+}
+
+func TestLoadConfigFromEnv_InvalidOfferFilter(t *testing.T) {
+	t.Setenv("OFFER_FILTER", "vram = 48")
+
+	_, err := LoadConfigFromEnv()
+	if err == nil {
+		t.Fatal("LoadConfigFromEnv() with invalid OFFER_FILTER error = nil")
+	}
+	if !strings.Contains(err.Error(), "OFFER_FILTER") {
+		t.Errorf("error = %v, want it to name OFFER_FILTER", err)
+	}
+}
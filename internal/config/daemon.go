@@ -0,0 +1,77 @@
+// Package config provides configuration and state management for spinup.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DaemonPIDFileName is the name of the file recording the supervisor daemon's PID
+// for the default session. Other sessions use ".spinup.<session>.daemon.pid".
+const DaemonPIDFileName = ".spinup.daemon.pid"
+
+// ErrNoDaemon is returned when no supervisor daemon PID is recorded.
+var ErrNoDaemon = errors.New("no supervisor daemon recorded")
+
+// daemonPIDPath returns the full path to this session's daemon PID file.
+func (m *StateManager) daemonPIDPath() string {
+	return m.SessionFilePath("daemon.pid")
+}
+
+// SessionFilePath returns the path of a per-session file in the state directory.
+// The default session uses ".spinup.<kind>"; other sessions use ".spinup.<session>.<kind>".
+func (m *StateManager) SessionFilePath(kind string) string {
+	if m.session == DefaultSessionName {
+		return filepath.Join(m.stateDir, ".spinup."+kind)
+	}
+	return filepath.Join(m.stateDir, ".spinup."+m.session+"."+kind)
+}
+
+// StateDir returns the directory holding the state file.
+func (m *StateManager) StateDir() string {
+	return m.stateDir
+}
+
+// WriteDaemonPID records the PID of the supervisor daemon owning this session.
+func (m *StateManager) WriteDaemonPID(pid int) error {
+	path := m.daemonPIDPath()
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, []byte(strconv.Itoa(pid)+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write daemon PID file: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename daemon PID file: %w", err)
+	}
+	return nil
+}
+
+// ReadDaemonPID returns the recorded supervisor daemon PID.
+// Returns ErrNoDaemon if no PID file exists.
+func (m *StateManager) ReadDaemonPID() (int, error) {
+	data, err := os.ReadFile(m.daemonPIDPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, ErrNoDaemon
+		}
+		return 0, fmt.Errorf("failed to read daemon PID file: %w", err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid daemon PID file contents: %q", strings.TrimSpace(string(data)))
+	}
+	return pid, nil
+}
+
+// ClearDaemonPID removes the daemon PID file. Removing a missing file is not an error.
+func (m *StateManager) ClearDaemonPID() error {
+	if err := os.Remove(m.daemonPIDPath()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove daemon PID file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateManager_DaemonPID(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm, err := NewStateManager(tmpDir)
+	if err != nil {
+		t.Fatalf("NewStateManager() error = %v", err)
+	}
+
+	if _, err := sm.ReadDaemonPID(); !errors.Is(err, ErrNoDaemon) {
+		t.Errorf("ReadDaemonPID() error = %v, want ErrNoDaemon", err)
+	}
+
+	if err := sm.WriteDaemonPID(4242); err != nil {
+		t.Fatalf("WriteDaemonPID() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(tmpDir, DaemonPIDFileName))
+	if err != nil {
+		t.Fatalf("PID file not created: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("PID file permissions = %o, want 0600", info.Mode().Perm())
+	}
+
+	pid, err := sm.ReadDaemonPID()
+	if err != nil {
+		t.Fatalf("ReadDaemonPID() error = %v", err)
+	}
+	if pid != 4242 {
+		t.Errorf("ReadDaemonPID() = %d, want 4242", pid)
+	}
+
+	if err := sm.ClearDaemonPID(); err != nil {
+		t.Fatalf("ClearDaemonPID() error = %v", err)
+	}
+	if _, err := sm.ReadDaemonPID(); !errors.Is(err, ErrNoDaemon) {
+		t.Errorf("ReadDaemonPID() after clear error = %v, want ErrNoDaemon", err)
+	}
+
+	// Clearing twice is not an error
+	if err := sm.ClearDaemonPID(); err != nil {
+		t.Errorf("ClearDaemonPID() on missing file error = %v", err)
+	}
+}
+
+func TestStateManager_ReadDaemonPID_Invalid(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm, _ := NewStateManager(tmpDir)
+
+	if err := os.WriteFile(filepath.Join(tmpDir, DaemonPIDFileName), []byte("not-a-pid\n"), 0600); err != nil {
+		t.Fatalf("failed to write PID file: %v", err)
+	}
+
+	if _, err := sm.ReadDaemonPID(); err == nil || errors.Is(err, ErrNoDaemon) {
+		t.Errorf("ReadDaemonPID() error = %v, want parse error", err)
+	}
+}
+
+func TestStateManager_DaemonPIDPerSession(t *testing.T) {
+	tmpDir := t.TempDir()
+	def, _ := NewStateManager(tmpDir)
+	chat, _ := NewStateManager(tmpDir, WithSession("chat"))
+
+	if err := def.WriteDaemonPID(100); err != nil {
+		t.Fatalf("WriteDaemonPID() error = %v", err)
+	}
+	if err := chat.WriteDaemonPID(200); err != nil {
+		t.Fatalf("WriteDaemonPID() error = %v", err)
+	}
+
+	if pid, _ := def.ReadDaemonPID(); pid != 100 {
+		t.Errorf("default session PID = %d, want 100", pid)
+	}
+	if pid, _ := chat.ReadDaemonPID(); pid != 200 {
+		t.Errorf("chat session PID = %d, want 200", pid)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, ".spinup.chat.daemon.pid")); err != nil {
+		t.Errorf("expected per-session PID file: %v", err)
+	}
+}
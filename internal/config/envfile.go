@@ -0,0 +1,200 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrConfigFileExists indicates spinup init would overwrite an existing .env file.
+var ErrConfigFileExists = errors.New("configuration file already exists")
+
+// providerKeyVars maps provider names to their API key variables, in the
+// order they are written to the .env file.
+var providerKeyVars = []struct {
+	provider string
+	envVar   string
+}{
+	{"vast", "VAST_API_KEY"},
+	{"lambda", "LAMBDA_API_KEY"},
+	{"runpod", "RUNPOD_API_KEY"},
+	{"coreweave", "COREWEAVE_API_KEY"},
+	{"paperspace", "PAPERSPACE_API_KEY"},
+}
+
+// SetAPIKey sets the API key of the named provider.
+func (c *Config) SetAPIKey(provider, key string) error {
+	switch provider {
+	case "vast":
+		c.VastAPIKey = key
+	case "lambda":
+		c.LambdaAPIKey = key
+	case "runpod":
+		c.RunPodAPIKey = key
+	case "coreweave":
+		c.CoreWeaveAPIKey = key
+	case "paperspace":
+		c.PaperspaceAPIKey = key
+	default:
+		return fmt.Errorf("unknown provider %q", provider)
+	}
+	return nil
+}
+
+// EnvFile holds the settings spinup init writes to a new .env file.
+type EnvFile struct {
+	// APIKeys maps provider names to their API keys.
+	APIKeys map[string]string
+
+	// WireGuardPrivateKey and WireGuardPublicKey are the client key pair.
+	// Empty keys make spinup generate a key pair for each deployment.
+	WireGuardPrivateKey string
+	WireGuardPublicKey  string
+
+	// DefaultTier is the model tier: small, medium or large.
+	DefaultTier string
+
+	// DeadmanTimeoutHours is the deadman switch timeout.
+	DeadmanTimeoutHours int
+
+	// Secrets stores the API keys and the WireGuard private key, which the
+	// file then references as secret://<provider> and secret://wireguard.
+	// Nil writes them to the file in plaintext.
+	Secrets SecretBackend
+
+	// SecretsConfig selects Secrets when the configuration is loaded. It is
+	// saved to GlobalConfig, as the .env file can't select the backend.
+	SecretsConfig SecretsConfig
+
+	// GlobalConfig is the global config file SecretsConfig is saved to.
+	// Empty uses GlobalConfigPath().
+	GlobalConfig string
+}
+
+// secretValue returns the value written for a secret: a reference to name
+// if the secrets are stored in a backend.
+func (e *EnvFile) secretValue(name, value string) string {
+	if e.Secrets == nil || value == "" {
+		return value
+	}
+	return SecretRef(name)
+}
+
+// Render returns the content of the .env file. Providers without an API
+// key are written with an empty value so they are easy to add later.
+func (e *EnvFile) Render() string {
+	var content strings.Builder
+
+	content.WriteString("# spinup Configuration\n")
+	content.WriteString("# Generated by spinup init\n")
+	content.WriteString("# IMPORTANT: Keep this file secure (permissions should be 0600)\n\n")
+
+	// Provider API Keys
+	content.WriteString("# Provider API Keys\n")
+	for _, p := range providerKeyVars {
+		content.WriteString(fmt.Sprintf("%s=%s\n", p.envVar, e.secretValue(p.provider, e.APIKeys[p.provider])))
+	}
+	content.WriteString("\n")
+
+	// Secret backend, configured in the global config file
+	if e.Secrets != nil {
+		content.WriteString("# Secret Store (" + e.Secrets.String() + ")\n")
+		content.WriteString("# The backend is selected in the global config file, see 'spinup config show'\n\n")
+	}
+
+	// WireGuard keys
+	content.WriteString("# WireGuard Keys\n")
+	content.WriteString(fmt.Sprintf("WIREGUARD_PRIVATE_KEY=%s\n", e.secretValue(WireGuardSecretName, e.WireGuardPrivateKey)))
+	content.WriteString(fmt.Sprintf("WIREGUARD_PUBLIC_KEY=%s\n", e.WireGuardPublicKey))
+	content.WriteString("\n")
+
+	// Preferences
+	content.WriteString("# Preferences\n")
+	content.WriteString(fmt.Sprintf("DEFAULT_TIER=%s\n", e.DefaultTier))
+	content.WriteString("DEFAULT_REGION=eu-west\n")
+	content.WriteString("PREFER_SPOT=true\n")
+	content.WriteString(fmt.Sprintf("DEADMAN_TIMEOUT_HOURS=%d\n", e.DeadmanTimeoutHours))
+	content.WriteString("\n")
+
+	// Alerting (optional, leave empty)
+	content.WriteString("# Alerting (optional)\n")
+	content.WriteString("ALERT_WEBHOOK_URL=\n")
+	content.WriteString("DAILY_BUDGET_EUR=20\n")
+
+	return content.String()
+}
+
+// Write writes the .env file to path with 0600 permissions. An existing file
+// is only replaced if overwrite is true; otherwise ErrConfigFileExists is
+// returned. With Secrets, the secrets are stored and SecretsConfig is saved
+// to the global config file first.
+func (e *EnvFile) Write(path string, overwrite bool) error {
+	if path == "" {
+		path = DefaultEnvPath
+	}
+	if !overwrite {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%w: %s", ErrConfigFileExists, path)
+		}
+	}
+	if e.Secrets != nil {
+		global := e.GlobalConfig
+		if global == "" {
+			global = GlobalConfigPath()
+		}
+		if err := e.SecretsConfig.saveGlobal(global); err != nil {
+			return fmt.Errorf("failed to save the secret backend: %w", err)
+		}
+	}
+	if err := e.storeSecrets(); err != nil {
+		return err
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if !overwrite {
+		flags |= os.O_EXCL
+	}
+
+	f, err := os.OpenFile(path, flags, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("%w: %s", ErrConfigFileExists, path)
+		}
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	// An overwritten file keeps its mode; tighten it before writing keys
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to set permissions of %s: %w", path, err)
+	}
+	if _, err := f.WriteString(e.Render()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// storeSecrets stores the API keys and the WireGuard private key in Secrets.
+func (e *EnvFile) storeSecrets() error {
+	if e.Secrets == nil {
+		return nil
+	}
+	for _, p := range providerKeyVars {
+		if key := e.APIKeys[p.provider]; key != "" {
+			if err := e.Secrets.Set(p.provider, key); err != nil {
+				return fmt.Errorf("failed to store %s: %w", p.envVar, err)
+			}
+		}
+	}
+	if e.WireGuardPrivateKey != "" {
+		if err := e.Secrets.Set(WireGuardSecretName, e.WireGuardPrivateKey); err != nil {
+			return fmt.Errorf("failed to store WIREGUARD_PRIVATE_KEY: %w", err)
+		}
+	}
+	return nil
+}
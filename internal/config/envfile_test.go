@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/joho/godotenv"
+)
+
+func TestEnvFile_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	env := &EnvFile{
+		APIKeys:             map[string]string{"vast": "vast-key", "runpod": "runpod-key"},
+		WireGuardPrivateKey: "private",
+		WireGuardPublicKey:  "public",
+		DefaultTier:         "large",
+		DeadmanTimeoutHours: 6,
+	}
+	if err := env.Write(path, false); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("mode = %04o, want 0600", mode)
+	}
+
+	// Read the file without touching the process environment
+	vars, err := godotenv.Read(path)
+	if err != nil {
+		t.Fatalf("godotenv.Read() error = %v", err)
+	}
+	want := map[string]string{
+		"VAST_API_KEY":          "vast-key",
+		"RUNPOD_API_KEY":        "runpod-key",
+		"LAMBDA_API_KEY":        "",
+		"WIREGUARD_PRIVATE_KEY": "private",
+		"WIREGUARD_PUBLIC_KEY":  "public",
+		"DEFAULT_TIER":          "large",
+		"DEADMAN_TIMEOUT_HOURS": "6",
+	}
+	for key, value := range want {
+		got, ok := vars[key]
+		if !ok {
+			t.Errorf("%s missing", key)
+			continue
+		}
+		if got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestEnvFile_WriteExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("VAST_API_KEY=old\n"), 0o644); err != nil {
+		t.Fatalf("failed to write .env: %v", err)
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatalf("failed to chmod .env: %v", err)
+	}
+
+	env := &EnvFile{APIKeys: map[string]string{"vast": "new"}, DefaultTier: "medium", DeadmanTimeoutHours: 10}
+
+	err := env.Write(path, false)
+	if !errors.Is(err, ErrConfigFileExists) {
+		t.Fatalf("Write() error = %v, want ErrConfigFileExists", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "VAST_API_KEY=old\n" {
+		t.Errorf("existing file was modified: %q", data)
+	}
+
+	if err := env.Write(path, true); err != nil {
+		t.Fatalf("Write() with overwrite error = %v", err)
+	}
+	vars, err := godotenv.Read(path)
+	if err != nil {
+		t.Fatalf("godotenv.Read() error = %v", err)
+	}
+	if vars["VAST_API_KEY"] != "new" {
+		t.Errorf("VAST_API_KEY = %q, want new", vars["VAST_API_KEY"])
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("mode after overwrite = %04o, want 0600", mode)
+	}
+}
+
+func TestConfig_SetAPIKey(t *testing.T) {
+	for _, p := range providerKeyVars {
+		cfg := &Config{}
+		if err := cfg.SetAPIKey(p.provider, "key"); err != nil {
+			t.Errorf("SetAPIKey(%s) error = %v", p.provider, err)
+			continue
+		}
+		if got := cfg.ConfiguredProviders(); len(got) != 1 || got[0] != p.provider {
+			t.Errorf("SetAPIKey(%s): ConfiguredProviders() = %v", p.provider, got)
+		}
+	}
+
+	cfg := &Config{}
+	if err := cfg.SetAPIKey("azure", "key"); err == nil {
+		t.Error("SetAPIKey(azure) expected error")
+	}
+}
@@ -0,0 +1,699 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+)
+
+// Scope identifies the layer a setting came from. Later scopes override
+// earlier ones: defaults, the global config file, the selected profile in
+// it, the project .env file, environment variables, and flags.
+type Scope string
+
+const (
+	// ScopeDefault is a built-in default.
+	ScopeDefault Scope = "default"
+	// ScopeGlobal is the global config file, ~/.config/spinup/config.toml.
+	ScopeGlobal Scope = "global"
+	// ScopeProfile is a [profiles.<name>] table in the global config file.
+	ScopeProfile Scope = "profile"
+	// ScopeProject is the .env file in the current directory.
+	ScopeProject Scope = "project"
+	// ScopeEnv is the process environment.
+	ScopeEnv Scope = "env"
+	// ScopeFlag is a command-line flag.
+	ScopeFlag Scope = "flag"
+)
+
+// ProfileEnv is the environment variable selecting a profile when no
+// --profile is given.
+const ProfileEnv = "SPINUP_PROFILE"
+
+// profileTablePrefix prefixes the names of profile tables in the global config file.
+const profileTablePrefix = "profiles."
+
+// ErrUnknownProfile indicates the selected profile is not defined in the
+// global config file.
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// ErrProjectSetting indicates the project .env sets a setting that is only
+// accepted from the global config file, the environment or flags.
+var ErrProjectSetting = errors.New("setting not allowed in the project .env")
+
+// Origin describes where a setting's value came from.
+type Origin struct {
+	Scope Scope
+
+	// Path is the file of the global, profile and project scopes.
+	Path string
+
+	// Name is the profile or flag name.
+	Name string
+}
+
+// String returns the origin as shown by 'spinup config show', e.g.
+// "profile work (/home/me/.config/spinup/config.toml)".
+func (o Origin) String() string {
+	switch o.Scope {
+	case ScopeProfile:
+		return fmt.Sprintf("profile %s (%s)", o.Name, o.Path)
+	case ScopeGlobal, ScopeProject:
+		return fmt.Sprintf("%s (%s)", o.Scope, o.Path)
+	case ScopeFlag:
+		return "flag " + o.Name
+	}
+	return string(o.Scope)
+}
+
+// Value is the effective value of one setting.
+type Value struct {
+	// Key is the setting's environment variable name, e.g. DEFAULT_TIER.
+	Key string
+
+	// Value is the raw value, before parsing.
+	Value string
+
+	// Origin is the layer the value came from.
+	Origin Origin
+}
+
+// IsSecret returns true if the setting holds a credential that should not
+// be displayed.
+func IsSecret(key string) bool {
+	return strings.HasSuffix(key, "_API_KEY") || key == "WIREGUARD_PRIVATE_KEY"
+}
+
+// GlobalConfigPath returns the path of the global config file:
+// $XDG_CONFIG_HOME/spinup/config.toml, or ~/.config/spinup/config.toml.
+// It returns an empty string if the home directory is unknown.
+func GlobalConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "spinup", "config.toml")
+}
+
+// DefaultStateDir returns the default state directory:
+// $XDG_STATE_HOME/spinup, or ~/.local/state/spinup. It returns an empty
+// string (the current directory) if the home directory is unknown.
+func DefaultStateDir() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "spinup")
+}
+
+// LoadOption is a functional option for Load.
+type LoadOption func(*loader)
+
+// WithGlobalConfig sets the global config file instead of GlobalConfigPath().
+// An empty path skips the global config.
+func WithGlobalConfig(path string) LoadOption {
+	return func(l *loader) {
+		l.globalPath = path
+	}
+}
+
+// WithProjectConfig sets the project .env file instead of DefaultEnvPath.
+// An empty path skips the project config.
+func WithProjectConfig(path string) LoadOption {
+	return func(l *loader) {
+		l.projectPath = path
+	}
+}
+
+// WithProfile selects a profile from the global config file. An empty name
+// selects the profile named by SPINUP_PROFILE, if any.
+func WithProfile(name string) LoadOption {
+	return func(l *loader) {
+		if name != "" {
+			l.profile = name
+		}
+	}
+}
+
+// WithFlag sets a setting from a command-line flag, overriding all other
+// layers. An empty value leaves the setting to the other layers.
+func WithFlag(key, value, flag string) LoadOption {
+	return func(l *loader) {
+		if value != "" {
+			l.flags = append(l.flags, layer{
+				origin: Origin{Scope: ScopeFlag, Name: flag},
+				values: map[string]string{key: value},
+			})
+		}
+	}
+}
+
+// WithPassphrase sets the function asked for the passphrase of the secrets
+// file when a setting references a secret and neither SECRETS_KEYFILE nor
+// SPINUP_SECRETS_PASSPHRASE is set.
+func WithPassphrase(passphrase PassphraseFunc) LoadOption {
+	return func(l *loader) {
+		l.passphrase = passphrase
+	}
+}
+
+// Load loads the layered configuration. Settings come from the built-in
+// defaults, the global config file (see GlobalConfigPath), the selected
+// profile in it, the project .env file, environment variables and flags,
+// each overriding the ones before. Missing files are skipped. Settings
+// referencing a secret, e.g. VAST_API_KEY=secret://vast, are read from the
+// secret backend.
+// Returns the config and any warnings (e.g., permission issues or unknown
+// settings) as a slice of strings.
+func Load(opts ...LoadOption) (*Config, []string, error) {
+	l := newLoader(opts)
+	r, warnings, err := l.resolver()
+	if err != nil {
+		return nil, warnings, err
+	}
+
+	cfg := &Config{Profile: l.profile, Files: l.files}
+	if err := cfg.load(r); err != nil {
+		return nil, warnings, err
+	}
+	cfg.StateDir = l.stateDir(r)
+	cfg.Effective = r.effective
+
+	warnings = append(warnings, l.unknownSettings(r)...)
+	return cfg, warnings, nil
+}
+
+// ResolveStateDir returns the state directory Load would configure, without
+// loading the rest of the configuration.
+func ResolveStateDir(opts ...LoadOption) (string, error) {
+	l := newLoader(opts)
+	r, _, err := l.resolver()
+	if err != nil {
+		return "", err
+	}
+	dir := l.stateDir(r)
+	if r.err != nil {
+		return "", r.err
+	}
+	return dir, nil
+}
+
+// loader builds the layers of a configuration.
+type loader struct {
+	globalPath  string
+	projectPath string
+	profile     string
+	flags       []layer
+	passphrase  PassphraseFunc
+
+	// files are the config files that were found, lowest precedence first
+	files []string
+	// tomlLayers are the layers read from TOML files, checked for unknown settings
+	tomlLayers []layer
+}
+
+func newLoader(opts []LoadOption) *loader {
+	l := &loader{
+		globalPath:  GlobalConfigPath(),
+		projectPath: DefaultEnvPath,
+		profile:     os.Getenv(ProfileEnv),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// resolver reads the config files and returns a resolver over all layers.
+func (l *loader) resolver() (*resolver, []string, error) {
+	var warnings []string
+	r := &resolver{seen: make(map[string]int), passphrase: l.passphrase}
+
+	profiles := map[string]map[string]string{}
+	if l.globalPath != "" {
+		tables, warning, err := readTOMLFile(l.globalPath)
+		if err != nil {
+			return nil, warnings, err
+		}
+		if tables != nil {
+			l.files = append(l.files, l.globalPath)
+			if warning != "" {
+				warnings = append(warnings, warning)
+			}
+
+			global := layer{origin: Origin{Scope: ScopeGlobal, Path: l.globalPath}, values: settingKeys(tables[""])}
+			r.layers = append(r.layers, global)
+			l.tomlLayers = append(l.tomlLayers, global)
+
+			names := make([]string, 0, len(tables))
+			for name := range tables {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				switch {
+				case name == "":
+				case strings.HasPrefix(name, profileTablePrefix):
+					profiles[strings.TrimPrefix(name, profileTablePrefix)] = tables[name]
+				case name == "profiles":
+					// Only holds the profile tables
+				default:
+					warnings = append(warnings, fmt.Sprintf("unknown table [%s] in %s", name, l.globalPath))
+				}
+			}
+		}
+	}
+
+	if l.profile != "" {
+		values, ok := profiles[l.profile]
+		if !ok {
+			return nil, warnings, unknownProfileError(l.profile, l.globalPath, profiles)
+		}
+		profile := layer{
+			origin: Origin{Scope: ScopeProfile, Path: l.globalPath, Name: l.profile},
+			values: settingKeys(values),
+		}
+		r.layers = append(r.layers, profile)
+		l.tomlLayers = append(l.tomlLayers, profile)
+	}
+
+	if l.projectPath != "" {
+		path, err := filepath.Abs(l.projectPath)
+		if err != nil {
+			return nil, warnings, fmt.Errorf("failed to resolve config path: %w", err)
+		}
+		values, warning, err := readEnvFile(path)
+		if err != nil {
+			return nil, warnings, err
+		}
+		if values != nil {
+			if err := checkProjectSettings(path, values); err != nil {
+				return nil, warnings, err
+			}
+			l.files = append(l.files, path)
+			if warning != "" {
+				warnings = append(warnings, warning)
+			}
+			r.layers = append(r.layers, layer{origin: Origin{Scope: ScopeProject, Path: path}, values: values})
+		}
+	}
+
+	r.layers = append(r.layers, layer{origin: Origin{Scope: ScopeEnv}})
+	r.layers = append(r.layers, l.flags...)
+
+	return r, warnings, nil
+}
+
+// stateDir returns the state directory: STATE_DIR if set, the current
+// directory if it holds a state file written before state moved to the
+// XDG state directory (so running instances stay tracked), or
+// DefaultStateDir().
+func (l *loader) stateDir(r *resolver) string {
+	if dir, ok := r.lookup("STATE_DIR"); ok {
+		return expandHome(dir)
+	}
+
+	if cwd, err := os.Getwd(); err == nil {
+		legacy := filepath.Join(cwd, StateFileName)
+		if _, err := os.Stat(legacy); err == nil {
+			r.record("STATE_DIR", cwd, Origin{Scope: ScopeProject, Path: legacy})
+			return cwd
+		}
+	}
+
+	dir := DefaultStateDir()
+	r.record("STATE_DIR", dir, Origin{Scope: ScopeDefault})
+	return dir
+}
+
+// unknownSettings returns a warning for every setting in a TOML file that
+// the configuration doesn't use, e.g. a misspelled key.
+func (l *loader) unknownSettings(r *resolver) []string {
+	var warnings []string
+	for _, layer := range l.tomlLayers {
+		keys := make([]string, 0, len(layer.values))
+		for key := range layer.values {
+			if _, ok := r.seen[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			warnings = append(warnings, fmt.Sprintf("unknown setting %s in %s", strings.ToLower(key), layer.origin))
+		}
+	}
+	return warnings
+}
+
+// projectRestricted reports whether a project .env can't set key. The
+// file may come with a cloned repository, so it can't pick the secret
+// backend, which would run its commands or read its files, nor a provider
+// API endpoint, which would be sent the API key.
+func projectRestricted(key string) bool {
+	return secretsSettings[key] || strings.HasSuffix(key, "_API_URL")
+}
+
+// checkProjectSettings returns an error if the project .env at path sets a
+// setting it isn't allowed to. Empty values count as unset.
+func checkProjectSettings(path string, values map[string]string) error {
+	var keys []string
+	for key, value := range values {
+		if value != "" && projectRestricted(key) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	return fmt.Errorf("%w: %s sets %s; set it in the global config file, the environment or a flag instead",
+		ErrProjectSetting, path, strings.Join(keys, ", "))
+}
+
+// unknownProfileError describes a missing profile and lists the defined ones.
+func unknownProfileError(name, path string, profiles map[string]map[string]string) error {
+	if len(profiles) == 0 {
+		if path == "" {
+			return fmt.Errorf("%w %q: no global config file", ErrUnknownProfile, name)
+		}
+		return fmt.Errorf("%w %q: %s defines no [profiles.<name>] tables", ErrUnknownProfile, name, path)
+	}
+
+	names := make([]string, 0, len(profiles))
+	for n := range profiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("%w %q (defined in %s: %s)", ErrUnknownProfile, name, path, strings.Join(names, ", "))
+}
+
+// readTOMLFile parses a TOML config file. It returns nil tables if the file
+// doesn't exist, and a warning if its permissions are insecure.
+func readTOMLFile(path string) (map[string]map[string]string, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc map[string]any
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	tables := make(map[string]map[string]string)
+	if err := flattenTOMLTable(tables, "", doc); err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return tables, permissionWarning(path), nil
+}
+
+// flattenTOMLTable adds the values of a decoded TOML table and of the
+// tables nested in it to tables, by dotted table name; the top-level
+// table is "". Values are converted to the strings settings are parsed from.
+func flattenTOMLTable(tables map[string]map[string]string, name string, values map[string]any) error {
+	if tables[name] == nil {
+		tables[name] = make(map[string]string)
+	}
+	for key, value := range values {
+		path := key
+		if name != "" {
+			path = name + "." + key
+		}
+		if table, ok := value.(map[string]any); ok {
+			if err := flattenTOMLTable(tables, path, table); err != nil {
+				return err
+			}
+			continue
+		}
+		s, err := tomlSettingValue(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		tables[name][key] = s
+	}
+	return nil
+}
+
+// tomlSettingValue converts a TOML value to a setting's string form.
+// Arrays are joined with commas, the format of list settings such as
+// PREFERRED_REGIONS.
+func tomlSettingValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case []any, map[string]any:
+				return "", errors.New("nested arrays and tables in arrays are not supported")
+			}
+			s, err := tomlSettingValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	case []map[string]any:
+		return "", errors.New("arrays of tables are not supported")
+	}
+	return "", fmt.Errorf("unsupported value %v (use a string, number, boolean or array)", value)
+}
+
+// readEnvFile reads a .env file without changing the process environment.
+// It returns nil values if the file doesn't exist, and a warning if its
+// permissions are insecure.
+func readEnvFile(path string) (map[string]string, string, error) {
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("failed to stat config file: %w", err)
+	}
+
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load config file: %w", err)
+	}
+	return values, permissionWarning(path), nil
+}
+
+// permissionWarning returns a warning if a config file isn't private to its owner.
+func permissionWarning(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		return fmt.Sprintf("config file %s has permissions %04o, should be 0600 for security", path, mode)
+	}
+	return ""
+}
+
+// settingKeys maps TOML keys (e.g. default_tier) to setting names (DEFAULT_TIER).
+func settingKeys(values map[string]string) map[string]string {
+	keys := make(map[string]string, len(values))
+	for key, value := range values {
+		keys[strings.ToUpper(key)] = value
+	}
+	return keys
+}
+
+// expandHome replaces a leading ~/ with the home directory.
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// layer is one source of settings.
+type layer struct {
+	origin Origin
+	values map[string]string // nil reads the process environment
+}
+
+// lookup returns the layer's value for key. Empty values count as unset.
+func (l layer) lookup(key string) (string, bool) {
+	var value string
+	var ok bool
+	if l.values == nil {
+		value, ok = os.LookupEnv(key)
+	} else {
+		value, ok = l.values[key]
+	}
+	return value, ok && value != ""
+}
+
+// resolver looks settings up in layers and records the effective value of
+// every setting it is asked for.
+type resolver struct {
+	layers    []layer // lowest precedence first
+	effective []Value
+	seen      map[string]int // key -> index in effective
+
+	// passphrase unlocks the secrets file; secrets is opened by the first
+	// secret reference
+	passphrase PassphraseFunc
+	secrets    SecretBackend
+	secretsErr error
+
+	// err is the first error resolving a secret reference, kept here as
+	// the getters don't return errors
+	err error
+}
+
+// envResolver returns a resolver over the process environment only.
+func envResolver() *resolver {
+	return &resolver{
+		layers: []layer{{origin: Origin{Scope: ScopeEnv}}},
+		seen:   make(map[string]int),
+	}
+}
+
+// lookup returns the value of key from the highest layer that sets it. A
+// secret reference is replaced by the secret, while the effective value
+// keeps the reference.
+func (r *resolver) lookup(key string) (string, bool) {
+	for i := len(r.layers) - 1; i >= 0; i-- {
+		if value, ok := r.layers[i].lookup(key); ok {
+			r.record(key, value, r.layers[i].origin)
+			if IsSecretRef(value) {
+				return r.resolveSecret(key, value), true
+			}
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// resolveSecret returns the secret a setting references, or an empty
+// string after recording the error in r.err.
+func (r *resolver) resolveSecret(key, ref string) string {
+	if r.err != nil {
+		return ""
+	}
+
+	name, err := parseSecretRef(ref)
+	if err == nil && secretsSettings[key] {
+		err = fmt.Errorf("%s configures the secret backend and can't reference a secret", key)
+	}
+	var backend SecretBackend
+	if err == nil {
+		backend, err = r.secretBackend()
+	}
+	var value string
+	if err == nil {
+		value, err = backend.Get(name)
+	}
+	if err != nil {
+		r.err = fmt.Errorf("%s: %w", key, err)
+		return ""
+	}
+	return value
+}
+
+// secretBackend opens the configured secret backend once.
+func (r *resolver) secretBackend() (SecretBackend, error) {
+	if r.secrets == nil && r.secretsErr == nil {
+		r.secrets, r.secretsErr = loadSecretsConfig(r).Open(r.passphrase)
+	}
+	return r.secrets, r.secretsErr
+}
+
+// record sets the effective value of key.
+func (r *resolver) record(key, value string, origin Origin) {
+	v := Value{Key: key, Value: value, Origin: origin}
+	if i, ok := r.seen[key]; ok {
+		r.effective[i] = v
+		return
+	}
+	r.seen[key] = len(r.effective)
+	r.effective = append(r.effective, v)
+}
+
+// get returns the value of key, or an empty string if no layer sets it.
+func (r *resolver) get(key string) string {
+	return r.getWithDefault(key, "")
+}
+
+func (r *resolver) getWithDefault(key, defaultValue string) string {
+	if value, ok := r.lookup(key); ok {
+		return value
+	}
+	r.record(key, defaultValue, Origin{Scope: ScopeDefault})
+	return defaultValue
+}
+
+func (r *resolver) getBool(key string, defaultValue bool) bool {
+	value, ok := r.lookup(key)
+	if !ok {
+		r.record(key, strconv.FormatBool(defaultValue), Origin{Scope: ScopeDefault})
+		return defaultValue
+	}
+	value = strings.ToLower(strings.TrimSpace(value))
+	return value == "true" || value == "1" || value == "yes"
+}
+
+func (r *resolver) getInt(key string, defaultValue int) int {
+	value, ok := r.lookup(key)
+	if !ok {
+		r.record(key, strconv.Itoa(defaultValue), Origin{Scope: ScopeDefault})
+		return defaultValue
+	}
+	if i, err := strconv.Atoi(value); err == nil {
+		return i
+	}
+	return defaultValue
+}
+
+func (r *resolver) getFloat(key string, defaultValue float64) float64 {
+	value, ok := r.lookup(key)
+	if !ok {
+		r.record(key, strconv.FormatFloat(defaultValue, 'g', -1, 64), Origin{Scope: ScopeDefault})
+		return defaultValue
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+	return defaultValue
+}
+
+func (r *resolver) getDuration(key string, defaultValue time.Duration) time.Duration {
+	value, ok := r.lookup(key)
+	if !ok {
+		r.record(key, defaultValue.String(), Origin{Scope: ScopeDefault})
+		return defaultValue
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return d
+	}
+	return defaultValue
+}
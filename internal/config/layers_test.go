@@ -0,0 +1,432 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearSettingsEnv unsets the variables the layering tests set in files, so
+// that the process environment doesn't override them.
+func clearSettingsEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		"VAST_API_KEY", "LAMBDA_API_KEY", "RUNPOD_API_KEY", "COREWEAVE_API_KEY", "PAPERSPACE_API_KEY",
+		"DEFAULT_TIER", "DEFAULT_REGION", "DEADMAN_TIMEOUT_HOURS", "DEADMAN_TERMINATION", "WATCHDOG_TIMEOUT", "DAILY_BUDGET_EUR",
+		"PREFERRED_REGIONS", "OFFER_FILTER", "STATE_DIR", ProfileEnv,
+		"VAST_API_URL", "LAMBDA_API_URL", "RUNPOD_API_URL", "COREWEAVE_API_URL", "PAPERSPACE_API_URL",
+		"SECRET_BACKEND", "SECRETS_FILE", "SECRETS_KEYFILE", "SECRET_COMMAND", "SECRET_STORE_COMMAND", SecretsPassphraseEnv,
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+// writeConfigFile writes a config file with 0600 permissions.
+func writeConfigFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// effective returns the effective value of key.
+func effective(t *testing.T, cfg *Config, key string) Value {
+	t.Helper()
+	for _, v := range cfg.Effective {
+		if v.Key == key {
+			return v
+		}
+	}
+	t.Fatalf("%s missing from Effective", key)
+	return Value{}
+}
+
+func TestLoad_Layers(t *testing.T) {
+	clearSettingsEnv(t)
+	dir := t.TempDir()
+
+	global := filepath.Join(dir, "config.toml")
+	writeConfigFile(t, global, `
+vast_api_key = "global-vast"
+default_tier = "small"
+default_region = "us-west"
+deadman_timeout_hours = 4
+preferred_regions = ["eu-west", "eu-central"]
+
+[profiles.work]
+default_tier = "large"
+daily_budget_eur = 100
+`)
+	project := filepath.Join(dir, ".env")
+	writeConfigFile(t, project, "DEFAULT_REGION=eu-north\nDEADMAN_TIMEOUT_HOURS=6\n")
+	t.Setenv("DEADMAN_TIMEOUT_HOURS", "8")
+
+	cfg, warnings, err := Load(
+		WithGlobalConfig(global),
+		WithProjectConfig(project),
+		WithProfile("work"),
+		WithFlag("STATE_DIR", filepath.Join(dir, "state"), "--state-dir"),
+		WithFlag("OFFER_FILTER", "", "--filter"),
+	)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %v, want none", warnings)
+	}
+
+	if cfg.VastAPIKey != "global-vast" || cfg.DefaultTier != "large" || cfg.DefaultRegion != "eu-north" ||
+		cfg.DeadmanTimeoutHours != 8 || cfg.DailyBudgetEUR != 100 || cfg.StateDir != filepath.Join(dir, "state") {
+		t.Errorf("Load() = %+v", cfg)
+	}
+	if len(cfg.Scoring.PreferredRegions) != 2 || cfg.Scoring.PreferredRegions[1] != "eu-central" {
+		t.Errorf("PreferredRegions = %v, want the global array", cfg.Scoring.PreferredRegions)
+	}
+	if cfg.Profile != "work" {
+		t.Errorf("Profile = %q, want work", cfg.Profile)
+	}
+	if len(cfg.Files) != 2 || cfg.Files[0] != global || cfg.Files[1] != project {
+		t.Errorf("Files = %v, want [%s %s]", cfg.Files, global, project)
+	}
+
+	tests := []struct {
+		key    string
+		value  string
+		origin string
+	}{
+		{"VAST_API_KEY", "global-vast", "global (" + global + ")"},
+		{"DEFAULT_TIER", "large", "profile work (" + global + ")"},
+		{"DEFAULT_REGION", "eu-north", "project (" + project + ")"},
+		{"DEADMAN_TIMEOUT_HOURS", "8", "env"},
+		{"STATE_DIR", filepath.Join(dir, "state"), "flag --state-dir"},
+		{"OFFER_FILTER", "", "default"},
+		{"PREFER_SPOT", "true", "default"},
+		{"OFFER_CACHE_TTL", "5m0s", "default"},
+	}
+	for _, tt := range tests {
+		v := effective(t, cfg, tt.key)
+		if v.Value != tt.value || v.Origin.String() != tt.origin {
+			t.Errorf("%s = %q from %s, want %q from %s", tt.key, v.Value, v.Origin, tt.value, tt.origin)
+		}
+	}
+}
+
+func TestLoad_ProfileFromEnv(t *testing.T) {
+	clearSettingsEnv(t)
+	global := filepath.Join(t.TempDir(), "config.toml")
+	writeConfigFile(t, global, "[profiles.home]\nvast_api_key = \"home-vast\"\n")
+
+	t.Setenv(ProfileEnv, "home")
+	cfg, _, err := Load(WithGlobalConfig(global), WithProjectConfig(""))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Profile != "home" || cfg.VastAPIKey != "home-vast" {
+		t.Errorf("Load() profile = %q, VastAPIKey = %q, want home-vast from profile home", cfg.Profile, cfg.VastAPIKey)
+	}
+
+	// --profile wins over SPINUP_PROFILE
+	if _, _, err := Load(WithGlobalConfig(global), WithProjectConfig(""), WithProfile("work")); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Load() with unknown profile error = %v, want ErrUnknownProfile", err)
+	}
+}
+
+func TestLoad_UnknownProfile(t *testing.T) {
+	clearSettingsEnv(t)
+	dir := t.TempDir()
+	global := filepath.Join(dir, "config.toml")
+	writeConfigFile(t, global, "[profiles.home]\n[profiles.work]\n")
+
+	_, _, err := Load(WithGlobalConfig(global), WithProjectConfig(""), WithProfile("play"))
+	if !errors.Is(err, ErrUnknownProfile) || !strings.Contains(err.Error(), "home, work") {
+		t.Errorf("Load() error = %v, want ErrUnknownProfile listing the profiles", err)
+	}
+
+	_, _, err = Load(WithGlobalConfig(filepath.Join(dir, "missing.toml")), WithProjectConfig(""), WithProfile("play"))
+	if !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("Load() without global config error = %v, want ErrUnknownProfile", err)
+	}
+}
+
+func TestLoad_ProjectAPIURL(t *testing.T) {
+	clearSettingsEnv(t)
+	dir := t.TempDir()
+	global := filepath.Join(dir, "config.toml")
+	project := filepath.Join(dir, ".env")
+
+	// A .env from a cloned repository can't send the API keys elsewhere
+	writeConfigFile(t, project, "VAST_API_KEY=k\nVAST_API_URL=https://attacker.example\nLAMBDA_API_URL=http://127.0.0.1:8787\n")
+	_, _, err := Load(WithGlobalConfig(global), WithProjectConfig(project))
+	if !errors.Is(err, ErrProjectSetting) || !strings.Contains(err.Error(), "LAMBDA_API_URL, VAST_API_URL") {
+		t.Errorf("Load() error = %v, want ErrProjectSetting naming both URLs", err)
+	}
+
+	// The global config file and the environment may set them
+	writeConfigFile(t, global, "vast_api_url = \"http://127.0.0.1:8787/vast\"\n")
+	writeConfigFile(t, project, "VAST_API_KEY=k\n")
+	t.Setenv("LAMBDA_API_URL", "http://127.0.0.1:8787/lambda")
+	cfg, _, err := Load(WithGlobalConfig(global), WithProjectConfig(project))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.VastAPIURL != "http://127.0.0.1:8787/vast" || cfg.LambdaAPIURL != "http://127.0.0.1:8787/lambda" {
+		t.Errorf("VastAPIURL = %q, LambdaAPIURL = %q, want the global and env URLs", cfg.VastAPIURL, cfg.LambdaAPIURL)
+	}
+}
+
+func TestLoad_Warnings(t *testing.T) {
+	clearSettingsEnv(t)
+	dir := t.TempDir()
+	global := filepath.Join(dir, "config.toml")
+	writeConfigFile(t, global, "vast_api_key = \"k\"\ndefault_teir = \"large\"\n\n[profile.work]\n")
+	if err := os.Chmod(global, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, warnings, err := Load(WithGlobalConfig(global), WithProjectConfig(""))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	got := strings.Join(warnings, "\n")
+	for _, want := range []string{"permissions 0644", "unknown table [profile.work]", "unknown setting default_teir"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("warnings = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestLoad_InvalidGlobalConfig(t *testing.T) {
+	clearSettingsEnv(t)
+	global := filepath.Join(t.TempDir(), "config.toml")
+	writeConfigFile(t, global, "default_tier = large\n")
+
+	_, _, err := Load(WithGlobalConfig(global), WithProjectConfig(""))
+	if err == nil || !strings.Contains(err.Error(), global) || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Load() error = %v, want the file and line", err)
+	}
+}
+
+func TestReadTOMLFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeConfigFile(t, path, `vast_api_key = "vast-key"
+deadman_timeout_hours = 12
+daily_budget_eur = 42.5
+prefer_spot = false
+preferred_regions = ["eu-west", 'eu-central']
+ssh_note = """
+two lines"""
+
+[profiles.work]
+runpod_api_key = "runpod-key"
+
+[profiles."home"]
+default_region = "us-east"
+`)
+
+	tables, _, err := readTOMLFile(path)
+	if err != nil {
+		t.Fatalf("readTOMLFile() error = %v", err)
+	}
+	want := map[string]map[string]string{
+		"": {
+			"vast_api_key":          "vast-key",
+			"deadman_timeout_hours": "12",
+			"daily_budget_eur":      "42.5",
+			"prefer_spot":           "false",
+			"preferred_regions":     "eu-west,eu-central",
+			"ssh_note":              "two lines",
+		},
+		"profiles":      {},
+		"profiles.work": {"runpod_api_key": "runpod-key"},
+		"profiles.home": {"default_region": "us-east"},
+	}
+	if len(tables) != len(want) {
+		t.Errorf("readTOMLFile() returned tables %v, want %v", tables, want)
+	}
+	for name, values := range want {
+		got := tables[name]
+		if len(got) != len(values) {
+			t.Errorf("[%s] = %v, want %v", name, got, values)
+		}
+		for key, value := range values {
+			if got[key] != value {
+				t.Errorf("[%s] %s = %q, want %q", name, key, got[key], value)
+			}
+		}
+	}
+
+	for _, data := range []string{
+		"[[servers]]\nname = \"a\"\n",
+		"regions = [[\"eu\"]]\n",
+		"when = 1979-05-27\n",
+		"key = value\n",
+	} {
+		writeConfigFile(t, path, data)
+		if _, _, err := readTOMLFile(path); err == nil {
+			t.Errorf("readTOMLFile(%q) error = nil", data)
+		}
+	}
+}
+
+func TestLoad_NoFiles(t *testing.T) {
+	clearSettingsEnv(t)
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	t.Chdir(dir)
+
+	cfg, _, err := Load(WithGlobalConfig(filepath.Join(dir, "missing.toml")))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Files) != 0 {
+		t.Errorf("Files = %v, want none", cfg.Files)
+	}
+	if err := cfg.Validate(); !errors.Is(err, ErrNoProviderConfigured) {
+		t.Errorf("Validate() error = %v, want ErrNoProviderConfigured", err)
+	}
+	if want := filepath.Join(dir, "state", "spinup"); cfg.StateDir != want {
+		t.Errorf("StateDir = %q, want %q", cfg.StateDir, want)
+	}
+}
+
+func TestLoad_DeadmanTermination(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"", "scoped", false},
+		{"relay", "relay", false},
+		{"API-Key", "api-key", false},
+		{"shutdown", "shutdown", false},
+		{"full-key", "full-key", true},
+	}
+
+	for _, tt := range tests {
+		clearSettingsEnv(t)
+		t.Setenv("VAST_API_KEY", "vast-key")
+		t.Setenv("DEADMAN_TERMINATION", tt.value)
+
+		cfg, _, err := Load(WithGlobalConfig(""), WithProjectConfig(""))
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if cfg.DeadmanTermination != tt.want {
+			t.Errorf("DeadmanTermination for %q = %q, want %q", tt.value, cfg.DeadmanTermination, tt.want)
+		}
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() for %q error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoad_WatchdogTimeout(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", DefaultWatchdogTimeout, false},
+		{"30m", 30 * time.Minute, false},
+		{"0", 0, false},
+		{"5m", 5 * time.Minute, true},
+		{"soon", DefaultWatchdogTimeout, false},
+	}
+
+	for _, tt := range tests {
+		clearSettingsEnv(t)
+		t.Setenv("VAST_API_KEY", "vast-key")
+		t.Setenv("WATCHDOG_TIMEOUT", tt.value)
+
+		cfg, _, err := Load(WithGlobalConfig(""), WithProjectConfig(""))
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if cfg.WatchdogTimeout != tt.want {
+			t.Errorf("WatchdogTimeout for %q = %v, want %v", tt.value, cfg.WatchdogTimeout, tt.want)
+		}
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate() for %q error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestResolveStateDir(t *testing.T) {
+	clearSettingsEnv(t)
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "xdg"))
+	t.Chdir(dir)
+	opts := []LoadOption{WithGlobalConfig(""), WithProjectConfig("")}
+
+	got, err := ResolveStateDir(opts...)
+	if err != nil {
+		t.Fatalf("ResolveStateDir() error = %v", err)
+	}
+	if want := filepath.Join(dir, "xdg", "spinup"); got != want {
+		t.Errorf("ResolveStateDir() = %q, want %q", got, want)
+	}
+
+	// A state file in the current directory keeps its instances tracked
+	if err := os.WriteFile(filepath.Join(dir, StateFileName), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cwd, _ := os.Getwd()
+	if got, _ := ResolveStateDir(opts...); got != cwd {
+		t.Errorf("ResolveStateDir() with legacy state = %q, want %q", got, cwd)
+	}
+
+	t.Setenv("STATE_DIR", "/var/lib/spinup")
+	if got, _ := ResolveStateDir(opts...); got != "/var/lib/spinup" {
+		t.Errorf("ResolveStateDir() with STATE_DIR = %q, want /var/lib/spinup", got)
+	}
+}
+
+func TestGlobalConfigPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg/config")
+	if got := GlobalConfigPath(); got != "/xdg/config/spinup/config.toml" {
+		t.Errorf("GlobalConfigPath() = %q", got)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("HOME", "/home/me")
+	if got := GlobalConfigPath(); got != "/home/me/.config/spinup/config.toml" {
+		t.Errorf("GlobalConfigPath() without XDG_CONFIG_HOME = %q", got)
+	}
+	t.Setenv("XDG_STATE_HOME", "")
+	if got := DefaultStateDir(); got != "/home/me/.local/state/spinup" {
+		t.Errorf("DefaultStateDir() = %q", got)
+	}
+}
+
+func TestLoadConfig_IgnoresGlobalConfig(t *testing.T) {
+	clearSettingsEnv(t)
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	writeConfigFile(t, filepath.Join(dir, "spinup", "config.toml"), "lambda_api_key = \"global\"\n")
+
+	project := filepath.Join(dir, ".env")
+	writeConfigFile(t, project, "VAST_API_KEY=project\n")
+
+	cfg, _, err := LoadConfig(project)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.VastAPIKey != "project" || cfg.LambdaAPIKey != "" {
+		t.Errorf("LoadConfig() providers = %v, want only the project's", cfg.ConfiguredProviders())
+	}
+	// The file is read without changing the environment
+	if v := os.Getenv("VAST_API_KEY"); v != "" {
+		t.Errorf("VAST_API_KEY = %q after LoadConfig(), want it unset", v)
+	}
+
+	if _, _, err := LoadConfig(filepath.Join(dir, "missing.env")); !errors.Is(err, ErrNoConfigFile) {
+		t.Errorf("LoadConfig() of missing file error = %v, want ErrNoConfigFile", err)
+	}
+}
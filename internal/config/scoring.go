@@ -0,0 +1,127 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ScoringConfig configures how offers are ranked before deployment.
+// Each weight scales one factor of the offer score; a weight of zero ignores
+// the factor. If all weights are zero, offers are ranked by price alone.
+type ScoringConfig struct {
+	// PriceWeight weighs the hourly price relative to the cheapest offer.
+	PriceWeight float64 `json:"price_weight"`
+
+	// RegionWeight weighs how early the offer's region appears in PreferredRegions.
+	RegionWeight float64 `json:"region_weight"`
+
+	// ProviderWeight weighs how early the offer's provider appears in ProviderPriority.
+	ProviderWeight float64 `json:"provider_weight"`
+
+	// ReliabilityWeight weighs the host reliability reported by the provider.
+	ReliabilityWeight float64 `json:"reliability_weight"`
+
+	// BootTimeWeight weighs the provider's historical boot time.
+	BootTimeWeight float64 `json:"boot_time_weight"`
+
+	// PreferredRegions lists regions from most to least preferred (e.g., "eu-west").
+	PreferredRegions []string `json:"preferred_regions"`
+
+	// ProviderPriority lists providers from most to least preferred (e.g., "lambda").
+	ProviderPriority []string `json:"provider_priority"`
+}
+
+// DefaultScoringConfig returns the default offer scoring: price dominates, the
+// other factors break ties between similarly priced offers.
+func DefaultScoringConfig() ScoringConfig {
+	return ScoringConfig{
+		PriceWeight:       1.0,
+		RegionWeight:      0.25,
+		ProviderWeight:    0.25,
+		ReliabilityWeight: 0.25,
+		BootTimeWeight:    0.25,
+	}
+}
+
+// Validate checks that no weight is negative.
+func (s ScoringConfig) Validate() error {
+	weights := []struct {
+		key    string
+		weight float64
+	}{
+		{"SCORE_WEIGHT_PRICE", s.PriceWeight},
+		{"SCORE_WEIGHT_REGION", s.RegionWeight},
+		{"SCORE_WEIGHT_PROVIDER", s.ProviderWeight},
+		{"SCORE_WEIGHT_RELIABILITY", s.ReliabilityWeight},
+		{"SCORE_WEIGHT_BOOT_TIME", s.BootTimeWeight},
+	}
+	for _, w := range weights {
+		if w.weight < 0 {
+			return fmt.Errorf("%s cannot be negative: %.2f", w.key, w.weight)
+		}
+	}
+	return nil
+}
+
+// LoadScoringFile reads a scoring config from a JSON file. Fields missing in
+// the file keep their value in base.
+func LoadScoringFile(path string, base ScoringConfig) (ScoringConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return base, fmt.Errorf("failed to read scoring config: %w", err)
+	}
+
+	scoring := base
+	if err := json.Unmarshal(data, &scoring); err != nil {
+		return base, fmt.Errorf("failed to parse scoring config %s: %w", path, err)
+	}
+	return scoring, nil
+}
+
+// loadScoringFromEnv builds the scoring config from the defaults, the file
+// named by SCORING_CONFIG_FILE (if any), and the SCORE_* variables, in that order.
+func loadScoringFromEnv() (ScoringConfig, error) {
+	return loadScoring(envResolver())
+}
+
+// loadScoring builds the scoring config like loadScoringFromEnv, reading
+// the settings from r.
+func loadScoring(r *resolver) (ScoringConfig, error) {
+	scoring := DefaultScoringConfig()
+
+	if path := r.get("SCORING_CONFIG_FILE"); path != "" {
+		var err error
+		scoring, err = LoadScoringFile(path, scoring)
+		if err != nil {
+			return scoring, err
+		}
+	}
+
+	scoring.PriceWeight = r.getFloat("SCORE_WEIGHT_PRICE", scoring.PriceWeight)
+	scoring.RegionWeight = r.getFloat("SCORE_WEIGHT_REGION", scoring.RegionWeight)
+	scoring.ProviderWeight = r.getFloat("SCORE_WEIGHT_PROVIDER", scoring.ProviderWeight)
+	scoring.ReliabilityWeight = r.getFloat("SCORE_WEIGHT_RELIABILITY", scoring.ReliabilityWeight)
+	scoring.BootTimeWeight = r.getFloat("SCORE_WEIGHT_BOOT_TIME", scoring.BootTimeWeight)
+
+	if v := r.get("PREFERRED_REGIONS"); v != "" {
+		scoring.PreferredRegions = splitList(v)
+	}
+	if v := r.get("PROVIDER_PRIORITY"); v != "" {
+		scoring.ProviderPriority = splitList(v)
+	}
+
+	return scoring, nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScoringConfig_Validate(t *testing.T) {
+	if err := DefaultScoringConfig().Validate(); err != nil {
+		t.Errorf("default Validate() error = %v", err)
+	}
+
+	s := DefaultScoringConfig()
+	s.BootTimeWeight = -1
+	if err := s.Validate(); err == nil {
+		t.Error("Validate() with negative weight error = nil")
+	}
+}
+
+func TestLoadScoringFromEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scoring.json")
+	data := `{"region_weight": 2, "preferred_regions": ["eu-west", "eu-central"], "provider_priority": ["vast"]}`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("SCORING_CONFIG_FILE", path)
+	t.Setenv("SCORE_WEIGHT_PRICE", "3")
+	t.Setenv("PROVIDER_PRIORITY", "lambda, runpod,")
+
+	scoring, err := loadScoringFromEnv()
+	if err != nil {
+		t.Fatalf("loadScoringFromEnv() error = %v", err)
+	}
+
+	if scoring.PriceWeight != 3 {
+		t.Errorf("PriceWeight = %v, want 3 (env)", scoring.PriceWeight)
+	}
+	if scoring.RegionWeight != 2 {
+		t.Errorf("RegionWeight = %v, want 2 (file)", scoring.RegionWeight)
+	}
+	if scoring.ReliabilityWeight != DefaultScoringConfig().ReliabilityWeight {
+		t.Errorf("ReliabilityWeight = %v, want default", scoring.ReliabilityWeight)
+	}
+	if len(scoring.PreferredRegions) != 2 || scoring.PreferredRegions[0] != "eu-west" {
+		t.Errorf("PreferredRegions = %v, want file value", scoring.PreferredRegions)
+	}
+	if len(scoring.ProviderPriority) != 2 || scoring.ProviderPriority[0] != "lambda" || scoring.ProviderPriority[1] != "runpod" {
+		t.Errorf("ProviderPriority = %v, want [lambda runpod]", scoring.ProviderPriority)
+	}
+}
+
+func TestLoadScoringFile_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scoring.json")
+	os.WriteFile(path, []byte("{"), 0600)
+
+	if _, err := LoadScoringFile(path, DefaultScoringConfig()); err == nil {
+		t.Error("LoadScoringFile() with invalid JSON error = nil")
+	}
+	if _, err := LoadScoringFile(filepath.Join(t.TempDir(), "missing.json"), DefaultScoringConfig()); err == nil {
+		t.Error("LoadScoringFile() with missing file error = nil")
+	}
+}
@@ -0,0 +1,476 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// SecretRefPrefix prefixes a reference to a secret in the secret store. A
+// setting like VAST_API_KEY=secret://vast is replaced by the secret named
+// vast when the configuration is loaded.
+const SecretRefPrefix = "secret://"
+
+// Secret backends, selected with SECRET_BACKEND.
+const (
+	// SecretBackendFile stores secrets in an age-encrypted file, unlocked
+	// by a passphrase or a keyfile. It is the default.
+	SecretBackendFile = "file"
+	// SecretBackendCommand reads secrets with an external command, such as
+	// 'pass show' or 'op read'.
+	SecretBackendCommand = "command"
+	// SecretBackendEnv reads secrets from SPINUP_SECRET_<NAME> variables.
+	SecretBackendEnv = "env"
+)
+
+// SecretsPassphraseEnv is the environment variable holding the passphrase
+// of the secrets file.
+const SecretsPassphraseEnv = "SPINUP_SECRETS_PASSPHRASE"
+
+// SecretEnvPrefix prefixes the variables of the env backend: secret://vast
+// reads SPINUP_SECRET_VAST.
+const SecretEnvPrefix = "SPINUP_SECRET_"
+
+// WireGuardSecretName is the name spinup init stores the WireGuard private
+// key under.
+const WireGuardSecretName = "wireguard"
+
+// secretNamePlaceholder is replaced by the secret name in SECRET_COMMAND
+// and SECRET_STORE_COMMAND.
+const secretNamePlaceholder = "{name}"
+
+var (
+	// ErrSecretNotFound indicates the secret store has no secret of that name.
+	ErrSecretNotFound = errors.New("secret not found")
+
+	// ErrSecretsLocked indicates the secrets file needs a passphrase and
+	// none is available.
+	ErrSecretsLocked = errors.New("secrets file is locked")
+
+	// ErrSecretBackendReadOnly indicates the secret backend can't store secrets.
+	ErrSecretBackendReadOnly = errors.New("secret backend is read-only")
+)
+
+// SecretBackend reads and stores the secrets that settings reference.
+type SecretBackend interface {
+	// Get returns the named secret, or ErrSecretNotFound.
+	Get(name string) (string, error)
+
+	// Set stores the named secret.
+	Set(name, value string) error
+
+	// String describes the backend, e.g. "encrypted file ~/.config/spinup/secrets.age".
+	String() string
+}
+
+// PassphraseFunc returns the passphrase of the secrets file, e.g. by
+// prompting for it. confirm is true when a new file is created. It returns
+// nil if no passphrase is available.
+type PassphraseFunc func(confirm bool) ([]byte, error)
+
+// SecretsConfig selects and configures the secret backend.
+type SecretsConfig struct {
+	// Backend is file, command or env (SECRET_BACKEND).
+	Backend string
+
+	// File is the secrets file of the file backend (SECRETS_FILE).
+	File string
+
+	// Keyfile holds the passphrase of the secrets file (SECRETS_KEYFILE).
+	// Without it, the passphrase is read from SPINUP_SECRETS_PASSPHRASE or
+	// prompted for.
+	Keyfile string
+
+	// Command prints a secret for the command backend (SECRET_COMMAND),
+	// e.g. "pass show spinup/{name}". {name} is replaced by the secret
+	// name, which is appended if the command has no {name}.
+	Command string
+
+	// StoreCommand stores a secret read from stdin for the command backend
+	// (SECRET_STORE_COMMAND), e.g. "pass insert -m spinup/{name}".
+	StoreCommand string
+}
+
+// secretsSettings are the settings configuring the secret backend, which
+// can't be secret references themselves.
+var secretsSettings = map[string]bool{
+	"SECRET_BACKEND":       true,
+	"SECRETS_FILE":         true,
+	"SECRETS_KEYFILE":      true,
+	"SECRET_COMMAND":       true,
+	"SECRET_STORE_COMMAND": true,
+}
+
+// DefaultSecretsFile returns the default secrets file, secrets.age next to
+// the global config file.
+func DefaultSecretsFile() string {
+	path := GlobalConfigPath()
+	if path == "" {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(path), "secrets.age")
+}
+
+// loadSecretsConfig reads the secret backend settings.
+func loadSecretsConfig(r *resolver) SecretsConfig {
+	return SecretsConfig{
+		Backend:      r.getWithDefault("SECRET_BACKEND", SecretBackendFile),
+		File:         expandHome(r.getWithDefault("SECRETS_FILE", DefaultSecretsFile())),
+		Keyfile:      expandHome(r.get("SECRETS_KEYFILE")),
+		Command:      r.get("SECRET_COMMAND"),
+		StoreCommand: r.get("SECRET_STORE_COMMAND"),
+	}
+}
+
+// Open returns the configured secret backend. The file backend gets its
+// passphrase from SECRETS_KEYFILE, SPINUP_SECRETS_PASSPHRASE or else
+// passphrase, which may be nil.
+func (s SecretsConfig) Open(passphrase PassphraseFunc) (SecretBackend, error) {
+	switch strings.ToLower(s.Backend) {
+	case "", SecretBackendFile:
+		return openFileSecretBackend(s, passphrase)
+	case SecretBackendCommand:
+		if s.Command == "" {
+			return nil, errors.New("SECRET_BACKEND=command requires SECRET_COMMAND, e.g. \"pass show spinup/{name}\"")
+		}
+		return &commandSecretBackend{command: s.Command, storeCommand: s.StoreCommand}, nil
+	case SecretBackendEnv:
+		return envSecretBackend{}, nil
+	}
+	return nil, fmt.Errorf("unknown SECRET_BACKEND %q (valid: file, command, env)", s.Backend)
+}
+
+// settings returns the settings that select this backend.
+func (s SecretsConfig) settings() [][2]string {
+	backend := strings.ToLower(s.Backend)
+	if backend == "" {
+		backend = SecretBackendFile
+	}
+	settings := [][2]string{{"SECRET_BACKEND", backend}}
+	switch backend {
+	case SecretBackendFile:
+		if s.File != "" && s.File != DefaultSecretsFile() {
+			settings = append(settings, [2]string{"SECRETS_FILE", s.File})
+		}
+		if s.Keyfile != "" {
+			settings = append(settings, [2]string{"SECRETS_KEYFILE", s.Keyfile})
+		}
+	case SecretBackendCommand:
+		settings = append(settings, [2]string{"SECRET_COMMAND", s.Command})
+		if s.StoreCommand != "" {
+			settings = append(settings, [2]string{"SECRET_STORE_COMMAND", s.StoreCommand})
+		}
+	}
+	return settings
+}
+
+// saveGlobal writes the settings that select this backend to the global
+// config file at path, as the project .env can't select it. Settings the
+// file already holds are kept; a different value is an error.
+func (s SecretsConfig) saveGlobal(path string) error {
+	if path == "" {
+		return errors.New("no global config file to save the secret backend in: set HOME or XDG_CONFIG_HOME")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc map[string]any
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	current := make(map[string]string, len(doc))
+	for key, value := range doc {
+		if v, err := tomlSettingValue(value); err == nil {
+			key = strings.ToUpper(key)
+			if key == "SECRETS_FILE" || key == "SECRETS_KEYFILE" {
+				v = expandHome(v)
+			}
+			current[key] = v
+		}
+	}
+
+	missing := map[string]string{}
+	for _, setting := range s.settings() {
+		key, value := setting[0], setting[1]
+		if v, ok := current[key]; ok {
+			if v != value {
+				return fmt.Errorf("%s sets %s = %q, not %q: change it there to use this secret backend",
+					path, strings.ToLower(key), v, value)
+			}
+			continue
+		}
+		missing[strings.ToLower(key)] = value
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	// Top-level keys go before the first table
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(missing); err != nil {
+		return fmt.Errorf("failed to encode secret backend settings: %w", err)
+	}
+	if len(data) > 0 {
+		buf.WriteString("\n")
+		buf.Write(data)
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// IsSecretRef returns true if value references a secret, e.g. secret://vast.
+func IsSecretRef(value string) bool {
+	return strings.HasPrefix(value, SecretRefPrefix)
+}
+
+// SecretRef returns the reference to the named secret.
+func SecretRef(name string) string {
+	return SecretRefPrefix + name
+}
+
+// parseSecretRef returns the secret name a reference names.
+func parseSecretRef(ref string) (string, error) {
+	name := strings.TrimPrefix(ref, SecretRefPrefix)
+	if err := validateSecretName(name); err != nil {
+		return "", fmt.Errorf("invalid secret reference %q: %w", ref, err)
+	}
+	return name, nil
+}
+
+// validateSecretName checks that a secret name is safe to use as a file
+// key, a command argument and part of a variable name.
+func validateSecretName(name string) error {
+	if name == "" {
+		return errors.New("empty secret name")
+	}
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case i > 0 && strings.ContainsRune("_-./", c):
+		default:
+			return fmt.Errorf("secret name %q may only contain letters, digits and _-./", name)
+		}
+	}
+	return nil
+}
+
+// fileSecretBackend stores secrets as JSON in an age-encrypted file.
+type fileSecretBackend struct {
+	path       string
+	passphrase []byte
+	logN       int
+	secrets    map[string]string
+}
+
+// openFileSecretBackend unlocks and reads the secrets file. A missing file
+// is created by the first Set.
+func openFileSecretBackend(s SecretsConfig, passphrase PassphraseFunc) (*fileSecretBackend, error) {
+	if s.File == "" {
+		return nil, errors.New("SECRETS_FILE is not set and the home directory is unknown")
+	}
+	b := &fileSecretBackend{path: s.File, logN: defaultScryptLogN, secrets: make(map[string]string)}
+
+	data, err := os.ReadFile(s.File)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	switch {
+	case s.Keyfile != "":
+		key, err := os.ReadFile(s.Keyfile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SECRETS_KEYFILE: %w", err)
+		}
+		b.passphrase = bytes.TrimRight(key, "\r\n")
+	case os.Getenv(SecretsPassphraseEnv) != "":
+		b.passphrase = []byte(os.Getenv(SecretsPassphraseEnv))
+	case passphrase != nil:
+		if b.passphrase, err = passphrase(!exists); err != nil {
+			return nil, err
+		}
+	}
+	if len(b.passphrase) == 0 {
+		return nil, fmt.Errorf("%w: %s needs a passphrase, set %s or SECRETS_KEYFILE",
+			ErrSecretsLocked, s.File, SecretsPassphraseEnv)
+	}
+
+	if !exists {
+		return b, nil
+	}
+	plaintext, err := ageDecrypt(data, b.passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.File, err)
+	}
+	if err := json.Unmarshal(plaintext, &b.secrets); err != nil {
+		return nil, fmt.Errorf("%s: invalid secrets: %w", s.File, err)
+	}
+	return b, nil
+}
+
+func (b *fileSecretBackend) Get(name string) (string, error) {
+	value, ok := b.secrets[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s in %s", ErrSecretNotFound, name, b.path)
+	}
+	return value, nil
+}
+
+// Set stores the secret and rewrites the secrets file.
+func (b *fileSecretBackend) Set(name, value string) error {
+	if err := validateSecretName(name); err != nil {
+		return err
+	}
+	b.secrets[name] = value
+
+	plaintext, err := json.MarshalIndent(b.secrets, "", "  ")
+	if err != nil {
+		return err
+	}
+	data, err := ageEncrypt(append(plaintext, '\n'), b.passphrase, b.logN)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(b.path, data)
+}
+
+func (b *fileSecretBackend) String() string {
+	return "encrypted file " + b.path
+}
+
+// writeFileAtomic replaces path with data, readable only by its owner.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// commandSecretBackend reads and stores secrets with external commands.
+type commandSecretBackend struct {
+	command      string
+	storeCommand string
+}
+
+// Get runs SECRET_COMMAND and returns the first line of its output, so
+// 'pass show' entries may hold notes below the secret.
+func (b *commandSecretBackend) Get(name string) (string, error) {
+	out, err := runSecretCommand(b.command, name, nil)
+	if err != nil {
+		return "", err
+	}
+	value, _, _ := strings.Cut(string(out), "\n")
+	value = strings.TrimSuffix(value, "\r")
+	if value == "" {
+		return "", fmt.Errorf("%w: %s (SECRET_COMMAND printed nothing)", ErrSecretNotFound, name)
+	}
+	return value, nil
+}
+
+// Set runs SECRET_STORE_COMMAND with the secret on stdin.
+func (b *commandSecretBackend) Set(name, value string) error {
+	if b.storeCommand == "" {
+		return fmt.Errorf("%w: set SECRET_STORE_COMMAND to store secrets with the command backend", ErrSecretBackendReadOnly)
+	}
+	_, err := runSecretCommand(b.storeCommand, name, []byte(value+"\n"))
+	return err
+}
+
+func (b *commandSecretBackend) String() string {
+	return "command " + strings.Fields(b.command)[0]
+}
+
+// runSecretCommand runs a secret command for the named secret. The command
+// is split on spaces and run without a shell.
+func runSecretCommand(command, name string, stdin []byte) ([]byte, error) {
+	if err := validateSecretName(name); err != nil {
+		return nil, err
+	}
+
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, errors.New("empty secret command")
+	}
+	if !strings.Contains(command, secretNamePlaceholder) {
+		args = append(args, name)
+	}
+	for i, arg := range args {
+		args[i] = strings.ReplaceAll(arg, secretNamePlaceholder, name)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("secret command %s failed for %s: %w: %s", args[0], name, err, msg)
+		}
+		return nil, fmt.Errorf("secret command %s failed for %s: %w", args[0], name, err)
+	}
+	return out, nil
+}
+
+// envSecretBackend reads secrets from SPINUP_SECRET_<NAME> variables.
+type envSecretBackend struct{}
+
+func (envSecretBackend) Get(name string) (string, error) {
+	key := SecretEnvVar(name)
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return "", fmt.Errorf("%w: %s (%s is not set)", ErrSecretNotFound, name, key)
+	}
+	return value, nil
+}
+
+func (envSecretBackend) Set(name, value string) error {
+	return fmt.Errorf("%w: set %s instead", ErrSecretBackendReadOnly, SecretEnvVar(name))
+}
+
+func (envSecretBackend) String() string {
+	return "environment"
+}
+
+// SecretEnvVar returns the variable the env backend reads the named secret
+// from: SPINUP_SECRET_ and the name in upper case, with other characters
+// than letters and digits replaced by underscores.
+func SecretEnvVar(name string) string {
+	var b strings.Builder
+	b.WriteString(SecretEnvPrefix)
+	for _, c := range strings.ToUpper(name) {
+		if c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
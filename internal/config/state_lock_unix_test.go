@@ -0,0 +1,160 @@
+//go:build unix
+
+package config
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// holdStateLock takes the state file lock of dir the way another process
+// would and returns a function that releases it.
+func holdStateLock(t *testing.T, dir string) func() {
+	t.Helper()
+
+	f, err := os.OpenFile((&StateManager{stateDir: dir}).lockPath(), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		t.Fatalf("failed to open lock file: %v", err)
+	}
+	if locked, err := tryFileLock(f); !locked {
+		t.Fatalf("failed to lock state file: %v", err)
+	}
+	return func() {
+		releaseFileLock(f)
+		f.Close()
+	}
+}
+
+func TestStateLock_WaitsForOtherProcess(t *testing.T) {
+	dir := t.TempDir()
+	sm, err := NewStateManager(dir)
+	if err != nil {
+		t.Fatalf("NewStateManager failed: %v", err)
+	}
+
+	release := holdStateLock(t, dir)
+	done := make(chan error, 1)
+	go func() {
+		done <- sm.SaveState(&State{Instance: &InstanceState{ID: "test"}})
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("SaveState returned while the lock was held: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	release()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("SaveState failed after the lock was released: %v", err)
+		}
+	case <-time.After(stateLockTimeout):
+		t.Fatal("SaveState did not take the released lock")
+	}
+	if state, _ := sm.LoadState(); state == nil || state.Instance == nil || state.Instance.ID != "test" {
+		t.Errorf("LoadState() = %+v, want the saved instance", state)
+	}
+}
+
+func TestStateLock_Timeout(t *testing.T) {
+	prev := stateLockTimeout
+	stateLockTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { stateLockTimeout = prev })
+
+	dir := t.TempDir()
+	sm, err := NewStateManager(dir)
+	if err != nil {
+		t.Fatalf("NewStateManager failed: %v", err)
+	}
+
+	release := holdStateLock(t, dir)
+	defer release()
+
+	if err := sm.SaveState(&State{Instance: &InstanceState{ID: "test"}}); !errors.Is(err, ErrStateLocked) {
+		t.Errorf("SaveState() error = %v, want ErrStateLocked", err)
+	}
+}
+
+// stateLockHelperEnv makes the test binary act as a second process that
+// saves state, see TestStateLock_HelperProcess.
+const stateLockHelperEnv = "SPINUP_TEST_STATE_LOCK_HELPER"
+
+// stateLockSaves is how many times each process saves its session.
+const stateLockSaves = 200
+
+// TestStateLock_HelperProcess saves the "daemon" session in the directory
+// named by stateLockHelperEnv, like a supervisor daemon updating its cost.
+// It does nothing when run as a regular test.
+func TestStateLock_HelperProcess(t *testing.T) {
+	dir := os.Getenv(stateLockHelperEnv)
+	if dir == "" {
+		return
+	}
+
+	sm, err := NewStateManager(dir, WithSession("daemon"))
+	if err != nil {
+		t.Fatalf("NewStateManager failed: %v", err)
+	}
+	for i := range stateLockSaves {
+		if err := sm.SaveState(&State{Instance: &InstanceState{ID: "daemon-" + strconv.Itoa(i)}}); err != nil {
+			t.Fatalf("SaveState %d failed: %v", i, err)
+		}
+	}
+}
+
+func TestStateLock_ConcurrentProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a second process")
+	}
+
+	dir := t.TempDir()
+	sm, err := NewStateManager(dir)
+	if err != nil {
+		t.Fatalf("NewStateManager failed: %v", err)
+	}
+
+	// The other process saves its session while this one saves its own
+	cmd := exec.Command(os.Args[0], "-test.run=^TestStateLock_HelperProcess$")
+	cmd.Env = append(os.Environ(), stateLockHelperEnv+"="+dir)
+	helper := make(chan []byte, 1)
+	go func() {
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			out = append(out, []byte("\n"+err.Error())...)
+		} else {
+			out = nil
+		}
+		helper <- out
+	}()
+
+	for i := range stateLockSaves {
+		if err := sm.SaveState(&State{Instance: &InstanceState{ID: "cli-" + strconv.Itoa(i)}}); err != nil {
+			t.Fatalf("SaveState %d failed: %v", i, err)
+		}
+	}
+	if out := <-helper; out != nil {
+		t.Fatalf("helper process failed:\n%s", out)
+	}
+
+	// Neither process lost the other's session
+	sessions, err := sm.ListSessions()
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	want := map[string]string{
+		DefaultSessionName: "cli-" + strconv.Itoa(stateLockSaves-1),
+		"daemon":           "daemon-" + strconv.Itoa(stateLockSaves-1),
+	}
+	for name, id := range want {
+		state := sessions[name]
+		if state == nil || state.Instance == nil || state.Instance.ID != id {
+			t.Errorf("session %s = %+v, want instance %s", name, state, id)
+		}
+	}
+}
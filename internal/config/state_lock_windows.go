@@ -6,14 +6,14 @@ import (
 	"os"
 )
 
-// acquireFileLock acquires an exclusive lock on the given file.
-// On Windows, we use a simpler approach with LockFileEx.
+// tryFileLock tries to acquire an exclusive lock on the given file without
+// waiting.
 // Note: This is a basic implementation for Windows compatibility.
-func acquireFileLock(_ *os.File) error {
+func tryFileLock(_ *os.File) (bool, error) {
 	// Windows file locking would require using syscall.LockFileEx
 	// For now, return nil as Windows is not a primary target for this tool.
 	// The in-process mutex still provides protection for single-process scenarios.
-	return nil
+	return true, nil
 }
 
 // releaseFileLock releases the lock on the given file.
@@ -9,6 +9,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/tmeurs/spinup/internal/clock"
 )
 
 // TestNewStateManager tests the StateManager constructor.
@@ -887,15 +889,28 @@ func TestStateJSONFormat(t *testing.T) {
 	}
 
 	// Check top-level keys
-	expectedKeys := []string{"version", "instance", "model", "wireguard", "cost", "deadman"}
+	if parsed["version"] != float64(StateVersion) {
+		t.Errorf("expected version=%d, got %v", StateVersion, parsed["version"])
+	}
+	sessions, ok := parsed["sessions"].(map[string]any)
+	if !ok {
+		t.Fatal("sessions is not an object")
+	}
+	session, ok := sessions[DefaultSessionName].(map[string]any)
+	if !ok {
+		t.Fatalf("missing %q session", DefaultSessionName)
+	}
+
+	// Check session keys
+	expectedKeys := []string{"instance", "model", "wireguard", "cost", "deadman"}
 	for _, key := range expectedKeys {
-		if _, ok := parsed[key]; !ok {
+		if _, ok := session[key]; !ok {
 			t.Errorf("missing expected key: %q", key)
 		}
 	}
 
 	// Check instance keys
-	inst, ok := parsed["instance"].(map[string]any)
+	inst, ok := session["instance"].(map[string]any)
 	if !ok {
 		t.Fatal("instance is not an object")
 	}
@@ -954,3 +969,267 @@ func TestStatePartialState(t *testing.T) {
 		t.Error("Deadman should be nil")
 	}
 }
+
+// TestStateMigrateV1 tests that a version 1 state file is migrated into the default session.
+func TestStateMigrateV1(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm, err := NewStateManager(tmpDir)
+	if err != nil {
+		t.Fatalf("NewStateManager failed: %v", err)
+	}
+
+	v1 := `{
+  "version": 1,
+  "instance": {"id": "legacy-1", "provider": "vast", "wireguard_ip": "10.13.37.1"},
+  "model": {"name": "qwen2.5-coder:7b", "status": "ready"},
+  "wireguard": {"server_public_key": "key", "interface_name": "wg-spinup"},
+  "cost": {"hourly_rate": 0.5, "accumulated": 1.5, "currency": "EUR"}
+}`
+	statePath := filepath.Join(tmpDir, StateFileName)
+	if err := os.WriteFile(statePath, []byte(v1), 0600); err != nil {
+		t.Fatalf("failed to write v1 state: %v", err)
+	}
+
+	loaded, err := sm.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if loaded == nil || loaded.Instance == nil || loaded.Instance.ID != "legacy-1" {
+		t.Fatalf("expected migrated instance legacy-1, got %+v", loaded)
+	}
+	if loaded.Version != StateVersion {
+		t.Errorf("expected version=%d, got %d", StateVersion, loaded.Version)
+	}
+	if loaded.Cost.Accumulated != 1.5 {
+		t.Errorf("expected accumulated=1.5, got %f", loaded.Cost.Accumulated)
+	}
+
+	// The file on disk should now be in the version 2 format
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		t.Fatalf("failed to read state file: %v", err)
+	}
+	var file StateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("failed to parse migrated state: %v", err)
+	}
+	if file.Version != StateVersion {
+		t.Errorf("expected file version=%d, got %d", StateVersion, file.Version)
+	}
+	if file.Sessions[DefaultSessionName] == nil {
+		t.Errorf("expected %q session in migrated file", DefaultSessionName)
+	}
+
+	// Named sessions do not see the migrated default session
+	other, err := sm.ForSession("chat")
+	if err != nil {
+		t.Fatalf("ForSession failed: %v", err)
+	}
+	state, err := other.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if state != nil {
+		t.Errorf("expected no state for session chat, got %+v", state)
+	}
+}
+
+// TestStateMultipleSessions tests that sessions are stored and cleared independently.
+func TestStateMultipleSessions(t *testing.T) {
+	tmpDir := t.TempDir()
+	autocomplete, err := NewStateManager(tmpDir, WithSession("autocomplete"))
+	if err != nil {
+		t.Fatalf("NewStateManager failed: %v", err)
+	}
+	chat, err := NewStateManager(tmpDir, WithSession("chat"))
+	if err != nil {
+		t.Fatalf("NewStateManager failed: %v", err)
+	}
+
+	if autocomplete.Session() != "autocomplete" {
+		t.Errorf("expected session autocomplete, got %q", autocomplete.Session())
+	}
+
+	if err := autocomplete.SaveState(&State{
+		Instance: &InstanceState{ID: "small-1"},
+		Cost:     &CostState{HourlyRate: 0.3},
+		Deadman:  &DeadmanState{TimeoutHours: 4},
+	}); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+	if err := chat.SaveState(&State{
+		Instance: &InstanceState{ID: "large-1"},
+		Cost:     &CostState{HourlyRate: 2.1},
+		Deadman:  &DeadmanState{TimeoutHours: 8},
+	}); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	// Per-session updates don't touch other sessions
+	if err := chat.UpdateCost(4.2); err != nil {
+		t.Fatalf("UpdateCost failed: %v", err)
+	}
+	small, _ := autocomplete.LoadState()
+	if small.Instance.ID != "small-1" || small.Cost.Accumulated != 0 {
+		t.Errorf("autocomplete session changed unexpectedly: %+v", small)
+	}
+
+	names, err := chat.SessionNames()
+	if err != nil {
+		t.Fatalf("SessionNames failed: %v", err)
+	}
+	if len(names) != 2 || names[0] != "autocomplete" || names[1] != "chat" {
+		t.Errorf("expected [autocomplete chat], got %v", names)
+	}
+
+	// Clearing one session keeps the other
+	if err := autocomplete.ClearState(); err != nil {
+		t.Fatalf("ClearState failed: %v", err)
+	}
+	if has, _ := autocomplete.HasActiveInstance(); has {
+		t.Error("expected autocomplete session to be cleared")
+	}
+	large, err := chat.LoadState()
+	if err != nil || large == nil || large.Instance.ID != "large-1" {
+		t.Fatalf("expected chat session to survive, got %+v, %v", large, err)
+	}
+	if large.Cost.Accumulated != 4.2 {
+		t.Errorf("expected accumulated=4.2, got %f", large.Cost.Accumulated)
+	}
+
+	// Clearing the last session removes the file
+	if err := chat.ClearState(); err != nil {
+		t.Fatalf("ClearState failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, StateFileName)); !os.IsNotExist(err) {
+		t.Error("state file should be removed after the last session is cleared")
+	}
+}
+
+// TestValidateSessionName tests session name validation.
+func TestValidateSessionName(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"default", false},
+		{"chat", false},
+		{"team-a_2", false},
+		{"", true},
+		{"Chat", true},
+		{"-leading", true},
+		{"has space", true},
+		{"../escape", true},
+		{"a234567890123456789012345678901234", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSessionName(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateSessionName(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSessionName) {
+				t.Errorf("expected ErrInvalidSessionName, got %v", err)
+			}
+		})
+	}
+
+	if _, err := NewStateManager(t.TempDir(), WithSession("Bad Name")); !errors.Is(err, ErrInvalidSessionName) {
+		t.Errorf("NewStateManager with invalid session: expected ErrInvalidSessionName, got %v", err)
+	}
+}
+
+// TestStateClock tests that heartbeats, durations, costs and the deadman
+// countdown follow an injected clock.
+func TestStateClock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	sm, err := NewStateManager(t.TempDir(), WithStateClock(fake))
+	if err != nil {
+		t.Fatalf("NewStateManager failed: %v", err)
+	}
+
+	start := fake.Now()
+	if err := sm.SaveState(NewState(
+		&InstanceState{ID: "test", CreatedAt: start},
+		nil,
+		nil,
+		&CostState{HourlyRate: 1.5, Currency: "EUR"},
+		&DeadmanState{TimeoutHours: 10, LastHeartbeat: start},
+	)); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+
+	fake.Advance(4 * time.Hour)
+	if err := sm.UpdateHeartbeat(); err != nil {
+		t.Fatalf("UpdateHeartbeat failed: %v", err)
+	}
+	fake.Advance(6 * time.Hour)
+	now := fake.Now()
+
+	state, err := sm.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if want := start.Add(4 * time.Hour); !state.Deadman.LastHeartbeat.Equal(want) {
+		t.Errorf("LastHeartbeat = %v, want %v", state.Deadman.LastHeartbeat, want)
+	}
+	if got := state.Instance.DurationAt(now); got != 10*time.Hour {
+		t.Errorf("DurationAt() = %v, want 10h", got)
+	}
+	if got := state.AccumulatedCostAt(now); got != 15.0 {
+		t.Errorf("AccumulatedCostAt() = %.2f, want 15.00", got)
+	}
+	if got := state.Deadman.RemainingAt(now); got != 4*time.Hour {
+		t.Errorf("RemainingAt() = %v, want 4h", got)
+	}
+	if got := state.Deadman.RemainingAt(now.Add(5 * time.Hour)); got != 0 {
+		t.Errorf("RemainingAt() after expiry = %v, want 0", got)
+	}
+
+	var none *DeadmanState
+	if got := none.RemainingAt(now); got != 0 {
+		t.Errorf("nil RemainingAt() = %v, want 0", got)
+	}
+}
+
+// TestStateForSession tests that a StateManager for another session keeps
+// the options of the one it was derived from.
+func TestStateForSession(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	sm, err := NewStateManager(t.TempDir(), WithStateClock(fake))
+	if err != nil {
+		t.Fatalf("NewStateManager failed: %v", err)
+	}
+
+	chat, err := sm.ForSession("chat")
+	if err != nil {
+		t.Fatalf("ForSession failed: %v", err)
+	}
+	if chat.Session() != "chat" || sm.Session() != DefaultSessionName {
+		t.Errorf("sessions = %q and %q, want chat and %q", chat.Session(), sm.Session(), DefaultSessionName)
+	}
+
+	start := fake.Now()
+	if err := chat.SaveState(NewState(&InstanceState{ID: "test", CreatedAt: start}, nil, nil, nil, &DeadmanState{TimeoutHours: 10, LastHeartbeat: start})); err != nil {
+		t.Fatalf("SaveState failed: %v", err)
+	}
+	fake.Advance(2 * time.Hour)
+	if err := chat.UpdateHeartbeat(); err != nil {
+		t.Fatalf("UpdateHeartbeat failed: %v", err)
+	}
+	state, err := chat.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState failed: %v", err)
+	}
+	if want := start.Add(2 * time.Hour); !state.Deadman.LastHeartbeat.Equal(want) {
+		t.Errorf("LastHeartbeat = %v, want the parent's clock at %v", state.Deadman.LastHeartbeat, want)
+	}
+
+	if other, err := sm.ForSession(""); err != nil || other.Session() != DefaultSessionName {
+		t.Errorf("ForSession(\"\") = %v, %v, want the default session", other, err)
+	}
+	if _, err := sm.ForSession("Bad Name"); !errors.Is(err, ErrInvalidSessionName) {
+		t.Errorf("ForSession(invalid) error = %v, want ErrInvalidSessionName", err)
+	}
+}
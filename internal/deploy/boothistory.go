@@ -0,0 +1,108 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+)
+
+// BootHistoryFileName is the file in the state directory that records boot
+// times. It is shared by all sessions.
+const BootHistoryFileName = ".spinup.boottimes"
+
+// bootHistoryWindow caps how many past boots the moving average represents,
+// so a provider that got faster (or slower) is noticed.
+const bootHistoryWindow = 10
+
+// BootStats holds the boot time statistics of one provider.
+type BootStats struct {
+	// Count is the number of boots recorded, capped at the averaging window.
+	Count int `json:"count"`
+
+	// AverageSeconds is the moving average boot time in seconds.
+	AverageSeconds float64 `json:"average_seconds"`
+}
+
+// Average returns the average boot time.
+func (s BootStats) Average() time.Duration {
+	return time.Duration(s.AverageSeconds * float64(time.Second))
+}
+
+// BootHistory records how long instances took to boot, per provider.
+// It feeds the boot time factor of offer scoring.
+type BootHistory struct {
+	Providers map[string]BootStats `json:"providers"`
+
+	path string
+}
+
+// LoadBootHistory reads the boot history from the state directory.
+// A missing file yields an empty history.
+func LoadBootHistory(stateManager *config.StateManager) (*BootHistory, error) {
+	h := &BootHistory{
+		Providers: make(map[string]BootStats),
+		path:      filepath.Join(stateManager.StateDir(), BootHistoryFileName),
+	}
+
+	data, err := os.ReadFile(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return h, nil
+		}
+		return h, fmt.Errorf("failed to read boot history: %w", err)
+	}
+	if err := json.Unmarshal(data, h); err != nil {
+		return h, fmt.Errorf("boot history is corrupt: %w", err)
+	}
+	if h.Providers == nil {
+		h.Providers = make(map[string]BootStats)
+	}
+	return h, nil
+}
+
+// Record adds a boot time to the provider's moving average.
+func (h *BootHistory) Record(providerName string, d time.Duration) {
+	stats := h.Providers[providerName]
+	if stats.Count < bootHistoryWindow {
+		stats.Count++
+	}
+	stats.AverageSeconds += (d.Seconds() - stats.AverageSeconds) / float64(stats.Count)
+	h.Providers[providerName] = stats
+}
+
+// BootTimes returns the average boot time per provider.
+func (h *BootHistory) BootTimes() map[string]time.Duration {
+	times := make(map[string]time.Duration, len(h.Providers))
+	for name, stats := range h.Providers {
+		if stats.Count > 0 {
+			times[name] = stats.Average()
+		}
+	}
+	return times
+}
+
+// Save writes the boot history to the state directory.
+func (h *BootHistory) Save() error {
+	if h.path == "" {
+		return fmt.Errorf("boot history has no path")
+	}
+
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal boot history: %w", err)
+	}
+
+	tempPath := h.path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write boot history: %w", err)
+	}
+	if err := os.Rename(tempPath, h.path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename boot history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+)
+
+func TestBootHistory_RecordSaveLoad(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+
+	history, err := LoadBootHistory(sm)
+	if err != nil {
+		t.Fatalf("LoadBootHistory() error = %v", err)
+	}
+	if len(history.BootTimes()) != 0 {
+		t.Fatalf("BootTimes() = %v, want empty", history.BootTimes())
+	}
+
+	history.Record("vast", 60*time.Second)
+	history.Record("vast", 120*time.Second)
+	history.Record("lambda", 30*time.Second)
+	if err := history.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	loaded, err := LoadBootHistory(sm)
+	if err != nil {
+		t.Fatalf("LoadBootHistory() error = %v", err)
+	}
+	times := loaded.BootTimes()
+	if times["vast"] != 90*time.Second {
+		t.Errorf("vast = %v, want 90s", times["vast"])
+	}
+	if times["lambda"] != 30*time.Second {
+		t.Errorf("lambda = %v, want 30s", times["lambda"])
+	}
+
+	info, err := os.Stat(filepath.Join(sm.StateDir(), BootHistoryFileName))
+	if err != nil {
+		t.Fatalf("boot history not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("permissions = %o, want 600", info.Mode().Perm())
+	}
+}
+
+func TestBootHistory_MovingWindow(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+	history, _ := LoadBootHistory(sm)
+
+	for i := 0; i < bootHistoryWindow*3; i++ {
+		history.Record("vast", 300*time.Second)
+	}
+	for i := 0; i < bootHistoryWindow*3; i++ {
+		history.Record("vast", 60*time.Second)
+	}
+
+	stats := history.Providers["vast"]
+	if stats.Count != bootHistoryWindow {
+		t.Errorf("Count = %d, want %d", stats.Count, bootHistoryWindow)
+	}
+	if avg := stats.Average(); avg > 75*time.Second {
+		t.Errorf("Average() = %v, want close to 60s after recent fast boots", avg)
+	}
+}
+
+func TestLoadBootHistory_Corrupt(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+	os.WriteFile(filepath.Join(sm.StateDir(), BootHistoryFileName), []byte("{not json"), 0600)
+
+	history, err := LoadBootHistory(sm)
+	if err == nil {
+		t.Error("LoadBootHistory() error = nil, want corrupt error")
+	}
+	if history == nil || history.Providers == nil {
+		t.Fatal("LoadBootHistory() should return a usable empty history on error")
+	}
+	history.Record("vast", time.Minute)
+	if err := history.Save(); err != nil {
+		t.Errorf("Save() error = %v", err)
+	}
+}
+
+func TestDeployer_recordBootTime(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+	d := &Deployer{stateManager: sm}
+
+	d.recordBootTime("runpod", 45*time.Second)
+
+	history, _ := LoadBootHistory(sm)
+	if got := history.BootTimes()["runpod"]; got != 45*time.Second {
+		t.Errorf("runpod boot time = %v, want 45s", got)
+	}
+}
@@ -25,7 +25,19 @@ type CloudInitParams struct {
 	// Model to deploy
 	Model string
 
-	// API key for deadman self-termination (provider-specific)
+	// TerminationMode selects the credential the deadman switch terminates
+	// the instance with. The zero value means TerminationModeShutdown.
+	TerminationMode TerminationMode
+
+	// TerminationToken is the one-time relay token (TerminationModeRelay) or
+	// the scoped provider key (TerminationModeScoped).
+	TerminationToken string
+
+	// RelayURL is the termination relay endpoint (TerminationModeRelay).
+	RelayURL string
+
+	// APIKey is the full provider API key. It is only written to the
+	// instance with TerminationModeAPIKey.
 	APIKey string
 }
 
@@ -43,6 +55,14 @@ type WireGuardParams struct {
 	ClientAllowedIPs string
 }
 
+// ServerIP returns the server's tunnel IP without CIDR notation.
+func (w WireGuardParams) ServerIP() string {
+	if ip, _, ok := strings.Cut(w.ServerAddress, "/"); ok {
+		return ip
+	}
+	return w.ServerAddress
+}
+
 // DeadmanParams contains deadman switch configuration.
 type DeadmanParams struct {
 	// TimeoutSeconds is the deadman switch timeout in seconds.
@@ -52,7 +72,8 @@ type DeadmanParams struct {
 
 // NewCloudInitParams creates a CloudInitParams with default values.
 // The caller must set WireGuard.ServerPrivateKey, WireGuard.ClientPublicKey,
-// Provider, InstanceID, Model, and APIKey.
+// Provider, InstanceID, and Model, and the termination credential for
+// TerminationMode.
 func NewCloudInitParams() *CloudInitParams {
 	return &CloudInitParams{
 		WireGuard: WireGuardParams{
@@ -68,6 +89,8 @@ func NewCloudInitParams() *CloudInitParams {
 
 // CloudInitParamsFromServerConfig creates CloudInitParams from a WireGuard ServerConfig.
 // This is a convenience function for when you already have a ServerConfig.
+// The termination mode is left unset, so apiKey is only written to the
+// instance if the caller opts in with TerminationModeAPIKey.
 func CloudInitParamsFromServerConfig(serverCfg *wireguard.ServerConfig, provider, instanceID, model, apiKey string, deadmanTimeout int) *CloudInitParams {
 	params := NewCloudInitParams()
 
@@ -112,7 +135,6 @@ func (p *CloudInitParams) Validate() error {
 		return fmt.Errorf("model is required")
 	}
 	// InstanceID can be empty for some providers during initial creation
-	// APIKey can be empty if we don't want self-termination capability
 
 	// Validate provider is known
 	validProviders := map[string]bool{
@@ -126,11 +148,42 @@ func (p *CloudInitParams) Validate() error {
 		return fmt.Errorf("unknown provider: %s (valid: vast, lambda, runpod, coreweave, paperspace)", p.Provider)
 	}
 
+	switch p.TerminationMode {
+	case "", TerminationModeShutdown:
+	case TerminationModeRelay:
+		if p.TerminationToken == "" || p.RelayURL == "" {
+			return fmt.Errorf("relay termination requires a relay token and URL")
+		}
+	case TerminationModeScoped:
+		if p.TerminationToken == "" {
+			return fmt.Errorf("scoped termination requires a scoped key")
+		}
+	case TerminationModeAPIKey:
+		if p.APIKey == "" {
+			return fmt.Errorf("api-key termination requires an API key")
+		}
+	default:
+		return fmt.Errorf("unknown termination mode: %s (valid: %s)", p.TerminationMode, strings.Join(terminationModeNames(), ", "))
+	}
+
 	return nil
 }
 
+// ProviderCredential returns the credential the deadman switch calls the
+// provider's API with, or "" if it does not call the provider.
+func (p *CloudInitParams) ProviderCredential() string {
+	switch p.TerminationMode {
+	case TerminationModeScoped:
+		return p.TerminationToken
+	case TerminationModeAPIKey:
+		return p.APIKey
+	}
+	return ""
+}
+
 // cloudInitTemplate is the embedded template for cloud-init configuration.
-// This matches PRD Section 10.2 exactly.
+// This follows PRD Section 10.2, except that the deadman switch only holds
+// the credential its TerminationMode calls for.
 const cloudInitTemplate = `#cloud-config
 # Generated by spinup - DO NOT EDIT
 
@@ -157,7 +210,7 @@ write_files:
       AllowedIPs = {{ .WireGuard.ClientAllowedIPs }}
 
   - path: /usr/local/bin/deadman.sh
-    permissions: '0755'
+    permissions: '0700'
     content: |
       #!/bin/bash
       TIMEOUT_SECONDS={{ .Deadman.TimeoutSeconds }}
@@ -171,24 +224,30 @@ write_files:
           sleep 60
           if [ $(($(date +%s) - $(stat -c %Y $HEARTBEAT_FILE))) -gt $TIMEOUT_SECONDS ]; then
               echo "Deadman triggered"
+              {{- if eq .TerminationMode "relay" }}
+              curl -sf --max-time 30 -X POST "{{ .RelayURL }}" \
+                  -H "Authorization: Bearer {{ .TerminationToken }}" \
+                  || echo "Termination relay unreachable, only shutting down - the instance may still be billed"
+              {{- else if .ProviderCredential }}
               {{- if eq .Provider "vast" }}
               curl -X DELETE "https://console.vast.ai/api/v0/instances/${INSTANCE_ID}/" \
-                  -H "Authorization: Bearer {{ .APIKey }}"
+                  -H "Authorization: Bearer {{ .ProviderCredential }}"
               {{- else if eq .Provider "lambda" }}
               curl -X POST "https://cloud.lambdalabs.com/api/v1/instance-operations/terminate" \
-                  -H "Authorization: Bearer {{ .APIKey }}" \
+                  -H "Authorization: Bearer {{ .ProviderCredential }}" \
                   -d '{"instance_ids": ["'${INSTANCE_ID}'"]}'
               {{- else if eq .Provider "runpod" }}
               curl -X POST "https://api.runpod.io/graphql" \
-                  -H "Authorization: Bearer {{ .APIKey }}" \
+                  -H "Authorization: Bearer {{ .ProviderCredential }}" \
                   -H "Content-Type: application/json" \
                   -d '{"query":"mutation { podTerminate(input: {podId: \"'${INSTANCE_ID}'\"}) { id } }"}'
               {{- else if eq .Provider "coreweave" }}
               curl -X DELETE "https://api.coreweave.com/v1/instances/${INSTANCE_ID}" \
-                  -H "Authorization: Bearer {{ .APIKey }}"
+                  -H "Authorization: Bearer {{ .ProviderCredential }}"
               {{- else if eq .Provider "paperspace" }}
               curl -X POST "https://api.paperspace.io/machines/${INSTANCE_ID}/destroyMachine" \
-                  -H "x-api-key: {{ .APIKey }}"
+                  -H "x-api-key: {{ .ProviderCredential }}"
+              {{- end }}
               {{- end }}
               shutdown -h now
           fi
@@ -381,11 +440,11 @@ runcmd:
   - systemctl restart docker
 
   # Start Ollama
-  - docker run -d --gpus all -v ollama:/root/.ollama -p 10.13.37.1:11434:11434 --name ollama --restart unless-stopped ollama/ollama
+  - docker run -d --gpus all -v ollama:/root/.ollama -p {{ .WireGuard.ServerIP }}:11434:11434 --name ollama --restart unless-stopped ollama/ollama
 
   # Wait and pull model
   - sleep 10
-  - until curl -s http://10.13.37.1:11434/api/tags > /dev/null; do sleep 2; done
+  - until curl -s http://{{ .WireGuard.ServerIP }}:11434/api/tags > /dev/null; do sleep 2; done
   - docker exec ollama ollama pull {{ .Model }}
 
   # Start deadman
@@ -424,6 +483,9 @@ func GenerateCloudInit(params *CloudInitParams) (string, error) {
 	if params.Deadman.TimeoutSeconds == 0 {
 		params.Deadman.TimeoutSeconds = 36000 // 10 hours
 	}
+	if params.TerminationMode == "" {
+		params.TerminationMode = TerminationModeShutdown
+	}
 
 	// Normalize provider name to lowercase
 	params.Provider = strings.ToLower(params.Provider)
@@ -269,6 +269,8 @@ func TestGenerateCloudInit_VastSpecific(t *testing.T) {
 		InstanceID: "12345",
 		Model:      "test-model",
 		APIKey:     "vast-api-key",
+
+		TerminationMode: TerminationModeAPIKey,
 	}
 
 	result, err := GenerateCloudInit(params)
@@ -294,6 +296,8 @@ func TestGenerateCloudInit_LambdaSpecific(t *testing.T) {
 		InstanceID: "12345",
 		Model:      "test-model",
 		APIKey:     "lambda-api-key",
+
+		TerminationMode: TerminationModeAPIKey,
 	}
 
 	result, err := GenerateCloudInit(params)
@@ -316,6 +320,8 @@ func TestGenerateCloudInit_RunPodSpecific(t *testing.T) {
 		InstanceID: "12345",
 		Model:      "test-model",
 		APIKey:     "runpod-api-key",
+
+		TerminationMode: TerminationModeAPIKey,
 	}
 
 	result, err := GenerateCloudInit(params)
@@ -341,6 +347,8 @@ func TestGenerateCloudInit_PaperspaceSpecific(t *testing.T) {
 		InstanceID: "12345",
 		Model:      "test-model",
 		APIKey:     "paperspace-api-key",
+
+		TerminationMode: TerminationModeAPIKey,
 	}
 
 	result, err := GenerateCloudInit(params)
@@ -545,3 +553,139 @@ func TestGenerateCloudInit_ValidYAML(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateCloudInit_SessionNetwork(t *testing.T) {
+	network, err := wireguard.NetworkForSlot(3)
+	if err != nil {
+		t.Fatalf("NetworkForSlot() error = %v", err)
+	}
+
+	params := NewCloudInitParams()
+	params.WireGuard.ServerPrivateKey = "server-key"
+	params.WireGuard.ClientPublicKey = "client-key"
+	params.WireGuard.ServerAddress = network.ServerAddress()
+	params.WireGuard.ClientAllowedIPs = network.ClientAllowedIPs()
+	params.Provider = "vast"
+	params.InstanceID = "instance-123"
+	params.Model = "qwen2.5-coder:7b"
+	params.APIKey = "api-key"
+
+	result, err := GenerateCloudInit(params)
+	if err != nil {
+		t.Fatalf("GenerateCloudInit() error = %v", err)
+	}
+
+	if !strings.Contains(result, "Address = 10.13.40.1/24") {
+		t.Error("expected session server address in WireGuard config")
+	}
+	if !strings.Contains(result, "-p 10.13.40.1:11434:11434") {
+		t.Error("expected Ollama to bind to the session server IP")
+	}
+	if strings.Contains(result, "10.13.37.1") {
+		t.Error("cloud-init should not reference the default network")
+	}
+}
+
+func TestWireGuardParams_ServerIP(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"10.13.37.1/24", "10.13.37.1"},
+		{"10.13.40.1/24", "10.13.40.1"},
+		{"10.13.37.1", "10.13.37.1"},
+	}
+
+	for _, tt := range tests {
+		w := WireGuardParams{ServerAddress: tt.address}
+		if got := w.ServerIP(); got != tt.want {
+			t.Errorf("ServerIP() for %q = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateCloudInit_TerminationModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     TerminationMode
+		token    string
+		relayURL string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "relay",
+			mode:     TerminationModeRelay,
+			token:    "relay-token",
+			relayURL: "http://10.13.37.2:51823/terminate",
+			want:     []string{`curl -sf --max-time 30 -X POST "http://10.13.37.2:51823/terminate"`, "Authorization: Bearer relay-token", "Termination relay unreachable, only shutting down"},
+		},
+		{name: "shutdown", mode: TerminationModeShutdown},
+		{name: "unset shuts down", mode: ""},
+		{name: "relay without token", mode: TerminationModeRelay, relayURL: "http://10.13.37.2:51823/terminate", wantErr: true},
+		{name: "relay without URL", mode: TerminationModeRelay, token: "relay-token", wantErr: true},
+		{
+			name:  "scoped",
+			mode:  TerminationModeScoped,
+			token: "scoped-credential",
+			want:  []string{`curl -X DELETE "https://console.vast.ai/api/v0/instances/${INSTANCE_ID}/"`, "Authorization: Bearer scoped-credential"},
+		},
+		{name: "scoped without key", mode: TerminationModeScoped, wantErr: true},
+		{name: "unknown", mode: "full-key", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := NewCloudInitParams()
+			params.WireGuard.ServerPrivateKey = "server-key"
+			params.WireGuard.ClientPublicKey = "client-key"
+			params.Provider = "vast"
+			params.InstanceID = "12345"
+			params.Model = "test-model"
+			params.APIKey = "vast-api-key"
+			params.TerminationMode = tt.mode
+			params.TerminationToken = tt.token
+			params.RelayURL = tt.relayURL
+
+			result, err := GenerateCloudInit(params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateCloudInit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			// The full API key is only written with TerminationModeAPIKey
+			if strings.Contains(result, "vast-api-key") {
+				t.Error("cloud-init must not contain the provider API key")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(result, want) {
+					t.Errorf("expected %q in output", want)
+				}
+			}
+			if tt.want == nil && strings.Contains(result, "curl -X") {
+				t.Error("expected no termination call without a credential")
+			}
+			if !strings.Contains(result, "shutdown -h now") {
+				t.Error("expected the deadman switch to always shut down")
+			}
+			if !strings.Contains(result, "permissions: '0700'") {
+				t.Error("expected deadman.sh to be readable by root only")
+			}
+		})
+	}
+}
+
+func TestGenerateCloudInit_APIKeyModeRequiresKey(t *testing.T) {
+	params := NewCloudInitParams()
+	params.WireGuard.ServerPrivateKey = "server-key"
+	params.WireGuard.ClientPublicKey = "client-key"
+	params.Provider = "vast"
+	params.Model = "test-model"
+	params.TerminationMode = TerminationModeAPIKey
+
+	if _, err := GenerateCloudInit(params); err == nil {
+		t.Error("expected error for api-key termination without an API key")
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/tmeurs/spinup/internal/clock"
 )
 
 // DeadmanConfig represents the configuration for the deadman switch.
@@ -24,6 +26,10 @@ type DeadmanConfig struct {
 	// CheckIntervalSeconds is how often the deadman script checks the heartbeat file.
 	// Default is 60 seconds.
 	CheckIntervalSeconds int
+
+	// Clock is used to measure the time since the last heartbeat.
+	// Default (nil) is the system clock.
+	Clock clock.Clock
 }
 
 // DeadmanStatus represents the current status of the deadman switch.
@@ -143,7 +149,7 @@ func (c *DeadmanConfig) RemainingTime(lastHeartbeat time.Time) time.Duration {
 	if lastHeartbeat.IsZero() {
 		return 0
 	}
-	elapsed := time.Since(lastHeartbeat)
+	elapsed := clock.OrReal(c.Clock).Since(lastHeartbeat)
 	remaining := c.Timeout() - elapsed
 	if remaining < 0 {
 		return 0
@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/tmeurs/spinup/internal/clock"
 )
 
 func TestNewDeadmanConfig(t *testing.T) {
@@ -180,6 +182,37 @@ func TestDeadmanConfig_IsExpired(t *testing.T) {
 	}
 }
 
+func TestDeadmanConfig_Clock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	cfg := NewDeadmanConfig()
+	cfg.Clock = fake
+	lastHeartbeat := fake.Now()
+
+	steps := []struct {
+		advance    time.Duration
+		wantStatus string
+		expired    bool
+	}{
+		{0, "10h 0m remaining", false},
+		{9*time.Hour + 30*time.Minute, "30m remaining", false},
+		{29 * time.Minute, "1m remaining", false},
+		{time.Minute, "expired", true},
+		{24 * time.Hour, "expired", true},
+	}
+	for _, step := range steps {
+		fake.Advance(step.advance)
+		elapsed := fake.Since(lastHeartbeat)
+
+		status := NewDeadmanStatus(cfg, lastHeartbeat)
+		if got := status.FormatRemaining(); got != step.wantStatus {
+			t.Errorf("after %v: FormatRemaining() = %q, want %q", elapsed, got, step.wantStatus)
+		}
+		if got := cfg.IsExpired(lastHeartbeat); got != step.expired {
+			t.Errorf("after %v: IsExpired() = %v, want %v", elapsed, got, step.expired)
+		}
+	}
+}
+
 func TestGetTerminationInfo(t *testing.T) {
 	providers := []struct {
 		name       string
@@ -5,13 +5,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"sort"
 	"strings"
 	"time"
 
+	"github.com/tmeurs/spinup/internal/clock"
 	"github.com/tmeurs/spinup/internal/config"
 	"github.com/tmeurs/spinup/internal/logging"
 	"github.com/tmeurs/spinup/internal/models"
+	"github.com/tmeurs/spinup/internal/offerexpr"
 	"github.com/tmeurs/spinup/internal/provider"
 	"github.com/tmeurs/spinup/internal/provider/registry"
 	"github.com/tmeurs/spinup/internal/wireguard"
@@ -83,12 +84,20 @@ type DeployConfig struct {
 	// Region is a specific region to use (empty means any region).
 	Region string
 
+	// Filter is an offer filter expression offers must match. Nil means the
+	// config's DefaultFilter.
+	Filter *offerexpr.Filter
+
 	// DeadmanTimeoutHours is the deadman switch timeout in hours.
 	DeadmanTimeoutHours int
 
 	// BootTimeout is the maximum time to wait for instance boot.
 	BootTimeout time.Duration
 
+	// BootPollInterval is how often the instance status is checked while it
+	// boots. Zero means every 5 seconds.
+	BootPollInterval time.Duration
+
 	// ModelPullTimeout is the maximum time to wait for model pull.
 	ModelPullTimeout time.Duration
 
@@ -98,6 +107,13 @@ type DeployConfig struct {
 	// HealthCheckTimeout is the maximum time to wait for health check.
 	HealthCheckTimeout time.Duration
 
+	// ProviderFetchTimeout is the maximum time to wait for a single provider's offers.
+	ProviderFetchTimeout time.Duration
+
+	// MaxAttempts is the number of ranked offers to try before giving up when
+	// an instance can't be created or doesn't boot. Zero means DefaultMaxAttempts.
+	MaxAttempts int
+
 	// DiskSizeGB is the disk size in GB for the instance.
 	DiskSizeGB int
 
@@ -108,13 +124,15 @@ type DeployConfig struct {
 // DefaultDeployConfig returns a DeployConfig with sensible defaults.
 func DefaultDeployConfig() *DeployConfig {
 	return &DeployConfig{
-		PreferSpot:          true,
-		DeadmanTimeoutHours: 10,
-		BootTimeout:         5 * time.Minute,
-		ModelPullTimeout:    15 * time.Minute,
-		TunnelTimeout:       2 * time.Minute,
-		HealthCheckTimeout:  30 * time.Second,
-		DiskSizeGB:          100,
+		PreferSpot:           true,
+		DeadmanTimeoutHours:  10,
+		BootTimeout:          5 * time.Minute,
+		ModelPullTimeout:     15 * time.Minute,
+		TunnelTimeout:        2 * time.Minute,
+		HealthCheckTimeout:   30 * time.Second,
+		ProviderFetchTimeout: DefaultProviderFetchTimeout,
+		MaxAttempts:          DefaultMaxAttempts,
+		DiskSizeGB:           100,
 	}
 }
 
@@ -141,6 +159,17 @@ func (c *DeployConfig) Validate() error {
 		return errors.New("disk size must be at least 50GB")
 	}
 
+	if c.MaxAttempts < 0 {
+		return errors.New("max attempts cannot be negative")
+	}
+
+	if c.BootPollInterval < 0 {
+		return errors.New("boot poll interval cannot be negative")
+	}
+	if c.MaxAttempts > MaxDeployAttempts {
+		return fmt.Errorf("max attempts cannot exceed %d", MaxDeployAttempts)
+	}
+
 	return nil
 }
 
@@ -163,6 +192,9 @@ type DeployProgress struct {
 
 	// Completed indicates if the step is complete.
 	Completed bool
+
+	// Attempt is the 1-based offer attempt during steps 2-4, zero otherwise.
+	Attempt int
 }
 
 // DeployResult holds the result of a successful deployment.
@@ -191,6 +223,17 @@ type DeployResult struct {
 	// ProvidersQueried is the number of providers that were queried.
 	ProvidersQueried int
 
+	// ProviderReports describes the offer request to each provider.
+	// Empty for resumed deployments.
+	ProviderReports []ProviderFetchReport
+
+	// Attempts records each offer that was tried, the last one being the
+	// deployed instance. Empty for resumed deployments.
+	Attempts []DeployAttempt
+
+	// SelectedScore is the score of SelectedOffer. Nil for resumed deployments.
+	SelectedScore *OfferScore
+
 	// StartedAt is when the deployment started.
 	StartedAt time.Time
 
@@ -209,11 +252,50 @@ type Deployer struct {
 	deployCfg    *DeployConfig
 	stateManager *config.StateManager
 	progressCb   func(DeployProgress)
+
+	// network is the tunnel addressing allocated to this deployment's session.
+	network *wireguard.Network
+
+	// journal is the write-ahead journal of the running deployment.
+	journal *DeployJournal
+
+	// attempt is the current offer attempt, reported with progress.
+	attempt int
+
+	// termination is the deadman termination credential of the instance
+	// created last.
+	termination *terminationSetup
+
+	// scorer ranks offers; nil means NewDefaultOfferScorer.
+	scorer OfferScorer
+
+	// offerFilter is the filter offers were fetched with, reused to re-check
+	// an offer before renting it.
+	offerFilter provider.OfferFilter
+
+	// providers are the providers to fetch offers from; nil means all
+	// configured providers.
+	providers []provider.Provider
 }
 
 // DeployerOption is a functional option for Deployer.
 type DeployerOption func(*Deployer)
 
+// WithOfferScorer sets the scorer used to rank offers.
+func WithOfferScorer(scorer OfferScorer) DeployerOption {
+	return func(d *Deployer) {
+		d.scorer = scorer
+	}
+}
+
+// WithProviders sets the providers to fetch offers from instead of all
+// configured providers.
+func WithProviders(providers ...provider.Provider) DeployerOption {
+	return func(d *Deployer) {
+		d.providers = providers
+	}
+}
+
 // WithProgressCallback sets a callback for progress reporting.
 func WithProgressCallback(cb func(DeployProgress)) DeployerOption {
 	return func(d *Deployer) {
@@ -268,69 +350,235 @@ func (d *Deployer) Deploy(ctx context.Context) (*DeployResult, error) {
 	}
 	result.Model = model
 
+	// Pick a tunnel interface and subnet not used by other sessions
+	network, err := d.allocateNetwork()
+	if err != nil {
+		return nil, err
+	}
+	d.network = network
+
+	// Start the write-ahead journal; it is removed again once the outcome is settled
+	d.startJournal()
+
 	// Step 1: Fetch prices from all providers
 	d.reportProgress(StepFetchPrices, "Fetching prices from providers...", "", false)
-	offers, providerCount, err := d.fetchOffers(ctx, model)
+	offers, reports, err := d.fetchOffers(ctx, model)
+	result.ProviderReports = reports
 	if err != nil {
 		d.reportProgress(StepFetchPrices, "Failed to fetch prices", err.Error(), false)
+		d.removeJournal()
 		return nil, fmt.Errorf("step 1 failed: %w", err)
 	}
 	result.TotalProviderOffers = len(offers)
-	result.ProvidersQueried = providerCount
-	d.reportProgress(StepFetchPrices, fmt.Sprintf("Found %d offers from %d providers", len(offers), providerCount), "", true)
+	for _, r := range reports {
+		if r.OK() {
+			result.ProvidersQueried++
+		}
+	}
+	var details []string
+	if cached := countCached(reports); cached > 0 {
+		details = append(details, fmt.Sprintf("%d cached", cached))
+	}
+	if failed := SummarizeFetchErrors(reports); failed != "" {
+		details = append(details, "failed: "+failed)
+	}
+	fetchDetail := strings.Join(details, "; ")
+	d.reportProgress(StepFetchPrices, fmt.Sprintf("Found %d offers from %d providers", len(offers), result.ProvidersQueried), fetchDetail, true)
 
-	// Step 2: Select best offer
+	// Step 2: Rank offers, best first
 	d.reportProgress(StepSelectOffer, "Selecting best option...", "", false)
-	selectedOffer, selectedProvider, err := d.selectOffer(ctx, offers, model)
+	ranked, err := d.rankOffers(ctx, offers, model)
 	if err != nil {
 		d.reportProgress(StepSelectOffer, "Failed to select offer", err.Error(), false)
+		d.removeJournal()
 		return nil, fmt.Errorf("step 2 failed: %w", err)
 	}
-	result.SelectedOffer = selectedOffer
-	result.Provider = selectedProvider
-	priceStr := d.formatOfferPrice(selectedOffer)
-	d.reportProgress(StepSelectOffer, fmt.Sprintf("Selected: %s %s %s @ %s", selectedOffer.Provider, selectedOffer.GPU, selectedOffer.Region, priceStr), "", true)
 
 	// Get client WireGuard keys from config or generate new ones
 	clientKeyPair, err := d.getClientKeyPair()
 	if err != nil {
+		d.removeJournal()
 		return nil, fmt.Errorf("failed to get WireGuard keys: %w", err)
 	}
 
-	// Step 3: Create instance
-	d.reportProgress(StepCreateInstance, "Creating instance...", "", false)
-	instance, wgConfig, err := d.createInstance(ctx, selectedProvider, selectedOffer, model, clientKeyPair)
+	// Steps 2-4: Create and boot an instance, failing over to the next offer
+	selectedProvider, err := d.launchInstance(ctx, ranked, model, clientKeyPair, result)
 	if err != nil {
-		d.reportProgress(StepCreateInstance, "Failed to create instance", err.Error(), false)
-		return nil, fmt.Errorf("step 3 failed: %w", err)
+		return nil, err
+	}
+
+	return d.completeDeploy(ctx, selectedProvider, result, StepWaitBoot)
+}
+
+// Resume continues a deployment from its journal.
+// If the journal records no instance, the journal is discarded and a fresh
+// deployment is started. Otherwise the journaled instance is checked at the
+// provider and the remaining steps are run against it. The Deployer should be
+// created with the journal's DeployConfig.
+func (d *Deployer) Resume(ctx context.Context, journal *DeployJournal) (*DeployResult, error) {
+	if journal == nil {
+		return nil, errors.New("deploy journal is required")
+	}
+
+	if !journal.HasInstance() {
+		// Nothing was created; start over
+		if err := journal.Remove(); err != nil {
+			return nil, err
+		}
+		return d.Deploy(ctx)
+	}
+
+	result := &DeployResult{
+		StartedAt:     time.Now(),
+		SelectedOffer: journal.Offer,
+	}
+
+	model, err := models.GetModelByName(journal.Config.Model)
+	if err != nil {
+		return nil, fmt.Errorf("invalid model: %w", err)
+	}
+	result.Model = model
+
+	network, err := wireguard.NetworkForSlot(journal.NetworkSlot)
+	if err != nil {
+		return nil, err
+	}
+	d.network = network
+
+	p, err := registry.GetProviderByName(journal.Provider, d.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider: %w", err)
+	}
+	result.Provider = p
+
+	wgConfig, err := d.resumeWireGuardConfig(journal)
+	if err != nil {
+		return nil, err
 	}
-	result.Instance = instance
 	result.WireGuardConfig = wgConfig
-	d.reportProgress(StepCreateInstance, fmt.Sprintf("Instance %s created", instance.ID), "", true)
+
+	journal.Status = JournalInProgress
+	journal.Error = ""
+	d.journal = journal
+	if journal.Termination != "" {
+		d.termination = &terminationSetup{
+			mode:         journal.Termination,
+			tokenHash:    journal.RelayTokenHash,
+			credentialID: journal.TerminationCredentialID,
+		}
+	}
+
+	// Steps 1-3 are done; report them so progress displays stay complete
+	d.reportProgress(StepFetchPrices, "Prices fetched (resumed)", "", true)
+	d.reportProgress(StepSelectOffer, fmt.Sprintf("Selected: %s (resumed)", journal.Provider), "", true)
+
+	instance, err := p.GetInstance(ctx, journal.InstanceID)
+	if err != nil {
+		if isInstanceNotFound(err) {
+			d.removeJournal()
+			return nil, fmt.Errorf("%w: %s", ErrResumeInstanceGone, journal.InstanceID)
+		}
+		return nil, fmt.Errorf("failed to get instance %s: %w", journal.InstanceID, err)
+	}
+	if instance.Status.IsTerminal() {
+		d.removeJournal()
+		return nil, fmt.Errorf("%w: %s is %s", ErrResumeInstanceGone, journal.InstanceID, instance.Status)
+	}
+	result.Instance = instance
+	d.reportProgress(StepCreateInstance, fmt.Sprintf("Instance %s created (resumed)", instance.ID), "", true)
+
+	return d.completeDeploy(ctx, p, result, journal.LastCompletedStep)
+}
+
+// resumeWireGuardConfig rebuilds the client side of a journaled deployment's
+// WireGuard config. The server side lives on the instance and is not needed.
+func (d *Deployer) resumeWireGuardConfig(journal *DeployJournal) (*wireguard.ConfigPair, error) {
+	if journal.ServerPublicKey == "" {
+		return nil, errors.New("deploy journal has no server public key")
+	}
+
+	var clientKeyPair *wireguard.KeyPair
+	var err error
+	switch {
+	case journal.ClientKeySecret != "":
+		var key string
+		if key, err = d.loadClientKey(journal.ClientKeySecret); err != nil {
+			return nil, err
+		}
+		clientKeyPair, err = wireguard.KeyPairFromPrivate(key)
+	case d.cfg.WireGuardPrivateKey != "":
+		clientKeyPair, err = wireguard.KeyPairFromPrivate(d.cfg.WireGuardPrivateKey)
+	default:
+		return nil, errors.New("deploy journal has no client key and WIREGUARD_PRIVATE_KEY is not set")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid client WireGuard key: %w", err)
+	}
+
+	client := wireguard.NewClientConfig()
+	client.ClientPrivateKey = clientKeyPair.PrivateKey
+	client.ServerPublicKey = journal.ServerPublicKey
+	client.ServerEndpoint = "pending:51820"
+
+	wgConfig := &wireguard.ConfigPair{
+		Client:        client,
+		ClientKeyPair: clientKeyPair,
+		ServerKeyPair: &wireguard.KeyPair{PublicKey: journal.ServerPublicKey},
+	}
+	d.wgNetwork().Apply(wgConfig)
+
+	return wgConfig, nil
+}
+
+// isInstanceNotFound returns true if err reports a missing instance.
+func isInstanceNotFound(err error) bool {
+	var provErr *provider.ProviderError
+	return errors.As(err, &provErr) && provErr.Code == provider.ErrInstanceNotFound.Code
+}
+
+// completeDeploy runs steps 4-8 for an instance that has been created.
+// Steps up to and including lastCompleted that can be skipped on resume
+// (boot and model pull) are reported as resumed instead of being re-run.
+// The tunnel is always (re)configured since it does not survive the process.
+func (d *Deployer) completeDeploy(ctx context.Context, p provider.Provider, result *DeployResult, lastCompleted DeployStep) (*DeployResult, error) {
+	instance := result.Instance
+	wgConfig := result.WireGuardConfig
 
 	// From this point on, we need to cleanup on failure
 	cleanup := func() {
 		cleanupCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
-		_ = selectedProvider.TerminateInstance(cleanupCtx, instance.ID)
+		if err := p.TerminateInstance(cleanupCtx, instance.ID); err != nil {
+			d.failJournal(fmt.Errorf("failed to terminate instance %s: %w", instance.ID, err))
+			return
+		}
+		if d.termination != nil {
+			revokeTerminationCredential(p, d.termination.credentialID)
+		}
+		d.removeJournal()
 	}
 
 	// Step 4: Wait for boot
-	d.reportProgress(StepWaitBoot, "Waiting for instance to boot...", "", false)
-	if err := d.waitForBoot(ctx, selectedProvider, instance.ID); err != nil {
-		d.reportProgress(StepWaitBoot, "Instance failed to boot", err.Error(), false)
-		cleanup()
-		return nil, fmt.Errorf("step 4 failed: %w", err)
+	if lastCompleted < StepWaitBoot {
+		d.reportProgress(StepWaitBoot, "Waiting for instance to boot...", "", false)
+		if err := d.waitForBoot(ctx, p, instance.ID); err != nil {
+			d.reportProgress(StepWaitBoot, "Instance failed to boot", err.Error(), false)
+			cleanup()
+			return nil, fmt.Errorf("step 4 failed: %w", err)
+		}
 	}
 
 	// Refresh instance info to get public IP
-	instance, err = selectedProvider.GetInstance(ctx, instance.ID)
+	instance, err := p.GetInstance(ctx, instance.ID)
 	if err != nil {
 		d.reportProgress(StepWaitBoot, "Failed to get instance info", err.Error(), false)
 		cleanup()
 		return nil, fmt.Errorf("step 4 failed: %w", err)
 	}
 	result.Instance = instance
+	if d.journal != nil {
+		d.journal.PublicIP = instance.PublicIP
+	}
 	d.reportProgress(StepWaitBoot, fmt.Sprintf("Instance running at %s", instance.PublicIP), "", true)
 
 	// Update WireGuard config with actual endpoint
@@ -341,6 +589,10 @@ func (d *Deployer) Deploy(ctx context.Context) (*DeployResult, error) {
 
 	// Step 5: Configure WireGuard tunnel
 	d.reportProgress(StepConfigureWireGuard, "Configuring WireGuard tunnel...", "", false)
+	if lastCompleted >= StepConfigureWireGuard {
+		// A previous run may have left the interface up; start clean
+		d.teardownWireGuard(ctx)
+	}
 	if err := d.setupWireGuard(ctx, wgConfig); err != nil {
 		d.reportProgress(StepConfigureWireGuard, "Failed to configure WireGuard", err.Error(), false)
 		cleanup()
@@ -349,14 +601,18 @@ func (d *Deployer) Deploy(ctx context.Context) (*DeployResult, error) {
 	d.reportProgress(StepConfigureWireGuard, "Tunnel configured and connected", "", true)
 
 	// Step 6: Wait for model to be pulled (via cloud-init)
-	d.reportProgress(StepInstallModel, fmt.Sprintf("Pulling model %s...", d.deployCfg.Model), "", false)
-	if err := d.waitForModel(ctx); err != nil {
-		d.reportProgress(StepInstallModel, "Failed to pull model", err.Error(), false)
-		d.teardownWireGuard(ctx)
-		cleanup()
-		return nil, fmt.Errorf("step 6 failed: %w", err)
+	if lastCompleted < StepInstallModel {
+		d.reportProgress(StepInstallModel, fmt.Sprintf("Pulling model %s...", d.deployCfg.Model), "", false)
+		if err := d.waitForModel(ctx); err != nil {
+			d.reportProgress(StepInstallModel, "Failed to pull model", err.Error(), false)
+			d.teardownWireGuard(ctx)
+			cleanup()
+			return nil, fmt.Errorf("step 6 failed: %w", err)
+		}
+		d.reportProgress(StepInstallModel, "Model ready", "", true)
+	} else {
+		d.reportProgress(StepInstallModel, "Model ready (resumed)", "", true)
 	}
-	d.reportProgress(StepInstallModel, "Model ready", "", true)
 
 	// Step 7: Verify deadman switch
 	d.reportProgress(StepConfigureDeadman, "Verifying deadman switch...", "", false)
@@ -373,23 +629,60 @@ func (d *Deployer) Deploy(ctx context.Context) (*DeployResult, error) {
 	}
 	d.reportProgress(StepVerifyHealth, "Model responding", "", true)
 
-	result.OllamaEndpoint = wireguard.OllamaEndpoint()
+	result.OllamaEndpoint = d.wgNetwork().OllamaEndpoint()
 	result.CompletedAt = time.Now()
 
 	// Save state if we have a state manager
 	if err := d.saveState(result); err != nil {
-		// Don't fail the deployment, just log the error
+		// Don't fail the deployment, just log the error. The journal is kept
+		// so the deployment can be resumed or cleaned up later.
 		d.reportProgress(StepVerifyHealth, "Warning: failed to save state", err.Error(), true)
+		d.failJournal(err)
+		return result, nil
 	}
+	d.removeJournal()
 
 	return result, nil
 }
 
-// fetchOffers fetches offers from all configured providers.
-func (d *Deployer) fetchOffers(ctx context.Context, model *models.Model) ([]rankedOffer, int, error) {
+// offerProviders returns the providers to fetch offers from: the one named
+// in the deploy config, or else all of them.
+func (d *Deployer) offerProviders() ([]provider.Provider, error) {
+	name := d.deployCfg.ProviderName
+	if d.providers != nil {
+		if name == "" {
+			return d.providers, nil
+		}
+		for _, p := range d.providers {
+			if p.Name() == name {
+				return []provider.Provider{p}, nil
+			}
+		}
+		return nil, fmt.Errorf("failed to get provider %s: not among the deployer's providers", name)
+	}
+
+	// If a specific provider is requested, only query that one
+	if name != "" {
+		p, err := registry.GetProviderByName(name, d.cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get provider %s: %w", name, err)
+		}
+		return []provider.Provider{p}, nil
+	}
+
 	providers, err := registry.GetConfiguredProviders(d.cfg)
 	if err != nil {
-		return nil, 0, fmt.Errorf("no providers configured: %w", err)
+		return nil, fmt.Errorf("no providers configured: %w", err)
+	}
+	return providers, nil
+}
+
+// fetchOffers fetches offers from all configured providers in parallel.
+// The returned reports cover every queried provider, also on error.
+func (d *Deployer) fetchOffers(ctx context.Context, model *models.Model) ([]rankedOffer, []ProviderFetchReport, error) {
+	providers, err := d.offerProviders()
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Build filter
@@ -407,61 +700,123 @@ func (d *Deployer) fetchOffers(ctx context.Context, model *models.Model) ([]rank
 		filter.OnDemandOnly = true
 	}
 
+	d.offerFilter = filter
+	fetched := d.offerCache().FetchOffers(ctx, providers, filter, d.deployCfg.ProviderFetchTimeout)
+	reports := FetchReports(fetched)
+
+	expr := d.offerExpr()
 	var allOffers []rankedOffer
-	providerCount := 0
+	filtered := 0
+	for _, f := range fetched {
+		for _, o := range f.Offers {
+			if !expr.Match(o) {
+				filtered++
+				continue
+			}
+			allOffers = append(allOffers, rankedOffer{Offer: o, Provider: f.Provider})
+		}
+	}
 
-	// If a specific provider is requested, only query that one
-	if d.deployCfg.ProviderName != "" {
-		p, err := registry.GetProviderByName(d.deployCfg.ProviderName, d.cfg)
-		if err != nil {
-			return nil, 0, fmt.Errorf("failed to get provider %s: %w", d.deployCfg.ProviderName, err)
+	if len(allOffers) == 0 {
+		if filtered > 0 {
+			return nil, reports, fmt.Errorf("none of %d compatible offers match the filter %q", filtered, expr)
+		}
+		if failed := SummarizeFetchErrors(reports); failed != "" {
+			return nil, reports, fmt.Errorf("no compatible offers found from any provider (failed: %s)", failed)
 		}
-		providers = []provider.Provider{p}
+		return nil, reports, errors.New("no compatible offers found from any provider")
 	}
 
-	for _, p := range providers {
-		offers, err := p.GetOffers(ctx, filter)
-		if err != nil {
-			// Log error but continue with other providers
-			continue
+	return allOffers, reports, nil
+}
+
+// offerExpr returns the filter expression offers must match, nil if any
+// offer will do.
+func (d *Deployer) offerExpr() *offerexpr.Filter {
+	if d.deployCfg.Filter != nil {
+		return d.deployCfg.Filter
+	}
+	return d.cfg.DefaultFilter
+}
+
+// offerCache returns the offer cache to fetch offers through, or nil if
+// caching is disabled or there is no state directory.
+func (d *Deployer) offerCache() *OfferCache {
+	if d.stateManager == nil || d.cfg.OfferCacheTTL <= 0 {
+		return nil
+	}
+	cache, err := LoadOfferCache(d.stateManager, d.cfg.OfferCacheTTL)
+	if err != nil {
+		logging.Warn().Err(err).Msg("Starting a new offer cache")
+	}
+	return cache
+}
+
+// recheckOffer asks the provider whether an offer is still available, since
+// it may come from the offer cache or have been rented by someone else since
+// it was listed. It returns the current listing, or an error wrapping
+// provider.ErrOfferNotFound if the offer is gone.
+func (d *Deployer) recheckOffer(ctx context.Context, p provider.Provider, offer *provider.Offer) (*provider.Offer, error) {
+	current := fetchProviderOffers(ctx, p, d.offerFilter, d.providerFetchTimeout())
+	if !current.Report.OK() {
+		return nil, fmt.Errorf("failed to re-check offer %s: %w", offer.OfferID, current.Report.Err)
+	}
+
+	for i := range current.Offers {
+		if current.Offers[i].OfferID == offer.OfferID {
+			return &current.Offers[i], nil
 		}
-		providerCount++
-		for _, o := range offers {
-			if o.Available {
-				allOffers = append(allOffers, rankedOffer{Offer: o, Provider: p})
+	}
+
+	// Whatever is cached for this provider is out of date too
+	if d.stateManager != nil {
+		if cache, err := LoadOfferCache(d.stateManager, d.cfg.OfferCacheTTL); err == nil && len(cache.Entries) > 0 {
+			cache.Invalidate(p.Name())
+			if err := cache.Save(); err != nil {
+				logging.Warn().Err(err).Msg("Failed to save offer cache")
 			}
 		}
 	}
+	return nil, fmt.Errorf("offer %s is no longer available: %w", offer.OfferID, provider.ErrOfferNotFound)
+}
 
-	if len(allOffers) == 0 {
-		return nil, providerCount, errors.New("no compatible offers found from any provider")
+// providerFetchTimeout returns the deadline for a single provider's offers.
+func (d *Deployer) providerFetchTimeout() time.Duration {
+	if d.deployCfg.ProviderFetchTimeout > 0 {
+		return d.deployCfg.ProviderFetchTimeout
 	}
-
-	return allOffers, providerCount, nil
+	return DefaultProviderFetchTimeout
 }
 
-// rankedOffer pairs an offer with its provider.
+// rankedOffer pairs an offer with its provider and score.
 type rankedOffer struct {
 	Offer    provider.Offer
 	Provider provider.Provider
+	Score    OfferScore
 }
 
-// selectOffer selects the best offer based on configuration.
-func (d *Deployer) selectOffer(_ context.Context, offers []rankedOffer, _ *models.Model) (*provider.Offer, provider.Provider, error) {
+// rankOffers orders offers from best to worst, which is the order they are
+// tried in when instance creation fails over.
+func (d *Deployer) rankOffers(_ context.Context, offers []rankedOffer, _ *models.Model) ([]rankedOffer, error) {
 	if len(offers) == 0 {
-		return nil, nil, errors.New("no offers to select from")
+		return nil, errors.New("no offers to select from")
+	}
+
+	scorer := d.scorer
+	if scorer == nil {
+		scorer = NewDefaultOfferScorer(d.cfg, d.stateManager, d.deployCfg.PreferSpot)
 	}
 
-	// Sort offers by price (prefer spot if available and configured)
-	sort.Slice(offers, func(i, j int) bool {
-		priceI := d.effectivePrice(&offers[i].Offer)
-		priceJ := d.effectivePrice(&offers[j].Offer)
-		return priceI < priceJ
-	})
+	plain := make([]provider.Offer, len(offers))
+	for i := range offers {
+		plain[i] = offers[i].Offer
+	}
+	for i, score := range scorer.Score(plain) {
+		offers[i].Score = score
+	}
 
-	// Return the cheapest
-	best := offers[0]
-	return &best.Offer, best.Provider, nil
+	sortByScore(offers, d.effectivePrice)
+	return offers, nil
 }
 
 // effectivePrice returns the effective price for an offer.
@@ -504,23 +859,30 @@ func (d *Deployer) createInstance(ctx context.Context, p provider.Provider, offe
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to generate WireGuard config: %w", err)
 	}
+	d.wgNetwork().Apply(wgConfig)
 
 	// Determine if we're using spot
 	useSpot := d.deployCfg.PreferSpot && offer.SpotPrice != nil && *offer.SpotPrice > 0
 
-	// Get API key for the provider (for deadman self-termination)
-	apiKey := d.getAPIKeyForProvider(p.Name())
+	// Prepare the credential the deadman switch terminates the instance with
+	termination, err := d.newTerminationSetup(ctx, p)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	// Generate cloud-init (instance ID will be updated after creation)
-	cloudInit, err := GenerateCloudInitFromConfigPair(
-		wgConfig,
+	params := CloudInitParamsFromServerConfig(
+		wgConfig.Server,
 		p.Name(),
 		"pending", // Will be set by provider
 		model.Name,
-		apiKey,
+		"",
 		DeadmanTimeoutFromHours(d.deployCfg.DeadmanTimeoutHours),
 	)
+	termination.apply(params)
+	cloudInit, err := GenerateCloudInit(params)
 	if err != nil {
+		revokeTerminationCredential(p, termination.credentialID)
 		return nil, nil, fmt.Errorf("failed to generate cloud-init: %w", err)
 	}
 
@@ -535,8 +897,10 @@ func (d *Deployer) createInstance(ctx context.Context, p provider.Provider, offe
 
 	instance, err := p.CreateInstance(ctx, req)
 	if err != nil {
+		revokeTerminationCredential(p, termination.credentialID)
 		return nil, nil, fmt.Errorf("failed to create instance: %w", err)
 	}
+	d.termination = termination
 
 	return instance, wgConfig, nil
 }
@@ -564,7 +928,11 @@ func (d *Deployer) waitForBoot(ctx context.Context, p provider.Provider, instanc
 	ctx, cancel := context.WithTimeout(ctx, d.deployCfg.BootTimeout)
 	defer cancel()
 
-	ticker := time.NewTicker(5 * time.Second)
+	interval := d.deployCfg.BootPollInterval
+	if interval == 0 {
+		interval = bootPollInterval
+	}
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -590,6 +958,7 @@ func (d *Deployer) waitForBoot(ctx context.Context, p provider.Provider, instanc
 func (d *Deployer) setupWireGuard(ctx context.Context, wgConfig *wireguard.ConfigPair) error {
 	// Create tunnel config from client config
 	tunnelCfg := wireguard.TunnelConfigFromClientConfig(wgConfig.Client)
+	tunnelCfg.InterfaceName = d.wgNetwork().InterfaceName
 
 	// Set up the tunnel
 	tunnel, err := wireguard.SetupTunnel(ctx, tunnelCfg)
@@ -600,8 +969,8 @@ func (d *Deployer) setupWireGuard(ctx context.Context, wgConfig *wireguard.Confi
 
 	// Wait for connection
 	verifyOpts := &wireguard.VerifyOptions{
-		InterfaceName: wireguard.InterfaceName,
-		ServerIP:      wireguard.ServerIP,
+		InterfaceName: d.wgNetwork().InterfaceName,
+		ServerIP:      d.wgNetwork().ServerIP,
 		CheckOllama:   false, // Don't check Ollama yet, model may still be loading
 		Timeout:       d.deployCfg.TunnelTimeout,
 	}
@@ -618,7 +987,48 @@ func (d *Deployer) setupWireGuard(ctx context.Context, wgConfig *wireguard.Confi
 
 // teardownWireGuard tears down the WireGuard tunnel.
 func (d *Deployer) teardownWireGuard(ctx context.Context) {
-	_ = wireguard.TeardownTunnel(ctx, wireguard.InterfaceName)
+	_ = wireguard.TeardownTunnel(ctx, d.wgNetwork().InterfaceName)
+}
+
+// wgNetwork returns the session's tunnel network, defaulting to slot 0.
+func (d *Deployer) wgNetwork() *wireguard.Network {
+	if d.network == nil {
+		return wireguard.DefaultNetwork()
+	}
+	return d.network
+}
+
+// allocateNetwork picks the lowest network slot whose interface is not used
+// by another session in the state file.
+func (d *Deployer) allocateNetwork() (*wireguard.Network, error) {
+	if d.stateManager == nil {
+		return wireguard.DefaultNetwork(), nil
+	}
+
+	sessions, err := d.stateManager.ListSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sessions: %w", err)
+	}
+
+	used := make(map[string]bool)
+	for name, state := range sessions {
+		if name == d.stateManager.Session() || state == nil || state.WireGuard == nil {
+			continue
+		}
+		used[state.WireGuard.InterfaceName] = true
+	}
+
+	for slot := 0; slot <= wireguard.MaxNetworkSlot; slot++ {
+		network, err := wireguard.NetworkForSlot(slot)
+		if err != nil {
+			return nil, err
+		}
+		if !used[network.InterfaceName] {
+			return network, nil
+		}
+	}
+
+	return nil, errors.New("no free WireGuard network for a new session")
 }
 
 // waitForModel waits for the model to be pulled and ready.
@@ -630,8 +1040,8 @@ func (d *Deployer) waitForModel(ctx context.Context) error {
 	defer ticker.Stop()
 
 	verifyOpts := &wireguard.VerifyOptions{
-		InterfaceName: wireguard.InterfaceName,
-		ServerIP:      wireguard.ServerIP,
+		InterfaceName: d.wgNetwork().InterfaceName,
+		ServerIP:      d.wgNetwork().ServerIP,
 		CheckOllama:   true,
 		Timeout:       10 * time.Second,
 	}
@@ -655,8 +1065,8 @@ func (d *Deployer) verifyHealth(ctx context.Context) error {
 	defer cancel()
 
 	verifyOpts := &wireguard.VerifyOptions{
-		InterfaceName: wireguard.InterfaceName,
-		ServerIP:      wireguard.ServerIP,
+		InterfaceName: d.wgNetwork().InterfaceName,
+		ServerIP:      d.wgNetwork().ServerIP,
 		CheckOllama:   true,
 		Timeout:       d.deployCfg.HealthCheckTimeout,
 	}
@@ -696,7 +1106,7 @@ func (d *Deployer) saveState(result *DeployResult) error {
 			Region:      result.Instance.Region,
 			Type:        instanceType,
 			PublicIP:    result.Instance.PublicIP,
-			WireGuardIP: wireguard.ServerIP,
+			WireGuardIP: d.wgNetwork().ServerIP,
 			CreatedAt:   result.Instance.CreatedAt,
 		},
 		&config.ModelState{
@@ -705,24 +1115,44 @@ func (d *Deployer) saveState(result *DeployResult) error {
 		},
 		&config.WireGuardState{
 			ServerPublicKey: result.WireGuardConfig.ServerKeyPair.PublicKey,
-			InterfaceName:   wireguard.InterfaceName,
+			InterfaceName:   d.wgNetwork().InterfaceName,
+			Subnet:          d.wgNetwork().Subnet(),
 		},
 		&config.CostState{
 			HourlyRate:  result.Instance.HourlyRate,
 			Accumulated: 0,
 			Currency:    "EUR",
 		},
-		&config.DeadmanState{
-			TimeoutHours:  d.deployCfg.DeadmanTimeoutHours,
-			LastHeartbeat: time.Now().UTC(),
-		},
+		d.deadmanState(),
 	)
 
 	return d.stateManager.SaveState(state)
 }
 
+// deadmanState returns the deadman state of the deployed instance.
+func (d *Deployer) deadmanState() *config.DeadmanState {
+	deadman := &config.DeadmanState{
+		TimeoutHours:  d.deployCfg.DeadmanTimeoutHours,
+		LastHeartbeat: time.Now().UTC(),
+	}
+	if d.termination != nil {
+		deadman.Termination = string(d.termination.mode)
+		switch d.termination.mode {
+		case TerminationModeRelay:
+			deadman.RelayAddr = d.relayAddr()
+			deadman.RelayTokenHash = d.termination.tokenHash
+		case TerminationModeScoped:
+			deadman.TerminationCredentialID = d.termination.credentialID
+		}
+	}
+	return deadman
+}
+
 // reportProgress reports progress to the callback if set.
+// Every transition is also written to the deploy journal.
 func (d *Deployer) reportProgress(step DeployStep, message, detail string, completed bool) {
+	d.recordJournal(step, message, detail, completed)
+
 	if d.progressCb == nil {
 		return
 	}
@@ -733,6 +1163,7 @@ func (d *Deployer) reportProgress(step DeployStep, message, detail string, compl
 		Message:    message,
 		Detail:     detail,
 		Completed:  completed,
+		Attempt:    d.attempt,
 	}
 
 	d.progressCb(progress)
@@ -923,6 +1354,13 @@ type Stopper struct {
 	progressCb      func(StopProgress)
 	manualVerifyCb  ManualVerificationCallback
 	criticalAlertCb CriticalAlertCallback
+
+	// provider is the provider to stop the instance at; nil means the one
+	// named in the state, from the registry.
+	provider provider.Provider
+
+	// clock is used to compute the session's duration and cost.
+	clock clock.Clock
 }
 
 // StopperOption is a functional option for Stopper.
@@ -942,6 +1380,21 @@ func WithStopStateManager(sm *config.StateManager) StopperOption {
 	}
 }
 
+// WithStopProvider sets the provider to stop the instance at instead of
+// the provider named in the state.
+func WithStopProvider(p provider.Provider) StopperOption {
+	return func(s *Stopper) {
+		s.provider = p
+	}
+}
+
+// WithStopClock sets the clock the session's duration and cost are computed with.
+func WithStopClock(c clock.Clock) StopperOption {
+	return func(s *Stopper) {
+		s.clock = c
+	}
+}
+
 // NewStopper creates a new Stopper with the given configuration.
 func NewStopper(cfg *config.Config, stopCfg *StopConfig, opts ...StopperOption) (*Stopper, error) {
 	if cfg == nil {
@@ -963,6 +1416,7 @@ func NewStopper(cfg *config.Config, stopCfg *StopConfig, opts ...StopperOption)
 	for _, opt := range opts {
 		opt(s)
 	}
+	s.clock = clock.OrReal(s.clock)
 
 	return s, nil
 }
@@ -998,16 +1452,19 @@ func (s *Stopper) Stop(ctx context.Context) (*StopResult, error) {
 	// Calculate session cost and duration
 	if state.Cost != nil {
 		// Calculate cost based on time since instance creation
-		duration := time.Since(state.Instance.CreatedAt)
+		duration := state.Instance.DurationAt(s.clock.Now())
 		hours := duration.Hours()
 		result.SessionCost = hours * state.Cost.HourlyRate
 		result.SessionDuration = duration
 	}
 
 	// Get the provider
-	p, err := registry.GetProviderByName(state.Instance.Provider, s.cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get provider: %w", err)
+	p := s.provider
+	if p == nil {
+		p, err = registry.GetProviderByName(state.Instance.Provider, s.cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get provider: %w", err)
+		}
 	}
 
 	// Step 1: Terminate instance with retry
@@ -1017,6 +1474,9 @@ func (s *Stopper) Stop(ctx context.Context) (*StopResult, error) {
 		return nil, fmt.Errorf("step 1 failed: %w", err)
 	}
 	s.reportStopProgress(StopStepTerminate, "Instance terminated", "", true, false)
+	if state.Deadman != nil {
+		revokeTerminationCredential(p, state.Deadman.TerminationCredentialID)
+	}
 
 	// Step 2: Verify billing stopped
 	s.reportStopProgress(StopStepVerifyBilling, "Verifying billing stopped...", "", false, false)
@@ -1046,7 +1506,11 @@ func (s *Stopper) Stop(ctx context.Context) (*StopResult, error) {
 
 	// Step 3: Remove WireGuard tunnel
 	s.reportStopProgress(StopStepRemoveTunnel, "Removing WireGuard tunnel...", "", false, false)
-	if err := wireguard.TeardownTunnel(ctx, wireguard.InterfaceName); err != nil {
+	interfaceName := wireguard.InterfaceName
+	if state.WireGuard != nil && state.WireGuard.InterfaceName != "" {
+		interfaceName = state.WireGuard.InterfaceName
+	}
+	if err := wireguard.TeardownTunnel(ctx, interfaceName); err != nil {
 		// Log but don't fail - tunnel may already be down
 		s.reportStopProgress(StopStepRemoveTunnel, "Tunnel removal (may have already been removed)", err.Error(), true, true)
 	} else {
@@ -1,13 +1,18 @@
 package deploy
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/models"
+	"github.com/tmeurs/spinup/internal/offerexpr"
 	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/mock"
 )
 
 func TestDefaultDeployConfig(t *testing.T) {
@@ -104,6 +109,17 @@ func TestDeployConfig_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "negative boot poll interval",
+			config: &DeployConfig{
+				Model:               "qwen2.5-coder:7b",
+				DeadmanTimeoutHours: 10,
+				DiskSizeGB:          100,
+				BootPollInterval:    -time.Second,
+			},
+			wantErr: true,
+			errMsg:  "boot poll interval cannot be negative",
+		},
 		{
 			name: "valid config with minimum values",
 			config: &DeployConfig{
@@ -352,6 +368,79 @@ func TestWithProgressCallback(t *testing.T) {
 	}
 }
 
+func TestDeployer_offerProviders(t *testing.T) {
+	vast := mock.New(mock.WithName("vast"))
+	lambda := mock.New(mock.WithName("lambda"))
+
+	d := &Deployer{cfg: &config.Config{}, deployCfg: DefaultDeployConfig()}
+	WithProviders(vast, lambda)(d)
+
+	got, err := d.offerProviders()
+	if err != nil || len(got) != 2 {
+		t.Errorf("offerProviders() = %v, %v, want both providers", got, err)
+	}
+
+	d.deployCfg.ProviderName = "lambda"
+	got, err = d.offerProviders()
+	if err != nil || len(got) != 1 || got[0] != lambda {
+		t.Errorf("offerProviders() = %v, %v, want lambda", got, err)
+	}
+
+	d.deployCfg.ProviderName = "runpod"
+	if _, err := d.offerProviders(); err == nil {
+		t.Error("offerProviders() for a provider not given error = nil")
+	}
+}
+
+func TestDeployer_fetchOffers_Filter(t *testing.T) {
+	spot := 0.5
+	vast := mock.New(mock.WithName("vast"), mock.WithOffers([]provider.Offer{
+		{OfferID: "v1", Provider: "vast", GPU: "A100 80GB", VRAM: 80, Region: "EU-West", OnDemandPrice: 1.5, SpotPrice: &spot, Available: true},
+		{OfferID: "v2", Provider: "vast", GPU: "A6000 48GB", VRAM: 48, Region: "US-East", OnDemandPrice: 0.8, Available: true},
+	}))
+	lambda := mock.New(mock.WithName("lambda"), mock.WithOffers([]provider.Offer{
+		{OfferID: "l1", Provider: "lambda", GPU: "A100 80GB", VRAM: 80, Region: "US-East", OnDemandPrice: 1.3, Available: true},
+	}))
+	model := &models.Model{Name: "test", VRAM: 24}
+
+	cfg := &config.Config{}
+	cfg.DefaultFilter, _ = offerexpr.Parse("region =~ '^eu'")
+	d := &Deployer{cfg: cfg, deployCfg: DefaultDeployConfig()}
+	WithProviders(vast, lambda)(d)
+
+	offerIDs := func() []string {
+		t.Helper()
+		offers, _, err := d.fetchOffers(context.Background(), model)
+		if err != nil {
+			t.Fatalf("fetchOffers() error = %v", err)
+		}
+		var ids []string
+		for _, o := range offers {
+			ids = append(ids, o.Offer.OfferID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	// The config's default filter applies without a deploy filter
+	if got := offerIDs(); len(got) != 1 || got[0] != "v1" {
+		t.Errorf("offers with default filter = %v, want [v1]", got)
+	}
+
+	// A deploy filter replaces it
+	d.deployCfg.Filter, _ = offerexpr.Parse("vram == 80 && provider != vast")
+	if got := offerIDs(); len(got) != 1 || got[0] != "l1" {
+		t.Errorf("offers with deploy filter = %v, want [l1]", got)
+	}
+
+	// No match is an error naming the filter
+	d.deployCfg.Filter, _ = offerexpr.Parse("vram > 80")
+	_, _, err := d.fetchOffers(context.Background(), model)
+	if err == nil || !strings.Contains(err.Error(), "vram > 80") {
+		t.Errorf("fetchOffers() with no matching offer error = %v, want one naming the filter", err)
+	}
+}
+
 func TestErrorVariables(t *testing.T) {
 	if ErrNoCompatibleOffers.Error() != "no compatible GPU offers found" {
 		t.Error("ErrNoCompatibleOffers has wrong message")
@@ -1103,3 +1192,70 @@ func TestStopper_MultipleCriticalAlertCallbacks(t *testing.T) {
 		t.Errorf("expected callCount to be 10 (only last callback), got %d", callCount)
 	}
 }
+
+func TestDeployer_allocateNetwork(t *testing.T) {
+	tmpDir := t.TempDir()
+	deployCfg := DefaultDeployConfig()
+	deployCfg.Model = "qwen2.5-coder:7b"
+
+	newSessionDeployer := func(session string) *Deployer {
+		sm, err := config.NewStateManager(tmpDir, config.WithSession(session))
+		if err != nil {
+			t.Fatalf("NewStateManager() error = %v", err)
+		}
+		d, err := NewDeployer(&config.Config{}, deployCfg, WithStateManager(sm))
+		if err != nil {
+			t.Fatalf("NewDeployer() error = %v", err)
+		}
+		return d
+	}
+
+	saveSession := func(session, iface string) {
+		sm, _ := config.NewStateManager(tmpDir, config.WithSession(session))
+		if err := sm.SaveState(&config.State{
+			Instance:  &config.InstanceState{ID: session + "-1"},
+			WireGuard: &config.WireGuardState{InterfaceName: iface},
+		}); err != nil {
+			t.Fatalf("SaveState() error = %v", err)
+		}
+	}
+
+	// No other sessions: slot 0
+	network, err := newSessionDeployer("chat").allocateNetwork()
+	if err != nil {
+		t.Fatalf("allocateNetwork() error = %v", err)
+	}
+	if network.Slot != 0 {
+		t.Errorf("Slot = %d, want 0", network.Slot)
+	}
+
+	// Slot 0 and 1 taken by other sessions: slot 2
+	saveSession("default", "wg-spinup")
+	saveSession("autocomplete", "wg-spinup1")
+	network, err = newSessionDeployer("chat").allocateNetwork()
+	if err != nil {
+		t.Fatalf("allocateNetwork() error = %v", err)
+	}
+	if network.Slot != 2 || network.InterfaceName != "wg-spinup2" {
+		t.Errorf("got slot %d (%s), want slot 2 (wg-spinup2)", network.Slot, network.InterfaceName)
+	}
+
+	// A session's own previous network does not count as taken
+	network, err = newSessionDeployer("autocomplete").allocateNetwork()
+	if err != nil {
+		t.Fatalf("allocateNetwork() error = %v", err)
+	}
+	if network.Slot != 1 {
+		t.Errorf("Slot = %d, want 1", network.Slot)
+	}
+
+	// Without a state manager the default network is used
+	d, _ := NewDeployer(&config.Config{}, deployCfg)
+	network, err = d.allocateNetwork()
+	if err != nil {
+		t.Fatalf("allocateNetwork() error = %v", err)
+	}
+	if network.Slot != 0 {
+		t.Errorf("Slot = %d, want 0", network.Slot)
+	}
+}
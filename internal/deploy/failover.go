@@ -0,0 +1,230 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/logging"
+	"github.com/tmeurs/spinup/internal/models"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/wireguard"
+)
+
+const (
+	// DefaultMaxAttempts is the default number of offers tried before a deployment fails.
+	DefaultMaxAttempts = 3
+
+	// MaxDeployAttempts is the upper bound for DeployConfig.MaxAttempts.
+	MaxDeployAttempts = 10
+)
+
+// bootPollInterval is how often waitForBoot checks the instance status.
+var bootPollInterval = 5 * time.Second
+
+// DeployAttempt records one try at creating and booting an instance from an offer.
+type DeployAttempt struct {
+	// Number is the 1-based attempt number.
+	Number int
+
+	// Provider is the provider name.
+	Provider string
+
+	// OfferID, GPU and Region identify the offer that was tried.
+	OfferID string
+	GPU     string
+	Region  string
+
+	// InstanceID is the created instance, empty if creation failed.
+	InstanceID string
+
+	// FailedStep is the step the attempt failed in, zero if it succeeded.
+	FailedStep DeployStep
+
+	// Err is the reason the attempt failed, nil if it succeeded.
+	Err error
+
+	// Terminated is true if the failed attempt's instance was terminated.
+	Terminated bool
+
+	// StartedAt and EndedAt bound the attempt.
+	StartedAt time.Time
+	EndedAt   time.Time
+}
+
+// Succeeded returns true if the attempt produced a running instance.
+func (a DeployAttempt) Succeeded() bool {
+	return a.Err == nil
+}
+
+// isFailoverError returns true if instance creation failed in a way that a
+// different offer might not, e.g. a stale listing or exhausted capacity.
+func isFailoverError(err error) bool {
+	var perr *provider.ProviderError
+	if !errors.As(err, &perr) {
+		return false
+	}
+	switch perr.Code {
+	case provider.ErrInsufficientCapacity.Code, provider.ErrSpotNotAvailable.Code, provider.ErrOfferNotFound.Code:
+		return true
+	}
+	return false
+}
+
+// maxAttempts returns the attempt budget for the given number of ranked offers.
+func (d *Deployer) maxAttempts(offerCount int) int {
+	budget := d.deployCfg.MaxAttempts
+	if budget <= 0 {
+		budget = DefaultMaxAttempts
+	}
+	if budget > offerCount {
+		budget = offerCount
+	}
+	return budget
+}
+
+// launchInstance walks the ranked offers until one yields a booted instance
+// (steps 2-4), trying at most MaxAttempts offers. Each offer is re-checked
+// with its provider before it is rented. Attempts whose offer is gone or that
+// fail at creation with a capacity-type error, or whose instance doesn't boot, move on
+// to the next offer; a booted-but-failed instance is terminated first. Every
+// attempt is appended to result.Attempts.
+//
+// On error the journal has been settled: removed if nothing is left running,
+// or marked failed if a failed attempt's instance could not be terminated.
+func (d *Deployer) launchInstance(ctx context.Context, offers []rankedOffer, model *models.Model, clientKeyPair *wireguard.KeyPair, result *DeployResult) (provider.Provider, error) {
+	budget := d.maxAttempts(len(offers))
+	defer func() { d.attempt = 0 }()
+
+	var lastErr error
+	for i := 0; i < budget; i++ {
+		offer := offers[i].Offer
+		p := offers[i].Provider
+		d.attempt = i + 1
+
+		attempt := DeployAttempt{
+			Number:    i + 1,
+			Provider:  p.Name(),
+			OfferID:   offer.OfferID,
+			GPU:       offer.GPU,
+			Region:    offer.Region,
+			StartedAt: time.Now(),
+		}
+		finish := func(step DeployStep, err error) {
+			attempt.FailedStep = step
+			attempt.Err = err
+			attempt.EndedAt = time.Now()
+			result.Attempts = append(result.Attempts, attempt)
+		}
+
+		score := offers[i].Score
+		result.SelectedOffer = &offer
+		result.SelectedScore = &score
+		result.Provider = p
+		detail := ""
+		if budget > 1 {
+			detail = fmt.Sprintf("attempt %d/%d", i+1, budget)
+		}
+		d.reportProgress(StepSelectOffer, fmt.Sprintf("Selected: %s %s %s @ %s", offer.Provider, offer.GPU, offer.Region, d.formatOfferPrice(&offer)), detail, true)
+
+		// Step 3: Create instance, after making sure the offer still exists
+		d.reportProgress(StepCreateInstance, "Creating instance...", "", false)
+		var instance *provider.Instance
+		var wgConfig *wireguard.ConfigPair
+		current, err := d.recheckOffer(ctx, p, &offer)
+		if err == nil {
+			offer = *current
+			instance, wgConfig, err = d.createInstance(ctx, p, &offer, model, clientKeyPair)
+		}
+		if err != nil {
+			finish(StepCreateInstance, err)
+			d.reportProgress(StepCreateInstance, "Failed to create instance", err.Error(), false)
+			lastErr = fmt.Errorf("step 3 failed: %w", err)
+			if !isFailoverError(err) || ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		attempt.InstanceID = instance.ID
+		result.Instance = instance
+		result.WireGuardConfig = wgConfig
+
+		// Record the instance before anything else can go wrong, so a killed
+		// process leaves enough behind to resume or terminate it.
+		d.journalInstance(p, &offer, instance, wgConfig)
+		createDetail := ""
+		if d.termination != nil {
+			if createDetail = d.termination.limitation(); createDetail != "" {
+				logging.Warn().Str("termination", string(d.termination.mode)).Msg("Deadman switch cannot always terminate the instance: " + createDetail)
+			}
+		}
+		d.reportProgress(StepCreateInstance, fmt.Sprintf("Instance %s created", instance.ID), createDetail, true)
+
+		// Step 4: Wait for boot
+		d.reportProgress(StepWaitBoot, "Waiting for instance to boot...", "", false)
+		if err := d.waitForBoot(ctx, p, instance.ID); err != nil {
+			d.reportProgress(StepWaitBoot, "Instance failed to boot", err.Error(), false)
+			lastErr = fmt.Errorf("step 4 failed: %w", err)
+
+			if termErr := d.terminateAttempt(p, instance.ID); termErr != nil {
+				finish(StepWaitBoot, err)
+				d.failJournal(termErr)
+				return nil, lastErr
+			}
+			attempt.Terminated = true
+			finish(StepWaitBoot, err)
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		finish(0, nil)
+		d.recordBootTime(p.Name(), attempt.EndedAt.Sub(attempt.StartedAt))
+		return p, nil
+	}
+
+	d.removeJournal()
+	if len(result.Attempts) > 1 {
+		return nil, fmt.Errorf("all %d attempts failed, last: %w", len(result.Attempts), lastErr)
+	}
+	return nil, lastErr
+}
+
+// terminateAttempt terminates the instance of a failed attempt and clears it
+// from the journal, so the next attempt starts from a clean record.
+func (d *Deployer) terminateAttempt(p provider.Provider, instanceID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := p.TerminateInstance(ctx, instanceID); err != nil && !isInstanceNotFound(err) {
+		return fmt.Errorf("failed to terminate instance %s: %w", instanceID, err)
+	}
+	if d.termination != nil {
+		revokeTerminationCredential(p, d.termination.credentialID)
+	}
+
+	if d.journal != nil {
+		d.journal.ClearInstance()
+		d.saveJournal()
+	}
+	return nil
+}
+
+// recordBootTime adds a successful boot to the boot history used for offer
+// scoring. Failures are logged; they don't affect the deployment.
+func (d *Deployer) recordBootTime(providerName string, bootTime time.Duration) {
+	if d.stateManager == nil {
+		return
+	}
+
+	history, err := LoadBootHistory(d.stateManager)
+	if err != nil {
+		logging.Warn().Err(err).Msg("Starting a new boot history")
+	}
+	history.Record(providerName, bootTime)
+	if err := history.Save(); err != nil {
+		logging.Warn().Err(err).Msg("Failed to save boot history")
+	}
+}
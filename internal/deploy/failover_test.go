@@ -0,0 +1,293 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/models"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/mock"
+	"github.com/tmeurs/spinup/internal/wireguard"
+)
+
+func TestIsFailoverError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"insufficient capacity", provider.ErrInsufficientCapacity, true},
+		{"spot not available", provider.ErrSpotNotAvailable, true},
+		{"stale offer", provider.ErrOfferNotFound, true},
+		{"wrapped capacity", fmt.Errorf("create: %w", provider.ErrInsufficientCapacity.Wrap(errors.New("409"))), true},
+		{"authentication", provider.ErrAuthenticationFailed, false},
+		{"plain error", errors.New("boom"), false},
+		{"nil", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFailoverError(tt.err); got != tt.want {
+				t.Errorf("isFailoverError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeployer_maxAttempts(t *testing.T) {
+	tests := []struct {
+		configured int
+		offers     int
+		want       int
+	}{
+		{0, 10, DefaultMaxAttempts},
+		{5, 10, 5},
+		{5, 2, 2},
+		{1, 10, 1},
+	}
+
+	for _, tt := range tests {
+		d := &Deployer{deployCfg: &DeployConfig{MaxAttempts: tt.configured}}
+		if got := d.maxAttempts(tt.offers); got != tt.want {
+			t.Errorf("maxAttempts(%d) with MaxAttempts=%d = %d, want %d", tt.offers, tt.configured, got, tt.want)
+		}
+	}
+}
+
+func TestDeployConfig_Validate_MaxAttempts(t *testing.T) {
+	cfg := DefaultDeployConfig()
+	cfg.Model = "qwen2.5-coder:7b"
+
+	cfg.MaxAttempts = -1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() with negative MaxAttempts error = nil")
+	}
+	cfg.MaxAttempts = MaxDeployAttempts + 1
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() with too many attempts error = nil")
+	}
+	cfg.MaxAttempts = MaxDeployAttempts
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
+
+// newFailoverTestDeployer returns a journaling Deployer with a short boot
+// timeout, and shortens the boot poll interval for the test.
+func newFailoverTestDeployer(t *testing.T, maxAttempts int, progress *[]DeployProgress) (*Deployer, *config.StateManager) {
+	t.Helper()
+
+	oldInterval := bootPollInterval
+	bootPollInterval = 5 * time.Millisecond
+	t.Cleanup(func() { bootPollInterval = oldInterval })
+
+	sm, _ := config.NewStateManager(t.TempDir())
+	deployCfg := DefaultDeployConfig()
+	deployCfg.Model = "qwen2.5-coder:7b"
+	deployCfg.PreferSpot = false
+	deployCfg.BootTimeout = 50 * time.Millisecond
+	deployCfg.MaxAttempts = maxAttempts
+
+	d, err := NewDeployer(&config.Config{}, deployCfg,
+		WithStateManager(sm),
+		WithProgressCallback(func(p DeployProgress) { *progress = append(*progress, p) }),
+	)
+	if err != nil {
+		t.Fatalf("NewDeployer() error = %v", err)
+	}
+	d.network = wireguard.DefaultNetwork()
+	d.startJournal()
+	return d, sm
+}
+
+// failoverOffer returns a ranked offer of p. The offer only exists at the
+// provider if p was created with it.
+func failoverOffer(p *mock.Provider, id string) rankedOffer {
+	return rankedOffer{Offer: failoverTestOffer(p.Name(), id), Provider: p}
+}
+
+func failoverTestOffer(providerName, id string) provider.Offer {
+	return provider.Offer{OfferID: id, Provider: providerName, GPU: "A100 80GB", VRAM: 80, Region: "EU", OnDemandPrice: 1.0, Available: true}
+}
+
+// newFailoverProvider returns a mock provider that has a single rentable offer.
+func newFailoverProvider(name, offerID string, opts ...mock.Option) (*mock.Provider, rankedOffer) {
+	opts = append([]mock.Option{mock.WithName(name), mock.WithOffers([]provider.Offer{failoverTestOffer(name, offerID)})}, opts...)
+	p := mock.New(opts...)
+	return p, failoverOffer(p, offerID)
+}
+
+func TestDeployer_launchInstance_FailsOverStaleOffer(t *testing.T) {
+	var progress []DeployProgress
+	d, sm := newFailoverTestDeployer(t, 3, &progress)
+
+	// The vast listing is gone by the time we try to rent it
+	stale := mock.New(mock.WithName("vast"))
+	_, good := newFailoverProvider("lambda", "lambda-1")
+
+	model, _ := models.GetModelByName("qwen2.5-coder:7b")
+	clientKeys, _ := wireguard.GenerateKeyPair()
+	result := &DeployResult{}
+
+	p, err := d.launchInstance(context.Background(), []rankedOffer{failoverOffer(stale, "vast-gone"), good}, model, clientKeys, result)
+	if err != nil {
+		t.Fatalf("launchInstance() error = %v", err)
+	}
+	if p.Name() != "lambda" {
+		t.Errorf("provider = %s, want lambda", p.Name())
+	}
+
+	if len(result.Attempts) != 2 {
+		t.Fatalf("len(Attempts) = %d, want 2", len(result.Attempts))
+	}
+	first, second := result.Attempts[0], result.Attempts[1]
+	if first.Succeeded() || first.FailedStep != StepCreateInstance || !errors.Is(first.Err, provider.ErrOfferNotFound) {
+		t.Errorf("Attempts[0] = %+v, want failed at create with ErrOfferNotFound", first)
+	}
+	if !second.Succeeded() || second.Number != 2 || second.InstanceID == "" {
+		t.Errorf("Attempts[1] = %+v, want succeeded", second)
+	}
+	if result.SelectedOffer.OfferID != "lambda-1" || result.Instance.ID != second.InstanceID {
+		t.Errorf("result = offer %s, instance %s", result.SelectedOffer.OfferID, result.Instance.ID)
+	}
+
+	// Progress carries the attempt number
+	var sawSecond bool
+	for _, pr := range progress {
+		if pr.Step == StepSelectOffer && pr.Completed && pr.Attempt == 2 && pr.Detail == "attempt 2/2" {
+			sawSecond = true
+		}
+	}
+	if !sawSecond {
+		t.Error("no progress reported for attempt 2")
+	}
+
+	journal, _ := LoadDeployJournal(sm)
+	if journal == nil || journal.InstanceID != second.InstanceID || journal.Provider != "lambda" {
+		t.Errorf("journal = %+v, want lambda instance %s", journal, second.InstanceID)
+	}
+}
+
+func TestDeployer_launchInstance_TerminatesUnbootedInstance(t *testing.T) {
+	var progress []DeployProgress
+	d, sm := newFailoverTestDeployer(t, 3, &progress)
+
+	// The instance is created but never reports a status
+	stuck, stuckOffer := newFailoverProvider("vast", "vast-1", mock.WithGetInstanceError(errors.New("gateway timeout")))
+	_, healthyOffer := newFailoverProvider("runpod", "runpod-1")
+
+	model, _ := models.GetModelByName("qwen2.5-coder:7b")
+	clientKeys, _ := wireguard.GenerateKeyPair()
+	result := &DeployResult{}
+
+	p, err := d.launchInstance(context.Background(), []rankedOffer{stuckOffer, healthyOffer}, model, clientKeys, result)
+	if err != nil {
+		t.Fatalf("launchInstance() error = %v", err)
+	}
+	if p.Name() != "runpod" {
+		t.Errorf("provider = %s, want runpod", p.Name())
+	}
+
+	first := result.Attempts[0]
+	if first.FailedStep != StepWaitBoot || !first.Terminated {
+		t.Errorf("Attempts[0] = %+v, want terminated after boot failure", first)
+	}
+	if len(stuck.TerminateInstanceCalls) != 1 || stuck.TerminateInstanceCalls[0].ID != first.InstanceID {
+		t.Errorf("TerminateInstanceCalls = %+v, want %s", stuck.TerminateInstanceCalls, first.InstanceID)
+	}
+
+	journal, _ := LoadDeployJournal(sm)
+	if journal == nil || journal.Provider != "runpod" {
+		t.Fatalf("journal = %+v, want runpod instance", journal)
+	}
+	if journal.LastCompletedStep != StepCreateInstance {
+		t.Errorf("LastCompletedStep = %v, want %v", journal.LastCompletedStep, StepCreateInstance)
+	}
+}
+
+func TestDeployer_launchInstance_StopsOnOtherErrors(t *testing.T) {
+	var progress []DeployProgress
+	d, sm := newFailoverTestDeployer(t, 3, &progress)
+
+	_, badKeyOffer := newFailoverProvider("vast", "vast-1", mock.WithCreateInstanceError(provider.ErrAuthenticationFailed))
+	other, otherOffer := newFailoverProvider("lambda", "lambda-1")
+
+	model, _ := models.GetModelByName("qwen2.5-coder:7b")
+	clientKeys, _ := wireguard.GenerateKeyPair()
+	result := &DeployResult{}
+
+	_, err := d.launchInstance(context.Background(), []rankedOffer{badKeyOffer, otherOffer}, model, clientKeys, result)
+	if !errors.Is(err, provider.ErrAuthenticationFailed) {
+		t.Fatalf("launchInstance() error = %v, want ErrAuthenticationFailed", err)
+	}
+	if len(result.Attempts) != 1 {
+		t.Errorf("len(Attempts) = %d, want 1", len(result.Attempts))
+	}
+	if len(other.CreateInstanceCalls) != 0 {
+		t.Error("failed over after a non-capacity error")
+	}
+	if journal, _ := LoadDeployJournal(sm); journal != nil {
+		t.Error("journal not removed after failed deployment")
+	}
+}
+
+func TestDeployer_launchInstance_AttemptBudget(t *testing.T) {
+	var progress []DeployProgress
+	d, _ := newFailoverTestDeployer(t, 2, &progress)
+
+	full := mock.New(mock.WithName("vast"), mock.WithCreateInstanceError(provider.ErrInsufficientCapacity), mock.WithOffers([]provider.Offer{
+		failoverTestOffer("vast", "a"), failoverTestOffer("vast", "b"), failoverTestOffer("vast", "c"),
+	}))
+	offers := []rankedOffer{failoverOffer(full, "a"), failoverOffer(full, "b"), failoverOffer(full, "c")}
+
+	model, _ := models.GetModelByName("qwen2.5-coder:7b")
+	clientKeys, _ := wireguard.GenerateKeyPair()
+	result := &DeployResult{}
+
+	_, err := d.launchInstance(context.Background(), offers, model, clientKeys, result)
+	if err == nil || !strings.Contains(err.Error(), "all 2 attempts failed") {
+		t.Errorf("launchInstance() error = %v, want all 2 attempts failed", err)
+	}
+	if len(full.CreateInstanceCalls) != 2 {
+		t.Errorf("CreateInstanceCalls = %d, want 2", len(full.CreateInstanceCalls))
+	}
+}
+
+func TestDeployer_launchInstance_RechecksOffer(t *testing.T) {
+	var progress []DeployProgress
+	d, _ := newFailoverTestDeployer(t, 3, &progress)
+
+	// The listing was rented out since it was fetched, the price of the
+	// fallback changed
+	gone, goneOffer := newFailoverProvider("vast", "vast-1")
+	gone.SetOffers(nil)
+	repriced := failoverTestOffer("lambda", "lambda-1")
+	repriced.OnDemandPrice = 1.5
+	lambda := mock.New(mock.WithName("lambda"), mock.WithOffers([]provider.Offer{repriced}))
+
+	model, _ := models.GetModelByName("qwen2.5-coder:7b")
+	clientKeys, _ := wireguard.GenerateKeyPair()
+	result := &DeployResult{}
+
+	p, err := d.launchInstance(context.Background(), []rankedOffer{goneOffer, failoverOffer(lambda, "lambda-1")}, model, clientKeys, result)
+	if err != nil {
+		t.Fatalf("launchInstance() error = %v", err)
+	}
+	if p.Name() != "lambda" {
+		t.Errorf("provider = %s, want lambda", p.Name())
+	}
+	if len(gone.CreateInstanceCalls) != 0 {
+		t.Error("CreateInstance called for an offer that is gone")
+	}
+	if !errors.Is(result.Attempts[0].Err, provider.ErrOfferNotFound) {
+		t.Errorf("Attempts[0].Err = %v, want ErrOfferNotFound", result.Attempts[0].Err)
+	}
+	if result.SelectedOffer.OnDemandPrice != 1.5 {
+		t.Errorf("SelectedOffer price = %v, want the re-checked 1.5", result.SelectedOffer.OnDemandPrice)
+	}
+}
@@ -0,0 +1,185 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/logging"
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+// DefaultProviderFetchTimeout is the default deadline for a single provider's offers.
+const DefaultProviderFetchTimeout = 20 * time.Second
+
+// Error codes in ProviderFetchReport for failures that aren't a provider.ProviderError.
+const (
+	FetchErrorTimeout  = "timeout"
+	FetchErrorCanceled = "canceled"
+	FetchErrorUnknown  = "unknown"
+)
+
+// ProviderFetchReport describes how fetching offers from one provider went.
+type ProviderFetchReport struct {
+	// Provider is the provider name.
+	Provider string
+
+	// Latency is how long the request took.
+	Latency time.Duration
+
+	// OfferCount is the number of available offers returned.
+	OfferCount int
+
+	// ErrorCode is a machine-readable error code, empty on success.
+	// It is the provider.ProviderError code if there is one, or one of the
+	// FetchError constants.
+	ErrorCode string
+
+	// Err is the error returned by the provider, nil on success.
+	Err error
+
+	// Cached is true if the offers came from the offer cache instead of the
+	// provider. Latency is zero then.
+	Cached bool
+
+	// FetchedAt is when the offers were fetched from the provider.
+	FetchedAt time.Time
+}
+
+// OK returns true if the provider returned offers without error.
+func (r ProviderFetchReport) OK() bool {
+	return r.Err == nil
+}
+
+// ProviderOffers holds the available offers of one provider and how they were fetched.
+type ProviderOffers struct {
+	// Provider is the provider that was queried.
+	Provider provider.Provider
+
+	// Offers are the available offers, empty on error.
+	Offers []provider.Offer
+
+	// Report describes the request.
+	Report ProviderFetchReport
+}
+
+// FetchOffers queries all providers in parallel, each with its own deadline,
+// so a slow or failing provider doesn't hold up the others. Results are
+// returned in the order of providers; unavailable offers are dropped.
+func FetchOffers(ctx context.Context, providers []provider.Provider, filter provider.OfferFilter, timeout time.Duration) []ProviderOffers {
+	if timeout <= 0 {
+		timeout = DefaultProviderFetchTimeout
+	}
+
+	results := make([]ProviderOffers, len(providers))
+
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p provider.Provider) {
+			defer wg.Done()
+			results[i] = fetchProviderOffers(ctx, p, filter, timeout)
+		}(i, p)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// fetchProviderOffers fetches the offers of a single provider.
+func fetchProviderOffers(ctx context.Context, p provider.Provider, filter provider.OfferFilter, timeout time.Duration) ProviderOffers {
+	fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	offers, err := p.GetOffers(fetchCtx, filter)
+	result := ProviderOffers{
+		Provider: p,
+		Report: ProviderFetchReport{
+			Provider:  p.Name(),
+			Latency:   time.Since(start),
+			FetchedAt: start,
+		},
+	}
+
+	// A provider that ignores its context is still cut off at the deadline
+	if err == nil && fetchCtx.Err() != nil {
+		err = fetchCtx.Err()
+	}
+	if err != nil {
+		result.Report.Err = err
+		result.Report.ErrorCode = fetchErrorCode(err)
+		logging.Warn().
+			Err(err).
+			Str("provider", p.Name()).
+			Str("code", result.Report.ErrorCode).
+			Dur("latency", result.Report.Latency).
+			Msg("Failed to fetch offers")
+		return result
+	}
+
+	for _, o := range offers {
+		if o.Available {
+			result.Offers = append(result.Offers, o)
+		}
+	}
+	result.Report.OfferCount = len(result.Offers)
+
+	logging.Debug().
+		Str("provider", p.Name()).
+		Int("offers", result.Report.OfferCount).
+		Dur("latency", result.Report.Latency).
+		Msg("Fetched offers")
+
+	return result
+}
+
+// fetchErrorCode returns the error code for a failed fetch.
+func fetchErrorCode(err error) string {
+	var perr *provider.ProviderError
+	switch {
+	case errors.As(err, &perr) && perr.Code != "":
+		return perr.Code
+	case errors.Is(err, context.DeadlineExceeded):
+		return FetchErrorTimeout
+	case errors.Is(err, context.Canceled):
+		return FetchErrorCanceled
+	default:
+		return FetchErrorUnknown
+	}
+}
+
+// FetchReports returns the reports of results.
+func FetchReports(results []ProviderOffers) []ProviderFetchReport {
+	reports := make([]ProviderFetchReport, len(results))
+	for i, r := range results {
+		reports[i] = r.Report
+	}
+	return reports
+}
+
+// SummarizeFetchErrors returns a short "name: code" list of failed providers,
+// or an empty string if all succeeded.
+func SummarizeFetchErrors(reports []ProviderFetchReport) string {
+	var failed []string
+	for _, r := range reports {
+		if !r.OK() {
+			failed = append(failed, fmt.Sprintf("%s: %s", r.Provider, r.ErrorCode))
+		}
+	}
+	return strings.Join(failed, ", ")
+}
+
+// countCached returns how many reports were served from the offer cache.
+func countCached(reports []ProviderFetchReport) int {
+	n := 0
+	for _, r := range reports {
+		if r.Cached {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,85 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/mock"
+)
+
+func TestFetchOffers(t *testing.T) {
+	offers := []provider.Offer{
+		{OfferID: "a", Provider: "vast", GPU: "A100", VRAM: 80, Available: true},
+		{OfferID: "b", Provider: "vast", GPU: "A100", VRAM: 80, Available: false},
+		{OfferID: "c", Provider: "vast", GPU: "A6000", VRAM: 48, Available: true},
+	}
+
+	healthy := mock.New(mock.WithName("vast"), mock.WithOffers(offers))
+	badKey := mock.New(mock.WithName("lambda"), mock.WithGetOffersError(provider.ErrAuthenticationFailed.Wrap(errors.New("401"))))
+	slow := mock.New(mock.WithName("runpod"), mock.WithOffers(offers), mock.WithGetOffersDelay(5*time.Second))
+	broken := mock.New(mock.WithName("paperspace"), mock.WithGetOffersError(errors.New("connection reset")))
+
+	start := time.Now()
+	results := FetchOffers(context.Background(), []provider.Provider{healthy, badKey, slow, broken}, provider.OfferFilter{}, 100*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("FetchOffers took %v, slow provider was not cut off", elapsed)
+	}
+
+	tests := []struct {
+		provider   string
+		offerCount int
+		errorCode  string
+	}{
+		{"vast", 2, ""},
+		{"lambda", 0, "authentication_failed"},
+		{"runpod", 0, FetchErrorTimeout},
+		{"paperspace", 0, FetchErrorUnknown},
+	}
+
+	if len(results) != len(tests) {
+		t.Fatalf("len(results) = %d, want %d", len(results), len(tests))
+	}
+	for i, tt := range tests {
+		r := results[i]
+		if r.Report.Provider != tt.provider {
+			t.Errorf("results[%d].Provider = %q, want %q", i, r.Report.Provider, tt.provider)
+		}
+		if r.Report.OfferCount != tt.offerCount || len(r.Offers) != tt.offerCount {
+			t.Errorf("%s: OfferCount = %d, len(Offers) = %d, want %d", tt.provider, r.Report.OfferCount, len(r.Offers), tt.offerCount)
+		}
+		if r.Report.ErrorCode != tt.errorCode {
+			t.Errorf("%s: ErrorCode = %q, want %q", tt.provider, r.Report.ErrorCode, tt.errorCode)
+		}
+		if r.Report.OK() != (tt.errorCode == "") {
+			t.Errorf("%s: OK() = %v", tt.provider, r.Report.OK())
+		}
+	}
+
+	summary := SummarizeFetchErrors(FetchReports(results))
+	want := "lambda: authentication_failed, runpod: timeout, paperspace: unknown"
+	if summary != want {
+		t.Errorf("SummarizeFetchErrors() = %q, want %q", summary, want)
+	}
+}
+
+func TestFetchOffers_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	p := mock.New(mock.WithName("vast"), mock.WithGetOffersDelay(time.Second))
+	results := FetchOffers(ctx, []provider.Provider{p}, provider.OfferFilter{}, 0)
+
+	if results[0].Report.ErrorCode != FetchErrorCanceled {
+		t.Errorf("ErrorCode = %q, want %q", results[0].Report.ErrorCode, FetchErrorCanceled)
+	}
+}
+
+func TestSummarizeFetchErrors_AllOK(t *testing.T) {
+	reports := []ProviderFetchReport{{Provider: "vast", OfferCount: 1}}
+	if got := SummarizeFetchErrors(reports); got != "" {
+		t.Errorf("SummarizeFetchErrors() = %q, want empty", got)
+	}
+}
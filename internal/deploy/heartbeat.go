@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/tmeurs/spinup/internal/clock"
 	"github.com/tmeurs/spinup/internal/wireguard"
 )
 
@@ -39,14 +40,23 @@ type HeartbeatConfig struct {
 	// The callback receives the number of previous consecutive failures (0 if none).
 	OnSuccess func(previousFailures int)
 
+	// OnHeartbeat is called after every successful heartbeat with the time it was sent.
+	// Unlike OnSuccess, it fires on every success, not only after recovering from failures.
+	OnHeartbeat func(at time.Time)
+
 	// MaxConsecutiveFailures is the maximum number of consecutive failures before
 	// escalating to critical status. Default is 3.
 	MaxConsecutiveFailures int
+
+	// Clock schedules heartbeats and timestamps them.
+	// Default (nil) is the system clock.
+	Clock clock.Clock
 }
 
 // HeartbeatClient manages the heartbeat goroutine that keeps the deadman switch alive.
 type HeartbeatClient struct {
 	config *HeartbeatConfig
+	clock  clock.Clock
 
 	// mu protects the following fields
 	mu                   sync.RWMutex
@@ -169,6 +179,7 @@ func NewHeartbeatClient(config *HeartbeatConfig) (*HeartbeatClient, error) {
 
 	return &HeartbeatClient{
 		config: config,
+		clock:  clock.OrReal(config.Clock),
 		httpClient: &http.Client{
 			Transport: transport,
 			Timeout:   config.Timeout,
@@ -264,14 +275,14 @@ func (hc *HeartbeatClient) heartbeatLoop(ctx context.Context) {
 		hc.mu.Unlock()
 	}()
 
-	ticker := time.NewTicker(hc.config.Interval)
+	ticker := hc.clock.NewTicker(hc.config.Interval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			hc.sendHeartbeat(ctx)
 		}
 	}
@@ -296,17 +307,22 @@ func (hc *HeartbeatClient) sendHeartbeat(ctx context.Context) {
 		}
 	} else {
 		previousFailures := hc.consecutiveFailures
-		hc.lastHeartbeat = time.Now()
+		now := hc.clock.Now()
+		hc.lastHeartbeat = now
 		hc.lastError = nil
 		hc.consecutiveFailures = 0
 		hc.totalHeartbeats++
 
 		callback := hc.config.OnSuccess
+		heartbeatCallback := hc.config.OnHeartbeat
 		hc.mu.Unlock()
 
 		if callback != nil && previousFailures > 0 {
 			callback(previousFailures)
 		}
+		if heartbeatCallback != nil {
+			heartbeatCallback(now)
+		}
 	}
 }
 
@@ -407,7 +423,7 @@ func (hc *HeartbeatClient) SendHeartbeatNow(ctx context.Context) error {
 		hc.consecutiveFailures++
 		hc.totalFailures++
 	} else {
-		hc.lastHeartbeat = time.Now()
+		hc.lastHeartbeat = hc.clock.Now()
 		hc.lastError = nil
 		hc.consecutiveFailures = 0
 		hc.totalHeartbeats++
@@ -426,7 +442,7 @@ func (hc *HeartbeatClient) TimeSinceLastHeartbeat() time.Duration {
 	if hc.lastHeartbeat.IsZero() {
 		return 0
 	}
-	return time.Since(hc.lastHeartbeat)
+	return hc.clock.Since(hc.lastHeartbeat)
 }
 
 // IsCritical returns true if the heartbeat system is in a critical state.
@@ -8,6 +8,8 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/tmeurs/spinup/internal/clock"
 )
 
 func TestNewHeartbeatConfig(t *testing.T) {
@@ -316,6 +318,59 @@ func TestHeartbeatClient_WithMockServer(t *testing.T) {
 	}
 }
 
+func TestHeartbeatClient_Clock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	failures := make(chan int, 10)
+	cfg := &HeartbeatConfig{
+		Interval:               DefaultHeartbeatInterval,
+		HeartbeatFile:          DefaultHeartbeatFile,
+		ServerIP:               "127.0.0.1", // Nothing listens, so every heartbeat fails
+		Timeout:                100 * time.Millisecond,
+		MaxConsecutiveFailures: 3,
+		OnFailure: func(err error, consecutiveFailures int) {
+			failures <- consecutiveFailures
+		},
+		Clock: fake,
+	}
+
+	client, err := NewHeartbeatClient(cfg)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if err := client.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start: %v", err)
+	}
+	defer client.Stop()
+
+	// The first heartbeat is sent on start, the others on the fake ticker
+	fake.BlockUntil(1)
+	for want := 1; want <= 3; want++ {
+		if want > 1 {
+			fake.Advance(DefaultHeartbeatInterval)
+		}
+		select {
+		case got := <-failures:
+			if got != want {
+				t.Errorf("consecutive failures = %d, want %d", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("heartbeat %d wasn't sent", want)
+		}
+	}
+
+	if !client.IsCritical() {
+		t.Error("expected client to be critical after 3 failures")
+	}
+
+	// Nothing is sent before the next interval
+	fake.Advance(DefaultHeartbeatInterval - time.Second)
+	select {
+	case got := <-failures:
+		t.Errorf("heartbeat sent early (failure %d)", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
 func TestHeartbeatClient_IsCritical(t *testing.T) {
 	cfg := &HeartbeatConfig{
 		Interval:               30 * time.Second, // Minimum valid interval
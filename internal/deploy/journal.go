@@ -0,0 +1,482 @@
+// Package deploy provides deployment orchestration for spinup.
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/logging"
+	"github.com/tmeurs/spinup/internal/offerexpr"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/registry"
+	"github.com/tmeurs/spinup/internal/wireguard"
+)
+
+// journalFileKind is the per-session file kind used for deploy journals.
+const journalFileKind = "journal"
+
+// ErrResumeInstanceGone is returned when resuming a deployment whose instance
+// no longer exists at the provider.
+var ErrResumeInstanceGone = errors.New("journaled instance no longer exists")
+
+// JournalStatus describes where a journaled deployment stands.
+type JournalStatus string
+
+const (
+	// JournalInProgress means the deployment was running when the journal was last written.
+	JournalInProgress JournalStatus = "in_progress"
+
+	// JournalFailed means the deployment failed and its instance could not be cleaned up.
+	JournalFailed JournalStatus = "failed"
+)
+
+// JournalEntry records a single DeployStep transition.
+type JournalEntry struct {
+	Step      DeployStep `json:"step"`
+	Completed bool       `json:"completed"`
+	Message   string     `json:"message,omitempty"`
+	Detail    string     `json:"detail,omitempty"`
+	At        time.Time  `json:"at"`
+}
+
+// JournalConfig is the subset of DeployConfig needed to resume a deployment.
+type JournalConfig struct {
+	Model               string `json:"model"`
+	PreferSpot          bool   `json:"prefer_spot"`
+	ProviderName        string `json:"provider_name,omitempty"`
+	GPUType             string `json:"gpu_type,omitempty"`
+	Region              string `json:"region,omitempty"`
+	Filter              string `json:"filter,omitempty"`
+	DeadmanTimeoutHours int    `json:"deadman_timeout_hours"`
+	DiskSizeGB          int    `json:"disk_size_gb"`
+	SSHPublicKey        string `json:"ssh_public_key,omitempty"`
+}
+
+// DeployJournal is a write-ahead record of an in-flight deployment.
+// It is written to the state directory before every step transition so a
+// killed process leaves enough behind to resume or clean up the instance.
+// The journal is removed once the session is saved to state, or once a
+// failed deployment's instance has been terminated.
+type DeployJournal struct {
+	Session   string        `json:"session"`
+	Status    JournalStatus `json:"status"`
+	Error     string        `json:"error,omitempty"`
+	StartedAt time.Time     `json:"started_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+
+	Config JournalConfig `json:"config"`
+
+	// Filled in as the deployment progresses
+	Provider    string          `json:"provider,omitempty"`
+	Offer       *provider.Offer `json:"offer,omitempty"`
+	InstanceID  string          `json:"instance_id,omitempty"`
+	Spot        bool            `json:"spot,omitempty"`
+	PublicIP    string          `json:"public_ip,omitempty"`
+	NetworkSlot int             `json:"network_slot"`
+
+	// ServerPublicKey is the instance's WireGuard public key.
+	ServerPublicKey string `json:"server_public_key,omitempty"`
+
+	// ClientKeySecret names the secret holding the client key, when it was
+	// generated for this deployment (not taken from .env) and so can't be
+	// rebuilt on resume. The key itself is never recorded.
+	ClientKeySecret string `json:"client_key_secret,omitempty"`
+
+	// Termination is the instance's deadman termination mode,
+	// RelayTokenHash the hash of its relay token and TerminationCredentialID
+	// the ID of its scoped key; the token and key themselves are never
+	// recorded.
+	Termination             TerminationMode `json:"termination,omitempty"`
+	RelayTokenHash          string          `json:"relay_token_hash,omitempty"`
+	TerminationCredentialID string          `json:"termination_credential_id,omitempty"`
+
+	LastCompletedStep DeployStep     `json:"last_completed_step"`
+	Entries           []JournalEntry `json:"entries"`
+
+	path string
+}
+
+// NewDeployJournal creates a journal for a deployment in the given session.
+// The journal is not written until Save is called.
+func NewDeployJournal(stateManager *config.StateManager, deployCfg *DeployConfig) *DeployJournal {
+	now := time.Now().UTC()
+	return &DeployJournal{
+		Session:   stateManager.Session(),
+		Status:    JournalInProgress,
+		StartedAt: now,
+		UpdatedAt: now,
+		Config: JournalConfig{
+			Model:               deployCfg.Model,
+			PreferSpot:          deployCfg.PreferSpot,
+			ProviderName:        deployCfg.ProviderName,
+			GPUType:             deployCfg.GPUType,
+			Region:              deployCfg.Region,
+			Filter:              deployCfg.Filter.String(),
+			DeadmanTimeoutHours: deployCfg.DeadmanTimeoutHours,
+			DiskSizeGB:          deployCfg.DiskSizeGB,
+			SSHPublicKey:        deployCfg.SSHPublicKey,
+		},
+		path: stateManager.SessionFilePath(journalFileKind),
+	}
+}
+
+// LoadDeployJournal loads the journal for the state manager's session.
+// Returns nil and no error if there is no journal.
+func LoadDeployJournal(stateManager *config.StateManager) (*DeployJournal, error) {
+	return loadDeployJournal(stateManager.SessionFilePath(journalFileKind))
+}
+
+// ListDeployJournals loads every deploy journal in the state manager's directory,
+// sorted by session name.
+func ListDeployJournals(stateManager *config.StateManager) ([]*DeployJournal, error) {
+	patterns := []string{
+		filepath.Join(stateManager.StateDir(), ".spinup."+journalFileKind),
+		filepath.Join(stateManager.StateDir(), ".spinup.*."+journalFileKind),
+	}
+
+	var journals []*DeployJournal
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deploy journals: %w", err)
+		}
+		for _, path := range matches {
+			j, err := loadDeployJournal(path)
+			if err != nil {
+				return nil, err
+			}
+			if j != nil {
+				journals = append(journals, j)
+			}
+		}
+	}
+
+	sort.Slice(journals, func(i, k int) bool {
+		return journals[i].Session < journals[k].Session
+	})
+	return journals, nil
+}
+
+// loadDeployJournal reads a journal file. Returns nil and no error if it doesn't exist.
+func loadDeployJournal(path string) (*DeployJournal, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read deploy journal: %w", err)
+	}
+
+	var j DeployJournal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("deploy journal %s is corrupt: %w", filepath.Base(path), err)
+	}
+	if j.Session == "" {
+		j.Session = config.DefaultSessionName
+	}
+	j.path = path
+	return &j, nil
+}
+
+// Path returns the journal file path.
+func (j *DeployJournal) Path() string {
+	return j.path
+}
+
+// HasInstance returns true if the journal records a created instance.
+func (j *DeployJournal) HasInstance() bool {
+	return j != nil && j.InstanceID != ""
+}
+
+// Record appends a step transition. Completed transitions advance LastCompletedStep.
+func (j *DeployJournal) Record(step DeployStep, message, detail string, completed bool) {
+	now := time.Now().UTC()
+	j.Entries = append(j.Entries, JournalEntry{
+		Step:      step,
+		Completed: completed,
+		Message:   message,
+		Detail:    detail,
+		At:        now,
+	})
+	if completed && step > j.LastCompletedStep {
+		j.LastCompletedStep = step
+	}
+	j.UpdatedAt = now
+}
+
+// ClearInstance forgets a terminated instance so the deployment can continue
+// with another offer. The journal goes back to the state after offer selection.
+func (j *DeployJournal) ClearInstance() {
+	j.Provider = ""
+	j.Offer = nil
+	j.InstanceID = ""
+	j.Spot = false
+	j.PublicIP = ""
+	j.ServerPublicKey = ""
+	j.TerminationCredentialID = ""
+	j.LastCompletedStep = StepSelectOffer
+	j.UpdatedAt = time.Now().UTC()
+}
+
+// MarkFailed records that the deployment failed and its instance is still around.
+func (j *DeployJournal) MarkFailed(err error) {
+	j.Status = JournalFailed
+	if err != nil {
+		j.Error = err.Error()
+	}
+	j.UpdatedAt = time.Now().UTC()
+}
+
+// DeployConfig rebuilds a DeployConfig from the journal, using defaults for
+// settings that are not journaled.
+func (j *DeployJournal) DeployConfig() *DeployConfig {
+	deployCfg := DefaultDeployConfig()
+	deployCfg.Model = j.Config.Model
+	deployCfg.PreferSpot = j.Config.PreferSpot
+	deployCfg.ProviderName = j.Config.ProviderName
+	deployCfg.GPUType = j.Config.GPUType
+	deployCfg.Region = j.Config.Region
+	if filter, err := offerexpr.Parse(j.Config.Filter); err == nil {
+		deployCfg.Filter = filter
+	}
+	if j.Config.DeadmanTimeoutHours > 0 {
+		deployCfg.DeadmanTimeoutHours = j.Config.DeadmanTimeoutHours
+	}
+	if j.Config.DiskSizeGB > 0 {
+		deployCfg.DiskSizeGB = j.Config.DiskSizeGB
+	}
+	deployCfg.SSHPublicKey = j.Config.SSHPublicKey
+	return deployCfg
+}
+
+// Save writes the journal to disk and syncs it before returning, so the
+// record survives the process being killed right afterwards.
+func (j *DeployJournal) Save() error {
+	if j.path == "" {
+		return errors.New("deploy journal has no path")
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal deploy journal: %w", err)
+	}
+
+	tempPath := j.path + ".tmp"
+	f, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write deploy journal: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write deploy journal: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to sync deploy journal: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to close deploy journal: %w", err)
+	}
+
+	if err := os.Rename(tempPath, j.path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename deploy journal: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the journal file. Removing a missing journal is not an error.
+func (j *DeployJournal) Remove() error {
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove deploy journal: %w", err)
+	}
+	return nil
+}
+
+// startJournal creates and writes the journal for a new deployment.
+// Journaling is skipped when the Deployer has no state manager.
+func (d *Deployer) startJournal() {
+	d.journal = nil
+	if d.stateManager == nil {
+		return
+	}
+
+	d.journal = NewDeployJournal(d.stateManager, d.deployCfg)
+	d.journal.NetworkSlot = d.wgNetwork().Slot
+	d.saveJournal()
+}
+
+// journalInstance records a newly created instance and writes the journal.
+func (d *Deployer) journalInstance(p provider.Provider, offer *provider.Offer, instance *provider.Instance, wgConfig *wireguard.ConfigPair) {
+	if d.journal == nil {
+		return
+	}
+
+	d.journal.Provider = p.Name()
+	d.journal.Offer = offer
+	d.journal.InstanceID = instance.ID
+	d.journal.Spot = instance.Spot
+	d.journal.PublicIP = instance.PublicIP
+	if wgConfig != nil {
+		if wgConfig.ServerKeyPair != nil {
+			d.journal.ServerPublicKey = wgConfig.ServerKeyPair.PublicKey
+		}
+		// Keys from .env can be re-read on resume; generated keys cannot
+		if wgConfig.ClientKeyPair != nil && d.cfg.WireGuardPrivateKey == "" && d.journal.ClientKeySecret == "" {
+			d.journal.ClientKeySecret = d.storeClientKey(wgConfig.ClientKeyPair.PrivateKey)
+		}
+	}
+	if d.termination != nil {
+		d.journal.Termination = d.termination.mode
+		d.journal.RelayTokenHash = d.termination.tokenHash
+		d.journal.TerminationCredentialID = d.termination.credentialID
+	}
+	d.saveJournal()
+}
+
+// clientKeySecretName returns the name of the secret holding the generated
+// client key of a session's deployment. Each deployment of the session
+// overwrites it.
+func clientKeySecretName(session string) string {
+	return "wireguard-deploy-" + session
+}
+
+// storeClientKey stores a generated client key in the secret store, without
+// prompting, so an interrupted deployment can be resumed. It returns the
+// secret's name, or "" if the store is locked or read-only; the deployment
+// can then only be cleaned up.
+func (d *Deployer) storeClientKey(key string) string {
+	secrets, err := d.cfg.Secrets.Open(nil)
+	if err == nil {
+		name := clientKeySecretName(d.journal.Session)
+		if err = secrets.Set(name, key); err == nil {
+			return name
+		}
+	}
+	logging.Warn().Err(err).Msg("Failed to store the WireGuard client key, an interrupted deployment can't be resumed (set WIREGUARD_PRIVATE_KEY to avoid this)")
+	return ""
+}
+
+// loadClientKey reads a client key stored by storeClientKey.
+func (d *Deployer) loadClientKey(name string) (string, error) {
+	secrets, err := d.cfg.Secrets.Open(nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to open the secret store for the client key: %w", err)
+	}
+	key, err := secrets.Get(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the client key: %w", err)
+	}
+	return key, nil
+}
+
+// recordJournal records a step transition and writes the journal.
+func (d *Deployer) recordJournal(step DeployStep, message, detail string, completed bool) {
+	if d.journal == nil {
+		return
+	}
+
+	d.journal.Record(step, message, detail, completed)
+	d.saveJournal()
+}
+
+// failJournal marks the journal failed and keeps it on disk for `spinup cleanup`.
+func (d *Deployer) failJournal(err error) {
+	if d.journal == nil {
+		return
+	}
+
+	d.journal.MarkFailed(err)
+	d.saveJournal()
+	logging.Warn().
+		Str("instance_id", d.journal.InstanceID).
+		Str("journal", d.journal.Path()).
+		Err(err).
+		Msg("Deployment left an instance behind, run 'spinup cleanup'")
+}
+
+// removeJournal removes the journal once the deployment's outcome is settled.
+func (d *Deployer) removeJournal() {
+	if d.journal == nil {
+		return
+	}
+
+	if err := d.journal.Remove(); err != nil {
+		logging.Warn().Err(err).Msg("Failed to remove deploy journal")
+	}
+	d.journal = nil
+}
+
+// saveJournal writes the journal, logging rather than failing the deployment on error.
+func (d *Deployer) saveJournal() {
+	if err := d.journal.Save(); err != nil {
+		logging.Warn().Err(err).Msg("Failed to write deploy journal")
+	}
+}
+
+// CleanupJournal terminates the instance recorded in a deploy journal and
+// removes the journal. It mirrors Stop, but works from the journal instead of
+// the state file since an unfinished deployment never reached it. A journal
+// without an instance is simply removed.
+func (s *Stopper) CleanupJournal(ctx context.Context, journal *DeployJournal) (*StopResult, error) {
+	if journal == nil {
+		return nil, errors.New("deploy journal is required")
+	}
+
+	result := &StopResult{
+		StartedAt:  time.Now(),
+		InstanceID: journal.InstanceID,
+		Provider:   journal.Provider,
+	}
+
+	if journal.HasInstance() {
+		p, err := registry.GetProviderByName(journal.Provider, s.cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get provider: %w", err)
+		}
+		if err := s.terminateAndVerify(ctx, p, journal.InstanceID, result); err != nil {
+			return nil, err
+		}
+		revokeTerminationCredential(p, journal.TerminationCredentialID)
+	}
+
+	// Step 3: Remove WireGuard tunnel, in case the deployment got that far
+	if journal.LastCompletedStep >= StepWaitBoot {
+		s.reportStopProgress(StopStepRemoveTunnel, "Removing WireGuard tunnel...", "", false, false)
+		interfaceName := wireguard.InterfaceName
+		if network, err := wireguard.NetworkForSlot(journal.NetworkSlot); err == nil {
+			interfaceName = network.InterfaceName
+		}
+		if err := wireguard.TeardownTunnel(ctx, interfaceName); err != nil {
+			s.reportStopProgress(StopStepRemoveTunnel, "Tunnel removal (may have already been removed)", err.Error(), true, true)
+		} else {
+			s.reportStopProgress(StopStepRemoveTunnel, "Tunnel removed", "", true, false)
+		}
+	}
+
+	// Step 4: Remove the journal
+	s.reportStopProgress(StopStepClearState, "Removing deploy journal...", "", false, false)
+	if err := journal.Remove(); err != nil {
+		s.reportStopProgress(StopStepClearState, "Failed to remove deploy journal", err.Error(), false, false)
+		return nil, fmt.Errorf("step 4 failed: %w", err)
+	}
+	s.reportStopProgress(StopStepClearState, "Done", "", true, false)
+
+	result.CompletedAt = time.Now()
+
+	if journal.HasInstance() && !result.BillingVerified && !result.ManualVerificationRequired {
+		return result, ErrBillingNotVerified
+	}
+
+	return result, nil
+}
@@ -0,0 +1,399 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/offerexpr"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/mock"
+	"github.com/tmeurs/spinup/internal/wireguard"
+)
+
+func newJournalTestDeployer(t *testing.T, sm *config.StateManager, cfg *config.Config) *Deployer {
+	t.Helper()
+	deployCfg := DefaultDeployConfig()
+	deployCfg.Model = "qwen2.5-coder:7b"
+	if cfg == nil {
+		cfg = &config.Config{}
+	}
+	d, err := NewDeployer(cfg, deployCfg, WithStateManager(sm))
+	if err != nil {
+		t.Fatalf("NewDeployer() error = %v", err)
+	}
+	return d
+}
+
+// newTestSecrets returns a secret store that keeps each secret in a file of
+// dir, through the command backend.
+func newTestSecrets(t *testing.T) (config.SecretsConfig, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the test secret store uses cat and tee")
+	}
+	dir := t.TempDir()
+	return config.SecretsConfig{
+		Backend:      config.SecretBackendCommand,
+		Command:      "cat " + filepath.Join(dir, "{name}"),
+		StoreCommand: "tee " + filepath.Join(dir, "{name}"),
+	}, dir
+}
+
+func TestDeployJournal_SaveLoad(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm, _ := config.NewStateManager(tmpDir)
+
+	deployCfg := DefaultDeployConfig()
+	deployCfg.Model = "qwen2.5-coder:7b"
+	deployCfg.Region = "EU-NL"
+	deployCfg.DeadmanTimeoutHours = 4
+	deployCfg.Filter, _ = offerexpr.Parse("vram >= 48 && has_spot")
+
+	j := NewDeployJournal(sm, deployCfg)
+	j.Record(StepFetchPrices, "Fetching", "", false)
+	j.Record(StepFetchPrices, "Found", "", true)
+	j.Record(StepSelectOffer, "Selected", "", true)
+	j.Record(StepCreateInstance, "Creating", "", false)
+	j.InstanceID = "inst-1"
+	j.Provider = "vast"
+	j.NetworkSlot = 2
+
+	if err := j.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(tmpDir, ".spinup.journal"))
+	if err != nil {
+		t.Fatalf("journal file not created: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("journal permissions = %o, want 0600", info.Mode().Perm())
+	}
+
+	loaded, err := LoadDeployJournal(sm)
+	if err != nil {
+		t.Fatalf("LoadDeployJournal() error = %v", err)
+	}
+	if loaded == nil {
+		t.Fatal("LoadDeployJournal() returned nil")
+	}
+	if loaded.InstanceID != "inst-1" || loaded.Provider != "vast" || loaded.NetworkSlot != 2 {
+		t.Errorf("loaded journal = %+v", loaded)
+	}
+	if loaded.LastCompletedStep != StepSelectOffer {
+		t.Errorf("LastCompletedStep = %v, want %v", loaded.LastCompletedStep, StepSelectOffer)
+	}
+	if len(loaded.Entries) != 4 {
+		t.Errorf("len(Entries) = %d, want 4", len(loaded.Entries))
+	}
+	if !loaded.HasInstance() {
+		t.Error("HasInstance() = false, want true")
+	}
+
+	restored := loaded.DeployConfig()
+	if restored.Model != deployCfg.Model || restored.Region != "EU-NL" || restored.DeadmanTimeoutHours != 4 {
+		t.Errorf("DeployConfig() = %+v", restored)
+	}
+	if restored.Filter.String() != "vram >= 48 && has_spot" {
+		t.Errorf("DeployConfig().Filter = %q, want the journaled filter", restored.Filter)
+	}
+	if err := restored.Validate(); err != nil {
+		t.Errorf("DeployConfig().Validate() error = %v", err)
+	}
+
+	if err := loaded.Remove(); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if j, err := LoadDeployJournal(sm); err != nil || j != nil {
+		t.Errorf("LoadDeployJournal() after Remove = %v, %v; want nil, nil", j, err)
+	}
+
+	// Removing twice is not an error
+	if err := loaded.Remove(); err != nil {
+		t.Errorf("Remove() on missing journal error = %v", err)
+	}
+}
+
+func TestDeployJournal_Corrupt(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm, _ := config.NewStateManager(tmpDir)
+
+	if err := os.WriteFile(filepath.Join(tmpDir, ".spinup.journal"), []byte("{not json"), 0600); err != nil {
+		t.Fatalf("failed to write journal: %v", err)
+	}
+
+	if _, err := LoadDeployJournal(sm); err == nil {
+		t.Error("LoadDeployJournal() error = nil, want error for corrupt journal")
+	}
+}
+
+func TestListDeployJournals(t *testing.T) {
+	tmpDir := t.TempDir()
+	deployCfg := DefaultDeployConfig()
+	deployCfg.Model = "qwen2.5-coder:7b"
+
+	for _, session := range []string{"zeta", config.DefaultSessionName, "chat"} {
+		sm, _ := config.NewStateManager(tmpDir, config.WithSession(session))
+		if err := NewDeployJournal(sm, deployCfg).Save(); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	sm, _ := config.NewStateManager(tmpDir)
+	journals, err := ListDeployJournals(sm)
+	if err != nil {
+		t.Fatalf("ListDeployJournals() error = %v", err)
+	}
+
+	want := []string{"chat", config.DefaultSessionName, "zeta"}
+	if len(journals) != len(want) {
+		t.Fatalf("len(journals) = %d, want %d", len(journals), len(want))
+	}
+	for i, j := range journals {
+		if j.Session != want[i] {
+			t.Errorf("journals[%d].Session = %q, want %q", i, j.Session, want[i])
+		}
+	}
+}
+
+func TestDeployer_JournalLifecycle(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm, _ := config.NewStateManager(tmpDir, config.WithSession("work"))
+	secrets, secretsDir := newTestSecrets(t)
+	d := newJournalTestDeployer(t, sm, &config.Config{Secrets: secrets})
+	d.network, _ = wireguard.NetworkForSlot(1)
+
+	d.startJournal()
+	journalPath := filepath.Join(tmpDir, ".spinup.work.journal")
+	info, err := os.Stat(journalPath)
+	if err != nil {
+		t.Fatalf("journal not written on start: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("journal mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	d.reportProgress(StepFetchPrices, "Found offers", "", true)
+
+	clientKeys, _ := wireguard.GenerateKeyPair()
+	serverKeys, _ := wireguard.GenerateKeyPair()
+	wgConfig, _ := wireguard.GenerateConfigPairWithServerKeys(clientKeys, serverKeys, "pending:51820")
+	p := mock.New(mock.WithName("vast"))
+	instance := &provider.Instance{ID: "inst-42", Spot: true}
+	d.journalInstance(p, &provider.Offer{OfferID: "offer-1", Provider: p.Name()}, instance, wgConfig)
+
+	loaded, err := LoadDeployJournal(sm)
+	if err != nil || loaded == nil {
+		t.Fatalf("LoadDeployJournal() = %v, %v", loaded, err)
+	}
+	if loaded.InstanceID != "inst-42" {
+		t.Errorf("InstanceID = %q, want inst-42", loaded.InstanceID)
+	}
+	if loaded.Session != "work" {
+		t.Errorf("Session = %q, want work", loaded.Session)
+	}
+	if loaded.NetworkSlot != 1 {
+		t.Errorf("NetworkSlot = %d, want 1", loaded.NetworkSlot)
+	}
+	if loaded.ServerPublicKey != serverKeys.PublicKey {
+		t.Error("ServerPublicKey not recorded")
+	}
+	// The generated client key goes to the secret store, not the journal
+	if loaded.ClientKeySecret != "wireguard-deploy-work" {
+		t.Errorf("ClientKeySecret = %q, want wireguard-deploy-work", loaded.ClientKeySecret)
+	}
+	if data, _ := os.ReadFile(journalPath); bytes.Contains(data, []byte(clientKeys.PrivateKey)) {
+		t.Error("journal contains the client private key")
+	}
+	if stored, _ := os.ReadFile(filepath.Join(secretsDir, "wireguard-deploy-work")); strings.TrimSpace(string(stored)) != clientKeys.PrivateKey {
+		t.Errorf("stored client key = %q", stored)
+	}
+	if loaded.LastCompletedStep != StepFetchPrices {
+		t.Errorf("LastCompletedStep = %v, want %v", loaded.LastCompletedStep, StepFetchPrices)
+	}
+
+	d.failJournal(errors.New("terminate failed"))
+	loaded, _ = LoadDeployJournal(sm)
+	if loaded == nil || loaded.Status != JournalFailed || loaded.Error != "terminate failed" {
+		t.Errorf("journal after failJournal = %+v", loaded)
+	}
+
+	d.removeJournal()
+	if _, err := os.Stat(journalPath); !os.IsNotExist(err) {
+		t.Errorf("journal still exists after removeJournal: %v", err)
+	}
+}
+
+func TestDeployer_JournalSkipsEnvClientKey(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm, _ := config.NewStateManager(tmpDir)
+	clientKeys, _ := wireguard.GenerateKeyPair()
+	d := newJournalTestDeployer(t, sm, &config.Config{WireGuardPrivateKey: clientKeys.PrivateKey})
+
+	d.startJournal()
+	serverKeys, _ := wireguard.GenerateKeyPair()
+	wgConfig, _ := wireguard.GenerateConfigPairWithServerKeys(clientKeys, serverKeys, "pending:51820")
+	d.journalInstance(mock.New(mock.WithName("vast")), nil, &provider.Instance{ID: "inst-1"}, wgConfig)
+
+	if d.journal.ClientKeySecret != "" {
+		t.Error("client key from .env should not be stored for the journal")
+	}
+}
+
+func TestDeployer_JournalWithoutSecretStore(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+	d := newJournalTestDeployer(t, sm, &config.Config{Secrets: config.SecretsConfig{Backend: config.SecretBackendEnv}})
+
+	d.startJournal()
+	clientKeys, _ := wireguard.GenerateKeyPair()
+	serverKeys, _ := wireguard.GenerateKeyPair()
+	wgConfig, _ := wireguard.GenerateConfigPairWithServerKeys(clientKeys, serverKeys, "pending:51820")
+	d.journalInstance(mock.New(mock.WithName("vast")), nil, &provider.Instance{ID: "inst-1"}, wgConfig)
+
+	// A read-only store leaves the deployment without resume, never with
+	// the key in the journal
+	loaded, _ := LoadDeployJournal(sm)
+	if loaded == nil || loaded.InstanceID != "inst-1" || loaded.ClientKeySecret != "" {
+		t.Errorf("journal = %+v, want the instance without a client key", loaded)
+	}
+	if _, err := d.resumeWireGuardConfig(loaded); err == nil {
+		t.Error("resumeWireGuardConfig() succeeded without a client key")
+	}
+}
+
+func TestDeployer_NoJournalWithoutStateManager(t *testing.T) {
+	d := newJournalTestDeployer(t, nil, nil)
+	d.startJournal()
+	if d.journal != nil {
+		t.Error("journal started without a state manager")
+	}
+
+	// Helpers are no-ops without a journal
+	d.reportProgress(StepFetchPrices, "Found offers", "", true)
+	d.failJournal(errors.New("boom"))
+	d.removeJournal()
+}
+
+func TestDeployer_resumeWireGuardConfig(t *testing.T) {
+	clientKeys, _ := wireguard.GenerateKeyPair()
+	serverKeys, _ := wireguard.GenerateKeyPair()
+	secrets, secretsDir := newTestSecrets(t)
+	if err := os.WriteFile(filepath.Join(secretsDir, "wireguard-deploy-default"), []byte(clientKeys.PrivateKey+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		cfg     *config.Config
+		journal *DeployJournal
+		wantErr bool
+	}{
+		{
+			name:    "stored client key",
+			cfg:     &config.Config{Secrets: secrets},
+			journal: &DeployJournal{ServerPublicKey: serverKeys.PublicKey, ClientKeySecret: "wireguard-deploy-default", NetworkSlot: 2},
+		},
+		{
+			name:    "stored client key missing",
+			cfg:     &config.Config{Secrets: secrets},
+			journal: &DeployJournal{ServerPublicKey: serverKeys.PublicKey, ClientKeySecret: "wireguard-deploy-gone"},
+			wantErr: true,
+		},
+		{
+			name:    "client key from config",
+			cfg:     &config.Config{WireGuardPrivateKey: clientKeys.PrivateKey},
+			journal: &DeployJournal{ServerPublicKey: serverKeys.PublicKey, NetworkSlot: 2},
+		},
+		{
+			name:    "no client key",
+			cfg:     &config.Config{},
+			journal: &DeployJournal{ServerPublicKey: serverKeys.PublicKey},
+			wantErr: true,
+		},
+		{
+			name:    "no server key",
+			cfg:     &config.Config{},
+			journal: &DeployJournal{ClientKeySecret: "wireguard-deploy-default"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newJournalTestDeployer(t, nil, tt.cfg)
+			d.network, _ = wireguard.NetworkForSlot(tt.journal.NetworkSlot)
+
+			wgConfig, err := d.resumeWireGuardConfig(tt.journal)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resumeWireGuardConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if wgConfig.Client.ClientPrivateKey != clientKeys.PrivateKey {
+				t.Error("client private key not restored")
+			}
+			if wgConfig.Client.ServerPublicKey != serverKeys.PublicKey {
+				t.Error("server public key not restored")
+			}
+			if wgConfig.Client.ClientAddress != "10.13.39.2/24" {
+				t.Errorf("ClientAddress = %q, want 10.13.39.2/24", wgConfig.Client.ClientAddress)
+			}
+			if wgConfig.ServerKeyPair.PublicKey != serverKeys.PublicKey {
+				t.Error("ServerKeyPair.PublicKey not set for saveState")
+			}
+		})
+	}
+}
+
+func TestIsInstanceNotFound(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{provider.ErrInstanceNotFound, true},
+		{provider.ErrInstanceNotFound.Wrap(errors.New("gone")), true},
+		{fmt.Errorf("lookup: %w", provider.ErrInstanceNotFound.Wrap(errors.New("gone"))), true},
+		{provider.ErrRateLimited, false},
+		{errors.New("instance not found"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isInstanceNotFound(tt.err); got != tt.want {
+			t.Errorf("isInstanceNotFound(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestStopper_CleanupJournal_NoInstance(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm, _ := config.NewStateManager(tmpDir)
+	deployCfg := DefaultDeployConfig()
+	deployCfg.Model = "qwen2.5-coder:7b"
+
+	j := NewDeployJournal(sm, deployCfg)
+	j.Record(StepFetchPrices, "Found offers", "", true)
+	if err := j.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	stopper, err := NewStopper(&config.Config{}, nil)
+	if err != nil {
+		t.Fatalf("NewStopper() error = %v", err)
+	}
+
+	if _, err := stopper.CleanupJournal(context.Background(), j); err != nil {
+		t.Fatalf("CleanupJournal() error = %v", err)
+	}
+	if loaded, _ := LoadDeployJournal(sm); loaded != nil {
+		t.Error("journal still exists after CleanupJournal")
+	}
+}
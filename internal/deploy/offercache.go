@@ -0,0 +1,218 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/logging"
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+// OfferCacheFileName is the file in the state directory that caches offer
+// lists. It is shared by all sessions.
+const OfferCacheFileName = ".spinup.offers"
+
+// OfferCacheEntry holds the offers one provider returned for one filter.
+type OfferCacheEntry struct {
+	Provider  string               `json:"provider"`
+	Filter    provider.OfferFilter `json:"filter"`
+	FetchedAt time.Time            `json:"fetched_at"`
+	Offers    []provider.Offer     `json:"offers"`
+}
+
+// Age returns how long ago the offers were fetched.
+func (e OfferCacheEntry) Age() time.Duration {
+	return time.Since(e.FetchedAt)
+}
+
+// OfferCache stores offer lists per provider and OfferFilter, so offers can
+// be shown before (or without) reaching the providers. Cached offers may be
+// gone at the provider; a deployment always re-checks the offer it rents.
+type OfferCache struct {
+	Entries map[string]OfferCacheEntry `json:"entries"`
+
+	path string
+	ttl  time.Duration
+}
+
+// LoadOfferCache reads the offer cache from the state directory. Entries
+// younger than ttl are fresh; a zero ttl makes every entry stale.
+// A missing file yields an empty cache.
+func LoadOfferCache(stateManager *config.StateManager, ttl time.Duration) (*OfferCache, error) {
+	c := &OfferCache{
+		Entries: make(map[string]OfferCacheEntry),
+		path:    filepath.Join(stateManager.StateDir(), OfferCacheFileName),
+		ttl:     ttl,
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return c, fmt.Errorf("failed to read offer cache: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return c, fmt.Errorf("offer cache is corrupt: %w", err)
+	}
+	if c.Entries == nil {
+		c.Entries = make(map[string]OfferCacheEntry)
+	}
+	return c, nil
+}
+
+// offerCacheKey identifies the offers of a provider for a filter.
+func offerCacheKey(providerName string, filter provider.OfferFilter) string {
+	return fmt.Sprintf("%s|gpu=%s|vram=%d|region=%s|spot=%t|ondemand=%t|max=%g",
+		providerName, filter.GPUType, filter.MinVRAM, filter.Region,
+		filter.SpotOnly, filter.OnDemandOnly, filter.MaxHourlyPrice)
+}
+
+// Lookup returns the cached offers of a provider for filter, fresh or not.
+func (c *OfferCache) Lookup(providerName string, filter provider.OfferFilter) (OfferCacheEntry, bool) {
+	e, ok := c.Entries[offerCacheKey(providerName, filter)]
+	return e, ok
+}
+
+// IsFresh returns true if the entry is younger than the cache TTL.
+func (c *OfferCache) IsFresh(e OfferCacheEntry) bool {
+	return c.ttl > 0 && e.Age() < c.ttl
+}
+
+// Store caches the offers of every successful result.
+func (c *OfferCache) Store(results []ProviderOffers, filter provider.OfferFilter) {
+	for _, r := range results {
+		if !r.Report.OK() || r.Report.Cached {
+			continue
+		}
+		fetchedAt := r.Report.FetchedAt
+		if fetchedAt.IsZero() {
+			fetchedAt = time.Now()
+		}
+		c.Entries[offerCacheKey(r.Report.Provider, filter)] = OfferCacheEntry{
+			Provider:  r.Report.Provider,
+			Filter:    filter,
+			FetchedAt: fetchedAt,
+			Offers:    r.Offers,
+		}
+	}
+}
+
+// Invalidate drops all cached offers of a provider.
+func (c *OfferCache) Invalidate(providerName string) {
+	for key, e := range c.Entries {
+		if e.Provider == providerName {
+			delete(c.Entries, key)
+		}
+	}
+}
+
+// Save writes the offer cache to the state directory.
+func (c *OfferCache) Save() error {
+	if c.path == "" {
+		return fmt.Errorf("offer cache has no path")
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to marshal offer cache: %w", err)
+	}
+
+	tempPath := c.path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write offer cache: %w", err)
+	}
+	if err := os.Rename(tempPath, c.path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename offer cache: %w", err)
+	}
+	return nil
+}
+
+// CachedOffers returns the cached offers of providers for filter regardless
+// of their age, in the order of providers. Providers without an entry are
+// left out. The reports are marked Cached.
+func (c *OfferCache) CachedOffers(providers []provider.Provider, filter provider.OfferFilter) []ProviderOffers {
+	var results []ProviderOffers
+	for _, p := range providers {
+		e, ok := c.Lookup(p.Name(), filter)
+		if !ok {
+			continue
+		}
+		results = append(results, cachedProviderOffers(p, e))
+	}
+	return results
+}
+
+// FetchOffers works like the package-level FetchOffers, but takes the offers
+// of providers with a fresh cache entry from the cache. Offers fetched from
+// providers are stored and the cache is saved. A nil cache fetches every
+// provider.
+func (c *OfferCache) FetchOffers(ctx context.Context, providers []provider.Provider, filter provider.OfferFilter, timeout time.Duration) []ProviderOffers {
+	if c == nil {
+		return FetchOffers(ctx, providers, filter, timeout)
+	}
+
+	results := make([]ProviderOffers, len(providers))
+	var live []provider.Provider
+	var liveIndex []int
+	for i, p := range providers {
+		if e, ok := c.Lookup(p.Name(), filter); ok && c.IsFresh(e) {
+			results[i] = cachedProviderOffers(p, e)
+			logging.Debug().
+				Str("provider", p.Name()).
+				Int("offers", len(e.Offers)).
+				Dur("age", e.Age()).
+				Msg("Using cached offers")
+			continue
+		}
+		live = append(live, p)
+		liveIndex = append(liveIndex, i)
+	}
+
+	if len(live) == 0 {
+		return results
+	}
+
+	fetched := FetchOffers(ctx, live, filter, timeout)
+	for j, r := range fetched {
+		results[liveIndex[j]] = r
+	}
+
+	c.Store(fetched, filter)
+	if err := c.Save(); err != nil {
+		logging.Warn().Err(err).Msg("Failed to save offer cache")
+	}
+	return results
+}
+
+// cachedProviderOffers returns a cache entry as a fetch result.
+func cachedProviderOffers(p provider.Provider, e OfferCacheEntry) ProviderOffers {
+	return ProviderOffers{
+		Provider: p,
+		Offers:   e.Offers,
+		Report: ProviderFetchReport{
+			Provider:   p.Name(),
+			OfferCount: len(e.Offers),
+			Cached:     true,
+			FetchedAt:  e.FetchedAt,
+		},
+	}
+}
+
+// OldestFetch returns the earliest FetchedAt of the cached results, or the
+// zero time if none are cached.
+func OldestFetch(results []ProviderOffers) time.Time {
+	var oldest time.Time
+	for _, r := range results {
+		if r.Report.Cached && (oldest.IsZero() || r.Report.FetchedAt.Before(oldest)) {
+			oldest = r.Report.FetchedAt
+		}
+	}
+	return oldest
+}
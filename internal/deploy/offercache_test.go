@@ -0,0 +1,208 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/mock"
+)
+
+func TestOfferCacheKey(t *testing.T) {
+	base := provider.OfferFilter{GPUType: "A100", MinVRAM: 40, Region: "eu-west"}
+	variants := []provider.OfferFilter{
+		{GPUType: "H100", MinVRAM: 40, Region: "eu-west"},
+		{GPUType: "A100", MinVRAM: 80, Region: "eu-west"},
+		{GPUType: "A100", MinVRAM: 40, Region: "us-east"},
+		{GPUType: "A100", MinVRAM: 40, Region: "eu-west", OnDemandOnly: true},
+		{GPUType: "A100", MinVRAM: 40, Region: "eu-west", SpotOnly: true},
+		{GPUType: "A100", MinVRAM: 40, Region: "eu-west", MaxHourlyPrice: 2},
+	}
+
+	key := offerCacheKey("vast", base)
+	if key != offerCacheKey("vast", base) {
+		t.Error("offerCacheKey() is not stable")
+	}
+	if key == offerCacheKey("lambda", base) {
+		t.Error("offerCacheKey() ignores the provider")
+	}
+	for _, f := range variants {
+		if key == offerCacheKey("vast", f) {
+			t.Errorf("offerCacheKey() doesn't distinguish %+v", f)
+		}
+	}
+}
+
+func TestOfferCache_StoreSaveLoad(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+	filter := provider.OfferFilter{MinVRAM: 24}
+
+	cache, err := LoadOfferCache(sm, time.Minute)
+	if err != nil {
+		t.Fatalf("LoadOfferCache() error = %v", err)
+	}
+
+	cache.Store([]ProviderOffers{
+		{Offers: []provider.Offer{failoverTestOffer("vast", "v1")}, Report: ProviderFetchReport{Provider: "vast", OfferCount: 1, FetchedAt: time.Now()}},
+		{Report: ProviderFetchReport{Provider: "lambda", Err: provider.ErrAuthenticationFailed}},
+	}, filter)
+	if err := cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(sm.StateDir(), OfferCacheFileName))
+	if err != nil {
+		t.Fatalf("offer cache not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("permissions = %o, want 600", info.Mode().Perm())
+	}
+
+	loaded, err := LoadOfferCache(sm, time.Minute)
+	if err != nil {
+		t.Fatalf("LoadOfferCache() error = %v", err)
+	}
+	e, ok := loaded.Lookup("vast", filter)
+	if !ok || len(e.Offers) != 1 || e.Offers[0].OfferID != "v1" {
+		t.Fatalf("Lookup(vast) = %+v, %v", e, ok)
+	}
+	if !loaded.IsFresh(e) {
+		t.Error("IsFresh() = false for a new entry")
+	}
+	if _, ok := loaded.Lookup("lambda", filter); ok {
+		t.Error("failed fetch was cached")
+	}
+	if _, ok := loaded.Lookup("vast", provider.OfferFilter{MinVRAM: 80}); ok {
+		t.Error("entry found for a different filter")
+	}
+
+	loaded.Invalidate("vast")
+	if _, ok := loaded.Lookup("vast", filter); ok {
+		t.Error("Invalidate() kept the entry")
+	}
+}
+
+func TestOfferCache_IsFresh(t *testing.T) {
+	old := OfferCacheEntry{FetchedAt: time.Now().Add(-10 * time.Minute)}
+	recent := OfferCacheEntry{FetchedAt: time.Now().Add(-time.Minute)}
+
+	cache := &OfferCache{ttl: 5 * time.Minute}
+	if cache.IsFresh(old) || !cache.IsFresh(recent) {
+		t.Error("IsFresh() doesn't respect the TTL")
+	}
+
+	disabled := &OfferCache{}
+	if disabled.IsFresh(recent) {
+		t.Error("IsFresh() = true with a zero TTL")
+	}
+}
+
+func TestOfferCache_FetchOffers(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+	filter := provider.OfferFilter{}
+
+	vast := mock.New(mock.WithName("vast"), mock.WithOffers([]provider.Offer{failoverTestOffer("vast", "v1")}))
+	lambda := mock.New(mock.WithName("lambda"), mock.WithOffers([]provider.Offer{failoverTestOffer("lambda", "l1")}))
+	providers := []provider.Provider{vast, lambda}
+
+	// First fetch goes to the providers and fills the cache
+	cache, _ := LoadOfferCache(sm, time.Minute)
+	results := cache.FetchOffers(context.Background(), providers, filter, time.Second)
+	if results[0].Report.Cached || results[1].Report.Cached {
+		t.Error("first fetch reported cached offers")
+	}
+
+	// Stale lambda entry is fetched again, vast comes from the cache
+	cache, _ = LoadOfferCache(sm, time.Minute)
+	e, _ := cache.Lookup("lambda", filter)
+	e.FetchedAt = time.Now().Add(-time.Hour)
+	cache.Entries[offerCacheKey("lambda", filter)] = e
+
+	results = cache.FetchOffers(context.Background(), providers, filter, time.Second)
+	if len(vast.GetOffersCalls) != 1 {
+		t.Errorf("vast GetOffers calls = %d, want 1", len(vast.GetOffersCalls))
+	}
+	if len(lambda.GetOffersCalls) != 2 {
+		t.Errorf("lambda GetOffers calls = %d, want 2", len(lambda.GetOffersCalls))
+	}
+	if !results[0].Report.Cached || results[0].Offers[0].OfferID != "v1" {
+		t.Errorf("results[0] = %+v, want cached vast offer", results[0])
+	}
+	if results[1].Report.Cached || results[1].Report.Provider != "lambda" {
+		t.Errorf("results[1] = %+v, want live lambda offers", results[1])
+	}
+
+	// A nil cache always fetches
+	var none *OfferCache
+	none.FetchOffers(context.Background(), providers, filter, time.Second)
+	if len(vast.GetOffersCalls) != 2 {
+		t.Errorf("vast GetOffers calls = %d, want 2", len(vast.GetOffersCalls))
+	}
+}
+
+func TestOfferCache_CachedOffers(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+	filter := provider.OfferFilter{}
+
+	vast := mock.New(mock.WithName("vast"))
+	lambda := mock.New(mock.WithName("lambda"))
+
+	// Entries are returned however old they are
+	cache, _ := LoadOfferCache(sm, 0)
+	fetchedAt := time.Now().Add(-time.Hour)
+	cache.Store([]ProviderOffers{
+		{Offers: []provider.Offer{failoverTestOffer("lambda", "l1")}, Report: ProviderFetchReport{Provider: "lambda", FetchedAt: fetchedAt}},
+	}, filter)
+
+	results := cache.CachedOffers([]provider.Provider{vast, lambda}, filter)
+	if len(results) != 1 || results[0].Provider != lambda || !results[0].Report.Cached {
+		t.Fatalf("CachedOffers() = %+v, want cached lambda offers", results)
+	}
+	if got := OldestFetch(results); !got.Equal(fetchedAt) {
+		t.Errorf("OldestFetch() = %v, want %v", got, fetchedAt)
+	}
+	if len(vast.GetOffersCalls)+len(lambda.GetOffersCalls) != 0 {
+		t.Error("CachedOffers() queried a provider")
+	}
+}
+
+func TestLoadOfferCache_Corrupt(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+	os.WriteFile(filepath.Join(sm.StateDir(), OfferCacheFileName), []byte("nope"), 0600)
+
+	cache, err := LoadOfferCache(sm, time.Minute)
+	if err == nil {
+		t.Error("LoadOfferCache() error = nil, want corrupt error")
+	}
+	if cache == nil || cache.Entries == nil {
+		t.Fatal("LoadOfferCache() should return a usable empty cache on error")
+	}
+}
+
+func TestDeployer_recheckOffer_InvalidatesCache(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+	cfg := &config.Config{OfferCacheTTL: time.Minute}
+	d := &Deployer{cfg: cfg, deployCfg: DefaultDeployConfig(), stateManager: sm}
+
+	cache, _ := LoadOfferCache(sm, cfg.OfferCacheTTL)
+	cache.Store([]ProviderOffers{
+		{Offers: []provider.Offer{failoverTestOffer("vast", "v1")}, Report: ProviderFetchReport{Provider: "vast", FetchedAt: time.Now()}},
+	}, provider.OfferFilter{})
+	cache.Save()
+
+	vast := mock.New(mock.WithName("vast"))
+	offer := failoverTestOffer("vast", "v1")
+	if _, err := d.recheckOffer(context.Background(), vast, &offer); !errors.Is(err, provider.ErrOfferNotFound) {
+		t.Fatalf("recheckOffer() error = %v, want ErrOfferNotFound", err)
+	}
+
+	cache, _ = LoadOfferCache(sm, cfg.OfferCacheTTL)
+	if _, ok := cache.Lookup("vast", provider.OfferFilter{}); ok {
+		t.Error("stale vast offers still cached")
+	}
+}
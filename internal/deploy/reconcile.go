@@ -111,6 +111,10 @@ type ReconcileOptions struct {
 	// WarningCallback is called when a warning is generated.
 	// If nil, warnings are ignored.
 	WarningCallback func(*ReconcileWarning)
+
+	// Provider is the provider to verify the instance at.
+	// If nil, the provider named in the state is used.
+	Provider provider.Provider
 }
 
 // DefaultReconcileOptions returns ReconcileOptions with sensible defaults.
@@ -176,7 +180,10 @@ func (r *Reconciler) ReconcileState(ctx context.Context) (*ReconcileResult, erro
 	providerName := state.Instance.Provider
 
 	// Get provider client
-	p, err := registry.GetProviderByName(providerName, r.cfg)
+	p := r.opts.Provider
+	if p == nil {
+		p, err = registry.GetProviderByName(providerName, r.cfg)
+	}
 	if err != nil {
 		// Cannot get provider - this could be a configuration issue
 		// or the provider API key was removed
@@ -0,0 +1,259 @@
+package deploy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/logging"
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+// Offer score factor names.
+const (
+	FactorPrice       = "price"
+	FactorRegion      = "region"
+	FactorProvider    = "provider"
+	FactorReliability = "reliability"
+	FactorBootTime    = "boot_time"
+)
+
+// unknownBootTimeValue is the boot time factor of a provider without history
+// when other providers have some: neither rewarded nor fully penalized.
+const unknownBootTimeValue = 0.5
+
+// ScoreFactor is one weighted component of an offer score.
+type ScoreFactor struct {
+	// Name is the factor name (one of the Factor constants).
+	Name string
+
+	// Value is the factor's rating of the offer, from 0 (worst) to 1 (best).
+	Value float64
+
+	// Weight is the configured weight of the factor.
+	Weight float64
+}
+
+// OfferScore is the rating of an offer, from 0 (worst) to 1 (best).
+type OfferScore struct {
+	// Total is the weighted average of the factor values.
+	Total float64
+
+	// Factors is the breakdown of Total.
+	Factors []ScoreFactor
+}
+
+// Factor returns the factor with the given name.
+func (s OfferScore) Factor(name string) (ScoreFactor, bool) {
+	for _, f := range s.Factors {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return ScoreFactor{}, false
+}
+
+// String returns a compact breakdown, e.g. "0.87 (price 0.92, region 1.00)".
+// Factors with zero weight are left out.
+func (s OfferScore) String() string {
+	var parts []string
+	for _, f := range s.Factors {
+		if f.Weight > 0 {
+			parts = append(parts, fmt.Sprintf("%s %.2f", f.Name, f.Value))
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("%.2f", s.Total)
+	}
+	return fmt.Sprintf("%.2f (%s)", s.Total, strings.Join(parts, ", "))
+}
+
+// OfferScorer rates offers for deployment. Offers are scored as a set, since
+// factors such as price are relative to the other offers.
+type OfferScorer interface {
+	// Score returns one score per offer, in the order of offers.
+	Score(offers []provider.Offer) []OfferScore
+}
+
+// WeightedScorer is the default OfferScorer. It combines price, region
+// preference, provider priority, host reliability and historical boot time
+// using the weights of a config.ScoringConfig.
+type WeightedScorer struct {
+	scoring    config.ScoringConfig
+	preferSpot bool
+	bootTimes  map[string]time.Duration
+}
+
+// WeightedScorerOption is a functional option for WeightedScorer.
+type WeightedScorerOption func(*WeightedScorer)
+
+// WithScorerPreferSpot rates offers by their spot price where available.
+func WithScorerPreferSpot(preferSpot bool) WeightedScorerOption {
+	return func(s *WeightedScorer) {
+		s.preferSpot = preferSpot
+	}
+}
+
+// WithBootTimes sets the average boot time per provider.
+func WithBootTimes(bootTimes map[string]time.Duration) WeightedScorerOption {
+	return func(s *WeightedScorer) {
+		s.bootTimes = bootTimes
+	}
+}
+
+// NewWeightedScorer creates a WeightedScorer.
+func NewWeightedScorer(scoring config.ScoringConfig, opts ...WeightedScorerOption) *WeightedScorer {
+	s := &WeightedScorer{
+		scoring: scoring,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// NewDefaultOfferScorer creates the WeightedScorer for cfg, using the boot
+// history in the state directory if stateManager is set.
+func NewDefaultOfferScorer(cfg *config.Config, stateManager *config.StateManager, preferSpot bool) *WeightedScorer {
+	opts := []WeightedScorerOption{WithScorerPreferSpot(preferSpot)}
+	if stateManager != nil {
+		history, err := LoadBootHistory(stateManager)
+		if err != nil {
+			logging.Warn().Err(err).Msg("Ignoring boot history for offer scoring")
+		} else {
+			opts = append(opts, WithBootTimes(history.BootTimes()))
+		}
+	}
+	return NewWeightedScorer(cfg.Scoring, opts...)
+}
+
+// Score implements OfferScorer.
+func (s *WeightedScorer) Score(offers []provider.Offer) []OfferScore {
+	weights := s.scoring
+	if weights.PriceWeight+weights.RegionWeight+weights.ProviderWeight+weights.ReliabilityWeight+weights.BootTimeWeight == 0 {
+		weights.PriceWeight = 1
+	}
+
+	minPrice := 0.0
+	for _, o := range offers {
+		if p := s.price(o); p > 0 && (minPrice == 0 || p < minPrice) {
+			minPrice = p
+		}
+	}
+
+	fastestBoot := time.Duration(0)
+	for _, o := range offers {
+		if d, ok := s.bootTimes[o.Provider]; ok && d > 0 && (fastestBoot == 0 || d < fastestBoot) {
+			fastestBoot = d
+		}
+	}
+
+	scores := make([]OfferScore, len(offers))
+	for i, o := range offers {
+		factors := []ScoreFactor{
+			{Name: FactorPrice, Value: priceValue(s.price(o), minPrice), Weight: weights.PriceWeight},
+			{Name: FactorRegion, Value: rankValue(o.Region, s.scoring.PreferredRegions), Weight: weights.RegionWeight},
+			{Name: FactorProvider, Value: rankValue(o.Provider, s.scoring.ProviderPriority), Weight: weights.ProviderWeight},
+			{Name: FactorReliability, Value: reliabilityValue(o.Reliability), Weight: weights.ReliabilityWeight},
+			{Name: FactorBootTime, Value: s.bootTimeValue(o.Provider, fastestBoot), Weight: weights.BootTimeWeight},
+		}
+
+		var sum, totalWeight float64
+		for _, f := range factors {
+			sum += f.Value * f.Weight
+			totalWeight += f.Weight
+		}
+		scores[i] = OfferScore{Total: sum / totalWeight, Factors: factors}
+	}
+
+	return scores
+}
+
+// price returns the hourly price the offer would be rented at.
+func (s *WeightedScorer) price(o provider.Offer) float64 {
+	if s.preferSpot && o.SpotPrice != nil && *o.SpotPrice > 0 {
+		return *o.SpotPrice
+	}
+	return o.OnDemandPrice
+}
+
+// priceValue rates a price relative to the cheapest offer: the cheapest is 1,
+// twice as expensive is 0.5.
+func priceValue(price, minPrice float64) float64 {
+	if price <= 0 || minPrice <= 0 {
+		return 1
+	}
+	return minPrice / price
+}
+
+// rankValue rates a name by its position in a preference list: the first
+// entry is 1, later entries less, unlisted names 0. Without preferences every
+// name is 1. Names match case-insensitively, and a preference also matches
+// names it is a prefix of ("eu" matches "EU-West").
+func rankValue(name string, preferences []string) float64 {
+	if len(preferences) == 0 {
+		return 1
+	}
+	name = strings.ToLower(name)
+	for i, pref := range preferences {
+		if strings.HasPrefix(name, strings.ToLower(pref)) {
+			return 1 - float64(i)/float64(len(preferences))
+		}
+	}
+	return 0
+}
+
+// reliabilityValue rates a reported host reliability. Providers that don't
+// report one (managed clouds) count as fully reliable.
+func reliabilityValue(reliability float64) float64 {
+	if reliability <= 0 {
+		return 1
+	}
+	if reliability > 1 {
+		return 1
+	}
+	return reliability
+}
+
+// bootTimeValue rates a provider's average boot time relative to the fastest
+// provider among the offers.
+func (s *WeightedScorer) bootTimeValue(providerName string, fastest time.Duration) float64 {
+	if fastest == 0 {
+		return 1
+	}
+	d, ok := s.bootTimes[providerName]
+	if !ok || d <= 0 {
+		return unknownBootTimeValue
+	}
+	return float64(fastest) / float64(d)
+}
+
+// sortByScore orders offers by descending score. Equal scores keep the
+// cheaper offer first.
+func sortByScore(offers []rankedOffer, effectivePrice func(*provider.Offer) float64) {
+	sort.SliceStable(offers, func(i, j int) bool {
+		if offers[i].Score.Total != offers[j].Score.Total {
+			return offers[i].Score.Total > offers[j].Score.Total
+		}
+		return effectivePrice(&offers[i].Offer) < effectivePrice(&offers[j].Offer)
+	})
+}
+
+// OfferKey identifies an offer across providers, e.g. to look up its score.
+func OfferKey(o provider.Offer) string {
+	return o.Provider + "/" + o.OfferID
+}
+
+// ScoreOffers scores offers and returns the scores keyed by OfferKey.
+func ScoreOffers(scorer OfferScorer, offers []provider.Offer) map[string]OfferScore {
+	scores := scorer.Score(offers)
+	byKey := make(map[string]OfferScore, len(offers))
+	for i, o := range offers {
+		byKey[OfferKey(o)] = scores[i]
+	}
+	return byKey
+}
@@ -0,0 +1,195 @@
+package deploy
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+func scoreTestOffer(providerName, id, region string, price float64) provider.Offer {
+	return provider.Offer{OfferID: id, Provider: providerName, GPU: "A100 80GB", Region: region, OnDemandPrice: price, Available: true}
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestPriceValue(t *testing.T) {
+	tests := []struct {
+		price, minPrice float64
+		want            float64
+	}{
+		{1.0, 1.0, 1},
+		{2.0, 1.0, 0.5},
+		{4.0, 1.0, 0.25},
+		{0, 1.0, 1},
+		{1.0, 0, 1},
+	}
+
+	for _, tt := range tests {
+		if got := priceValue(tt.price, tt.minPrice); !almostEqual(got, tt.want) {
+			t.Errorf("priceValue(%v, %v) = %v, want %v", tt.price, tt.minPrice, got, tt.want)
+		}
+	}
+}
+
+func TestRankValue(t *testing.T) {
+	prefs := []string{"eu", "us-east"}
+	tests := []struct {
+		name  string
+		prefs []string
+		want  float64
+	}{
+		{"EU-West", prefs, 1},
+		{"US-East-1", prefs, 0.5},
+		{"asia", prefs, 0},
+		{"anything", nil, 1},
+	}
+
+	for _, tt := range tests {
+		if got := rankValue(tt.name, tt.prefs); !almostEqual(got, tt.want) {
+			t.Errorf("rankValue(%q, %v) = %v, want %v", tt.name, tt.prefs, got, tt.want)
+		}
+	}
+}
+
+func TestReliabilityValue(t *testing.T) {
+	tests := []struct {
+		reliability float64
+		want        float64
+	}{
+		{0, 1},
+		{0.95, 0.95},
+		{1.5, 1},
+	}
+
+	for _, tt := range tests {
+		if got := reliabilityValue(tt.reliability); !almostEqual(got, tt.want) {
+			t.Errorf("reliabilityValue(%v) = %v, want %v", tt.reliability, got, tt.want)
+		}
+	}
+}
+
+func TestWeightedScorer_BootTime(t *testing.T) {
+	scorer := NewWeightedScorer(config.ScoringConfig{BootTimeWeight: 1}, WithBootTimes(map[string]time.Duration{
+		"lambda": 60 * time.Second,
+		"vast":   120 * time.Second,
+	}))
+
+	scores := scorer.Score([]provider.Offer{
+		scoreTestOffer("lambda", "l", "EU", 1),
+		scoreTestOffer("vast", "v", "EU", 1),
+		scoreTestOffer("runpod", "r", "EU", 1),
+	})
+
+	want := []float64{1, 0.5, unknownBootTimeValue}
+	for i, w := range want {
+		if !almostEqual(scores[i].Total, w) {
+			t.Errorf("scores[%d].Total = %v, want %v", i, scores[i].Total, w)
+		}
+	}
+}
+
+func TestWeightedScorer_ZeroWeightsRankByPrice(t *testing.T) {
+	scorer := NewWeightedScorer(config.ScoringConfig{})
+	scores := scorer.Score([]provider.Offer{
+		scoreTestOffer("vast", "a", "EU", 2),
+		scoreTestOffer("vast", "b", "EU", 1),
+	})
+
+	if !almostEqual(scores[0].Total, 0.5) || !almostEqual(scores[1].Total, 1) {
+		t.Errorf("scores = %v, %v, want 0.5, 1", scores[0].Total, scores[1].Total)
+	}
+	if f, ok := scores[0].Factor(FactorPrice); !ok || f.Weight != 1 {
+		t.Errorf("price factor = %+v, want weight 1", f)
+	}
+}
+
+func TestWeightedScorer_PreferSpot(t *testing.T) {
+	spot := 0.5
+	cheapSpot := scoreTestOffer("vast", "a", "EU", 2)
+	cheapSpot.SpotPrice = &spot
+	onDemand := scoreTestOffer("lambda", "b", "EU", 1)
+
+	scores := NewWeightedScorer(config.ScoringConfig{PriceWeight: 1}, WithScorerPreferSpot(true)).Score([]provider.Offer{cheapSpot, onDemand})
+	if scores[0].Total <= scores[1].Total {
+		t.Errorf("spot offer scored %v, on-demand %v, want spot higher", scores[0].Total, scores[1].Total)
+	}
+
+	scores = NewWeightedScorer(config.ScoringConfig{PriceWeight: 1}).Score([]provider.Offer{cheapSpot, onDemand})
+	if scores[0].Total >= scores[1].Total {
+		t.Errorf("without spot, spot offer scored %v, on-demand %v, want on-demand higher", scores[0].Total, scores[1].Total)
+	}
+}
+
+func TestWeightedScorer_Preferences(t *testing.T) {
+	scoring := config.DefaultScoringConfig()
+	scoring.PreferredRegions = []string{"eu"}
+	scoring.ProviderPriority = []string{"lambda"}
+
+	offers := []provider.Offer{
+		scoreTestOffer("vast", "cheap", "US", 1.00),
+		scoreTestOffer("lambda", "preferred", "EU", 1.05),
+	}
+	scores := NewWeightedScorer(scoring).Score(offers)
+	if scores[1].Total <= scores[0].Total {
+		t.Errorf("preferred offer scored %v, cheap offer %v, want preferred higher", scores[1].Total, scores[0].Total)
+	}
+
+	// A large price gap still wins over preferences
+	offers[1].OnDemandPrice = 3.0
+	scores = NewWeightedScorer(scoring).Score(offers)
+	if scores[0].Total <= scores[1].Total {
+		t.Errorf("cheap offer scored %v, expensive preferred offer %v, want cheap higher", scores[0].Total, scores[1].Total)
+	}
+}
+
+func TestSortByScore(t *testing.T) {
+	offers := []rankedOffer{
+		{Offer: scoreTestOffer("vast", "low", "EU", 1), Score: OfferScore{Total: 0.4}},
+		{Offer: scoreTestOffer("vast", "tie-expensive", "EU", 2), Score: OfferScore{Total: 0.8}},
+		{Offer: scoreTestOffer("vast", "tie-cheap", "EU", 1.5), Score: OfferScore{Total: 0.8}},
+	}
+
+	sortByScore(offers, func(o *provider.Offer) float64 { return o.OnDemandPrice })
+
+	want := []string{"tie-cheap", "tie-expensive", "low"}
+	for i, id := range want {
+		if offers[i].Offer.OfferID != id {
+			t.Errorf("offers[%d] = %s, want %s", i, offers[i].Offer.OfferID, id)
+		}
+	}
+}
+
+func TestOfferScore_String(t *testing.T) {
+	score := OfferScore{
+		Total: 0.875,
+		Factors: []ScoreFactor{
+			{Name: FactorPrice, Value: 0.9, Weight: 1},
+			{Name: FactorRegion, Value: 1, Weight: 0},
+			{Name: FactorReliability, Value: 0.8, Weight: 0.25},
+		},
+	}
+
+	if got, want := score.String(), "0.88 (price 0.90, reliability 0.80)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestScoreOffers(t *testing.T) {
+	offers := []provider.Offer{
+		scoreTestOffer("vast", "a", "EU", 2),
+		scoreTestOffer("lambda", "a", "EU", 1),
+	}
+
+	scores := ScoreOffers(NewWeightedScorer(config.ScoringConfig{PriceWeight: 1}), offers)
+	if len(scores) != 2 {
+		t.Fatalf("len(scores) = %d, want 2", len(scores))
+	}
+	if !almostEqual(scores["lambda/a"].Total, 1) || !almostEqual(scores["vast/a"].Total, 0.5) {
+		t.Errorf("scores = %+v", scores)
+	}
+}
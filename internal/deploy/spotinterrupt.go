@@ -11,6 +11,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/tmeurs/spinup/internal/clock"
 	"github.com/tmeurs/spinup/internal/wireguard"
 )
 
@@ -74,6 +75,10 @@ type SpotInterruptMonitorConfig struct {
 
 	// OnInterruption is called when an interruption is detected.
 	OnInterruption func(*SpotInterruption)
+
+	// Clock schedules polls and timestamps interruptions.
+	// Default (nil) is the system clock.
+	Clock clock.Clock
 }
 
 // NewSpotInterruptMonitorConfig creates a config with default values.
@@ -94,6 +99,7 @@ const SpotInterruptEndpointPort = 51822
 // It polls the instance for interruption signals and also monitors connection health.
 type SpotInterruptMonitor struct {
 	config *SpotInterruptMonitorConfig
+	clock  clock.Clock
 
 	mu                  sync.RWMutex
 	running             bool
@@ -136,6 +142,7 @@ func NewSpotInterruptMonitor(config *SpotInterruptMonitorConfig) (*SpotInterrupt
 
 	return &SpotInterruptMonitor{
 		config: config,
+		clock:  clock.OrReal(config.Clock),
 		httpClient: &http.Client{
 			Transport: transport,
 			Timeout:   config.Timeout,
@@ -213,14 +220,14 @@ func (m *SpotInterruptMonitor) monitorLoop(ctx context.Context) {
 		m.mu.Unlock()
 	}()
 
-	ticker := time.NewTicker(m.config.PollInterval)
+	ticker := m.clock.NewTicker(m.config.PollInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			m.checkForInterruption(ctx)
 		}
 	}
@@ -242,7 +249,7 @@ func (m *SpotInterruptMonitor) checkForInterruption(ctx context.Context) {
 			m.interrupted = true
 			m.lastInterruption = &SpotInterruption{
 				Reason:     InterruptionReasonConnectionLost,
-				DetectedAt: time.Now(),
+				DetectedAt: m.clock.Now(),
 				Message:    fmt.Sprintf("Connection to instance lost after %d consecutive failures", m.consecutiveFailures),
 			}
 
@@ -310,7 +317,7 @@ func (m *SpotInterruptMonitor) pollInterruptionStatus(ctx context.Context) (*Spo
 
 	interruption := &SpotInterruption{
 		Reason:     parseInterruptionReason(status.Reason),
-		DetectedAt: time.Now(),
+		DetectedAt: m.clock.Now(),
 		Message:    status.Message,
 	}
 
@@ -518,7 +525,7 @@ func (m *SpotInterruptMonitor) ForceInterruption(reason SpotInterruptionReason,
 	m.interrupted = true
 	m.lastInterruption = &SpotInterruption{
 		Reason:     reason,
-		DetectedAt: time.Now(),
+		DetectedAt: m.clock.Now(),
 		Message:    message,
 	}
 
@@ -9,6 +9,8 @@ import (
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/tmeurs/spinup/internal/clock"
 )
 
 func TestNewSpotInterruptMonitorConfig(t *testing.T) {
@@ -419,3 +421,60 @@ func TestSpotInterruptMonitor_WaitForInterruption(t *testing.T) {
 		t.Error("WaitForInterruption should return after force interruption")
 	}
 }
+
+func TestSpotInterruptMonitor_Clock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	interrupted := make(chan *SpotInterruption, 1)
+	config := &SpotInterruptMonitorConfig{
+		ServerIP:               "127.0.0.1", // Nothing listens, so every poll fails
+		PollInterval:           10 * time.Second,
+		Timeout:                time.Second,
+		MaxConsecutiveFailures: 3,
+		OnInterruption: func(i *SpotInterruption) {
+			interrupted <- i
+		},
+		Clock: fake,
+	}
+
+	monitor, err := NewSpotInterruptMonitor(config)
+	if err != nil {
+		t.Fatalf("NewSpotInterruptMonitor() error = %v", err)
+	}
+	if err := monitor.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer monitor.Stop()
+
+	fake.BlockUntil(1)
+	for poll := 1; poll <= 3; poll++ {
+		fake.Advance(config.PollInterval)
+
+		// Wait for the poll to fail before the next tick
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			monitor.mu.RLock()
+			failures := monitor.consecutiveFailures
+			monitor.mu.RUnlock()
+			if failures >= poll || monitor.IsInterrupted() {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("poll %d didn't happen", poll)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	select {
+	case got := <-interrupted:
+		if got.Reason != InterruptionReasonConnectionLost {
+			t.Errorf("Reason = %v, want %v", got.Reason, InterruptionReasonConnectionLost)
+		}
+		if want := start.Add(30 * time.Second); !got.DetectedAt.Equal(want) {
+			t.Errorf("DetectedAt = %v, want %v", got.DetectedAt, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no interruption after 3 failed polls")
+	}
+}
@@ -0,0 +1,579 @@
+// Package deploy provides deployment orchestration for spinup.
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/alert"
+	"github.com/tmeurs/spinup/internal/clock"
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/logging"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/registry"
+	"github.com/tmeurs/spinup/internal/wireguard"
+)
+
+// SupervisorConfig holds configuration for the background supervisor.
+type SupervisorConfig struct {
+	// CostInterval is how often accumulated cost is written to state and checked
+	// against the daily budget. This is also how often the supervisor notices
+	// that the session has been stopped.
+	CostInterval time.Duration
+
+	// Heartbeat configures the heartbeat client that keeps the deadman alive.
+	// ServerIP is taken from state if it is left empty.
+	Heartbeat *HeartbeatConfig
+
+	// SpotMonitor configures the spot interruption monitor.
+	// The monitor only runs for spot instances.
+	SpotMonitor *SpotInterruptMonitorConfig
+
+	// Watchdog configures the watchdog that terminates the instance through
+	// the provider API once it has been unreachable for too long. Nil or a
+	// zero timeout disables it. InterfaceName is taken from state if it is
+	// left empty.
+	Watchdog *WatchdogConfig
+
+	// Stop configures how the watchdog terminates the instance.
+	// Nil means DefaultStopConfig.
+	Stop *StopConfig
+
+	// DailyBudgetEUR is the daily budget used for budget alerts.
+	// Zero disables budget checking.
+	DailyBudgetEUR float64
+}
+
+// DefaultSupervisorCostInterval is the default interval between cost updates.
+const DefaultSupervisorCostInterval = time.Minute
+
+// DefaultSupervisorConfig returns a SupervisorConfig with sensible defaults.
+func DefaultSupervisorConfig() *SupervisorConfig {
+	return &SupervisorConfig{
+		CostInterval: DefaultSupervisorCostInterval,
+		Heartbeat:    NewHeartbeatConfig(),
+		SpotMonitor:  NewSpotInterruptMonitorConfig(),
+		Watchdog:     NewWatchdogConfig(),
+	}
+}
+
+// Validate validates the supervisor configuration.
+func (c *SupervisorConfig) Validate() error {
+	if c.CostInterval <= 0 {
+		return errors.New("cost interval must be positive")
+	}
+	if c.DailyBudgetEUR < 0 {
+		return errors.New("daily budget cannot be negative")
+	}
+	if c.Watchdog != nil {
+		if err := c.Watchdog.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Stop != nil {
+		if err := c.Stop.Validate(); err != nil {
+			return fmt.Errorf("invalid stop config: %w", err)
+		}
+	}
+	return nil
+}
+
+// SupervisorStatus is a snapshot of the supervisor's loops.
+type SupervisorStatus struct {
+	// Running indicates if the supervisor is active.
+	Running bool
+
+	// InstanceID is the instance being supervised.
+	InstanceID string
+
+	// Heartbeat is the heartbeat client status (nil if not started).
+	Heartbeat *HeartbeatStatus
+
+	// SpotMonitorRunning indicates if the spot interruption monitor is active.
+	SpotMonitorRunning bool
+
+	// Watchdog is the watchdog status (nil if not started).
+	Watchdog *WatchdogStatus
+
+	// LastCostUpdate is when accumulated cost was last written to state.
+	LastCostUpdate time.Time
+
+	// AccumulatedCost is the last accumulated cost written to state.
+	AccumulatedCost float64
+}
+
+// Supervisor owns the long-running loops for an active session: the heartbeat
+// that keeps the deadman switch alive, the termination relay the deadman
+// switch calls, the watchdog that terminates the instance if it goes dark,
+// spot interruption monitoring, and periodic cost accounting with budget
+// alerts. It is intended to run in a
+// process that outlives the CLI invocation that deployed the instance.
+type Supervisor struct {
+	cfg          *config.Config
+	supCfg       *SupervisorConfig
+	stateManager *config.StateManager
+	dispatcher   *alert.Dispatcher
+	budget       *alert.BudgetChecker
+	clock        clock.Clock
+	provider     provider.Provider
+
+	mu              sync.RWMutex
+	running         bool
+	instanceID      string
+	heartbeat       *HeartbeatClient
+	relay           *TerminationRelay
+	watchdog        *Watchdog
+	spotMonitor     *SpotInterruptMonitor
+	lastCostUpdate  time.Time
+	accumulatedCost float64
+}
+
+// SupervisorOption is a functional option for Supervisor.
+type SupervisorOption func(*Supervisor)
+
+// WithSupervisorDispatcher sets the alert dispatcher used by the supervisor.
+func WithSupervisorDispatcher(d *alert.Dispatcher) SupervisorOption {
+	return func(s *Supervisor) {
+		s.dispatcher = d
+	}
+}
+
+// WithSupervisorBudgetChecker sets the budget checker used by the supervisor.
+func WithSupervisorBudgetChecker(bc *alert.BudgetChecker) SupervisorOption {
+	return func(s *Supervisor) {
+		s.budget = bc
+	}
+}
+
+// WithSupervisorClock sets the clock the supervisor's loops run on. It is
+// passed on to the heartbeat client and spot monitor unless their configs set
+// their own.
+func WithSupervisorClock(c clock.Clock) SupervisorOption {
+	return func(s *Supervisor) {
+		s.clock = c
+	}
+}
+
+// WithSupervisorProvider sets the provider the termination relay and the
+// watchdog terminate the instance on. By default it is created from the config.
+func WithSupervisorProvider(p provider.Provider) SupervisorOption {
+	return func(s *Supervisor) {
+		s.provider = p
+	}
+}
+
+// NewSupervisor creates a new Supervisor for the session in the given state manager.
+func NewSupervisor(cfg *config.Config, stateManager *config.StateManager, supCfg *SupervisorConfig, opts ...SupervisorOption) (*Supervisor, error) {
+	if cfg == nil {
+		return nil, errors.New("config is required")
+	}
+	if stateManager == nil {
+		return nil, errors.New("state manager is required")
+	}
+	if supCfg == nil {
+		supCfg = DefaultSupervisorConfig()
+		supCfg.DailyBudgetEUR = cfg.DailyBudgetEUR
+		supCfg.Watchdog.Timeout = cfg.WatchdogTimeout
+	}
+
+	if err := supCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid supervisor config: %w", err)
+	}
+
+	s := &Supervisor{
+		cfg:          cfg,
+		supCfg:       supCfg,
+		stateManager: stateManager,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.dispatcher == nil {
+		s.dispatcher = alert.GetDispatcher()
+	}
+	if s.budget == nil {
+		s.budget = alert.NewBudgetChecker(supCfg.DailyBudgetEUR, alert.WithBudgetDispatcher(s.dispatcher))
+	}
+	s.clock = clock.OrReal(s.clock)
+
+	return s, nil
+}
+
+// Run starts the supervisor loops and blocks until the context is cancelled
+// or the session is removed from state (e.g., by spinup --stop).
+// Returns config.ErrNoActiveInstance if there is no session to supervise.
+func (s *Supervisor) Run(ctx context.Context) error {
+	state, err := s.stateManager.LoadState()
+	if err != nil {
+		return fmt.Errorf("failed to load state: %w", err)
+	}
+	if state == nil || state.Instance == nil {
+		return config.ErrNoActiveInstance
+	}
+
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return errors.New("supervisor is already running")
+	}
+	s.running = true
+	s.instanceID = state.Instance.ID
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	logging.Info().
+		Str("instance_id", state.Instance.ID).
+		Str("provider", state.Instance.Provider).
+		Msg("Supervisor started")
+
+	if s.supCfg.Watchdog != nil && s.supCfg.Watchdog.Timeout > 0 {
+		if err := s.startWatchdog(ctx, state); err != nil {
+			return err
+		}
+		defer s.watchdog.Stop()
+	}
+
+	if err := s.startHeartbeat(ctx, state); err != nil {
+		return err
+	}
+	defer s.heartbeat.Stop()
+
+	if state.Deadman != nil && state.Deadman.Termination == string(TerminationModeRelay) {
+		// Without the relay the deadman switch still shuts the instance down
+		if err := s.startRelay(ctx, state); err != nil {
+			logging.Warn().Err(err).Msg("Termination relay not started")
+			s.dispatcher.Warn(ctx, "Termination relay not started - the deadman switch can only shut the instance down", alertContextFromState(state))
+		} else {
+			defer s.relay.Stop()
+		}
+	}
+
+	if state.Instance.IsSpot() {
+		if err := s.startSpotMonitor(ctx, state); err != nil {
+			return err
+		}
+		defer s.spotMonitor.Stop()
+	}
+
+	// Account for cost immediately so status reflects the supervisor at once
+	if !s.updateCost(ctx) {
+		return nil
+	}
+
+	ticker := s.clock.NewTicker(s.supCfg.CostInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logging.Info().Str("instance_id", state.Instance.ID).Msg("Supervisor stopped")
+			return nil
+		case <-ticker.C():
+			if !s.updateCost(ctx) {
+				logging.Info().Str("instance_id", state.Instance.ID).Msg("Session ended, supervisor exiting")
+				return nil
+			}
+		}
+	}
+}
+
+// Status returns a snapshot of the supervisor's state.
+func (s *Supervisor) Status() *SupervisorStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := &SupervisorStatus{
+		Running:         s.running,
+		InstanceID:      s.instanceID,
+		LastCostUpdate:  s.lastCostUpdate,
+		AccumulatedCost: s.accumulatedCost,
+	}
+	if s.heartbeat != nil {
+		status.Heartbeat = s.heartbeat.Status()
+	}
+	if s.spotMonitor != nil {
+		status.SpotMonitorRunning = s.spotMonitor.IsRunning()
+	}
+	if s.watchdog != nil {
+		status.Watchdog = s.watchdog.Status()
+	}
+	return status
+}
+
+// startHeartbeat creates and starts the heartbeat client for the session.
+func (s *Supervisor) startHeartbeat(ctx context.Context, state *config.State) error {
+	hbCfg := NewHeartbeatConfig()
+	if s.supCfg.Heartbeat != nil {
+		copied := *s.supCfg.Heartbeat
+		hbCfg = &copied
+	}
+	if hbCfg.Clock == nil {
+		hbCfg.Clock = s.clock
+	}
+	if hbCfg.ServerIP == "" || hbCfg.ServerIP == wireguard.ServerIP {
+		if state.Instance.WireGuardIP != "" {
+			hbCfg.ServerIP = state.Instance.WireGuardIP
+		}
+	}
+
+	alertCtx := alertContextFromState(state)
+	userOnHeartbeat := hbCfg.OnHeartbeat
+	hbCfg.OnHeartbeat = func(at time.Time) {
+		if err := s.stateManager.UpdateHeartbeat(); err != nil && !errors.Is(err, config.ErrStateLocked) {
+			logging.Warn().Err(err).Msg("Failed to record heartbeat in state")
+		}
+		s.mu.RLock()
+		watchdog := s.watchdog
+		s.mu.RUnlock()
+		if watchdog != nil {
+			watchdog.RecordContact(at)
+		}
+		if userOnHeartbeat != nil {
+			userOnHeartbeat(at)
+		}
+	}
+	userOnFailure := hbCfg.OnFailure
+	hbCfg.OnFailure = func(err error, consecutiveFailures int) {
+		if consecutiveFailures == hbCfg.MaxConsecutiveFailures {
+			failCtx := alertCtx
+			failCtx.Error = err.Error()
+			s.dispatcher.Error(ctx, fmt.Sprintf("Heartbeat failing (%d consecutive failures) - deadman switch is not being refreshed", consecutiveFailures), failCtx)
+		}
+		if userOnFailure != nil {
+			userOnFailure(err, consecutiveFailures)
+		}
+	}
+
+	hc, err := NewHeartbeatClient(hbCfg)
+	if err != nil {
+		return fmt.Errorf("failed to create heartbeat client: %w", err)
+	}
+
+	s.mu.Lock()
+	s.heartbeat = hc
+	s.mu.Unlock()
+
+	if err := hc.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start heartbeat: %w", err)
+	}
+	return nil
+}
+
+// startWatchdog creates and starts the watchdog for the session.
+func (s *Supervisor) startWatchdog(ctx context.Context, state *config.State) error {
+	wdCfg := *s.supCfg.Watchdog
+	if wdCfg.Clock == nil {
+		wdCfg.Clock = s.clock
+	}
+	if wdCfg.InterfaceName == "" || wdCfg.InterfaceName == wireguard.InterfaceName {
+		if state.WireGuard != nil && state.WireGuard.InterfaceName != "" {
+			wdCfg.InterfaceName = state.WireGuard.InterfaceName
+		}
+	}
+	userOnUnreachable := wdCfg.OnUnreachable
+	wdCfg.OnUnreachable = func(ctx context.Context, silent time.Duration) error {
+		err := s.terminateUnreachable(ctx, state, silent)
+		if userOnUnreachable != nil {
+			if userErr := userOnUnreachable(ctx, silent); err == nil {
+				err = userErr
+			}
+		}
+		return err
+	}
+
+	watchdog, err := NewWatchdog(&wdCfg)
+	if err != nil {
+		return fmt.Errorf("failed to create watchdog: %w", err)
+	}
+
+	s.mu.Lock()
+	s.watchdog = watchdog
+	s.mu.Unlock()
+
+	if err := watchdog.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start watchdog: %w", err)
+	}
+	return nil
+}
+
+// terminateUnreachable terminates an instance that has gone dark through the
+// provider API and verifies its billing stopped, alerting at critical level
+// if either fails. Stopping clears the session, which ends the supervisor.
+func (s *Supervisor) terminateUnreachable(ctx context.Context, state *config.State, silent time.Duration) error {
+	alertCtx := alertContextFromState(state)
+	s.dispatcher.Error(ctx, fmt.Sprintf("Instance unreachable for %s - terminating it through the provider API", silent.Round(time.Minute)), alertCtx)
+
+	stopper, err := NewStopper(s.cfg, s.supCfg.Stop,
+		WithStopStateManager(s.stateManager),
+		WithStopProvider(s.provider),
+		WithStopClock(s.clock),
+	)
+	if err != nil {
+		return err
+	}
+
+	result, err := stopper.Stop(ctx)
+	switch {
+	case errors.Is(err, ErrNoActiveInstance):
+		return nil
+	case errors.Is(err, ErrBillingNotVerified):
+		failCtx := alertCtx
+		failCtx.Error = err.Error()
+		s.dispatcher.Critical(ctx, "Watchdog terminated the unreachable instance but could not verify billing stopped - check the provider console", failCtx)
+		return nil
+	case err != nil:
+		failCtx := alertCtx
+		failCtx.Error = err.Error()
+		s.dispatcher.Critical(ctx, "Watchdog failed to terminate the unreachable instance - terminate it manually", failCtx)
+		return err
+	}
+
+	if result.ManualVerificationRequired {
+		s.dispatcher.Warn(ctx, fmt.Sprintf("Watchdog terminated the unreachable instance - verify billing stopped at %s", result.ConsoleURL), alertCtx)
+	} else {
+		s.dispatcher.Warn(ctx, "Watchdog terminated the unreachable instance, billing stopped", alertCtx)
+	}
+	return nil
+}
+
+// startRelay creates and starts the termination relay for the session.
+func (s *Supervisor) startRelay(ctx context.Context, state *config.State) error {
+	p := s.provider
+	if p == nil {
+		var err error
+		p, err = registry.GetProviderByName(state.Instance.Provider, s.cfg)
+		if err != nil {
+			return fmt.Errorf("failed to get provider: %w", err)
+		}
+	}
+
+	relay, err := NewTerminationRelay(p, state.Instance.ID, state.Deadman.RelayTokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to create termination relay: %w", err)
+	}
+	alertCtx := alertContextFromState(state)
+	relay.OnTerminate = func(instanceID string) {
+		s.dispatcher.Critical(ctx, fmt.Sprintf("Deadman switch fired: instance %s terminated through the relay", instanceID), alertCtx)
+	}
+
+	if err := relay.Start(state.Deadman.RelayAddr); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.relay = relay
+	s.mu.Unlock()
+	return nil
+}
+
+// startSpotMonitor creates and starts the spot interruption monitor for the session.
+func (s *Supervisor) startSpotMonitor(ctx context.Context, state *config.State) error {
+	monCfg := NewSpotInterruptMonitorConfig()
+	if s.supCfg.SpotMonitor != nil {
+		copied := *s.supCfg.SpotMonitor
+		monCfg = &copied
+	}
+	if monCfg.Clock == nil {
+		monCfg.Clock = s.clock
+	}
+	if monCfg.ServerIP == "" || monCfg.ServerIP == wireguard.ServerIP {
+		if state.Instance.WireGuardIP != "" {
+			monCfg.ServerIP = state.Instance.WireGuardIP
+		}
+	}
+
+	alertCtx := alertContextFromState(state)
+	userOnInterruption := monCfg.OnInterruption
+	monCfg.OnInterruption = func(interruption *SpotInterruption) {
+		interruption.Provider = state.Instance.Provider
+		interruption.InstanceID = state.Instance.ID
+		s.dispatcher.Warn(ctx, fmt.Sprintf("Spot interruption detected: %s", interruption.Reason), alertCtx)
+		if userOnInterruption != nil {
+			userOnInterruption(interruption)
+		}
+	}
+
+	monitor, err := NewSpotInterruptMonitor(monCfg)
+	if err != nil {
+		return fmt.Errorf("failed to create spot interrupt monitor: %w", err)
+	}
+
+	s.mu.Lock()
+	s.spotMonitor = monitor
+	s.mu.Unlock()
+
+	if err := monitor.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start spot interrupt monitor: %w", err)
+	}
+	return nil
+}
+
+// updateCost writes the accumulated cost to state and checks it against the budget.
+// Returns false if the session no longer exists and the supervisor should exit.
+func (s *Supervisor) updateCost(ctx context.Context) bool {
+	state, err := s.stateManager.LoadState()
+	if err != nil {
+		// Locked or unreadable state is transient - try again next tick
+		logging.Debug().Err(err).Msg("Supervisor could not load state")
+		return true
+	}
+
+	s.mu.RLock()
+	instanceID := s.instanceID
+	s.mu.RUnlock()
+
+	if state == nil || state.Instance == nil || state.Instance.ID != instanceID {
+		return false
+	}
+	if state.Cost == nil {
+		return true
+	}
+
+	now := s.clock.Now()
+	accumulated := state.AccumulatedCostAt(now)
+	if err := s.stateManager.UpdateCost(accumulated); err != nil {
+		if errors.Is(err, config.ErrNoActiveInstance) {
+			return false
+		}
+		logging.Debug().Err(err).Msg("Supervisor could not update cost")
+	}
+
+	s.mu.Lock()
+	s.lastCostUpdate = now
+	s.accumulatedCost = accumulated
+	s.mu.Unlock()
+
+	s.budget.CheckAndAlert(ctx, accumulated, alertContextFromState(state))
+	return true
+}
+
+// alertContextFromState builds an alert context for the session in state.
+func alertContextFromState(state *config.State) alert.Context {
+	alertCtx := alert.Context{}
+	if state == nil {
+		return alertCtx
+	}
+	if state.Instance != nil {
+		alertCtx.InstanceID = state.Instance.ID
+		alertCtx.Provider = state.Instance.Provider
+		alertCtx.GPU = state.Instance.GPU
+		alertCtx.Region = state.Instance.Region
+	}
+	if state.Model != nil {
+		alertCtx.Model = state.Model.Name
+	}
+	return alertCtx
+}
@@ -0,0 +1,363 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/alert"
+	"github.com/tmeurs/spinup/internal/clock"
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/provider/mock"
+)
+
+func newSupervisorTestState(t *testing.T, instanceType string) *config.StateManager {
+	t.Helper()
+
+	sm, err := config.NewStateManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStateManager() error = %v", err)
+	}
+
+	state := config.NewState(
+		&config.InstanceState{
+			ID:          "sup-123",
+			Provider:    "vast",
+			GPU:         "A100 40GB",
+			Region:      "EU-West",
+			Type:        instanceType,
+			WireGuardIP: "127.0.0.1",
+			CreatedAt:   time.Now().Add(-2 * time.Hour),
+		},
+		&config.ModelState{Name: "qwen2.5-coder:7b", Status: "ready"},
+		nil,
+		&config.CostState{HourlyRate: 1.0, Currency: "EUR"},
+		&config.DeadmanState{TimeoutHours: 10, LastHeartbeat: time.Now().Add(-time.Hour)},
+	)
+	if err := sm.SaveState(state); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+	return sm
+}
+
+func newTestSupervisorConfig() *SupervisorConfig {
+	supCfg := DefaultSupervisorConfig()
+	supCfg.CostInterval = 20 * time.Millisecond
+	supCfg.Heartbeat.ServerIP = "127.0.0.1"
+	supCfg.Heartbeat.Timeout = 100 * time.Millisecond
+	supCfg.SpotMonitor.ServerIP = "127.0.0.1"
+	supCfg.SpotMonitor.Timeout = time.Second
+	supCfg.Watchdog.Handshake = noHandshake
+	return supCfg
+}
+
+func TestSupervisorConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *SupervisorConfig
+		wantErr bool
+	}{
+		{"default", DefaultSupervisorConfig(), false},
+		{"zero cost interval", &SupervisorConfig{CostInterval: 0}, true},
+		{"negative budget", &SupervisorConfig{CostInterval: time.Minute, DailyBudgetEUR: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewSupervisor_RequiresDependencies(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+
+	if _, err := NewSupervisor(nil, sm, nil); err == nil {
+		t.Error("expected error for nil config")
+	}
+	if _, err := NewSupervisor(&config.Config{}, nil, nil); err == nil {
+		t.Error("expected error for nil state manager")
+	}
+	if _, err := NewSupervisor(&config.Config{}, sm, &SupervisorConfig{}); err == nil {
+		t.Error("expected error for invalid supervisor config")
+	}
+}
+
+func TestSupervisor_Run_NoState(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+	sup, err := NewSupervisor(&config.Config{}, sm, newTestSupervisorConfig())
+	if err != nil {
+		t.Fatalf("NewSupervisor() error = %v", err)
+	}
+
+	err = sup.Run(context.Background())
+	if !errors.Is(err, config.ErrNoActiveInstance) {
+		t.Errorf("Run() error = %v, want ErrNoActiveInstance", err)
+	}
+}
+
+func TestSupervisor_Run_UpdatesCostAndExitsWhenStateCleared(t *testing.T) {
+	sm := newSupervisorTestState(t, "on-demand")
+	sup, err := NewSupervisor(&config.Config{}, sm, newTestSupervisorConfig(),
+		WithSupervisorDispatcher(alert.NewDispatcher()),
+	)
+	if err != nil {
+		t.Fatalf("NewSupervisor() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- sup.Run(ctx)
+	}()
+
+	// Wait for the first cost update to land in state
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		state, err := sm.LoadState()
+		if err == nil && state != nil && state.Cost.Accumulated > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	state, err := sm.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if state.Cost.Accumulated < 1.9 {
+		t.Errorf("accumulated cost = %.2f, want ~2.00", state.Cost.Accumulated)
+	}
+
+	status := sup.Status()
+	if !status.Running {
+		t.Error("expected supervisor to be running")
+	}
+	if status.InstanceID != "sup-123" {
+		t.Errorf("InstanceID = %q, want sup-123", status.InstanceID)
+	}
+	if status.SpotMonitorRunning {
+		t.Error("spot monitor should not run for on-demand instances")
+	}
+
+	// Simulate spinup --stop clearing the state
+	if err := sm.ClearState(); err != nil {
+		t.Fatalf("ClearState() error = %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("supervisor did not exit after state was cleared")
+	}
+
+	if sup.Status().Running {
+		t.Error("expected supervisor to report not running after exit")
+	}
+}
+
+func TestSupervisor_Run_StopsOnContextCancel(t *testing.T) {
+	sm := newSupervisorTestState(t, "spot")
+	sup, err := NewSupervisor(&config.Config{}, sm, newTestSupervisorConfig(),
+		WithSupervisorDispatcher(alert.NewDispatcher()),
+	)
+	if err != nil {
+		t.Fatalf("NewSupervisor() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- sup.Run(ctx)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if !sup.Status().SpotMonitorRunning {
+		t.Error("expected spot monitor to run for spot instances")
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("supervisor did not stop after context cancel")
+	}
+
+	// State must survive a supervisor shutdown
+	state, err := sm.LoadState()
+	if err != nil || state == nil || state.Instance == nil {
+		t.Errorf("state should be kept after supervisor stop, got %v, %v", state, err)
+	}
+}
+
+func TestSupervisor_Run_ServesTerminationRelay(t *testing.T) {
+	sm := newSupervisorTestState(t, "on-demand")
+
+	// Reserve a free local port for the relay
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	relayAddr := ln.Addr().String()
+	ln.Close()
+
+	state, _ := sm.LoadState()
+	state.Deadman.Termination = string(TerminationModeRelay)
+	state.Deadman.RelayAddr = relayAddr
+	state.Deadman.RelayTokenHash = hashRelayToken("relay-token")
+	if err := sm.SaveState(state); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+
+	p := mock.New(mock.WithName("vast"))
+	sup, err := NewSupervisor(&config.Config{}, sm, newTestSupervisorConfig(),
+		WithSupervisorDispatcher(alert.NewDispatcher()),
+		WithSupervisorProvider(p),
+	)
+	if err != nil {
+		t.Fatalf("NewSupervisor() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- sup.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	var resp *http.Response
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		req, _ := http.NewRequest(http.MethodPost, "http://"+relayAddr+"/terminate", nil)
+		req.Header.Set("Authorization", "Bearer relay-token")
+		resp, err = http.DefaultClient.Do(req)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("relay request error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("relay status = %d, want 204", resp.StatusCode)
+	}
+	if len(p.TerminateInstanceCalls) != 1 || p.TerminateInstanceCalls[0].ID != "sup-123" {
+		t.Errorf("TerminateInstanceCalls = %+v, want sup-123", p.TerminateInstanceCalls)
+	}
+}
+
+func TestSupervisor_BudgetAlert(t *testing.T) {
+	sm := newSupervisorTestState(t, "on-demand")
+	dispatcher := alert.NewDispatcher()
+	budget := alert.NewBudgetChecker(1.0, alert.WithBudgetDispatcher(dispatcher))
+
+	sup, err := NewSupervisor(&config.Config{}, sm, newTestSupervisorConfig(),
+		WithSupervisorDispatcher(dispatcher),
+		WithSupervisorBudgetChecker(budget),
+	)
+	if err != nil {
+		t.Fatalf("NewSupervisor() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_ = sup.Run(ctx)
+
+	if !budget.HasAlerted100() {
+		t.Error("expected budget exceeded alert for €2.00 spent against €1.00 budget")
+	}
+}
+
+func TestSupervisor_FullDayBudget(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	sm, err := config.NewStateManager(t.TempDir(), config.WithStateClock(fake))
+	if err != nil {
+		t.Fatalf("NewStateManager() error = %v", err)
+	}
+	state := config.NewState(
+		&config.InstanceState{ID: "sup-day", Provider: "vast", Type: "on-demand", WireGuardIP: "127.0.0.1", CreatedAt: fake.Now()},
+		nil,
+		nil,
+		&config.CostState{HourlyRate: 1.0, Currency: "EUR"},
+		&config.DeadmanState{TimeoutHours: 10, LastHeartbeat: fake.Now()},
+	)
+	if err := sm.SaveState(state); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+
+	dispatcher := alert.NewDispatcher()
+	budget := alert.NewBudgetChecker(20.0, alert.WithBudgetDispatcher(dispatcher))
+	supCfg := newTestSupervisorConfig()
+	supCfg.CostInterval = time.Hour
+	sup, err := NewSupervisor(&config.Config{}, sm, supCfg,
+		WithSupervisorDispatcher(dispatcher),
+		WithSupervisorBudgetChecker(budget),
+		WithSupervisorClock(fake),
+	)
+	if err != nil {
+		t.Fatalf("NewSupervisor() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- sup.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// The heartbeat and cost tickers
+	fake.BlockUntil(2)
+	for hour := 1; hour <= 24; hour++ {
+		fake.Advance(time.Hour)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for !sup.Status().LastCostUpdate.Equal(fake.Now()) {
+			if time.Now().After(deadline) {
+				t.Fatalf("hour %d: cost wasn't updated", hour)
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		if got, want := budget.HasAlerted80(), hour >= 16; got != want {
+			t.Errorf("hour %d: HasAlerted80() = %v, want %v", hour, got, want)
+		}
+		if got, want := budget.HasAlerted100(), hour >= 20; got != want {
+			t.Errorf("hour %d: HasAlerted100() = %v, want %v", hour, got, want)
+		}
+	}
+
+	if got := sup.Status().AccumulatedCost; got != 24.0 {
+		t.Errorf("AccumulatedCost = %.2f after a day, want 24.00", got)
+	}
+	saved, err := sm.LoadState()
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if saved.Cost.Accumulated != 24.0 {
+		t.Errorf("saved accumulated cost = %.2f, want 24.00", saved.Cost.Accumulated)
+	}
+}
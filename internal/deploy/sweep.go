@@ -0,0 +1,279 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/logging"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/registry"
+)
+
+// DefaultSweepTimeout is the default per-provider timeout for listing instances.
+const DefaultSweepTimeout = 30 * time.Second
+
+// OrphanedInstance is a spinup instance at a provider that no local session
+// or deploy journal owns.
+type OrphanedInstance struct {
+	// Instance is the instance as reported by the provider.
+	Instance provider.Instance
+
+	// Client is the provider the instance runs on.
+	Client provider.Provider
+
+	// Running is how long the instance has been up. Zero if the provider
+	// doesn't report a creation time.
+	Running time.Duration
+
+	// AccruedCost is the estimated cost so far in EUR, based on Running.
+	AccruedCost float64
+}
+
+// SweepResult holds the outcome of a search for orphaned instances.
+type SweepResult struct {
+	// Orphans are the unowned spinup instances, sorted by provider and ID.
+	Orphans []OrphanedInstance
+
+	// ProvidersChecked is the number of providers whose instances were listed.
+	ProvidersChecked int
+
+	// ProviderErrors maps provider names to listing errors.
+	// Orphans at these providers may have been missed.
+	ProviderErrors map[string]error
+}
+
+// HourlyCost returns the combined hourly rate of all orphans in EUR.
+func (r *SweepResult) HourlyCost() float64 {
+	var total float64
+	for _, o := range r.Orphans {
+		total += o.Instance.HourlyRate
+	}
+	return total
+}
+
+// Sweeper finds spinup instances that were left running without local state,
+// e.g. by a crashed run, another machine, or a deleted state file.
+type Sweeper struct {
+	cfg          *config.Config
+	stateManager *config.StateManager
+	providers    []provider.Provider
+	timeout      time.Duration
+}
+
+// SweeperOption is a functional option for Sweeper.
+type SweeperOption func(*Sweeper)
+
+// WithSweepProviders sets the providers to sweep instead of all configured providers.
+func WithSweepProviders(providers ...provider.Provider) SweeperOption {
+	return func(s *Sweeper) {
+		s.providers = providers
+	}
+}
+
+// WithSweepTimeout sets the per-provider timeout for listing instances.
+func WithSweepTimeout(d time.Duration) SweeperOption {
+	return func(s *Sweeper) {
+		s.timeout = d
+	}
+}
+
+// NewSweeper creates a new Sweeper.
+func NewSweeper(cfg *config.Config, stateManager *config.StateManager, opts ...SweeperOption) (*Sweeper, error) {
+	if cfg == nil {
+		return nil, errors.New("config is required")
+	}
+	if stateManager == nil {
+		return nil, errors.New("state manager is required")
+	}
+
+	s := &Sweeper{
+		cfg:          cfg,
+		stateManager: stateManager,
+		timeout:      DefaultSweepTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
+}
+
+// FindOrphans lists instances at every provider and returns the spinup
+// instances that are neither in a session's state nor in a deploy journal.
+// A provider that fails to list is recorded in ProviderErrors and skipped.
+func (s *Sweeper) FindOrphans(ctx context.Context) (*SweepResult, error) {
+	owned, err := s.ownedInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	providers := s.providers
+	if providers == nil {
+		providers, err = registry.GetConfiguredProviders(s.cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get providers: %w", err)
+		}
+	}
+
+	result := &SweepResult{
+		ProviderErrors: make(map[string]error),
+	}
+
+	type listing struct {
+		p         provider.Provider
+		instances []provider.Instance
+		err       error
+	}
+	listings := make([]listing, len(providers))
+
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p provider.Provider) {
+			defer wg.Done()
+			listCtx, cancel := context.WithTimeout(ctx, s.timeout)
+			defer cancel()
+			instances, err := p.ListInstances(listCtx)
+			listings[i] = listing{p: p, instances: instances, err: err}
+		}(i, p)
+	}
+	wg.Wait()
+
+	now := time.Now()
+	for _, l := range listings {
+		if l.err != nil {
+			logging.Warn().Err(l.err).Str("provider", l.p.Name()).Msg("Failed to list instances")
+			result.ProviderErrors[l.p.Name()] = l.err
+			continue
+		}
+		result.ProvidersChecked++
+
+		for _, instance := range l.instances {
+			if !instance.IsSpinupInstance() || instance.Status.IsTerminal() {
+				continue
+			}
+			if owned[ownedKey(l.p.Name(), instance.ID)] {
+				continue
+			}
+
+			orphan := OrphanedInstance{
+				Instance: instance,
+				Client:   l.p,
+			}
+			if !instance.CreatedAt.IsZero() && instance.CreatedAt.Before(now) {
+				orphan.Running = now.Sub(instance.CreatedAt)
+				orphan.AccruedCost = orphan.Running.Hours() * instance.HourlyRate
+			}
+			result.Orphans = append(result.Orphans, orphan)
+		}
+	}
+
+	sort.Slice(result.Orphans, func(i, j int) bool {
+		a, b := result.Orphans[i], result.Orphans[j]
+		if a.Client.Name() != b.Client.Name() {
+			return a.Client.Name() < b.Client.Name()
+		}
+		return a.Instance.ID < b.Instance.ID
+	})
+
+	return result, nil
+}
+
+// ownedInstances returns the instances tracked by any session or deploy journal,
+// keyed by ownedKey.
+func (s *Sweeper) ownedInstances() (map[string]bool, error) {
+	owned := make(map[string]bool)
+
+	sessions, err := s.stateManager.ListSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sessions: %w", err)
+	}
+	for _, state := range sessions {
+		if state != nil && state.Instance != nil {
+			owned[ownedKey(state.Instance.Provider, state.Instance.ID)] = true
+		}
+	}
+
+	// Unfinished deployments are handled by 'spinup cleanup', not by the sweeper
+	journals, err := ListDeployJournals(s.stateManager)
+	if err != nil {
+		return nil, err
+	}
+	for _, j := range journals {
+		if j.HasInstance() {
+			owned[ownedKey(j.Provider, j.InstanceID)] = true
+		}
+	}
+
+	return owned, nil
+}
+
+// ownedKey identifies an instance across providers.
+func ownedKey(providerName, instanceID string) string {
+	return providerName + "/" + instanceID
+}
+
+// TerminateOrphan terminates an orphaned instance with the same retries and
+// billing verification as Stop. No local state is touched.
+func (s *Stopper) TerminateOrphan(ctx context.Context, orphan OrphanedInstance) (*StopResult, error) {
+	if orphan.Client == nil {
+		return nil, errors.New("orphan has no provider")
+	}
+
+	result := &StopResult{
+		StartedAt:       time.Now(),
+		InstanceID:      orphan.Instance.ID,
+		Provider:        orphan.Client.Name(),
+		SessionCost:     orphan.AccruedCost,
+		SessionDuration: orphan.Running,
+	}
+
+	if err := s.terminateAndVerify(ctx, orphan.Client, orphan.Instance.ID, result); err != nil {
+		return nil, err
+	}
+	result.CompletedAt = time.Now()
+
+	if !result.BillingVerified && !result.ManualVerificationRequired {
+		return result, ErrBillingNotVerified
+	}
+	return result, nil
+}
+
+// terminateAndVerify runs the terminate and billing verification steps of Stop
+// for an instance, filling in result. It only fails if termination fails;
+// billing verification problems are recorded in result.
+func (s *Stopper) terminateAndVerify(ctx context.Context, p provider.Provider, instanceID string, result *StopResult) error {
+	// Step 1: Terminate instance with retry
+	s.reportStopProgress(StopStepTerminate, "Terminating instance...", "", false, false)
+	if err := s.terminateWithRetry(ctx, p, instanceID, result); err != nil {
+		s.reportStopProgress(StopStepTerminate, "Failed to terminate instance", err.Error(), false, false)
+		return fmt.Errorf("step 1 failed: %w", err)
+	}
+	s.reportStopProgress(StopStepTerminate, "Instance terminated", "", true, false)
+
+	// Step 2: Verify billing stopped
+	s.reportStopProgress(StopStepVerifyBilling, "Verifying billing stopped...", "", false, false)
+	if err := s.verifyBillingWithRetry(ctx, p, instanceID, result); err != nil {
+		if !p.SupportsBillingVerification() {
+			result.ManualVerificationRequired = true
+			result.ConsoleURL = p.ConsoleURL()
+			s.reportStopProgress(StopStepVerifyBilling, "Billing verification not available", "Manual verification required", true, true)
+			if s.manualVerifyCb != nil {
+				s.manualVerifyCb(NewManualVerification(p.Name(), instanceID, p.ConsoleURL()))
+			}
+		} else {
+			s.reportStopProgress(StopStepVerifyBilling, "Could not verify billing stopped", err.Error(), false, true)
+		}
+		return nil
+	}
+
+	result.BillingVerified = true
+	s.reportStopProgress(StopStepVerifyBilling, "Billing confirmed stopped", "", true, false)
+	return nil
+}
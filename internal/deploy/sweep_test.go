@@ -0,0 +1,138 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/mock"
+)
+
+func TestNewSweeper_Validation(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+
+	if _, err := NewSweeper(nil, sm); err == nil {
+		t.Error("NewSweeper(nil config) error = nil, want error")
+	}
+	if _, err := NewSweeper(&config.Config{}, nil); err == nil {
+		t.Error("NewSweeper(nil state manager) error = nil, want error")
+	}
+}
+
+func TestSweeper_FindOrphans(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm, _ := config.NewStateManager(tmpDir)
+	chat, _ := config.NewStateManager(tmpDir, config.WithSession("chat"))
+
+	vast := mock.New(mock.WithName("vast"))
+	runpod := mock.New(mock.WithName("runpod"))
+	created := time.Now().Add(-2 * time.Hour)
+
+	vast.AddInstance(&provider.Instance{ID: "owned", Name: "spinup", Status: provider.InstanceStatusRunning, HourlyRate: 1.0, CreatedAt: created})
+	vast.AddInstance(&provider.Instance{ID: "orphan", Name: "spinup", Status: provider.InstanceStatusRunning, HourlyRate: 0.5, CreatedAt: created})
+	vast.AddInstance(&provider.Instance{ID: "journaled", Name: "spinup", Status: provider.InstanceStatusCreating})
+	vast.AddInstance(&provider.Instance{ID: "dead", Name: "spinup", Status: provider.InstanceStatusTerminated})
+	vast.AddInstance(&provider.Instance{ID: "notebook", Name: "my-notebook", Status: provider.InstanceStatusRunning})
+	runpod.AddInstance(&provider.Instance{ID: "owned", Name: "spinup-1700000000", Status: provider.InstanceStatusRunning, HourlyRate: 0.8})
+
+	// "owned" at vast belongs to the chat session, not to runpod's "owned"
+	if err := chat.SaveState(&config.State{Instance: &config.InstanceState{ID: "owned", Provider: "vast"}}); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+
+	deployCfg := DefaultDeployConfig()
+	deployCfg.Model = "qwen2.5-coder:7b"
+	journal := NewDeployJournal(sm, deployCfg)
+	journal.Provider = "vast"
+	journal.InstanceID = "journaled"
+	if err := journal.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	sweeper, err := NewSweeper(&config.Config{}, sm, WithSweepProviders(vast, runpod))
+	if err != nil {
+		t.Fatalf("NewSweeper() error = %v", err)
+	}
+
+	result, err := sweeper.FindOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("FindOrphans() error = %v", err)
+	}
+
+	if result.ProvidersChecked != 2 {
+		t.Errorf("ProvidersChecked = %d, want 2", result.ProvidersChecked)
+	}
+	if len(result.Orphans) != 2 {
+		t.Fatalf("len(Orphans) = %d, want 2: %+v", len(result.Orphans), result.Orphans)
+	}
+
+	// Sorted by provider name, then ID
+	if result.Orphans[0].Client.Name() != "runpod" || result.Orphans[0].Instance.ID != "owned" {
+		t.Errorf("Orphans[0] = %s/%s, want runpod/owned", result.Orphans[0].Client.Name(), result.Orphans[0].Instance.ID)
+	}
+	if result.Orphans[1].Client.Name() != "vast" || result.Orphans[1].Instance.ID != "orphan" {
+		t.Errorf("Orphans[1] = %s/%s, want vast/orphan", result.Orphans[1].Client.Name(), result.Orphans[1].Instance.ID)
+	}
+
+	// Cost is only known when the creation time is
+	if result.Orphans[0].AccruedCost != 0 || result.Orphans[0].Running != 0 {
+		t.Errorf("orphan without CreatedAt has cost %.2f, running %v", result.Orphans[0].AccruedCost, result.Orphans[0].Running)
+	}
+	if cost := result.Orphans[1].AccruedCost; cost < 0.99 || cost > 1.01 {
+		t.Errorf("AccruedCost = %.2f, want ~1.00", cost)
+	}
+	if hourly := result.HourlyCost(); hourly < 1.29 || hourly > 1.31 {
+		t.Errorf("HourlyCost() = %.2f, want 1.30", hourly)
+	}
+}
+
+func TestSweeper_FindOrphans_ProviderError(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+
+	failing := mock.New(mock.WithName("lambda"), mock.WithListInstancesError(provider.ErrRateLimited))
+	healthy := mock.New(mock.WithName("vast"))
+	healthy.AddInstance(&provider.Instance{ID: "orphan", Name: "spinup", Status: provider.InstanceStatusRunning})
+
+	sweeper, _ := NewSweeper(&config.Config{}, sm, WithSweepProviders(failing, healthy))
+	result, err := sweeper.FindOrphans(context.Background())
+	if err != nil {
+		t.Fatalf("FindOrphans() error = %v", err)
+	}
+
+	if result.ProvidersChecked != 1 {
+		t.Errorf("ProvidersChecked = %d, want 1", result.ProvidersChecked)
+	}
+	if !errors.Is(result.ProviderErrors["lambda"], provider.ErrRateLimited) {
+		t.Errorf("ProviderErrors[lambda] = %v, want ErrRateLimited", result.ProviderErrors["lambda"])
+	}
+	if len(result.Orphans) != 1 {
+		t.Errorf("len(Orphans) = %d, want 1", len(result.Orphans))
+	}
+}
+
+func TestStopper_TerminateOrphan(t *testing.T) {
+	p := mock.New(mock.WithName("vast"))
+	p.AddInstance(&provider.Instance{ID: "orphan", Name: "spinup", Status: provider.InstanceStatusRunning})
+
+	stopper, _ := NewStopper(&config.Config{}, nil)
+	result, err := stopper.TerminateOrphan(context.Background(), OrphanedInstance{
+		Instance: provider.Instance{ID: "orphan"},
+		Client:   p,
+	})
+	if err != nil {
+		t.Fatalf("TerminateOrphan() error = %v", err)
+	}
+	if !result.BillingVerified {
+		t.Error("BillingVerified = false, want true")
+	}
+	if len(p.TerminateInstanceCalls) != 1 || p.TerminateInstanceCalls[0].ID != "orphan" {
+		t.Errorf("TerminateInstanceCalls = %+v", p.TerminateInstanceCalls)
+	}
+
+	if _, err := stopper.TerminateOrphan(context.Background(), OrphanedInstance{}); err == nil {
+		t.Error("TerminateOrphan() without provider error = nil, want error")
+	}
+}
@@ -0,0 +1,328 @@
+// Package deploy provides deployment orchestration for spinup.
+package deploy
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/logging"
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+// TerminationMode selects how an instance's deadman switch terminates it,
+// and so which credential the instance holds.
+type TerminationMode string
+
+const (
+	// TerminationModeScoped has the instance call the provider's API with a
+	// restricted API key issued for it, which can manage instances but not
+	// the account, and is revoked when the instance is stopped. Providers
+	// that can't issue one fall back to TerminationModeRelay.
+	TerminationModeScoped TerminationMode = "scoped"
+
+	// TerminationModeRelay has the instance ask the termination relay in the
+	// supervisor to terminate it, over the tunnel, with a one-time token.
+	// The relay terminates the instance with the local API key. If the relay
+	// is unreachable the instance only shuts down - and the deadman switch
+	// usually fires because this machine is unreachable.
+	TerminationModeRelay TerminationMode = "relay"
+
+	// TerminationModeAPIKey has the instance call the provider's API with
+	// the full, account-wide API key. It must be opted in to explicitly.
+	TerminationModeAPIKey TerminationMode = "api-key"
+
+	// TerminationModeShutdown gives the instance no credential; it only
+	// shuts itself down, which may not stop all billing.
+	TerminationModeShutdown TerminationMode = "shutdown"
+)
+
+// DefaultTerminationMode is the termination mode used when none is configured.
+const DefaultTerminationMode = TerminationModeScoped
+
+// TerminationRelayPort is the port the termination relay listens on, on the
+// client's tunnel IP.
+const TerminationRelayPort = 51823
+
+// terminationModeNames returns the valid termination modes.
+func terminationModeNames() []string {
+	return []string{
+		string(TerminationModeScoped),
+		string(TerminationModeRelay),
+		string(TerminationModeAPIKey),
+		string(TerminationModeShutdown),
+	}
+}
+
+// ParseTerminationMode parses a DEADMAN_TERMINATION value. An empty value
+// is DefaultTerminationMode.
+func ParseTerminationMode(s string) (TerminationMode, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return DefaultTerminationMode, nil
+	}
+	for _, name := range terminationModeNames() {
+		if s == name {
+			return TerminationMode(s), nil
+		}
+	}
+	return "", fmt.Errorf("unknown termination mode: %s (valid: %s)", s, strings.Join(terminationModeNames(), ", "))
+}
+
+// terminationSetup is the deadman termination credential of one instance.
+type terminationSetup struct {
+	mode TerminationMode
+
+	// token is the relay token, scoped key or API key. It is only held
+	// until the cloud-init is generated and never persisted.
+	token string
+
+	// credentialID identifies the scoped key, to revoke it.
+	credentialID string
+
+	// tokenHash is the hex SHA-256 of the relay token, which is all the
+	// relay needs to check it.
+	tokenHash string
+
+	// relayURL is the relay endpoint the instance calls.
+	relayURL string
+}
+
+// newTerminationSetup prepares the termination credential for a new instance
+// on p, in the mode configured by DEADMAN_TERMINATION.
+func (d *Deployer) newTerminationSetup(ctx context.Context, p provider.Provider) (*terminationSetup, error) {
+	mode, err := ParseTerminationMode(d.cfg.DeadmanTermination)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == TerminationModeScoped {
+		if issuer, ok := p.(provider.TerminationCredentialIssuer); ok {
+			name := "spinup-deadman"
+			if d.stateManager != nil {
+				name += "-" + d.stateManager.Session()
+			}
+			cred, err := issuer.IssueTerminationCredential(ctx, name)
+			if err == nil {
+				return &terminationSetup{mode: mode, token: cred.Key, credentialID: cred.ID}, nil
+			}
+			logging.Warn().Err(err).Str("provider", p.Name()).Msg("Failed to issue a scoped termination key, using the termination relay")
+		} else {
+			logging.Info().Str("provider", p.Name()).Msg("Provider cannot issue scoped termination keys, using the termination relay")
+		}
+		mode = TerminationModeRelay
+	}
+
+	switch mode {
+	case TerminationModeRelay:
+		token, err := newRelayToken()
+		if err != nil {
+			return nil, err
+		}
+		return &terminationSetup{
+			mode:      mode,
+			token:     token,
+			tokenHash: hashRelayToken(token),
+			relayURL:  "http://" + d.relayAddr() + "/terminate",
+		}, nil
+	case TerminationModeAPIKey:
+		apiKey := d.getAPIKeyForProvider(p.Name())
+		if apiKey == "" {
+			return nil, fmt.Errorf("api-key termination requires the %s API key", p.Name())
+		}
+		return &terminationSetup{mode: mode, token: apiKey}, nil
+	default:
+		return &terminationSetup{mode: mode}, nil
+	}
+}
+
+// limitation returns what the deadman switch can't do in the setup's mode,
+// for the user to know at deploy time, or "" if it terminates the instance
+// through the provider itself.
+func (t *terminationSetup) limitation() string {
+	switch t.mode {
+	case TerminationModeRelay:
+		return "if this machine is offline when the deadman switch fires, the instance only shuts down and may still be billed"
+	case TerminationModeShutdown:
+		return "the deadman switch only shuts the instance down, which may not stop billing"
+	}
+	return ""
+}
+
+// apply sets the termination credential of the cloud-init params.
+func (t *terminationSetup) apply(params *CloudInitParams) {
+	params.TerminationMode = t.mode
+	switch t.mode {
+	case TerminationModeRelay:
+		params.TerminationToken = t.token
+		params.RelayURL = t.relayURL
+	case TerminationModeScoped:
+		params.TerminationToken = t.token
+	case TerminationModeAPIKey:
+		params.APIKey = t.token
+	}
+}
+
+// revokeTerminationCredential revokes the scoped key of an instance's
+// deadman switch once the instance is gone. Failures are only logged; the
+// key can still be deleted in the provider's console.
+func revokeTerminationCredential(p provider.Provider, id string) {
+	issuer, ok := p.(provider.TerminationCredentialIssuer)
+	if id == "" || !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := issuer.RevokeTerminationCredential(ctx, id); err != nil {
+		logging.Warn().Err(err).Str("provider", p.Name()).Str("key_id", id).
+			Msg("Failed to revoke the deadman switch key, delete it in the provider's console")
+	}
+}
+
+// relayAddr returns the address the session's termination relay listens on.
+func (d *Deployer) relayAddr() string {
+	return net.JoinHostPort(d.wgNetwork().ClientIP, strconv.Itoa(TerminationRelayPort))
+}
+
+// newRelayToken returns a random one-time relay token.
+func newRelayToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate relay token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashRelayToken returns the hex SHA-256 of a relay token.
+func hashRelayToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TerminationRelay is the HTTP endpoint an instance's deadman switch calls
+// to have the client terminate the instance. The instance only holds a
+// one-time token; the relay holds its hash and terminates the instance
+// with the local provider credentials.
+type TerminationRelay struct {
+	provider   provider.Provider
+	instanceID string
+	tokenHash  []byte
+
+	// OnTerminate is called after the relay has terminated the instance.
+	OnTerminate func(instanceID string)
+
+	mu     sync.Mutex
+	used   bool
+	server *http.Server
+}
+
+// NewTerminationRelay creates a relay that terminates instanceID on p for
+// the token with the given hex SHA-256.
+func NewTerminationRelay(p provider.Provider, instanceID, tokenHash string) (*TerminationRelay, error) {
+	if p == nil {
+		return nil, errors.New("provider is required")
+	}
+	if instanceID == "" {
+		return nil, errors.New("instance ID is required")
+	}
+	hash, err := hex.DecodeString(tokenHash)
+	if err != nil || len(hash) != sha256.Size {
+		return nil, errors.New("invalid relay token hash")
+	}
+	return &TerminationRelay{
+		provider:   p,
+		instanceID: instanceID,
+		tokenHash:  hash,
+	}, nil
+}
+
+// ServeHTTP handles POST /terminate with the relay token as bearer token.
+// The token is spent once the instance has been terminated.
+func (r *TerminationRelay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/terminate" {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	sum := sha256.Sum256([]byte(token))
+	if !ok || subtle.ConstantTimeCompare(sum[:], r.tokenHash) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.used {
+		http.Error(w, "relay token already used", http.StatusGone)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), 30*time.Second)
+	defer cancel()
+	logging.Warn().Str("instance_id", r.instanceID).Msg("Deadman switch requested termination through the relay")
+	if err := r.provider.TerminateInstance(ctx, r.instanceID); err != nil && !isInstanceNotFound(err) {
+		logging.Error().Err(err).Str("instance_id", r.instanceID).Msg("Relay failed to terminate instance")
+		http.Error(w, "termination failed", http.StatusBadGateway)
+		return
+	}
+	r.used = true
+
+	if r.OnTerminate != nil {
+		r.OnTerminate(r.instanceID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Start starts serving the relay on addr.
+func (r *TerminationRelay) Start(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	server := &http.Server{
+		Handler:           r,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	r.mu.Lock()
+	r.server = server
+	r.mu.Unlock()
+
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Warn().Err(err).Msg("Termination relay stopped")
+		}
+	}()
+	return nil
+}
+
+// Stop stops serving the relay.
+func (r *TerminationRelay) Stop() {
+	r.mu.Lock()
+	server := r.server
+	r.server = nil
+	r.mu.Unlock()
+
+	if server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}
+}
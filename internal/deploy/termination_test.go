@@ -0,0 +1,285 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/models"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/mock"
+	"github.com/tmeurs/spinup/internal/wireguard"
+)
+
+// scopedProvider is a mock provider that issues scoped credentials.
+type scopedProvider struct {
+	*mock.Provider
+	name    string
+	err     error
+	revoked []string
+}
+
+func (p *scopedProvider) IssueTerminationCredential(ctx context.Context, name string) (*provider.TerminationCredential, error) {
+	p.name = name
+	if p.err != nil {
+		return nil, p.err
+	}
+	return &provider.TerminationCredential{ID: "key-1", Key: "scoped-credential"}, nil
+}
+
+func (p *scopedProvider) RevokeTerminationCredential(ctx context.Context, id string) error {
+	p.revoked = append(p.revoked, id)
+	return nil
+}
+
+func TestParseTerminationMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    TerminationMode
+		wantErr bool
+	}{
+		{"", TerminationModeScoped, false},
+		{"relay", TerminationModeRelay, false},
+		{"scoped", TerminationModeScoped, false},
+		{" API-Key ", TerminationModeAPIKey, false},
+		{"shutdown", TerminationModeShutdown, false},
+		{"full-key", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTerminationMode(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTerminationMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTerminationMode(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDeployer_newTerminationSetup(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      string
+		apiKey    string
+		provider  provider.Provider
+		wantMode  TerminationMode
+		wantToken string
+		wantErr   bool
+	}{
+		{name: "default is scoped", mode: "", provider: &scopedProvider{Provider: mock.New(mock.WithName("vast"))}, wantMode: TerminationModeScoped, wantToken: "scoped-credential"},
+		{name: "default falls back to relay", mode: "", provider: mock.New(mock.WithName("lambda")), wantMode: TerminationModeRelay},
+		{name: "scoped issue error falls back to relay", mode: "scoped", provider: &scopedProvider{Provider: mock.New(mock.WithName("vast")), err: errors.New("forbidden")}, wantMode: TerminationModeRelay},
+		{name: "relay", mode: "relay", provider: &scopedProvider{Provider: mock.New(mock.WithName("vast"))}, wantMode: TerminationModeRelay},
+		{name: "api-key", mode: "api-key", apiKey: "vast-key", provider: mock.New(mock.WithName("vast")), wantMode: TerminationModeAPIKey, wantToken: "vast-key"},
+		{name: "api-key without key", mode: "api-key", provider: mock.New(mock.WithName("vast")), wantErr: true},
+		{name: "shutdown", mode: "shutdown", apiKey: "vast-key", provider: mock.New(mock.WithName("vast")), wantMode: TerminationModeShutdown},
+		{name: "unknown", mode: "bogus", provider: mock.New(mock.WithName("vast")), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{DeadmanTermination: tt.mode, VastAPIKey: tt.apiKey}
+			d := newJournalTestDeployer(t, nil, cfg)
+
+			setup, err := d.newTerminationSetup(context.Background(), tt.provider)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newTerminationSetup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if setup.mode != tt.wantMode {
+				t.Errorf("mode = %q, want %q", setup.mode, tt.wantMode)
+			}
+
+			if tt.wantMode == TerminationModeRelay {
+				if len(setup.token) != 64 || setup.tokenHash != hashRelayToken(setup.token) {
+					t.Errorf("relay token = %q, hash = %q", setup.token, setup.tokenHash)
+				}
+				if setup.relayURL != "http://10.13.37.2:51823/terminate" {
+					t.Errorf("relayURL = %q", setup.relayURL)
+				}
+			} else if setup.token != tt.wantToken {
+				t.Errorf("token = %q, want %q", setup.token, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestTerminationSetup_limitation(t *testing.T) {
+	tests := []struct {
+		mode TerminationMode
+		want string
+	}{
+		{TerminationModeRelay, "only shuts down"},
+		{TerminationModeShutdown, "only shuts the instance down"},
+		{TerminationModeScoped, ""},
+		{TerminationModeAPIKey, ""},
+	}
+
+	for _, tt := range tests {
+		got := (&terminationSetup{mode: tt.mode}).limitation()
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Errorf("limitation() for %s = %q, want it to contain %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestDeployer_createInstance_KeepsAPIKeyOffInstance(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir())
+	d := newJournalTestDeployer(t, sm, &config.Config{VastAPIKey: "vast-account-key"})
+	d.network, _ = wireguard.NetworkForSlot(2)
+	d.startJournal()
+
+	p, offer := newFailoverProvider("vast", "vast-1")
+	model, _ := models.GetModelByName("qwen2.5-coder:7b")
+	clientKeys, _ := wireguard.GenerateKeyPair()
+
+	instance, wgConfig, err := d.createInstance(context.Background(), p, &offer.Offer, model, clientKeys)
+	if err != nil {
+		t.Fatalf("createInstance() error = %v", err)
+	}
+
+	cloudInit := p.CreateInstanceCalls[0].Request.CloudInit
+	if strings.Contains(cloudInit, "vast-account-key") {
+		t.Error("cloud-init must not contain the provider API key")
+	}
+	if !strings.Contains(cloudInit, `"http://10.13.39.2:51823/terminate"`) {
+		t.Error("expected the session's relay URL in cloud-init")
+	}
+	match := regexp.MustCompile(`Authorization: Bearer ([0-9a-f]+)`).FindStringSubmatch(cloudInit)
+	if match == nil {
+		t.Fatal("expected the relay token in cloud-init")
+	}
+
+	// Only the token's hash is kept locally
+	deadman := d.deadmanState()
+	if deadman.Termination != "relay" || deadman.RelayAddr != "10.13.39.2:51823" || deadman.RelayTokenHash != hashRelayToken(match[1]) {
+		t.Errorf("deadman state = %+v", deadman)
+	}
+
+	d.journalInstance(p, &offer.Offer, instance, wgConfig)
+	journal, _ := LoadDeployJournal(sm)
+	if journal == nil || journal.Termination != TerminationModeRelay || journal.RelayTokenHash != deadman.RelayTokenHash {
+		t.Errorf("journal = %+v, want the relay mode and token hash", journal)
+	}
+}
+
+func TestDeployer_createInstance_ScopedCredential(t *testing.T) {
+	sm, _ := config.NewStateManager(t.TempDir(), config.WithSession("gpu"))
+	d := newJournalTestDeployer(t, sm, &config.Config{VastAPIKey: "vast-account-key"})
+	d.startJournal()
+
+	mp, offer := newFailoverProvider("vast", "vast-1")
+	p := &scopedProvider{Provider: mp}
+	model, _ := models.GetModelByName("qwen2.5-coder:7b")
+	clientKeys, _ := wireguard.GenerateKeyPair()
+
+	instance, wgConfig, err := d.createInstance(context.Background(), p, &offer.Offer, model, clientKeys)
+	if err != nil {
+		t.Fatalf("createInstance() error = %v", err)
+	}
+	if p.name != "spinup-deadman-gpu" {
+		t.Errorf("credential name = %q, want spinup-deadman-gpu", p.name)
+	}
+
+	// The instance holds the scoped key, never the account key
+	cloudInit := mp.CreateInstanceCalls[0].Request.CloudInit
+	if strings.Contains(cloudInit, "vast-account-key") || !strings.Contains(cloudInit, "Authorization: Bearer scoped-credential") {
+		t.Error("expected only the scoped key in cloud-init")
+	}
+
+	// Only the key's ID is kept locally, to revoke it
+	if deadman := d.deadmanState(); deadman.Termination != "scoped" || deadman.TerminationCredentialID != "key-1" {
+		t.Errorf("deadman state = %+v", deadman)
+	}
+	d.journalInstance(p, &offer.Offer, instance, wgConfig)
+	journal, _ := LoadDeployJournal(sm)
+	if journal == nil || journal.TerminationCredentialID != "key-1" {
+		t.Errorf("journal = %+v, want the scoped key's ID", journal)
+	}
+
+	// Terminating the instance revokes its key
+	if err := d.terminateAttempt(p, instance.ID); err != nil {
+		t.Fatalf("terminateAttempt() error = %v", err)
+	}
+	if len(p.revoked) != 1 || p.revoked[0] != "key-1" {
+		t.Errorf("revoked = %v, want [key-1]", p.revoked)
+	}
+}
+
+func TestTerminationRelay_ServeHTTP(t *testing.T) {
+	p := mock.New(mock.WithName("vast"))
+	relay, err := NewTerminationRelay(p, "inst-1", hashRelayToken("good-token"))
+	if err != nil {
+		t.Fatalf("NewTerminationRelay() error = %v", err)
+	}
+	var terminated []string
+	relay.OnTerminate = func(id string) { terminated = append(terminated, id) }
+
+	do := func(method, path, auth string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		relay.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := do(http.MethodPost, "/other", "Bearer good-token"); code != http.StatusNotFound {
+		t.Errorf("wrong path = %d, want 404", code)
+	}
+	if code := do(http.MethodGet, "/terminate", "Bearer good-token"); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET = %d, want 405", code)
+	}
+	if code := do(http.MethodPost, "/terminate", ""); code != http.StatusUnauthorized {
+		t.Errorf("no token = %d, want 401", code)
+	}
+	if code := do(http.MethodPost, "/terminate", "Bearer bad-token"); code != http.StatusUnauthorized {
+		t.Errorf("bad token = %d, want 401", code)
+	}
+	if len(p.TerminateInstanceCalls) != 0 {
+		t.Fatal("rejected requests must not terminate the instance")
+	}
+
+	// A failed termination leaves the token usable
+	p.FailNext("TerminateInstance", provider.ErrRateLimited)
+	if code := do(http.MethodPost, "/terminate", "Bearer good-token"); code != http.StatusBadGateway {
+		t.Errorf("failed termination = %d, want 502", code)
+	}
+
+	if code := do(http.MethodPost, "/terminate", "Bearer good-token"); code != http.StatusNoContent {
+		t.Errorf("good token = %d, want 204", code)
+	}
+	if code := do(http.MethodPost, "/terminate", "Bearer good-token"); code != http.StatusGone {
+		t.Errorf("reused token = %d, want 410", code)
+	}
+
+	if len(p.TerminateInstanceCalls) != 2 || p.TerminateInstanceCalls[1].ID != "inst-1" {
+		t.Errorf("TerminateInstanceCalls = %+v", p.TerminateInstanceCalls)
+	}
+	if len(terminated) != 1 || terminated[0] != "inst-1" {
+		t.Errorf("OnTerminate calls = %v, want [inst-1]", terminated)
+	}
+}
+
+func TestNewTerminationRelay_Validation(t *testing.T) {
+	p := mock.New()
+	if _, err := NewTerminationRelay(nil, "inst-1", hashRelayToken("t")); err == nil {
+		t.Error("expected error without provider")
+	}
+	if _, err := NewTerminationRelay(p, "", hashRelayToken("t")); err == nil {
+		t.Error("expected error without instance ID")
+	}
+	if _, err := NewTerminationRelay(p, "inst-1", "not-a-hash"); err == nil {
+		t.Error("expected error for a malformed token hash")
+	}
+}
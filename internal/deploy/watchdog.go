@@ -0,0 +1,262 @@
+// Package deploy provides deployment orchestration for spinup.
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/clock"
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/logging"
+	"github.com/tmeurs/spinup/internal/wireguard"
+)
+
+// WatchdogConfig holds configuration for the client-side watchdog.
+type WatchdogConfig struct {
+	// Timeout is how long the instance may go without a successful heartbeat
+	// or tunnel handshake before the watchdog gives up on it. Zero disables
+	// the watchdog.
+	Timeout time.Duration
+
+	// Interval is how often the tunnel is checked.
+	Interval time.Duration
+
+	// InterfaceName is the WireGuard interface whose handshakes are tracked.
+	// The supervisor takes it from state if it is left empty.
+	InterfaceName string
+
+	// Handshake returns the time of the tunnel's last handshake. Default is
+	// the handshake reported by wireguard.GetTunnelStatus.
+	Handshake func(ctx context.Context, interfaceName string) (time.Time, error)
+
+	// OnUnreachable is called once the instance has been unreachable for
+	// Timeout, with how long it has been silent. If it returns an error it
+	// is called again after another Timeout.
+	OnUnreachable func(ctx context.Context, silent time.Duration) error
+
+	// Clock schedules checks. Default (nil) is the system clock.
+	Clock clock.Clock
+}
+
+// Constants for watchdog configuration.
+const (
+	// DefaultWatchdogTimeout is how long the instance may be unreachable
+	// before the watchdog terminates it.
+	DefaultWatchdogTimeout = config.DefaultWatchdogTimeout
+
+	// DefaultWatchdogInterval is the default interval between tunnel checks.
+	DefaultWatchdogInterval = time.Minute
+)
+
+// NewWatchdogConfig creates a new WatchdogConfig with default values.
+func NewWatchdogConfig() *WatchdogConfig {
+	return &WatchdogConfig{
+		Timeout:       DefaultWatchdogTimeout,
+		Interval:      DefaultWatchdogInterval,
+		InterfaceName: wireguard.InterfaceName,
+	}
+}
+
+// Validate validates the watchdog configuration.
+func (c *WatchdogConfig) Validate() error {
+	if c.Timeout < 0 {
+		return errors.New("watchdog timeout cannot be negative")
+	}
+	if c.Timeout > 0 && c.Interval <= 0 {
+		return errors.New("watchdog interval must be positive")
+	}
+	return nil
+}
+
+// WatchdogStatus represents the current status of the watchdog.
+type WatchdogStatus struct {
+	// Running indicates if the watchdog goroutine is active.
+	Running bool
+
+	// LastContact is the last time the instance was seen: a successful
+	// heartbeat or tunnel handshake, or the watchdog starting.
+	LastContact time.Time
+
+	// Fired indicates the watchdog has given up on the instance.
+	Fired bool
+}
+
+// Watchdog is the client side of the deadman switch. It tracks heartbeats
+// and tunnel handshakes, and calls OnUnreachable once the instance has gone
+// dark for longer than the timeout, in case the deadman switch on the
+// instance can't terminate it.
+type Watchdog struct {
+	config *WatchdogConfig
+	clock  clock.Clock
+
+	mu          sync.RWMutex
+	running     bool
+	lastContact time.Time
+	lastCheck   time.Time
+	retryAt     time.Time
+	fired       bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWatchdog creates a new watchdog.
+func NewWatchdog(config *WatchdogConfig) (*Watchdog, error) {
+	if config == nil {
+		config = NewWatchdogConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Handshake == nil {
+		config.Handshake = tunnelHandshake
+	}
+
+	return &Watchdog{
+		config: config,
+		clock:  clock.OrReal(config.Clock),
+	}, nil
+}
+
+// tunnelHandshake returns the last handshake of the interface's peer.
+func tunnelHandshake(ctx context.Context, interfaceName string) (time.Time, error) {
+	status, err := wireguard.GetTunnelStatus(ctx, interfaceName)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return status.LastHandshake, nil
+}
+
+// Start begins watching the instance. The instance counts as seen at start.
+func (w *Watchdog) Start(ctx context.Context) error {
+	w.mu.Lock()
+	if w.running {
+		w.mu.Unlock()
+		return fmt.Errorf("watchdog is already running")
+	}
+
+	ctx, w.cancel = context.WithCancel(ctx)
+	now := w.clock.Now()
+	w.running = true
+	w.lastContact = now
+	w.lastCheck = now
+	w.retryAt = time.Time{}
+	w.fired = false
+	w.done = make(chan struct{})
+	w.mu.Unlock()
+
+	go w.watchLoop(ctx)
+
+	return nil
+}
+
+// Stop stops the watchdog.
+func (w *Watchdog) Stop() {
+	w.mu.Lock()
+	if !w.running {
+		w.mu.Unlock()
+		return
+	}
+	cancel := w.cancel
+	done := w.done
+	w.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+
+	if done != nil {
+		<-done
+	}
+}
+
+// RecordContact records that the instance was seen at the given time, e.g.
+// by a successful heartbeat.
+func (w *Watchdog) RecordContact(at time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if at.After(w.lastContact) {
+		w.lastContact = at
+	}
+}
+
+// Status returns the current status of the watchdog.
+func (w *Watchdog) Status() *WatchdogStatus {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return &WatchdogStatus{
+		Running:     w.running,
+		LastContact: w.lastContact,
+		Fired:       w.fired,
+	}
+}
+
+// watchLoop checks the instance at regular intervals.
+func (w *Watchdog) watchLoop(ctx context.Context) {
+	defer func() {
+		w.mu.Lock()
+		w.running = false
+		close(w.done)
+		w.mu.Unlock()
+	}()
+
+	ticker := w.clock.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			w.check(ctx)
+		}
+	}
+}
+
+// check records the latest tunnel handshake and calls OnUnreachable if the
+// instance has been silent for longer than the timeout.
+func (w *Watchdog) check(ctx context.Context) {
+	handshake, err := w.config.Handshake(ctx, w.config.InterfaceName)
+	if err != nil {
+		logging.Debug().Err(err).Str("interface", w.config.InterfaceName).Msg("Watchdog could not read tunnel status")
+	} else if !handshake.IsZero() {
+		w.RecordContact(handshake)
+	}
+
+	now := w.clock.Now()
+	w.mu.Lock()
+	// A gap much longer than the interval means this machine was asleep,
+	// not that the instance went dark; give it a fresh timeout.
+	if now.Sub(w.lastCheck) > 3*w.config.Interval {
+		logging.Info().Dur("gap", now.Sub(w.lastCheck)).Msg("Watchdog resumed after a pause, restarting its timeout")
+		if now.After(w.lastContact) {
+			w.lastContact = now
+		}
+	}
+	w.lastCheck = now
+
+	silent := now.Sub(w.lastContact)
+	if w.fired || silent <= w.config.Timeout || now.Before(w.retryAt) {
+		w.mu.Unlock()
+		return
+	}
+	callback := w.config.OnUnreachable
+	w.mu.Unlock()
+
+	logging.Warn().Dur("silent", silent).Msg("Instance unreachable past the watchdog timeout")
+	err = nil
+	if callback != nil {
+		err = callback(ctx, silent)
+	}
+
+	w.mu.Lock()
+	if err != nil {
+		w.retryAt = w.clock.Now().Add(w.config.Timeout)
+	} else {
+		w.fired = true
+	}
+	w.mu.Unlock()
+}
@@ -0,0 +1,307 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/alert"
+	"github.com/tmeurs/spinup/internal/clock"
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/mock"
+)
+
+// noHandshake reports a tunnel that can't be read.
+func noHandshake(context.Context, string) (time.Time, error) {
+	return time.Time{}, errors.New("no tunnel")
+}
+
+// recordingNotifier records the alerts dispatched to it.
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []recordedAlert
+}
+
+type recordedAlert struct {
+	level   alert.Level
+	message string
+}
+
+func (n *recordingNotifier) Notify(level alert.Level, message string, ctx alert.Context) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, recordedAlert{level: level, message: message})
+}
+
+func (n *recordingNotifier) count(level alert.Level) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	count := 0
+	for _, a := range n.alerts {
+		if a.level == level {
+			count++
+		}
+	}
+	return count
+}
+
+// newCheckedWatchdog returns a watchdog whose checks are driven by the test
+// through check, as if it had been started at the fake clock's time.
+func newCheckedWatchdog(t *testing.T, fake *clock.Fake, cfg *WatchdogConfig) *Watchdog {
+	t.Helper()
+	cfg.Clock = fake
+	w, err := NewWatchdog(cfg)
+	if err != nil {
+		t.Fatalf("NewWatchdog() error = %v", err)
+	}
+	w.lastContact = fake.Now()
+	w.lastCheck = fake.Now()
+	return w
+}
+
+func TestWatchdogConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *WatchdogConfig
+		wantErr bool
+	}{
+		{"default", NewWatchdogConfig(), false},
+		{"disabled", &WatchdogConfig{}, false},
+		{"negative timeout", &WatchdogConfig{Timeout: -time.Minute, Interval: time.Minute}, true},
+		{"zero interval", &WatchdogConfig{Timeout: time.Hour}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWatchdog_FiresOnceAfterTimeout(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	var calls []time.Duration
+	w := newCheckedWatchdog(t, fake, &WatchdogConfig{
+		Timeout:   10 * time.Minute,
+		Interval:  time.Minute,
+		Handshake: noHandshake,
+		OnUnreachable: func(ctx context.Context, silent time.Duration) error {
+			calls = append(calls, silent)
+			return nil
+		},
+	})
+
+	for i := 0; i < 15; i++ {
+		fake.Advance(time.Minute)
+		w.check(context.Background())
+	}
+
+	if len(calls) != 1 || calls[0] != 11*time.Minute {
+		t.Errorf("OnUnreachable calls = %v, want one after 11m", calls)
+	}
+	if !w.Status().Fired {
+		t.Error("expected the watchdog to report it fired")
+	}
+}
+
+func TestWatchdog_ContactResetsTimeout(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	var handshake time.Time
+	fired := false
+	w := newCheckedWatchdog(t, fake, &WatchdogConfig{
+		Timeout:  10 * time.Minute,
+		Interval: time.Minute,
+		Handshake: func(context.Context, string) (time.Time, error) {
+			return handshake, nil
+		},
+		OnUnreachable: func(ctx context.Context, silent time.Duration) error {
+			fired = true
+			return nil
+		},
+	})
+
+	for i := 1; i <= 30; i++ {
+		fake.Advance(time.Minute)
+		switch {
+		case i%8 == 0:
+			// A heartbeat got through
+			w.RecordContact(fake.Now())
+		case i%4 == 0:
+			// The tunnel handshaked
+			handshake = fake.Now()
+		}
+		w.check(context.Background())
+	}
+
+	if fired {
+		t.Error("watchdog fired although the instance was seen within the timeout")
+	}
+	if !w.Status().LastContact.Equal(fake.Now().Add(-2 * time.Minute)) {
+		t.Errorf("LastContact = %v, want the handshake at minute 28", w.Status().LastContact)
+	}
+}
+
+func TestWatchdog_PauseRestartsTimeout(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	fired := false
+	w := newCheckedWatchdog(t, fake, &WatchdogConfig{
+		Timeout:   10 * time.Minute,
+		Interval:  time.Minute,
+		Handshake: noHandshake,
+		OnUnreachable: func(ctx context.Context, silent time.Duration) error {
+			fired = true
+			return nil
+		},
+	})
+
+	// The machine slept for two hours
+	fake.Advance(2 * time.Hour)
+	w.check(context.Background())
+	if fired {
+		t.Fatal("watchdog fired right after the machine woke up")
+	}
+
+	for i := 0; i < 11; i++ {
+		fake.Advance(time.Minute)
+		w.check(context.Background())
+	}
+	if !fired {
+		t.Error("expected the watchdog to fire a timeout after waking up")
+	}
+}
+
+func TestWatchdog_RetriesAfterFailure(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	calls := 0
+	w := newCheckedWatchdog(t, fake, &WatchdogConfig{
+		Timeout:   10 * time.Minute,
+		Interval:  time.Minute,
+		Handshake: noHandshake,
+		OnUnreachable: func(ctx context.Context, silent time.Duration) error {
+			calls++
+			if calls == 1 {
+				return errors.New("provider API unreachable")
+			}
+			return nil
+		},
+	})
+
+	for i := 0; i < 30; i++ {
+		fake.Advance(time.Minute)
+		w.check(context.Background())
+	}
+
+	// Minute 11 fails, minute 21 succeeds
+	if calls != 2 {
+		t.Errorf("OnUnreachable called %d times, want 2", calls)
+	}
+	if !w.Status().Fired {
+		t.Error("expected the watchdog to report it fired")
+	}
+}
+
+// newWatchdogSupervisor runs a supervisor whose watchdog fires within
+// milliseconds, and returns the channel Run's result is sent on.
+func newWatchdogSupervisor(t *testing.T, sm *config.StateManager, p provider.Provider, notifier *recordingNotifier) (*Supervisor, <-chan error) {
+	t.Helper()
+
+	supCfg := newTestSupervisorConfig()
+	supCfg.Watchdog = &WatchdogConfig{Timeout: 50 * time.Millisecond, Interval: 10 * time.Millisecond, Handshake: noHandshake}
+	supCfg.Stop = &StopConfig{MaxRetries: 1, BaseRetryDelay: time.Second, TerminateTimeout: time.Second, BillingCheckTimeout: time.Second}
+	sup, err := NewSupervisor(&config.Config{}, sm, supCfg,
+		WithSupervisorDispatcher(alert.NewDispatcher(alert.WithTUINotifier(notifier))),
+		WithSupervisorProvider(p),
+	)
+	if err != nil {
+		t.Fatalf("NewSupervisor() error = %v", err)
+	}
+
+	// The supervisor must stop writing state before the temp dir is removed
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		done <- sup.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-exited
+	})
+	return sup, done
+}
+
+// useTestInterface points the session's state at a tunnel interface that
+// doesn't exist, so stopping it can't touch a real tunnel.
+func useTestInterface(t *testing.T, sm *config.StateManager) {
+	t.Helper()
+	state, _ := sm.LoadState()
+	state.WireGuard = &config.WireGuardState{InterfaceName: "wg-spinuptest"}
+	if err := sm.SaveState(state); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+}
+
+func TestSupervisor_Watchdog_TerminatesUnreachableInstance(t *testing.T) {
+	sm := newSupervisorTestState(t, "on-demand")
+	useTestInterface(t, sm)
+	p := mock.New(mock.WithName("vast"), mock.WithBillingVerificationSupport(true))
+	p.AddInstance(&provider.Instance{ID: "sup-123", Provider: "vast", Status: provider.InstanceStatusRunning})
+	notifier := &recordingNotifier{}
+
+	sup, done := newWatchdogSupervisor(t, sm, p, notifier)
+
+	// Terminating clears the session, which ends the supervisor
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor did not exit after the watchdog fired")
+	}
+
+	if len(p.TerminateInstanceCalls) != 1 || p.TerminateInstanceCalls[0].ID != "sup-123" {
+		t.Errorf("TerminateInstanceCalls = %+v, want sup-123", p.TerminateInstanceCalls)
+	}
+	if len(p.GetBillingStatusCalls) == 0 {
+		t.Error("expected billing to be verified after termination")
+	}
+	if state, _ := sm.LoadState(); state != nil && state.Instance != nil {
+		t.Error("expected the session to be cleared")
+	}
+	if notifier.count(alert.LevelCritical) != 0 {
+		t.Errorf("unexpected critical alert: %+v", notifier.alerts)
+	}
+	if !sup.Status().Watchdog.Fired {
+		t.Error("expected the watchdog to report it fired")
+	}
+}
+
+func TestSupervisor_Watchdog_AlertsWhenTerminationFails(t *testing.T) {
+	sm := newSupervisorTestState(t, "on-demand")
+	useTestInterface(t, sm)
+	p := mock.New(mock.WithName("vast"), mock.WithTerminateInstanceError(provider.ErrAuthenticationFailed))
+	notifier := &recordingNotifier{}
+
+	newWatchdogSupervisor(t, sm, p, notifier)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for notifier.count(alert.LevelCritical) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected a critical alert when the watchdog cannot terminate the instance")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The session is kept so the instance can still be stopped
+	if state, _ := sm.LoadState(); state == nil || state.Instance == nil {
+		t.Error("expected the session to be kept")
+	}
+}
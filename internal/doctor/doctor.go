@@ -0,0 +1,529 @@
+// Package doctor implements the preflight checks of 'spinup doctor'.
+//
+// A deployment that fails at tunnel setup has already rented an instance, and
+// the cause is usually local: a missing wg binary, no root or sudo, a leftover
+// interface, a bad key or a clashing VPN. The Doctor checks these up front,
+// together with the configuration, the state file and each provider's API
+// key, and gives a remediation hint for every problem it finds.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/registry"
+	"github.com/tmeurs/spinup/internal/wireguard"
+)
+
+// DefaultProviderTimeout is the default timeout for validating one provider's API key.
+const DefaultProviderTimeout = 15 * time.Second
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	// StatusOK means the check passed.
+	StatusOK Status = "ok"
+	// StatusWarn means a deployment will work but something deserves attention.
+	StatusWarn Status = "warn"
+	// StatusFail means a deployment will fail.
+	StatusFail Status = "fail"
+	// StatusSkip means the check could not run because an earlier one failed.
+	StatusSkip Status = "skip"
+)
+
+// Check is the outcome of one diagnostic.
+type Check struct {
+	// Name identifies the check (e.g., "privileges" or "provider_vast").
+	Name string
+
+	// Status is the outcome of the check.
+	Status Status
+
+	// Message describes what was found.
+	Message string
+
+	// Hint describes how to fix a warning or failure.
+	Hint string
+}
+
+// Report holds the outcome of all checks in the order they ran.
+type Report struct {
+	Checks []Check
+}
+
+// Count returns the number of checks with the given status.
+func (r *Report) Count(status Status) int {
+	n := 0
+	for _, c := range r.Checks {
+		if c.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Status returns StatusFail if any check failed, StatusWarn if any check
+// warned, and StatusOK otherwise.
+func (r *Report) Status() Status {
+	switch {
+	case r.Count(StatusFail) > 0:
+		return StatusFail
+	case r.Count(StatusWarn) > 0:
+		return StatusWarn
+	}
+	return StatusOK
+}
+
+// Get returns the check with the given name, or nil if it didn't run.
+func (r *Report) Get(name string) *Check {
+	for i := range r.Checks {
+		if r.Checks[i].Name == name {
+			return &r.Checks[i]
+		}
+	}
+	return nil
+}
+
+// add appends a check to the report.
+func (r *Report) add(name string, status Status, message, hint string) {
+	r.Checks = append(r.Checks, Check{Name: name, Status: status, Message: message, Hint: hint})
+}
+
+// System is the local machine the checks inspect.
+type System interface {
+	// Tools returns the external commands tunnel setup uses.
+	Tools() []wireguard.Tool
+
+	// Privileges returns whether this process can configure interfaces.
+	Privileges() wireguard.Privileges
+
+	// InterfaceExists returns true if the WireGuard interface exists.
+	InterfaceExists(name string) bool
+
+	// Conflicts returns local addresses overlapping the network's subnet.
+	Conflicts(n *wireguard.Network) ([]wireguard.AddressConflict, error)
+}
+
+// localSystem inspects the machine spinup runs on.
+type localSystem struct{}
+
+func (localSystem) Tools() []wireguard.Tool          { return wireguard.Tools() }
+func (localSystem) Privileges() wireguard.Privileges { return wireguard.CheckPrivileges() }
+func (localSystem) InterfaceExists(name string) bool { return wireguard.InterfaceExists(name) }
+func (localSystem) Conflicts(n *wireguard.Network) ([]wireguard.AddressConflict, error) {
+	return n.Conflicts()
+}
+
+// Doctor runs the preflight checks.
+type Doctor struct {
+	envPath    string
+	globalPath string
+	profile    string
+	stateDir   string
+	system     System
+	providers  []provider.Provider
+	timeout    time.Duration
+}
+
+// Option is a functional option for Doctor.
+type Option func(*Doctor)
+
+// WithEnvPath sets the .env file to check instead of config.DefaultEnvPath.
+func WithEnvPath(path string) Option {
+	return func(d *Doctor) {
+		d.envPath = path
+	}
+}
+
+// WithGlobalConfig sets the global config file to load below the .env file
+// (default: none).
+func WithGlobalConfig(path string) Option {
+	return func(d *Doctor) {
+		d.globalPath = path
+	}
+}
+
+// WithProfile selects a profile from the global config file.
+func WithProfile(name string) Option {
+	return func(d *Doctor) {
+		d.profile = name
+	}
+}
+
+// WithStateDir sets the directory of the state file (default: the current directory).
+func WithStateDir(dir string) Option {
+	return func(d *Doctor) {
+		d.stateDir = dir
+	}
+}
+
+// WithSystem sets the machine to inspect instead of the local one.
+func WithSystem(s System) Option {
+	return func(d *Doctor) {
+		d.system = s
+	}
+}
+
+// WithProviders sets the providers whose API keys are validated instead of
+// all configured providers.
+func WithProviders(providers ...provider.Provider) Option {
+	return func(d *Doctor) {
+		d.providers = providers
+	}
+}
+
+// WithProviderTimeout sets the timeout for validating one provider's API key.
+func WithProviderTimeout(timeout time.Duration) Option {
+	return func(d *Doctor) {
+		d.timeout = timeout
+	}
+}
+
+// New creates a new Doctor.
+func New(opts ...Option) *Doctor {
+	d := &Doctor{
+		envPath: config.DefaultEnvPath,
+		system:  localSystem{},
+		timeout: DefaultProviderTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// Run runs all checks. Checks that depend on the configuration are skipped
+// if it can't be loaded; all others always run.
+func (d *Doctor) Run(ctx context.Context) *Report {
+	report := &Report{}
+
+	cfg := d.checkConfig(report)
+	d.checkWireGuardKeys(report, cfg)
+	d.checkTools(report)
+	d.checkPrivileges(report)
+	sessions := d.checkState(report)
+	d.checkInterface(report, sessions)
+	d.checkSubnet(report)
+	d.checkProviders(ctx, report, cfg)
+
+	return report
+}
+
+// checkConfig checks the .env file and the configuration it holds, and
+// returns the configuration if it could be loaded.
+func (d *Doctor) checkConfig(report *Report) *config.Config {
+	path, err := filepath.Abs(d.envPath)
+	if err != nil {
+		path = d.envPath
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err) && d.hasGlobalConfig():
+		report.add("env_file", StatusOK, fmt.Sprintf("%s not found, using %s", path, d.globalPath), "")
+	case os.IsNotExist(err):
+		report.add("env_file", StatusFail, fmt.Sprintf("%s not found", path),
+			"run 'spinup init' to create it")
+		report.add("config", StatusSkip, "no configuration file", "")
+		return nil
+	case err != nil:
+		report.add("env_file", StatusFail, err.Error(), "")
+		report.add("config", StatusSkip, "no configuration file", "")
+		return nil
+	case info.Mode().Perm()&0o077 != 0:
+		report.add("env_file", StatusWarn,
+			fmt.Sprintf("%s has permissions %04o, other users can read your API keys", path, info.Mode().Perm()),
+			fmt.Sprintf("run 'chmod 600 %s'", path))
+	default:
+		report.add("env_file", StatusOK, fmt.Sprintf("%s (mode %04o)", path, info.Mode().Perm()), "")
+	}
+
+	cfg, _, err := config.Load(
+		config.WithGlobalConfig(d.globalPath),
+		config.WithProjectConfig(path),
+		config.WithProfile(d.profile),
+	)
+	if err != nil {
+		hint := fmt.Sprintf("fix the setting in %s", path)
+		switch {
+		case errors.Is(err, config.ErrUnknownProfile):
+			hint = fmt.Sprintf("add a [profiles.%s] table to %s", d.profile, d.globalPath)
+		case errors.Is(err, config.ErrSecretsLocked):
+			hint = fmt.Sprintf("set %s or SECRETS_KEYFILE to unlock the secrets file", config.SecretsPassphraseEnv)
+		case errors.Is(err, config.ErrWrongPassphrase):
+			hint = fmt.Sprintf("check %s or SECRETS_KEYFILE", config.SecretsPassphraseEnv)
+		case errors.Is(err, config.ErrSecretNotFound):
+			hint = "store the secret, e.g. with 'spinup init --secret-backend', or fix the secret:// reference"
+		}
+		report.add("config", StatusFail, err.Error(), hint)
+		return nil
+	}
+
+	if err := cfg.Validate(); err != nil {
+		hint := fmt.Sprintf("fix the setting in %s", path)
+		if errors.Is(err, config.ErrNoProviderConfigured) {
+			hint = "add a provider API key, e.g. with 'spinup init'"
+		}
+		report.add("config", StatusFail, err.Error(), hint)
+		return cfg
+	}
+
+	providers := cfg.ConfiguredProviders()
+	message := fmt.Sprintf("%d provider(s) configured: %s", len(providers), strings.Join(providers, ", "))
+	if cfg.Profile != "" {
+		message += fmt.Sprintf(" (profile %s)", cfg.Profile)
+	}
+	report.add("config", StatusOK, message, "")
+	return cfg
+}
+
+// hasGlobalConfig returns true if the global config file exists.
+func (d *Doctor) hasGlobalConfig() bool {
+	if d.globalPath == "" {
+		return false
+	}
+	_, err := os.Stat(d.globalPath)
+	return err == nil
+}
+
+// checkWireGuardKeys checks the WireGuard key pair from the configuration.
+func (d *Doctor) checkWireGuardKeys(report *Report, cfg *config.Config) {
+	const name = "wireguard_keys"
+	if cfg == nil {
+		report.add(name, StatusSkip, "no configuration", "")
+		return
+	}
+	if cfg.WireGuardPrivateKey == "" {
+		report.add(name, StatusOK, "not set, a key pair is generated for each deployment", "")
+		return
+	}
+
+	if err := wireguard.ValidatePrivateKey(cfg.WireGuardPrivateKey); err != nil {
+		report.add(name, StatusFail, fmt.Sprintf("WIREGUARD_PRIVATE_KEY: %v", err),
+			"generate a key with 'wg genkey', or remove WIREGUARD_PRIVATE_KEY to generate one per deployment")
+		return
+	}
+
+	if cfg.WireGuardPublicKey != "" {
+		public, err := wireguard.PublicKeyFromPrivate(cfg.WireGuardPrivateKey)
+		if err == nil && public != cfg.WireGuardPublicKey {
+			report.add(name, StatusWarn, "WIREGUARD_PUBLIC_KEY doesn't belong to WIREGUARD_PRIVATE_KEY",
+				fmt.Sprintf("set WIREGUARD_PUBLIC_KEY=%s or remove it; spinup derives it from the private key", public))
+			return
+		}
+	}
+
+	report.add(name, StatusOK, "WIREGUARD_PRIVATE_KEY is valid", "")
+}
+
+// checkTools checks that the commands tunnel setup runs are installed.
+func (d *Doctor) checkTools(report *Report) {
+	for _, tool := range d.system.Tools() {
+		name := "tool_" + strings.ReplaceAll(tool.Name, "-", "_")
+		switch {
+		case tool.Found():
+			report.add(name, StatusOK, fmt.Sprintf("%s found at %s", tool.Name, tool.Path), "")
+		case tool.Required:
+			report.add(name, StatusFail, fmt.Sprintf("%s not found in PATH, tunnels can't be set up", tool.Name), tool.Hint)
+		default:
+			report.add(name, StatusWarn, fmt.Sprintf("%s not found in PATH, only needed to inspect tunnels", tool.Name), tool.Hint)
+		}
+	}
+}
+
+// checkPrivileges checks that this process can configure network interfaces.
+func (d *Doctor) checkPrivileges(report *Report) {
+	const name = "privileges"
+	privileges := d.system.Privileges()
+	switch {
+	case privileges.Root:
+		report.add(name, StatusOK, "running as root", "")
+	case privileges.Sudo:
+		report.add(name, StatusOK, "passwordless sudo is available", "")
+	default:
+		report.add(name, StatusFail, "not root and sudo asks for a password, tunnels can't be set up",
+			"run spinup with sudo, or allow passwordless sudo for your user")
+	}
+}
+
+// checkState checks that the state file can be read, and returns its
+// sessions (nil if there are none or it can't be read).
+func (d *Doctor) checkState(report *Report) map[string]*config.State {
+	const name = "state"
+
+	sm, err := config.NewStateManager(d.stateDir)
+	if err != nil {
+		report.add(name, StatusFail, err.Error(), "")
+		return nil
+	}
+	path := filepath.Join(sm.StateDir(), config.StateFileName)
+
+	sessions, err := sm.ListSessions()
+	switch {
+	case errors.Is(err, config.ErrStateCorrupt):
+		report.add(name, StatusFail, fmt.Sprintf("%s: %v", path, err),
+			fmt.Sprintf("move %s aside, then run 'spinup gc' to find the instances it tracked", path))
+		return nil
+	case errors.Is(err, config.ErrStateLocked):
+		report.add(name, StatusWarn, "the state file is locked by another spinup process",
+			"wait for the other spinup command to finish")
+		return nil
+	case err != nil:
+		report.add(name, StatusFail, err.Error(), "")
+		return nil
+	}
+
+	if len(sessions) == 0 {
+		report.add(name, StatusOK, "no sessions", "")
+		return nil
+	}
+
+	running := 0
+	for _, state := range sessions {
+		if state != nil && state.Instance != nil {
+			running++
+		}
+	}
+	report.add(name, StatusOK, fmt.Sprintf("%d session(s), %d with an instance", len(sessions), running), "")
+	return sessions
+}
+
+// checkInterface checks that the default tunnel interface is free, or in
+// use by a session's instance.
+func (d *Doctor) checkInterface(report *Report, sessions map[string]*config.State) {
+	const name = "interface"
+	iface := wireguard.InterfaceName
+
+	if !d.system.InterfaceExists(iface) {
+		report.add(name, StatusOK, fmt.Sprintf("%s is free", iface), "")
+		return
+	}
+
+	for session, state := range sessions {
+		if state != nil && state.Instance != nil && state.WireGuard != nil && state.WireGuard.InterfaceName == iface {
+			report.add(name, StatusOK, fmt.Sprintf("%s is the tunnel of session %q", iface, session), "")
+			return
+		}
+	}
+
+	report.add(name, StatusFail, fmt.Sprintf("%s exists but no session uses it, the next deployment can't create it", iface),
+		fmt.Sprintf("remove it with '%s'", wireguard.RemoveInterfaceCommand(iface)))
+}
+
+// checkSubnet checks that no other interface uses the default tunnel subnet.
+func (d *Doctor) checkSubnet(report *Report) {
+	const name = "subnet"
+	network := wireguard.DefaultNetwork()
+
+	conflicts, err := d.system.Conflicts(network)
+	if err != nil {
+		report.add(name, StatusWarn, err.Error(), "")
+		return
+	}
+	if len(conflicts) == 0 {
+		report.add(name, StatusOK, fmt.Sprintf("%s is not used by other interfaces", network.Subnet()), "")
+		return
+	}
+
+	var used []string
+	for _, c := range conflicts {
+		used = append(used, fmt.Sprintf("%s (%s)", c.Interface, c.Address))
+	}
+	report.add(name, StatusFail,
+		fmt.Sprintf("%s overlaps %s, tunnel traffic would be misrouted", network.Subnet(), strings.Join(used, ", ")),
+		"disconnect the VPN or network that uses this range before deploying")
+}
+
+// checkProviders validates the API key of each configured provider in parallel.
+func (d *Doctor) checkProviders(ctx context.Context, report *Report, cfg *config.Config) {
+	providers := d.providers
+	var setupErrs []Check
+	if providers == nil {
+		if cfg == nil {
+			report.add("providers", StatusSkip, "no configuration", "")
+			return
+		}
+		for _, name := range cfg.ConfiguredProviders() {
+			p, err := registry.NewProvider(name, cfg)
+			if err != nil {
+				setupErrs = append(setupErrs, Check{Name: "provider_" + name, Status: StatusFail, Message: err.Error()})
+				continue
+			}
+			providers = append(providers, p)
+		}
+	}
+
+	checks := make([]Check, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func(i int, p provider.Provider) {
+			defer wg.Done()
+			checks[i] = d.checkProvider(ctx, p)
+		}(i, p)
+	}
+	wg.Wait()
+
+	report.Checks = append(report.Checks, setupErrs...)
+	report.Checks = append(report.Checks, checks...)
+}
+
+// checkProvider validates one provider's API key.
+func (d *Doctor) checkProvider(ctx context.Context, p provider.Provider) Check {
+	check := Check{Name: "provider_" + p.Name()}
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	info, err := p.ValidateAPIKey(ctx)
+	if err != nil {
+		check.Status = StatusFail
+		check.Message = err.Error()
+		if errors.Is(err, provider.ErrAuthenticationFailed) {
+			// Provider names map to their key variables, e.g. vast -> VAST_API_KEY
+			check.Hint = fmt.Sprintf("check %s_API_KEY, the key may be wrong, expired or revoked", strings.ToUpper(p.Name()))
+		} else {
+			check.Hint = "check your network connection, or retry later if the provider is down"
+		}
+		return check
+	}
+
+	check.Status = StatusOK
+	check.Message = "API key valid"
+	if account := describeAccount(info); account != "" {
+		check.Message += " (" + account + ")"
+	}
+	return check
+}
+
+// describeAccount returns the account name and balance, if the provider reports them.
+func describeAccount(info *provider.AccountInfo) string {
+	if info == nil {
+		return ""
+	}
+
+	var parts []string
+	switch {
+	case info.Email != "":
+		parts = append(parts, info.Email)
+	case info.Username != "":
+		parts = append(parts, info.Username)
+	case info.AccountID != "":
+		parts = append(parts, info.AccountID)
+	}
+	if info.Balance != nil {
+		parts = append(parts, strings.TrimSpace(fmt.Sprintf("balance %.2f %s", *info.Balance, info.BalanceCurrency)))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,334 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/mock"
+	"github.com/tmeurs/spinup/internal/wireguard"
+)
+
+// fakeSystem is a System with fixed answers.
+type fakeSystem struct {
+	tools      []wireguard.Tool
+	privileges wireguard.Privileges
+	interfaces map[string]bool
+	conflicts  []wireguard.AddressConflict
+	err        error
+}
+
+func (s *fakeSystem) Tools() []wireguard.Tool          { return s.tools }
+func (s *fakeSystem) Privileges() wireguard.Privileges { return s.privileges }
+func (s *fakeSystem) InterfaceExists(name string) bool { return s.interfaces[name] }
+func (s *fakeSystem) Conflicts(*wireguard.Network) ([]wireguard.AddressConflict, error) {
+	return s.conflicts, s.err
+}
+
+// healthySystem returns a system that passes all local checks.
+func healthySystem() *fakeSystem {
+	return &fakeSystem{
+		tools: []wireguard.Tool{
+			{Name: "ip", Path: "/usr/sbin/ip", Required: true},
+			{Name: "wg", Path: "/usr/bin/wg"},
+		},
+		privileges: wireguard.Privileges{Root: true},
+	}
+}
+
+// setupEnv writes an .env file with the given mode and sets the
+// configuration variables. Settings come from the environment rather than
+// the file, so that loading it doesn't leak into other tests.
+func setupEnv(t *testing.T, mode os.FileMode, vars map[string]string) string {
+	t.Helper()
+
+	for _, key := range []string{
+		"VAST_API_KEY", "LAMBDA_API_KEY", "RUNPOD_API_KEY", "COREWEAVE_API_KEY", "PAPERSPACE_API_KEY",
+		"WIREGUARD_PRIVATE_KEY", "WIREGUARD_PUBLIC_KEY", "OFFER_FILTER",
+	} {
+		t.Setenv(key, vars[key])
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("# spinup test configuration\n"), mode); err != nil {
+		t.Fatalf("failed to write .env: %v", err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatalf("failed to chmod .env: %v", err)
+	}
+	return path
+}
+
+// checkStatuses compares the statuses of the named checks.
+func checkStatuses(t *testing.T, report *Report, want map[string]Status) {
+	t.Helper()
+	for name, status := range want {
+		c := report.Get(name)
+		if c == nil {
+			t.Errorf("check %s missing from report", name)
+			continue
+		}
+		if c.Status != status {
+			t.Errorf("check %s = %s (%s), want %s", name, c.Status, c.Message, status)
+		}
+		if (status == StatusFail || status == StatusWarn) && c.Hint == "" {
+			t.Errorf("check %s has no hint", name)
+		}
+	}
+}
+
+func TestRun_Healthy(t *testing.T) {
+	keys, err := wireguard.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("GenerateKeyPair() error = %v", err)
+	}
+	envPath := setupEnv(t, 0o600, map[string]string{
+		"VAST_API_KEY":          "vast-key",
+		"WIREGUARD_PRIVATE_KEY": keys.PrivateKey,
+		"WIREGUARD_PUBLIC_KEY":  keys.PublicKey,
+	})
+
+	balance := 12.5
+	d := New(
+		WithEnvPath(envPath),
+		WithStateDir(t.TempDir()),
+		WithSystem(healthySystem()),
+		WithProviders(mock.New(mock.WithName("vast"), mock.WithAccountInfo(&provider.AccountInfo{
+			Email: "dev@example.com", Balance: &balance, BalanceCurrency: "USD",
+		}))),
+	)
+	report := d.Run(context.Background())
+
+	if report.Status() != StatusOK {
+		for _, c := range report.Checks {
+			t.Logf("%s: %s %s", c.Name, c.Status, c.Message)
+		}
+		t.Fatalf("Status() = %s, want ok", report.Status())
+	}
+
+	var names []string
+	for _, c := range report.Checks {
+		names = append(names, c.Name)
+	}
+	want := "env_file,config,wireguard_keys,tool_ip,tool_wg,privileges,state,interface,subnet,provider_vast"
+	if got := strings.Join(names, ","); got != want {
+		t.Errorf("checks = %s, want %s", got, want)
+	}
+
+	if msg := report.Get("provider_vast").Message; msg != "API key valid (dev@example.com, balance 12.50 USD)" {
+		t.Errorf("provider_vast message = %q", msg)
+	}
+}
+
+func TestRun_Problems(t *testing.T) {
+	envPath := setupEnv(t, 0o644, map[string]string{
+		"VAST_API_KEY":          "vast-key",
+		"WIREGUARD_PRIVATE_KEY": "not-a-key",
+	})
+
+	stateDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(stateDir, config.StateFileName), []byte("{not json"), 0o600); err != nil {
+		t.Fatalf("failed to write state: %v", err)
+	}
+
+	system := &fakeSystem{
+		tools: []wireguard.Tool{
+			{Name: "ip", Required: true, Hint: "install iproute2"},
+			{Name: "wg", Hint: "install wireguard-tools"},
+		},
+		interfaces: map[string]bool{wireguard.InterfaceName: true},
+		conflicts:  []wireguard.AddressConflict{{Interface: "tun0", Address: "10.13.37.9/24"}},
+	}
+
+	d := New(
+		WithEnvPath(envPath),
+		WithStateDir(stateDir),
+		WithSystem(system),
+		WithProviders(mock.New(mock.WithName("vast"), mock.WithValidateAPIKeyError(provider.ErrAuthenticationFailed))),
+	)
+	report := d.Run(context.Background())
+
+	checkStatuses(t, report, map[string]Status{
+		"env_file":       StatusWarn,
+		"config":         StatusOK,
+		"wireguard_keys": StatusFail,
+		"tool_ip":        StatusFail,
+		"tool_wg":        StatusWarn,
+		"privileges":     StatusFail,
+		"state":          StatusFail,
+		"interface":      StatusFail,
+		"subnet":         StatusFail,
+		"provider_vast":  StatusFail,
+	})
+	if report.Status() != StatusFail {
+		t.Errorf("Status() = %s, want fail", report.Status())
+	}
+
+	if hint := report.Get("env_file").Hint; !strings.Contains(hint, "chmod 600") {
+		t.Errorf("env_file hint = %q, want chmod", hint)
+	}
+	if hint := report.Get("interface").Hint; !strings.Contains(hint, wireguard.RemoveInterfaceCommand(wireguard.InterfaceName)) {
+		t.Errorf("interface hint = %q, want the removal command", hint)
+	}
+	if msg := report.Get("subnet").Message; !strings.Contains(msg, "tun0 (10.13.37.9/24)") {
+		t.Errorf("subnet message = %q, want the conflicting interface", msg)
+	}
+	if hint := report.Get("provider_vast").Hint; !strings.Contains(hint, "VAST_API_KEY") {
+		t.Errorf("provider_vast hint = %q, want the key variable", hint)
+	}
+}
+
+func TestRun_NoEnvFile(t *testing.T) {
+	d := New(
+		WithEnvPath(filepath.Join(t.TempDir(), ".env")),
+		WithStateDir(t.TempDir()),
+		WithSystem(healthySystem()),
+	)
+	report := d.Run(context.Background())
+
+	checkStatuses(t, report, map[string]Status{
+		"env_file":       StatusFail,
+		"config":         StatusSkip,
+		"wireguard_keys": StatusSkip,
+		"providers":      StatusSkip,
+		"privileges":     StatusOK,
+	})
+	if hint := report.Get("env_file").Hint; !strings.Contains(hint, "spinup init") {
+		t.Errorf("env_file hint = %q, want spinup init", hint)
+	}
+}
+
+func TestRun_GlobalConfig(t *testing.T) {
+	setupEnv(t, 0o600, nil)
+	t.Setenv(config.ProfileEnv, "")
+	dir := t.TempDir()
+	global := filepath.Join(dir, "config.toml")
+	data := "default_tier = \"large\"\n\n[profiles.work]\nvast_api_key = \"vast-key\"\n"
+	if err := os.WriteFile(global, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write config.toml: %v", err)
+	}
+
+	d := New(
+		WithEnvPath(filepath.Join(dir, ".env")),
+		WithGlobalConfig(global),
+		WithProfile("work"),
+		WithStateDir(t.TempDir()),
+		WithSystem(healthySystem()),
+		WithProviders(mock.New(mock.WithName("vast"))),
+	)
+	report := d.Run(context.Background())
+
+	checkStatuses(t, report, map[string]Status{"env_file": StatusOK, "config": StatusOK})
+	if msg := report.Get("config").Message; !strings.Contains(msg, "vast") || !strings.Contains(msg, "profile work") {
+		t.Errorf("config message = %q, want vast from profile work", msg)
+	}
+
+	report = New(
+		WithEnvPath(filepath.Join(dir, ".env")),
+		WithGlobalConfig(global),
+		WithProfile("play"),
+		WithStateDir(t.TempDir()),
+		WithSystem(healthySystem()),
+	).Run(context.Background())
+	checkStatuses(t, report, map[string]Status{"config": StatusFail})
+	if hint := report.Get("config").Hint; !strings.Contains(hint, "[profiles.play]") {
+		t.Errorf("config hint = %q, want the profile table", hint)
+	}
+}
+
+func TestRun_NoProviderConfigured(t *testing.T) {
+	envPath := setupEnv(t, 0o600, nil)
+
+	d := New(WithEnvPath(envPath), WithStateDir(t.TempDir()), WithSystem(healthySystem()))
+	report := d.Run(context.Background())
+
+	checkStatuses(t, report, map[string]Status{"config": StatusFail})
+	if !strings.Contains(report.Get("config").Hint, "spinup init") {
+		t.Errorf("config hint = %q, want spinup init", report.Get("config").Hint)
+	}
+	// No providers to validate
+	for _, c := range report.Checks {
+		if strings.HasPrefix(c.Name, "provider_") {
+			t.Errorf("unexpected check %s", c.Name)
+		}
+	}
+}
+
+func TestRun_InterfaceOwnedBySession(t *testing.T) {
+	envPath := setupEnv(t, 0o600, map[string]string{"VAST_API_KEY": "vast-key"})
+
+	stateDir := t.TempDir()
+	sm, err := config.NewStateManager(stateDir, config.WithSession("work"))
+	if err != nil {
+		t.Fatalf("NewStateManager() error = %v", err)
+	}
+	err = sm.SaveState(&config.State{
+		Instance:  &config.InstanceState{ID: "i-1", Provider: "vast"},
+		WireGuard: &config.WireGuardState{InterfaceName: wireguard.InterfaceName},
+	})
+	if err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+
+	system := healthySystem()
+	system.interfaces = map[string]bool{wireguard.InterfaceName: true}
+	d := New(
+		WithEnvPath(envPath),
+		WithStateDir(stateDir),
+		WithSystem(system),
+		WithProviders(mock.New(mock.WithName("vast"))),
+	)
+	report := d.Run(context.Background())
+
+	checkStatuses(t, report, map[string]Status{"interface": StatusOK, "state": StatusOK})
+	if msg := report.Get("interface").Message; !strings.Contains(msg, `session "work"`) {
+		t.Errorf("interface message = %q, want the owning session", msg)
+	}
+	if msg := report.Get("state").Message; msg != "1 session(s), 1 with an instance" {
+		t.Errorf("state message = %q", msg)
+	}
+}
+
+func TestRun_ProviderUnreachable(t *testing.T) {
+	envPath := setupEnv(t, 0o600, map[string]string{"LAMBDA_API_KEY": "lambda-key"})
+
+	d := New(
+		WithEnvPath(envPath),
+		WithStateDir(t.TempDir()),
+		WithSystem(healthySystem()),
+		WithProviders(mock.New(mock.WithName("lambda"), mock.WithValidateAPIKeyError(errors.New("connection refused")))),
+	)
+	report := d.Run(context.Background())
+
+	checkStatuses(t, report, map[string]Status{"provider_lambda": StatusFail})
+	if hint := report.Get("provider_lambda").Hint; !strings.Contains(hint, "network") {
+		t.Errorf("provider_lambda hint = %q, want a network hint", hint)
+	}
+}
+
+func TestReport_Status(t *testing.T) {
+	tests := []struct {
+		statuses []Status
+		want     Status
+	}{
+		{nil, StatusOK},
+		{[]Status{StatusOK, StatusSkip}, StatusOK},
+		{[]Status{StatusOK, StatusWarn}, StatusWarn},
+		{[]Status{StatusWarn, StatusFail, StatusOK}, StatusFail},
+	}
+
+	for _, tt := range tests {
+		r := &Report{}
+		for _, s := range tt.statuses {
+			r.add("check", s, "", "")
+		}
+		if got := r.Status(); got != tt.want {
+			t.Errorf("Status() of %v = %s, want %s", tt.statuses, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package fakecloud
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// routeAdmin registers the control API below /_fakecloud/. It offers the
+// Server's controls over HTTP, so a fake cloud running as a separate process
+// can be scripted too.
+func (s *Server) routeAdmin() {
+	s.mux.HandleFunc("GET /_fakecloud/state", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		state := make(map[string]interface{}, len(s.clouds))
+		for _, name := range s.names() {
+			state[name] = s.clouds[name].snapshot()
+		}
+		writeJSON(w, http.StatusOK, state)
+	})
+
+	s.mux.HandleFunc("PUT /_fakecloud/{provider}/offers", s.admin(func(w http.ResponseWriter, r *http.Request, name string) error {
+		var offers []Offer
+		if err := decodeBody(r, &offers); err != nil {
+			return err
+		}
+		s.SetOffers(name, offers)
+		return nil
+	}))
+
+	s.mux.HandleFunc("PUT /_fakecloud/{provider}/lifecycle", s.admin(func(w http.ResponseWriter, r *http.Request, name string) error {
+		var lc Lifecycle
+		if err := decodeBody(r, &lc); err != nil {
+			return err
+		}
+		s.SetLifecycle(name, lc)
+		return nil
+	}))
+
+	s.mux.HandleFunc("POST /_fakecloud/{provider}/faults", s.admin(func(w http.ResponseWriter, r *http.Request, name string) error {
+		var f Fault
+		if err := decodeBody(r, &f); err != nil {
+			return err
+		}
+		if !f.Op.valid() {
+			return fmt.Errorf("unknown op %q", f.Op)
+		}
+		if f.Status < 400 || f.Status > 599 {
+			return fmt.Errorf("invalid status %d (must be an HTTP error status)", f.Status)
+		}
+		s.FailNext(name, f.Op, f.Status, f.Message)
+		return nil
+	}))
+
+	s.mux.HandleFunc("PUT /_fakecloud/{provider}/down", s.admin(func(w http.ResponseWriter, r *http.Request, name string) error {
+		var req struct {
+			Down bool `json:"down"`
+		}
+		if err := decodeBody(r, &req); err != nil {
+			return err
+		}
+		s.SetDown(name, req.Down)
+		return nil
+	}))
+
+	s.mux.HandleFunc("POST /_fakecloud/{provider}/instances", s.admin(func(w http.ResponseWriter, r *http.Request, name string) error {
+		var inst Instance
+		if err := decodeBody(r, &inst); err != nil {
+			return err
+		}
+		if inst.Status != "" && !inst.Status.valid() {
+			return fmt.Errorf("unknown status %q", inst.Status)
+		}
+		writeJSON(w, http.StatusCreated, map[string]string{"id": s.AddInstance(name, inst)})
+		return nil
+	}))
+
+	s.mux.HandleFunc("PUT /_fakecloud/{provider}/instances/{id}/status", s.admin(func(w http.ResponseWriter, r *http.Request, name string) error {
+		var req struct {
+			Status Status `json:"status"`
+		}
+		if err := decodeBody(r, &req); err != nil {
+			return err
+		}
+		return s.SetInstanceStatus(name, r.PathValue("id"), req.Status)
+	}))
+}
+
+// admin wraps a control API handler for one provider. Unknown providers get
+// 404 and errors of the handler 400; a handler that wrote nothing gets 204.
+func (s *Server) admin(h func(w http.ResponseWriter, r *http.Request, name string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("provider")
+		if _, ok := s.clouds[name]; !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("unknown provider %q", name)})
+			return
+		}
+
+		rw := &recordingWriter{ResponseWriter: w}
+		if err := h(rw, r, name); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if !rw.written {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+// recordingWriter records whether a response was written.
+type recordingWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *recordingWriter) WriteHeader(status int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter.
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(p)
+}
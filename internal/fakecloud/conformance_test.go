@@ -0,0 +1,109 @@
+package fakecloud
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/coreweave"
+	"github.com/tmeurs/spinup/internal/provider/httpx"
+	"github.com/tmeurs/spinup/internal/provider/lambda"
+	"github.com/tmeurs/spinup/internal/provider/paperspace"
+	"github.com/tmeurs/spinup/internal/provider/providertest"
+	"github.com/tmeurs/spinup/internal/provider/registry"
+	"github.com/tmeurs/spinup/internal/provider/runpod"
+	"github.com/tmeurs/spinup/internal/provider/vast"
+)
+
+// conformanceOps maps Provider methods to the operations of a fake cloud.
+var conformanceOps = map[string]Op{
+	"GetOffers":         OpOffers,
+	"CreateInstance":    OpCreate,
+	"GetInstance":       OpGet,
+	"ListInstances":     OpList,
+	"TerminateInstance": OpTerminate,
+	"ValidateAPIKey":    OpAccount,
+}
+
+// conformanceBackend is a provider on a fake cloud, as a providertest.Backend.
+type conformanceBackend struct {
+	ts   *TestServer
+	name string
+}
+
+func (b conformanceBackend) SetInstanceStatus(id string, status provider.InstanceStatus) error {
+	return b.ts.SetInstanceStatus(b.name, id, Status(status))
+}
+
+func (b conformanceBackend) FailNext(operation string, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, provider.ErrAuthenticationFailed):
+		status = http.StatusUnauthorized
+	case errors.Is(err, provider.ErrRateLimited):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, provider.ErrInstanceNotFound):
+		status = http.StatusNotFound
+	}
+	b.ts.FailNext(b.name, conformanceOps[operation], status, "")
+}
+
+// newConformanceClient returns the real client of a provider, talking to ts
+// without retries, so scripted faults reach the client unchanged.
+func newConformanceClient(t *testing.T, ts *TestServer, name string) provider.Provider {
+	t.Helper()
+
+	transport := []httpx.Option{httpx.WithMinInterval(0), httpx.WithMaxAttempts(1), httpx.WithCircuitBreaker(nil)}
+	url := ts.BaseURL(name)
+
+	var p provider.Provider
+	var err error
+	switch name {
+	case registry.ProviderVast:
+		p, err = vast.NewClient("fakecloud", vast.WithBaseURL(url), vast.WithTransportOptions(transport...))
+	case registry.ProviderLambda:
+		p, err = lambda.NewClient("fakecloud", lambda.WithBaseURL(url), lambda.WithTransportOptions(transport...))
+	case registry.ProviderRunPod:
+		p, err = runpod.NewClient("fakecloud", runpod.WithGraphQLURL(url), runpod.WithTransportOptions(transport...))
+	case registry.ProviderCoreWeave:
+		p, err = coreweave.NewClient("fakecloud", coreweave.WithBaseURL(url), coreweave.WithTransportOptions(transport...))
+	case registry.ProviderPaperspace:
+		p, err = paperspace.NewClient("fakecloud", paperspace.WithBaseURL(url), paperspace.WithTransportOptions(transport...))
+	default:
+		t.Fatalf("unknown provider %s", name)
+	}
+	if err != nil {
+		t.Fatalf("NewClient(%s) error = %v", name, err)
+	}
+	return p
+}
+
+func TestClientsConformance(t *testing.T) {
+	for _, name := range registry.AllProviderNames() {
+		t.Run(name, func(t *testing.T) {
+			providertest.RunConformance(t, func(t *testing.T) *providertest.Target {
+				ts := NewTestServer(t)
+				// Statuses hold until the suite changes them.
+				ts.SetLifecycle(name, Lifecycle{BootPolls: math.MaxInt32, StopPolls: math.MaxInt32})
+
+				target := &providertest.Target{
+					Provider: newConformanceClient(t, ts, name),
+					Backend:  conformanceBackend{ts: ts, name: name},
+				}
+				switch name {
+				case registry.ProviderVast:
+					// Vast.ai IDs are numeric.
+					target.MissingInstanceID = "99999999"
+					target.MissingOfferID = "99999999"
+				case registry.ProviderLambda:
+					target.MissingOfferID = "gpu_1x_missing@us-east-1"
+				case registry.ProviderCoreWeave:
+					target.MissingOfferID = "MISSING_GPU@LGA1"
+				}
+				return target
+			})
+		})
+	}
+}
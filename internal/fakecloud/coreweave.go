@@ -0,0 +1,167 @@
+package fakecloud
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tmeurs/spinup/internal/provider/registry"
+)
+
+// coreweaveAPI is the CoreWeave API dialect: bearer tokens and
+// {"error", "message", "status"} error bodies.
+var coreweaveAPI = dialect{
+	name:       registry.ProviderCoreWeave,
+	credential: bearerToken,
+	writeError: func(w http.ResponseWriter, status int, message string) {
+		writeJSON(w, status, map[string]interface{}{
+			"error":   strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_"),
+			"message": message,
+			"status":  status,
+		})
+	},
+}
+
+// routeCoreWeave registers the CoreWeave REST API.
+func (s *Server) routeCoreWeave() {
+	const prefix = "/coreweave/v1"
+
+	s.handle(coreweaveAPI, "GET "+prefix+"/gpu-types", OpOffers, s.coreweaveOffers)
+	s.handle(coreweaveAPI, "POST "+prefix+"/instances", OpCreate, s.coreweaveCreate)
+	s.handle(coreweaveAPI, "GET "+prefix+"/instances", OpList, s.coreweaveList)
+	s.handle(coreweaveAPI, "GET "+prefix+"/instances/{id}", OpGet, s.coreweaveGet)
+	s.handle(coreweaveAPI, "DELETE "+prefix+"/instances/{id}", OpTerminate, s.coreweaveTerminate)
+	s.handle(coreweaveAPI, "GET "+prefix+"/user", OpAccount, func(w http.ResponseWriter, r *http.Request, c *cloud) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":       "fakecloud",
+			"email":    "fakecloud@example.com",
+			"org_name": "fakecloud",
+			"org_id":   "fakecloud-org",
+		})
+	})
+}
+
+// coreweaveOffers lists GPU types with their regions. The prices of a GPU
+// type are those of its first offer.
+func (s *Server) coreweaveOffers(w http.ResponseWriter, r *http.Request, c *cloud) {
+	var data []map[string]interface{}
+	index := make(map[string]int)
+	for _, o := range c.offers {
+		i, ok := index[o.ID]
+		if !ok {
+			i = len(data)
+			index[o.ID] = i
+			data = append(data, map[string]interface{}{
+				"name":                    o.ID,
+				"vram_gb":                 o.VRAM,
+				"on_demand_rate_per_hour": o.OnDemandPrice,
+				"spot_rate_per_hour":      o.SpotPrice,
+				"available":               false,
+				"regions":                 []map[string]interface{}{},
+			})
+		}
+		if !o.Unavailable {
+			data[i]["available"] = true
+		}
+		data[i]["regions"] = append(data[i]["regions"].([]map[string]interface{}), map[string]interface{}{
+			"name":          o.Region,
+			"available":     !o.Unavailable,
+			"spot_capacity": o.SpotPrice > 0,
+		})
+	}
+	if data == nil {
+		data = []map[string]interface{}{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+}
+
+// coreweaveCreate creates an instance.
+func (s *Server) coreweaveCreate(w http.ResponseWriter, r *http.Request, c *cloud) {
+	var req struct {
+		Name      string `json:"name"`
+		GPUType   string `json:"gpu_type"`
+		Region    string `json:"region"`
+		Spot      bool   `json:"spot"`
+		CloudInit string `json:"cloud_init"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		coreweaveAPI.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	offer, ok := c.findOffer(req.GPUType, req.Region)
+	if !ok {
+		coreweaveAPI.writeError(w, http.StatusNotFound, fmt.Sprintf("GPU type %s not found in region %s", req.GPUType, req.Region))
+		return
+	}
+	if offer.Unavailable {
+		coreweaveAPI.writeError(w, http.StatusConflict, "insufficient capacity in region "+req.Region)
+		return
+	}
+	if req.Spot && offer.SpotPrice == 0 {
+		coreweaveAPI.writeError(w, http.StatusConflict, "no spot capacity in region "+req.Region)
+		return
+	}
+
+	inst := s.create(c, offer, req.Spot, req.Name, req.CloudInit)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"id":     inst.ID,
+		"name":   inst.Name,
+		"status": coreweaveStatus(inst.Status),
+	})
+}
+
+// coreweaveGet returns an instance.
+func (s *Server) coreweaveGet(w http.ResponseWriter, r *http.Request, c *cloud) {
+	inst, ok := s.lookup(c, r.PathValue("id"))
+	if !ok {
+		coreweaveAPI.writeError(w, http.StatusNotFound, "instance not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": coreweaveInstance(inst)})
+}
+
+// coreweaveList returns all instances.
+func (s *Server) coreweaveList(w http.ResponseWriter, r *http.Request, c *cloud) {
+	instances := make([]map[string]interface{}, 0)
+	for _, inst := range s.list(c) {
+		instances = append(instances, coreweaveInstance(inst))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": instances})
+}
+
+// coreweaveTerminate deletes an instance.
+func (s *Server) coreweaveTerminate(w http.ResponseWriter, r *http.Request, c *cloud) {
+	inst, ok := c.find(r.PathValue("id"))
+	if !ok {
+		coreweaveAPI.writeError(w, http.StatusNotFound, "instance not found")
+		return
+	}
+	s.terminate(c, inst)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
+
+// coreweaveStatus returns the CoreWeave name of a status.
+func coreweaveStatus(status Status) string {
+	if status == StatusCreating {
+		return "pending"
+	}
+	return string(status)
+}
+
+// coreweaveInstance renders an instance the way CoreWeave reports it.
+func coreweaveInstance(inst *Instance) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           inst.ID,
+		"name":         inst.Name,
+		"status":       coreweaveStatus(inst.Status),
+		"public_ip":    inst.PublicIP,
+		"gpu_type":     inst.OfferID,
+		"gpu_count":    1,
+		"region":       inst.Region,
+		"spot":         inst.Spot,
+		"hourly_rate":  inst.HourlyRate,
+		"created_at":   inst.CreatedAt,
+		"disk_size_gb": 100,
+	}
+}
@@ -0,0 +1,738 @@
+// Package fakecloud is an offline stand-in for the provider APIs spinup talks
+// to. A Server speaks enough of the Vast.ai, Lambda Labs, CoreWeave and
+// Paperspace REST APIs and the RunPod GraphQL API to list offers and to
+// create, get, list and terminate instances, so the real provider clients
+// (and everything built on them, like the Deployer and Stopper) can run
+// against it by pointing their base URLs at it.
+//
+// Instances move through a scriptable lifecycle: a Lifecycle per provider
+// sets how many polls an instance takes to boot or stop and whether booting
+// fails, SetInstanceStatus forces a status (e.g. to simulate a spot
+// interruption), and FailNext makes the next call of an operation fail with
+// an error in the provider's own format.
+//
+// Each provider is served under its own path prefix, see BaseURL. The same
+// controls are available over HTTP below /_fakecloud/ for the
+// spinup-fakecloud binary.
+package fakecloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/provider/registry"
+)
+
+// Status is the provider-independent status of a fake instance. Each
+// provider API reports it in its own vocabulary.
+type Status string
+
+// Instance statuses.
+const (
+	StatusCreating   Status = "creating"
+	StatusRunning    Status = "running"
+	StatusStopping   Status = "stopping"
+	StatusTerminated Status = "terminated"
+	StatusError      Status = "error"
+)
+
+// valid reports whether s is a known status.
+func (s Status) valid() bool {
+	switch s {
+	case StatusCreating, StatusRunning, StatusStopping, StatusTerminated, StatusError:
+		return true
+	default:
+		return false
+	}
+}
+
+// Op is an API operation that can be scripted to fail.
+type Op string
+
+// Operations every provider API supports.
+const (
+	OpOffers    Op = "offers"
+	OpCreate    Op = "create"
+	OpGet       Op = "get"
+	OpList      Op = "list"
+	OpTerminate Op = "terminate"
+	OpAccount   Op = "account"
+)
+
+// OpAPIKeys creates and deletes restricted API keys, on the providers that
+// have them (Vast.ai).
+const OpAPIKeys Op = "api_keys"
+
+// valid reports whether op is a known operation.
+func (op Op) valid() bool {
+	switch op {
+	case OpOffers, OpCreate, OpGet, OpList, OpTerminate, OpAccount, OpAPIKeys:
+		return true
+	default:
+		return false
+	}
+}
+
+// Offer is a rentable GPU listing. Its fields hold the provider's own names,
+// so the client under test does the normalizing.
+type Offer struct {
+	// ID identifies what is rented: the ask ID on Vast.ai, the instance
+	// type on Lambda Labs, the GPU type ID on RunPod, the GPU type on
+	// CoreWeave and the template ID on Paperspace.
+	ID string `json:"id"`
+
+	// GPU is the provider's GPU name, e.g. "A100_80GB" on Vast.ai.
+	GPU string `json:"gpu"`
+
+	// VRAM is the GPU memory in GB.
+	VRAM int `json:"vram"`
+
+	// Region is the provider's region name, e.g. "us-east-1" on Lambda Labs.
+	// On RunPod it is "Secure Cloud" or "Community Cloud".
+	Region string `json:"region"`
+
+	// OnDemandPrice is the on-demand price in USD per hour.
+	OnDemandPrice float64 `json:"on_demand_price"`
+
+	// SpotPrice is the spot price in USD per hour, zero if there is no spot.
+	SpotPrice float64 `json:"spot_price,omitempty"`
+
+	// Unavailable lists the offer without capacity; creating from it fails.
+	Unavailable bool `json:"unavailable,omitempty"`
+}
+
+// Instance is an instance created on the fake cloud.
+type Instance struct {
+	ID         string    `json:"id"`
+	Provider   string    `json:"provider"`
+	OfferID    string    `json:"offer_id"`
+	Name       string    `json:"name"`
+	Status     Status    `json:"status"`
+	PublicIP   string    `json:"public_ip,omitempty"`
+	GPU        string    `json:"gpu"`
+	VRAM       int       `json:"vram"`
+	Region     string    `json:"region"`
+	Spot       bool      `json:"spot"`
+	HourlyRate float64   `json:"hourly_rate"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	// CloudInit is the startup script the instance was created with.
+	CloudInit string `json:"cloud_init,omitempty"`
+
+	// polls counts the polls seen in the current status.
+	polls int
+}
+
+// Lifecycle scripts how instances of a provider move between statuses.
+// The zero value boots and stops instances on the first poll.
+type Lifecycle struct {
+	// BootPolls is how many polls a new instance is still creating.
+	BootPolls int `json:"boot_polls,omitempty"`
+
+	// FailBoot makes instances end up in error instead of running.
+	FailBoot bool `json:"fail_boot,omitempty"`
+
+	// StopPolls is how many polls a terminated instance is still stopping.
+	StopPolls int `json:"stop_polls,omitempty"`
+
+	// ForgetTerminated makes terminated instances disappear, so they are
+	// reported as not found, like Vast.ai and RunPod do.
+	ForgetTerminated bool `json:"forget_terminated,omitempty"`
+}
+
+// Fault is a scripted API error.
+type Fault struct {
+	// Op is the failing operation.
+	Op Op `json:"op"`
+
+	// Status is the HTTP status, e.g. 401, 404, 409 or 503.
+	Status int `json:"status"`
+
+	// Message is the error message, sent in the provider's error format.
+	Message string `json:"message,omitempty"`
+}
+
+// cloud is the state of one provider.
+type cloud struct {
+	name      string
+	offers    []Offer
+	instances map[string]*Instance
+	order     []string
+	lifecycle Lifecycle
+	faults    []Fault
+	calls     map[Op]int
+	down      bool
+
+	// apiKeys are the restricted API keys by ID. They are accepted for
+	// instance lookups and termination only.
+	apiKeys map[string]string
+}
+
+// Server is a fake cloud serving all provider APIs. It is an http.Handler.
+type Server struct {
+	mu     sync.Mutex
+	clouds map[string]*cloud
+	apiKey string
+	nextID int
+	nextIP int
+
+	mux *http.ServeMux
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithAPIKey makes the server accept only this API key. By default any
+// non-empty key is accepted.
+func WithAPIKey(key string) Option {
+	return func(s *Server) {
+		s.apiKey = key
+	}
+}
+
+// WithScenario loads a scenario instead of DefaultScenario.
+func WithScenario(sc *Scenario) Option {
+	return func(s *Server) {
+		s.apply(sc)
+	}
+}
+
+// New creates a Server with the offers of DefaultScenario.
+func New(opts ...Option) *Server {
+	s := &Server{
+		clouds: make(map[string]*cloud),
+		mux:    http.NewServeMux(),
+	}
+	for _, name := range registry.AllProviderNames() {
+		s.clouds[name] = &cloud{
+			name:      name,
+			instances: make(map[string]*Instance),
+			calls:     make(map[Op]int),
+			apiKeys:   make(map[string]string),
+		}
+	}
+	s.apply(DefaultScenario())
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.routeVast()
+	s.routeLambda()
+	s.routeRunPod()
+	s.routeCoreWeave()
+	s.routePaperspace()
+	s.routeAdmin()
+
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// BaseURL returns the API base URL of a provider on a fake cloud served at
+// root, in the form the provider's client expects for WithBaseURL (or
+// WithGraphQLURL for RunPod).
+func BaseURL(root, providerName string) string {
+	root = strings.TrimSuffix(root, "/")
+	switch providerName {
+	case registry.ProviderVast:
+		return root + "/vast/api/v0"
+	case registry.ProviderLambda:
+		return root + "/lambda/api/v1"
+	case registry.ProviderRunPod:
+		return root + "/runpod/graphql"
+	case registry.ProviderCoreWeave:
+		return root + "/coreweave/v1"
+	case registry.ProviderPaperspace:
+		return root + "/paperspace"
+	default:
+		return ""
+	}
+}
+
+// Env returns the environment, as KEY=VALUE pairs, that points spinup at a
+// fake cloud served at root: the API URL of every provider and a placeholder
+// API key for each.
+func Env(root string) []string {
+	var env []string
+	for _, name := range registry.AllProviderNames() {
+		keyVar := registry.GetProviderAPIKeyEnvVar(name)
+		urlVar := strings.TrimSuffix(keyVar, "_KEY") + "_URL"
+		env = append(env, urlVar+"="+BaseURL(root, name), keyVar+"=fakecloud-"+name)
+	}
+	return env
+}
+
+// cloud returns the state of a provider. It panics on unknown providers,
+// which is a mistake in the calling test or scenario.
+func (s *Server) cloud(providerName string) *cloud {
+	c, ok := s.clouds[providerName]
+	if !ok {
+		panic(fmt.Sprintf("fakecloud: unknown provider %q", providerName))
+	}
+	return c
+}
+
+// apply loads a scenario.
+func (s *Server) apply(sc *Scenario) {
+	if sc == nil {
+		return
+	}
+	for name, p := range sc.Providers {
+		c := s.cloud(name)
+		if p.Offers != nil {
+			c.offers = append([]Offer(nil), p.Offers...)
+		}
+		c.lifecycle = p.Lifecycle
+		c.faults = append(c.faults, p.Faults...)
+		for _, inst := range p.Instances {
+			inst := inst
+			inst.Provider = name
+			if inst.Status == "" {
+				inst.Status = StatusRunning
+			}
+			if inst.CreatedAt.IsZero() {
+				inst.CreatedAt = time.Now()
+			}
+			s.addInstance(c, &inst)
+		}
+	}
+}
+
+// SetOffers replaces the offers of a provider.
+func (s *Server) SetOffers(providerName string, offers []Offer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cloud(providerName).offers = append([]Offer(nil), offers...)
+}
+
+// Offers returns the offers of a provider.
+func (s *Server) Offers(providerName string) []Offer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Offer(nil), s.cloud(providerName).offers...)
+}
+
+// SetLifecycle sets how instances of a provider boot and stop.
+func (s *Server) SetLifecycle(providerName string, lc Lifecycle) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cloud(providerName).lifecycle = lc
+}
+
+// FailNext makes the next call of op on a provider fail with an HTTP status
+// and message. Queued faults of the same operation fail consecutive calls.
+func (s *Server) FailNext(providerName string, op Op, status int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.cloud(providerName)
+	c.faults = append(c.faults, Fault{Op: op, Status: status, Message: message})
+}
+
+// SetDown makes every call to a provider fail with 503 Service Unavailable
+// until it is brought back up.
+func (s *Server) SetDown(providerName string, down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cloud(providerName).down = down
+}
+
+// AddInstance adds an existing instance to a provider, e.g. one a test
+// pretends was deployed earlier. Empty fields get defaults; the instance ID
+// is returned.
+func (s *Server) AddInstance(providerName string, inst Instance) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inst.Provider = providerName
+	if inst.Status == "" {
+		inst.Status = StatusRunning
+	}
+	if inst.CreatedAt.IsZero() {
+		inst.CreatedAt = time.Now()
+	}
+	return s.addInstance(s.cloud(providerName), &inst)
+}
+
+// Instance returns a copy of an instance, or false if there is none.
+func (s *Server) Instance(providerName, id string) (Instance, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inst, ok := s.cloud(providerName).instances[id]
+	if !ok {
+		return Instance{}, false
+	}
+	return *inst, true
+}
+
+// Instances returns copies of all instances of a provider, oldest first.
+func (s *Server) Instances(providerName string) []Instance {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.cloud(providerName)
+	instances := make([]Instance, 0, len(c.order))
+	for _, id := range c.order {
+		if inst, ok := c.instances[id]; ok {
+			instances = append(instances, *inst)
+		}
+	}
+	return instances
+}
+
+// SetInstanceStatus forces the status of an instance, e.g. StatusTerminated
+// to simulate a spot interruption. The lifecycle continues from there.
+func (s *Server) SetInstanceStatus(providerName, id string, status Status) error {
+	if !status.valid() {
+		return fmt.Errorf("unknown status %q", status)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inst, ok := s.cloud(providerName).instances[id]
+	if !ok {
+		return fmt.Errorf("%s instance %s not found", providerName, id)
+	}
+	inst.setStatus(status, s)
+	return nil
+}
+
+// APIKeys returns the IDs of the restricted API keys of a provider that
+// have not been deleted, sorted.
+func (s *Server) APIKeys(providerName string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.cloud(providerName)
+	ids := make([]string, 0, len(c.apiKeys))
+	for id := range c.apiKeys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Calls returns how often an operation was called on a provider, including
+// failed calls.
+func (s *Server) Calls(providerName string, op Op) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cloud(providerName).calls[op]
+}
+
+// addInstance stores an instance, assigning an ID if it has none. The
+// caller holds s.mu.
+func (s *Server) addInstance(c *cloud, inst *Instance) string {
+	if inst.ID == "" {
+		s.nextID++
+		inst.ID = instanceID(c.name, s.nextID)
+	}
+	if inst.Status == StatusRunning && inst.PublicIP == "" {
+		inst.PublicIP = s.allocateIP()
+	}
+	if _, exists := c.instances[inst.ID]; !exists {
+		c.order = append(c.order, inst.ID)
+	}
+	c.instances[inst.ID] = inst
+	return inst.ID
+}
+
+// instanceID formats the nth instance ID the way the provider's IDs look.
+func instanceID(providerName string, n int) string {
+	switch providerName {
+	case registry.ProviderVast:
+		return fmt.Sprintf("%d", 9000000+n)
+	case registry.ProviderLambda:
+		return fmt.Sprintf("%032x", 0xfa4e0000+n)
+	case registry.ProviderRunPod:
+		return fmt.Sprintf("fakepod%06d", n)
+	case registry.ProviderPaperspace:
+		return fmt.Sprintf("psfake%04d", n)
+	default:
+		return fmt.Sprintf("%s-%06d", providerName, n)
+	}
+}
+
+// allocateIP returns the next public IP from the TEST-NET-3 documentation
+// range. The caller holds s.mu.
+func (s *Server) allocateIP() string {
+	s.nextIP++
+	return fmt.Sprintf("203.0.113.%d", s.nextIP%254+1)
+}
+
+// setStatus moves an instance to a status. The caller holds s.mu.
+func (inst *Instance) setStatus(status Status, s *Server) {
+	inst.Status = status
+	inst.polls = 0
+	switch status {
+	case StatusRunning:
+		if inst.PublicIP == "" {
+			inst.PublicIP = s.allocateIP()
+		}
+	case StatusTerminated, StatusError:
+		inst.PublicIP = ""
+	}
+}
+
+// poll advances an instance along the lifecycle; it is called whenever the
+// instance is looked up. The caller holds s.mu.
+func (s *Server) poll(c *cloud, inst *Instance) {
+	switch inst.Status {
+	case StatusCreating:
+		if inst.polls >= c.lifecycle.BootPolls {
+			if c.lifecycle.FailBoot {
+				inst.setStatus(StatusError, s)
+			} else {
+				inst.setStatus(StatusRunning, s)
+			}
+			return
+		}
+	case StatusStopping:
+		if inst.polls >= c.lifecycle.StopPolls {
+			inst.setStatus(StatusTerminated, s)
+			return
+		}
+	}
+	inst.polls++
+}
+
+// find returns an instance. Forgotten terminated instances are not found.
+// The caller holds s.mu.
+func (c *cloud) find(id string) (*Instance, bool) {
+	inst, ok := c.instances[id]
+	if !ok || (inst.Status == StatusTerminated && c.lifecycle.ForgetTerminated) {
+		return nil, false
+	}
+	return inst, true
+}
+
+// lookup finds an instance and advances its lifecycle, as a poll of the
+// instance does. The caller holds s.mu.
+func (s *Server) lookup(c *cloud, id string) (*Instance, bool) {
+	inst, ok := c.find(id)
+	if ok {
+		s.poll(c, inst)
+	}
+	return inst, ok
+}
+
+// list returns the instances of a provider, oldest first, without
+// forgotten terminated ones. The caller holds s.mu.
+func (s *Server) list(c *cloud) []*Instance {
+	var instances []*Instance
+	for _, id := range c.order {
+		inst := c.instances[id]
+		if inst.Status == StatusTerminated && c.lifecycle.ForgetTerminated {
+			continue
+		}
+		instances = append(instances, inst)
+	}
+	return instances
+}
+
+// create starts a new instance from an offer. The caller holds s.mu.
+func (s *Server) create(c *cloud, offer Offer, spot bool, name, cloudInit string) *Instance {
+	rate := offer.OnDemandPrice
+	if spot {
+		rate = offer.SpotPrice
+	}
+	if name == "" {
+		name = "spinup"
+	}
+
+	inst := &Instance{
+		Provider:   c.name,
+		OfferID:    offer.ID,
+		Name:       name,
+		Status:     StatusCreating,
+		GPU:        offer.GPU,
+		VRAM:       offer.VRAM,
+		Region:     offer.Region,
+		Spot:       spot,
+		HourlyRate: rate,
+		CreatedAt:  time.Now(),
+		CloudInit:  cloudInit,
+	}
+	s.addInstance(c, inst)
+	return inst
+}
+
+// terminate starts terminating an instance. Terminating a terminated
+// instance is a no-op. The caller holds s.mu.
+func (s *Server) terminate(c *cloud, inst *Instance) {
+	switch inst.Status {
+	case StatusTerminated, StatusStopping:
+		return
+	}
+	if c.lifecycle.StopPolls > 0 {
+		inst.setStatus(StatusStopping, s)
+	} else {
+		inst.setStatus(StatusTerminated, s)
+	}
+}
+
+// findOffer returns the offer with an ID, and a region if one is given.
+// The caller holds s.mu.
+func (c *cloud) findOffer(id, region string) (Offer, bool) {
+	for _, o := range c.offers {
+		if o.ID == id && (region == "" || o.Region == region) {
+			return o, true
+		}
+	}
+	return Offer{}, false
+}
+
+// begin records a call of op and returns the fault it should fail with, if
+// any. The caller holds s.mu.
+func (c *cloud) begin(op Op) *Fault {
+	c.calls[op]++
+	if c.down {
+		return &Fault{Op: op, Status: http.StatusServiceUnavailable, Message: "service unavailable"}
+	}
+	for i, f := range c.faults {
+		if f.Op == op {
+			c.faults = append(c.faults[:i:i], c.faults[i+1:]...)
+			return &f
+		}
+	}
+	return nil
+}
+
+// snapshot returns the public state of a provider for the admin API. The
+// caller holds s.mu.
+func (c *cloud) snapshot() map[string]interface{} {
+	instances := make([]Instance, 0, len(c.order))
+	for _, id := range c.order {
+		instances = append(instances, *c.instances[id])
+	}
+	calls := make(map[string]int, len(c.calls))
+	for op, n := range c.calls {
+		calls[string(op)] = n
+	}
+	return map[string]interface{}{
+		"offers":    c.offers,
+		"instances": instances,
+		"lifecycle": c.lifecycle,
+		"faults":    c.faults,
+		"calls":     calls,
+		"down":      c.down,
+		"api_keys":  len(c.apiKeys),
+	}
+}
+
+// names returns the provider names in a stable order.
+func (s *Server) names() []string {
+	names := make([]string, 0, len(s.clouds))
+	for name := range s.clouds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if v != nil {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+}
+
+// bearerToken returns the token of a bearer Authorization header.
+func bearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// authorized reports whether a credential is accepted.
+func (s *Server) authorized(key string) bool {
+	if s.apiKey != "" {
+		return key == s.apiKey
+	}
+	return key != ""
+}
+
+// dialect is how a provider API authenticates requests and reports errors.
+type dialect struct {
+	name       string
+	credential func(r *http.Request) string
+	writeError func(w http.ResponseWriter, status int, message string)
+}
+
+// handlerFunc handles one operation of a provider API for a provider's state.
+type handlerFunc func(w http.ResponseWriter, r *http.Request, c *cloud)
+
+// handle registers the handler of one operation of a provider API.
+func (s *Server) handle(d dialect, pattern string, op Op, h handlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		s.serve(d, op, w, r, h)
+	})
+}
+
+// serve runs a handler with s.mu held, after the request was authenticated
+// and any scripted fault was answered.
+func (s *Server) serve(d dialect, op Op, w http.ResponseWriter, r *http.Request, h handlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.cloud(d.name)
+	if key := d.credential(r); !s.authorized(key) && !c.restrictedKeyAllows(key, op) {
+		d.writeError(w, http.StatusUnauthorized, "invalid API key")
+		return
+	}
+	if f := c.begin(op); f != nil {
+		d.writeError(w, f.Status, f.message())
+		return
+	}
+	h(w, r, c)
+}
+
+// restrictedKeyAllows reports whether key is a restricted API key and op
+// one of the instance operations it may call.
+func (c *cloud) restrictedKeyAllows(key string, op Op) bool {
+	switch op {
+	case OpGet, OpList, OpTerminate:
+	default:
+		return false
+	}
+	for _, k := range c.apiKeys {
+		if key == k {
+			return true
+		}
+	}
+	return false
+}
+
+// message returns the fault's message, defaulting to the status text.
+func (f *Fault) message() string {
+	if f.Message != "" {
+		return f.Message
+	}
+	return strings.ToLower(http.StatusText(f.Status))
+}
+
+// decodeBody decodes a JSON request body, tolerating an empty one.
+func decodeBody(r *http.Request, v interface{}) error {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read request body: %w", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
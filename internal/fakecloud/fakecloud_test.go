@@ -0,0 +1,432 @@
+package fakecloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/registry"
+)
+
+// newClient returns the real client of a provider, talking to ts.
+func newClient(t *testing.T, ts *TestServer, name string) provider.Provider {
+	t.Helper()
+
+	cfg := &config.Config{}
+	ts.Configure(cfg)
+	p, err := registry.NewProvider(name, cfg)
+	if err != nil {
+		t.Fatalf("NewProvider(%s) error = %v", name, err)
+	}
+	return p
+}
+
+// errorCode returns the code of a provider error, or "" for other errors.
+func errorCode(err error) string {
+	if pe, ok := err.(*provider.ProviderError); ok {
+		return pe.Code
+	}
+	return ""
+}
+
+func TestClientsAgainstFakeCloud(t *testing.T) {
+	for _, name := range registry.AllProviderNames() {
+		t.Run(name, func(t *testing.T) {
+			ts := NewTestServer(t)
+			ts.SetLifecycle(name, Lifecycle{BootPolls: 1})
+			p := newClient(t, ts, name)
+			ctx := context.Background()
+
+			offers, err := p.GetOffers(ctx, provider.OfferFilter{})
+			if err != nil {
+				t.Fatalf("GetOffers() error = %v", err)
+			}
+			if len(offers) == 0 {
+				t.Fatal("GetOffers() returned no offers")
+			}
+			for _, o := range offers {
+				if o.Provider != name {
+					t.Errorf("offer %s Provider = %q, want %q", o.OfferID, o.Provider, name)
+				}
+				if o.VRAM == 0 || o.OnDemandPrice == 0 {
+					t.Errorf("offer %s has VRAM %d and price %v, want both set", o.OfferID, o.VRAM, o.OnDemandPrice)
+				}
+			}
+
+			inst, err := p.CreateInstance(ctx, provider.CreateRequest{
+				OfferID:   offers[0].OfferID,
+				CloudInit: "#cloud-config\n",
+			})
+			if err != nil {
+				t.Fatalf("CreateInstance(%s) error = %v", offers[0].OfferID, err)
+			}
+			if inst.ID == "" {
+				t.Fatal("CreateInstance() returned no instance ID")
+			}
+			if _, ok := ts.Instance(name, inst.ID); !ok {
+				t.Fatalf("instance %s not on the fake cloud", inst.ID)
+			}
+
+			// The instance boots on the second poll.
+			var got *provider.Instance
+			for i := 0; i < 3; i++ {
+				if got, err = p.GetInstance(ctx, inst.ID); err != nil {
+					t.Fatalf("GetInstance() error = %v", err)
+				}
+				if got.Status == provider.InstanceStatusRunning {
+					break
+				}
+			}
+			if got.Status != provider.InstanceStatusRunning {
+				t.Fatalf("GetInstance() Status = %v, want running", got.Status)
+			}
+			if got.PublicIP == "" {
+				t.Error("running instance has no public IP")
+			}
+
+			list, err := p.ListInstances(ctx)
+			if err != nil {
+				t.Fatalf("ListInstances() error = %v", err)
+			}
+			if len(list) != 1 || list[0].ID != inst.ID {
+				t.Errorf("ListInstances() = %v, want only %s", list, inst.ID)
+			}
+
+			if err := p.TerminateInstance(ctx, inst.ID); err != nil {
+				t.Fatalf("TerminateInstance() error = %v", err)
+			}
+			if fake, _ := ts.Instance(name, inst.ID); fake.Status != StatusTerminated {
+				t.Errorf("fake instance status = %v, want terminated", fake.Status)
+			}
+			if err := p.TerminateInstance(ctx, "does-not-exist"); err != nil {
+				t.Errorf("TerminateInstance(unknown) error = %v, want nil (idempotent)", err)
+			}
+
+			status, err := p.GetBillingStatus(ctx, inst.ID)
+			if p.SupportsBillingVerification() {
+				if err != nil || status != provider.BillingStopped {
+					t.Errorf("GetBillingStatus() = %v, %v, want stopped", status, err)
+				}
+			} else if errorCode(err) != provider.ErrBillingNotSupported.Code {
+				t.Errorf("GetBillingStatus() error = %v, want billing_not_supported", err)
+			}
+
+			if _, err := p.ValidateAPIKey(ctx); err != nil {
+				t.Errorf("ValidateAPIKey() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestClientsAgainstFakeCloud_Faults(t *testing.T) {
+	for _, name := range registry.AllProviderNames() {
+		t.Run(name, func(t *testing.T) {
+			ts := NewTestServer(t)
+			p := newClient(t, ts, name)
+			ctx := context.Background()
+
+			offers, err := p.GetOffers(ctx, provider.OfferFilter{})
+			if err != nil || len(offers) == 0 {
+				t.Fatalf("GetOffers() = %d offers, %v", len(offers), err)
+			}
+
+			ts.FailNext(name, OpCreate, http.StatusConflict, "no capacity available")
+			_, err = p.CreateInstance(ctx, provider.CreateRequest{OfferID: offers[0].OfferID})
+			if err == nil {
+				t.Fatal("CreateInstance() error = nil, want capacity error")
+			}
+			if n := len(ts.Instances(name)); n != 0 {
+				t.Errorf("failed create left %d instances", n)
+			}
+
+			// The fault is used up; the next create succeeds.
+			if _, err := p.CreateInstance(ctx, provider.CreateRequest{OfferID: offers[0].OfferID}); err != nil {
+				t.Errorf("CreateInstance() after fault error = %v", err)
+			}
+			if n := ts.Calls(name, OpCreate); n != 2 {
+				t.Errorf("Calls(create) = %d, want 2", n)
+			}
+
+			ts.FailNext(name, OpOffers, http.StatusUnauthorized, "")
+			_, err = p.GetOffers(ctx, provider.OfferFilter{})
+			if errorCode(err) != provider.ErrAuthenticationFailed.Code {
+				t.Errorf("GetOffers() error = %v, want authentication_failed", err)
+			}
+		})
+	}
+}
+
+func TestClientsAgainstFakeCloud_FailBoot(t *testing.T) {
+	for _, name := range registry.AllProviderNames() {
+		t.Run(name, func(t *testing.T) {
+			ts := NewTestServer(t)
+			ts.SetLifecycle(name, Lifecycle{FailBoot: true})
+			p := newClient(t, ts, name)
+			ctx := context.Background()
+
+			offers, err := p.GetOffers(ctx, provider.OfferFilter{})
+			if err != nil || len(offers) == 0 {
+				t.Fatalf("GetOffers() = %d offers, %v", len(offers), err)
+			}
+			inst, err := p.CreateInstance(ctx, provider.CreateRequest{OfferID: offers[0].OfferID})
+			if err != nil {
+				t.Fatalf("CreateInstance() error = %v", err)
+			}
+
+			got, err := p.GetInstance(ctx, inst.ID)
+			if err != nil {
+				t.Fatalf("GetInstance() error = %v", err)
+			}
+			if got.Status != provider.InstanceStatusError {
+				t.Errorf("GetInstance() Status = %v, want error", got.Status)
+			}
+		})
+	}
+}
+
+func TestServer_APIKey(t *testing.T) {
+	ts := NewTestServer(t, WithAPIKey("secret"))
+
+	for _, name := range registry.AllProviderNames() {
+		t.Run(name, func(t *testing.T) {
+			p := newClient(t, ts, name)
+			_, err := p.GetOffers(context.Background(), provider.OfferFilter{})
+			if errorCode(err) != provider.ErrAuthenticationFailed.Code {
+				t.Errorf("GetOffers() with wrong key error = %v, want authentication_failed", err)
+			}
+		})
+	}
+}
+
+func TestServer_RestrictedAPIKey(t *testing.T) {
+	ts := NewTestServer(t, WithAPIKey("secret"))
+	ctx := context.Background()
+	id := ts.AddInstance(registry.ProviderVast, Instance{})
+
+	cfg := &config.Config{VastAPIKey: "secret"}
+	ts.Configure(cfg)
+	p, err := registry.NewProvider(registry.ProviderVast, cfg)
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	issuer, ok := p.(provider.TerminationCredentialIssuer)
+	if !ok {
+		t.Fatal("the Vast.ai client does not issue termination credentials")
+	}
+	cred, err := issuer.IssueTerminationCredential(ctx, "spinup-deadman-test")
+	if err != nil {
+		t.Fatalf("IssueTerminationCredential() error = %v", err)
+	}
+
+	// The restricted key can terminate instances, and nothing else
+	cfg.VastAPIKey = cred.Key
+	restricted, err := registry.NewProvider(registry.ProviderVast, cfg)
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	if _, err := restricted.GetOffers(ctx, provider.OfferFilter{}); errorCode(err) != provider.ErrAuthenticationFailed.Code {
+		t.Errorf("GetOffers() with the restricted key error = %v, want authentication_failed", err)
+	}
+	if err := restricted.TerminateInstance(ctx, id); err != nil {
+		t.Errorf("TerminateInstance() with the restricted key error = %v", err)
+	}
+
+	if err := issuer.RevokeTerminationCredential(ctx, cred.ID); err != nil {
+		t.Fatalf("RevokeTerminationCredential() error = %v", err)
+	}
+	if keys := ts.APIKeys(registry.ProviderVast); len(keys) != 0 {
+		t.Errorf("APIKeys() = %v after revoking, want none", keys)
+	}
+	if _, err := restricted.GetInstance(ctx, id); errorCode(err) != provider.ErrAuthenticationFailed.Code {
+		t.Errorf("GetInstance() with the revoked key error = %v, want authentication_failed", err)
+	}
+}
+
+func TestServer_Lifecycle(t *testing.T) {
+	s := New()
+	c := s.cloud(registry.ProviderVast)
+	s.SetLifecycle(registry.ProviderVast, Lifecycle{BootPolls: 2, StopPolls: 1, ForgetTerminated: true})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inst := s.create(c, c.offers[0], false, "test", "")
+	want := []Status{StatusCreating, StatusCreating, StatusRunning}
+	for i, status := range want {
+		got, ok := s.lookup(c, inst.ID)
+		if !ok {
+			t.Fatalf("poll %d: instance not found", i)
+		}
+		if got.Status != status {
+			t.Errorf("poll %d: status = %v, want %v", i, got.Status, status)
+		}
+	}
+
+	s.terminate(c, inst)
+	if got, _ := s.lookup(c, inst.ID); got.Status != StatusStopping {
+		t.Errorf("status after terminate = %v, want stopping", got.Status)
+	}
+	if got, _ := s.lookup(c, inst.ID); got.Status != StatusTerminated {
+		t.Errorf("status after stop poll = %v, want terminated", got.Status)
+	}
+	if _, ok := s.lookup(c, inst.ID); ok {
+		t.Error("terminated instance found, want it forgotten")
+	}
+}
+
+func TestServer_Admin(t *testing.T) {
+	ts := NewTestServer(t)
+	name := registry.ProviderLambda
+
+	do := func(method, path string, body interface{}) int {
+		t.Helper()
+		data, _ := json.Marshal(body)
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s error = %v", method, path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		body   interface{}
+		want   int
+	}{
+		{"PUT", "/_fakecloud/lambda/offers", []Offer{{ID: "gpu_1x_h100_pcie", GPU: "H100", VRAM: 80, Region: "us-west-1", OnDemandPrice: 2.49}}, http.StatusNoContent},
+		{"PUT", "/_fakecloud/lambda/lifecycle", Lifecycle{BootPolls: 3}, http.StatusNoContent},
+		{"POST", "/_fakecloud/lambda/faults", Fault{Op: OpList, Status: http.StatusTooManyRequests}, http.StatusNoContent},
+		{"POST", "/_fakecloud/lambda/faults", Fault{Op: "reboot", Status: http.StatusConflict}, http.StatusBadRequest},
+		{"POST", "/_fakecloud/lambda/instances", Instance{ID: "abc", GPU: "H100"}, http.StatusCreated},
+		{"PUT", "/_fakecloud/lambda/instances/abc/status", map[string]string{"status": "terminated"}, http.StatusNoContent},
+		{"PUT", "/_fakecloud/lambda/instances/abc/status", map[string]string{"status": "gone"}, http.StatusBadRequest},
+		{"PUT", "/_fakecloud/nimbus/down", map[string]bool{"down": true}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if got := do(tt.method, tt.path, tt.body); got != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, got, tt.want)
+		}
+	}
+
+	if offers := ts.Offers(name); len(offers) != 1 || offers[0].ID != "gpu_1x_h100_pcie" {
+		t.Errorf("Offers() = %v, want the H100 offer", offers)
+	}
+	if inst, ok := ts.Instance(name, "abc"); !ok || inst.Status != StatusTerminated {
+		t.Errorf("Instance(abc) = %v, %v, want terminated", inst, ok)
+	}
+
+	if got := do("PUT", "/_fakecloud/lambda/down", map[string]bool{"down": true}); got != http.StatusNoContent {
+		t.Fatalf("PUT down = %d, want 204", got)
+	}
+	req, _ := http.NewRequest(http.MethodGet, ts.BaseURL(name)+"/instances", nil)
+	req.SetBasicAuth("key", "")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("request to a down provider = %d, want 503", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/_fakecloud/state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var state map[string]struct {
+		Down  bool           `json:"down"`
+		Calls map[string]int `json:"calls"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		t.Fatalf("decoding state: %v", err)
+	}
+	if len(state) != len(registry.AllProviderNames()) {
+		t.Errorf("state has %d providers, want %d", len(state), len(registry.AllProviderNames()))
+	}
+	if !state[name].Down || state[name].Calls["list"] != 1 {
+		t.Errorf("state[%s] = %+v, want down with 1 list call", name, state[name])
+	}
+}
+
+func TestLoadScenario(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			json: `{"providers": {"vast": {"offers": [{"id": "42", "gpu": "H100", "vram": 80, "region": "US", "on_demand_price": 2.5}],
+				"lifecycle": {"boot_polls": 2}, "faults": [{"op": "create", "status": 409}]}}}`,
+		},
+		{name: "unknown provider", json: `{"providers": {"nimbus": {}}}`, wantErr: true},
+		{name: "non-numeric vast offer", json: `{"providers": {"vast": {"offers": [{"id": "abc"}]}}}`, wantErr: true},
+		{name: "unknown op", json: `{"providers": {"lambda": {"faults": [{"op": "reboot", "status": 500}]}}}`, wantErr: true},
+		{name: "non-error status", json: `{"providers": {"lambda": {"faults": [{"op": "list", "status": 200}]}}}`, wantErr: true},
+		{name: "unknown instance status", json: `{"providers": {"runpod": {"instances": [{"status": "zombie"}]}}}`, wantErr: true},
+		{name: "malformed", json: `{"providers": [`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "scenario.json")
+			if err := os.WriteFile(path, []byte(tt.json), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			sc, err := LoadScenario(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadScenario() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			s := New(WithScenario(sc))
+			if offers := s.Offers(registry.ProviderVast); len(offers) != 1 || offers[0].ID != "42" {
+				t.Errorf("vast offers = %v, want the scenario's", offers)
+			}
+			if offers := s.Offers(registry.ProviderLambda); len(offers) == 0 {
+				t.Error("lambda offers empty, want the default ones")
+			}
+		})
+	}
+}
+
+func TestEnv(t *testing.T) {
+	for _, kv := range Env("http://127.0.0.1:8787") {
+		key, value, _ := strings.Cut(kv, "=")
+		t.Setenv(key, value)
+	}
+
+	cfg, err := config.LoadConfigFromEnv()
+	if err != nil {
+		t.Fatalf("config.LoadConfigFromEnv() error = %v", err)
+	}
+	if cfg.VastAPIURL != "http://127.0.0.1:8787/vast/api/v0" {
+		t.Errorf("VastAPIURL = %q", cfg.VastAPIURL)
+	}
+	if cfg.RunPodAPIURL != "http://127.0.0.1:8787/runpod/graphql" {
+		t.Errorf("RunPodAPIURL = %q", cfg.RunPodAPIURL)
+	}
+	if cfg.PaperspaceAPIKey != "fakecloud-paperspace" {
+		t.Errorf("PaperspaceAPIKey = %q", cfg.PaperspaceAPIKey)
+	}
+	providers, err := registry.GetConfiguredProviders(cfg)
+	if err != nil || len(providers) != len(registry.AllProviderNames()) {
+		t.Errorf("GetConfiguredProviders() = %d providers, %v, want all", len(providers), err)
+	}
+}
@@ -0,0 +1,187 @@
+package fakecloud
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/tmeurs/spinup/internal/provider/registry"
+)
+
+// lambdaAPI is the Lambda Labs API dialect: the API key as basic auth user
+// and {"error": {"code", "message"}} error bodies.
+var lambdaAPI = dialect{
+	name: registry.ProviderLambda,
+	credential: func(r *http.Request) string {
+		user, _, _ := r.BasicAuth()
+		return user
+	},
+	writeError: func(w http.ResponseWriter, status int, message string) {
+		code := "global/unknown"
+		switch status {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			code = "global/invalid-api-key"
+		case http.StatusNotFound:
+			code = "global/object-does-not-exist"
+		case http.StatusTooManyRequests:
+			code = "global/quota-exceeded"
+		case http.StatusConflict:
+			code = "instance-operations/launch/insufficient-capacity"
+		}
+		writeJSON(w, status, map[string]interface{}{
+			"error": map[string]string{"code": code, "message": message},
+		})
+	},
+}
+
+// routeLambda registers the Lambda Labs REST API.
+func (s *Server) routeLambda() {
+	const prefix = "/lambda/api/v1"
+
+	s.handle(lambdaAPI, "GET "+prefix+"/instance-types", OpOffers, s.lambdaOffers)
+	s.handle(lambdaAPI, "POST "+prefix+"/instance-operations/launch", OpCreate, s.lambdaCreate)
+	s.handle(lambdaAPI, "GET "+prefix+"/instances", OpList, s.lambdaList)
+	s.handle(lambdaAPI, "GET "+prefix+"/instances/{id}", OpGet, s.lambdaGet)
+	s.handle(lambdaAPI, "POST "+prefix+"/instance-operations/terminate", OpTerminate, s.lambdaTerminate)
+	s.handle(lambdaAPI, "GET "+prefix+"/ssh-keys", OpAccount, func(w http.ResponseWriter, r *http.Request, c *cloud) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": []interface{}{}})
+	})
+}
+
+// lambdaOffers lists instance types with the regions that have capacity.
+func (s *Server) lambdaOffers(w http.ResponseWriter, r *http.Request, c *cloud) {
+	type instanceType struct {
+		InstanceType map[string]interface{} `json:"instance_type"`
+		Regions      []map[string]string    `json:"regions_with_capacity_available"`
+	}
+
+	data := make(map[string]*instanceType)
+	for _, o := range c.offers {
+		t, ok := data[o.ID]
+		if !ok {
+			t = &instanceType{
+				InstanceType: lambdaInstanceType(o.ID, o.GPU, o.VRAM, o.OnDemandPrice),
+				Regions:      []map[string]string{},
+			}
+			data[o.ID] = t
+		}
+		if !o.Unavailable {
+			t.Regions = append(t.Regions, map[string]string{"name": o.Region, "description": o.Region})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+}
+
+// lambdaCreate launches an instance.
+func (s *Server) lambdaCreate(w http.ResponseWriter, r *http.Request, c *cloud) {
+	var req struct {
+		RegionName       string `json:"region_name"`
+		InstanceTypeName string `json:"instance_type_name"`
+		Name             string `json:"name"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		lambdaAPI.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	offer, ok := c.findOffer(req.InstanceTypeName, req.RegionName)
+	if !ok {
+		lambdaAPI.writeError(w, http.StatusNotFound, fmt.Sprintf("Instance type %s not found in region %s", req.InstanceTypeName, req.RegionName))
+		return
+	}
+	if offer.Unavailable {
+		lambdaAPI.writeError(w, http.StatusConflict, "Not enough capacity to fulfill launch request.")
+		return
+	}
+
+	inst := s.create(c, offer, false, req.Name, "")
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"instance_ids": []string{inst.ID}},
+	})
+}
+
+// lambdaGet returns an instance.
+func (s *Server) lambdaGet(w http.ResponseWriter, r *http.Request, c *cloud) {
+	inst, ok := s.lookup(c, r.PathValue("id"))
+	if !ok {
+		lambdaAPI.writeError(w, http.StatusNotFound, "Instance not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": lambdaInstance(inst)})
+}
+
+// lambdaList returns all instances.
+func (s *Server) lambdaList(w http.ResponseWriter, r *http.Request, c *cloud) {
+	instances := make([]map[string]interface{}, 0)
+	for _, inst := range s.list(c) {
+		instances = append(instances, lambdaInstance(inst))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": instances})
+}
+
+// lambdaTerminate terminates instances. Like Lambda Labs, it terminates
+// nothing if any of the instances doesn't exist.
+func (s *Server) lambdaTerminate(w http.ResponseWriter, r *http.Request, c *cloud) {
+	var req struct {
+		InstanceIDs []string `json:"instance_ids"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		lambdaAPI.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var found []*Instance
+	for _, id := range req.InstanceIDs {
+		inst, ok := c.find(id)
+		if !ok {
+			lambdaAPI.writeError(w, http.StatusNotFound, fmt.Sprintf("Instance %s not found", id))
+			return
+		}
+		found = append(found, inst)
+	}
+
+	terminated := make([]map[string]interface{}, 0, len(found))
+	for _, inst := range found {
+		s.terminate(c, inst)
+		terminated = append(terminated, lambdaInstance(inst))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"terminated_instances": terminated},
+	})
+}
+
+// lambdaInstanceType renders an instance type.
+func lambdaInstanceType(name, gpu string, vram int, price float64) map[string]interface{} {
+	return map[string]interface{}{
+		"name":                 name,
+		"description":          fmt.Sprintf("1x %s (%d GB)", gpu, vram),
+		"price_cents_per_hour": int(math.Round(price * 100)),
+		"specs":                map[string]int{"vcpus": 30, "memory_gib": 200, "storage_gib": 512, "gpus": 1},
+	}
+}
+
+// lambdaInstance renders an instance the way Lambda Labs reports it.
+func lambdaInstance(inst *Instance) map[string]interface{} {
+	status := "active"
+	switch inst.Status {
+	case StatusCreating:
+		status = "booting"
+	case StatusStopping:
+		status = "terminating"
+	case StatusTerminated:
+		status = "terminated"
+	case StatusError:
+		status = "unhealthy"
+	}
+
+	return map[string]interface{}{
+		"id":                inst.ID,
+		"name":              inst.Name,
+		"ip":                inst.PublicIP,
+		"status":            status,
+		"ssh_key_names":     []string{},
+		"file_system_names": []string{},
+		"region":            map[string]string{"name": inst.Region, "description": inst.Region},
+		"instance_type":     lambdaInstanceType(inst.OfferID, inst.GPU, inst.VRAM, inst.HourlyRate),
+	}
+}
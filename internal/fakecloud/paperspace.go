@@ -0,0 +1,152 @@
+package fakecloud
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/tmeurs/spinup/internal/provider/registry"
+)
+
+// paperspaceAPI is the Paperspace API dialect: an x-api-key header and
+// {"name", "message", "status"} error bodies.
+var paperspaceAPI = dialect{
+	name: registry.ProviderPaperspace,
+	credential: func(r *http.Request) string {
+		return r.Header.Get("x-api-key")
+	},
+	writeError: func(w http.ResponseWriter, status int, message string) {
+		writeJSON(w, status, map[string]interface{}{
+			"name":    "Error",
+			"message": message,
+			"status":  status,
+		})
+	},
+}
+
+// routePaperspace registers the Paperspace REST API. Paperspace has no
+// billing API, so there is nothing to serve for it.
+func (s *Server) routePaperspace() {
+	const prefix = "/paperspace"
+
+	s.handle(paperspaceAPI, "GET "+prefix+"/templates/getTemplates", OpOffers, s.paperspaceOffers)
+	s.handle(paperspaceAPI, "POST "+prefix+"/machines/createSingleMachinePublic", OpCreate, s.paperspaceCreate)
+	s.handle(paperspaceAPI, "GET "+prefix+"/machines/getMachines", OpList, s.paperspaceList)
+	s.handle(paperspaceAPI, "GET "+prefix+"/machines/getMachinePublic", OpGet, s.paperspaceGet)
+	s.handle(paperspaceAPI, "POST "+prefix+"/machines/{id}/destroyMachine", OpTerminate, s.paperspaceTerminate)
+	s.handle(paperspaceAPI, "GET "+prefix+"/users/getUser", OpAccount, func(w http.ResponseWriter, r *http.Request, c *cloud) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":        "ufakecloud",
+			"email":     "fakecloud@example.com",
+			"firstName": "Fake",
+			"lastName":  "Cloud",
+		})
+	})
+}
+
+// paperspaceOffers lists machine templates.
+func (s *Server) paperspaceOffers(w http.ResponseWriter, r *http.Request, c *cloud) {
+	templates := make([]map[string]interface{}, 0, len(c.offers))
+	for _, o := range c.offers {
+		templates = append(templates, map[string]interface{}{
+			"id":         o.ID,
+			"name":       o.GPU,
+			"label":      o.GPU,
+			"gpuType":    o.GPU,
+			"gpuCount":   1,
+			"ram":        45,
+			"vram":       o.VRAM,
+			"cpuCount":   8,
+			"hourlyRate": o.OnDemandPrice,
+			"available":  !o.Unavailable,
+			"region":     o.Region,
+		})
+	}
+	writeJSON(w, http.StatusOK, templates)
+}
+
+// paperspaceCreate creates a machine from a template.
+func (s *Server) paperspaceCreate(w http.ResponseWriter, r *http.Request, c *cloud) {
+	var req struct {
+		TemplateID    string `json:"templateId"`
+		MachineName   string `json:"machineName"`
+		StartupScript string `json:"startupScript"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		paperspaceAPI.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	offer, ok := c.findOffer(req.TemplateID, "")
+	if !ok {
+		paperspaceAPI.writeError(w, http.StatusNotFound, fmt.Sprintf("template %s not found", req.TemplateID))
+		return
+	}
+	if offer.Unavailable {
+		paperspaceAPI.writeError(w, http.StatusBadRequest, "no capacity available for this machine type")
+		return
+	}
+
+	inst := s.create(c, offer, false, req.MachineName, req.StartupScript)
+	writeJSON(w, http.StatusOK, paperspaceMachine(inst))
+}
+
+// paperspaceGet returns a machine.
+func (s *Server) paperspaceGet(w http.ResponseWriter, r *http.Request, c *cloud) {
+	inst, ok := s.lookup(c, r.URL.Query().Get("machineId"))
+	if !ok {
+		paperspaceAPI.writeError(w, http.StatusNotFound, "machine not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, paperspaceMachine(inst))
+}
+
+// paperspaceList returns all machines.
+func (s *Server) paperspaceList(w http.ResponseWriter, r *http.Request, c *cloud) {
+	machines := make([]map[string]interface{}, 0)
+	for _, inst := range s.list(c) {
+		machines = append(machines, paperspaceMachine(inst))
+	}
+	writeJSON(w, http.StatusOK, machines)
+}
+
+// paperspaceTerminate destroys a machine.
+func (s *Server) paperspaceTerminate(w http.ResponseWriter, r *http.Request, c *cloud) {
+	inst, ok := c.find(r.PathValue("id"))
+	if !ok {
+		paperspaceAPI.writeError(w, http.StatusNotFound, "machine not found")
+		return
+	}
+	s.terminate(c, inst)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// paperspaceMachine renders an instance the way Paperspace reports a machine.
+func paperspaceMachine(inst *Instance) map[string]interface{} {
+	state := "ready"
+	switch inst.Status {
+	case StatusCreating:
+		state = "provisioning"
+	case StatusStopping:
+		state = "stopping"
+	case StatusTerminated:
+		state = "off"
+	case StatusError:
+		state = "error"
+	}
+
+	return map[string]interface{}{
+		"id":              inst.ID,
+		"name":            inst.Name,
+		"state":           state,
+		"os":              "Ubuntu 22.04",
+		"ram":             "48318382080",
+		"cpus":            8,
+		"gpu":             inst.GPU,
+		"storageTotal":    "107374182400",
+		"usageRate":       fmt.Sprintf("$%.2f/hr", inst.HourlyRate),
+		"publicIpAddress": inst.PublicIP,
+		"region":          inst.Region,
+		"machineType":     inst.OfferID,
+		"dtCreated":       inst.CreatedAt,
+	}
+}
@@ -0,0 +1,246 @@
+package fakecloud
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/provider/registry"
+)
+
+// runpodAPI is the RunPod API dialect: bearer tokens and GraphQL errors.
+// Like RunPod, request errors come back as HTTP 200 with an "errors" list;
+// only rate limiting and server errors use the HTTP status.
+var runpodAPI = dialect{
+	name:       registry.ProviderRunPod,
+	credential: bearerToken,
+	writeError: func(w http.ResponseWriter, status int, message string) {
+		code := "BAD_REQUEST"
+		switch status {
+		case http.StatusUnauthorized:
+			code = "UNAUTHENTICATED"
+		case http.StatusForbidden:
+			code = "FORBIDDEN"
+		case http.StatusNotFound:
+			code = "NOT_FOUND"
+		case http.StatusTooManyRequests:
+			code = "RATE_LIMITED"
+		}
+		if status != http.StatusTooManyRequests && status < 500 {
+			status = http.StatusOK
+		}
+		writeJSON(w, status, map[string]interface{}{
+			"data": nil,
+			"errors": []map[string]interface{}{
+				{"message": message, "extensions": map[string]string{"code": code}},
+			},
+		})
+	},
+}
+
+// runpodRequest is a GraphQL request.
+type runpodRequest struct {
+	Query     string `json:"query"`
+	Variables struct {
+		Input map[string]interface{} `json:"input"`
+	} `json:"variables"`
+}
+
+// routeRunPod registers the RunPod GraphQL API.
+func (s *Server) routeRunPod() {
+	s.mux.HandleFunc("POST /runpod/graphql", func(w http.ResponseWriter, r *http.Request) {
+		var req runpodRequest
+		if err := decodeBody(r, &req); err != nil {
+			runpodAPI.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		op, h := runpodOperation(s, req)
+		if h == nil {
+			runpodAPI.writeError(w, http.StatusBadRequest, "unsupported query")
+			return
+		}
+		s.serve(runpodAPI, op, w, r, func(w http.ResponseWriter, r *http.Request, c *cloud) {
+			h(w, req, c)
+		})
+	})
+}
+
+// runpodOperation picks the handler of a GraphQL request by the field it
+// queries.
+func runpodOperation(s *Server, req runpodRequest) (Op, func(http.ResponseWriter, runpodRequest, *cloud)) {
+	q := req.Query
+	switch {
+	case strings.Contains(q, "gpuTypes"):
+		return OpOffers, s.runpodOffers
+	case strings.Contains(q, "podFindAndDeployOnDemand"):
+		return OpCreate, func(w http.ResponseWriter, req runpodRequest, c *cloud) {
+			s.runpodCreate(w, req, c, false)
+		}
+	case strings.Contains(q, "podRentInterruptable"):
+		return OpCreate, func(w http.ResponseWriter, req runpodRequest, c *cloud) {
+			s.runpodCreate(w, req, c, true)
+		}
+	case strings.Contains(q, "podTerminate"):
+		return OpTerminate, s.runpodTerminate
+	case strings.Contains(q, "pod("):
+		return OpGet, s.runpodGet
+	case strings.Contains(q, "pods"):
+		return OpList, s.runpodList
+	case strings.Contains(q, "myself"):
+		return OpAccount, func(w http.ResponseWriter, req runpodRequest, c *cloud) {
+			runpodData(w, map[string]interface{}{
+				"myself": map[string]interface{}{
+					"id":            "fakecloud",
+					"email":         "fakecloud@example.com",
+					"serverBalance": 100.0,
+				},
+			})
+		}
+	default:
+		return "", nil
+	}
+}
+
+// runpodData writes a successful GraphQL response.
+func runpodData(w http.ResponseWriter, data interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": data})
+}
+
+// runpodOffers lists GPU types with their lowest prices, one per GPU type.
+func (s *Server) runpodOffers(w http.ResponseWriter, req runpodRequest, c *cloud) {
+	seen := make(map[string]bool)
+	gpuTypes := make([]map[string]interface{}, 0, len(c.offers))
+	for _, o := range c.offers {
+		if seen[o.ID] {
+			continue
+		}
+		seen[o.ID] = true
+
+		stock, count := "High", 4
+		if o.Unavailable {
+			stock, count = "unavailable", 0
+		}
+		community := o.Region == "Community Cloud"
+		gpuTypes = append(gpuTypes, map[string]interface{}{
+			"id":             o.ID,
+			"displayName":    o.GPU,
+			"memoryInGb":     o.VRAM,
+			"secureCloud":    !community,
+			"communityCloud": community,
+			"lowestPrice": map[string]interface{}{
+				"minimumBidPrice":      o.SpotPrice,
+				"uninterruptablePrice": o.OnDemandPrice,
+				"stockStatus":          stock,
+				"countAvailable":       count,
+			},
+		})
+	}
+	runpodData(w, map[string]interface{}{"gpuTypes": gpuTypes})
+}
+
+// runpodCreate deploys an on-demand or spot pod.
+func (s *Server) runpodCreate(w http.ResponseWriter, req runpodRequest, c *cloud, spot bool) {
+	input := req.Variables.Input
+	gpuTypeID, _ := input["gpuTypeId"].(string)
+	name, _ := input["name"].(string)
+	dockerArgs, _ := input["dockerArgs"].(string)
+
+	offer, ok := c.findOffer(gpuTypeID, "")
+	if !ok {
+		runpodAPI.writeError(w, http.StatusBadRequest, "Invalid GPU type: "+gpuTypeID)
+		return
+	}
+	if offer.Unavailable || (spot && offer.SpotPrice == 0) {
+		runpodAPI.writeError(w, http.StatusBadRequest, "There are no longer any instances available with the requested specifications. Please refresh and try again.")
+		return
+	}
+
+	inst := s.create(c, offer, spot, name, dockerArgs)
+	field := "podFindAndDeployOnDemand"
+	if spot {
+		field = "podRentInterruptable"
+	}
+	runpodData(w, map[string]interface{}{field: runpodPod(inst)})
+}
+
+// runpodGet returns a pod, or null if there is none.
+func (s *Server) runpodGet(w http.ResponseWriter, req runpodRequest, c *cloud) {
+	podID, _ := req.Variables.Input["podId"].(string)
+	inst, ok := s.lookup(c, podID)
+	if !ok {
+		runpodData(w, map[string]interface{}{"pod": nil})
+		return
+	}
+	runpodData(w, map[string]interface{}{"pod": runpodPod(inst)})
+}
+
+// runpodList returns all pods.
+func (s *Server) runpodList(w http.ResponseWriter, req runpodRequest, c *cloud) {
+	pods := make([]map[string]interface{}, 0)
+	for _, inst := range s.list(c) {
+		pods = append(pods, runpodPod(inst))
+	}
+	runpodData(w, map[string]interface{}{"myself": map[string]interface{}{"pods": pods}})
+}
+
+// runpodTerminate terminates a pod.
+func (s *Server) runpodTerminate(w http.ResponseWriter, req runpodRequest, c *cloud) {
+	podID, _ := req.Variables.Input["podId"].(string)
+	inst, ok := c.find(podID)
+	if !ok {
+		runpodAPI.writeError(w, http.StatusNotFound, "pod not found")
+		return
+	}
+	s.terminate(c, inst)
+	runpodData(w, map[string]interface{}{"podTerminate": nil})
+}
+
+// runpodPod renders an instance the way RunPod reports a pod. Pods only
+// have a runtime, with the public IP, while they run.
+func runpodPod(inst *Instance) map[string]interface{} {
+	status := "RUNNING"
+	switch inst.Status {
+	case StatusCreating:
+		status = "CREATED"
+	case StatusStopping:
+		status = "STOPPING"
+	case StatusTerminated:
+		status = "TERMINATED"
+	case StatusError:
+		status = "ERROR"
+	}
+
+	pod := map[string]interface{}{
+		"id":            inst.ID,
+		"name":          inst.Name,
+		"desiredStatus": status,
+		"imageName":     "runpod/pytorch:latest",
+		"machineId":     "fake" + inst.ID,
+		"machine": map[string]string{
+			"gpuDisplayName": inst.GPU,
+			"location":       inst.Region,
+		},
+		"runtime":    nil,
+		"costPerHr":  inst.HourlyRate,
+		"gpuCount":   1,
+		"volumeInGb": 100,
+	}
+
+	if inst.Status == StatusRunning {
+		uptime := int(time.Since(inst.CreatedAt).Seconds())
+		if uptime < 1 {
+			uptime = 1
+		}
+		pod["runtime"] = map[string]interface{}{
+			"uptimeInSeconds": uptime,
+			"ports": []map[string]interface{}{
+				{"ip": inst.PublicIP, "isIpPublic": true, "privatePort": 22, "publicPort": 22},
+			},
+			"gpus": []map[string]interface{}{
+				{"id": "gpu0", "gpuUtilPercent": 0, "memoryUtilPercent": 0},
+			},
+		}
+	}
+	return pod
+}
@@ -0,0 +1,123 @@
+package fakecloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/tmeurs/spinup/internal/provider/registry"
+)
+
+// Scenario is the initial state of a fake cloud: per provider the offers,
+// the instance lifecycle, faults to fail calls with and existing instances.
+// Scenarios are stored as JSON, see LoadScenario.
+type Scenario struct {
+	Providers map[string]ProviderScenario `json:"providers"`
+}
+
+// ProviderScenario is the initial state of one provider. Nil Offers keep
+// the offers of DefaultScenario; an empty list removes them.
+type ProviderScenario struct {
+	Offers    []Offer    `json:"offers,omitempty"`
+	Lifecycle Lifecycle  `json:"lifecycle,omitempty"`
+	Faults    []Fault    `json:"faults,omitempty"`
+	Instances []Instance `json:"instances,omitempty"`
+}
+
+// DefaultScenario returns the state a new Server starts with: a few A100 and
+// A6000 offers on every provider, in their own naming, that boot and stop on
+// the first poll.
+func DefaultScenario() *Scenario {
+	return &Scenario{
+		Providers: map[string]ProviderScenario{
+			registry.ProviderVast: {
+				Offers: []Offer{
+					{ID: "4000001", GPU: "A100_80GB", VRAM: 80, Region: "US", OnDemandPrice: 1.35, SpotPrice: 0.85},
+					{ID: "4000002", GPU: "RTX_A6000", VRAM: 48, Region: "NL", OnDemandPrice: 0.48, SpotPrice: 0.30},
+					{ID: "4000003", GPU: "A100", VRAM: 40, Region: "US", OnDemandPrice: 0.95},
+				},
+			},
+			registry.ProviderLambda: {
+				Offers: []Offer{
+					{ID: "gpu_1x_a100_sxm4", GPU: "A100", VRAM: 80, Region: "us-east-1", OnDemandPrice: 1.29},
+					{ID: "gpu_1x_a6000", GPU: "A6000", VRAM: 48, Region: "europe-central-1", OnDemandPrice: 0.80},
+				},
+			},
+			registry.ProviderRunPod: {
+				Offers: []Offer{
+					{ID: "NVIDIA A100 80GB PCIe", GPU: "A100 80GB", VRAM: 80, Region: "Secure Cloud", OnDemandPrice: 1.64, SpotPrice: 0.82},
+					{ID: "NVIDIA RTX A6000", GPU: "RTX A6000", VRAM: 48, Region: "Secure Cloud", OnDemandPrice: 0.76, SpotPrice: 0.39},
+				},
+			},
+			registry.ProviderCoreWeave: {
+				Offers: []Offer{
+					{ID: "A100_PCIE_80GB", GPU: "A100_PCIE_80GB", VRAM: 80, Region: "LGA1", OnDemandPrice: 2.21, SpotPrice: 1.10},
+					{ID: "RTX_A6000", GPU: "RTX_A6000", VRAM: 48, Region: "ORD1", OnDemandPrice: 1.28, SpotPrice: 0.64},
+				},
+			},
+			registry.ProviderPaperspace: {
+				Offers: []Offer{
+					{ID: "tfake-a100-80g", GPU: "A100-80G", VRAM: 80, Region: "East Coast (NY2)", OnDemandPrice: 3.18},
+					{ID: "tfake-a6000", GPU: "A6000", VRAM: 48, Region: "Europe (AMS1)", OnDemandPrice: 1.89},
+				},
+			},
+		},
+	}
+}
+
+// LoadScenario reads and validates a JSON scenario file.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+
+	var sc Scenario
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario %s: %w", path, err)
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return &sc, nil
+}
+
+// Validate checks that a scenario only names known providers, operations
+// and statuses.
+func (sc *Scenario) Validate() error {
+	for name, p := range sc.Providers {
+		if !registry.IsValidProviderName(name) {
+			return fmt.Errorf("unknown provider %q", name)
+		}
+		for _, o := range p.Offers {
+			if o.ID == "" {
+				return fmt.Errorf("%s: offer without id", name)
+			}
+			if name == registry.ProviderVast {
+				if _, err := strconv.Atoi(o.ID); err != nil {
+					return fmt.Errorf("%s: offer id %q must be numeric", name, o.ID)
+				}
+			}
+		}
+		for _, f := range p.Faults {
+			if !f.Op.valid() {
+				return fmt.Errorf("%s: unknown op %q", name, f.Op)
+			}
+			if f.Status < 400 || f.Status > 599 {
+				return fmt.Errorf("%s: invalid fault status %d", name, f.Status)
+			}
+		}
+		for _, inst := range p.Instances {
+			if inst.Status != "" && !inst.Status.valid() {
+				return fmt.Errorf("%s: unknown status %q", name, inst.Status)
+			}
+			if name == registry.ProviderVast && inst.ID != "" {
+				if _, err := strconv.Atoi(inst.ID); err != nil {
+					return fmt.Errorf("%s: instance id %q must be numeric", name, inst.ID)
+				}
+			}
+		}
+	}
+	return nil
+}
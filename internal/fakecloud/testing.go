@@ -0,0 +1,56 @@
+package fakecloud
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/provider/registry"
+)
+
+// TestServer is a Server listening on a local port for the duration of a
+// test.
+type TestServer struct {
+	*Server
+
+	// URL is the root URL of the server.
+	URL string
+}
+
+// NewTestServer starts a fake cloud that is shut down when the test ends.
+func NewTestServer(t testing.TB, opts ...Option) *TestServer {
+	t.Helper()
+
+	s := New(opts...)
+	hs := httptest.NewServer(s)
+	t.Cleanup(hs.Close)
+
+	return &TestServer{Server: s, URL: hs.URL}
+}
+
+// BaseURL returns the API base URL of a provider on the server.
+func (ts *TestServer) BaseURL(providerName string) string {
+	return BaseURL(ts.URL, providerName)
+}
+
+// Configure points every provider in cfg at the server and gives providers
+// without an API key a placeholder one, so they all count as configured.
+func (ts *TestServer) Configure(cfg *config.Config) {
+	cfg.VastAPIURL = ts.BaseURL(registry.ProviderVast)
+	cfg.LambdaAPIURL = ts.BaseURL(registry.ProviderLambda)
+	cfg.RunPodAPIURL = ts.BaseURL(registry.ProviderRunPod)
+	cfg.CoreWeaveAPIURL = ts.BaseURL(registry.ProviderCoreWeave)
+	cfg.PaperspaceAPIURL = ts.BaseURL(registry.ProviderPaperspace)
+
+	for _, key := range []*string{
+		&cfg.VastAPIKey,
+		&cfg.LambdaAPIKey,
+		&cfg.RunPodAPIKey,
+		&cfg.CoreWeaveAPIKey,
+		&cfg.PaperspaceAPIKey,
+	} {
+		if *key == "" {
+			*key = "fakecloud"
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package fakecloud
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/tmeurs/spinup/internal/provider/registry"
+)
+
+// vastAPI is the Vast.ai API dialect: bearer tokens and {"success": false}
+// error bodies.
+var vastAPI = dialect{
+	name:       registry.ProviderVast,
+	credential: bearerToken,
+	writeError: func(w http.ResponseWriter, status int, message string) {
+		writeJSON(w, status, map[string]interface{}{"success": false, "error": message})
+	},
+}
+
+// routeVast registers the Vast.ai REST API.
+func (s *Server) routeVast() {
+	const prefix = "/vast/api/v0"
+
+	s.handle(vastAPI, "POST "+prefix+"/bundles/{$}", OpOffers, s.vastOffers)
+	s.handle(vastAPI, "PUT "+prefix+"/asks/{id}/{$}", OpCreate, s.vastCreate)
+	s.handle(vastAPI, "GET "+prefix+"/instances/{$}", OpList, s.vastList)
+	s.handle(vastAPI, "GET "+prefix+"/instances/{id}/{$}", OpGet, s.vastGet)
+	s.handle(vastAPI, "DELETE "+prefix+"/instances/{id}/{$}", OpTerminate, s.vastTerminate)
+	s.handle(vastAPI, "POST "+prefix+"/auth/apikeys/{$}", OpAPIKeys, s.vastCreateAPIKey)
+	s.handle(vastAPI, "DELETE "+prefix+"/auth/apikeys/{id}/{$}", OpAPIKeys, s.vastDeleteAPIKey)
+	s.handle(vastAPI, "GET "+prefix+"/users/current/{$}", OpAccount, func(w http.ResponseWriter, r *http.Request, c *cloud) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"id":       1,
+			"username": "fakecloud",
+			"email":    "fakecloud@example.com",
+			"credit":   100.0,
+		})
+	})
+}
+
+// vastOffers searches offers. It supports the filters the client sends.
+func (s *Server) vastOffers(w http.ResponseWriter, r *http.Request, c *cloud) {
+	var req struct {
+		GPUName  map[string]string  `json:"gpu_name"`
+		GPURam   map[string]float64 `json:"gpu_ram"`
+		DphTotal map[string]float64 `json:"dph_total"`
+		Rentable map[string]bool    `json:"rentable"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		vastAPI.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	matches := make([]Offer, 0, len(c.offers))
+	for _, o := range c.offers {
+		if name, ok := req.GPUName["eq"]; ok && o.GPU != name {
+			continue
+		}
+		if vram, ok := req.GPURam["gte"]; ok && float64(o.VRAM) < vram {
+			continue
+		}
+		if price, ok := req.DphTotal["lte"]; ok && o.OnDemandPrice > price {
+			continue
+		}
+		if rentable, ok := req.Rentable["eq"]; ok && rentable == o.Unavailable {
+			continue
+		}
+		matches = append(matches, o)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].OnDemandPrice < matches[j].OnDemandPrice
+	})
+
+	offers := make([]map[string]interface{}, 0, len(matches))
+	for _, o := range matches {
+		id, _ := strconv.Atoi(o.ID)
+		offers = append(offers, map[string]interface{}{
+			"id":             id,
+			"machine_id":     id + 1000,
+			"gpu_name":       o.GPU,
+			"num_gpus":       1,
+			"gpu_ram":        o.VRAM,
+			"dph_total":      o.OnDemandPrice,
+			"min_bid":        o.SpotPrice,
+			"storage_cost":   0.1,
+			"inet_down_cost": 0.005,
+			"geolocation":    o.Region,
+			"rentable":       !o.Unavailable,
+			"verified":       true,
+			"reliability2":   0.99,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"offers": offers})
+}
+
+// vastCreate accepts an ask, renting the offer.
+func (s *Server) vastCreate(w http.ResponseWriter, r *http.Request, c *cloud) {
+	var req struct {
+		Price   *float64 `json:"price"`
+		Onstart string   `json:"onstart"`
+		Label   string   `json:"label"`
+	}
+	if err := decodeBody(r, &req); err != nil {
+		vastAPI.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	offer, ok := c.findOffer(r.PathValue("id"), "")
+	if !ok || offer.Unavailable {
+		vastAPI.writeError(w, http.StatusNotFound, "no_such_ask")
+		return
+	}
+	spot := req.Price != nil
+	if spot && offer.SpotPrice == 0 {
+		vastAPI.writeError(w, http.StatusBadRequest, "offer does not accept bids")
+		return
+	}
+
+	inst := s.create(c, offer, spot, req.Label, req.Onstart)
+	id, _ := strconv.Atoi(inst.ID)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true, "new_contract": id})
+}
+
+// vastGet returns an instance.
+func (s *Server) vastGet(w http.ResponseWriter, r *http.Request, c *cloud) {
+	inst, ok := s.lookup(c, r.PathValue("id"))
+	if !ok {
+		vastAPI.writeError(w, http.StatusNotFound, "Instance not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, vastInstance(inst))
+}
+
+// vastList returns all instances.
+func (s *Server) vastList(w http.ResponseWriter, r *http.Request, c *cloud) {
+	instances := make([]map[string]interface{}, 0)
+	for _, inst := range s.list(c) {
+		instances = append(instances, vastInstance(inst))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"instances": instances})
+}
+
+// vastTerminate destroys an instance.
+func (s *Server) vastTerminate(w http.ResponseWriter, r *http.Request, c *cloud) {
+	inst, ok := c.find(r.PathValue("id"))
+	if !ok {
+		vastAPI.writeError(w, http.StatusNotFound, "Instance not found")
+		return
+	}
+	s.terminate(c, inst)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
+
+// vastCreateAPIKey creates a restricted API key. Whatever permissions are
+// asked for, the key can only look up and terminate instances.
+func (s *Server) vastCreateAPIKey(w http.ResponseWriter, r *http.Request, c *cloud) {
+	var req struct {
+		Name        string                 `json:"name"`
+		Permissions map[string]interface{} `json:"permissions"`
+	}
+	if err := decodeBody(r, &req); err != nil || len(req.Permissions) == 0 {
+		vastAPI.writeError(w, http.StatusBadRequest, "permissions are required")
+		return
+	}
+
+	s.nextID++
+	id := strconv.Itoa(s.nextID)
+	c.apiKeys[id] = "fakecloud-restricted-" + id
+	writeJSON(w, http.StatusOK, map[string]interface{}{"id": s.nextID, "key": c.apiKeys[id]})
+}
+
+// vastDeleteAPIKey deletes a restricted API key.
+func (s *Server) vastDeleteAPIKey(w http.ResponseWriter, r *http.Request, c *cloud) {
+	id := r.PathValue("id")
+	if _, ok := c.apiKeys[id]; !ok {
+		vastAPI.writeError(w, http.StatusNotFound, "API key not found")
+		return
+	}
+	delete(c.apiKeys, id)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
+
+// vastInstance renders an instance the way Vast.ai reports it.
+func vastInstance(inst *Instance) map[string]interface{} {
+	actual, cur := "running", "running"
+	switch inst.Status {
+	case StatusCreating:
+		actual = "loading"
+	case StatusStopping:
+		actual = "exited"
+	case StatusTerminated:
+		actual, cur = "exited", "stopped"
+	case StatusError:
+		actual = "error"
+	}
+
+	id, _ := strconv.Atoi(inst.ID)
+	return map[string]interface{}{
+		"id":            id,
+		"actual_status": actual,
+		"cur_state":     cur,
+		"public_ipaddr": inst.PublicIP,
+		"gpu_name":      inst.GPU,
+		"num_gpus":      1,
+		"dph_total":     inst.HourlyRate,
+		"start_date":    float64(inst.CreatedAt.Unix()),
+		"geolocation":   inst.Region,
+		"is_bid":        inst.Spot,
+		"label":         inst.Name,
+	}
+}
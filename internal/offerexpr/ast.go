@@ -0,0 +1,319 @@
+package offerexpr
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+// Kind is the type of a field or literal.
+type Kind int
+
+const (
+	KindString Kind = iota
+	KindNumber
+	KindBool
+)
+
+// String returns the name of the kind as used in error messages.
+func (k Kind) String() string {
+	switch k {
+	case KindString:
+		return "string"
+	case KindNumber:
+		return "number"
+	case KindBool:
+		return "bool"
+	}
+	return "unknown"
+}
+
+// Field is an Offer field that filters can refer to.
+type Field struct {
+	Name string
+	Kind Kind
+	Doc  string
+
+	// value returns the field of an offer; ok is false if the offer has no
+	// value, e.g. the spot price of an on-demand-only offer.
+	value func(o *provider.Offer) (v Value, ok bool)
+}
+
+// fields are the fields of the filter language, in documentation order.
+var fields = []*Field{
+	{Name: "provider", Kind: KindString, Doc: "provider name, e.g. vast",
+		value: func(o *provider.Offer) (Value, bool) { return stringValue(o.Provider), true }},
+	{Name: "gpu", Kind: KindString, Doc: "GPU type, e.g. A100 80GB",
+		value: func(o *provider.Offer) (Value, bool) { return stringValue(o.GPU), true }},
+	{Name: "region", Kind: KindString, Doc: "provider region, e.g. EU-West",
+		value: func(o *provider.Offer) (Value, bool) { return stringValue(o.Region), true }},
+	{Name: "vram", Kind: KindNumber, Doc: "GPU memory in GB",
+		value: func(o *provider.Offer) (Value, bool) { return numberValue(float64(o.VRAM)), true }},
+	{Name: "price", Kind: KindNumber, Doc: "hourly price in EUR, spot if available, on-demand otherwise",
+		value: func(o *provider.Offer) (Value, bool) {
+			if o.SpotPrice != nil {
+				return numberValue(*o.SpotPrice), true
+			}
+			return numberValue(o.OnDemandPrice), true
+		}},
+	{Name: "spot", Kind: KindNumber, Doc: "hourly spot price in EUR, missing without spot pricing",
+		value: func(o *provider.Offer) (Value, bool) {
+			if o.SpotPrice == nil {
+				return Value{}, false
+			}
+			return numberValue(*o.SpotPrice), true
+		}},
+	{Name: "on_demand", Kind: KindNumber, Doc: "hourly on-demand price in EUR",
+		value: func(o *provider.Offer) (Value, bool) { return numberValue(o.OnDemandPrice), true }},
+	{Name: "reliability", Kind: KindNumber, Doc: "host reliability from 0 to 1, 0 if unknown",
+		value: func(o *provider.Offer) (Value, bool) { return numberValue(o.Reliability), true }},
+	{Name: "has_spot", Kind: KindBool, Doc: "whether the offer has spot pricing",
+		value: func(o *provider.Offer) (Value, bool) { return boolValue(o.SpotPrice != nil), true }},
+}
+
+// Fields returns the fields filters can refer to.
+func Fields() []Field {
+	out := make([]Field, len(fields))
+	for i, f := range fields {
+		out[i] = *f
+	}
+	return out
+}
+
+// lookupField returns the field with the given name, ignoring case.
+func lookupField(name string) *Field {
+	for _, f := range fields {
+		if strings.EqualFold(f.Name, name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// Value is a literal or field value.
+type Value struct {
+	Kind Kind
+	Str  string
+	Num  float64
+	Bool bool
+}
+
+func stringValue(s string) Value  { return Value{Kind: KindString, Str: s} }
+func numberValue(n float64) Value { return Value{Kind: KindNumber, Num: n} }
+func boolValue(b bool) Value      { return Value{Kind: KindBool, Bool: b} }
+
+// String returns the value as a literal.
+func (v Value) String() string {
+	switch v.Kind {
+	case KindNumber:
+		return strconv.FormatFloat(v.Num, 'f', -1, 64)
+	case KindBool:
+		return strconv.FormatBool(v.Bool)
+	}
+	return strconv.Quote(v.Str)
+}
+
+// Op is a comparison operator.
+type Op string
+
+const (
+	OpEq    Op = "=="
+	OpNe    Op = "!="
+	OpLt    Op = "<"
+	OpLe    Op = "<="
+	OpGt    Op = ">"
+	OpGe    Op = ">="
+	OpMatch Op = "=~"
+)
+
+// Node is a node of a parsed filter expression.
+type Node interface {
+	// Eval reports whether the offer satisfies the expression.
+	Eval(o *provider.Offer) bool
+
+	// String returns the expression in canonical form.
+	String() string
+}
+
+// AndExpr is satisfied if both X and Y are.
+type AndExpr struct {
+	X, Y Node
+}
+
+// Eval implements Node.
+func (e *AndExpr) Eval(o *provider.Offer) bool { return e.X.Eval(o) && e.Y.Eval(o) }
+
+// String implements Node.
+func (e *AndExpr) String() string { return "(" + e.X.String() + " && " + e.Y.String() + ")" }
+
+// OrExpr is satisfied if X or Y is.
+type OrExpr struct {
+	X, Y Node
+}
+
+// Eval implements Node.
+func (e *OrExpr) Eval(o *provider.Offer) bool { return e.X.Eval(o) || e.Y.Eval(o) }
+
+// String implements Node.
+func (e *OrExpr) String() string { return "(" + e.X.String() + " || " + e.Y.String() + ")" }
+
+// NotExpr is satisfied if X isn't.
+type NotExpr struct {
+	X Node
+}
+
+// Eval implements Node.
+func (e *NotExpr) Eval(o *provider.Offer) bool { return !e.X.Eval(o) }
+
+// String implements Node.
+func (e *NotExpr) String() string {
+	switch e.X.(type) {
+	case *AndExpr, *OrExpr, *FieldExpr, *LiteralExpr:
+		return "!" + e.X.String()
+	}
+	return "!(" + e.X.String() + ")"
+}
+
+// LiteralExpr is a constant true or false.
+type LiteralExpr struct {
+	Value bool
+}
+
+// Eval implements Node.
+func (e *LiteralExpr) Eval(*provider.Offer) bool { return e.Value }
+
+// String implements Node.
+func (e *LiteralExpr) String() string { return strconv.FormatBool(e.Value) }
+
+// FieldExpr is a bool field used as a condition, e.g. has_spot.
+type FieldExpr struct {
+	Field *Field
+}
+
+// Eval implements Node.
+func (e *FieldExpr) Eval(o *provider.Offer) bool {
+	v, ok := e.Field.value(o)
+	return ok && v.Bool
+}
+
+// String implements Node.
+func (e *FieldExpr) String() string { return e.Field.Name }
+
+// CompareExpr compares a field with a literal. Strings compare without
+// regard to case. It is not satisfied if the offer has no value for the
+// field, whatever the operator.
+type CompareExpr struct {
+	Field *Field
+	Op    Op
+	Value Value
+}
+
+// Eval implements Node.
+func (e *CompareExpr) Eval(o *provider.Offer) bool {
+	v, ok := e.Field.value(o)
+	if !ok {
+		return false
+	}
+	c := compare(v, e.Value)
+	switch e.Op {
+	case OpEq:
+		return c == 0
+	case OpNe:
+		return c != 0
+	case OpLt:
+		return c < 0
+	case OpLe:
+		return c <= 0
+	case OpGt:
+		return c > 0
+	case OpGe:
+		return c >= 0
+	}
+	return false
+}
+
+// String implements Node.
+func (e *CompareExpr) String() string {
+	return e.Field.Name + " " + string(e.Op) + " " + e.Value.String()
+}
+
+// InExpr is satisfied if a field equals one of the values, or with Not,
+// none of them. Like CompareExpr, it is never satisfied by an offer without
+// a value for the field.
+type InExpr struct {
+	Field  *Field
+	Values []Value
+	Not    bool
+}
+
+// Eval implements Node.
+func (e *InExpr) Eval(o *provider.Offer) bool {
+	v, ok := e.Field.value(o)
+	if !ok {
+		return false
+	}
+	for _, want := range e.Values {
+		if compare(v, want) == 0 {
+			return !e.Not
+		}
+	}
+	return e.Not
+}
+
+// String implements Node.
+func (e *InExpr) String() string {
+	values := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		values[i] = v.String()
+	}
+	op := " in "
+	if e.Not {
+		op = " not in "
+	}
+	return e.Field.Name + op + "[" + strings.Join(values, ", ") + "]"
+}
+
+// MatchExpr is satisfied if a string field matches a regular expression,
+// without regard to case.
+type MatchExpr struct {
+	Field   *Field
+	Pattern string
+	re      *regexp.Regexp
+}
+
+// Eval implements Node.
+func (e *MatchExpr) Eval(o *provider.Offer) bool {
+	v, ok := e.Field.value(o)
+	return ok && e.re.MatchString(v.Str)
+}
+
+// String implements Node.
+func (e *MatchExpr) String() string {
+	return e.Field.Name + " =~ " + strconv.Quote(e.Pattern)
+}
+
+// compare returns -1, 0 or 1 as a is less than, equal to or greater than b,
+// which have the same kind. Strings are compared without regard to case.
+func compare(a, b Value) int {
+	switch a.Kind {
+	case KindNumber:
+		switch {
+		case a.Num < b.Num:
+			return -1
+		case a.Num > b.Num:
+			return 1
+		}
+		return 0
+	case KindBool:
+		if a.Bool == b.Bool {
+			return 0
+		}
+		if !a.Bool {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(strings.ToLower(a.Str), strings.ToLower(b.Str))
+}
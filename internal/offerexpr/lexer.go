@@ -0,0 +1,180 @@
+package offerexpr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a lexical token.
+type tokenKind int
+
+const (
+	tokEOF    tokenKind = iota
+	tokWord             // field name, keyword or bare string
+	tokString           // quoted string
+	tokNumber           // number literal
+	tokLParen           // (
+	tokRParen           // )
+	tokLBrack           // [
+	tokRBrack           // ]
+	tokComma            // ,
+	tokAnd              // && or "and"
+	tokOr               // || or "or"
+	tokNot              // ! or "not"
+	tokOp               // comparison operator, see Op
+)
+
+// token is a lexical token. pos is the 1-based column it starts at.
+type token struct {
+	kind tokenKind
+	text string // word, unquoted string or operator
+	num  float64
+	pos  int
+}
+
+// String returns the token as it is quoted in syntax errors.
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of filter"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// lex splits a filter expression into tokens, ending with tokEOF.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		pos := utf8.RuneCountInString(src[:i]) + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+
+		case r == '"' || r == '\'':
+			text, n, err := lexString(src[i:], r)
+			if err != nil {
+				return nil, &SyntaxError{Pos: pos, Msg: err.Error()}
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, pos: pos})
+			i += n
+			continue
+
+		case r >= '0' && r <= '9' || r == '.':
+			n := lexRun(src[i:], func(r rune) bool { return r >= '0' && r <= '9' || r == '.' })
+			num, err := strconv.ParseFloat(src[i:i+n], 64)
+			if err != nil {
+				return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("invalid number %q", src[i:i+n])}
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[i : i+n], num: num, pos: pos})
+			i += n
+			continue
+
+		case isWordStart(r):
+			n := lexRun(src[i:], isWordPart)
+			word := src[i : i+n]
+			tok := token{kind: tokWord, text: word, pos: pos}
+			switch strings.ToLower(word) {
+			case "and":
+				tok.kind = tokAnd
+			case "or":
+				tok.kind = tokOr
+			case "not":
+				tok.kind = tokNot
+			}
+			tokens = append(tokens, tok)
+			i += n
+			continue
+		}
+
+		// Punctuation and operators, longest first
+		tok := token{pos: pos}
+		switch two := src[i:min(i+2, len(src))]; two {
+		case "&&":
+			tok.kind, tok.text = tokAnd, two
+		case "||":
+			tok.kind, tok.text = tokOr, two
+		case "==", "!=", "<=", ">=", "=~":
+			tok.kind, tok.text = tokOp, two
+		}
+		if tok.text == "" {
+			switch r {
+			case '(':
+				tok.kind = tokLParen
+			case ')':
+				tok.kind = tokRParen
+			case '[':
+				tok.kind = tokLBrack
+			case ']':
+				tok.kind = tokRBrack
+			case ',':
+				tok.kind = tokComma
+			case '!':
+				tok.kind = tokNot
+			case '<', '>':
+				tok.kind = tokOp
+			case '=':
+				return nil, &SyntaxError{Pos: pos, Msg: `unexpected "=", use "==" to compare`}
+			default:
+				return nil, &SyntaxError{Pos: pos, Msg: fmt.Sprintf("unexpected character %q", r)}
+			}
+			tok.text = string(r)
+		}
+		tokens = append(tokens, tok)
+		i += len(tok.text)
+	}
+
+	pos := utf8.RuneCountInString(src) + 1
+	return append(tokens, token{kind: tokEOF, pos: pos}), nil
+}
+
+// lexString reads a string quoted with quote at the start of s. It returns
+// the unquoted text and the number of bytes read. A backslash escapes the
+// next character.
+func lexString(s string, quote rune) (string, int, error) {
+	var b strings.Builder
+	escaped := false
+	for i, r := range s[1:] {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == quote:
+			return b.String(), i + 2, nil
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// lexRun returns the length in bytes of the prefix of s whose runes satisfy f.
+func lexRun(s string, f func(rune) bool) int {
+	for i, r := range s {
+		if !f(r) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// isWordStart reports whether r can start a word.
+func isWordStart(r rune) bool {
+	return unicode.IsLetter(r) || r == '_'
+}
+
+// isWordPart reports whether r can appear in a word. Words may contain
+// hyphens and dots so regions and GPU names need no quotes.
+func isWordPart(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '.'
+}
@@ -0,0 +1,108 @@
+// Package offerexpr implements the offer filter expression language.
+//
+// A filter is a boolean expression over the fields of a provider.Offer:
+//
+//	vram>=48 && region in ["eu-west","us-east"] && provider!="paperspace"
+//	(spot < 0.9 || on_demand < 1.4) && gpu =~ "a100|h100"
+//
+// Conditions compare a field with a literal using ==, !=, <, <=, >, >=,
+// "in [...]", "not in [...]", or =~ (regular expression). They are combined
+// with && (and), || (or), ! (not) and parentheses. Strings may be quoted
+// with " or ', or left bare when they contain no spaces; all string
+// comparisons ignore case. Fields are listed by Fields.
+//
+// A condition on a field the offer has no value for, such as the spot price
+// of an offer without spot pricing, is false.
+package offerexpr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+// SyntaxError describes why a filter could not be parsed.
+type SyntaxError struct {
+	// Pos is the 1-based column of the offending token.
+	Pos int
+
+	// Msg describes the problem.
+	Msg string
+}
+
+// Error implements error.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Pos, e.Msg)
+}
+
+// Filter is a parsed filter expression. A nil *Filter matches every offer.
+type Filter struct {
+	src  string
+	root Node
+}
+
+// Parse parses a filter expression. A blank expression returns a nil
+// Filter, which matches every offer. Errors are *SyntaxError.
+func Parse(src string) (*Filter, error) {
+	src = strings.TrimSpace(src)
+	if src == "" {
+		return nil, nil
+	}
+
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{src: src, root: root}, nil
+}
+
+// MatchAll returns a filter that matches every offer. Unlike a nil Filter,
+// it overrides a default filter such as OFFER_FILTER.
+func MatchAll() *Filter {
+	return &Filter{src: "true", root: &LiteralExpr{Value: true}}
+}
+
+// Root returns the root of the expression's syntax tree.
+func (f *Filter) Root() Node {
+	if f == nil {
+		return &LiteralExpr{Value: true}
+	}
+	return f.root
+}
+
+// String returns the expression as it was parsed.
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.src
+}
+
+// Match reports whether the offer satisfies the filter.
+func (f *Filter) Match(o provider.Offer) bool {
+	if f == nil {
+		return true
+	}
+	return f.root.Eval(&o)
+}
+
+// Apply returns the offers that satisfy the filter, in their original order.
+// Without a filter, offers is returned as is.
+func (f *Filter) Apply(offers []provider.Offer) []provider.Offer {
+	if f == nil {
+		return offers
+	}
+	matched := make([]provider.Offer, 0, len(offers))
+	for i := range offers {
+		if f.root.Eval(&offers[i]) {
+			matched = append(matched, offers[i])
+		}
+	}
+	return matched
+}
@@ -0,0 +1,191 @@
+package offerexpr
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+// testOffers are the offers the evaluation tests filter.
+var testOffers = []provider.Offer{
+	{OfferID: "v1", Provider: "vast", GPU: "A100 80GB", VRAM: 80, Region: "EU-West", SpotPrice: floatPtr(0.85), OnDemandPrice: 1.35, Reliability: 0.99},
+	{OfferID: "l1", Provider: "lambda", GPU: "A6000 48GB", VRAM: 48, Region: "US-East", OnDemandPrice: 0.80},
+	{OfferID: "p1", Provider: "paperspace", GPU: "A6000 48GB", VRAM: 48, Region: "EU-West", OnDemandPrice: 1.89},
+	{OfferID: "r1", Provider: "runpod", GPU: "H100 80GB", VRAM: 80, Region: "US-West", SpotPrice: floatPtr(1.20), OnDemandPrice: 2.40},
+	{OfferID: "c1", Provider: "coreweave", GPU: "A100 40GB", VRAM: 40, Region: "US-East", SpotPrice: floatPtr(0.70), OnDemandPrice: 1.10},
+}
+
+// matchIDs returns the IDs of the test offers matching expr.
+func matchIDs(t *testing.T, expr string) string {
+	t.Helper()
+	f, err := Parse(expr)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", expr, err)
+	}
+	var ids []string
+	for _, o := range f.Apply(testOffers) {
+		ids = append(ids, o.OfferID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"vram >= 48", "v1,l1,p1,r1"},
+		{"vram>=48&&vram<80", "l1,p1"},
+		{`provider != "paperspace"`, "v1,l1,r1,c1"},
+		{"provider != paperspace", "v1,l1,r1,c1"},
+		{`region == "eu-west"`, "v1,p1"},
+		{`region in ["eu-west", "us-east"]`, "v1,l1,p1,c1"},
+		{`region in [eu-west, us-east,]`, "v1,l1,p1,c1"},
+		{`region not in ['eu-west']`, "l1,r1,c1"},
+		{`gpu =~ "^a100"`, "v1,c1"},
+		{`gpu =~ "a100|h100" && vram == 80`, "v1,r1"},
+		{"spot < 0.9", "v1,c1"},
+		{"!(spot < 0.9)", "l1,p1,r1"},
+		{"spot != 0.85", "r1,c1"},
+		{"spot in [0.85, 1.2]", "v1,r1"},
+		{"has_spot", "v1,r1,c1"},
+		{"not has_spot", "l1,p1"},
+		{"has_spot == false", "l1,p1"},
+		{"price <= 0.85", "v1,l1,c1"},
+		{"on_demand > 2", "r1"},
+		{"reliability > 0.9", "v1"},
+		{"vram >= 80 || provider == lambda && region == us-east", "v1,l1,r1"},
+		{"(vram >= 80 || provider == lambda) && region == us-east", "l1"},
+		{"vram >= 80 and not (provider == runpod or spot > 1)", "v1"},
+		{"true", "v1,l1,p1,r1,c1"},
+		{"false", ""},
+		{"VRAM >= 80 AND Provider IN [VAST]", "v1"},
+		// The example from the request
+		{`vram>=48 && region in ["eu-west","us-east"] && provider!="paperspace" && ((has_spot && spot < 0.9) || (!has_spot && on_demand < 1.4))`, "v1,l1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if got := matchIDs(t, tt.expr); got != tt.want {
+				t.Errorf("matched %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_String(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"vram>=48", "vram >= 48"},
+		{"a || b_c", ""},
+		{"vram >= 48 && provider != 'paperspace' || has_spot", `((vram >= 48 && provider != "paperspace") || has_spot)`},
+		{"!(spot < 0.9)", "!(spot < 0.9)"},
+		{"!has_spot && !(vram < 48 || gpu == a6000)", `(!has_spot && !(vram < 48 || gpu == "a6000"))`},
+		{"region not in [eu-west,us-east]", `region not in ["eu-west", "us-east"]`},
+		{`gpu =~ "a100"`, `gpu =~ "a100"`},
+		{"gpu == 4090", `gpu == "4090"`},
+	}
+
+	for _, tt := range tests {
+		f, err := Parse(tt.expr)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("Parse(%q) expected error", tt.expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if got := f.Root().String(); got != tt.want {
+			t.Errorf("Parse(%q).Root() = %s, want %s", tt.expr, got, tt.want)
+		}
+		if f.String() != tt.expr {
+			t.Errorf("Parse(%q).String() = %q, want the source", tt.expr, f.String())
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantPos int
+		wantMsg string
+	}{
+		{"vram >=", 8, "expected a value"},
+		{"vram = 48", 6, `use "=="`},
+		{"cost < 1", 1, `unknown field "cost"`},
+		{"vram >= 48 &&", 14, "expected a field name"},
+		{"vram >= 48 provider == vast", 12, "expected && or ||"},
+		{"(vram >= 48", 12, "expected )"},
+		{"vram", 5, "expected an operator after vram"},
+		{"vram >= big", 9, "vram is a number"},
+		{`provider == "vast`, 13, "unterminated string"},
+		{"has_spot < true", 10, "has_spot is a bool"},
+		{`vram =~ "8"`, 6, "=~ needs a string field"},
+		{`gpu =~ "a100("`, 8, "invalid regular expression"},
+		{"region in []", 12, "empty list"},
+		{"region in [eu west]", 15, "expected , or ]"},
+		{"region in eu", 11, "expected ["},
+		{"vram >= 1.2.3", 9, "invalid number"},
+		{"vram # 1", 6, "unexpected character"},
+		{"€ > 1", 1, "unexpected character"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse(%q) error = %v, want a *SyntaxError", tt.expr, err)
+			}
+			if syntaxErr.Pos != tt.wantPos {
+				t.Errorf("Pos = %d, want %d (%v)", syntaxErr.Pos, tt.wantPos, err)
+			}
+			if !strings.Contains(syntaxErr.Msg, tt.wantMsg) {
+				t.Errorf("Msg = %q, want it to contain %q", syntaxErr.Msg, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestParse_Blank(t *testing.T) {
+	f, err := Parse("   ")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if f != nil {
+		t.Fatalf("Parse() = %v, want nil", f)
+	}
+
+	// A nil filter matches everything
+	if !f.Match(testOffers[0]) {
+		t.Error("nil filter should match every offer")
+	}
+	if got := f.Apply(testOffers); len(got) != len(testOffers) {
+		t.Errorf("nil filter kept %d offers, want %d", len(got), len(testOffers))
+	}
+	if f.String() != "" {
+		t.Errorf("nil filter String() = %q, want empty", f.String())
+	}
+}
+
+func TestFields(t *testing.T) {
+	for _, f := range Fields() {
+		if f.Doc == "" {
+			t.Errorf("field %s has no documentation", f.Name)
+		}
+		if lookupField(f.Name) == nil {
+			t.Errorf("field %s can't be looked up", f.Name)
+		}
+	}
+}
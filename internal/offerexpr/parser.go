@@ -0,0 +1,244 @@
+package offerexpr
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// parser is a recursive descent parser over the tokens of one expression.
+//
+//	or      = and { ("||" | "or") and }
+//	and     = unary { ("&&" | "and") unary }
+//	unary   = ("!" | "not") unary | "(" or ")" | term
+//	term    = "true" | "false" | field [ op value | ["not"] "in" list ]
+//	list    = "[" value { "," value } [","] "]"
+//	value   = string | number | word
+type parser struct {
+	tokens []token
+	i      int
+}
+
+// peek returns the current token.
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+// next returns the current token and advances past it.
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// errorf returns a SyntaxError at token t.
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parse parses the whole expression.
+func (p *parser) parse() (Node, error) {
+	if p.peek().kind == tokEOF {
+		return nil, p.errorf(p.peek(), "empty filter")
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %s, expected && or ||", t)
+	}
+	return n, nil
+}
+
+func (p *parser) parseOr() (Node, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = &OrExpr{X: x, Y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		y, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		x = &AndExpr{X: x, Y: y}
+	}
+	return x, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNot:
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{X: x}, nil
+
+	case tokLParen:
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, p.errorf(closing, "unexpected %s, expected )", closing)
+		}
+		return x, nil
+
+	case tokWord:
+		return p.parseTerm(t)
+	}
+	return nil, p.errorf(t, "unexpected %s, expected a field name", t)
+}
+
+// parseTerm parses a condition on a field, starting at the field name t.
+func (p *parser) parseTerm(t token) (Node, error) {
+	switch strings.ToLower(t.text) {
+	case "true":
+		return &LiteralExpr{Value: true}, nil
+	case "false":
+		return &LiteralExpr{Value: false}, nil
+	}
+
+	field := lookupField(t.text)
+	if field == nil {
+		return nil, p.errorf(t, "unknown field %q (fields: %s)", t.text, fieldNames())
+	}
+
+	switch op := p.peek(); {
+	case op.kind == tokOp:
+		p.next()
+		return p.parseComparison(field, op)
+
+	case op.kind == tokWord && strings.EqualFold(op.text, "in"):
+		p.next()
+		return p.parseIn(field, false)
+
+	case op.kind == tokNot && strings.EqualFold(op.text, "not"):
+		if in := p.tokens[p.i+1]; in.kind == tokWord && strings.EqualFold(in.text, "in") {
+			p.next()
+			p.next()
+			return p.parseIn(field, true)
+		}
+	}
+
+	if field.Kind == KindBool {
+		return &FieldExpr{Field: field}, nil
+	}
+	return nil, p.errorf(p.peek(), "expected an operator after %s", field.Name)
+}
+
+// parseComparison parses the value of a comparison of field with operator op.
+func (p *parser) parseComparison(field *Field, op token) (Node, error) {
+	if Op(op.text) == OpMatch && field.Kind != KindString {
+		return nil, p.errorf(op, "=~ needs a string field, %s is a %s", field.Name, field.Kind)
+	}
+
+	vt := p.peek()
+	v, err := p.parseValue(field)
+	if err != nil {
+		return nil, err
+	}
+
+	switch Op(op.text) {
+	case OpEq, OpNe:
+	case OpLt, OpLe, OpGt, OpGe:
+		if field.Kind == KindBool {
+			return nil, p.errorf(op, "%s is a bool, use == or !=", field.Name)
+		}
+	case OpMatch:
+		re, err := regexp.Compile("(?i)" + v.Str)
+		if err != nil {
+			return nil, p.errorf(vt, "invalid regular expression: %v", err)
+		}
+		return &MatchExpr{Field: field, Pattern: v.Str, re: re}, nil
+	}
+	return &CompareExpr{Field: field, Op: Op(op.text), Value: v}, nil
+}
+
+// parseIn parses the list of an in or not in condition on field.
+func (p *parser) parseIn(field *Field, not bool) (Node, error) {
+	if t := p.next(); t.kind != tokLBrack {
+		return nil, p.errorf(t, "unexpected %s, expected [", t)
+	}
+
+	expr := &InExpr{Field: field, Not: not}
+	for p.peek().kind != tokRBrack {
+		v, err := p.parseValue(field)
+		if err != nil {
+			return nil, err
+		}
+		expr.Values = append(expr.Values, v)
+
+		if t := p.peek(); t.kind == tokComma {
+			p.next()
+		} else if t.kind != tokRBrack {
+			return nil, p.errorf(t, "unexpected %s, expected , or ]", t)
+		}
+	}
+	closing := p.next()
+	if len(expr.Values) == 0 {
+		return nil, p.errorf(closing, "empty list")
+	}
+	return expr, nil
+}
+
+// parseValue parses a literal compared with field and checks its type.
+// Numbers and bare words are accepted as strings for string fields.
+func (p *parser) parseValue(field *Field) (Value, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		if field.Kind == KindString {
+			return stringValue(t.text), nil
+		}
+	case tokNumber:
+		switch field.Kind {
+		case KindNumber:
+			return numberValue(t.num), nil
+		case KindString:
+			return stringValue(t.text), nil
+		}
+	case tokWord:
+		switch {
+		case field.Kind == KindString:
+			return stringValue(t.text), nil
+		case field.Kind == KindBool && strings.EqualFold(t.text, "true"):
+			return boolValue(true), nil
+		case field.Kind == KindBool && strings.EqualFold(t.text, "false"):
+			return boolValue(false), nil
+		}
+	default:
+		return Value{}, p.errorf(t, "unexpected %s, expected a value", t)
+	}
+	return Value{}, p.errorf(t, "%s is a %s, got %s", field.Name, field.Kind, t)
+}
+
+// fieldNames returns the names of all fields for error messages.
+func fieldNames() string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.Name
+	}
+	return strings.Join(names, ", ")
+}
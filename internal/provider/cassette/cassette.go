@@ -0,0 +1,127 @@
+// Package cassette records provider API exchanges into redacted cassette files
+// and replays them, so provider clients can be tested offline against real
+// payloads.
+//
+// A Recorder is an http.RoundTripper that forwards requests to the real API
+// and keeps every exchange, with credentials and secrets redacted. A Replayer
+// is an http.RoundTripper that answers requests from a cassette without
+// touching the network. Both plug into a provider client via its
+// WithHTTPClient option.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cassette is a recorded sequence of HTTP exchanges with one provider.
+type Cassette struct {
+	// Provider is the name of the provider the exchanges were recorded with.
+	Provider string `json:"provider"`
+
+	// RecordedAt is when the recording was made. It is zero for a synthetic
+	// cassette.
+	RecordedAt time.Time `json:"recorded_at,omitzero"`
+
+	// Synthetic marks a cassette written by hand from the provider's API
+	// documentation rather than recorded. Recording replaces it.
+	Synthetic bool `json:"synthetic,omitempty"`
+
+	// Interactions are the exchanges in the order they were made.
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a redacted HTTP request.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+
+	// Body holds a JSON body; Text holds any other body.
+	Body json.RawMessage `json:"body,omitempty"`
+	Text string          `json:"text,omitempty"`
+}
+
+// RecordedResponse is a redacted HTTP response.
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+
+	// Body holds a JSON body; Text holds any other body.
+	Body json.RawMessage `json:"body,omitempty"`
+	Text string          `json:"text,omitempty"`
+}
+
+// Load reads a cassette from a file.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("cassette %s is corrupt: %w", path, err)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to a file, creating its directory if needed.
+// Cassettes are meant to be committed, so the file is world-readable; it
+// must only ever hold redacted exchanges.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	data = append(data, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to rename cassette: %w", err)
+	}
+	return nil
+}
+
+// splitBody stores a body as JSON if it is valid JSON, as text otherwise.
+func splitBody(body []byte) (json.RawMessage, string) {
+	if len(body) == 0 {
+		return nil, ""
+	}
+	if json.Valid(body) {
+		return json.RawMessage(body), ""
+	}
+	return nil, string(body)
+}
+
+// joinBody returns the body stored by splitBody. JSON bodies come back
+// compact, as the cassette file indents them.
+func joinBody(body json.RawMessage, text string) []byte {
+	if len(body) > 0 {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, body); err != nil {
+			return body
+		}
+		return buf.Bytes()
+	}
+	return []byte(text)
+}
@@ -0,0 +1,272 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorder_RecordAndReplay(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		switch r.URL.Path {
+		case "/offers":
+			w.Write([]byte(`{"offers":[{"id":1,"price":0.5}],"email":"me@example.com"}`))
+		case "/create":
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("not found"))
+		}
+	}))
+	defer srv.Close()
+
+	rec := NewRecorder("test", nil)
+	client := &http.Client{Transport: rec}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/offers?api_key=secret&limit=5", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("GET /offers error = %v", err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(got), "me@example.com") {
+		t.Errorf("recorder changed the live response: %s", got)
+	}
+
+	resp, err = client.Post(srv.URL+"/create", "application/json", strings.NewReader(`{"gpu":"A100","cloud_init":"wg private key","nested":{"password":"hunter2"}}`))
+	if err != nil {
+		t.Fatalf("POST /create error = %v", err)
+	}
+	resp.Body.Close()
+
+	resp, err = client.Get(srv.URL + "/missing")
+	if err != nil {
+		t.Fatalf("GET /missing error = %v", err)
+	}
+	resp.Body.Close()
+
+	path := filepath.Join(t.TempDir(), "testdata", "test.json")
+	if err := rec.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if c.Provider != "test" || len(c.Interactions) != 3 {
+		t.Fatalf("cassette = %s with %d interactions, want test with 3", c.Provider, len(c.Interactions))
+	}
+	if c.Synthetic || c.RecordedAt.IsZero() {
+		t.Errorf("cassette synthetic = %v, recorded at %v, want a dated recording", c.Synthetic, c.RecordedAt)
+	}
+
+	// Nothing secret may reach the file
+	raw, _ := json.Marshal(c)
+	for _, secret := range []string{"secret", "me@example.com", "wg private key", "hunter2", "session=abc"} {
+		if bytes.Contains(raw, []byte(secret)) {
+			t.Errorf("cassette contains %q", secret)
+		}
+	}
+	if c.Interactions[2].Response.Text != "not found" {
+		t.Errorf("text body = %q, want %q", c.Interactions[2].Response.Text, "not found")
+	}
+
+	// Replay without the server
+	srv.Close()
+	calls = 0
+	replay := &http.Client{Transport: NewReplayer(c)}
+
+	resp, err = replay.Get(srv.URL + "/offers?limit=5&api_key=other")
+	if err != nil {
+		t.Fatalf("replay GET /offers error = %v", err)
+	}
+	got, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(got), `"price":0.5`) {
+		t.Errorf("replayed response = %d %s", resp.StatusCode, got)
+	}
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("replayed Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+
+	resp, err = replay.Get(srv.URL + "/missing")
+	if err != nil {
+		t.Fatalf("replay GET /missing error = %v", err)
+	}
+	got, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || string(got) != "not found" {
+		t.Errorf("replayed response = %d %s", resp.StatusCode, got)
+	}
+	if calls != 0 {
+		t.Errorf("replay reached the server %d times", calls)
+	}
+}
+
+func TestCassette_Synthetic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "synthetic.json")
+	if err := (&Cassette{Provider: "test", Synthetic: true}).Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// A synthetic cassette must not claim to have been recorded
+	raw, _ := os.ReadFile(path)
+	if bytes.Contains(raw, []byte("recorded_at")) || !bytes.Contains(raw, []byte(`"synthetic": true`)) {
+		t.Errorf("synthetic cassette = %s", raw)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !c.Synthetic || !c.RecordedAt.IsZero() {
+		t.Errorf("Load() synthetic = %v, recorded at %v, want synthetic without a date", c.Synthetic, c.RecordedAt)
+	}
+}
+
+func TestReplayer_Order(t *testing.T) {
+	c := &Cassette{
+		Provider: "test",
+		Interactions: []Interaction{
+			{Request: RecordedRequest{Method: "GET", URL: "https://api.example.com/instances/1"}, Response: RecordedResponse{StatusCode: 200, Text: "creating"}},
+			{Request: RecordedRequest{Method: "GET", URL: "https://api.example.com/instances/2"}, Response: RecordedResponse{StatusCode: 200, Text: "other"}},
+			{Request: RecordedRequest{Method: "GET", URL: "https://api.example.com/instances/1"}, Response: RecordedResponse{StatusCode: 200, Text: "running"}},
+		},
+	}
+	r := NewReplayer(c)
+	client := &http.Client{Transport: r}
+
+	get := func(url string) (string, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body), nil
+	}
+
+	// Repeated requests replay the recorded progression; the host doesn't matter
+	for _, want := range []string{"creating", "running"} {
+		if got, err := get("http://localhost/instances/1"); err != nil || got != want {
+			t.Errorf("GET /instances/1 = %q, %v, want %q", got, err, want)
+		}
+	}
+	if r.Remaining() != 1 {
+		t.Errorf("Remaining() = %d, want 1", r.Remaining())
+	}
+
+	if _, err := get("http://localhost/instances/1"); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("GET after the recording ran out error = %v, want no recorded response", err)
+	}
+	if _, err := get("http://localhost/instances/3"); err == nil {
+		t.Error("GET of an unrecorded path succeeded")
+	}
+}
+
+func TestDefaultMatcher_GraphQL(t *testing.T) {
+	recorded := RecordedRequest{
+		Method: "POST",
+		URL:    "https://api.example.com/graphql",
+		Body:   json.RawMessage(`{"query":"\nquery Pod($input: PodFilter!) {\n  pod(input: $input) { id }\n}\n"}`),
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  bool
+	}{
+		{"same operation", "query Pod($input: PodFilter!) { pod(input: $input) { id name } }", true},
+		{"other operation", "query MyPods { myself { pods { id } } }", false},
+		{"mutation", "mutation Pod($input: PodFilter!) { pod(input: $input) }", false},
+		{"anonymous", "{ pod { id } }", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]string{"query": tt.query})
+			req := httptest.NewRequest(http.MethodPost, "http://localhost/graphql", bytes.NewReader(body))
+			if got := DefaultMatcher(req, body, recorded); got != tt.want {
+				t.Errorf("DefaultMatcher() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGraphQLOperation(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"query":"query GpuTypes {\n gpuTypes { id } }"}`, "query GpuTypes"},
+		{`{"query":"mutation CreatePod($input: X!) { a }"}`, "mutation CreatePod"},
+		{`{"query":"\nquery {\n myself { id } }"}`, "query"},
+		{`{"query":"{ myself { id } }"}`, "query"},
+		{`{"gpu":"A100"}`, ""},
+		{`not json`, ""},
+		{``, ""},
+	}
+
+	for _, tt := range tests {
+		if got := graphQLOperation([]byte(tt.body)); got != tt.want {
+			t.Errorf("graphQLOperation(%s) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestRedaction_Options(t *testing.T) {
+	o := newOptions([]Option{
+		WithRedactedHeaders("X-Team"),
+		WithRedactedParams("sig"),
+		WithRedactedFields("machineId"),
+	})
+
+	h := o.redactHeader(http.Header{"X-Team": {"acme"}, "Accept": {"application/json"}})
+	if h.Get("X-Team") != Redacted || h.Get("Accept") != "application/json" {
+		t.Errorf("redactHeader() = %v", h)
+	}
+
+	if got := o.redactURL("https://api.example.com/x?sig=abc&page=2"); got != "https://api.example.com/x?page=2&sig=REDACTED" {
+		t.Errorf("redactURL() = %q", got)
+	}
+
+	// Numbers keep their precision, empty secrets stay empty
+	got := string(o.redactBody([]byte(`{"items":[{"machineId":"m-1","price":0.4120000001}],"token":""}`)))
+	if got != `{"items":[{"machineId":"REDACTED","price":0.4120000001}],"token":""}` {
+		t.Errorf("redactBody() = %s", got)
+	}
+
+	// Bodies without secrets are kept byte for byte
+	body := `{ "b": 1, "a": 2 }`
+	if got := string(o.redactBody([]byte(body))); got != body {
+		t.Errorf("redactBody() = %s, want unchanged", got)
+	}
+}
+
+func TestValue(t *testing.T) {
+	t.Setenv(RecordEnv, "")
+	t.Setenv("CASSETTE_TEST_KEY", "live-key")
+	if got := Value(t, "CASSETTE_TEST_KEY", "recorded"); got != "recorded" {
+		t.Errorf("Value() while replaying = %q, want recorded", got)
+	}
+
+	t.Setenv(RecordEnv, "1")
+	if got := Value(t, "CASSETTE_TEST_KEY", "recorded"); got != "live-key" {
+		t.Errorf("Value() while recording = %q, want live-key", got)
+	}
+}
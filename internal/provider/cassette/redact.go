@@ -0,0 +1,199 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces every secret in a cassette.
+const Redacted = "REDACTED"
+
+// DefaultRedactedHeaders are the headers that carry credentials.
+var DefaultRedactedHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"X-Api-Key",
+	"Api-Key",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// DefaultRedactedParams are the query parameters that carry credentials.
+var DefaultRedactedParams = []string{
+	"api_key",
+	"apikey",
+	"token",
+	"access_token",
+}
+
+// DefaultRedactedFields are the JSON fields, at any depth, that hold
+// credentials, personal data or startup scripts. Startup scripts are redacted
+// because they carry the instance's WireGuard private key.
+var DefaultRedactedFields = []string{
+	"api_key",
+	"apikey",
+	"token",
+	"access_token",
+	"password",
+	"secret",
+	"email",
+	"jupyter_token",
+	"ssh_key",
+	"ssh_public_key",
+	"onstart",
+	"cloud_init",
+	"startupScript",
+	"dockerArgs",
+	"user_data",
+}
+
+// Option is a functional option for Recorder and Replayer.
+type Option func(*options)
+
+type options struct {
+	headers map[string]bool
+	params  map[string]bool
+	fields  map[string]bool
+	matcher Matcher
+}
+
+// WithRedactedHeaders redacts additional headers.
+func WithRedactedHeaders(names ...string) Option {
+	return func(o *options) {
+		for _, name := range names {
+			o.headers[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// WithRedactedParams redacts additional query parameters.
+func WithRedactedParams(names ...string) Option {
+	return func(o *options) {
+		for _, name := range names {
+			o.params[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// WithRedactedFields redacts additional JSON fields.
+func WithRedactedFields(names ...string) Option {
+	return func(o *options) {
+		for _, name := range names {
+			o.fields[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// WithMatcher sets how a Replayer matches requests to recorded ones.
+func WithMatcher(m Matcher) Option {
+	return func(o *options) {
+		o.matcher = m
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		headers: make(map[string]bool),
+		params:  make(map[string]bool),
+		fields:  make(map[string]bool),
+		matcher: DefaultMatcher,
+	}
+	WithRedactedHeaders(DefaultRedactedHeaders...)(o)
+	WithRedactedParams(DefaultRedactedParams...)(o)
+	WithRedactedFields(DefaultRedactedFields...)(o)
+
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// redactHeader returns a copy of h with credential headers redacted.
+func (o *options) redactHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	out := h.Clone()
+	for name := range out {
+		if o.headers[http.CanonicalHeaderKey(name)] {
+			out[name] = []string{Redacted}
+		}
+	}
+	return out
+}
+
+// redactURL returns rawURL with credential query parameters redacted.
+func (o *options) redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+
+	query := u.Query()
+	changed := false
+	for name := range query {
+		if o.params[strings.ToLower(name)] {
+			query[name] = []string{Redacted}
+			changed = true
+		}
+	}
+	if changed {
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// redactBody returns a JSON body with secret fields redacted. Other bodies
+// are returned unchanged.
+func (o *options) redactBody(body []byte) []byte {
+	if len(body) == 0 || !json.Valid(body) {
+		return body
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+
+	if !o.redactValue(v) {
+		return body
+	}
+	redacted, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// redactValue redacts secret fields in a decoded JSON value in place and
+// reports whether anything was redacted.
+func (o *options) redactValue(v interface{}) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if o.fields[strings.ToLower(key)] {
+				if value != nil && value != "" {
+					v[key] = Redacted
+					changed = true
+				}
+				continue
+			}
+			if o.redactValue(value) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if o.redactValue(value) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
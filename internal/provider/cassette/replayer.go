@@ -0,0 +1,152 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Matcher reports whether a live request, with its body, matches a recorded
+// request. The live request's URL has already been redacted like a recorded one.
+type Matcher func(req *http.Request, body []byte, recorded RecordedRequest) bool
+
+// DefaultMatcher matches requests by method, path and query. GraphQL requests,
+// which all share one URL, also have to match the recorded operation.
+func DefaultMatcher(req *http.Request, body []byte, recorded RecordedRequest) bool {
+	if req.Method != recorded.Method {
+		return false
+	}
+
+	u, err := url.Parse(recorded.URL)
+	if err != nil || u.Path != req.URL.Path || u.Query().Encode() != req.URL.Query().Encode() {
+		return false
+	}
+
+	return graphQLOperation(body) == graphQLOperation(joinBody(recorded.Body, recorded.Text))
+}
+
+// graphQLOperation returns the operation type and name of a GraphQL request
+// body, e.g. "mutation CreatePod", or "" if body isn't a GraphQL request.
+func graphQLOperation(body []byte) string {
+	var req struct {
+		Query string `json:"query"`
+	}
+	if len(body) == 0 || json.Unmarshal(body, &req) != nil || req.Query == "" {
+		return ""
+	}
+
+	query := strings.TrimSpace(req.Query)
+	var kind string
+	for _, k := range []string{"query", "mutation", "subscription"} {
+		if strings.HasPrefix(query, k) {
+			kind = k
+			break
+		}
+	}
+	if kind == "" {
+		// Anonymous query shorthand
+		return "query"
+	}
+
+	rest := strings.TrimSpace(query[len(kind):])
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	if end == -1 {
+		end = len(rest)
+	}
+	if end == 0 {
+		return kind
+	}
+	return kind + " " + rest[:end]
+}
+
+// Replayer is an http.RoundTripper that answers requests from a cassette.
+// Each recorded interaction is played once, in recorded order among the
+// interactions a request matches, so a sequence of identical requests (e.g.
+// polling an instance until it runs) replays the recorded progression.
+type Replayer struct {
+	cassette *Cassette
+	opts     *options
+
+	mu     sync.Mutex
+	played []bool
+}
+
+// NewReplayer creates a Replayer for a cassette.
+func NewReplayer(c *Cassette, opts ...Option) *Replayer {
+	return &Replayer{
+		cassette: c,
+		opts:     newOptions(opts),
+		played:   make([]bool, len(c.Interactions)),
+	}
+}
+
+// RoundTrip implements http.RoundTripper. A request without an unplayed
+// matching interaction fails.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare against the live request the way it would have been recorded
+	redacted := req.Clone(req.Context())
+	if u, err := url.Parse(r.opts.redactURL(req.URL.String())); err == nil {
+		redacted.URL = u
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.played[i] || !r.opts.matcher(redacted, body, interaction.Request) {
+			continue
+		}
+		r.played[i] = true
+		return newResponse(req, interaction.Response), nil
+	}
+
+	return nil, fmt.Errorf("cassette %s: no recorded response for %s %s", r.cassette.Provider, req.Method, redacted.URL.String())
+}
+
+// Remaining returns the number of interactions not played yet.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, played := range r.played {
+		if !played {
+			n++
+		}
+	}
+	return n
+}
+
+// newResponse builds the HTTP response for a recorded one.
+func newResponse(req *http.Request, recorded RecordedResponse) *http.Response {
+	body := joinBody(recorded.Body, recorded.Text)
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
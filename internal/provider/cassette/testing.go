@@ -0,0 +1,59 @@
+package cassette
+
+import (
+	"net/http"
+	"os"
+	"testing"
+)
+
+// RecordEnv is the environment variable that switches tests from replaying
+// cassettes to recording them against the real provider APIs.
+const RecordEnv = "SPINUP_RECORD_CASSETTES"
+
+// Recording returns true if tests should record new cassettes.
+func Recording() bool {
+	v := os.Getenv(RecordEnv)
+	return v != "" && v != "0" && v != "false"
+}
+
+// NewHTTPClient returns the HTTP client a provider client test should use.
+// It replays the cassette at path and fails the test if the cassette can't be
+// loaded. When recording, it talks to the real API instead and saves the
+// redacted exchanges to path when the test ends.
+func NewHTTPClient(t testing.TB, providerName, path string, opts ...Option) *http.Client {
+	t.Helper()
+
+	if Recording() {
+		rec := NewRecorder(providerName, nil, opts...)
+		t.Cleanup(func() {
+			if err := rec.Save(path); err != nil {
+				t.Errorf("failed to save cassette: %v", err)
+				return
+			}
+			t.Logf("recorded %d interactions to %s", len(rec.Cassette().Interactions), path)
+		})
+		return &http.Client{Transport: rec}
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+	return &http.Client{Transport: NewReplayer(c, opts...)}
+}
+
+// Value returns the value of envVar when recording and the recorded value
+// otherwise. It is meant for API keys and for IDs that only exist on the
+// recording account. A test that is recording without envVar set is skipped.
+func Value(t testing.TB, envVar, recorded string) string {
+	t.Helper()
+
+	if !Recording() {
+		return recorded
+	}
+	v := os.Getenv(envVar)
+	if v == "" {
+		t.Skipf("%s is required to record this cassette", envVar)
+	}
+	return v
+}
@@ -0,0 +1,107 @@
+package coreweave
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/cassette"
+	"github.com/tmeurs/spinup/internal/provider/httpx"
+)
+
+// newTestClient returns a client that replays (or records) the named cassette in testdata.
+func newTestClient(t *testing.T, name string) *Client {
+	t.Helper()
+
+	c, err := NewClient(
+		cassette.Value(t, "COREWEAVE_API_KEY", "test-api-key"),
+		WithHTTPClient(cassette.NewHTTPClient(t, "coreweave", filepath.Join("testdata", name+".json"))),
+		WithTransportOptions(httpx.WithMinInterval(0), httpx.WithMaxAttempts(1), httpx.WithCircuitBreaker(nil)),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return c
+}
+
+func TestClient_GetOffers(t *testing.T) {
+	c := newTestClient(t, "get_offers")
+
+	offers, err := c.GetOffers(context.Background(), provider.OfferFilter{})
+	if err != nil {
+		t.Fatalf("GetOffers() error = %v", err)
+	}
+
+	// LGA1 has no A100 capacity
+	if len(offers) != 2 {
+		t.Fatalf("GetOffers() returned %d offers, want 2: %+v", len(offers), offers)
+	}
+
+	a100 := offers[0]
+	if a100.OfferID != "A100_PCIE_80GB@ORD1" || a100.Provider != "coreweave" || a100.GPU != "A100 80GB" || a100.VRAM != 80 || a100.Region != "US-Central" {
+		t.Errorf("offers[0] = %+v", a100)
+	}
+	if a100.OnDemandPrice != 2.21 || a100.SpotPrice == nil || *a100.SpotPrice != 1.10 {
+		t.Errorf("offers[0] prices = %v / %v, want 2.21 / 1.10", a100.OnDemandPrice, a100.SpotPrice)
+	}
+
+	a6000 := offers[1]
+	if a6000.OfferID != "RTX_A6000@LAS1" || a6000.GPU != "A6000 48GB" || a6000.Region != "US-West" || a6000.SpotPrice != nil {
+		t.Errorf("offers[1] = %+v, want on-demand only A6000 in US-West", a6000)
+	}
+}
+
+func TestClient_GetOffers_SpotOnly(t *testing.T) {
+	c := newTestClient(t, "get_offers")
+
+	offers, err := c.GetOffers(context.Background(), provider.OfferFilter{SpotOnly: true})
+	if err != nil {
+		t.Fatalf("GetOffers() error = %v", err)
+	}
+	if len(offers) != 1 || offers[0].OfferID != "A100_PCIE_80GB@ORD1" {
+		t.Errorf("GetOffers(spot only) = %+v, want the ORD1 A100", offers)
+	}
+}
+
+func TestClient_CreateInstance(t *testing.T) {
+	c := newTestClient(t, "create_instance")
+
+	instance, err := c.CreateInstance(context.Background(), provider.CreateRequest{
+		OfferID:      cassette.Value(t, "SPINUP_RECORD_OFFER_ID", "A100_PCIE_80GB@ORD1"),
+		Spot:         true,
+		CloudInit:    "#cloud-config\n",
+		SSHPublicKey: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGr7 spinup",
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance() error = %v", err)
+	}
+
+	if instance.ID != "vs-7f3d9b2c" || instance.Provider != "coreweave" {
+		t.Errorf("instance = %+v", instance)
+	}
+	if instance.Status != provider.InstanceStatusCreating {
+		t.Errorf("Status = %v, want creating", instance.Status)
+	}
+	if instance.GPU != "A100 80GB" || instance.Region != "US-Central" || !instance.Spot || instance.HourlyRate != 1.10 {
+		t.Errorf("instance GPU/region/spot/rate = %s / %s / %v / %v", instance.GPU, instance.Region, instance.Spot, instance.HourlyRate)
+	}
+	if instance.PublicIP != "10.135.4.17" {
+		t.Errorf("PublicIP = %q, want the private IP while no public IP is assigned", instance.PublicIP)
+	}
+}
+
+func TestClient_GetBillingStatus(t *testing.T) {
+	c := newTestClient(t, "get_billing_status")
+	ctx := context.Background()
+
+	status, err := c.GetBillingStatus(ctx, cassette.Value(t, "SPINUP_RECORD_INSTANCE_ID", "vs-7f3d9b2c"))
+	if err != nil || status != provider.BillingActive {
+		t.Errorf("GetBillingStatus(running) = %v, %v, want active", status, err)
+	}
+
+	status, err = c.GetBillingStatus(ctx, cassette.Value(t, "SPINUP_RECORD_GONE_INSTANCE_ID", "vs-1a2b3c4d"))
+	if err != nil || status != provider.BillingStopped {
+		t.Errorf("GetBillingStatus(terminated) = %v, %v, want stopped", status, err)
+	}
+}
@@ -0,0 +1,184 @@
+package httpx
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+const (
+	// DefaultBreakerThreshold is the number of consecutive failures that opens a circuit.
+	DefaultBreakerThreshold = 5
+
+	// DefaultBreakerCooldown is how long an open circuit rejects requests.
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned without contacting the provider while its
+// circuit breaker is open.
+var ErrCircuitOpen = &provider.ProviderError{Code: "circuit_open", Message: "provider temporarily skipped after repeated failures"}
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets all requests through.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen rejects requests until the cooldown has passed.
+	BreakerOpen
+
+	// BreakerHalfOpen lets a single trial request through.
+	BreakerHalfOpen
+)
+
+// String returns the state name.
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker stops sending requests to a provider that keeps failing.
+// After Threshold consecutive failures (server errors or no response) it
+// opens and rejects requests with ErrCircuitOpen for the cooldown. Then one
+// trial request is let through: success closes the circuit, failure opens it
+// again. A nil *CircuitBreaker lets everything through.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     BreakerState
+	openedAt  time.Time
+	trial     bool
+
+	// now returns the current time; replaced in tests.
+	now func() time.Time
+}
+
+// NewCircuitBreaker creates a closed circuit breaker.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*CircuitBreaker)
+)
+
+// BreakerFor returns the circuit breaker shared by all clients of a provider,
+// so a provider that is down is skipped by every caller in the process.
+func BreakerFor(providerName string) *CircuitBreaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	b, ok := breakers[providerName]
+	if !ok {
+		b = NewCircuitBreaker(DefaultBreakerThreshold, DefaultBreakerCooldown)
+		breakers[providerName] = b
+	}
+	return b
+}
+
+// Allow returns ErrCircuitOpen if a request must not be sent.
+func (b *CircuitBreaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		remaining := b.cooldown - b.now().Sub(b.openedAt)
+		if remaining > 0 {
+			return ErrCircuitOpen.Wrap(fmt.Errorf("%d consecutive failures, retrying in %s", b.failures, remaining.Round(time.Second)))
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+		return nil
+	case BreakerHalfOpen:
+		if b.trial {
+			return ErrCircuitOpen.Wrap(fmt.Errorf("waiting for trial request"))
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// Success records a request the provider handled.
+func (b *CircuitBreaker) Success() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.state = BreakerClosed
+	b.trial = false
+}
+
+// Failure records a request that failed on the provider's side.
+func (b *CircuitBreaker) Failure() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = b.now()
+	}
+}
+
+// release ends a request without a verdict, so a half-open circuit lets
+// the next trial request through.
+func (b *CircuitBreaker) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
+
+// State returns the current state.
+func (b *CircuitBreaker) State() BreakerState {
+	if b == nil {
+		return BreakerClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Reset closes the circuit.
+func (b *CircuitBreaker) Reset() {
+	b.Success()
+}
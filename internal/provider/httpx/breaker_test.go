@@ -0,0 +1,143 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+// newTestBreaker returns a breaker with a controllable clock.
+func newTestBreaker(threshold int, cooldown time.Duration) (*CircuitBreaker, *time.Time) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := NewCircuitBreaker(threshold, cooldown)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func isCircuitOpen(err error) bool {
+	var perr *provider.ProviderError
+	return errors.As(err, &perr) && perr.Code == ErrCircuitOpen.Code
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	b, now := newTestBreaker(3, 30*time.Second)
+
+	// Failures below the threshold, interrupted by a success, keep it closed
+	b.Failure()
+	b.Failure()
+	b.Success()
+	b.Failure()
+	b.Failure()
+	if b.State() != BreakerClosed || b.Allow() != nil {
+		t.Fatalf("State() = %v, want closed", b.State())
+	}
+
+	// The third consecutive failure opens it
+	b.Failure()
+	if b.State() != BreakerOpen {
+		t.Fatalf("State() = %v, want open", b.State())
+	}
+	if err := b.Allow(); !isCircuitOpen(err) {
+		t.Fatalf("Allow() = %v, want ErrCircuitOpen", err)
+	}
+
+	// After the cooldown a single trial request goes through
+	*now = now.Add(31 * time.Second)
+	if b.State() != BreakerHalfOpen {
+		t.Errorf("State() = %v, want half-open", b.State())
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatalf("trial Allow() = %v", err)
+	}
+	if err := b.Allow(); !isCircuitOpen(err) {
+		t.Errorf("second Allow() during trial = %v, want ErrCircuitOpen", err)
+	}
+
+	// A failed trial opens it again
+	b.Failure()
+	if err := b.Allow(); !isCircuitOpen(err) {
+		t.Errorf("Allow() after failed trial = %v, want ErrCircuitOpen", err)
+	}
+
+	// A successful trial closes it
+	*now = now.Add(31 * time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("trial Allow() = %v", err)
+	}
+	b.Success()
+	if b.State() != BreakerClosed || b.Allow() != nil {
+		t.Errorf("State() = %v, want closed", b.State())
+	}
+}
+
+func TestCircuitBreaker_ReleasedTrial(t *testing.T) {
+	b, now := newTestBreaker(1, time.Second)
+	b.Failure()
+	*now = now.Add(2 * time.Second)
+
+	if err := b.Allow(); err != nil {
+		t.Fatalf("trial Allow() = %v", err)
+	}
+	b.release()
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow() after released trial = %v, want another trial", err)
+	}
+}
+
+func TestCircuitBreaker_Nil(t *testing.T) {
+	var b *CircuitBreaker
+	b.Failure()
+	b.Success()
+	if b.Allow() != nil || b.State() != BreakerClosed {
+		t.Error("nil breaker should let everything through")
+	}
+}
+
+func TestBreakerFor(t *testing.T) {
+	if BreakerFor("breaker-test") != BreakerFor("breaker-test") {
+		t.Error("BreakerFor() returned different breakers for one provider")
+	}
+	if BreakerFor("breaker-test") == BreakerFor("breaker-test-other") {
+		t.Error("BreakerFor() shared a breaker between providers")
+	}
+}
+
+func TestClient_Do_CircuitBreaker(t *testing.T) {
+	srv, calls := statusSequence(t, nil, 502, 502, 502, 502, 502, 502)
+	b, _ := newTestBreaker(2, time.Minute)
+	c, _ := newTestClient(t, WithCircuitBreaker(b), WithMaxAttempts(5))
+
+	// The request stops retrying once the circuit opens
+	_, err := c.Do(context.Background(), Request{Method: http.MethodGet, URL: srv.URL})
+	if !isCircuitOpen(err) {
+		t.Fatalf("Do() error = %v, want ErrCircuitOpen", err)
+	}
+	if *calls != 2 {
+		t.Errorf("calls = %d, want 2", *calls)
+	}
+
+	// Later requests fail fast without reaching the provider
+	if _, err := c.Do(context.Background(), Request{Method: http.MethodGet, URL: srv.URL}); !isCircuitOpen(err) {
+		t.Errorf("Do() error = %v, want ErrCircuitOpen", err)
+	}
+	if *calls != 2 {
+		t.Errorf("calls = %d, want no more requests", *calls)
+	}
+}
+
+func TestClient_Do_RateLimitDoesNotTripBreaker(t *testing.T) {
+	srv, _ := statusSequence(t, nil, 429, 429, 429)
+	b, _ := newTestBreaker(2, time.Minute)
+	c, _ := newTestClient(t, WithCircuitBreaker(b))
+
+	if _, err := c.Do(context.Background(), Request{Method: http.MethodGet, URL: srv.URL}); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if b.State() != BreakerClosed {
+		t.Errorf("State() = %v, want closed after rate limiting", b.State())
+	}
+}
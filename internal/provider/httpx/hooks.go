@@ -0,0 +1,133 @@
+package httpx
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/logging"
+)
+
+// RequestHook is called before every attempt with the outgoing request.
+// It may add headers.
+type RequestHook func(req *http.Request, attempt int)
+
+// ResponseHook is called after every attempt.
+type ResponseHook func(e Event)
+
+// Event describes one attempt of a request.
+type Event struct {
+	// Provider is the provider name.
+	Provider string
+
+	// Method and URL identify the request.
+	Method string
+	URL    string
+
+	// Attempt is the 1-based attempt number.
+	Attempt int
+
+	// StatusCode is the response status, zero if no response was received.
+	StatusCode int
+
+	// Err is the transport error, nil if a response was received.
+	Err error
+
+	// Latency is how long the attempt took.
+	Latency time.Duration
+
+	// Retry is true if the request will be retried after Wait.
+	Retry bool
+	Wait  time.Duration
+}
+
+// LogHook logs every attempt at debug level, and retries at warn level.
+func LogHook(e Event) {
+	log := logging.Debug()
+	if e.Retry {
+		log = logging.Warn()
+	}
+	log = log.
+		Str("provider", e.Provider).
+		Str("method", e.Method).
+		Str("url", e.URL).
+		Int("attempt", e.Attempt).
+		Dur("latency", e.Latency)
+	if e.StatusCode != 0 {
+		log = log.Int("status", e.StatusCode)
+	}
+	if e.Err != nil {
+		log = log.Err(e.Err)
+	}
+	if e.Retry {
+		log.Dur("wait", e.Wait).Msg("Retrying provider request")
+		return
+	}
+	log.Msg("Provider request")
+}
+
+// Metrics counts requests per provider. Its Hook method is a ResponseHook.
+type Metrics struct {
+	mu        sync.Mutex
+	providers map[string]ProviderMetrics
+}
+
+// ProviderMetrics are the request counters of one provider.
+type ProviderMetrics struct {
+	// Attempts is the number of HTTP requests sent, including retries.
+	Attempts int
+
+	// Retries is the number of attempts that were retried.
+	Retries int
+
+	// RateLimited is the number of 429 responses.
+	RateLimited int
+
+	// ServerErrors is the number of 5xx responses.
+	ServerErrors int
+
+	// TransportErrors is the number of attempts without a response.
+	TransportErrors int
+
+	// TotalLatency is the summed latency of all attempts.
+	TotalLatency time.Duration
+}
+
+// NewMetrics creates empty metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{providers: make(map[string]ProviderMetrics)}
+}
+
+// Hook records an attempt.
+func (m *Metrics) Hook(e Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pm := m.providers[e.Provider]
+	pm.Attempts++
+	pm.TotalLatency += e.Latency
+	if e.Retry {
+		pm.Retries++
+	}
+	switch {
+	case e.Err != nil:
+		pm.TransportErrors++
+	case e.StatusCode == http.StatusTooManyRequests:
+		pm.RateLimited++
+	case e.StatusCode >= 500:
+		pm.ServerErrors++
+	}
+	m.providers[e.Provider] = pm
+}
+
+// Snapshot returns a copy of the counters per provider.
+func (m *Metrics) Snapshot() map[string]ProviderMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]ProviderMetrics, len(m.providers))
+	for name, pm := range m.providers {
+		snapshot[name] = pm
+	}
+	return snapshot
+}
@@ -0,0 +1,447 @@
+// Package httpx provides the HTTP transport shared by the provider clients.
+// It handles rate limiting, retries with jittered exponential backoff,
+// Retry-After, a per-provider circuit breaker and request/response hooks, so
+// every provider behaves the same on 429 and 5xx responses. Mapping responses
+// to provider errors is left to the clients.
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+const (
+	// DefaultMaxAttempts is the default number of attempts per request.
+	DefaultMaxAttempts = 5
+
+	// DefaultBaseDelay is the default base delay for exponential backoff.
+	DefaultBaseDelay = 2 * time.Second
+
+	// DefaultMaxDelay caps the backoff and any Retry-After wait.
+	DefaultMaxDelay = 60 * time.Second
+
+	// DefaultMinInterval is the default minimum time between requests (10 req/sec).
+	DefaultMinInterval = 100 * time.Millisecond
+)
+
+// Request is a request to a provider API.
+type Request struct {
+	// Method is the HTTP method.
+	Method string
+
+	// URL is the absolute request URL.
+	URL string
+
+	// Body is encoded as JSON if not nil.
+	Body interface{}
+
+	// Header holds extra headers for the request.
+	Header http.Header
+
+	// Idempotent allows retrying the request after a server error for
+	// methods that aren't idempotent by definition, e.g. a GraphQL query
+	// sent as POST. Requests that may have taken effect (creating an
+	// instance) must leave it false, so they're only retried when the
+	// provider says it didn't process them (429, 503).
+	Idempotent bool
+
+	// NotIdempotent marks a request whose method is idempotent by definition
+	// but whose effect isn't, e.g. Vast renting an instance with PUT. It
+	// overrides the method, so the request is only retried when the
+	// provider says it didn't process it (429, 503).
+	NotIdempotent bool
+}
+
+// Response is the final response to a request, with its body read.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Client sends requests for one provider.
+type Client struct {
+	provider    string
+	httpClient  *http.Client
+	authorize   func(*http.Request)
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	minInterval time.Duration
+	breaker     *CircuitBreaker
+
+	requestHooks  []RequestHook
+	responseHooks []ResponseHook
+
+	// sleep waits between attempts; replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu          sync.Mutex
+	lastRequest time.Time
+	retryAfter  time.Time
+}
+
+// Option is a functional option for Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the underlying HTTP client.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAuth sets a function that adds credentials to every request.
+func WithAuth(authorize func(*http.Request)) Option {
+	return func(c *Client) {
+		c.authorize = authorize
+	}
+}
+
+// WithMaxAttempts sets the number of attempts per request (at least 1).
+func WithMaxAttempts(n int) Option {
+	return func(c *Client) {
+		if n < 1 {
+			n = 1
+		}
+		c.maxAttempts = n
+	}
+}
+
+// WithBackoff sets the base and maximum delay between attempts.
+func WithBackoff(base, max time.Duration) Option {
+	return func(c *Client) {
+		c.baseDelay = base
+		c.maxDelay = max
+	}
+}
+
+// WithMinInterval sets the minimum time between requests.
+func WithMinInterval(d time.Duration) Option {
+	return func(c *Client) {
+		c.minInterval = d
+	}
+}
+
+// WithCircuitBreaker sets the circuit breaker instead of the provider's
+// shared one. Nil disables the circuit breaker.
+func WithCircuitBreaker(b *CircuitBreaker) Option {
+	return func(c *Client) {
+		c.breaker = b
+	}
+}
+
+// WithRequestHook adds a hook that is called before every attempt.
+func WithRequestHook(hook RequestHook) Option {
+	return func(c *Client) {
+		c.requestHooks = append(c.requestHooks, hook)
+	}
+}
+
+// WithResponseHook adds a hook that is called after every attempt.
+func WithResponseHook(hook ResponseHook) Option {
+	return func(c *Client) {
+		c.responseHooks = append(c.responseHooks, hook)
+	}
+}
+
+// New creates a Client for the named provider. It uses the provider's shared
+// circuit breaker (see BreakerFor) and logs every attempt at debug level.
+func New(providerName string, opts ...Option) *Client {
+	c := &Client{
+		provider:      providerName,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		maxAttempts:   DefaultMaxAttempts,
+		baseDelay:     DefaultBaseDelay,
+		maxDelay:      DefaultMaxDelay,
+		minInterval:   DefaultMinInterval,
+		breaker:       BreakerFor(providerName),
+		responseHooks: []ResponseHook{LogHook},
+		sleep:         sleepContext,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Do sends a request, retrying rate limited requests, server errors and
+// network failures. Any response that isn't retried, or the last one, is
+// returned without error whatever its status; errors are returned as
+// *provider.ProviderError when no response was received, the context ended
+// or the circuit breaker is open.
+func (c *Client) Do(ctx context.Context, r Request) (*Response, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = json.Marshal(r.Body)
+		if err != nil {
+			return nil, provider.NewProviderError("request_encode_failed", "failed to encode request body", err)
+		}
+	}
+
+	if _, err := http.NewRequestWithContext(ctx, r.Method, r.URL, nil); err != nil {
+		return nil, provider.NewProviderError("request_create_failed", "failed to create request", err)
+	}
+
+	idempotent := !r.NotIdempotent && (r.Idempotent || isIdempotentMethod(r.Method))
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		if err := c.waitForRateLimit(ctx); err != nil {
+			return nil, err
+		}
+		if err := c.breaker.Allow(); err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		resp, err := c.send(ctx, r, body, attempt)
+		latency := time.Since(start)
+		retryable := false
+		if err != nil {
+			// A request we cancelled says nothing about the provider
+			if ctx.Err() != nil {
+				c.breaker.release()
+			} else {
+				c.breaker.Failure()
+			}
+			lastErr = provider.NewProviderError("request_failed", "HTTP request failed", err)
+			retryable = ctx.Err() == nil && idempotent
+		} else {
+			if isServerError(resp.StatusCode) {
+				c.breaker.Failure()
+			} else {
+				c.breaker.Success()
+			}
+			retryable = shouldRetry(resp.StatusCode, idempotent)
+		}
+
+		var wait time.Duration
+		if retryable && attempt < c.maxAttempts {
+			wait = c.backoff(attempt)
+			if resp != nil {
+				if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+					wait = min(d, c.maxDelay)
+				}
+				if resp.StatusCode == http.StatusTooManyRequests {
+					c.holdRequests(wait)
+				}
+			}
+		} else {
+			retryable = false
+		}
+
+		c.runResponseHooks(Event{
+			Provider:   c.provider,
+			Method:     r.Method,
+			URL:        r.URL,
+			Attempt:    attempt,
+			StatusCode: statusCode(resp),
+			Err:        err,
+			Latency:    latency,
+			Retry:      retryable,
+			Wait:       wait,
+		})
+
+		if !retryable {
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, provider.NewProviderError("context_cancelled", "request cancelled", ctx.Err())
+				}
+				return nil, lastErr
+			}
+			return resp, nil
+		}
+
+		if err := c.sleep(ctx, wait); err != nil {
+			return nil, provider.NewProviderError("context_cancelled", "request cancelled", err)
+		}
+	}
+}
+
+// send makes a single attempt.
+func (c *Client) send(ctx context.Context, r Request, body []byte, attempt int) (*Response, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, r.URL, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, values := range r.Header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	if c.authorize != nil {
+		c.authorize(req)
+	}
+	for _, hook := range c.requestHooks {
+		hook(req, attempt)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       respBody,
+	}, nil
+}
+
+// waitForRateLimit waits for the minimum interval since the last request and
+// for any Retry-After another request was told to respect.
+func (c *Client) waitForRateLimit(ctx context.Context) error {
+	c.mu.Lock()
+	now := time.Now()
+	next := c.lastRequest.Add(c.minInterval)
+	if c.retryAfter.After(next) {
+		next = c.retryAfter
+	}
+	if next.Before(now) {
+		next = now
+	}
+	c.lastRequest = next
+	c.mu.Unlock()
+
+	if wait := next.Sub(now); wait > 0 {
+		if err := c.sleep(ctx, wait); err != nil {
+			return provider.NewProviderError("context_cancelled", "request cancelled while waiting for rate limit", err)
+		}
+	}
+	return nil
+}
+
+// holdRequests makes all requests of this client wait for d.
+func (c *Client) holdRequests(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if until := time.Now().Add(d); until.After(c.retryAfter) {
+		c.retryAfter = until
+	}
+}
+
+// backoff returns the jittered delay before the next attempt: a random
+// duration between half and all of the exponential delay.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := time.Duration(float64(c.baseDelay) * math.Pow(2, float64(attempt-1)))
+	if delay > c.maxDelay || delay <= 0 {
+		delay = c.maxDelay
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + rand.N(half+1)
+}
+
+func (c *Client) runResponseHooks(e Event) {
+	for _, hook := range c.responseHooks {
+		hook(e)
+	}
+}
+
+// shouldRetry returns true if a response status is worth another attempt.
+// Requests that aren't idempotent are only retried if the provider didn't
+// process them.
+func shouldRetry(statusCode int, idempotent bool) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// isServerError returns true for statuses that count against the circuit breaker.
+func isServerError(statusCode int) bool {
+	return statusCode >= 500 && statusCode != http.StatusNotImplemented
+}
+
+// isIdempotentMethod returns true for HTTP methods that are safe to repeat.
+func isIdempotentMethod(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// statusCode returns the status of resp, zero if there is none.
+func statusCode(resp *Response) int {
+	if resp == nil {
+		return 0
+	}
+	return resp.StatusCode
+}
@@ -0,0 +1,347 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+// newTestClient returns a client without rate limiting or circuit breaker
+// that records the waits between attempts instead of sleeping.
+func newTestClient(t *testing.T, opts ...Option) (*Client, *[]time.Duration) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var waits []time.Duration
+	c := New("test", append([]Option{WithMinInterval(0), WithCircuitBreaker(nil)}, opts...)...)
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		mu.Lock()
+		defer mu.Unlock()
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	return c, &waits
+}
+
+// statusSequence serves the given statuses in order, then 200.
+func statusSequence(t *testing.T, headers http.Header, statuses ...int) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		for k, v := range headers {
+			w.Header()[k] = v
+		}
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestClient_Do_RetriesServerErrors(t *testing.T) {
+	srv, calls := statusSequence(t, nil, http.StatusBadGateway, http.StatusServiceUnavailable)
+	c, waits := newTestClient(t, WithBackoff(time.Second, 10*time.Second))
+
+	resp, err := c.Do(context.Background(), Request{Method: http.MethodGet, URL: srv.URL})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || string(resp.Body) != `{"ok":true}` {
+		t.Errorf("response = %d %s", resp.StatusCode, resp.Body)
+	}
+	if *calls != 3 {
+		t.Errorf("calls = %d, want 3", *calls)
+	}
+
+	// Jittered exponential backoff: attempt n waits between half and all of base*2^(n-1)
+	if len(*waits) != 2 {
+		t.Fatalf("waits = %v, want 2", *waits)
+	}
+	for i, w := range *waits {
+		full := time.Second << i
+		if w < full/2 || w > full {
+			t.Errorf("waits[%d] = %v, want between %v and %v", i, w, full/2, full)
+		}
+	}
+}
+
+func TestClient_Do_RetryAfter(t *testing.T) {
+	srv, _ := statusSequence(t, http.Header{"Retry-After": []string{"7"}}, http.StatusTooManyRequests)
+	c, waits := newTestClient(t, WithBackoff(time.Millisecond, 30*time.Second))
+
+	if _, err := c.Do(context.Background(), Request{Method: http.MethodGet, URL: srv.URL}); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	// The retry waits for Retry-After; the rate limiter holds the next request
+	// for it too, so later requests don't run into the limit again
+	if len(*waits) == 0 || (*waits)[0] != 7*time.Second {
+		t.Errorf("waits = %v, want first wait 7s", *waits)
+	}
+	c.mu.Lock()
+	held := time.Until(c.retryAfter)
+	c.mu.Unlock()
+	if held < 6*time.Second {
+		t.Errorf("requests held for %v, want about 7s", held)
+	}
+}
+
+func TestClient_Do_RetryAfterCapped(t *testing.T) {
+	srv, _ := statusSequence(t, http.Header{"Retry-After": []string{"3600"}}, http.StatusServiceUnavailable)
+	c, waits := newTestClient(t, WithBackoff(time.Millisecond, 5*time.Second))
+
+	if _, err := c.Do(context.Background(), Request{Method: http.MethodGet, URL: srv.URL}); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if (*waits)[0] != 5*time.Second {
+		t.Errorf("wait = %v, want capped at 5s", (*waits)[0])
+	}
+}
+
+func TestClient_Do_NonIdempotent(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		idempotent bool
+		wantCalls  int32
+	}{
+		{"post 500 not retried", http.StatusInternalServerError, false, 1},
+		{"post 502 not retried", http.StatusBadGateway, false, 1},
+		{"post 429 retried", http.StatusTooManyRequests, false, 2},
+		{"post 503 retried", http.StatusServiceUnavailable, false, 2},
+		{"idempotent post 500 retried", http.StatusInternalServerError, true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := statusSequence(t, nil, tt.status)
+			c, _ := newTestClient(t)
+
+			resp, err := c.Do(context.Background(), Request{Method: http.MethodPost, URL: srv.URL, Body: map[string]string{"a": "b"}, Idempotent: tt.idempotent})
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", *calls, tt.wantCalls)
+			}
+			if tt.wantCalls == 1 && resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestClient_Do_NotIdempotentPut(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantCalls int32
+	}{
+		{"put 502 not retried", http.StatusBadGateway, 1},
+		{"put 500 not retried", http.StatusInternalServerError, 1},
+		{"put 503 retried", http.StatusServiceUnavailable, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := statusSequence(t, nil, tt.status)
+			c, _ := newTestClient(t)
+
+			resp, err := c.Do(context.Background(), Request{Method: http.MethodPut, URL: srv.URL, Body: map[string]string{"a": "b"}, NotIdempotent: true})
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", *calls, tt.wantCalls)
+			}
+			if tt.wantCalls == 1 && resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestClient_Do_NotIdempotentNetworkError(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		// Drop the connection as if the response got lost
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	t.Cleanup(srv.Close)
+	c, _ := newTestClient(t)
+
+	if _, err := c.Do(context.Background(), Request{Method: http.MethodPut, URL: srv.URL, NotIdempotent: true}); err == nil {
+		t.Fatal("expected an error for the lost response")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestClient_Do_GivesUpAfterMaxAttempts(t *testing.T) {
+	srv, calls := statusSequence(t, nil, 503, 503, 503, 503)
+	c, waits := newTestClient(t, WithMaxAttempts(3))
+
+	resp, err := c.Do(context.Background(), Request{Method: http.MethodGet, URL: srv.URL})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want the last 503", resp.StatusCode)
+	}
+	if *calls != 3 || len(*waits) != 2 {
+		t.Errorf("calls = %d, waits = %d, want 3 calls and 2 waits", *calls, len(*waits))
+	}
+}
+
+func TestClient_Do_ClientErrorsNotRetried(t *testing.T) {
+	srv, calls := statusSequence(t, nil, http.StatusNotFound)
+	c, _ := newTestClient(t)
+
+	resp, err := c.Do(context.Background(), Request{Method: http.MethodGet, URL: srv.URL})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusNotFound || *calls != 1 {
+		t.Errorf("status = %d, calls = %d, want one 404", resp.StatusCode, *calls)
+	}
+}
+
+func TestClient_Do_TransportError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	c, waits := newTestClient(t, WithMaxAttempts(2))
+	_, err := c.Do(context.Background(), Request{Method: http.MethodGet, URL: url})
+
+	var perr *provider.ProviderError
+	if !errors.As(err, &perr) || perr.Code != "request_failed" {
+		t.Errorf("Do() error = %v, want request_failed", err)
+	}
+	if len(*waits) != 1 {
+		t.Errorf("waits = %d, want 1 retry", len(*waits))
+	}
+}
+
+func TestClient_Do_ContextCancelled(t *testing.T) {
+	srv, _ := statusSequence(t, nil, 503, 503)
+	c := New("test", WithMinInterval(0), WithCircuitBreaker(nil), WithBackoff(time.Hour, time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Do(ctx, Request{Method: http.MethodGet, URL: srv.URL})
+	var perr *provider.ProviderError
+	if !errors.As(err, &perr) || perr.Code != "context_cancelled" || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do() error = %v, want context_cancelled", err)
+	}
+}
+
+func TestClient_Do_HooksAndHeaders(t *testing.T) {
+	var gotAuth, gotTrace, gotContentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotTrace = r.Header.Get("X-Trace")
+		gotContentType = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	var events []Event
+	metrics := NewMetrics()
+	c, _ := newTestClient(t,
+		WithAuth(func(req *http.Request) { req.Header.Set("Authorization", "Bearer key") }),
+		WithRequestHook(func(req *http.Request, attempt int) { req.Header.Set("X-Trace", "t1") }),
+		WithResponseHook(func(e Event) { events = append(events, e) }),
+		WithResponseHook(metrics.Hook),
+	)
+
+	if _, err := c.Do(context.Background(), Request{Method: http.MethodPost, URL: srv.URL, Body: struct{}{}}); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	if gotAuth != "Bearer key" || gotTrace != "t1" || gotContentType != "application/json" {
+		t.Errorf("headers = %q %q %q", gotAuth, gotTrace, gotContentType)
+	}
+	if len(events) != 1 || events[0].StatusCode != http.StatusCreated || events[0].Provider != "test" || events[0].Retry {
+		t.Errorf("events = %+v", events)
+	}
+	if m := metrics.Snapshot()["test"]; m.Attempts != 1 || m.Retries != 0 {
+		t.Errorf("metrics = %+v", m)
+	}
+}
+
+func TestMetrics_Hook(t *testing.T) {
+	m := NewMetrics()
+	m.Hook(Event{Provider: "vast", StatusCode: 429, Retry: true, Latency: time.Second})
+	m.Hook(Event{Provider: "vast", StatusCode: 502, Retry: true, Latency: time.Second})
+	m.Hook(Event{Provider: "vast", Err: errors.New("reset")})
+	m.Hook(Event{Provider: "lambda", StatusCode: 200})
+
+	vast := m.Snapshot()["vast"]
+	want := ProviderMetrics{Attempts: 3, Retries: 2, RateLimited: 1, ServerErrors: 1, TransportErrors: 1, TotalLatency: 2 * time.Second}
+	if vast != want {
+		t.Errorf("vast = %+v, want %+v", vast, want)
+	}
+	if m.Snapshot()["lambda"].Attempts != 1 {
+		t.Error("lambda attempt not counted")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{" 0 ", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	future := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	if got, ok := parseRetryAfter(future); !ok || got < 28*time.Second || got > 30*time.Second {
+		t.Errorf("parseRetryAfter(date) = %v, %v, want about 30s", got, ok)
+	}
+}
+
+func TestClient_backoff(t *testing.T) {
+	c := New("test", WithBackoff(time.Second, 4*time.Second))
+
+	for attempt := 1; attempt <= 6; attempt++ {
+		full := time.Second << (attempt - 1)
+		if full > 4*time.Second {
+			full = 4 * time.Second
+		}
+		for i := 0; i < 20; i++ {
+			if d := c.backoff(attempt); d < full/2 || d > full {
+				t.Fatalf("backoff(%d) = %v, want between %v and %v", attempt, d, full/2, full)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package lambda
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/cassette"
+	"github.com/tmeurs/spinup/internal/provider/httpx"
+)
+
+// newTestClient returns a client that replays (or records) the named cassette in testdata.
+func newTestClient(t *testing.T, name string) *Client {
+	t.Helper()
+
+	c, err := NewClient(
+		cassette.Value(t, "LAMBDA_API_KEY", "test-api-key"),
+		WithHTTPClient(cassette.NewHTTPClient(t, "lambda", filepath.Join("testdata", name+".json"))),
+		WithTransportOptions(httpx.WithMinInterval(0), httpx.WithMaxAttempts(1), httpx.WithCircuitBreaker(nil)),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return c
+}
+
+func TestClient_GetOffers(t *testing.T) {
+	c := newTestClient(t, "get_offers")
+
+	offers, err := c.GetOffers(context.Background(), provider.OfferFilter{})
+	if err != nil {
+		t.Fatalf("GetOffers() error = %v", err)
+	}
+
+	// One offer per region with capacity; the 8x A100 has none
+	sort.Slice(offers, func(i, j int) bool { return offers[i].OfferID < offers[j].OfferID })
+	want := []struct {
+		id     string
+		gpu    string
+		vram   int
+		region string
+		price  float64
+	}{
+		{"gpu_1x_a10@us-east-1", "A10", 24, "US-East", 0.75},
+		{"gpu_1x_h100_pcie@us-east-1", "H100 80GB", 80, "US-East", 2.49},
+		{"gpu_1x_h100_pcie@us-west-1", "H100 80GB", 80, "US-West", 2.49},
+	}
+	if len(offers) != len(want) {
+		t.Fatalf("GetOffers() returned %d offers, want %d: %+v", len(offers), len(want), offers)
+	}
+	for i, w := range want {
+		o := offers[i]
+		if o.OfferID != w.id || o.GPU != w.gpu || o.VRAM != w.vram || o.Region != w.region || o.OnDemandPrice != w.price {
+			t.Errorf("offers[%d] = %+v, want %+v", i, o, w)
+		}
+		if o.Provider != "lambda" || o.SpotPrice != nil || !o.Available {
+			t.Errorf("offers[%d] = %+v, want available on-demand lambda offer", i, o)
+		}
+	}
+}
+
+func TestClient_GetOffers_SpotOnly(t *testing.T) {
+	c := newTestClient(t, "get_offers")
+
+	offers, err := c.GetOffers(context.Background(), provider.OfferFilter{SpotOnly: true})
+	if err != nil || len(offers) != 0 {
+		t.Errorf("GetOffers(spot only) = %v, %v, want no offers", offers, err)
+	}
+}
+
+func TestClient_CreateInstance(t *testing.T) {
+	c := newTestClient(t, "create_instance")
+
+	instance, err := c.CreateInstance(context.Background(), provider.CreateRequest{
+		OfferID: cassette.Value(t, "SPINUP_RECORD_OFFER_ID", "gpu_1x_a10@us-east-1"),
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance() error = %v", err)
+	}
+
+	if instance.ID != "0920582c7ff041399e34823a0be62549" || instance.Provider != "lambda" || instance.Name != "spinup" {
+		t.Errorf("instance = %+v", instance)
+	}
+	if instance.Status != provider.InstanceStatusCreating {
+		t.Errorf("Status = %v, want creating", instance.Status)
+	}
+	if instance.GPU != "A10" || instance.Region != "US-East" || instance.HourlyRate != 0.75 || instance.Spot {
+		t.Errorf("instance GPU/region/rate/spot = %s / %s / %v / %v", instance.GPU, instance.Region, instance.HourlyRate, instance.Spot)
+	}
+}
+
+func TestClient_GetBillingStatus(t *testing.T) {
+	c := newTestClient(t, "get_billing_status")
+	ctx := context.Background()
+
+	status, err := c.GetBillingStatus(ctx, cassette.Value(t, "SPINUP_RECORD_INSTANCE_ID", "0920582c7ff041399e34823a0be62549"))
+	if err != nil || status != provider.BillingActive {
+		t.Errorf("GetBillingStatus(active) = %v, %v, want active", status, err)
+	}
+
+	status, err = c.GetBillingStatus(ctx, cassette.Value(t, "SPINUP_RECORD_GONE_INSTANCE_ID", "5b1c3e8a2f9d4e7b8c6a0d1e2f3a4b5c"))
+	if err != nil || status != provider.BillingStopped {
+		t.Errorf("GetBillingStatus(terminated) = %v, %v, want stopped", status, err)
+	}
+}
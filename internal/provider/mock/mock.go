@@ -4,9 +4,11 @@ package mock
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/tmeurs/spinup/internal/clock"
 	"github.com/tmeurs/spinup/internal/provider"
 )
 
@@ -29,6 +31,7 @@ type Provider struct {
 	getOffersError        error
 	createInstanceError   error
 	getInstanceError      error
+	listInstancesError    error
 	terminateInstanceError error
 	getBillingStatusError error
 	validateAPIKeyError   error
@@ -37,6 +40,7 @@ type Provider struct {
 	getOffersDelay        time.Duration
 	createInstanceDelay   time.Duration
 	getInstanceDelay      time.Duration
+	listInstancesDelay    time.Duration
 	terminateInstanceDelay time.Duration
 	getBillingStatusDelay time.Duration
 
@@ -46,10 +50,18 @@ type Provider struct {
 	// Account info for validation
 	accountInfo *provider.AccountInfo
 
+	// One-off errors per operation (see FailNext)
+	failNext map[string][]error
+
+	// Scripted behavior over time (see Scenario)
+	scenario *scenarioState
+	clock    clock.Clock
+
 	// Call tracking for assertions
 	GetOffersCalls        []GetOffersCall
 	CreateInstanceCalls   []CreateInstanceCall
 	GetInstanceCalls      []GetInstanceCall
+	ListInstancesCalls    int
 	TerminateInstanceCalls []TerminateInstanceCall
 	GetBillingStatusCalls []GetBillingStatusCall
 	ValidateAPIKeyCalls   int
@@ -91,6 +103,7 @@ func New(opts ...Option) *Provider {
 		supportsBillingVerification: true,
 		instances:                   make(map[string]*provider.Instance),
 		nextID:                      1000,
+		clock:                       clock.Real(),
 		accountInfo: &provider.AccountInfo{
 			Valid:    true,
 			Email:    "test@example.com",
@@ -154,6 +167,13 @@ func WithGetInstanceError(err error) Option {
 	}
 }
 
+// WithListInstancesError sets an error to return from ListInstances.
+func WithListInstancesError(err error) Option {
+	return func(p *Provider) {
+		p.listInstancesError = err
+	}
+}
+
 // WithTerminateInstanceError sets an error to return from TerminateInstance.
 func WithTerminateInstanceError(err error) Option {
 	return func(p *Provider) {
@@ -196,6 +216,13 @@ func WithGetInstanceDelay(d time.Duration) Option {
 	}
 }
 
+// WithListInstancesDelay sets a delay for ListInstances.
+func WithListInstancesDelay(d time.Duration) Option {
+	return func(p *Provider) {
+		p.listInstancesDelay = d
+	}
+}
+
 // WithTerminateInstanceDelay sets a delay for TerminateInstance.
 func WithTerminateInstanceDelay(d time.Duration) Option {
 	return func(p *Provider) {
@@ -224,6 +251,21 @@ func WithAccountInfo(info *provider.AccountInfo) Option {
 	}
 }
 
+// WithScenario scripts the provider's behavior with a scenario.
+func WithScenario(sc *Scenario) Option {
+	return func(p *Provider) {
+		p.scenario = newScenarioState(sc)
+	}
+}
+
+// WithClock sets the clock that scenario steps and instance creation times
+// are measured with.
+func WithClock(c clock.Clock) Option {
+	return func(p *Provider) {
+		p.clock = c
+	}
+}
+
 // Name returns the provider name.
 func (p *Provider) Name() string {
 	return p.name
@@ -244,8 +286,7 @@ func (p *Provider) GetOffers(ctx context.Context, filter provider.OfferFilter) (
 	p.mu.Lock()
 	p.GetOffersCalls = append(p.GetOffersCalls, GetOffersCall{Filter: filter})
 	offers := p.offers
-	delay := p.getOffersDelay
-	err := p.getOffersError
+	delay, err := p.applyScenario("GetOffers", p.getOffersDelay, p.getOffersError)
 	p.mu.Unlock()
 
 	// Apply delay
@@ -303,8 +344,7 @@ func matchesFilter(offer provider.Offer, filter provider.OfferFilter) bool {
 func (p *Provider) CreateInstance(ctx context.Context, req provider.CreateRequest) (*provider.Instance, error) {
 	p.mu.Lock()
 	p.CreateInstanceCalls = append(p.CreateInstanceCalls, CreateInstanceCall{Request: req})
-	delay := p.createInstanceDelay
-	err := p.createInstanceError
+	delay, err := p.applyScenario("CreateInstance", p.createInstanceDelay, p.createInstanceError)
 	p.mu.Unlock()
 
 	// Apply delay
@@ -353,14 +393,20 @@ func (p *Provider) CreateInstance(ctx context.Context, req provider.CreateReques
 	instance := &provider.Instance{
 		ID:         id,
 		Provider:   p.name,
+		Name:       provider.InstanceNamePrefix,
 		Status:     provider.InstanceStatusRunning,
 		PublicIP:   fmt.Sprintf("10.0.0.%d", p.nextID%256),
 		GPU:        offer.GPU,
 		Region:     offer.Region,
 		Spot:       req.Spot,
-		CreatedAt:  time.Now(),
+		CreatedAt:  p.clock.Now(),
 		HourlyRate: hourlyRate,
 	}
+	if p.scenario != nil {
+		if status := p.scenario.startInstance(id, instance.CreatedAt); status != "" {
+			instance.Status = status
+		}
+	}
 
 	p.instances[id] = instance
 	p.mu.Unlock()
@@ -374,9 +420,11 @@ func (p *Provider) CreateInstance(ctx context.Context, req provider.CreateReques
 func (p *Provider) GetInstance(ctx context.Context, id string) (*provider.Instance, error) {
 	p.mu.Lock()
 	p.GetInstanceCalls = append(p.GetInstanceCalls, GetInstanceCall{ID: id})
-	delay := p.getInstanceDelay
-	err := p.getInstanceError
+	delay, err := p.applyScenario("GetInstance", p.getInstanceDelay, p.getInstanceError)
 	instance, exists := p.instances[id]
+	if err == nil && exists {
+		p.advanceStatus(instance, true)
+	}
 	p.mu.Unlock()
 
 	// Apply delay
@@ -401,12 +449,42 @@ func (p *Provider) GetInstance(ctx context.Context, id string) (*provider.Instan
 	return &instanceCopy, nil
 }
 
+// ListInstances returns copies of all mock instances, sorted by ID.
+func (p *Provider) ListInstances(ctx context.Context) ([]provider.Instance, error) {
+	p.mu.Lock()
+	p.ListInstancesCalls++
+	delay, err := p.applyScenario("ListInstances", p.listInstancesDelay, p.listInstancesError)
+	instances := make([]provider.Instance, 0, len(p.instances))
+	for _, instance := range p.instances {
+		p.advanceStatus(instance, false)
+		instances = append(instances, *instance)
+	}
+	p.mu.Unlock()
+
+	// Apply delay
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].ID < instances[j].ID
+	})
+	return instances, nil
+}
+
 // TerminateInstance terminates a mock instance.
 func (p *Provider) TerminateInstance(ctx context.Context, id string) error {
 	p.mu.Lock()
 	p.TerminateInstanceCalls = append(p.TerminateInstanceCalls, TerminateInstanceCall{ID: id})
-	delay := p.terminateInstanceDelay
-	err := p.terminateInstanceError
+	delay, err := p.applyScenario("TerminateInstance", p.terminateInstanceDelay, p.terminateInstanceError)
 	instance, exists := p.instances[id]
 	p.mu.Unlock()
 
@@ -428,9 +506,14 @@ func (p *Provider) TerminateInstance(ctx context.Context, id string) error {
 		return nil
 	}
 
-	// Mark as terminated
+	// Mark as terminated, or start the scripted termination
 	p.mu.Lock()
 	instance.Status = provider.InstanceStatusTerminated
+	if p.scenario != nil {
+		if status := p.scenario.terminateInstance(id, p.clock.Now()); status != "" {
+			instance.Status = status
+		}
+	}
 	p.mu.Unlock()
 
 	return nil
@@ -440,10 +523,13 @@ func (p *Provider) TerminateInstance(ctx context.Context, id string) error {
 func (p *Provider) GetBillingStatus(ctx context.Context, id string) (provider.BillingStatus, error) {
 	p.mu.Lock()
 	p.GetBillingStatusCalls = append(p.GetBillingStatusCalls, GetBillingStatusCall{ID: id})
-	delay := p.getBillingStatusDelay
-	err := p.getBillingStatusError
+	delay, err := p.applyScenario("GetBillingStatus", p.getBillingStatusDelay, p.getBillingStatusError)
 	override := p.billingStatusOverride
 	instance, exists := p.instances[id]
+	var scripted provider.BillingStatus
+	if err == nil && override == nil && p.scenario != nil {
+		scripted = p.scenario.billingStatus(id, p.clock.Now())
+	}
 	p.mu.Unlock()
 
 	// Apply delay
@@ -459,10 +545,13 @@ func (p *Provider) GetBillingStatus(ctx context.Context, id string) (provider.Bi
 		return provider.BillingUnknown, err
 	}
 
-	// Use override if set
+	// Use override if set, then the scenario
 	if override != nil {
 		return *override, nil
 	}
+	if scripted != "" {
+		return scripted, nil
+	}
 
 	// Derive from instance status
 	if !exists {
@@ -483,10 +572,19 @@ func (p *Provider) GetBillingStatus(ctx context.Context, id string) (provider.Bi
 func (p *Provider) ValidateAPIKey(ctx context.Context) (*provider.AccountInfo, error) {
 	p.mu.Lock()
 	p.ValidateAPIKeyCalls++
-	err := p.validateAPIKeyError
+	delay, err := p.applyScenario("ValidateAPIKey", 0, p.validateAPIKeyError)
 	info := p.accountInfo
 	p.mu.Unlock()
 
+	// Apply delay
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -519,6 +617,8 @@ func (p *Provider) SetError(operation string, err error) {
 		p.createInstanceError = err
 	case "GetInstance":
 		p.getInstanceError = err
+	case "ListInstances":
+		p.listInstancesError = err
 	case "TerminateInstance":
 		p.terminateInstanceError = err
 	case "GetBillingStatus":
@@ -528,6 +628,18 @@ func (p *Provider) SetError(operation string, err error) {
 	}
 }
 
+// FailNext makes the next call of an operation return err, once. Errors
+// queued for the same operation fail consecutive calls.
+func (p *Provider) FailNext(operation string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failNext == nil {
+		p.failNext = make(map[string][]error)
+	}
+	p.failNext[operation] = append(p.failNext[operation], err)
+}
+
 // SetDelay sets a delay for a specific operation at runtime.
 func (p *Provider) SetDelay(operation string, delay time.Duration) {
 	p.mu.Lock()
@@ -540,6 +652,8 @@ func (p *Provider) SetDelay(operation string, delay time.Duration) {
 		p.createInstanceDelay = delay
 	case "GetInstance":
 		p.getInstanceDelay = delay
+	case "ListInstances":
+		p.listInstancesDelay = delay
 	case "TerminateInstance":
 		p.terminateInstanceDelay = delay
 	case "GetBillingStatus":
@@ -558,6 +672,9 @@ func (p *Provider) SetInstanceStatus(id string, status provider.InstanceStatus)
 	}
 
 	instance.Status = status
+	if p.scenario != nil {
+		delete(p.scenario.statuses, id)
+	}
 	return nil
 }
 
@@ -578,6 +695,7 @@ func (p *Provider) Reset() {
 	p.GetOffersCalls = nil
 	p.CreateInstanceCalls = nil
 	p.GetInstanceCalls = nil
+	p.ListInstancesCalls = 0
 	p.TerminateInstanceCalls = nil
 	p.GetBillingStatusCalls = nil
 	p.ValidateAPIKeyCalls = 0
@@ -586,6 +704,7 @@ func (p *Provider) Reset() {
 	p.getOffersError = nil
 	p.createInstanceError = nil
 	p.getInstanceError = nil
+	p.listInstancesError = nil
 	p.terminateInstanceError = nil
 	p.getBillingStatusError = nil
 	p.validateAPIKeyError = nil
@@ -594,11 +713,55 @@ func (p *Provider) Reset() {
 	p.getOffersDelay = 0
 	p.createInstanceDelay = 0
 	p.getInstanceDelay = 0
+	p.listInstancesDelay = 0
 	p.terminateInstanceDelay = 0
 	p.getBillingStatusDelay = 0
 
 	// Clear overrides
 	p.billingStatusOverride = nil
+	p.failNext = nil
+	p.scenario = nil
+}
+
+// SetScenario scripts the provider's behavior with a scenario at runtime,
+// replacing the previous one. Instances that exist already keep their
+// status until they are terminated. A nil scenario stops scripting.
+func (p *Provider) SetScenario(sc *Scenario) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.scenario = nil
+	if sc != nil {
+		p.scenario = newScenarioState(sc)
+	}
+}
+
+// applyScenario adds the scenario's latency to the configured delay of a
+// call and replaces the configured error with a queued one-off error or the
+// scenario's scripted error, if any. Must be called with p.mu held.
+func (p *Provider) applyScenario(method string, delay time.Duration, err error) (time.Duration, error) {
+	if p.scenario != nil {
+		delay += p.scenario.latency(method)
+		if scripted, ok := p.scenario.nextError(method); ok {
+			err = scripted
+		}
+	}
+	if queued := p.failNext[method]; len(queued) > 0 {
+		err = queued[0]
+		p.failNext[method] = queued[1:]
+	}
+	return delay, err
+}
+
+// advanceStatus moves an instance to its scripted status, counting a poll
+// if asked. Must be called with p.mu held.
+func (p *Provider) advanceStatus(instance *provider.Instance, poll bool) {
+	if p.scenario == nil {
+		return
+	}
+	if status := p.scenario.instanceStatus(instance.ID, p.clock.Now(), poll); status != "" {
+		instance.Status = status
+	}
 }
 
 // Ensure Provider implements the provider.Provider interface.
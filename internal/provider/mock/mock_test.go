@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/providertest"
 )
 
 func TestProvider_Name(t *testing.T) {
@@ -231,6 +232,43 @@ func TestProvider_GetInstance(t *testing.T) {
 	})
 }
 
+func TestProvider_ListInstances(t *testing.T) {
+	offers := []provider.Offer{
+		{OfferID: "offer1", GPU: "A100 40GB", VRAM: 40, Region: "EU-West", OnDemandPrice: 1.00, Available: true},
+	}
+
+	p := New(WithOffers(offers))
+	ctx := context.Background()
+
+	created, _ := p.CreateInstance(ctx, provider.CreateRequest{OfferID: "offer1"})
+	p.AddInstance(&provider.Instance{ID: "external-1", Name: "my-notebook", Status: provider.InstanceStatusRunning})
+
+	instances, err := p.ListInstances(ctx)
+	if err != nil {
+		t.Fatalf("ListInstances() error = %v", err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("len(instances) = %d, want 2", len(instances))
+	}
+	if instances[0].ID != "external-1" || instances[1].ID != created.ID {
+		t.Errorf("instances not sorted by ID: %q, %q", instances[0].ID, instances[1].ID)
+	}
+	if !instances[1].IsSpinupInstance() {
+		t.Errorf("created instance name = %q, want it to be a spinup instance", instances[1].Name)
+	}
+	if instances[0].IsSpinupInstance() {
+		t.Error("external instance should not be a spinup instance")
+	}
+	if p.ListInstancesCalls != 1 {
+		t.Errorf("ListInstancesCalls = %d, want 1", p.ListInstancesCalls)
+	}
+
+	p.SetError("ListInstances", provider.ErrRateLimited)
+	if _, err := p.ListInstances(ctx); !errors.Is(err, provider.ErrRateLimited) {
+		t.Errorf("ListInstances() error = %v, want ErrRateLimited", err)
+	}
+}
+
 func TestProvider_TerminateInstance(t *testing.T) {
 	spotPrice := 0.50
 	offers := []provider.Offer{
@@ -474,3 +512,33 @@ func TestProvider_ImplementsInterface(t *testing.T) {
 	// This test verifies at compile time that Provider implements provider.Provider
 	var _ provider.Provider = (*Provider)(nil)
 }
+
+func TestProvider_Conformance(t *testing.T) {
+	spotPrice := 0.40
+	offers := []provider.Offer{
+		{OfferID: "offer1", Provider: "mock", GPU: "A100 80GB", VRAM: 80, Region: "EU-West", OnDemandPrice: 1.20, Available: true},
+		{OfferID: "offer2", Provider: "mock", GPU: "A6000 48GB", VRAM: 48, Region: "US-East", OnDemandPrice: 0.80, SpotPrice: &spotPrice, Available: true},
+	}
+
+	providertest.RunConformance(t, func(t *testing.T) *providertest.Target {
+		p := New(WithOffers(offers))
+		return &providertest.Target{Provider: p, Backend: p}
+	})
+}
+
+func TestProvider_FailNext(t *testing.T) {
+	p := New()
+	ctx := context.Background()
+
+	p.FailNext("ListInstances", provider.ErrRateLimited)
+	p.FailNext("ListInstances", provider.ErrAuthenticationFailed)
+	if _, err := p.ListInstances(ctx); !errors.Is(err, provider.ErrRateLimited) {
+		t.Errorf("first call error = %v, want ErrRateLimited", err)
+	}
+	if _, err := p.ListInstances(ctx); !errors.Is(err, provider.ErrAuthenticationFailed) {
+		t.Errorf("second call error = %v, want ErrAuthenticationFailed", err)
+	}
+	if _, err := p.ListInstances(ctx); err != nil {
+		t.Errorf("third call error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,386 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+// Scenario scripts how the mock provider behaves over time: the statuses an
+// instance goes through after it is created and after it is terminated, the
+// billing status reported for it, and per method a sequence of errors and a
+// latency distribution. Scenarios are written in YAML:
+//
+//	# Boot for four minutes, then fail; terminate fails twice.
+//	seed: 42
+//	instance:
+//	  boot:
+//	    - {status: creating, for: 4m}
+//	    - {status: error}
+//	  terminate:
+//	    - {status: stopping, polls: 2}
+//	    - {status: terminated}
+//	  billing:
+//	    - {status: active, polls: 1}
+//	    - {status: stopped}
+//	methods:
+//	  TerminateInstance:
+//	    errors:
+//	      - {error: rate_limited, times: 2}
+//	    latency: {min: 10ms, max: 50ms}
+//
+// Time is read from the provider's clock (see WithClock), so tests can move
+// through time-based steps without waiting.
+type Scenario struct {
+	// Name describes the scenario in test output.
+	Name string `yaml:"name"`
+
+	// Seed seeds the random latencies, so a scenario replays identically.
+	Seed int64 `yaml:"seed"`
+
+	// Instance scripts the statuses of every instance.
+	Instance InstanceScript `yaml:"instance"`
+
+	// Methods scripts the methods by name, e.g. "TerminateInstance".
+	Methods map[string]MethodScript `yaml:"methods"`
+}
+
+// InstanceScript holds the status sequences of an instance.
+type InstanceScript struct {
+	// Boot is the status sequence from creation. Instances start in its
+	// first status; without it they are running at once.
+	Boot []Step `yaml:"boot"`
+
+	// Terminate is the status sequence from termination. Without it
+	// instances are terminated at once.
+	Terminate []Step `yaml:"terminate"`
+
+	// Billing is the billing status sequence from the first GetBillingStatus
+	// call for an instance. Without it billing follows the instance status.
+	Billing []Step `yaml:"billing"`
+}
+
+// Step is one status of a sequence. It lasts For, or Polls observations of
+// the status (GetInstance or GetBillingStatus calls), whichever ends first.
+// The last step of a sequence lasts forever; every other step needs For or
+// Polls.
+type Step struct {
+	Status string        `yaml:"status"`
+	For    time.Duration `yaml:"for"`
+	Polls  int           `yaml:"polls"`
+}
+
+// MethodScript scripts one method.
+type MethodScript struct {
+	// Errors are returned by consecutive calls. Once they are used up, calls
+	// behave normally again.
+	Errors []ErrorStep `yaml:"errors"`
+
+	// Latency delays every call.
+	Latency Latency `yaml:"latency"`
+}
+
+// ErrorStep fails Times consecutive calls (once if zero) with an error.
+type ErrorStep struct {
+	// Error is a provider error code, e.g. "rate_limited" or
+	// "instance_not_found", "timeout" for a deadline exceeded, or "none" to
+	// let the calls succeed.
+	Error string `yaml:"error"`
+
+	// Message prefixes the error if set; errors.Is still matches it.
+	Message string `yaml:"message"`
+
+	Times int `yaml:"times"`
+}
+
+// Latency is a latency distribution: Fixed, uniform between Min and Max, or
+// normal with Mean and StdDev (never negative). Fields combine by addition,
+// so Fixed can offset a distribution.
+type Latency struct {
+	Fixed  time.Duration `yaml:"fixed"`
+	Min    time.Duration `yaml:"min"`
+	Max    time.Duration `yaml:"max"`
+	Mean   time.Duration `yaml:"mean"`
+	StdDev time.Duration `yaml:"stddev"`
+}
+
+// scenarioErrors maps the error names of a scenario to provider errors.
+var scenarioErrors = map[string]error{
+	provider.ErrOfferNotFound.Code:        provider.ErrOfferNotFound,
+	provider.ErrInstanceNotFound.Code:     provider.ErrInstanceNotFound,
+	provider.ErrSpotNotAvailable.Code:     provider.ErrSpotNotAvailable,
+	provider.ErrInsufficientCapacity.Code: provider.ErrInsufficientCapacity,
+	provider.ErrAuthenticationFailed.Code: provider.ErrAuthenticationFailed,
+	provider.ErrRateLimited.Code:          provider.ErrRateLimited,
+	provider.ErrBillingNotSupported.Code:  provider.ErrBillingNotSupported,
+	"timeout":                             context.DeadlineExceeded,
+	"none":                                nil,
+}
+
+// scenarioMethods are the methods a scenario can script.
+var scenarioMethods = map[string]bool{
+	"GetOffers":         true,
+	"CreateInstance":    true,
+	"GetInstance":       true,
+	"ListInstances":     true,
+	"TerminateInstance": true,
+	"GetBillingStatus":  true,
+	"ValidateAPIKey":    true,
+}
+
+// LoadScenario reads and validates a YAML scenario file.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario: %w", err)
+	}
+	sc, err := ParseScenario(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return sc, nil
+}
+
+// ParseScenario parses and validates a YAML scenario.
+func ParseScenario(data []byte) (*Scenario, error) {
+	var sc Scenario
+	if err := yaml.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("invalid scenario: %w", err)
+	}
+	if err := sc.Validate(); err != nil {
+		return nil, err
+	}
+	return &sc, nil
+}
+
+// Validate checks the statuses, errors and methods a scenario names, and
+// that its sequences and latencies are well-formed.
+func (sc *Scenario) Validate() error {
+	instanceStatus := func(s string) bool {
+		switch provider.InstanceStatus(s) {
+		case provider.InstanceStatusCreating, provider.InstanceStatusRunning, provider.InstanceStatusStopping,
+			provider.InstanceStatusTerminated, provider.InstanceStatusError:
+			return true
+		}
+		return false
+	}
+	billingStatus := func(s string) bool {
+		switch provider.BillingStatus(s) {
+		case provider.BillingActive, provider.BillingStopped, provider.BillingUnknown:
+			return true
+		}
+		return false
+	}
+
+	if err := validateSteps("boot", sc.Instance.Boot, instanceStatus); err != nil {
+		return err
+	}
+	if err := validateSteps("terminate", sc.Instance.Terminate, instanceStatus); err != nil {
+		return err
+	}
+	if err := validateSteps("billing", sc.Instance.Billing, billingStatus); err != nil {
+		return err
+	}
+
+	for name, m := range sc.Methods {
+		if !scenarioMethods[name] {
+			return fmt.Errorf("unknown method %q", name)
+		}
+		for i, e := range m.Errors {
+			if _, ok := scenarioErrors[e.Error]; !ok {
+				return fmt.Errorf("%s: errors[%d]: unknown error %q", name, i, e.Error)
+			}
+			if e.Times < 0 {
+				return fmt.Errorf("%s: errors[%d]: times must not be negative", name, i)
+			}
+		}
+		l := m.Latency
+		if l.Fixed < 0 || l.Min < 0 || l.Mean < 0 || l.StdDev < 0 {
+			return fmt.Errorf("%s: latency must not be negative", name)
+		}
+		if l.Max != 0 && l.Max < l.Min {
+			return fmt.Errorf("%s: latency max %v is below min %v", name, l.Max, l.Min)
+		}
+	}
+	return nil
+}
+
+// validateSteps checks a status sequence.
+func validateSteps(name string, steps []Step, valid func(string) bool) error {
+	for i, s := range steps {
+		if !valid(s.Status) {
+			return fmt.Errorf("%s[%d]: unknown status %q", name, i, s.Status)
+		}
+		if s.For < 0 || s.Polls < 0 {
+			return fmt.Errorf("%s[%d]: for and polls must not be negative", name, i)
+		}
+		if i < len(steps)-1 && s.For == 0 && s.Polls == 0 {
+			return fmt.Errorf("%s[%d]: only the last step can last forever; set for or polls", name, i)
+		}
+	}
+	return nil
+}
+
+// sequence is the progress through a status sequence.
+type sequence struct {
+	steps     []Step
+	terminate bool // whether this is the terminate sequence
+	index     int
+	started   time.Time // when the current step started
+	polls     int       // observations of the current step
+}
+
+// newSequence starts a sequence at now.
+func newSequence(steps []Step, now time.Time) *sequence {
+	return &sequence{steps: steps, started: now}
+}
+
+// status returns the current status at now, advancing past the steps that
+// ended. A poll counts as an observation of the status.
+func (s *sequence) status(now time.Time, poll bool) string {
+	for s.index < len(s.steps)-1 {
+		step := s.steps[s.index]
+		if step.For > 0 && !now.Before(s.started.Add(step.For)) {
+			s.started = s.started.Add(step.For)
+		} else if step.Polls > 0 && s.polls >= step.Polls {
+			s.started = now
+		} else {
+			break
+		}
+		s.index++
+		s.polls = 0
+	}
+
+	if poll {
+		s.polls++
+	}
+	return s.steps[s.index].Status
+}
+
+// scenarioState is the progress of a provider through its scenario.
+type scenarioState struct {
+	scenario *Scenario
+	rng      *rand.Rand
+
+	// errorSteps and errorCalls are the position in each method's errors.
+	errorSteps map[string]int
+	errorCalls map[string]int
+
+	// Per instance ID: the instance status and billing sequences.
+	statuses map[string]*sequence
+	billing  map[string]*sequence
+}
+
+// newScenarioState starts a scenario.
+func newScenarioState(sc *Scenario) *scenarioState {
+	return &scenarioState{
+		scenario:   sc,
+		rng:        rand.New(rand.NewSource(sc.Seed)),
+		errorSteps: make(map[string]int),
+		errorCalls: make(map[string]int),
+		statuses:   make(map[string]*sequence),
+		billing:    make(map[string]*sequence),
+	}
+}
+
+// nextError returns the scripted result of the next call of a method: an
+// error, or nil and false if the call isn't scripted (any more).
+func (st *scenarioState) nextError(method string) (error, bool) {
+	steps := st.scenario.Methods[method].Errors
+	i := st.errorSteps[method]
+	if i >= len(steps) {
+		return nil, false
+	}
+
+	step := steps[i]
+	times := step.Times
+	if times == 0 {
+		times = 1
+	}
+	st.errorCalls[method]++
+	if st.errorCalls[method] >= times {
+		st.errorSteps[method]++
+		st.errorCalls[method] = 0
+	}
+
+	err := scenarioErrors[step.Error]
+	if err != nil && step.Message != "" {
+		return fmt.Errorf("%s: %w", step.Message, err), true
+	}
+	return err, true
+}
+
+// latency draws the scripted latency of a call of a method.
+func (st *scenarioState) latency(method string) time.Duration {
+	l := st.scenario.Methods[method].Latency
+	d := l.Fixed
+	if l.Max > l.Min {
+		d += l.Min + time.Duration(st.rng.Int63n(int64(l.Max-l.Min)))
+	} else {
+		d += l.Min
+	}
+	if l.Mean > 0 || l.StdDev > 0 {
+		if n := l.Mean + time.Duration(st.rng.NormFloat64()*float64(l.StdDev)); n > 0 {
+			d += n
+		}
+	}
+	return d
+}
+
+// startInstance starts the boot sequence of a new instance and returns its
+// first status, or "" if the scenario has no boot sequence.
+func (st *scenarioState) startInstance(id string, now time.Time) provider.InstanceStatus {
+	if len(st.scenario.Instance.Boot) == 0 {
+		return ""
+	}
+	seq := newSequence(st.scenario.Instance.Boot, now)
+	st.statuses[id] = seq
+	return provider.InstanceStatus(seq.steps[0].Status)
+}
+
+// terminateInstance starts the terminate sequence of an instance and returns
+// its first status, or "" if the scenario has no terminate sequence.
+func (st *scenarioState) terminateInstance(id string, now time.Time) provider.InstanceStatus {
+	// Terminating again doesn't restart the sequence.
+	if seq, ok := st.statuses[id]; ok && seq.terminate {
+		return provider.InstanceStatus(seq.status(now, false))
+	}
+	if len(st.scenario.Instance.Terminate) == 0 {
+		delete(st.statuses, id)
+		return ""
+	}
+	seq := newSequence(st.scenario.Instance.Terminate, now)
+	seq.terminate = true
+	st.statuses[id] = seq
+	return provider.InstanceStatus(seq.steps[0].Status)
+}
+
+// instanceStatus returns the scripted status of an instance, or "" if it
+// has none.
+func (st *scenarioState) instanceStatus(id string, now time.Time, poll bool) provider.InstanceStatus {
+	seq, ok := st.statuses[id]
+	if !ok {
+		return ""
+	}
+	return provider.InstanceStatus(seq.status(now, poll))
+}
+
+// billingStatus returns the scripted billing status of an instance, or ""
+// if the scenario has no billing sequence.
+func (st *scenarioState) billingStatus(id string, now time.Time) provider.BillingStatus {
+	if len(st.scenario.Instance.Billing) == 0 {
+		return ""
+	}
+	seq, ok := st.billing[id]
+	if !ok {
+		seq = newSequence(st.scenario.Instance.Billing, now)
+		st.billing[id] = seq
+	}
+	return provider.BillingStatus(seq.status(now, true))
+}
@@ -0,0 +1,316 @@
+package mock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/clock"
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+func newTestClock() *clock.Fake {
+	return clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+}
+
+func mustParseScenario(t *testing.T, yaml string) *Scenario {
+	t.Helper()
+	sc, err := ParseScenario([]byte(yaml))
+	if err != nil {
+		t.Fatalf("ParseScenario() error = %v", err)
+	}
+	return sc
+}
+
+// newScenarioProvider returns a mock provider with one offer, running sc on clock.
+func newScenarioProvider(t *testing.T, sc *Scenario, clock *clock.Fake) *Provider {
+	t.Helper()
+	return New(
+		WithOffers([]provider.Offer{{OfferID: "offer1", GPU: "A100", VRAM: 80, OnDemandPrice: 1.0, Available: true}}),
+		WithScenario(sc),
+		WithClock(clock),
+	)
+}
+
+func TestParseScenario(t *testing.T) {
+	sc := mustParseScenario(t, `
+name: slow boot
+seed: 7
+instance:
+  boot:
+    - {status: creating, for: 4m}
+    - {status: error}
+  billing:
+    - {status: active, polls: 2}
+    - {status: stopped}
+methods:
+  TerminateInstance:
+    errors:
+      - {error: rate_limited, times: 2}
+    latency: {min: 10ms, max: 50ms}
+`)
+
+	if sc.Name != "slow boot" || sc.Seed != 7 {
+		t.Errorf("Name, Seed = %q, %d", sc.Name, sc.Seed)
+	}
+	if len(sc.Instance.Boot) != 2 || sc.Instance.Boot[0].For != 4*time.Minute || sc.Instance.Boot[1].Status != "error" {
+		t.Errorf("Boot = %+v", sc.Instance.Boot)
+	}
+	if len(sc.Instance.Billing) != 2 || sc.Instance.Billing[0].Polls != 2 {
+		t.Errorf("Billing = %+v", sc.Instance.Billing)
+	}
+	m := sc.Methods["TerminateInstance"]
+	if len(m.Errors) != 1 || m.Errors[0].Times != 2 || m.Latency.Max != 50*time.Millisecond {
+		t.Errorf("Methods[TerminateInstance] = %+v", m)
+	}
+}
+
+func TestParseScenario_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"not yaml", "instance: [", "invalid scenario"},
+		{"unknown status", "instance: {boot: [{status: booting}]}", `unknown status "booting"`},
+		{"unknown billing status", "instance: {billing: [{status: running}]}", `unknown status "running"`},
+		{"step lasts forever", "instance: {boot: [{status: creating}, {status: running}]}", "only the last step"},
+		{"negative polls", "instance: {terminate: [{status: stopping, polls: -1}]}", "must not be negative"},
+		{"unknown method", "methods: {Reboot: {}}", `unknown method "Reboot"`},
+		{"unknown error", "methods: {GetInstance: {errors: [{error: oops}]}}", `unknown error "oops"`},
+		{"latency max below min", "methods: {GetInstance: {latency: {min: 2s, max: 1s}}}", "below min"},
+		{"bad duration", "instance: {boot: [{status: creating, for: soon}]}", "invalid scenario"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScenario([]byte(tt.yaml))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseScenario() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadScenario(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scenario.yaml")
+	if err := os.WriteFile(path, []byte("methods: {GetOffers: {errors: [{error: timeout}]}}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadScenario(path); err != nil {
+		t.Errorf("LoadScenario() error = %v", err)
+	}
+
+	if _, err := LoadScenario(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadScenario() of a missing file error = nil")
+	}
+}
+
+func TestScenario_BootByTime(t *testing.T) {
+	clock := newTestClock()
+	p := newScenarioProvider(t, mustParseScenario(t, `
+instance:
+  boot:
+    - {status: creating, for: 4m}
+    - {status: error}
+`), clock)
+	ctx := context.Background()
+
+	inst, err := p.CreateInstance(ctx, provider.CreateRequest{OfferID: "offer1"})
+	if err != nil {
+		t.Fatalf("CreateInstance() error = %v", err)
+	}
+	if inst.Status != provider.InstanceStatusCreating {
+		t.Errorf("created status = %s, want creating", inst.Status)
+	}
+
+	steps := []struct {
+		advance time.Duration
+		want    provider.InstanceStatus
+	}{
+		{3 * time.Minute, provider.InstanceStatusCreating},
+		{time.Minute, provider.InstanceStatusError},
+		{time.Hour, provider.InstanceStatusError},
+	}
+	for _, step := range steps {
+		clock.Advance(step.advance)
+		got, err := p.GetInstance(ctx, inst.ID)
+		if err != nil {
+			t.Fatalf("GetInstance() error = %v", err)
+		}
+		if got.Status != step.want {
+			t.Errorf("after %v: status = %s, want %s", step.advance, got.Status, step.want)
+		}
+	}
+}
+
+func TestScenario_BootByPolls(t *testing.T) {
+	clock := newTestClock()
+	p := newScenarioProvider(t, mustParseScenario(t, `
+instance:
+  boot:
+    - {status: creating, polls: 2}
+    - {status: running}
+`), clock)
+	ctx := context.Background()
+
+	inst, _ := p.CreateInstance(ctx, provider.CreateRequest{OfferID: "offer1"})
+
+	// Listing doesn't count as a poll
+	list, _ := p.ListInstances(ctx)
+	if list[0].Status != provider.InstanceStatusCreating {
+		t.Errorf("listed status = %s, want creating", list[0].Status)
+	}
+
+	want := []provider.InstanceStatus{provider.InstanceStatusCreating, provider.InstanceStatusCreating, provider.InstanceStatusRunning}
+	for i, w := range want {
+		got, _ := p.GetInstance(ctx, inst.ID)
+		if got.Status != w {
+			t.Errorf("poll %d: status = %s, want %s", i+1, got.Status, w)
+		}
+	}
+}
+
+func TestScenario_TerminateAndBilling(t *testing.T) {
+	clock := newTestClock()
+	p := newScenarioProvider(t, mustParseScenario(t, `
+instance:
+  terminate:
+    - {status: stopping, for: 30s}
+    - {status: terminated}
+  billing:
+    - {status: active, polls: 1}
+    - {status: stopped}
+`), clock)
+	ctx := context.Background()
+
+	inst, _ := p.CreateInstance(ctx, provider.CreateRequest{OfferID: "offer1"})
+	if inst.Status != provider.InstanceStatusRunning {
+		t.Errorf("created status = %s, want running without a boot sequence", inst.Status)
+	}
+
+	if err := p.TerminateInstance(ctx, inst.ID); err != nil {
+		t.Fatalf("TerminateInstance() error = %v", err)
+	}
+	if got, _ := p.GetInstance(ctx, inst.ID); got.Status != provider.InstanceStatusStopping {
+		t.Errorf("status after terminate = %s, want stopping", got.Status)
+	}
+
+	// Terminating again doesn't restart the sequence
+	clock.Advance(20 * time.Second)
+	_ = p.TerminateInstance(ctx, inst.ID)
+	clock.Advance(10 * time.Second)
+	if got, _ := p.GetInstance(ctx, inst.ID); got.Status != provider.InstanceStatusTerminated {
+		t.Errorf("status 30s after terminate = %s, want terminated", got.Status)
+	}
+
+	for i, want := range []provider.BillingStatus{provider.BillingActive, provider.BillingStopped} {
+		got, err := p.GetBillingStatus(ctx, inst.ID)
+		if err != nil {
+			t.Fatalf("GetBillingStatus() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("billing check %d = %s, want %s", i+1, got, want)
+		}
+	}
+}
+
+func TestScenario_Errors(t *testing.T) {
+	p := newScenarioProvider(t, mustParseScenario(t, `
+methods:
+  TerminateInstance:
+    errors:
+      - {error: rate_limited, times: 2}
+      - {error: none}
+      - {error: timeout, message: gateway timed out}
+`), newTestClock())
+	ctx := context.Background()
+
+	checks := []struct {
+		name string
+		want error
+	}{
+		{"call 1", provider.ErrRateLimited},
+		{"call 2", provider.ErrRateLimited},
+		{"call 3", nil},
+		{"call 4", context.DeadlineExceeded},
+		{"call 5 (script used up)", nil},
+	}
+	for _, c := range checks {
+		err := p.TerminateInstance(ctx, "mock-1")
+		if c.want == nil {
+			if err != nil {
+				t.Errorf("%s: error = %v, want nil", c.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: error = %v, want %v", c.name, err, c.want)
+		}
+	}
+
+	// A configured error comes back once the script is used up
+	p.SetError("TerminateInstance", provider.ErrAuthenticationFailed)
+	if err := p.TerminateInstance(ctx, "mock-1"); !errors.Is(err, provider.ErrAuthenticationFailed) {
+		t.Errorf("error = %v, want configured error", err)
+	}
+}
+
+func TestScenario_Latency(t *testing.T) {
+	sc := mustParseScenario(t, `
+seed: 42
+methods:
+  GetOffers: {latency: {min: 10ms, max: 50ms}}
+  GetInstance: {latency: {fixed: 5ms, mean: 20ms, stddev: 5ms}}
+`)
+
+	a, b := newScenarioState(sc), newScenarioState(sc)
+	for i := 0; i < 20; i++ {
+		got := a.latency("GetOffers")
+		if got < 10*time.Millisecond || got >= 50*time.Millisecond {
+			t.Errorf("uniform latency %v out of [10ms, 50ms)", got)
+		}
+		if again := b.latency("GetOffers"); again != got {
+			t.Errorf("latency %v differs with the same seed (%v)", again, got)
+		}
+		if n := a.latency("GetInstance"); n < 5*time.Millisecond {
+			t.Errorf("normal latency %v below the fixed offset", n)
+		}
+		b.latency("GetInstance")
+	}
+	if d := a.latency("ListInstances"); d != 0 {
+		t.Errorf("unscripted latency = %v, want 0", d)
+	}
+}
+
+func TestProvider_SetScenario(t *testing.T) {
+	clock := newTestClock()
+	p := New(WithOffers([]provider.Offer{{OfferID: "offer1", Available: true}}), WithClock(clock))
+	ctx := context.Background()
+
+	p.SetScenario(mustParseScenario(t, "instance: {boot: [{status: creating}]}"))
+	inst, _ := p.CreateInstance(ctx, provider.CreateRequest{OfferID: "offer1"})
+	if inst.Status != provider.InstanceStatusCreating {
+		t.Errorf("status = %s, want creating", inst.Status)
+	}
+	if !inst.CreatedAt.Equal(clock.Now()) {
+		t.Errorf("CreatedAt = %v, want the clock's time", inst.CreatedAt)
+	}
+
+	// An explicitly set status overrides the scenario
+	_ = p.SetInstanceStatus(inst.ID, provider.InstanceStatusRunning)
+	if got, _ := p.GetInstance(ctx, inst.ID); got.Status != provider.InstanceStatusRunning {
+		t.Errorf("status = %s, want running", got.Status)
+	}
+
+	p.Reset()
+	p.SetOffers([]provider.Offer{{OfferID: "offer1", Available: true}})
+	inst, _ = p.CreateInstance(ctx, provider.CreateRequest{OfferID: "offer1"})
+	if inst.Status != provider.InstanceStatusRunning {
+		t.Errorf("status after Reset = %s, want running", inst.Status)
+	}
+}
@@ -3,18 +3,15 @@
 package paperspace
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"math"
 	"net/http"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/httpx"
 )
 
 const (
@@ -24,18 +21,6 @@ const (
 	// defaultTimeout is the default HTTP timeout.
 	defaultTimeout = 30 * time.Second
 
-	// maxRetries is the maximum number of retry attempts.
-	maxRetries = 5
-
-	// baseRetryDelay is the base delay for exponential backoff.
-	baseRetryDelay = 2 * time.Second
-
-	// maxRetryDelay caps the exponential backoff.
-	maxRetryDelay = 60 * time.Second
-
-	// rateLimitDelay is the delay when rate limited.
-	rateLimitDelay = 5 * time.Second
-
 	// consoleURL is the Paperspace web console URL.
 	consoleURL = "https://console.paperspace.com/"
 )
@@ -46,16 +31,11 @@ type Client struct {
 	httpClient *http.Client
 	baseURL    string
 
-	// rateLimiter tracks rate limiting state.
-	rateLimiter *rateLimiter
-}
+	// transportOpts are applied to the transport after the client's own settings.
+	transportOpts []httpx.Option
 
-// rateLimiter implements simple rate limiting tracking.
-type rateLimiter struct {
-	mu          sync.Mutex
-	lastRequest time.Time
-	minInterval time.Duration
-	retryAfter  time.Time
+	// transport sends requests with rate limiting, retries and a circuit breaker.
+	transport *httpx.Client
 }
 
 // ClientOption is a function that configures a Client.
@@ -75,6 +55,14 @@ func WithBaseURL(url string) ClientOption {
 	}
 }
 
+// WithTransportOptions configures the shared HTTP transport, e.g. to add
+// request hooks or use a different circuit breaker.
+func WithTransportOptions(opts ...httpx.Option) ClientOption {
+	return func(c *Client) {
+		c.transportOpts = append(c.transportOpts, opts...)
+	}
+}
+
 // NewClient creates a new Paperspace API client.
 func NewClient(apiKey string, opts ...ClientOption) (*Client, error) {
 	if apiKey == "" {
@@ -87,15 +75,20 @@ func NewClient(apiKey string, opts ...ClientOption) (*Client, error) {
 		httpClient: &http.Client{
 			Timeout: defaultTimeout,
 		},
-		rateLimiter: &rateLimiter{
-			minInterval: 100 * time.Millisecond, // Basic rate limiting: 10 req/sec
-		},
 	}
 
 	for _, opt := range opts {
 		opt(c)
 	}
 
+	c.transport = httpx.New(c.Name(), append([]httpx.Option{
+		httpx.WithHTTPClient(c.httpClient),
+		httpx.WithAuth(func(req *http.Request) {
+			// Paperspace uses the x-api-key header for authentication
+			req.Header.Set("x-api-key", c.apiKey)
+		}),
+	}, c.transportOpts...)...)
+
 	return c, nil
 }
 
@@ -124,103 +117,29 @@ type apiError struct {
 	Status  int    `json:"status"`
 }
 
-// request makes an HTTP request to the Paperspace API with retry logic.
+// request makes an HTTP request to the Paperspace API and decodes the result.
 func (c *Client) request(ctx context.Context, method, path string, body interface{}, result interface{}) error {
-	// Wait for rate limiter
-	if err := c.waitForRateLimit(ctx); err != nil {
+	resp, err := c.transport.Do(ctx, httpx.Request{
+		Method: method,
+		URL:    c.baseURL + path,
+		Body:   body,
+	})
+	if err != nil {
 		return err
 	}
-
-	var bodyReader io.Reader
-	if body != nil {
-		bodyBytes, err := json.Marshal(body)
-		if err != nil {
-			return provider.NewProviderError("request_encode_failed", "failed to encode request body", err)
-		}
-		bodyReader = bytes.NewReader(bodyBytes)
-	}
-
-	url := c.baseURL + path
-
-	var lastErr error
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		// Check context cancellation
-		if ctx.Err() != nil {
-			return provider.NewProviderError("context_cancelled", "request cancelled", ctx.Err())
-		}
-
-		// Create request
-		req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
-		if err != nil {
-			return provider.NewProviderError("request_create_failed", "failed to create request", err)
-		}
-
-		// Set headers - Paperspace uses x-api-key header for authentication
-		req.Header.Set("Accept", "application/json")
-		req.Header.Set("x-api-key", c.apiKey)
-		if body != nil {
-			req.Header.Set("Content-Type", "application/json")
-			// Reset body reader for retry
-			if bodyBytes, ok := body.([]byte); ok {
-				bodyReader = bytes.NewReader(bodyBytes)
-			} else {
-				bodyBytes, _ := json.Marshal(body)
-				bodyReader = bytes.NewReader(bodyBytes)
-			}
-			req.Body = io.NopCloser(bodyReader)
-		}
-
-		// Execute request
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			lastErr = provider.NewProviderError("request_failed", "HTTP request failed", err)
-			c.sleep(ctx, c.calculateBackoff(attempt))
-			continue
-		}
-
-		// Process response
-		processErr := c.processResponse(resp, result)
-		if processErr == nil {
-			return nil
-		}
-
-		// Check if we should retry
-		if shouldRetry(processErr, resp.StatusCode) {
-			lastErr = processErr
-
-			// Handle rate limiting
-			if resp.StatusCode == http.StatusTooManyRequests {
-				c.handleRateLimitResponse(resp)
-				c.sleep(ctx, rateLimitDelay)
-			} else {
-				c.sleep(ctx, c.calculateBackoff(attempt))
-			}
-			continue
-		}
-
-		return processErr
-	}
-
-	return lastErr
+	return c.processResponse(resp, result)
 }
 
 // processResponse processes the HTTP response and decodes the result.
-func (c *Client) processResponse(resp *http.Response, result interface{}) error {
-	defer resp.Body.Close()
-
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return provider.NewProviderError("response_read_failed", "failed to read response body", err)
-	}
-
+func (c *Client) processResponse(resp *httpx.Response, result interface{}) error {
 	// Check for HTTP errors
 	if resp.StatusCode >= 400 {
-		return c.parseAPIError(resp.StatusCode, bodyBytes)
+		return c.parseAPIError(resp.StatusCode, resp.Body)
 	}
 
 	// Decode successful response
-	if result != nil && len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, result); err != nil {
+	if result != nil && len(resp.Body) > 0 {
+		if err := json.Unmarshal(resp.Body, result); err != nil {
 			return provider.NewProviderError("response_decode_failed", "failed to decode response", err)
 		}
 	}
@@ -263,109 +182,6 @@ func (c *Client) statusCodeToError(statusCode int, message string) error {
 	}
 }
 
-// shouldRetry determines if a request should be retried.
-func shouldRetry(err error, statusCode int) bool {
-	// Retry on rate limiting
-	if statusCode == http.StatusTooManyRequests {
-		return true
-	}
-
-	// Retry on server errors
-	if statusCode >= 500 {
-		return true
-	}
-
-	// Retry on network errors (check if it's a temporary error)
-	if provErr, ok := err.(*provider.ProviderError); ok {
-		switch provErr.Code {
-		case "request_failed", "service_unavailable":
-			return true
-		}
-	}
-
-	return false
-}
-
-// calculateBackoff calculates the backoff delay for a given attempt.
-func (c *Client) calculateBackoff(attempt int) time.Duration {
-	delay := time.Duration(float64(baseRetryDelay) * math.Pow(2, float64(attempt-1)))
-	if delay > maxRetryDelay {
-		delay = maxRetryDelay
-	}
-	return delay
-}
-
-// sleep waits for the given duration, respecting context cancellation.
-func (c *Client) sleep(ctx context.Context, duration time.Duration) {
-	select {
-	case <-ctx.Done():
-	case <-time.After(duration):
-	}
-}
-
-// waitForRateLimit waits if necessary to respect rate limits.
-func (c *Client) waitForRateLimit(ctx context.Context) error {
-	c.rateLimiter.mu.Lock()
-
-	// Check if we need to wait for a retry-after
-	if !c.rateLimiter.retryAfter.IsZero() && time.Now().Before(c.rateLimiter.retryAfter) {
-		waitDuration := time.Until(c.rateLimiter.retryAfter)
-		c.rateLimiter.mu.Unlock()
-
-		select {
-		case <-ctx.Done():
-			return provider.NewProviderError("context_cancelled", "request cancelled while waiting for rate limit", ctx.Err())
-		case <-time.After(waitDuration):
-		}
-		return nil
-	}
-
-	// Ensure minimum interval between requests
-	if !c.rateLimiter.lastRequest.IsZero() {
-		elapsed := time.Since(c.rateLimiter.lastRequest)
-		if elapsed < c.rateLimiter.minInterval {
-			waitDuration := c.rateLimiter.minInterval - elapsed
-			c.rateLimiter.mu.Unlock()
-
-			select {
-			case <-ctx.Done():
-				return provider.NewProviderError("context_cancelled", "request cancelled while waiting", ctx.Err())
-			case <-time.After(waitDuration):
-			}
-
-			c.rateLimiter.mu.Lock()
-		}
-	}
-
-	c.rateLimiter.lastRequest = time.Now()
-	c.rateLimiter.mu.Unlock()
-	return nil
-}
-
-// handleRateLimitResponse updates rate limiter state from a rate limit response.
-func (c *Client) handleRateLimitResponse(resp *http.Response) {
-	c.rateLimiter.mu.Lock()
-	defer c.rateLimiter.mu.Unlock()
-
-	// Check for Retry-After header
-	retryAfter := resp.Header.Get("Retry-After")
-	if retryAfter != "" {
-		// Try to parse as seconds
-		if seconds, err := time.ParseDuration(retryAfter + "s"); err == nil {
-			c.rateLimiter.retryAfter = time.Now().Add(seconds)
-			return
-		}
-		// Try to parse as HTTP date
-		if t, err := http.ParseTime(retryAfter); err == nil {
-			c.rateLimiter.retryAfter = t
-			return
-		}
-	}
-
-	// Default to rate limit delay
-	c.rateLimiter.retryAfter = time.Now().Add(rateLimitDelay)
-}
-
 // paperspaceTemplate represents a machine template (GPU type) available on Paperspace.
 type paperspaceTemplate struct {
 	ID           string  `json:"id"`
@@ -706,6 +522,20 @@ func (c *Client) GetInstance(ctx context.Context, id string) (*provider.Instance
 	return &instance, nil
 }
 
+// ListInstances returns all machines on the account.
+func (c *Client) ListInstances(ctx context.Context) ([]provider.Instance, error) {
+	var machines []paperspaceMachine
+	if err := c.request(ctx, http.MethodGet, "/machines/getMachines", nil, &machines); err != nil {
+		return nil, err
+	}
+
+	instances := make([]provider.Instance, 0, len(machines))
+	for _, pm := range machines {
+		instances = append(instances, convertPaperspaceMachine(pm))
+	}
+	return instances, nil
+}
+
 // convertPaperspaceMachine converts a Paperspace machine to the standard Instance type.
 func convertPaperspaceMachine(pm paperspaceMachine) provider.Instance {
 	// Parse hourly rate from usageRate string (format: "$1.89/hr")
@@ -714,6 +544,7 @@ func convertPaperspaceMachine(pm paperspaceMachine) provider.Instance {
 	instance := provider.Instance{
 		ID:         pm.ID,
 		Provider:   "paperspace",
+		Name:       pm.Name,
 		Status:     mapPaperspaceStatus(pm.State),
 		PublicIP:   pm.PublicIpAddress,
 		GPU:        normalizeGPUName(pm.GPU),
@@ -0,0 +1,91 @@
+package paperspace
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/cassette"
+	"github.com/tmeurs/spinup/internal/provider/httpx"
+)
+
+// newTestClient returns a client that replays (or records) the named cassette in testdata.
+func newTestClient(t *testing.T, name string) *Client {
+	t.Helper()
+
+	c, err := NewClient(
+		cassette.Value(t, "PAPERSPACE_API_KEY", "test-api-key"),
+		WithHTTPClient(cassette.NewHTTPClient(t, "paperspace", filepath.Join("testdata", name+".json"))),
+		WithTransportOptions(httpx.WithMinInterval(0), httpx.WithMaxAttempts(1), httpx.WithCircuitBreaker(nil)),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return c
+}
+
+func TestClient_GetOffers(t *testing.T) {
+	c := newTestClient(t, "get_offers")
+
+	offers, err := c.GetOffers(context.Background(), provider.OfferFilter{})
+	if err != nil {
+		t.Fatalf("GetOffers() error = %v", err)
+	}
+
+	// The H100 template is unavailable and C5 has no GPU
+	if len(offers) != 2 {
+		t.Fatalf("GetOffers() returned %d offers, want 2: %+v", len(offers), offers)
+	}
+
+	a100 := offers[0]
+	if a100.OfferID != "tkni3aa4" || a100.Provider != "paperspace" || a100.GPU != "A100 80GB" || a100.VRAM != 80 || a100.Region != "US-East" {
+		t.Errorf("offers[0] = %+v", a100)
+	}
+	if a100.OnDemandPrice != 3.18 || a100.SpotPrice != nil {
+		t.Errorf("offers[0] prices = %v / %v, want 3.18 on-demand only", a100.OnDemandPrice, a100.SpotPrice)
+	}
+
+	a6000 := offers[1]
+	if a6000.OfferID != "t0nspur5" || a6000.GPU != "A6000 48GB" || a6000.Region != "EU-West" {
+		t.Errorf("offers[1] = %+v, want A6000 in EU-West", a6000)
+	}
+}
+
+func TestClient_CreateInstance(t *testing.T) {
+	c := newTestClient(t, "create_instance")
+
+	instance, err := c.CreateInstance(context.Background(), provider.CreateRequest{
+		OfferID:   cassette.Value(t, "SPINUP_RECORD_OFFER_ID", "tkni3aa4"),
+		CloudInit: "#!/bin/bash\n",
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance() error = %v", err)
+	}
+
+	if instance.ID != "psl2w9fjq" || instance.Provider != "paperspace" || instance.Name != "spinup-1760519570" {
+		t.Errorf("instance = %+v", instance)
+	}
+	if instance.Status != provider.InstanceStatusCreating {
+		t.Errorf("Status = %v, want creating", instance.Status)
+	}
+	if instance.GPU != "A100 80GB" || instance.Region != "US-East" || instance.HourlyRate != 3.18 {
+		t.Errorf("instance GPU/region/rate = %s / %s / %v", instance.GPU, instance.Region, instance.HourlyRate)
+	}
+	if instance.CreatedAt.IsZero() {
+		t.Error("CreatedAt is zero")
+	}
+}
+
+func TestClient_GetBillingStatus(t *testing.T) {
+	// Paperspace has no billing API: the cassette holds no exchanges, so any
+	// request fails the call
+	c := newTestClient(t, "get_billing_status")
+
+	status, err := c.GetBillingStatus(context.Background(), "psl2w9fjq")
+	var perr *provider.ProviderError
+	if status != provider.BillingUnknown || !errors.As(err, &perr) || perr.Code != provider.ErrBillingNotSupported.Code {
+		t.Errorf("GetBillingStatus() = %v, %v, want unknown with ErrBillingNotSupported", status, err)
+	}
+}
@@ -3,6 +3,7 @@ package provider
 
 import (
 	"context"
+	"strings"
 	"time"
 )
 
@@ -22,9 +23,13 @@ type Provider interface {
 	CreateInstance(ctx context.Context, req CreateRequest) (*Instance, error)
 
 	// GetInstance retrieves the current status of an instance by ID.
-	// Returns nil if the instance doesn't exist.
+	// Returns ErrInstanceNotFound if the instance doesn't exist.
 	GetInstance(ctx context.Context, id string) (*Instance, error)
 
+	// ListInstances returns all instances on the account, including ones not
+	// created by spinup. Used to find orphaned instances no local session owns.
+	ListInstances(ctx context.Context) ([]Instance, error)
+
 	// TerminateInstance terminates an instance by ID.
 	// This should be idempotent - terminating an already-terminated instance should not error.
 	TerminateInstance(ctx context.Context, id string) error
@@ -48,6 +53,28 @@ type Provider interface {
 	ValidateAPIKey(ctx context.Context) (*AccountInfo, error)
 }
 
+// TerminationCredentialIssuer is implemented by providers that can issue a
+// restricted API key for an instance's deadman switch, so the instance
+// never holds the account-wide API key.
+type TerminationCredentialIssuer interface {
+	// IssueTerminationCredential creates a key that can terminate instances
+	// and do little else. name labels the key in the provider's console.
+	IssueTerminationCredential(ctx context.Context, name string) (*TerminationCredential, error)
+
+	// RevokeTerminationCredential deletes a key created by
+	// IssueTerminationCredential. Revoking a deleted key is not an error.
+	RevokeTerminationCredential(ctx context.Context, id string) error
+}
+
+// TerminationCredential is a restricted API key for a deadman switch.
+type TerminationCredential struct {
+	// ID identifies the key to revoke it. It is not secret.
+	ID string
+
+	// Key is used in place of the API key by the provider's terminate call.
+	Key string
+}
+
 // AccountInfo contains account information returned from API key validation.
 type AccountInfo struct {
 	// Email is the account email (if available from the provider).
@@ -141,6 +168,10 @@ type Offer struct {
 
 	// Available indicates if this offer is currently available.
 	Available bool
+
+	// Reliability is the host reliability reported by the provider (0-1).
+	// Zero if the provider doesn't report it.
+	Reliability float64
 }
 
 // CreateRequest contains the parameters for creating a new instance.
@@ -173,6 +204,9 @@ type Instance struct {
 	// Provider is the provider name (e.g., "vast", "lambda").
 	Provider string
 
+	// Name is the provider-side instance name or label (e.g., "spinup").
+	Name string
+
 	// Status is the instance status.
 	Status InstanceStatus
 
@@ -196,6 +230,15 @@ type Instance struct {
 	HourlyRate float64
 }
 
+// InstanceNamePrefix is the name or label spinup gives the instances it creates.
+// Providers that need unique names append a suffix (e.g., "spinup-1700000000").
+const InstanceNamePrefix = "spinup"
+
+// IsSpinupInstance returns true if the instance's name marks it as created by spinup.
+func (i *Instance) IsSpinupInstance() bool {
+	return i.Name == InstanceNamePrefix || strings.HasPrefix(i.Name, InstanceNamePrefix+"-")
+}
+
 // InstanceStatus represents the lifecycle status of an instance.
 type InstanceStatus string
 
@@ -269,6 +312,13 @@ func (e *ProviderError) Unwrap() error {
 	return e.Cause
 }
 
+// Is reports whether target is a ProviderError with the same code, so
+// errors.Is matches the error variables above also when they were wrapped.
+func (e *ProviderError) Is(target error) bool {
+	t, ok := target.(*ProviderError)
+	return ok && t.Code == e.Code
+}
+
 // Wrap returns a new ProviderError with the same code and message but with a cause.
 func (e *ProviderError) Wrap(cause error) *ProviderError {
 	return &ProviderError{
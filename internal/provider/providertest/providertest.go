@@ -0,0 +1,488 @@
+// Package providertest provides a conformance test suite for provider.Provider
+// implementations. It pins down the behavior the rest of spinup relies on:
+// the interface contract, the mapping of API errors to provider.ProviderError
+// codes, the mapping of API statuses to provider.InstanceStatus and the
+// semantics of provider.OfferFilter.
+//
+// A provider is tested together with the service it talks to, e.g. the mock
+// provider or a real client talking to a fake cloud:
+//
+//	func TestConformance(t *testing.T) {
+//		providertest.RunConformance(t, func(t *testing.T) *providertest.Target {
+//			p := mock.New(mock.WithOffers(offers))
+//			return &providertest.Target{Provider: p, Backend: p}
+//		})
+//	}
+package providertest
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/provider"
+)
+
+// DefaultMissingID is the instance and offer ID the suite assumes doesn't
+// exist if a Target doesn't name one.
+const DefaultMissingID = "providertest-missing"
+
+// Backend controls the service behind a provider under test.
+type Backend interface {
+	// SetInstanceStatus moves an instance to a status at the service, which
+	// reports it in its own vocabulary. The status must hold until it is
+	// changed again.
+	SetInstanceStatus(id string, status provider.InstanceStatus) error
+
+	// FailNext makes the next call of an operation fail the way the service
+	// reports err, one of the provider error variables. The operation is
+	// the name of a Provider method, e.g. "GetInstance".
+	FailNext(operation string, err error)
+}
+
+// Target is a provider under test and the service it talks to.
+type Target struct {
+	// Provider is the provider under test.
+	Provider provider.Provider
+
+	// Backend controls the service behind Provider.
+	Backend Backend
+
+	// MissingInstanceID is an instance ID in the provider's format that no
+	// instance has. Empty means DefaultMissingID.
+	MissingInstanceID string
+
+	// MissingOfferID is an offer ID in the provider's format that no offer
+	// has. Empty means DefaultMissingID.
+	MissingOfferID string
+}
+
+// Factory returns a new Target. It is called for every test of the suite, so
+// each test starts with a fresh service that has at least one offer, one of
+// them available on demand.
+type Factory func(t *testing.T) *Target
+
+// testTimeout bounds every test of the suite.
+const testTimeout = 30 * time.Second
+
+// RunConformance runs the conformance suite against the targets of factory.
+func RunConformance(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, ctx context.Context, target *Target)
+	}{
+		{"Identity", testIdentity},
+		{"ValidateAPIKey", testValidateAPIKey},
+		{"GetOffers", testGetOffers},
+		{"OfferFilter", testOfferFilter},
+		{"Lifecycle", testLifecycle},
+		{"GetInstanceMissing", testGetInstanceMissing},
+		{"TerminateIdempotent", testTerminateIdempotent},
+		{"CreateUnknownOffer", testCreateUnknownOffer},
+		{"CreateSpotUnavailable", testCreateSpotUnavailable},
+		{"BillingStatus", testBillingStatus},
+		{"StatusMapping", testStatusMapping},
+		{"ErrorMapping", testErrorMapping},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := factory(t)
+			if target.MissingInstanceID == "" {
+				target.MissingInstanceID = DefaultMissingID
+			}
+			if target.MissingOfferID == "" {
+				target.MissingOfferID = DefaultMissingID
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+			defer cancel()
+			tt.run(t, ctx, target)
+		})
+	}
+}
+
+func testIdentity(t *testing.T, _ context.Context, target *Target) {
+	p := target.Provider
+	if p.Name() == "" {
+		t.Error("Name() is empty")
+	}
+	u, err := url.Parse(p.ConsoleURL())
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		t.Errorf("ConsoleURL() = %q, want an absolute URL", p.ConsoleURL())
+	}
+}
+
+func testValidateAPIKey(t *testing.T, ctx context.Context, target *Target) {
+	info, err := target.Provider.ValidateAPIKey(ctx)
+	if err != nil {
+		t.Fatalf("ValidateAPIKey() error = %v", err)
+	}
+	if info == nil || !info.Valid {
+		t.Errorf("ValidateAPIKey() = %+v, want a valid account", info)
+	}
+}
+
+func testGetOffers(t *testing.T, ctx context.Context, target *Target) {
+	p := target.Provider
+	offers := allOffers(t, ctx, p)
+
+	seen := make(map[string]bool)
+	for _, o := range offers {
+		if o.OfferID == "" {
+			t.Errorf("offer %+v has no OfferID", o)
+		}
+		if seen[o.OfferID] {
+			t.Errorf("offer %s listed twice", o.OfferID)
+		}
+		seen[o.OfferID] = true
+
+		if o.Provider != p.Name() {
+			t.Errorf("offer %s Provider = %q, want %q", o.OfferID, o.Provider, p.Name())
+		}
+		if o.GPU == "" || o.VRAM <= 0 {
+			t.Errorf("offer %s has GPU %q and VRAM %d, want both set", o.OfferID, o.GPU, o.VRAM)
+		}
+		if o.OnDemandPrice < 0 || (o.SpotPrice != nil && *o.SpotPrice <= 0) {
+			t.Errorf("offer %s has on-demand price %v and spot price %v", o.OfferID, o.OnDemandPrice, o.SpotPrice)
+		}
+		if !o.Available {
+			t.Errorf("offer %s is listed but not available", o.OfferID)
+		}
+	}
+}
+
+func testOfferFilter(t *testing.T, ctx context.Context, target *Target) {
+	p := target.Provider
+	offers := allOffers(t, ctx, p)
+	byID := make(map[string]provider.Offer, len(offers))
+	maxVRAM := 0
+	for _, o := range offers {
+		byID[o.OfferID] = o
+		if o.VRAM > maxVRAM {
+			maxVRAM = o.VRAM
+		}
+	}
+	first := offers[0]
+
+	tests := []struct {
+		name   string
+		filter provider.OfferFilter
+		// include is an offer the filter must return, if any.
+		include string
+		// match checks every returned offer.
+		match func(provider.Offer) bool
+	}{
+		{
+			name:   "MinVRAM",
+			filter: provider.OfferFilter{MinVRAM: maxVRAM},
+			match:  func(o provider.Offer) bool { return o.VRAM >= maxVRAM },
+		},
+		{
+			name:   "MinVRAM above every offer",
+			filter: provider.OfferFilter{MinVRAM: maxVRAM + 1},
+			match:  func(provider.Offer) bool { return false },
+		},
+		{
+			name:   "MaxHourlyPrice",
+			filter: provider.OfferFilter{MaxHourlyPrice: first.OnDemandPrice},
+			match:  func(o provider.Offer) bool { return o.OnDemandPrice <= first.OnDemandPrice },
+		},
+		{
+			name:   "SpotOnly",
+			filter: provider.OfferFilter{SpotOnly: true},
+			match:  func(o provider.Offer) bool { return o.SpotPrice != nil },
+		},
+		{
+			name:   "OnDemandOnly",
+			filter: provider.OfferFilter{OnDemandOnly: true},
+			match:  func(o provider.Offer) bool { return o.OnDemandPrice > 0 },
+		},
+		{
+			name:    "GPUType",
+			filter:  provider.OfferFilter{GPUType: first.GPU},
+			include: first.OfferID,
+		},
+		{
+			name:    "Region",
+			filter:  provider.OfferFilter{Region: first.Region},
+			include: first.OfferID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.GetOffers(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("GetOffers(%+v) error = %v", tt.filter, err)
+			}
+
+			included := tt.include == ""
+			for _, o := range got {
+				if _, ok := byID[o.OfferID]; !ok {
+					t.Errorf("offer %s is not listed without a filter", o.OfferID)
+				}
+				if tt.match != nil && !tt.match(o) {
+					t.Errorf("offer %+v doesn't match filter %+v", o, tt.filter)
+				}
+				if o.OfferID == tt.include {
+					included = true
+				}
+			}
+			if !included {
+				t.Errorf("GetOffers(%+v) doesn't return offer %s", tt.filter, tt.include)
+			}
+		})
+	}
+}
+
+func testLifecycle(t *testing.T, ctx context.Context, target *Target) {
+	p := target.Provider
+	inst := createInstance(t, ctx, p)
+
+	if inst.ID == "" {
+		t.Fatal("CreateInstance() returned no ID")
+	}
+	if inst.Provider != p.Name() {
+		t.Errorf("created instance Provider = %q, want %q", inst.Provider, p.Name())
+	}
+	if inst.Status.IsTerminal() {
+		t.Errorf("created instance Status = %s, want a live status", inst.Status)
+	}
+
+	got, err := p.GetInstance(ctx, inst.ID)
+	if err != nil {
+		t.Fatalf("GetInstance() error = %v", err)
+	}
+	if got.ID != inst.ID || got.Provider != p.Name() {
+		t.Errorf("GetInstance() = %s at %q, want %s at %q", got.ID, got.Provider, inst.ID, p.Name())
+	}
+
+	list, err := p.ListInstances(ctx)
+	if err != nil {
+		t.Fatalf("ListInstances() error = %v", err)
+	}
+	var listed bool
+	for _, i := range list {
+		listed = listed || i.ID == inst.ID
+	}
+	if !listed {
+		t.Errorf("ListInstances() doesn't list instance %s", inst.ID)
+	}
+
+	if err := p.TerminateInstance(ctx, inst.ID); err != nil {
+		t.Fatalf("TerminateInstance() error = %v", err)
+	}
+	got, err = p.GetInstance(ctx, inst.ID)
+	switch {
+	case errors.Is(err, provider.ErrInstanceNotFound):
+		// Some providers forget terminated instances.
+	case err != nil:
+		t.Errorf("GetInstance() after terminate error = %v", err)
+	case got.Status != provider.InstanceStatusStopping && got.Status != provider.InstanceStatusTerminated:
+		t.Errorf("GetInstance() after terminate Status = %s, want stopping or terminated", got.Status)
+	}
+}
+
+func testGetInstanceMissing(t *testing.T, ctx context.Context, target *Target) {
+	inst, err := target.Provider.GetInstance(ctx, target.MissingInstanceID)
+	if !errors.Is(err, provider.ErrInstanceNotFound) {
+		t.Errorf("GetInstance(%s) = %v, %v, want ErrInstanceNotFound", target.MissingInstanceID, inst, err)
+	}
+}
+
+func testTerminateIdempotent(t *testing.T, ctx context.Context, target *Target) {
+	p := target.Provider
+	inst := createInstance(t, ctx, p)
+
+	for i := 1; i <= 2; i++ {
+		if err := p.TerminateInstance(ctx, inst.ID); err != nil {
+			t.Errorf("TerminateInstance() call %d error = %v", i, err)
+		}
+	}
+	if err := p.TerminateInstance(ctx, target.MissingInstanceID); err != nil {
+		t.Errorf("TerminateInstance(%s) of a missing instance error = %v", target.MissingInstanceID, err)
+	}
+}
+
+func testCreateUnknownOffer(t *testing.T, ctx context.Context, target *Target) {
+	_, err := target.Provider.CreateInstance(ctx, provider.CreateRequest{OfferID: target.MissingOfferID, DiskSizeGB: 100})
+	if !errors.Is(err, provider.ErrOfferNotFound) {
+		t.Errorf("CreateInstance(%s) error = %v, want ErrOfferNotFound", target.MissingOfferID, err)
+	}
+}
+
+func testCreateSpotUnavailable(t *testing.T, ctx context.Context, target *Target) {
+	p := target.Provider
+	var offer *provider.Offer
+	for _, o := range allOffers(t, ctx, p) {
+		if o.SpotPrice == nil {
+			offer = &o
+			break
+		}
+	}
+	if offer == nil {
+		t.Skip("every offer has spot pricing")
+	}
+
+	// Providers that can tell return ErrSpotNotAvailable; others pass on
+	// the API's refusal.
+	if _, err := p.CreateInstance(ctx, provider.CreateRequest{OfferID: offer.OfferID, Spot: true, DiskSizeGB: 100}); err == nil {
+		t.Errorf("CreateInstance(%s, spot) error = nil, want spot refused", offer.OfferID)
+	}
+}
+
+func testBillingStatus(t *testing.T, ctx context.Context, target *Target) {
+	p := target.Provider
+	if !p.SupportsBillingVerification() {
+		t.Skip("provider doesn't support billing verification")
+	}
+
+	inst := createInstance(t, ctx, p)
+	if err := target.Backend.SetInstanceStatus(inst.ID, provider.InstanceStatusRunning); err != nil {
+		t.Fatalf("SetInstanceStatus(running) error = %v", err)
+	}
+	status, err := p.GetBillingStatus(ctx, inst.ID)
+	if err != nil {
+		t.Fatalf("GetBillingStatus() error = %v", err)
+	}
+	if status != provider.BillingActive {
+		t.Errorf("GetBillingStatus() of a running instance = %s, want active", status)
+	}
+
+	if err := p.TerminateInstance(ctx, inst.ID); err != nil {
+		t.Fatalf("TerminateInstance() error = %v", err)
+	}
+	if err := target.Backend.SetInstanceStatus(inst.ID, provider.InstanceStatusTerminated); err != nil {
+		t.Fatalf("SetInstanceStatus(terminated) error = %v", err)
+	}
+	status, err = p.GetBillingStatus(ctx, inst.ID)
+	if err != nil && !errors.Is(err, provider.ErrInstanceNotFound) {
+		t.Fatalf("GetBillingStatus() after terminate error = %v", err)
+	}
+	if err == nil && status != provider.BillingStopped {
+		t.Errorf("GetBillingStatus() of a terminated instance = %s, want stopped", status)
+	}
+}
+
+func testStatusMapping(t *testing.T, ctx context.Context, target *Target) {
+	p := target.Provider
+	inst := createInstance(t, ctx, p)
+
+	statuses := []provider.InstanceStatus{
+		provider.InstanceStatusCreating,
+		provider.InstanceStatusRunning,
+		provider.InstanceStatusError,
+		provider.InstanceStatusStopping,
+		provider.InstanceStatusTerminated,
+	}
+	for _, want := range statuses {
+		if err := target.Backend.SetInstanceStatus(inst.ID, want); err != nil {
+			t.Fatalf("SetInstanceStatus(%s) error = %v", want, err)
+		}
+		got, err := p.GetInstance(ctx, inst.ID)
+		if err != nil {
+			if want == provider.InstanceStatusTerminated && errors.Is(err, provider.ErrInstanceNotFound) {
+				continue // Some providers forget terminated instances.
+			}
+			t.Errorf("GetInstance() of a %s instance error = %v", want, err)
+			continue
+		}
+		if got.Status != want {
+			t.Errorf("GetInstance() Status = %s, want %s", got.Status, want)
+		}
+	}
+}
+
+func testErrorMapping(t *testing.T, ctx context.Context, target *Target) {
+	p := target.Provider
+	inst := createInstance(t, ctx, p)
+
+	calls := map[string]func() error{
+		"GetOffers": func() error {
+			_, err := p.GetOffers(ctx, provider.OfferFilter{})
+			return err
+		},
+		"GetInstance": func() error {
+			_, err := p.GetInstance(ctx, inst.ID)
+			return err
+		},
+		"ListInstances": func() error {
+			_, err := p.ListInstances(ctx)
+			return err
+		},
+		"ValidateAPIKey": func() error {
+			_, err := p.ValidateAPIKey(ctx)
+			return err
+		},
+	}
+
+	tests := []struct {
+		operation string
+		err       *provider.ProviderError
+	}{
+		{"GetOffers", provider.ErrAuthenticationFailed},
+		{"GetOffers", provider.ErrRateLimited},
+		{"GetInstance", provider.ErrInstanceNotFound},
+		{"GetInstance", provider.ErrAuthenticationFailed},
+		{"GetInstance", provider.ErrRateLimited},
+		{"ListInstances", provider.ErrAuthenticationFailed},
+		{"ListInstances", provider.ErrRateLimited},
+		{"ValidateAPIKey", provider.ErrAuthenticationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.operation+"/"+tt.err.Code, func(t *testing.T) {
+			target.Backend.FailNext(tt.operation, tt.err)
+			err := calls[tt.operation]()
+
+			var pe *provider.ProviderError
+			if !errors.As(err, &pe) || pe.Code != tt.err.Code {
+				t.Errorf("%s() error = %v, want a ProviderError with code %s", tt.operation, err, tt.err.Code)
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s() error = %v doesn't match %v with errors.Is", tt.operation, err, tt.err)
+			}
+		})
+	}
+}
+
+// allOffers returns the unfiltered offers of p, failing the test if there
+// are none.
+func allOffers(t *testing.T, ctx context.Context, p provider.Provider) []provider.Offer {
+	t.Helper()
+
+	offers, err := p.GetOffers(ctx, provider.OfferFilter{})
+	if err != nil {
+		t.Fatalf("GetOffers() error = %v", err)
+	}
+	if len(offers) == 0 {
+		t.Fatal("GetOffers() returned no offers")
+	}
+	return offers
+}
+
+// createInstance creates an on-demand instance from the first offer that
+// has on-demand pricing.
+func createInstance(t *testing.T, ctx context.Context, p provider.Provider) *provider.Instance {
+	t.Helper()
+
+	for _, o := range allOffers(t, ctx, p) {
+		if o.OnDemandPrice <= 0 {
+			continue
+		}
+		inst, err := p.CreateInstance(ctx, provider.CreateRequest{
+			OfferID:    o.OfferID,
+			CloudInit:  "#cloud-config\n",
+			DiskSizeGB: 100,
+		})
+		if err != nil {
+			t.Fatalf("CreateInstance(%s) error = %v", o.OfferID, err)
+		}
+		return inst
+	}
+	t.Fatal("no offer has on-demand pricing")
+	return nil
+}
@@ -4,6 +4,7 @@ package registry
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/tmeurs/spinup/internal/config"
 	"github.com/tmeurs/spinup/internal/provider"
@@ -38,7 +39,8 @@ func AllProviderNames() []string {
 var ErrUnknownProvider = &provider.ProviderError{Code: "unknown_provider", Message: "unknown provider"}
 
 // NewProvider creates a new Provider instance for the given provider name.
-// The provider is configured using the API key from the Config.
+// The provider is configured using the API key from the Config, and talks to
+// the provider's API endpoint from the Config if one is set.
 // Returns ErrUnknownProvider if the provider name is not recognized.
 // Returns provider.ErrAuthenticationFailed if the provider's API key is not configured.
 func NewProvider(name string, cfg *config.Config) (provider.Provider, error) {
@@ -47,31 +49,51 @@ func NewProvider(name string, cfg *config.Config) (provider.Provider, error) {
 		if cfg.VastAPIKey == "" {
 			return nil, provider.ErrAuthenticationFailed.Wrap(fmt.Errorf("VAST_API_KEY not configured"))
 		}
-		return vast.NewClient(cfg.VastAPIKey)
+		var opts []vast.ClientOption
+		if cfg.VastAPIURL != "" {
+			opts = append(opts, vast.WithBaseURL(cfg.VastAPIURL))
+		}
+		return vast.NewClient(cfg.VastAPIKey, opts...)
 
 	case ProviderLambda:
 		if cfg.LambdaAPIKey == "" {
 			return nil, provider.ErrAuthenticationFailed.Wrap(fmt.Errorf("LAMBDA_API_KEY not configured"))
 		}
-		return lambda.NewClient(cfg.LambdaAPIKey)
+		var opts []lambda.ClientOption
+		if cfg.LambdaAPIURL != "" {
+			opts = append(opts, lambda.WithBaseURL(cfg.LambdaAPIURL))
+		}
+		return lambda.NewClient(cfg.LambdaAPIKey, opts...)
 
 	case ProviderRunPod:
 		if cfg.RunPodAPIKey == "" {
 			return nil, provider.ErrAuthenticationFailed.Wrap(fmt.Errorf("RUNPOD_API_KEY not configured"))
 		}
-		return runpod.NewClient(cfg.RunPodAPIKey)
+		var opts []runpod.ClientOption
+		if cfg.RunPodAPIURL != "" {
+			opts = append(opts, runpod.WithGraphQLURL(cfg.RunPodAPIURL))
+		}
+		return runpod.NewClient(cfg.RunPodAPIKey, opts...)
 
 	case ProviderCoreWeave:
 		if cfg.CoreWeaveAPIKey == "" {
 			return nil, provider.ErrAuthenticationFailed.Wrap(fmt.Errorf("COREWEAVE_API_KEY not configured"))
 		}
-		return coreweave.NewClient(cfg.CoreWeaveAPIKey)
+		var opts []coreweave.ClientOption
+		if cfg.CoreWeaveAPIURL != "" {
+			opts = append(opts, coreweave.WithBaseURL(cfg.CoreWeaveAPIURL))
+		}
+		return coreweave.NewClient(cfg.CoreWeaveAPIKey, opts...)
 
 	case ProviderPaperspace:
 		if cfg.PaperspaceAPIKey == "" {
 			return nil, provider.ErrAuthenticationFailed.Wrap(fmt.Errorf("PAPERSPACE_API_KEY not configured"))
 		}
-		return paperspace.NewClient(cfg.PaperspaceAPIKey)
+		var opts []paperspace.ClientOption
+		if cfg.PaperspaceAPIURL != "" {
+			opts = append(opts, paperspace.WithBaseURL(cfg.PaperspaceAPIURL))
+		}
+		return paperspace.NewClient(cfg.PaperspaceAPIKey, opts...)
 
 	default:
 		return nil, ErrUnknownProvider.Wrap(fmt.Errorf("provider %q not recognized", name))
@@ -92,36 +114,8 @@ func GetConfiguredProviders(cfg *config.Config) ([]provider.Provider, error) {
 	var providers []provider.Provider
 
 	// Check each provider in priority order
-	if cfg.VastAPIKey != "" {
-		p, err := vast.NewClient(cfg.VastAPIKey)
-		if err == nil {
-			providers = append(providers, p)
-		}
-	}
-
-	if cfg.LambdaAPIKey != "" {
-		p, err := lambda.NewClient(cfg.LambdaAPIKey)
-		if err == nil {
-			providers = append(providers, p)
-		}
-	}
-
-	if cfg.RunPodAPIKey != "" {
-		p, err := runpod.NewClient(cfg.RunPodAPIKey)
-		if err == nil {
-			providers = append(providers, p)
-		}
-	}
-
-	if cfg.CoreWeaveAPIKey != "" {
-		p, err := coreweave.NewClient(cfg.CoreWeaveAPIKey)
-		if err == nil {
-			providers = append(providers, p)
-		}
-	}
-
-	if cfg.PaperspaceAPIKey != "" {
-		p, err := paperspace.NewClient(cfg.PaperspaceAPIKey)
+	for _, name := range AllProviderNames() {
+		p, err := NewProvider(name, cfg)
 		if err == nil {
 			providers = append(providers, p)
 		}
@@ -167,3 +161,22 @@ func GetProviderAPIKeyEnvVar(name string) string {
 func GetProviderByName(name string, cfg *config.Config) (provider.Provider, error) {
 	return NewProvider(name, cfg)
 }
+
+// NewProviderWithKey creates a new Provider instance for the given provider
+// name using apiKey instead of a loaded Config. It is used to validate keys
+// before they are saved, e.g. by 'spinup init'. The API endpoints are still
+// taken from the environment (e.g. VAST_API_URL) so that spinup-fakecloud
+// can stand in for the providers.
+func NewProviderWithKey(name, apiKey string) (provider.Provider, error) {
+	cfg := &config.Config{
+		VastAPIURL:       os.Getenv("VAST_API_URL"),
+		LambdaAPIURL:     os.Getenv("LAMBDA_API_URL"),
+		RunPodAPIURL:     os.Getenv("RUNPOD_API_URL"),
+		CoreWeaveAPIURL:  os.Getenv("COREWEAVE_API_URL"),
+		PaperspaceAPIURL: os.Getenv("PAPERSPACE_API_URL"),
+	}
+	if err := cfg.SetAPIKey(name, apiKey); err != nil {
+		return nil, ErrUnknownProvider.Wrap(fmt.Errorf("provider %q not recognized", name))
+	}
+	return NewProvider(name, cfg)
+}
@@ -0,0 +1,100 @@
+package runpod
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/cassette"
+	"github.com/tmeurs/spinup/internal/provider/httpx"
+)
+
+// newTestClient returns a client that replays (or records) the named cassette in testdata.
+func newTestClient(t *testing.T, name string) *Client {
+	t.Helper()
+
+	c, err := NewClient(
+		cassette.Value(t, "RUNPOD_API_KEY", "test-api-key"),
+		WithHTTPClient(cassette.NewHTTPClient(t, "runpod", filepath.Join("testdata", name+".json"))),
+		WithTransportOptions(httpx.WithMinInterval(0), httpx.WithMaxAttempts(1), httpx.WithCircuitBreaker(nil)),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return c
+}
+
+func TestClient_GetOffers(t *testing.T) {
+	c := newTestClient(t, "get_offers")
+
+	offers, err := c.GetOffers(context.Background(), provider.OfferFilter{})
+	if err != nil {
+		t.Fatalf("GetOffers() error = %v", err)
+	}
+
+	// The H100 is out of stock and the MI300X has no price
+	if len(offers) != 2 {
+		t.Fatalf("GetOffers() returned %d offers, want 2: %+v", len(offers), offers)
+	}
+
+	rtx := offers[0]
+	if rtx.OfferID != "NVIDIA GeForce RTX 4090" || rtx.Provider != "runpod" || rtx.GPU != "RTX 4090" || rtx.VRAM != 24 || rtx.Region != "Secure Cloud" {
+		t.Errorf("offers[0] = %+v", rtx)
+	}
+	if rtx.OnDemandPrice != 0.69 || rtx.SpotPrice == nil || *rtx.SpotPrice != 0.34 {
+		t.Errorf("offers[0] prices = %v / %v, want 0.69 / 0.34", rtx.OnDemandPrice, rtx.SpotPrice)
+	}
+
+	a100 := offers[1]
+	if a100.GPU != "A100 80GB" || a100.VRAM != 80 || a100.OnDemandPrice != 1.64 || a100.SpotPrice != nil {
+		t.Errorf("offers[1] = %+v, want on-demand only A100 80GB", a100)
+	}
+}
+
+func TestClient_CreateInstance(t *testing.T) {
+	c := newTestClient(t, "create_instance")
+
+	instance, err := c.CreateInstance(context.Background(), provider.CreateRequest{
+		OfferID:      cassette.Value(t, "SPINUP_RECORD_OFFER_ID", "NVIDIA GeForce RTX 4090"),
+		CloudInit:    "#cloud-config\n",
+		SSHPublicKey: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGr7 spinup",
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance() error = %v", err)
+	}
+
+	if instance.ID != "x7k2m9q4r1t8vz" || instance.Provider != "runpod" || instance.Name != "spinup" {
+		t.Errorf("instance = %+v", instance)
+	}
+	if instance.Status != provider.InstanceStatusRunning {
+		t.Errorf("Status = %v, want running", instance.Status)
+	}
+	if instance.GPU != "RTX 4090" || instance.Region != "US" || instance.Spot {
+		t.Errorf("instance GPU/region/spot = %s / %s / %v", instance.GPU, instance.Region, instance.Spot)
+	}
+}
+
+func TestClient_GetBillingStatus(t *testing.T) {
+	c := newTestClient(t, "get_billing_status")
+	ctx := context.Background()
+
+	id := cassette.Value(t, "SPINUP_RECORD_INSTANCE_ID", "x7k2m9q4r1t8vz")
+	instance, err := c.GetInstance(ctx, id)
+	if err != nil {
+		t.Fatalf("GetInstance() error = %v", err)
+	}
+	if instance.PublicIP != "203.0.113.88" || instance.HourlyRate != 0.69 || instance.CreatedAt.IsZero() {
+		t.Errorf("instance = %+v, want public port IP, rate and creation time", instance)
+	}
+
+	status, err := c.GetBillingStatus(ctx, id)
+	if err != nil || status != provider.BillingActive {
+		t.Errorf("GetBillingStatus(running) = %v, %v, want active", status, err)
+	}
+
+	status, err = c.GetBillingStatus(ctx, cassette.Value(t, "SPINUP_RECORD_GONE_INSTANCE_ID", "q2w8e4r6t0y1ui"))
+	if err != nil || status != provider.BillingStopped {
+		t.Errorf("GetBillingStatus(terminated) = %v, %v, want stopped", status, err)
+	}
+}
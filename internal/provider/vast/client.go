@@ -2,17 +2,15 @@
 package vast
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"math"
 	"net/http"
-	"sync"
 	"time"
 
 	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/httpx"
 )
 
 const (
@@ -22,18 +20,6 @@ const (
 	// defaultTimeout is the default HTTP timeout.
 	defaultTimeout = 30 * time.Second
 
-	// maxRetries is the maximum number of retry attempts.
-	maxRetries = 5
-
-	// baseRetryDelay is the base delay for exponential backoff.
-	baseRetryDelay = 2 * time.Second
-
-	// maxRetryDelay caps the exponential backoff.
-	maxRetryDelay = 60 * time.Second
-
-	// rateLimitDelay is the delay when rate limited.
-	rateLimitDelay = 5 * time.Second
-
 	// consoleURL is the Vast.ai web console URL.
 	consoleURL = "https://console.vast.ai/"
 )
@@ -44,16 +30,11 @@ type Client struct {
 	httpClient *http.Client
 	baseURL    string
 
-	// rateLimiter tracks rate limiting state.
-	rateLimiter *rateLimiter
-}
+	// transportOpts are applied to the transport after the client's own settings.
+	transportOpts []httpx.Option
 
-// rateLimiter implements simple rate limiting tracking.
-type rateLimiter struct {
-	mu            sync.Mutex
-	lastRequest   time.Time
-	minInterval   time.Duration
-	retryAfter    time.Time
+	// transport sends requests with rate limiting, retries and a circuit breaker.
+	transport *httpx.Client
 }
 
 // ClientOption is a function that configures a Client.
@@ -73,6 +54,14 @@ func WithBaseURL(url string) ClientOption {
 	}
 }
 
+// WithTransportOptions configures the shared HTTP transport, e.g. to add
+// request hooks or use a different circuit breaker.
+func WithTransportOptions(opts ...httpx.Option) ClientOption {
+	return func(c *Client) {
+		c.transportOpts = append(c.transportOpts, opts...)
+	}
+}
+
 // NewClient creates a new Vast.ai API client.
 func NewClient(apiKey string, opts ...ClientOption) (*Client, error) {
 	if apiKey == "" {
@@ -85,15 +74,19 @@ func NewClient(apiKey string, opts ...ClientOption) (*Client, error) {
 		httpClient: &http.Client{
 			Timeout: defaultTimeout,
 		},
-		rateLimiter: &rateLimiter{
-			minInterval: 100 * time.Millisecond, // Basic rate limiting: 10 req/sec
-		},
 	}
 
 	for _, opt := range opts {
 		opt(c)
 	}
 
+	c.transport = httpx.New(c.Name(), append([]httpx.Option{
+		httpx.WithHTTPClient(c.httpClient),
+		httpx.WithAuth(func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		}),
+	}, c.transportOpts...)...)
+
 	return c, nil
 }
 
@@ -119,103 +112,34 @@ type apiError struct {
 	Msg     string `json:"msg"`
 }
 
-// request makes an HTTP request to the Vast.ai API with retry logic.
+// request makes an HTTP request to the Vast.ai API and decodes the result.
 func (c *Client) request(ctx context.Context, method, path string, body interface{}, result interface{}) error {
-	// Wait for rate limiter
-	if err := c.waitForRateLimit(ctx); err != nil {
-		return err
-	}
-
-	var bodyReader io.Reader
-	if body != nil {
-		bodyBytes, err := json.Marshal(body)
-		if err != nil {
-			return provider.NewProviderError("request_encode_failed", "failed to encode request body", err)
-		}
-		bodyReader = bytes.NewReader(bodyBytes)
-	}
-
-	url := c.baseURL + path
-
-	var lastErr error
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		// Check context cancellation
-		if ctx.Err() != nil {
-			return provider.NewProviderError("context_cancelled", "request cancelled", ctx.Err())
-		}
-
-		// Create request
-		req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
-		if err != nil {
-			return provider.NewProviderError("request_create_failed", "failed to create request", err)
-		}
-
-		// Set headers
-		req.Header.Set("Accept", "application/json")
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
-		if body != nil {
-			req.Header.Set("Content-Type", "application/json")
-			// Reset body reader for retry
-			if bodyBytes, ok := body.([]byte); ok {
-				bodyReader = bytes.NewReader(bodyBytes)
-			} else {
-				bodyBytes, _ := json.Marshal(body)
-				bodyReader = bytes.NewReader(bodyBytes)
-			}
-			req.Body = io.NopCloser(bodyReader)
-		}
-
-		// Execute request
-		resp, err := c.httpClient.Do(req)
-		if err != nil {
-			lastErr = provider.NewProviderError("request_failed", "HTTP request failed", err)
-			c.sleep(ctx, c.calculateBackoff(attempt))
-			continue
-		}
-
-		// Process response
-		processErr := c.processResponse(resp, result)
-		if processErr == nil {
-			return nil
-		}
-
-		// Check if we should retry
-		if shouldRetry(processErr, resp.StatusCode) {
-			lastErr = processErr
-
-			// Handle rate limiting
-			if resp.StatusCode == http.StatusTooManyRequests {
-				c.handleRateLimitResponse(resp)
-				c.sleep(ctx, rateLimitDelay)
-			} else {
-				c.sleep(ctx, c.calculateBackoff(attempt))
-			}
-			continue
-		}
-
-		return processErr
-	}
-
-	return lastErr
+	return c.do(ctx, httpx.Request{
+		Method: method,
+		URL:    c.baseURL + path,
+		Body:   body,
+	}, result)
 }
 
-// processResponse processes the HTTP response and decodes the result.
-func (c *Client) processResponse(resp *http.Response, result interface{}) error {
-	defer resp.Body.Close()
-
-	bodyBytes, err := io.ReadAll(resp.Body)
+// do sends a prepared request and decodes the result.
+func (c *Client) do(ctx context.Context, req httpx.Request, result interface{}) error {
+	resp, err := c.transport.Do(ctx, req)
 	if err != nil {
-		return provider.NewProviderError("response_read_failed", "failed to read response body", err)
+		return err
 	}
+	return c.processResponse(resp, result)
+}
 
+// processResponse processes the HTTP response and decodes the result.
+func (c *Client) processResponse(resp *httpx.Response, result interface{}) error {
 	// Check for HTTP errors
 	if resp.StatusCode >= 400 {
-		return c.parseAPIError(resp.StatusCode, bodyBytes)
+		return c.parseAPIError(resp.StatusCode, resp.Body)
 	}
 
 	// Decode successful response
-	if result != nil && len(bodyBytes) > 0 {
-		if err := json.Unmarshal(bodyBytes, result); err != nil {
+	if result != nil && len(resp.Body) > 0 {
+		if err := json.Unmarshal(resp.Body, result); err != nil {
 			return provider.NewProviderError("response_decode_failed", "failed to decode response", err)
 		}
 	}
@@ -258,109 +182,6 @@ func (c *Client) statusCodeToError(statusCode int, message string) error {
 	}
 }
 
-// shouldRetry determines if a request should be retried.
-func shouldRetry(err error, statusCode int) bool {
-	// Retry on rate limiting
-	if statusCode == http.StatusTooManyRequests {
-		return true
-	}
-
-	// Retry on server errors
-	if statusCode >= 500 {
-		return true
-	}
-
-	// Retry on network errors (check if it's a temporary error)
-	if provErr, ok := err.(*provider.ProviderError); ok {
-		switch provErr.Code {
-		case "request_failed", "service_unavailable":
-			return true
-		}
-	}
-
-	return false
-}
-
-// calculateBackoff calculates the backoff delay for a given attempt.
-func (c *Client) calculateBackoff(attempt int) time.Duration {
-	delay := time.Duration(float64(baseRetryDelay) * math.Pow(2, float64(attempt-1)))
-	if delay > maxRetryDelay {
-		delay = maxRetryDelay
-	}
-	return delay
-}
-
-// sleep waits for the given duration, respecting context cancellation.
-func (c *Client) sleep(ctx context.Context, duration time.Duration) {
-	select {
-	case <-ctx.Done():
-	case <-time.After(duration):
-	}
-}
-
-// waitForRateLimit waits if necessary to respect rate limits.
-func (c *Client) waitForRateLimit(ctx context.Context) error {
-	c.rateLimiter.mu.Lock()
-
-	// Check if we need to wait for a retry-after
-	if !c.rateLimiter.retryAfter.IsZero() && time.Now().Before(c.rateLimiter.retryAfter) {
-		waitDuration := time.Until(c.rateLimiter.retryAfter)
-		c.rateLimiter.mu.Unlock()
-
-		select {
-		case <-ctx.Done():
-			return provider.NewProviderError("context_cancelled", "request cancelled while waiting for rate limit", ctx.Err())
-		case <-time.After(waitDuration):
-		}
-		return nil
-	}
-
-	// Ensure minimum interval between requests
-	if !c.rateLimiter.lastRequest.IsZero() {
-		elapsed := time.Since(c.rateLimiter.lastRequest)
-		if elapsed < c.rateLimiter.minInterval {
-			waitDuration := c.rateLimiter.minInterval - elapsed
-			c.rateLimiter.mu.Unlock()
-
-			select {
-			case <-ctx.Done():
-				return provider.NewProviderError("context_cancelled", "request cancelled while waiting", ctx.Err())
-			case <-time.After(waitDuration):
-			}
-
-			c.rateLimiter.mu.Lock()
-		}
-	}
-
-	c.rateLimiter.lastRequest = time.Now()
-	c.rateLimiter.mu.Unlock()
-	return nil
-}
-
-// handleRateLimitResponse updates rate limiter state from a rate limit response.
-func (c *Client) handleRateLimitResponse(resp *http.Response) {
-	c.rateLimiter.mu.Lock()
-	defer c.rateLimiter.mu.Unlock()
-
-	// Check for Retry-After header
-	retryAfter := resp.Header.Get("Retry-After")
-	if retryAfter != "" {
-		// Try to parse as seconds
-		if seconds, err := time.ParseDuration(retryAfter + "s"); err == nil {
-			c.rateLimiter.retryAfter = time.Now().Add(seconds)
-			return
-		}
-		// Try to parse as HTTP date
-		if t, err := http.ParseTime(retryAfter); err == nil {
-			c.rateLimiter.retryAfter = t
-			return
-		}
-	}
-
-	// Default to rate limit delay
-	c.rateLimiter.retryAfter = time.Now().Add(rateLimitDelay)
-}
-
 // vastOffer represents an offer from the Vast.ai API.
 type vastOffer struct {
 	ID             int     `json:"id"`
@@ -472,6 +293,7 @@ func convertVastOffer(vo vastOffer) provider.Offer {
 		StoragePrice:  storagePerHour,
 		EgressPrice:   vo.InetDownCost, // Egress is download from provider
 		Available:     vo.Rentable,
+		Reliability:   vo.Reliability,
 	}
 
 	// Set spot price if bidding is available (min_bid > 0)
@@ -486,15 +308,15 @@ func convertVastOffer(vo vastOffer) provider.Offer {
 
 // normalizeGPUNameForVast converts our standard GPU name to Vast.ai's naming convention.
 func normalizeGPUNameForVast(gpuType string) string {
-	// Map our GPU names to Vast.ai's naming
+	// Map our GPU names, and the names offers are reported with, to Vast.ai's naming
 	switch gpuType {
-	case "A100-40GB":
+	case "A100-40GB", "A100 40GB":
 		return "A100"
-	case "A100-80GB":
+	case "A100-80GB", "A100 80GB":
 		return "A100_80GB"
-	case "H100-80GB":
+	case "H100-80GB", "H100 80GB":
 		return "H100"
-	case "A6000":
+	case "A6000", "A6000 48GB":
 		return "RTX_A6000"
 	default:
 		return gpuType
@@ -695,7 +517,15 @@ func (c *Client) CreateInstance(ctx context.Context, req provider.CreateRequest)
 	// Create the instance by accepting the offer
 	var resp vastCreateResponse
 	path := fmt.Sprintf("/asks/%s/", req.OfferID)
-	if err := c.request(ctx, http.MethodPut, path, createReq, &resp); err != nil {
+	// Renting is a PUT, but repeating it after a lost response could rent a
+	// second instance, so it must not be retried like one
+	err := c.do(ctx, httpx.Request{
+		Method:        http.MethodPut,
+		URL:           c.baseURL + path,
+		Body:          createReq,
+		NotIdempotent: true,
+	}, &resp)
+	if err != nil {
 		// Check for specific error conditions
 		if provErr, ok := err.(*provider.ProviderError); ok {
 			if provErr.Code == "instance_not_found" {
@@ -751,11 +581,31 @@ func (c *Client) GetInstance(ctx context.Context, id string) (*provider.Instance
 	return &instance, nil
 }
 
+// vastInstancesResponse represents the response from the instance list endpoint.
+type vastInstancesResponse struct {
+	Instances []vastInstance `json:"instances"`
+}
+
+// ListInstances returns all instances on the account.
+func (c *Client) ListInstances(ctx context.Context) ([]provider.Instance, error) {
+	var resp vastInstancesResponse
+	if err := c.request(ctx, http.MethodGet, "/instances/", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	instances := make([]provider.Instance, 0, len(resp.Instances))
+	for _, vi := range resp.Instances {
+		instances = append(instances, convertVastInstance(vi))
+	}
+	return instances, nil
+}
+
 // convertVastInstance converts a Vast.ai instance to the standard Instance type.
 func convertVastInstance(vi vastInstance) provider.Instance {
 	instance := provider.Instance{
 		ID:         fmt.Sprintf("%d", vi.ID),
 		Provider:   "vast",
+		Name:       vi.Label,
 		Status:     mapVastStatus(vi.ActualStatus, vi.CurState),
 		PublicIP:   vi.PublicIPAddr,
 		GPU:        normalizeGPUNameFromVast(vi.GPUName),
@@ -858,6 +708,53 @@ func (c *Client) TerminateInstance(ctx context.Context, id string) error {
 	return nil
 }
 
+// vastAPIKeyRequest creates a restricted API key.
+type vastAPIKeyRequest struct {
+	Name        string                               `json:"name"`
+	Permissions map[string]map[string]map[string]any `json:"permissions"`
+}
+
+// vastAPIKeyResponse is a created API key.
+type vastAPIKeyResponse struct {
+	ID  json.Number `json:"id"`
+	Key string      `json:"key"`
+}
+
+// terminationPermissions are the permissions of a deadman switch key: it
+// can manage instances, but not read or change the account or billing.
+var terminationPermissions = map[string]map[string]map[string]any{
+	"api": {
+		"instance_write": {},
+	},
+}
+
+// IssueTerminationCredential creates an API key that can only manage
+// instances, for an instance's deadman switch.
+func (c *Client) IssueTerminationCredential(ctx context.Context, name string) (*provider.TerminationCredential, error) {
+	var resp vastAPIKeyResponse
+	req := vastAPIKeyRequest{Name: name, Permissions: terminationPermissions}
+	if err := c.request(ctx, http.MethodPost, "/auth/apikeys/", req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.ID == "" || resp.Key == "" {
+		return nil, provider.NewProviderError("api_error", "API key response has no key", nil)
+	}
+	return &provider.TerminationCredential{ID: resp.ID.String(), Key: resp.Key}, nil
+}
+
+// RevokeTerminationCredential deletes an API key created by
+// IssueTerminationCredential.
+func (c *Client) RevokeTerminationCredential(ctx context.Context, id string) error {
+	if id == "" {
+		return provider.NewProviderError("invalid_request", "API key ID is required", nil)
+	}
+	path := fmt.Sprintf("/auth/apikeys/%s/", id)
+	if err := c.request(ctx, http.MethodDelete, path, nil, nil); err != nil && !errors.Is(err, provider.ErrInstanceNotFound) {
+		return err
+	}
+	return nil
+}
+
 // GetBillingStatus returns the billing status for an instance.
 // For Vast.ai, billing is determined by the instance's actual_status:
 // - If instance is "running", "created", or "loading" → BillingActive
@@ -0,0 +1,150 @@
+package vast
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/cassette"
+	"github.com/tmeurs/spinup/internal/provider/httpx"
+)
+
+// newTestClient returns a client that replays (or records) the named cassette in testdata.
+func newTestClient(t *testing.T, name string, opts ...cassette.Option) *Client {
+	t.Helper()
+
+	c, err := NewClient(
+		cassette.Value(t, "VAST_API_KEY", "test-api-key"),
+		WithHTTPClient(cassette.NewHTTPClient(t, "vast", filepath.Join("testdata", name+".json"), opts...)),
+		WithTransportOptions(httpx.WithMinInterval(0), httpx.WithMaxAttempts(1), httpx.WithCircuitBreaker(nil)),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return c
+}
+
+func TestClient_GetOffers(t *testing.T) {
+	c := newTestClient(t, "get_offers")
+
+	offers, err := c.GetOffers(context.Background(), provider.OfferFilter{})
+	if err != nil {
+		t.Fatalf("GetOffers() error = %v", err)
+	}
+
+	// The H100 offer is no longer rentable
+	if len(offers) != 2 {
+		t.Fatalf("GetOffers() returned %d offers, want 2", len(offers))
+	}
+
+	rtx := offers[0]
+	if rtx.OfferID != "8811234" || rtx.Provider != "vast" || rtx.GPU != "RTX 4090" || rtx.VRAM != 24 || rtx.Region != "US" {
+		t.Errorf("offers[0] = %+v", rtx)
+	}
+	if rtx.OnDemandPrice != 0.412 || rtx.SpotPrice == nil || *rtx.SpotPrice != 0.25 {
+		t.Errorf("offers[0] prices = %v / %v, want 0.412 / 0.25", rtx.OnDemandPrice, rtx.SpotPrice)
+	}
+	if rtx.Reliability != 0.9981 || rtx.EgressPrice != 0.003 {
+		t.Errorf("offers[0] reliability/egress = %v / %v", rtx.Reliability, rtx.EgressPrice)
+	}
+
+	a100 := offers[1]
+	if a100.GPU != "A100 40GB" || a100.Region != "EU-North" || a100.SpotPrice != nil {
+		t.Errorf("offers[1] = %+v, want on-demand only A100 40GB in EU-North", a100)
+	}
+}
+
+func TestClient_CreateInstance(t *testing.T) {
+	c := newTestClient(t, "create_instance")
+
+	instance, err := c.CreateInstance(context.Background(), provider.CreateRequest{
+		OfferID:      cassette.Value(t, "SPINUP_RECORD_OFFER_ID", "8811234"),
+		CloudInit:    "#cloud-config\n",
+		SSHPublicKey: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIGr7 spinup",
+	})
+	if err != nil {
+		t.Fatalf("CreateInstance() error = %v", err)
+	}
+
+	if instance.ID != "19452233" || instance.Provider != "vast" || instance.Name != "spinup" {
+		t.Errorf("instance = %+v", instance)
+	}
+	if instance.Status != provider.InstanceStatusCreating {
+		t.Errorf("Status = %v, want creating", instance.Status)
+	}
+	if instance.GPU != "RTX 4090" || instance.Region != "US" || instance.HourlyRate != 0.412 {
+		t.Errorf("instance GPU/region/rate = %s / %s / %v", instance.GPU, instance.Region, instance.HourlyRate)
+	}
+	if instance.PublicIP != "ssh4.vast.ai" {
+		t.Errorf("PublicIP = %q, want the SSH host while no IP is assigned", instance.PublicIP)
+	}
+	if instance.CreatedAt.IsZero() {
+		t.Error("CreatedAt is zero")
+	}
+}
+
+func TestClient_CreateInstance_NotRetried(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(srv.Close)
+
+	c, err := NewClient("test-api-key",
+		WithBaseURL(srv.URL),
+		WithTransportOptions(httpx.WithMinInterval(0), httpx.WithCircuitBreaker(nil), httpx.WithBackoff(time.Millisecond, time.Millisecond)),
+	)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	if _, err := c.CreateInstance(context.Background(), provider.CreateRequest{OfferID: "8811234"}); err == nil {
+		t.Fatal("expected an error for the 502")
+	}
+	if calls != 1 {
+		t.Errorf("create sent %d times, want 1", calls)
+	}
+}
+
+func TestClient_GetBillingStatus(t *testing.T) {
+	c := newTestClient(t, "get_billing_status")
+	ctx := context.Background()
+
+	status, err := c.GetBillingStatus(ctx, cassette.Value(t, "SPINUP_RECORD_INSTANCE_ID", "19452233"))
+	if err != nil || status != provider.BillingActive {
+		t.Errorf("GetBillingStatus(running) = %v, %v, want active", status, err)
+	}
+
+	status, err = c.GetBillingStatus(ctx, cassette.Value(t, "SPINUP_RECORD_GONE_INSTANCE_ID", "19452101"))
+	if err != nil || status != provider.BillingStopped {
+		t.Errorf("GetBillingStatus(destroyed) = %v, %v, want stopped", status, err)
+	}
+}
+
+func TestClient_TerminationCredential(t *testing.T) {
+	// The created key is a credential too
+	c := newTestClient(t, "termination_credential", cassette.WithRedactedFields("key"))
+	ctx := context.Background()
+
+	cred, err := c.IssueTerminationCredential(ctx, "spinup-deadman-default")
+	if err != nil {
+		t.Fatalf("IssueTerminationCredential() error = %v", err)
+	}
+	if cred.ID != "48213" || cred.Key == "" {
+		t.Errorf("credential = %+v, want ID 48213 and a key", cred)
+	}
+
+	if err := c.RevokeTerminationCredential(ctx, cred.ID); err != nil {
+		t.Errorf("RevokeTerminationCredential() error = %v", err)
+	}
+	// Revoking is idempotent
+	if err := c.RevokeTerminationCredential(ctx, cred.ID); err != nil {
+		t.Errorf("RevokeTerminationCredential(revoked) error = %v", err)
+	}
+}
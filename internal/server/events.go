@@ -0,0 +1,128 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/logging"
+)
+
+// subscriberBuffer is the number of events buffered per event stream.
+// Events for a stream that falls further behind are dropped.
+const subscriberBuffer = 64
+
+// message is an encoded event.
+type message struct {
+	event string
+	data  []byte
+}
+
+// broker fans events out to the event stream subscribers.
+type broker struct {
+	mu     sync.Mutex
+	subs   map[chan message]struct{}
+	closed bool
+}
+
+func newBroker() *broker {
+	return &broker{subs: make(map[chan message]struct{})}
+}
+
+// subscribe returns a channel receiving events until unsubscribe or close.
+// It returns nil after close.
+func (b *broker) subscribe() chan message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	ch := make(chan message, subscriberBuffer)
+	b.subs[ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe removes and closes a subscriber channel.
+func (b *broker) unsubscribe(ch chan message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// publish sends an event to every subscriber without blocking.
+func (b *broker) publish(event string, data any) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		logging.Warn().Err(err).Str("event", event).Msg("Failed to encode API event")
+		return
+	}
+	msg := message{event: event, data: encoded}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- msg:
+		default:
+			logging.Debug().Str("event", event).Msg("Event stream is behind, dropping event")
+		}
+	}
+}
+
+// close ends all event streams.
+func (b *broker) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// handleEvents streams events as Server-Sent Events.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	ch := s.broker.subscribe()
+	if ch == nil {
+		writeError(w, http.StatusServiceUnavailable, fmt.Errorf("server is shutting down"))
+		return
+	}
+	defer s.broker.unsubscribe(ch)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(s.keepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.event, msg.data)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,421 @@
+// Package server provides the local HTTP control API of 'spinup serve'.
+//
+// The API is meant for editor plugins and other tools on the same machine.
+// It only listens on loopback addresses and every request must carry the
+// bearer token the server was started with. Deployments run in the
+// background; their progress and result are streamed as Server-Sent Events.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/logging"
+)
+
+// DefaultAddr is the default listen address.
+const DefaultAddr = "127.0.0.1:7717"
+
+// Event names sent on the event stream.
+const (
+	EventDeployProgress = "deploy_progress"
+	EventDeployResult   = "deploy_result"
+	EventStopProgress   = "stop_progress"
+	EventStopResult     = "stop_result"
+)
+
+// Errors a Backend returns to select the HTTP status of a response.
+var (
+	// ErrBadRequest is returned for invalid request parameters (400).
+	ErrBadRequest = errors.New("bad request")
+
+	// ErrConflict is returned when the request conflicts with the session,
+	// e.g. a deployment while an instance is running (409).
+	ErrConflict = errors.New("conflict")
+
+	// ErrNotLoopback is returned by ListenAndServe for addresses that are
+	// reachable from other machines.
+	ErrNotLoopback = errors.New("listen address is not a loopback address")
+)
+
+// DeployRequest is the body of POST /v1/deploy.
+type DeployRequest struct {
+	Model       string `json:"model"`
+	Provider    string `json:"provider,omitempty"`
+	GPU         string `json:"gpu,omitempty"`
+	Region      string `json:"region,omitempty"`
+	Filter      string `json:"filter,omitempty"` // offer filter expression
+	OnDemand    bool   `json:"on_demand,omitempty"`
+	Timeout     string `json:"timeout,omitempty"` // deadman timeout, e.g. "10h"
+	MaxAttempts int    `json:"max_attempts,omitempty"`
+}
+
+// OffersRequest holds the query parameters of GET /v1/offers.
+type OffersRequest struct {
+	Model    string
+	Provider string
+	GPU      string
+	Region   string
+	Filter   string
+	SpotOnly bool
+	MaxPrice float64
+}
+
+// Operation is a long-running operation started by a Backend. It returns
+// the result to publish, which may be set even if the operation failed.
+type Operation func(ctx context.Context) (any, error)
+
+// Backend carries out API requests. Results are encoded as JSON as is.
+type Backend interface {
+	// Status returns the status of the session.
+	Status(ctx context.Context) (any, error)
+
+	// Deploy checks that a deployment can start and returns it. The server
+	// runs it in the background and publishes its result as EventDeployResult.
+	Deploy(ctx context.Context, req DeployRequest) (Operation, error)
+
+	// Stop stops the session's instance.
+	Stop(ctx context.Context) (any, error)
+
+	// Offers returns the available offers.
+	Offers(ctx context.Context, req OffersRequest) (any, error)
+
+	// Models returns the supported models.
+	Models(ctx context.Context) (any, error)
+}
+
+// Server is the HTTP control API.
+type Server struct {
+	backend   Backend
+	token     string
+	broker    *broker
+	keepAlive time.Duration
+
+	mu        sync.Mutex
+	operation string // running deploy or stop, empty if none
+	ops       sync.WaitGroup
+	baseCtx   context.Context
+}
+
+// Option is a functional option for Server.
+type Option func(*Server)
+
+// WithKeepAlive sets how often an idle event stream gets a comment to keep
+// proxies and clients from timing it out.
+func WithKeepAlive(d time.Duration) Option {
+	return func(s *Server) {
+		s.keepAlive = d
+	}
+}
+
+// New creates a Server that accepts requests carrying token.
+func New(backend Backend, token string, opts ...Option) (*Server, error) {
+	if backend == nil {
+		return nil, errors.New("backend is required")
+	}
+	if token == "" {
+		return nil, errors.New("token is required")
+	}
+
+	s := &Server{
+		backend:   backend,
+		token:     token,
+		broker:    newBroker(),
+		keepAlive: 15 * time.Second,
+		baseCtx:   context.Background(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// GenerateToken returns a random token for New.
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Publish sends an event to all event stream subscribers. data is encoded
+// as JSON.
+func (s *Server) Publish(event string, data any) {
+	s.broker.publish(event, data)
+}
+
+// Handler returns the HTTP handler of the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", s.handleStatus)
+	mux.HandleFunc("POST /v1/deploy", s.handleDeploy)
+	mux.HandleFunc("POST /v1/stop", s.handleStop)
+	mux.HandleFunc("GET /v1/offers", s.handleOffers)
+	mux.HandleFunc("GET /v1/models", s.handleModels)
+	mux.HandleFunc("GET /v1/events", s.handleEvents)
+	return s.authenticate(mux)
+}
+
+// ListenAndServe serves the API on addr until ctx is canceled. Background
+// operations are canceled with ctx and waited for before it returns, so a
+// deployment can clean up its instance.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	if err := checkLoopback(addr); err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve serves the API on ln until ctx is canceled, like ListenAndServe.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	s.mu.Lock()
+	s.baseCtx = ctx
+	s.mu.Unlock()
+
+	srv := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Event streams never end by themselves
+	s.broker.close()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	s.ops.Wait()
+	return err
+}
+
+// checkLoopback returns ErrNotLoopback unless addr's host is a loopback
+// address or "localhost".
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrNotLoopback, addr)
+}
+
+// authenticate rejects requests without the bearer token.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="spinup"`)
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// begin marks an operation as running. It returns false if another one is.
+func (s *Server) begin(operation string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.operation != "" {
+		return false
+	}
+	s.operation = operation
+	return true
+}
+
+// end marks the running operation as done.
+func (s *Server) end() {
+	s.mu.Lock()
+	s.operation = ""
+	s.mu.Unlock()
+}
+
+// busyError returns the error for a request made while an operation runs.
+func (s *Server) busyError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Errorf("%w: %s in progress", ErrConflict, s.operation)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	result, err := s.backend.Status(r.Context())
+	writeResult(w, http.StatusOK, result, err)
+}
+
+func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
+	var req DeployRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	if !s.begin("deploy") {
+		writeError(w, http.StatusConflict, s.busyError())
+		return
+	}
+
+	op, err := s.backend.Deploy(r.Context(), req)
+	if err != nil {
+		s.end()
+		writeResult(w, http.StatusAccepted, nil, err)
+		return
+	}
+
+	s.mu.Lock()
+	ctx := s.baseCtx
+	s.mu.Unlock()
+
+	s.ops.Add(1)
+	go func() {
+		defer s.ops.Done()
+		defer s.end()
+
+		result, err := op(ctx)
+		if err != nil {
+			logging.Warn().Err(err).Msg("Deployment requested through the API failed")
+			if result == nil {
+				result = errorBody(err)
+			}
+		}
+		s.Publish(EventDeployResult, result)
+	}()
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "deploying"})
+}
+
+func (s *Server) handleStop(w http.ResponseWriter, r *http.Request) {
+	if !s.begin("stop") {
+		writeError(w, http.StatusConflict, s.busyError())
+		return
+	}
+	defer s.end()
+
+	// A client that hangs up must not leave the instance half stopped
+	s.mu.Lock()
+	ctx := s.baseCtx
+	s.mu.Unlock()
+	s.ops.Add(1)
+	defer s.ops.Done()
+
+	result, err := s.backend.Stop(ctx)
+	if result != nil {
+		s.Publish(EventStopResult, result)
+	}
+	writeResult(w, http.StatusOK, result, err)
+}
+
+func (s *Server) handleOffers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	req := OffersRequest{
+		Model:    q.Get("model"),
+		Provider: q.Get("provider"),
+		GPU:      q.Get("gpu"),
+		Region:   q.Get("region"),
+		Filter:   q.Get("filter"),
+	}
+	if v := q.Get("spot_only"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid spot_only %q", v))
+			return
+		}
+		req.SpotOnly = b
+	}
+	if v := q.Get("max_price"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid max_price %q", v))
+			return
+		}
+		req.MaxPrice = f
+	}
+
+	result, err := s.backend.Offers(r.Context(), req)
+	writeResult(w, http.StatusOK, result, err)
+}
+
+func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
+	result, err := s.backend.Models(r.Context())
+	writeResult(w, http.StatusOK, result, err)
+}
+
+// errorBody returns the JSON body of an error response, the same shape the
+// command line prints with --output=json.
+func errorBody(err error) map[string]string {
+	return map[string]string{
+		"status": "error",
+		"error":  err.Error(),
+	}
+}
+
+// writeResult writes a backend result with status, or err with the status
+// it maps to. A result returned with an error is written instead of the
+// plain error body.
+func writeResult(w http.ResponseWriter, status int, result any, err error) {
+	if err == nil {
+		writeJSON(w, status, result)
+		return
+	}
+
+	status = http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrBadRequest):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, context.Canceled):
+		status = http.StatusServiceUnavailable
+	}
+	if result != nil {
+		writeJSON(w, status, result)
+		return
+	}
+	writeError(w, status, err)
+}
+
+// writeError writes an error response.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorBody(err))
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logging.Debug().Err(err).Msg("Failed to write API response")
+	}
+}
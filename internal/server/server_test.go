@@ -0,0 +1,372 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testToken = "test-token"
+
+// fakeBackend records requests and returns canned results.
+type fakeBackend struct {
+	mu         sync.Mutex
+	deployReqs []DeployRequest
+	offersReqs []OffersRequest
+	deployErr  error
+	release    chan struct{} // deployments block until closed
+	publish    func(event string, data any)
+}
+
+func (b *fakeBackend) Status(ctx context.Context) (any, error) {
+	return map[string]string{"status": "none_active"}, nil
+}
+
+func (b *fakeBackend) Deploy(ctx context.Context, req DeployRequest) (Operation, error) {
+	b.mu.Lock()
+	b.deployReqs = append(b.deployReqs, req)
+	b.mu.Unlock()
+	if b.deployErr != nil {
+		return nil, b.deployErr
+	}
+	return func(ctx context.Context) (any, error) {
+		b.publish(EventDeployProgress, map[string]any{"step": 1})
+		select {
+		case <-b.release:
+		case <-ctx.Done():
+			return map[string]string{"status": "error"}, ctx.Err()
+		}
+		return map[string]string{"status": "ready", "model": req.Model}, nil
+	}, nil
+}
+
+func (b *fakeBackend) Stop(ctx context.Context) (any, error) {
+	return map[string]string{"status": "stopped"}, nil
+}
+
+func (b *fakeBackend) Offers(ctx context.Context, req OffersRequest) (any, error) {
+	b.mu.Lock()
+	b.offersReqs = append(b.offersReqs, req)
+	b.mu.Unlock()
+	return map[string]any{"offers": []string{}}, nil
+}
+
+func (b *fakeBackend) Models(ctx context.Context) (any, error) {
+	return []string{"qwen2.5-coder:7b"}, nil
+}
+
+func newTestServer(t *testing.T) (*Server, *fakeBackend, *httptest.Server) {
+	t.Helper()
+
+	backend := &fakeBackend{release: make(chan struct{})}
+	srv, err := New(backend, testToken, WithKeepAlive(time.Hour))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	backend.publish = srv.Publish
+
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		srv.broker.close()
+		ts.Close()
+	})
+	return srv, backend, ts
+}
+
+func doRequest(t *testing.T, ts *httptest.Server, method, path, body string) (int, map[string]any) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	var decoded map[string]any
+	data, _ := io.ReadAll(resp.Body)
+	_ = json.Unmarshal(data, &decoded)
+	return resp.StatusCode, decoded
+}
+
+// readEvent reads the next event from an event stream, skipping comments.
+func readEvent(t *testing.T, r *bufio.Reader) (string, string) {
+	t.Helper()
+
+	var event, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading event stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && event != "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestNew_RequiresBackendAndToken(t *testing.T) {
+	if _, err := New(nil, testToken); err == nil {
+		t.Error("expected error for nil backend")
+	}
+	if _, err := New(&fakeBackend{}, ""); err == nil {
+		t.Error("expected error for empty token")
+	}
+}
+
+func TestGenerateToken(t *testing.T) {
+	a, err := GenerateToken()
+	if err != nil {
+		t.Fatalf("GenerateToken() error = %v", err)
+	}
+	b, _ := GenerateToken()
+	if len(a) != 64 || a == b {
+		t.Errorf("GenerateToken() = %q, %q, want two different 64 character tokens", a, b)
+	}
+}
+
+func TestServer_Authentication(t *testing.T) {
+	_, _, ts := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"no header", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer nope", http.StatusUnauthorized},
+		{"wrong scheme", "Basic " + testToken, http.StatusUnauthorized},
+		{"valid", "Bearer " + testToken, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/status", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET /v1/status error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_Routes(t *testing.T) {
+	_, backend, ts := newTestServer(t)
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/v1/status", http.StatusOK},
+		{http.MethodGet, "/v1/models", http.StatusOK},
+		{http.MethodGet, "/v1/offers?model=qwen2.5-coder:7b&spot_only=true&max_price=1.5&filter=vram%3E%3D48", http.StatusOK},
+		{http.MethodGet, "/v1/offers?max_price=cheap", http.StatusBadRequest},
+		{http.MethodGet, "/v1/offers?spot_only=maybe", http.StatusBadRequest},
+		{http.MethodPost, "/v1/stop", http.StatusOK},
+		{http.MethodPost, "/v1/status", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/v1/unknown", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if got, _ := doRequest(t, ts, tt.method, tt.path, ""); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	if len(backend.offersReqs) != 1 {
+		t.Fatalf("offers requests = %d, want 1", len(backend.offersReqs))
+	}
+	if got := backend.offersReqs[0]; got.Model != "qwen2.5-coder:7b" || !got.SpotOnly || got.MaxPrice != 1.5 || got.Filter != "vram>=48" {
+		t.Errorf("offers request = %+v", got)
+	}
+}
+
+func TestServer_Deploy(t *testing.T) {
+	srv, backend, ts := newTestServer(t)
+
+	// Subscribe before deploying so no event is missed
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/events", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /v1/events error = %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	events := bufio.NewReader(resp.Body)
+
+	status, body := doRequest(t, ts, http.MethodPost, "/v1/deploy", `{"model": "qwen2.5-coder:7b", "on_demand": true}`)
+	if status != http.StatusAccepted || body["status"] != "deploying" {
+		t.Fatalf("POST /v1/deploy = %d %v, want 202 deploying", status, body)
+	}
+
+	if event, _ := readEvent(t, events); event != EventDeployProgress {
+		t.Errorf("first event = %s, want %s", event, EventDeployProgress)
+	}
+
+	// Only one operation at a time
+	if status, _ := doRequest(t, ts, http.MethodPost, "/v1/deploy", `{"model": "qwen2.5-coder:7b"}`); status != http.StatusConflict {
+		t.Errorf("second deploy status = %d, want 409", status)
+	}
+	if status, _ := doRequest(t, ts, http.MethodPost, "/v1/stop", ""); status != http.StatusConflict {
+		t.Errorf("stop during deploy status = %d, want 409", status)
+	}
+
+	close(backend.release)
+
+	event, data := readEvent(t, events)
+	if event != EventDeployResult || !strings.Contains(data, `"status":"ready"`) {
+		t.Errorf("result event = %s %s, want deploy_result ready", event, data)
+	}
+
+	backend.mu.Lock()
+	if len(backend.deployReqs) != 1 || !backend.deployReqs[0].OnDemand {
+		t.Errorf("deploy requests = %+v", backend.deployReqs)
+	}
+	backend.mu.Unlock()
+
+	// The operation slot is freed once the result is published
+	deadline := time.Now().Add(2 * time.Second)
+	for !srv.begin("test") {
+		if time.Now().After(deadline) {
+			t.Fatal("deployment still marked as running")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	srv.end()
+}
+
+func TestServer_DeployErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"invalid body", `{"model": `, nil, http.StatusBadRequest},
+		{"unknown field", `{"modle": "x"}`, nil, http.StatusBadRequest},
+		{"rejected", `{"model": "x"}`, fmt.Errorf("%w: unknown model", ErrBadRequest), http.StatusBadRequest},
+		{"session active", `{"model": "x"}`, fmt.Errorf("%w: instance running", ErrConflict), http.StatusConflict},
+		{"failure", `{"model": "x"}`, errors.New("boom"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, backend, ts := newTestServer(t)
+			backend.deployErr = tt.err
+
+			status, body := doRequest(t, ts, http.MethodPost, "/v1/deploy", tt.body)
+			if status != tt.want {
+				t.Errorf("status = %d, want %d", status, tt.want)
+			}
+			if body["status"] != "error" || body["error"] == "" {
+				t.Errorf("body = %v, want error body", body)
+			}
+
+			// A rejected deployment doesn't block the next one
+			backend.deployErr = nil
+			close(backend.release)
+			if status, _ := doRequest(t, ts, http.MethodPost, "/v1/deploy", `{"model": "x"}`); status != http.StatusAccepted {
+				t.Errorf("next deploy status = %d, want 202", status)
+			}
+		})
+	}
+}
+
+func TestServer_Serve_CancelsDeployment(t *testing.T) {
+	backend := &fakeBackend{release: make(chan struct{})}
+	srv, err := New(backend, testToken)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	backend.publish = srv.Publish
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Serve(ctx, ln)
+	}()
+
+	url := "http://" + ln.Addr().String()
+	req, _ := http.NewRequest(http.MethodPost, url+"/v1/deploy", strings.NewReader(`{"model": "x"}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /v1/deploy error = %v", err)
+	}
+	resp.Body.Close()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve() did not return after cancel")
+	}
+	if !srv.begin("test") {
+		t.Error("deployment should have finished before Serve() returned")
+	}
+}
+
+func TestCheckLoopback(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{"127.0.0.1:7717", false},
+		{"localhost:7717", false},
+		{"[::1]:7717", false},
+		{"0.0.0.0:7717", true},
+		{":7717", true},
+		{"192.168.1.10:7717", true},
+		{"7717", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			err := checkLoopback(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkLoopback(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -4,7 +4,6 @@ package ui
 import (
 	"context"
 	"fmt"
-	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -12,7 +11,9 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/tmeurs/spinup/internal/config"
 	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/registry"
 	"github.com/tmeurs/spinup/internal/wireguard"
 )
 
@@ -131,6 +132,10 @@ type InitWizardModel struct {
 	savingConfig bool
 	saveError    error
 
+	// Secret backend the keys are stored in (nil writes them to .env)
+	secrets       config.SecretBackend
+	secretsConfig config.SecretsConfig
+
 	// Terminal dimensions
 	width  int
 	height int
@@ -203,6 +208,13 @@ func (m *InitWizardModel) SetProviderFactory(factory ProviderFactory) {
 	m.providerFactory = factory
 }
 
+// SetSecrets sets the secret backend the API keys and the WireGuard private
+// key are stored in. The .env file then references them.
+func (m *InitWizardModel) SetSecrets(backend config.SecretBackend, cfg config.SecretsConfig) {
+	m.secrets = backend
+	m.secretsConfig = cfg
+}
+
 // Init implements tea.Model.
 func (m InitWizardModel) Init() tea.Cmd {
 	return nil
@@ -677,70 +689,20 @@ func (m InitWizardModel) validateExistingWireGuardKey(privateKey string) tea.Cmd
 // saveConfig creates a command to save the configuration to .env file.
 func (m InitWizardModel) saveConfig() tea.Cmd {
 	return func() tea.Msg {
-		// Build the .env content
-		var content strings.Builder
-
-		content.WriteString("# spinup Configuration\n")
-		content.WriteString("# Generated by spinup init\n")
-		content.WriteString("# IMPORTANT: Keep this file secure (permissions should be 0600)\n\n")
-
-		// Provider API Keys
-		content.WriteString("# Provider API Keys\n")
-		validatedKeys := m.GetValidatedAPIKeys()
-
-		// Write all provider keys (empty for unconfigured)
-		for _, p := range AllProviders {
-			envKey := strings.ToUpper(p.Name) + "_API_KEY"
-			if p.Name == "vast" {
-				envKey = "VAST_API_KEY"
-			} else if p.Name == "lambda" {
-				envKey = "LAMBDA_API_KEY"
-			} else if p.Name == "runpod" {
-				envKey = "RUNPOD_API_KEY"
-			} else if p.Name == "coreweave" {
-				envKey = "COREWEAVE_API_KEY"
-			} else if p.Name == "paperspace" {
-				envKey = "PAPERSPACE_API_KEY"
-			}
-
-			if key, ok := validatedKeys[p.Name]; ok {
-				content.WriteString(fmt.Sprintf("%s=%s\n", envKey, key))
-			} else {
-				content.WriteString(fmt.Sprintf("%s=\n", envKey))
-			}
+		env := &config.EnvFile{
+			APIKeys:             m.GetValidatedAPIKeys(),
+			DefaultTier:         m.selectedTier,
+			DeadmanTimeoutHours: m.GetDeadmanTimeout(),
+			Secrets:             m.secrets,
+			SecretsConfig:       m.secretsConfig,
 		}
-
-		content.WriteString("\n")
-
-		// WireGuard keys
-		content.WriteString("# WireGuard Keys\n")
 		if m.wireGuardKeyPair != nil {
-			content.WriteString(fmt.Sprintf("WIREGUARD_PRIVATE_KEY=%s\n", m.wireGuardKeyPair.PrivateKey))
-			content.WriteString(fmt.Sprintf("WIREGUARD_PUBLIC_KEY=%s\n", m.wireGuardKeyPair.PublicKey))
-		} else {
-			content.WriteString("WIREGUARD_PRIVATE_KEY=\n")
-			content.WriteString("WIREGUARD_PUBLIC_KEY=\n")
+			env.WireGuardPrivateKey = m.wireGuardKeyPair.PrivateKey
+			env.WireGuardPublicKey = m.wireGuardKeyPair.PublicKey
 		}
 
-		content.WriteString("\n")
-
-		// Preferences
-		content.WriteString("# Preferences\n")
-		content.WriteString(fmt.Sprintf("DEFAULT_TIER=%s\n", m.selectedTier))
-		content.WriteString("DEFAULT_REGION=eu-west\n")
-		content.WriteString("PREFER_SPOT=true\n")
-		content.WriteString(fmt.Sprintf("DEADMAN_TIMEOUT_HOURS=%s\n", m.deadmanTimeoutInput))
-
-		content.WriteString("\n")
-
-		// Alerting (optional, leave empty)
-		content.WriteString("# Alerting (optional)\n")
-		content.WriteString("ALERT_WEBHOOK_URL=\n")
-		content.WriteString("DAILY_BUDGET_EUR=20\n")
-
-		// Write to file with 0600 permissions
-		err := os.WriteFile(".env", []byte(content.String()), 0o600)
-		if err != nil {
+		// spinup init checks for an existing .env before starting the wizard
+		if err := env.Write(config.DefaultEnvPath, true); err != nil {
 			return ConfigSavedMsg{Error: fmt.Errorf("failed to write .env file: %w", err)}
 		}
 
@@ -1014,50 +976,50 @@ func (m InitWizardModel) renderAccountInfo(b *strings.Builder, info *provider.Ac
 	}
 }
 
+// APIKeyValidationTimeout is the timeout for validating one API key.
+const APIKeyValidationTimeout = 30 * time.Second
+
 // validateAPIKey creates a command to validate an API key.
 func (m InitWizardModel) validateAPIKey(providerName, apiKey string) tea.Cmd {
 	return func() tea.Msg {
-		// Use the provider factory if available
-		if m.providerFactory == nil {
-			return APIKeyValidationResultMsg{
-				Provider: providerName,
-				Valid:    false,
-				Error:    fmt.Errorf("no provider factory configured"),
-			}
-		}
-
-		// Create provider instance
-		p, err := m.providerFactory(providerName, apiKey)
-		if err != nil {
-			return APIKeyValidationResultMsg{
-				Provider: providerName,
-				Valid:    false,
-				Error:    err,
-			}
-		}
-
-		// Validate the API key
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), APIKeyValidationTimeout)
 		defer cancel()
 
-		info, err := p.ValidateAPIKey(ctx)
-		if err != nil {
-			return APIKeyValidationResultMsg{
-				Provider: providerName,
-				Valid:    false,
-				Error:    err,
-			}
-		}
-
+		info, err := ValidateAPIKey(ctx, m.providerFactory, providerName, apiKey)
 		return APIKeyValidationResultMsg{
 			Provider:    providerName,
-			Valid:       info != nil && info.Valid,
+			Valid:       err == nil,
 			AccountInfo: info,
-			Error:       nil,
+			Error:       err,
 		}
 	}
 }
 
+// ValidateAPIKey creates the named provider with factory and validates
+// apiKey against its API. It returns an error if the provider can't be
+// reached or doesn't accept the key. Both the init wizard and the
+// non-interactive 'spinup init' use it.
+func ValidateAPIKey(ctx context.Context, factory ProviderFactory, providerName, apiKey string) (*provider.AccountInfo, error) {
+	if factory == nil {
+		return nil, fmt.Errorf("no provider factory configured")
+	}
+
+	p, err := factory(providerName, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := p.ValidateAPIKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil || !info.Valid {
+		return info, provider.ErrAuthenticationFailed.Wrap(fmt.Errorf("%s did not accept the API key", providerName))
+	}
+
+	return info, nil
+}
+
 // renderWireGuard renders the WireGuard setup view.
 func (m InitWizardModel) renderWireGuard() string {
 	var b strings.Builder
@@ -1270,7 +1232,13 @@ func (m InitWizardModel) renderComplete() string {
 	// Summary
 	b.WriteString(Styles.Body.Render("Your configuration has been saved to "))
 	b.WriteString(Styles.Highlighted.Render(".env"))
-	b.WriteString("\n\n")
+	b.WriteString("\n")
+	if m.secrets != nil {
+		b.WriteString(Styles.Body.Render("Keys are stored in "))
+		b.WriteString(Styles.Highlighted.Render(m.secrets.String()))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
 
 	// What was configured
 	b.WriteString(Styles.Muted.Render("Configured:"))
@@ -1583,12 +1551,12 @@ type ConfigSavedMsg struct {
 
 // Commands
 
-// RunInitWizard starts the init wizard.
-func RunInitWizard() error {
-	p := tea.NewProgram(
-		NewInitWizardModel(),
-		tea.WithAltScreen(),
-	)
+// RunInitWizard starts the init wizard. With a secret backend, the API keys
+// and the WireGuard private key are stored in it instead of the .env file.
+func RunInitWizard(secrets config.SecretBackend, secretsConfig config.SecretsConfig) error {
+	model := NewInitWizardModelWithFactory(registry.NewProviderWithKey)
+	model.SetSecrets(secrets, secretsConfig)
+	p := tea.NewProgram(model, tea.WithAltScreen())
 
 	finalModel, err := p.Run()
 	if err != nil {
@@ -1,9 +1,14 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/mock"
 )
 
 func TestNewInitWizardModel(t *testing.T) {
@@ -400,6 +405,53 @@ func TestInitWizardWindowSizeMsg(t *testing.T) {
 	}
 }
 
+func TestValidateAPIKey(t *testing.T) {
+	factoryFor := func(opts ...mock.Option) ProviderFactory {
+		return func(providerName, apiKey string) (provider.Provider, error) {
+			return mock.New(append([]mock.Option{mock.WithName(providerName)}, opts...)...), nil
+		}
+	}
+
+	tests := []struct {
+		name    string
+		factory ProviderFactory
+		wantErr bool
+	}{
+		{"valid", factoryFor(mock.WithAccountInfo(&provider.AccountInfo{Valid: true, Email: "dev@example.com"})), false},
+		{"rejected", factoryFor(mock.WithValidateAPIKeyError(provider.ErrAuthenticationFailed)), true},
+		{"not valid", factoryFor(mock.WithAccountInfo(&provider.AccountInfo{Valid: false})), true},
+		{"factory error", func(string, string) (provider.Provider, error) { return nil, errors.New("boom") }, true},
+		{"no factory", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ValidateAPIKey(context.Background(), tt.factory, "vast", "key")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateAPIKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && info.Email != "dev@example.com" {
+				t.Errorf("Email = %q, want dev@example.com", info.Email)
+			}
+		})
+	}
+}
+
+func TestInitWizardValidateAPIKeyMsg(t *testing.T) {
+	m := NewInitWizardModelWithFactory(func(providerName, apiKey string) (provider.Provider, error) {
+		return mock.New(mock.WithName(providerName), mock.WithValidateAPIKeyError(provider.ErrAuthenticationFailed)), nil
+	})
+
+	msg := m.validateAPIKey("vast", "bad-key")()
+	result, ok := msg.(APIKeyValidationResultMsg)
+	if !ok {
+		t.Fatalf("validateAPIKey() returned %T, want APIKeyValidationResultMsg", msg)
+	}
+	if result.Valid || !errors.Is(result.Error, provider.ErrAuthenticationFailed) {
+		t.Errorf("result = %+v, want an authentication failure", result)
+	}
+}
+
 // Helper function
 func containsString(s, substr string) bool {
 	return len(s) > 0 && len(substr) > 0 && (s == substr || len(s) > len(substr) && (s[:len(substr)] == substr || s[len(s)-len(substr):] == substr || findSubstring(s, substr)))
@@ -5,18 +5,39 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/offerexpr"
 	"github.com/tmeurs/spinup/internal/provider"
 )
 
 // ProviderSelectModel is the Bubbletea model for the provider selection view.
 // It displays a table of available GPU offers across all configured providers.
 type ProviderSelectModel struct {
-	// Offers is the list of available GPU offers
+	// Offers is the list of available GPU offers that match the filter
 	offers []provider.Offer
 
+	// allOffers is the unfiltered list of offers
+	allOffers []provider.Offer
+
+	// filter is the offer filter expression (nil shows all offers)
+	filter *offerexpr.Filter
+
+	// searching indicates the filter search box has focus
+	searching bool
+
+	// filterInput is the text of the filter search box
+	filterInput string
+
+	// filterCursorPos is the cursor position in the filter search box
+	filterCursorPos int
+
+	// filterErr holds the error of the last filter that failed to parse
+	filterErr error
+
 	// cursor is the index of the currently highlighted row
 	cursor int
 
@@ -29,6 +50,21 @@ type ProviderSelectModel struct {
 	// err holds any error during offer fetching
 	err error
 
+	// reports describes the last offer request to each provider
+	reports []deploy.ProviderFetchReport
+
+	// scores holds the offer scores keyed by deploy.OfferKey (nil if not scored)
+	scores map[string]deploy.OfferScore
+
+	// stale indicates the offers come from the offer cache and may be outdated
+	stale bool
+
+	// fetchedAt is when the stale offers were fetched
+	fetchedAt time.Time
+
+	// refreshErr holds the error of a failed refresh while stale offers are shown
+	refreshErr error
+
 	// width is the terminal width
 	width int
 
@@ -56,9 +92,7 @@ func NewProviderSelectModel() ProviderSelectModel {
 // NewProviderSelectModelWithOffers creates a new model with pre-loaded offers
 func NewProviderSelectModelWithOffers(offers []provider.Offer) ProviderSelectModel {
 	m := NewProviderSelectModel()
-	m.offers = offers
-	m.loading = false
-	m.sortOffersByPrice()
+	m.SetOffers(offers)
 	return m
 }
 
@@ -84,16 +118,27 @@ func (m ProviderSelectModel) Update(msg tea.Msg) (ProviderSelectModel, tea.Cmd)
 		return m, nil
 
 	case OffersLoadedMsg:
-		m.offers = msg.Offers
+		m.allOffers = msg.Offers
+		m.reports = msg.Reports
+		m.scores = msg.Scores
+		m.stale = msg.Stale
+		m.fetchedAt = msg.FetchedAt
+		m.refreshErr = nil
 		m.loading = false
 		m.err = nil
-		// Sort offers by effective price (spot if available, else on-demand)
-		m.sortOffersByPrice()
+		// Filter, then sort offers by score if scored, else by effective price
+		m.applyFilter()
 		return m, nil
 
 	case OffersLoadErrorMsg:
-		m.err = msg.Err
+		m.reports = msg.Reports
 		m.loading = false
+		// Keep showing cached offers when the providers can't be reached
+		if m.stale && len(m.allOffers) > 0 {
+			m.refreshErr = msg.Err
+			return m, nil
+		}
+		m.err = msg.Err
 		return m, nil
 	}
 
@@ -102,6 +147,10 @@ func (m ProviderSelectModel) Update(msg tea.Msg) (ProviderSelectModel, tea.Cmd)
 
 // handleKeyPress processes keyboard input
 func (m ProviderSelectModel) handleKeyPress(msg tea.KeyMsg) (ProviderSelectModel, tea.Cmd) {
+	if m.searching {
+		return m.handleSearchKey(msg)
+	}
+
 	switch msg.String() {
 	case "up", "k":
 		if m.cursor > 0 {
@@ -141,9 +190,21 @@ func (m ProviderSelectModel) handleKeyPress(msg tea.KeyMsg) (ProviderSelectModel
 			}
 		}
 
+	case "/":
+		// Open the filter search box with the current filter
+		m.searching = true
+		m.filterInput = m.filter.String()
+		m.filterCursorPos = len(m.filterInput)
+		m.filterErr = nil
+
 	case "r":
-		// Refresh prices - return command to trigger refresh
-		m.loading = true
+		// Refresh prices - return command to trigger refresh.
+		// Cached offers stay visible while refreshing.
+		if m.stale {
+			m.refreshErr = nil
+		} else {
+			m.loading = true
+		}
 		return m, func() tea.Msg {
 			return RefreshOffersMsg{}
 		}
@@ -152,6 +213,80 @@ func (m ProviderSelectModel) handleKeyPress(msg tea.KeyMsg) (ProviderSelectModel
 	return m, nil
 }
 
+// handleSearchKey processes keyboard input while the filter search box has focus
+func (m ProviderSelectModel) handleSearchKey(msg tea.KeyMsg) (ProviderSelectModel, tea.Cmd) {
+	switch msg.String() {
+	case "enter":
+		// Apply the filter; an empty filter shows all offers
+		filter, err := offerexpr.Parse(m.filterInput)
+		if err != nil {
+			m.filterErr = err
+			return m, nil
+		}
+		m.filter = filter
+		m.filterErr = nil
+		m.searching = false
+		m.cursor = 0
+		m.selected = -1
+		m.applyFilter()
+
+	case "esc":
+		// Close the search box and keep the current filter
+		m.searching = false
+		m.filterErr = nil
+
+	case "backspace":
+		if m.filterCursorPos > 0 && len(m.filterInput) > 0 {
+			m.filterInput = m.filterInput[:m.filterCursorPos-1] + m.filterInput[m.filterCursorPos:]
+			m.filterCursorPos--
+		}
+
+	case "delete":
+		if m.filterCursorPos < len(m.filterInput) {
+			m.filterInput = m.filterInput[:m.filterCursorPos] + m.filterInput[m.filterCursorPos+1:]
+		}
+
+	case "left":
+		if m.filterCursorPos > 0 {
+			m.filterCursorPos--
+		}
+
+	case "right":
+		if m.filterCursorPos < len(m.filterInput) {
+			m.filterCursorPos++
+		}
+
+	case "home", "ctrl+a":
+		m.filterCursorPos = 0
+
+	case "end", "ctrl+e":
+		m.filterCursorPos = len(m.filterInput)
+
+	case "ctrl+u":
+		m.filterInput = ""
+		m.filterCursorPos = 0
+
+	default:
+		// Only accept printable characters; pasted text arrives as several runes
+		if msg.Type != tea.KeyRunes && msg.Type != tea.KeySpace {
+			return m, nil
+		}
+		text := string(msg.Runes)
+		if msg.Type == tea.KeySpace {
+			text = " "
+		}
+		for _, c := range text {
+			if c < 32 || c > 126 {
+				return m, nil
+			}
+		}
+		m.filterInput = m.filterInput[:m.filterCursorPos] + text + m.filterInput[m.filterCursorPos:]
+		m.filterCursorPos += len(text)
+	}
+
+	return m, nil
+}
+
 // View implements tea.Model
 func (m ProviderSelectModel) View() string {
 	if !m.ready {
@@ -187,20 +322,33 @@ func (m ProviderSelectModel) renderTable() string {
 		errorStyle := Styles.Error
 		b.WriteString(errorStyle.Render(fmt.Sprintf("  Error: %v", m.err)))
 		b.WriteString("\n")
+		b.WriteString(m.renderProviderReports())
 		b.WriteString(Styles.Muted.Render("  Press [r] to retry"))
 		b.WriteString("\n")
 		return Styles.Box.Width(m.width - 4).Render(b.String())
 	}
 
+	b.WriteString(m.renderFilter())
+
 	// Handle empty state
 	if len(m.offers) == 0 {
-		b.WriteString(Styles.Muted.Render("  No offers available"))
-		b.WriteString("\n")
-		b.WriteString(Styles.Muted.Render("  Press [r] to refresh"))
+		if len(m.allOffers) > 0 {
+			b.WriteString(Styles.Muted.Render(fmt.Sprintf("  None of %d offers match the filter", len(m.allOffers))))
+			b.WriteString("\n")
+			b.WriteString(Styles.Muted.Render("  Press [/] to change the filter"))
+		} else {
+			b.WriteString(Styles.Muted.Render("  No offers available"))
+			b.WriteString("\n")
+			b.WriteString(Styles.Muted.Render("  Press [r] to refresh"))
+		}
 		b.WriteString("\n")
 		return Styles.Box.Width(m.width - 4).Render(b.String())
 	}
 
+	if m.stale {
+		b.WriteString(m.renderStaleNotice())
+	}
+
 	// Column widths
 	colProvider := 12
 	colGPU := 14
@@ -208,6 +356,7 @@ func (m ProviderSelectModel) renderTable() string {
 	colSpot := 10
 	colOnDemand := 12
 	colDayEst := 10
+	colScore := 6
 
 	// Header
 	headerStyle := Styles.TableHeader
@@ -218,11 +367,17 @@ func (m ProviderSelectModel) renderTable() string {
 		colSpot, "Spot/hr",
 		colOnDemand, "OnDemand/hr",
 		colDayEst, "Day Est.")
+	if m.scores != nil {
+		header += fmt.Sprintf(" %*s", colScore, "Score")
+	}
 	b.WriteString(headerStyle.Render(header))
 	b.WriteString("\n")
 
 	// Separator
 	sepLen := colProvider + colGPU + colRegion + colSpot + colOnDemand + colDayEst + 12
+	if m.scores != nil {
+		sepLen += colScore + 1
+	}
 	b.WriteString(Styles.Muted.Render("  " + strings.Repeat(TableHorizontal, sepLen)))
 	b.WriteString("\n")
 
@@ -265,6 +420,9 @@ func (m ProviderSelectModel) renderTable() string {
 			colSpot, spotStr,
 			colOnDemand, onDemandStr,
 			colDayEst, dayEstStr)
+		if m.scores != nil {
+			row += fmt.Sprintf(" %*s", colScore, m.formatScore(offer))
+		}
 
 		// Apply styling based on selection state
 		if i == m.cursor {
@@ -300,6 +458,8 @@ func (m ProviderSelectModel) renderTable() string {
 		b.WriteString("\n")
 	}
 
+	b.WriteString(m.renderScoreBreakdown())
+	b.WriteString(m.renderProviderReports())
 	b.WriteString("\n")
 
 	// Key hints
@@ -309,20 +469,129 @@ func (m ProviderSelectModel) renderTable() string {
 	return Styles.Box.Width(m.width - 4).Render(b.String())
 }
 
+// renderFilter renders the filter search box while it has focus, or the
+// active filter and how many offers it hides
+func (m ProviderSelectModel) renderFilter() string {
+	if m.searching {
+		input := m.filterInput[:m.filterCursorPos] + "_" + m.filterInput[m.filterCursorPos:]
+		line := Styles.Bold.Render("  Filter: ") + Styles.Body.Render(input) + "\n"
+		if m.filterErr != nil {
+			line += Styles.Error.Render(fmt.Sprintf("  %s %v", IconError, m.filterErr)) + "\n"
+		} else {
+			line += Styles.Muted.Render("  e.g. vram >= 48 && region in [eu-west, us-east] && spot < 0.9") + "\n"
+		}
+		return line + "\n"
+	}
+	if m.filter == nil {
+		return ""
+	}
+	return Styles.Muted.Render(fmt.Sprintf("  Filter: %s (%d of %d offers)", m.filter, len(m.offers), len(m.allOffers))) + "\n\n"
+}
+
+// renderProviderReports renders one line per provider with its offer count
+// and latency, or its error code if fetching failed
+func (m ProviderSelectModel) renderProviderReports() string {
+	if len(m.reports) == 0 {
+		return ""
+	}
+
+	var parts []string
+	for _, r := range m.reports {
+		latency := fmt.Sprintf("%dms", r.Latency.Milliseconds())
+		if r.OK() {
+			parts = append(parts, Styles.Muted.Render(fmt.Sprintf("%s %s %d (%s)", IconSuccess, r.Provider, r.OfferCount, latency)))
+		} else {
+			parts = append(parts, Styles.Warning.Render(fmt.Sprintf("%s %s %s (%s)", IconError, r.Provider, r.ErrorCode, latency)))
+		}
+	}
+	return "  " + strings.Join(parts, "  ") + "\n"
+}
+
+// renderStaleNotice renders the age of cached offers and the refresh state
+func (m ProviderSelectModel) renderStaleNotice() string {
+	age := "cached offers"
+	if !m.fetchedAt.IsZero() {
+		age = fmt.Sprintf("cached offers from %s ago", formatDuration(time.Since(m.fetchedAt)))
+	}
+	if m.refreshErr != nil {
+		return Styles.Warning.Render(fmt.Sprintf("  %s Showing %s, refresh failed: %v", IconWarning, age, m.refreshErr)) + "\n\n"
+	}
+	return Styles.Muted.Render(fmt.Sprintf("  Showing %s, refreshing...", age)) + "\n\n"
+}
+
+// renderScoreBreakdown renders the score factors of the highlighted offer
+func (m ProviderSelectModel) renderScoreBreakdown() string {
+	if m.cursor < 0 || m.cursor >= len(m.offers) {
+		return ""
+	}
+	score, ok := m.scores[deploy.OfferKey(m.offers[m.cursor])]
+	if !ok {
+		return ""
+	}
+	return Styles.Muted.Render("  Score: "+score.String()) + "\n"
+}
+
+// formatScore formats the score of an offer for display
+func (m ProviderSelectModel) formatScore(o provider.Offer) string {
+	score, ok := m.scores[deploy.OfferKey(o)]
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", score.Total)
+}
+
 // renderKeyHints renders the keyboard shortcut hints
 func (m ProviderSelectModel) renderKeyHints() string {
+	if m.searching {
+		hints := []string{
+			FormatKeyHint("Enter", "Apply"),
+			FormatKeyHint("Esc", "Cancel"),
+			FormatKeyHint("Ctrl+U", "Clear"),
+		}
+		return Styles.Muted.Render(strings.Join(hints, "  "))
+	}
+
 	hints := []string{
 		FormatKeyHint("↑↓", "Navigate"),
 		FormatKeyHint("Enter", "Select"),
+		FormatKeyHint("/", "Filter"),
 		FormatKeyHint("r", "Refresh"),
 		FormatKeyHint("q", "Quit"),
 	}
 	return Styles.Muted.Render(strings.Join(hints, "  "))
 }
 
-// sortOffersByPrice sorts offers by effective price (spot if available, then on-demand)
-func (m *ProviderSelectModel) sortOffersByPrice() {
-	sort.Slice(m.offers, func(i, j int) bool {
+// applyFilter sets the shown offers to the offers matching the filter, sorted,
+// and keeps the cursor in range
+func (m *ProviderSelectModel) applyFilter() {
+	m.offers = m.filter.Apply(m.allOffers)
+	if m.filter == nil {
+		// Apply returns allOffers itself; copy so sorting leaves it alone
+		m.offers = append([]provider.Offer(nil), m.allOffers...)
+	}
+	m.sortOffers()
+	if m.cursor >= len(m.offers) {
+		m.cursor = len(m.offers) - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+	if m.selected >= len(m.offers) {
+		m.selected = -1
+	}
+}
+
+// sortOffers sorts offers by descending score, or by effective price (spot if
+// available, then on-demand) if the offers have not been scored
+func (m *ProviderSelectModel) sortOffers() {
+	sort.SliceStable(m.offers, func(i, j int) bool {
+		if m.scores != nil {
+			scoreI := m.scores[deploy.OfferKey(m.offers[i])].Total
+			scoreJ := m.scores[deploy.OfferKey(m.offers[j])].Total
+			if scoreI != scoreJ {
+				return scoreI > scoreJ
+			}
+		}
 		priceI := effectivePrice(m.offers[i])
 		priceJ := effectivePrice(m.offers[j])
 		return priceI < priceJ
@@ -361,12 +630,24 @@ func formatDayEstimate(o provider.Offer) string {
 
 // OffersLoadedMsg is sent when offers have been loaded
 type OffersLoadedMsg struct {
-	Offers []provider.Offer
+	Offers  []provider.Offer
+	Reports []deploy.ProviderFetchReport
+
+	// Scores holds the offer scores keyed by deploy.OfferKey (optional)
+	Scores map[string]deploy.OfferScore
+
+	// Stale is true if the offers come from the offer cache; fresh offers
+	// are expected to follow
+	Stale bool
+
+	// FetchedAt is when stale offers were fetched (optional)
+	FetchedAt time.Time
 }
 
 // OffersLoadErrorMsg is sent when there's an error loading offers
 type OffersLoadErrorMsg struct {
-	Err error
+	Err     error
+	Reports []deploy.ProviderFetchReport
 }
 
 // OfferSelectedMsg is sent when an offer is selected
@@ -381,16 +662,32 @@ type RefreshOffersMsg struct{}
 
 // SetOffers sets the offers list
 func (m *ProviderSelectModel) SetOffers(offers []provider.Offer) {
-	m.offers = offers
+	m.allOffers = offers
 	m.loading = false
-	m.sortOffersByPrice()
+	m.applyFilter()
 }
 
-// GetOffers returns the current offers
+// GetOffers returns the current offers that match the filter
 func (m ProviderSelectModel) GetOffers() []provider.Offer {
 	return m.offers
 }
 
+// SetFilter sets the offer filter (nil shows all offers)
+func (m *ProviderSelectModel) SetFilter(filter *offerexpr.Filter) {
+	m.filter = filter
+	m.applyFilter()
+}
+
+// GetFilter returns the offer filter (nil if none)
+func (m ProviderSelectModel) GetFilter() *offerexpr.Filter {
+	return m.filter
+}
+
+// IsSearching returns true while the filter search box has focus
+func (m ProviderSelectModel) IsSearching() bool {
+	return m.searching
+}
+
 // SetLoading sets the loading state
 func (m *ProviderSelectModel) SetLoading(loading bool) {
 	m.loading = loading
@@ -407,6 +704,21 @@ func (m *ProviderSelectModel) SetError(err error) {
 	m.loading = false
 }
 
+// IsStale returns true if the shown offers come from the offer cache
+func (m ProviderSelectModel) IsStale() bool {
+	return m.stale
+}
+
+// GetScores returns the offer scores keyed by deploy.OfferKey
+func (m ProviderSelectModel) GetScores() map[string]deploy.OfferScore {
+	return m.scores
+}
+
+// GetReports returns the offer fetch reports of the last refresh
+func (m ProviderSelectModel) GetReports() []deploy.ProviderFetchReport {
+	return m.reports
+}
+
 // GetError returns the current error
 func (m ProviderSelectModel) GetError() error {
 	return m.err
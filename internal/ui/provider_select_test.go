@@ -3,8 +3,11 @@ package ui
 import (
 	"strings"
 	"testing"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/offerexpr"
 	"github.com/tmeurs/spinup/internal/provider"
 )
 
@@ -580,3 +583,259 @@ func TestProviderSelectModel_WindowSizeMsg(t *testing.T) {
 func contains(s, substr string) bool {
 	return strings.Contains(s, substr)
 }
+
+func TestProviderSelectModel_ProviderReports(t *testing.T) {
+	m := NewProviderSelectModel()
+	m.SetDimensions(120, 40)
+
+	reports := []deploy.ProviderFetchReport{
+		{Provider: "vast", OfferCount: 3, Latency: 340 * time.Millisecond},
+		{Provider: "lambda", ErrorCode: "authentication_failed", Err: provider.ErrAuthenticationFailed},
+	}
+	m, _ = m.Update(OffersLoadedMsg{Offers: createTestOffers(), Reports: reports})
+
+	if len(m.GetReports()) != 2 {
+		t.Fatalf("Expected 2 reports, got %d", len(m.GetReports()))
+	}
+
+	view := m.View()
+	if !strings.Contains(view, "vast 3 (340ms)") {
+		t.Error("Expected view to show vast offer count and latency")
+	}
+	if !strings.Contains(view, "lambda authentication_failed") {
+		t.Error("Expected view to show lambda error code")
+	}
+
+	// Reports also explain an empty result
+	m, _ = m.Update(OffersLoadErrorMsg{Err: provider.ErrAuthenticationFailed, Reports: reports[1:]})
+	if !strings.Contains(m.View(), "lambda authentication_failed") {
+		t.Error("Expected error view to show lambda error code")
+	}
+}
+
+func TestProviderSelectModel_Scores(t *testing.T) {
+	m := NewProviderSelectModel()
+	m.SetDimensions(140, 40)
+
+	offers := createTestOffers()
+	scores := make(map[string]deploy.OfferScore)
+	for _, o := range offers {
+		scores[deploy.OfferKey(o)] = deploy.OfferScore{Total: 0.5}
+	}
+	// The lambda offer isn't the cheapest but scores best
+	scores["lambda/lambda-1"] = deploy.OfferScore{
+		Total: 0.9,
+		Factors: []deploy.ScoreFactor{
+			{Name: deploy.FactorPrice, Value: 0.8, Weight: 1},
+			{Name: deploy.FactorProvider, Value: 1, Weight: 0.25},
+		},
+	}
+	m, _ = m.Update(OffersLoadedMsg{Offers: offers, Scores: scores})
+
+	if got := m.GetOffers()[0].OfferID; got != "lambda-1" {
+		t.Errorf("Expected best scored offer first, got %s", got)
+	}
+	if len(m.GetScores()) != len(offers) {
+		t.Errorf("Expected %d scores, got %d", len(offers), len(m.GetScores()))
+	}
+
+	view := m.View()
+	if !strings.Contains(view, "Score") {
+		t.Error("Expected view to show score column")
+	}
+	if !strings.Contains(view, "0.90 (price 0.80, provider 1.00)") {
+		t.Error("Expected view to show score breakdown of highlighted offer")
+	}
+
+	// Unscored offers drop the score column
+	m, _ = m.Update(OffersLoadedMsg{Offers: createTestOffers()})
+	if m.GetScores() != nil {
+		t.Error("Expected scores to be cleared")
+	}
+	if strings.Contains(m.View(), "Score") {
+		t.Error("Expected no score column without scores")
+	}
+}
+
+func TestProviderSelectModel_StaleOffers(t *testing.T) {
+	m := NewProviderSelectModel()
+	m.SetDimensions(140, 40)
+
+	m, _ = m.Update(OffersLoadedMsg{Offers: createTestOffers(), Stale: true, FetchedAt: time.Now().Add(-3 * time.Minute)})
+	if !m.IsStale() || m.IsLoading() {
+		t.Fatal("Expected stale offers to be shown without loading state")
+	}
+	if !strings.Contains(m.View(), "cached offers from 3m ago, refreshing") {
+		t.Error("Expected view to mark cached offers as refreshing")
+	}
+
+	// A failed refresh keeps the cached offers visible
+	m, _ = m.Update(OffersLoadErrorMsg{Err: provider.ErrRateLimited})
+	if m.GetError() != nil {
+		t.Errorf("Expected no error state, got %v", m.GetError())
+	}
+	if len(m.GetOffers()) != len(createTestOffers()) {
+		t.Error("Expected cached offers to be kept")
+	}
+	if !strings.Contains(m.View(), "refresh failed") {
+		t.Error("Expected view to show the failed refresh")
+	}
+
+	// Fresh offers replace the cached ones
+	m, _ = m.Update(OffersLoadedMsg{Offers: createTestOffers()[:2]})
+	if m.IsStale() {
+		t.Error("Expected fresh offers to clear the stale flag")
+	}
+	if strings.Contains(m.View(), "cached offers") {
+		t.Error("Expected no cache notice for fresh offers")
+	}
+}
+
+func TestProviderSelectModel_RefreshKeepsStaleOffers(t *testing.T) {
+	m := NewProviderSelectModel()
+	m.SetDimensions(140, 40)
+	m, _ = m.Update(OffersLoadedMsg{Offers: createTestOffers(), Stale: true})
+
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	if cmd == nil {
+		t.Fatal("Expected refresh command")
+	}
+	if m.IsLoading() {
+		t.Error("Expected cached offers to stay visible while refreshing")
+	}
+}
+
+// typeKeys sends text to the model one key at a time
+func typeKeys(m ProviderSelectModel, text string) ProviderSelectModel {
+	for _, r := range text {
+		if r == ' ' {
+			m, _ = m.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{r}})
+			continue
+		}
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return m
+}
+
+func TestProviderSelectModel_SetFilter(t *testing.T) {
+	m := NewProviderSelectModelWithOffers(createTestOffers())
+	m.SetDimensions(140, 40)
+	m.SetCursor(5)
+
+	filter, err := offerexpr.Parse("vram >= 48 && provider != paperspace")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	m.SetFilter(filter)
+
+	var ids []string
+	for _, o := range m.GetOffers() {
+		ids = append(ids, o.OfferID)
+	}
+	// Sorted by effective price: runpod-2 €0.45, vast-2 €0.89
+	if got := strings.Join(ids, ","); got != "runpod-2,vast-2" {
+		t.Errorf("filtered offers = %s, want runpod-2,vast-2", got)
+	}
+	if m.GetCursor() != 1 {
+		t.Errorf("Expected cursor clamped to 1, got %d", m.GetCursor())
+	}
+	if !strings.Contains(m.View(), "(2 of 6 offers)") {
+		t.Error("Expected view to show the active filter")
+	}
+
+	// New offers are filtered too
+	m, _ = m.Update(OffersLoadedMsg{Offers: createTestOffers()[:2]})
+	if len(m.GetOffers()) != 1 || m.GetOffers()[0].OfferID != "vast-2" {
+		t.Errorf("Expected only vast-2 after reload, got %v", m.GetOffers())
+	}
+
+	// Clearing the filter shows all offers
+	m.SetFilter(nil)
+	if len(m.GetOffers()) != 2 {
+		t.Errorf("Expected 2 offers without filter, got %d", len(m.GetOffers()))
+	}
+}
+
+func TestProviderSelectModel_FilterSearch(t *testing.T) {
+	m := NewProviderSelectModelWithOffers(createTestOffers())
+	m.SetDimensions(140, 40)
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	if !m.IsSearching() {
+		t.Fatal("Expected '/' to open the filter search box")
+	}
+
+	// Keys that are shortcuts outside the search box are typed
+	m = typeKeys(m, "gpu =~ a100 && vram >= 8x")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	m = typeKeys(m, "0 && region == us-east")
+	if m.filterInput != "gpu =~ a100 && vram >= 80 && region == us-east" {
+		t.Errorf("filterInput = %q", m.filterInput)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.IsSearching() {
+		t.Error("Expected enter to close the search box")
+	}
+	if m.GetFilter().String() != m.filterInput {
+		t.Errorf("filter = %q, want the typed expression", m.GetFilter())
+	}
+	if len(m.GetOffers()) != 2 {
+		t.Errorf("Expected 2 matching offers, got %d", len(m.GetOffers()))
+	}
+
+	// Reopening starts from the current filter; clearing it shows all offers
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	if m.filterInput != m.GetFilter().String() {
+		t.Errorf("Expected search box prefilled with %q, got %q", m.GetFilter(), m.filterInput)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.GetFilter() != nil {
+		t.Errorf("Expected cleared filter, got %q", m.GetFilter())
+	}
+	if len(m.GetOffers()) != len(createTestOffers()) {
+		t.Errorf("Expected all offers, got %d", len(m.GetOffers()))
+	}
+}
+
+func TestProviderSelectModel_FilterSearchError(t *testing.T) {
+	m := NewProviderSelectModelWithOffers(createTestOffers())
+	m.SetDimensions(140, 40)
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	m = typeKeys(m, "vram = 48")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.IsSearching() {
+		t.Fatal("Expected an invalid filter to keep the search box open")
+	}
+	if !strings.Contains(m.View(), "column 6") {
+		t.Error("Expected view to show where the filter is invalid")
+	}
+	if len(m.GetOffers()) != len(createTestOffers()) {
+		t.Error("Expected an invalid filter not to change the offers")
+	}
+
+	// Escape keeps the previous (empty) filter
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	if m.IsSearching() || m.GetFilter() != nil {
+		t.Error("Expected escape to close the search box without a filter")
+	}
+}
+
+func TestProviderSelectModel_FilterMatchesNothing(t *testing.T) {
+	m := NewProviderSelectModelWithOffers(createTestOffers())
+	m.SetDimensions(140, 40)
+
+	filter, _ := offerexpr.Parse("false")
+	m.SetFilter(filter)
+	if !strings.Contains(m.View(), "None of 6 offers match the filter") {
+		t.Error("Expected view to explain that the filter hides all offers")
+	}
+
+	// Enter does nothing without offers
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd != nil {
+		t.Error("Expected no selection without offers")
+	}
+}
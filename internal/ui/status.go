@@ -9,6 +9,7 @@ import (
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/tmeurs/spinup/internal/clock"
 	"github.com/tmeurs/spinup/internal/config"
 )
 
@@ -67,6 +68,9 @@ type StatusModel struct {
 
 	// testing indicates if a test is in progress.
 	testing bool
+
+	// clock is used for durations, costs and the deadman countdown.
+	clock clock.Clock
 }
 
 // TestResult holds the result of a connection test.
@@ -86,6 +90,7 @@ func NewStatusModel() StatusModel {
 	return StatusModel{
 		spinner:    s,
 		lastUpdate: time.Now(),
+		clock:      clock.Real(),
 	}
 }
 
@@ -290,7 +295,7 @@ func (m StatusModel) renderInstanceBox() string {
 		// Started time
 		if !inst.CreatedAt.IsZero() {
 			startedStr := inst.CreatedAt.Format("15:04:05")
-			duration := time.Since(inst.CreatedAt)
+			duration := m.clock.Since(inst.CreatedAt)
 			durationStr := formatDuration(duration)
 			b.WriteString(m.renderLine("Started:", fmt.Sprintf("%s (%s ago)", startedStr, durationStr)))
 		}
@@ -324,7 +329,7 @@ func (m StatusModel) renderInstanceBox() string {
 
 		// Current cost
 		if cost != nil {
-			currentCost := m.state.CalculateAccumulatedCost()
+			currentCost := m.state.AccumulatedCostAt(m.clock.Now())
 			costStr := fmt.Sprintf("%s%.2f", CurrencyEUR, currentCost)
 			b.WriteString(m.renderLine("Current cost:", Styles.Price.Render(costStr)))
 
@@ -446,18 +451,7 @@ func (m StatusModel) renderTestResult() string {
 
 // calculateDeadmanRemaining calculates the remaining time before deadman kills the instance.
 func (m StatusModel) calculateDeadmanRemaining(deadman *config.DeadmanState) time.Duration {
-	if deadman == nil {
-		return 0
-	}
-
-	timeout := time.Duration(deadman.TimeoutHours) * time.Hour
-	elapsed := time.Since(deadman.LastHeartbeat)
-	remaining := timeout - elapsed
-
-	if remaining < 0 {
-		return 0
-	}
-	return remaining
+	return deadman.RemainingAt(m.clock.Now())
 }
 
 // formatDuration formats a duration in a human-readable format.
@@ -485,7 +479,13 @@ func (m *StatusModel) SetDimensions(width, height int) {
 // SetState sets the current state.
 func (m *StatusModel) SetState(state *config.State) {
 	m.state = state
-	m.lastUpdate = time.Now()
+	m.lastUpdate = m.clock.Now()
+}
+
+// SetClock sets the clock durations, costs and the deadman countdown are
+// computed with.
+func (m *StatusModel) SetClock(c clock.Clock) {
+	m.clock = clock.OrReal(c)
 }
 
 // State returns the current state.
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/tmeurs/spinup/internal/clock"
 	"github.com/tmeurs/spinup/internal/config"
 )
 
@@ -269,6 +270,23 @@ func TestCalculateDeadmanRemaining(t *testing.T) {
 	}
 }
 
+func TestCalculateDeadmanRemaining_Clock(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC))
+	m := NewStatusModel()
+	m.SetClock(fake)
+
+	deadman := &config.DeadmanState{TimeoutHours: 10, LastHeartbeat: fake.Now()}
+	fake.Advance(9*time.Hour + 15*time.Minute)
+	if got := m.calculateDeadmanRemaining(deadman); got != 45*time.Minute {
+		t.Errorf("Expected 45m remaining, got %v", got)
+	}
+
+	fake.Advance(time.Hour)
+	if got := m.calculateDeadmanRemaining(deadman); got != 0 {
+		t.Errorf("Expected 0 after expiry, got %v", got)
+	}
+}
+
 func TestSetDimensions(t *testing.T) {
 	m := NewStatusModel()
 	m.SetDimensions(80, 24)
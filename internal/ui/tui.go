@@ -10,6 +10,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/offerexpr"
 )
 
 // View represents the different views in the TUI
@@ -364,6 +365,13 @@ func (m Model) View() string {
 
 // handleKeyPress processes keyboard input
 func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// The filter search box takes all keys, including the global ones
+	if m.IsSearching() {
+		var cmd tea.Cmd
+		m.providerSelect, cmd = m.providerSelect.Update(msg)
+		return m, cmd
+	}
+
 	// Global key handlers
 	switch msg.String() {
 	case "q", "ctrl+c":
@@ -381,7 +389,7 @@ func (m Model) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "?":
 		// Show help
-		m.statusMessage = "Help: q=quit, ↑↓=navigate, Enter=select, r=refresh"
+		m.statusMessage = "Help: q=quit, ↑↓=navigate, Enter=select, /=filter, r=refresh"
 		return m, nil
 	}
 
@@ -567,6 +575,22 @@ func (m Model) Cancel() {
 	}
 }
 
+// SetOfferFilter sets the filter of the provider selection view (nil shows
+// all offers).
+func (m *Model) SetOfferFilter(filter *offerexpr.Filter) {
+	m.providerSelect.SetFilter(filter)
+}
+
+// GetOfferFilter returns the filter of the provider selection view.
+func (m Model) GetOfferFilter() *offerexpr.Filter {
+	return m.providerSelect.GetFilter()
+}
+
+// IsSearching returns whether the offer filter search box has focus.
+func (m Model) IsSearching() bool {
+	return m.currentView == ViewProviderSelect && m.providerSelect.IsSearching()
+}
+
 // SetInstanceState sets the state for the instance status view.
 func (m *Model) SetInstanceState(state *config.State) {
 	m.statusView.SetState(state)
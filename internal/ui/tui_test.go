@@ -329,3 +329,30 @@ func TestAllViewsRender(t *testing.T) {
 		}
 	}
 }
+
+func TestModelFilterSearchTakesGlobalKeys(t *testing.T) {
+	m := NewModel()
+	m.currentView = ViewProviderSelect
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	model := updated.(Model)
+	if !model.IsSearching() {
+		t.Fatal("Expected '/' to open the filter search box")
+	}
+
+	// q and ? are typed into the search box instead of quitting or showing help
+	for _, key := range []string{"q", "?"} {
+		updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		model = updated.(Model)
+	}
+	if model.quitting {
+		t.Error("Expected q not to quit while searching")
+	}
+
+	// ESC closes the search box instead of quitting
+	updated, _ = model.Update(tea.KeyMsg{Type: tea.KeyEscape})
+	model = updated.(Model)
+	if model.quitting || model.IsSearching() {
+		t.Error("Expected ESC to close the search box only")
+	}
+}
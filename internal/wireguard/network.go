@@ -0,0 +1,170 @@
+package wireguard
+
+import (
+	"fmt"
+	"net"
+	"sort"
+)
+
+// Session network layout.
+// Slot 0 uses the PRD defaults (wg-spinup, 10.13.37.0/24). Each further slot
+// gets its own interface and /24 so concurrent sessions' tunnels don't collide:
+// slot N uses wg-spinupN and 10.13.(37+N).0/24.
+const (
+	// networkBaseOctet is the third octet of slot 0's subnet.
+	networkBaseOctet = 37
+
+	// MaxNetworkSlot is the highest usable network slot (10.13.255.0/24).
+	MaxNetworkSlot = 255 - networkBaseOctet
+)
+
+// Network describes the tunnel addressing for one session.
+type Network struct {
+	// Slot is the network slot this session was allocated.
+	Slot int
+	// InterfaceName is the local WireGuard interface name.
+	InterfaceName string
+	// ServerIP is the server's tunnel IP without CIDR notation.
+	ServerIP string
+	// ClientIP is the client's tunnel IP without CIDR notation.
+	ClientIP string
+}
+
+// DefaultNetwork returns the network for slot 0, matching the package constants.
+func DefaultNetwork() *Network {
+	return &Network{
+		Slot:          0,
+		InterfaceName: InterfaceName,
+		ServerIP:      ServerIP,
+		ClientIP:      ClientIP,
+	}
+}
+
+// NetworkForSlot returns the network for the given slot.
+func NetworkForSlot(slot int) (*Network, error) {
+	if slot < 0 || slot > MaxNetworkSlot {
+		return nil, fmt.Errorf("network slot %d out of range (0-%d)", slot, MaxNetworkSlot)
+	}
+	if slot == 0 {
+		return DefaultNetwork(), nil
+	}
+
+	octet := networkBaseOctet + slot
+	return &Network{
+		Slot:          slot,
+		InterfaceName: fmt.Sprintf("%s%d", InterfaceName, slot),
+		ServerIP:      fmt.Sprintf("10.13.%d.1", octet),
+		ClientIP:      fmt.Sprintf("10.13.%d.2", octet),
+	}, nil
+}
+
+// Subnet returns the session's /24 in CIDR notation (e.g., "10.13.37.0/24").
+func (n *Network) Subnet() string {
+	return fmt.Sprintf("10.13.%d.0/24", networkBaseOctet+n.Slot)
+}
+
+// ServerAddress returns the server's tunnel address with CIDR (e.g., "10.13.37.1/24").
+func (n *Network) ServerAddress() string {
+	return n.ServerIP + "/24"
+}
+
+// ClientAddress returns the client's tunnel address with CIDR (e.g., "10.13.37.2/24").
+func (n *Network) ClientAddress() string {
+	return n.ClientIP + "/24"
+}
+
+// ServerAllowedIPs returns the CIDR allowed for the server (client perspective).
+func (n *Network) ServerAllowedIPs() string {
+	return n.ServerIP + "/32"
+}
+
+// ClientAllowedIPs returns the CIDR allowed for the client (server perspective).
+func (n *Network) ClientAllowedIPs() string {
+	return n.ClientIP + "/32"
+}
+
+// OllamaEndpoint returns the Ollama API endpoint URL through this network's tunnel.
+func (n *Network) OllamaEndpoint() string {
+	return fmt.Sprintf("http://%s:11434", n.ServerIP)
+}
+
+// Apply sets the addresses of both sides of a ConfigPair to this network.
+func (n *Network) Apply(cp *ConfigPair) {
+	if cp == nil {
+		return
+	}
+	if cp.Client != nil {
+		cp.Client.ClientAddress = n.ClientAddress()
+		cp.Client.ServerAllowedIPs = n.ServerAllowedIPs()
+	}
+	if cp.Server != nil {
+		cp.Server.ServerAddress = n.ServerAddress()
+		cp.Server.ClientAllowedIPs = n.ClientAllowedIPs()
+	}
+}
+
+// AddressConflict is a local interface address that overlaps a session network.
+type AddressConflict struct {
+	// Interface is the name of the local interface.
+	Interface string
+	// Address is the interface address in CIDR notation.
+	Address string
+}
+
+// Conflicts returns the addresses of local interfaces that overlap this
+// network's subnet, other than this network's own tunnel address. Traffic
+// to the server would be routed to such an interface instead of the tunnel.
+func (n *Network) Conflicts() ([]AddressConflict, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network interfaces: %w", err)
+	}
+
+	addrs := make(map[string][]net.Addr)
+	for _, iface := range interfaces {
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		addrs[iface.Name] = ifaceAddrs
+	}
+	return n.conflicts(addrs), nil
+}
+
+// conflicts returns the addresses overlapping the subnet, keyed by interface
+// name and sorted by interface and address.
+func (n *Network) conflicts(addrs map[string][]net.Addr) []AddressConflict {
+	_, subnet, err := net.ParseCIDR(n.Subnet())
+	if err != nil {
+		return nil
+	}
+	own := net.ParseIP(n.ClientIP)
+
+	var conflicts []AddressConflict
+	for name, ifaceAddrs := range addrs {
+		if name == n.InterfaceName {
+			continue
+		}
+		for _, addr := range ifaceAddrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.To4() == nil {
+				continue
+			}
+			// On macOS the tunnel is a utun interface; recognize it by address
+			if ipNet.IP.Equal(own) {
+				continue
+			}
+			if subnet.Contains(ipNet.IP) || ipNet.Contains(subnet.IP) {
+				conflicts = append(conflicts, AddressConflict{Interface: name, Address: ipNet.String()})
+			}
+		}
+	}
+
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Interface != conflicts[j].Interface {
+			return conflicts[i].Interface < conflicts[j].Interface
+		}
+		return conflicts[i].Address < conflicts[j].Address
+	})
+	return conflicts
+}
@@ -0,0 +1,141 @@
+package wireguard
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDefaultNetwork(t *testing.T) {
+	n := DefaultNetwork()
+
+	if n.InterfaceName != InterfaceName {
+		t.Errorf("InterfaceName = %q, want %q", n.InterfaceName, InterfaceName)
+	}
+	if n.ServerAddress() != ServerAddress {
+		t.Errorf("ServerAddress() = %q, want %q", n.ServerAddress(), ServerAddress)
+	}
+	if n.ClientAddress() != ClientAddress {
+		t.Errorf("ClientAddress() = %q, want %q", n.ClientAddress(), ClientAddress)
+	}
+	if n.ServerAllowedIPs() != ServerAllowedIPs {
+		t.Errorf("ServerAllowedIPs() = %q, want %q", n.ServerAllowedIPs(), ServerAllowedIPs)
+	}
+	if n.ClientAllowedIPs() != ClientAllowedIPs {
+		t.Errorf("ClientAllowedIPs() = %q, want %q", n.ClientAllowedIPs(), ClientAllowedIPs)
+	}
+	if n.OllamaEndpoint() != OllamaEndpoint() {
+		t.Errorf("OllamaEndpoint() = %q, want %q", n.OllamaEndpoint(), OllamaEndpoint())
+	}
+}
+
+func TestNetworkForSlot(t *testing.T) {
+	tests := []struct {
+		slot          int
+		wantErr       bool
+		wantInterface string
+		wantServerIP  string
+		wantSubnet    string
+	}{
+		{0, false, "wg-spinup", "10.13.37.1", "10.13.37.0/24"},
+		{1, false, "wg-spinup1", "10.13.38.1", "10.13.38.0/24"},
+		{MaxNetworkSlot, false, "wg-spinup218", "10.13.255.1", "10.13.255.0/24"},
+		{-1, true, "", "", ""},
+		{MaxNetworkSlot + 1, true, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		n, err := NetworkForSlot(tt.slot)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NetworkForSlot(%d) error = %v, wantErr %v", tt.slot, err, tt.wantErr)
+			continue
+		}
+		if tt.wantErr {
+			continue
+		}
+		if n.InterfaceName != tt.wantInterface {
+			t.Errorf("slot %d: InterfaceName = %q, want %q", tt.slot, n.InterfaceName, tt.wantInterface)
+		}
+		if len(n.InterfaceName) > 15 {
+			t.Errorf("slot %d: interface name %q exceeds 15 characters", tt.slot, n.InterfaceName)
+		}
+		if n.ServerIP != tt.wantServerIP {
+			t.Errorf("slot %d: ServerIP = %q, want %q", tt.slot, n.ServerIP, tt.wantServerIP)
+		}
+		if n.Subnet() != tt.wantSubnet {
+			t.Errorf("slot %d: Subnet() = %q, want %q", tt.slot, n.Subnet(), tt.wantSubnet)
+		}
+	}
+}
+
+func TestNetworkApply(t *testing.T) {
+	clientKeys, _ := GenerateKeyPair()
+	serverKeys, _ := GenerateKeyPair()
+	cp, err := GenerateConfigPairWithServerKeys(clientKeys, serverKeys, "1.2.3.4:51820")
+	if err != nil {
+		t.Fatalf("GenerateConfigPairWithServerKeys() error = %v", err)
+	}
+
+	n, _ := NetworkForSlot(3)
+	n.Apply(cp)
+
+	if cp.Client.ClientAddress != "10.13.40.2/24" {
+		t.Errorf("Client.ClientAddress = %q, want 10.13.40.2/24", cp.Client.ClientAddress)
+	}
+	if cp.Client.ServerAllowedIPs != "10.13.40.1/32" {
+		t.Errorf("Client.ServerAllowedIPs = %q, want 10.13.40.1/32", cp.Client.ServerAllowedIPs)
+	}
+	if cp.Server.ServerAddress != "10.13.40.1/24" {
+		t.Errorf("Server.ServerAddress = %q, want 10.13.40.1/24", cp.Server.ServerAddress)
+	}
+	if cp.Server.ClientAllowedIPs != "10.13.40.2/32" {
+		t.Errorf("Server.ClientAllowedIPs = %q, want 10.13.40.2/32", cp.Server.ClientAllowedIPs)
+	}
+
+	// Nil config pair is ignored
+	n.Apply(nil)
+}
+
+func TestNetworkConflicts(t *testing.T) {
+	cidr := func(s string) net.Addr {
+		ip, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			t.Fatalf("ParseCIDR(%q) error = %v", s, err)
+		}
+		ipNet.IP = ip
+		return ipNet
+	}
+
+	addrs := map[string][]net.Addr{
+		"lo":        {cidr("127.0.0.1/8"), cidr("::1/128")},
+		"eth0":      {cidr("192.168.1.10/24")},
+		"wg-spinup": {cidr("10.13.37.2/24")},
+		"utun4":     {cidr("10.13.37.2/24")},
+		"tun0":      {cidr("10.13.37.9/24")},
+		"docker0":   {cidr("10.0.0.1/8")},
+		"wg0":       {cidr("10.13.38.1/24")},
+	}
+
+	got := DefaultNetwork().conflicts(addrs)
+	want := []AddressConflict{
+		{Interface: "docker0", Address: "10.0.0.1/8"},
+		{Interface: "tun0", Address: "10.13.37.9/24"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("conflicts() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("conflicts()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// Slot 1 only collides with wg0
+	n, err := NetworkForSlot(1)
+	if err != nil {
+		t.Fatalf("NetworkForSlot(1) error = %v", err)
+	}
+	got = n.conflicts(addrs)
+	if len(got) != 2 || got[1].Interface != "wg0" {
+		t.Errorf("slot 1 conflicts() = %v, want docker0 and wg0", got)
+	}
+}
@@ -0,0 +1,58 @@
+package wireguard
+
+// Tool is an external command that tunnel setup depends on.
+type Tool struct {
+	// Name is the command name (e.g., "wg").
+	Name string
+	// Path is where the command was found, empty if it was not found.
+	Path string
+	// Required is false for commands that are only used to inspect tunnels.
+	Required bool
+	// Hint describes how to install the command.
+	Hint string
+}
+
+// Found returns true if the command was found.
+func (t Tool) Found() bool {
+	return t.Path != ""
+}
+
+// Tools returns the external commands SetupTunnel uses on this platform and
+// where they were found.
+func Tools() []Tool {
+	return platformTools()
+}
+
+// Privileges describes how this process can configure network interfaces.
+type Privileges struct {
+	// Root is true if the process runs as root.
+	Root bool
+	// Sudo is true if sudo can be used without a password.
+	Sudo bool
+}
+
+// OK returns true if tunnels can be set up with these privileges.
+func (p Privileges) OK() bool {
+	return p.Root || p.Sudo
+}
+
+// CheckPrivileges returns whether this process can set up tunnels, either
+// as root or through passwordless sudo.
+func CheckPrivileges() Privileges {
+	if checkRoot() {
+		return Privileges{Root: true}
+	}
+	return Privileges{Sudo: checkRootOrSudo()}
+}
+
+// InterfaceExists returns true if a WireGuard interface with the given name
+// exists, so that SetupTunnel would fail to create it.
+func InterfaceExists(name string) bool {
+	return tunnelInterfaceExists(name)
+}
+
+// RemoveInterfaceCommand returns the shell command that removes a leftover
+// WireGuard interface.
+func RemoveInterfaceCommand(name string) string {
+	return removeInterfaceCommand(name)
+}
@@ -207,6 +207,28 @@ func findWgTool() (string, error) {
 	}
 }
 
+// platformTools returns the commands tunnel setup uses on macOS.
+func platformTools() []Tool {
+	wgGoPath, _ := findWireGuardGo()
+	wgPath, _ := findWgTool()
+	return []Tool{
+		{Name: "wireguard-go", Path: wgGoPath, Required: true, Hint: "install via 'brew install wireguard-go'"},
+		{Name: "wg", Path: wgPath, Required: true, Hint: "install via 'brew install wireguard-tools'"},
+	}
+}
+
+// tunnelInterfaceExists checks if a wireguard-go process serves the named
+// interface.
+func tunnelInterfaceExists(name string) bool {
+	return findExistingInterface(name) != ""
+}
+
+// removeInterfaceCommand returns the command that stops the wireguard-go
+// process of an interface.
+func removeInterfaceCommand(name string) string {
+	return fmt.Sprintf("sudo pkill -f 'wireguard-go %s'; sudo rm -f /var/run/wireguard/%s.sock", name, name)
+}
+
 // SetupTunnel creates and configures a WireGuard tunnel on macOS.
 // This function requires root privileges or sudo access.
 // It uses wireguard-go for userspace WireGuard implementation.
@@ -578,7 +600,14 @@ func assignAddressDarwin(ctx context.Context, name, address string, usesSudo boo
 	// On macOS, use ifconfig to set the address
 	// ifconfig utunX inet 10.13.37.2 10.13.37.1 netmask 255.255.255.0
 	// The format is: ifconfig <interface> inet <local-addr> <dest-addr> netmask <mask>
-	destIP := ServerIP // Point-to-point destination
+	// Point-to-point destination is the server, the first host of the subnet
+	destIP := ServerIP
+	if network := ipNet.IP.To4(); network != nil && maskSize < 32 {
+		dest := make(net.IP, len(network))
+		copy(dest, network)
+		dest[3]++
+		destIP = dest.String()
+	}
 	if maskSize == 32 {
 		// For /32, destination is same as source
 		destIP = ip.String()
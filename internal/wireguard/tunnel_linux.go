@@ -146,6 +146,39 @@ func checkRootOrSudo() bool {
 	return cmd.Run() == nil
 }
 
+// findWgTool searches for the wg command-line tool. Tunnels are configured
+// through wgctrl on Linux, so wg is only needed to inspect them.
+func findWgTool() (string, error) {
+	path, err := exec.LookPath("wg")
+	if err != nil {
+		return "", &TunnelError{
+			Op:      "check",
+			Message: "wg tool not found in PATH; install wireguard-tools",
+		}
+	}
+	return path, nil
+}
+
+// platformTools returns the commands tunnel setup uses on Linux.
+func platformTools() []Tool {
+	ipPath, _ := exec.LookPath("ip")
+	wgPath, _ := findWgTool()
+	return []Tool{
+		{Name: "ip", Path: ipPath, Required: true, Hint: "install iproute2 (e.g. 'apt install iproute2')"},
+		{Name: "wg", Path: wgPath, Required: false, Hint: "install wireguard-tools (e.g. 'apt install wireguard-tools')"},
+	}
+}
+
+// tunnelInterfaceExists checks if a WireGuard interface exists.
+func tunnelInterfaceExists(name string) bool {
+	return interfaceExists(name)
+}
+
+// removeInterfaceCommand returns the command that deletes an interface.
+func removeInterfaceCommand(name string) string {
+	return "sudo ip link delete dev " + name
+}
+
 // SetupTunnel creates and configures a WireGuard tunnel on Linux.
 // This function requires root privileges or sudo access.
 // It creates the WireGuard interface, assigns an IP address, and configures the peer.
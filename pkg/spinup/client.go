@@ -0,0 +1,386 @@
+package spinup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/offerexpr"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/registry"
+)
+
+var (
+	// ErrNoActiveSession is returned when the session has no instance.
+	ErrNoActiveSession = errors.New("no active session")
+
+	// ErrSessionActive is returned by Deploy when the session already has an
+	// instance. Stop it first or use another session.
+	ErrSessionActive = errors.New("session already has an instance")
+
+	// ErrUnfinishedDeploy is returned by Deploy when an interrupted deployment
+	// left an instance behind in the session. Run 'spinup deploy --resume' or
+	// 'spinup cleanup' on the session first.
+	ErrUnfinishedDeploy = errors.New("session has an unfinished deployment")
+
+	// ErrNoProviders is returned when no provider API key is configured.
+	ErrNoProviders = errors.New("no providers configured")
+)
+
+// Client deploys, inspects and stops the instance of one session.
+// A Client is safe for concurrent use, but operations on the same session
+// must not overlap: don't Deploy while a Stop is running.
+type Client struct {
+	cfg          *config.Config
+	envFile      string
+	profile      string
+	apiKeys      map[string]string
+	stateDir     string
+	session      string
+	events       chan<- Event
+	providers    []provider.Provider
+	stateManager *config.StateManager
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithEnvFile reads the project configuration from the given .env file
+// instead of the .env file in the current directory.
+func WithEnvFile(path string) Option {
+	return func(c *Client) {
+		c.envFile = path
+	}
+}
+
+// WithProfile selects a profile from the global config file, like the
+// --profile flag of the spinup command line. The default is the profile
+// named by SPINUP_PROFILE, if any.
+func WithProfile(name string) Option {
+	return func(c *Client) {
+		c.profile = name
+	}
+}
+
+// WithStateDir sets the state directory, overriding STATE_DIR. The default
+// is the directory the spinup command line uses.
+func WithStateDir(dir string) Option {
+	return func(c *Client) {
+		c.stateDir = dir
+	}
+}
+
+// WithSession sets the session name. The default is the default session of
+// the spinup command line.
+func WithSession(name string) Option {
+	return func(c *Client) {
+		c.session = name
+	}
+}
+
+// WithAPIKey sets the API key of a provider ("vast", "lambda", "runpod",
+// "coreweave" or "paperspace"), overriding the configured one.
+func WithAPIKey(providerName, key string) Option {
+	return func(c *Client) {
+		if c.apiKeys == nil {
+			c.apiKeys = make(map[string]string)
+		}
+		c.apiKeys[providerName] = key
+	}
+}
+
+// WithEvents sends progress of Deploy and Stop to ch. Sends never block:
+// events are dropped while ch is full, so give it a buffer.
+func WithEvents(ch chan<- Event) Option {
+	return func(c *Client) {
+		c.events = ch
+	}
+}
+
+// withProviders replaces the configured providers, for tests.
+func withProviders(providers ...provider.Provider) Option {
+	return func(c *Client) {
+		c.providers = providers
+	}
+}
+
+// New creates a Client. Configuration is loaded like the spinup command line
+// loads it: from the global config file and the selected profile in it, the
+// .env file in the current directory (or the one given with WithEnvFile),
+// and the environment. Secrets in the secrets file are read with the
+// SECRETS_KEYFILE or SPINUP_SECRETS_PASSPHRASE setting; the Client never
+// prompts for a passphrase.
+func New(opts ...Option) (*Client, error) {
+	c := &Client{}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	loadOpts := []config.LoadOption{
+		config.WithProfile(c.profile),
+		config.WithFlag("STATE_DIR", c.stateDir, "WithStateDir"),
+	}
+	if c.envFile != "" {
+		// Unlike the .env file in the current directory, a given one must exist
+		if _, err := os.Stat(c.envFile); err != nil {
+			return nil, fmt.Errorf("failed to load config: %w", err)
+		}
+		loadOpts = append(loadOpts, config.WithProjectConfig(c.envFile))
+	}
+
+	var err error
+	c.cfg, _, err = config.Load(loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	for name, key := range c.apiKeys {
+		if err := setAPIKey(c.cfg, name, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.cfg.StateDir != "" {
+		if err := os.MkdirAll(c.cfg.StateDir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create state directory: %w", err)
+		}
+	}
+	c.stateManager, err = config.NewStateManager(c.cfg.StateDir, config.WithSession(c.session))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create state manager: %w", err)
+	}
+
+	return c, nil
+}
+
+// setAPIKey sets the API key of the named provider in cfg.
+func setAPIKey(cfg *config.Config, name, key string) error {
+	switch name {
+	case registry.ProviderVast:
+		cfg.VastAPIKey = key
+	case registry.ProviderLambda:
+		cfg.LambdaAPIKey = key
+	case registry.ProviderRunPod:
+		cfg.RunPodAPIKey = key
+	case registry.ProviderCoreWeave:
+		cfg.CoreWeaveAPIKey = key
+	case registry.ProviderPaperspace:
+		cfg.PaperspaceAPIKey = key
+	default:
+		return fmt.Errorf("unknown provider %q", name)
+	}
+	return nil
+}
+
+// Session returns the name of the client's session.
+func (c *Client) Session() string {
+	return c.stateManager.Session()
+}
+
+// DeploySpec describes a deployment. Only Model is required; the cheapest
+// compatible offer of any configured provider is picked otherwise.
+type DeploySpec struct {
+	// Model is the model to deploy, e.g. "qwen2.5-coder:32b".
+	Model string
+
+	// Provider limits offers to one provider, e.g. "vast".
+	Provider string
+
+	// GPU limits offers to one GPU type, e.g. "a100-80".
+	GPU string
+
+	// Region limits offers to a region, e.g. "eu-west".
+	Region string
+
+	// OnDemand deploys an on-demand instance instead of a cheaper spot
+	// instance the provider may interrupt.
+	OnDemand bool
+
+	// Filter is an offer filter expression offers must match, see
+	// OfferQuery.Filter. Empty uses OFFER_FILTER from the configuration.
+	Filter string
+
+	// DeadmanTimeout is how long the instance lives without a heartbeat,
+	// in whole hours. Zero uses the configured timeout.
+	DeadmanTimeout time.Duration
+
+	// MaxAttempts is the number of offers to try if an instance can't be
+	// created or doesn't boot. Zero uses the default.
+	MaxAttempts int
+
+	// DiskSizeGB is the disk size of the instance. Zero uses the default.
+	DiskSizeGB int
+}
+
+// deployConfig converts the spec to a deployment configuration.
+func (s DeploySpec) deployConfig(cfg *config.Config) (*deploy.DeployConfig, error) {
+	if s.Model == "" {
+		return nil, errors.New("model is required")
+	}
+	if s.DeadmanTimeout < 0 || s.DeadmanTimeout%time.Hour != 0 {
+		return nil, fmt.Errorf("deadman timeout %s is not a whole number of hours", s.DeadmanTimeout)
+	}
+
+	filter, err := offerexpr.Parse(s.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+
+	deployCfg := deploy.DefaultDeployConfig()
+	deployCfg.Model = s.Model
+	deployCfg.Filter = filter
+	deployCfg.ProviderName = s.Provider
+	deployCfg.GPUType = s.GPU
+	deployCfg.Region = s.Region
+	deployCfg.PreferSpot = !s.OnDemand
+	if cfg.DeadmanTimeoutHours > 0 {
+		deployCfg.DeadmanTimeoutHours = cfg.DeadmanTimeoutHours
+	}
+	if s.DeadmanTimeout > 0 {
+		deployCfg.DeadmanTimeoutHours = int(s.DeadmanTimeout / time.Hour)
+	}
+	if s.MaxAttempts > 0 {
+		deployCfg.MaxAttempts = s.MaxAttempts
+	}
+	if s.DiskSizeGB > 0 {
+		deployCfg.DiskSizeGB = s.DiskSizeGB
+	}
+	return deployCfg, nil
+}
+
+// Deploy deploys a model on the cheapest compatible offer and returns once
+// the model is served. Progress is sent to the WithEvents channel.
+//
+// If an instance was created but the deployment fails later, the instance
+// is terminated before Deploy returns. Start Supervise once Deploy returns
+// to keep the deadman switch from terminating the instance.
+func (c *Client) Deploy(ctx context.Context, spec DeploySpec) (*Session, error) {
+	deployCfg, err := spec.deployConfig(c.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if c.providers == nil && !c.cfg.HasAnyProvider() {
+		return nil, ErrNoProviders
+	}
+
+	existing, err := c.stateManager.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	if existing != nil && existing.Instance != nil {
+		return nil, fmt.Errorf("%w: instance %s", ErrSessionActive, existing.Instance.ID)
+	}
+
+	journal, err := deploy.LoadDeployJournal(c.stateManager)
+	if err != nil {
+		return nil, err
+	}
+	if journal.HasInstance() {
+		return nil, fmt.Errorf("%w: instance %s (%s)", ErrUnfinishedDeploy, journal.InstanceID, journal.Provider)
+	}
+
+	session := c.Session()
+	opts := []deploy.DeployerOption{
+		deploy.WithStateManager(c.stateManager),
+		deploy.WithProgressCallback(func(p deploy.DeployProgress) {
+			c.emit(deployEvent(session, p))
+		}),
+	}
+	if c.providers != nil {
+		opts = append(opts, deploy.WithProviders(c.providers...))
+	}
+	deployer, err := deploy.NewDeployer(c.cfg, deployCfg, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create deployer: %w", err)
+	}
+
+	if _, err := deployer.Deploy(ctx); err != nil {
+		c.emit(failedEvent(EventDeploy, session, "Deployment failed", err))
+		return nil, err
+	}
+
+	state, err := c.stateManager.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	if state == nil || state.Instance == nil {
+		return nil, ErrNoActiveSession
+	}
+	s := sessionFromState(session, state)
+	return &s, nil
+}
+
+// Stop terminates the session's instance and verifies that billing stopped.
+// Progress is sent to the WithEvents channel.
+func (c *Client) Stop(ctx context.Context) (*StopResult, error) {
+	state, err := c.stateManager.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	if state == nil || state.Instance == nil {
+		return nil, ErrNoActiveSession
+	}
+
+	session := c.Session()
+	opts := []deploy.StopperOption{
+		deploy.WithStopStateManager(c.stateManager),
+		deploy.WithStopProgressCallback(func(p deploy.StopProgress) {
+			c.emit(stopEvent(session, p))
+		}),
+	}
+	for _, p := range c.providers {
+		if p.Name() == state.Instance.Provider {
+			opts = append(opts, deploy.WithStopProvider(p))
+		}
+	}
+	stopper, err := deploy.NewStopper(c.cfg, deploy.DefaultStopConfig(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stopper: %w", err)
+	}
+
+	result, err := stopper.Stop(ctx)
+	if errors.Is(err, deploy.ErrNoActiveInstance) {
+		return nil, ErrNoActiveSession
+	}
+	if err != nil {
+		c.emit(failedEvent(EventStop, session, "Stop failed", err))
+		return nil, err
+	}
+	return stopResult(result), nil
+}
+
+// Status returns the session's instance, cost so far and deadman switch.
+// It reads the state only and doesn't contact the provider.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	state, err := c.stateManager.LoadState()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	if state == nil || state.Instance == nil {
+		return nil, ErrNoActiveSession
+	}
+	return statusFromState(c.Session(), state, time.Now()), nil
+}
+
+// Supervise keeps the session alive until ctx is canceled or the session is
+// stopped: it sends deadman heartbeats, updates the session cost, alerts on
+// the daily budget and handles spot interruptions. Without it, the deadman
+// switch terminates the instance after its timeout.
+func (c *Client) Supervise(ctx context.Context) error {
+	sup, err := deploy.NewSupervisor(c.cfg, c.stateManager, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create supervisor: %w", err)
+	}
+	err = sup.Run(ctx)
+	if errors.Is(err, config.ErrNoActiveInstance) {
+		return ErrNoActiveSession
+	}
+	return err
+}
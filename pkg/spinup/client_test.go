@@ -0,0 +1,406 @@
+package spinup
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/mock"
+)
+
+func newTestClient(t *testing.T, opts ...Option) (*Client, *config.StateManager) {
+	t.Helper()
+
+	// Keep the user's global config out of the tests
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	opts = append([]Option{WithStateDir(dir), WithSession("sdk")}, opts...)
+	c, err := New(opts...)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	sm, err := config.NewStateManager(dir, config.WithSession("sdk"))
+	if err != nil {
+		t.Fatalf("NewStateManager() error = %v", err)
+	}
+	return c, sm
+}
+
+func saveTestState(t *testing.T, sm *config.StateManager, instanceID string) *config.State {
+	t.Helper()
+
+	state := config.NewState(
+		&config.InstanceState{
+			ID:          instanceID,
+			Provider:    "lambda",
+			GPU:         "A100 80GB",
+			Region:      "us-east-1",
+			Type:        "spot",
+			WireGuardIP: "10.13.37.1",
+			CreatedAt:   time.Now().Add(-2 * time.Hour),
+		},
+		&config.ModelState{Name: "qwen2.5-coder:32b", Status: "ready"},
+		&config.WireGuardState{InterfaceName: "wgmock0"},
+		&config.CostState{HourlyRate: 1.5, Currency: "EUR"},
+		&config.DeadmanState{TimeoutHours: 10, LastHeartbeat: time.Now().Add(-time.Hour)},
+	)
+	if err := sm.SaveState(state); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+	return state
+}
+
+func testOffer(providerName, id string, vram int, onDemand float64, spot *float64) provider.Offer {
+	return provider.Offer{
+		OfferID:       id,
+		Provider:      providerName,
+		GPU:           "A100 80GB",
+		VRAM:          vram,
+		Region:        "EU",
+		OnDemandPrice: onDemand,
+		SpotPrice:     spot,
+		Available:     true,
+	}
+}
+
+// useCLIEnvironment gives the test a home directory of its own and runs it
+// in an empty working directory, so it sees what the command line would.
+func useCLIEnvironment(t *testing.T) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(home, ".local", "state"))
+	t.Setenv(config.ProfileEnv, "")
+	t.Chdir(t.TempDir())
+	return home
+}
+
+// saveCLIState saves a session through a state manager set up the way the
+// command line sets up its own.
+func saveCLIState(t *testing.T, instanceID string, opts ...config.LoadOption) {
+	t.Helper()
+
+	dir, err := config.ResolveStateDir(opts...)
+	if err != nil {
+		t.Fatalf("ResolveStateDir() error = %v", err)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	sm, err := config.NewStateManager(dir, config.WithSession("sdk"))
+	if err != nil {
+		t.Fatalf("NewStateManager() error = %v", err)
+	}
+	saveTestState(t, sm, instanceID)
+}
+
+func TestNew_SeesCLISession(t *testing.T) {
+	useCLIEnvironment(t)
+	saveCLIState(t, "cli-1")
+
+	c, err := New(WithSession("sdk"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	status, err := c.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.InstanceID != "cli-1" {
+		t.Errorf("InstanceID = %q, want the command line's session", status.InstanceID)
+	}
+}
+
+func TestNew_ProfileStateDir(t *testing.T) {
+	home := useCLIEnvironment(t)
+	stateDir := filepath.Join(home, "work-state")
+	global := filepath.Join(home, ".config", "spinup", "config.toml")
+	if err := os.MkdirAll(filepath.Dir(global), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(global, []byte("[profiles.work]\nstate_dir = \""+stateDir+"\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	saveCLIState(t, "work-1", config.WithProfile("work"))
+
+	c, err := New(WithSession("sdk"), WithProfile("work"))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	status, err := c.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.InstanceID != "work-1" {
+		t.Errorf("InstanceID = %q, want the profile's session", status.InstanceID)
+	}
+	if c.cfg.StateDir != stateDir {
+		t.Errorf("StateDir = %q, want the profile's %q", c.cfg.StateDir, stateDir)
+	}
+}
+
+func TestNew_MissingEnvFile(t *testing.T) {
+	useCLIEnvironment(t)
+
+	if _, err := New(WithEnvFile(filepath.Join(t.TempDir(), "missing.env"))); err == nil {
+		t.Error("expected an error for a missing env file")
+	}
+}
+
+func TestWithAPIKey(t *testing.T) {
+	tests := []struct {
+		provider string
+		get      func(*config.Config) string
+		wantErr  bool
+	}{
+		{"vast", func(c *config.Config) string { return c.VastAPIKey }, false},
+		{"lambda", func(c *config.Config) string { return c.LambdaAPIKey }, false},
+		{"runpod", func(c *config.Config) string { return c.RunPodAPIKey }, false},
+		{"coreweave", func(c *config.Config) string { return c.CoreWeaveAPIKey }, false},
+		{"paperspace", func(c *config.Config) string { return c.PaperspaceAPIKey }, false},
+		{"unknown", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			c, err := New(WithStateDir(t.TempDir()), WithAPIKey(tt.provider, "key-123"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tt.get(c.cfg) != "key-123" {
+				t.Errorf("API key = %q, want key-123", tt.get(c.cfg))
+			}
+		})
+	}
+}
+
+func TestDeploySpec_deployConfig(t *testing.T) {
+	cfg := &config.Config{DeadmanTimeoutHours: 6}
+
+	tests := []struct {
+		name      string
+		spec      DeploySpec
+		wantHours int
+		wantSpot  bool
+		wantErr   bool
+	}{
+		{"defaults", DeploySpec{Model: "qwen2.5-coder:7b"}, 6, true, false},
+		{"on-demand", DeploySpec{Model: "qwen2.5-coder:7b", OnDemand: true}, 6, false, false},
+		{"timeout", DeploySpec{Model: "qwen2.5-coder:7b", DeadmanTimeout: 3 * time.Hour}, 3, true, false},
+		{"partial hour", DeploySpec{Model: "qwen2.5-coder:7b", DeadmanTimeout: 90 * time.Minute}, 0, false, true},
+		{"no model", DeploySpec{}, 0, false, true},
+		{"filter", DeploySpec{Model: "qwen2.5-coder:7b", Filter: "vram >= 48"}, 6, true, false},
+		{"invalid filter", DeploySpec{Model: "qwen2.5-coder:7b", Filter: "vram = 48"}, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.spec.deployConfig(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("deployConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.DeadmanTimeoutHours != tt.wantHours {
+				t.Errorf("DeadmanTimeoutHours = %d, want %d", got.DeadmanTimeoutHours, tt.wantHours)
+			}
+			if got.PreferSpot != tt.wantSpot {
+				t.Errorf("PreferSpot = %v, want %v", got.PreferSpot, tt.wantSpot)
+			}
+		})
+	}
+}
+
+func TestClient_ListOffers(t *testing.T) {
+	spot := 0.8
+	vast := mock.New(mock.WithName("vast"), mock.WithOffers([]provider.Offer{
+		testOffer("vast", "v-24", 24, 0.5, nil),
+		testOffer("vast", "v-80", 80, 2.0, nil),
+	}))
+	lambda := mock.New(mock.WithName("lambda"), mock.WithOffers([]provider.Offer{
+		testOffer("lambda", "l-80", 80, 1.5, &spot),
+	}))
+	broken := mock.New(mock.WithName("runpod"), mock.WithGetOffersError(provider.ErrRateLimited))
+
+	c, _ := newTestClient(t, withProviders(vast, lambda, broken))
+
+	list, err := c.ListOffers(context.Background(), OfferQuery{Model: "qwen2.5-coder:32b"})
+	if err != nil {
+		t.Fatalf("ListOffers() error = %v", err)
+	}
+
+	var ids []string
+	for _, o := range list.Offers {
+		ids = append(ids, o.ID)
+	}
+	if len(ids) != 2 || ids[0] != "l-80" || ids[1] != "v-80" {
+		t.Errorf("offers = %v, want [l-80 v-80]", ids)
+	}
+	if list.Offers[0].SpotPrice != 0.8 || list.Offers[0].LowestPrice() != 0.8 {
+		t.Errorf("offer l-80 = %+v, want spot price 0.8", list.Offers[0])
+	}
+	if !errors.Is(list.Errors["runpod"], provider.ErrRateLimited) || len(list.Errors) != 1 {
+		t.Errorf("Errors = %v, want runpod rate limited", list.Errors)
+	}
+
+	// A single provider
+	list, err = c.ListOffers(context.Background(), OfferQuery{Provider: "vast"})
+	if err != nil {
+		t.Fatalf("ListOffers(vast) error = %v", err)
+	}
+	if len(list.Offers) != 2 || list.Offers[0].ID != "v-24" {
+		t.Errorf("vast offers = %+v, want v-24 first", list.Offers)
+	}
+
+	// A filter expression
+	list, err = c.ListOffers(context.Background(), OfferQuery{Filter: "vram >= 80 && !has_spot"})
+	if err != nil {
+		t.Fatalf("ListOffers(filter) error = %v", err)
+	}
+	if len(list.Offers) != 1 || list.Offers[0].ID != "v-80" {
+		t.Errorf("filtered offers = %+v, want v-80", list.Offers)
+	}
+	if _, err := c.ListOffers(context.Background(), OfferQuery{Filter: "vram >"}); err == nil {
+		t.Error("ListOffers() with invalid filter error = nil")
+	}
+}
+
+func TestClient_ListOffers_AllProvidersFail(t *testing.T) {
+	broken := mock.New(mock.WithName("vast"), mock.WithGetOffersError(provider.ErrAuthenticationFailed))
+	c, _ := newTestClient(t, withProviders(broken))
+
+	if _, err := c.ListOffers(context.Background(), OfferQuery{}); err == nil {
+		t.Error("ListOffers() error = nil, want error when all providers fail")
+	}
+}
+
+func TestClient_Status(t *testing.T) {
+	c, sm := newTestClient(t)
+
+	if _, err := c.Status(context.Background()); !errors.Is(err, ErrNoActiveSession) {
+		t.Errorf("Status() error = %v, want ErrNoActiveSession", err)
+	}
+
+	saveTestState(t, sm, "inst-1")
+
+	status, err := c.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.Name != "sdk" || status.InstanceID != "inst-1" || status.Provider != "lambda" || !status.Spot {
+		t.Errorf("Status() session = %+v", status.Session)
+	}
+	if status.Endpoint != "http://10.13.37.1:11434" {
+		t.Errorf("Endpoint = %q", status.Endpoint)
+	}
+	if status.ModelStatus != "ready" || status.Model != "qwen2.5-coder:32b" {
+		t.Errorf("model = %q (%s)", status.Model, status.ModelStatus)
+	}
+	if status.AccumulatedCost < 2.9 || status.AccumulatedCost > 3.1 {
+		t.Errorf("AccumulatedCost = %.2f, want ~3.00", status.AccumulatedCost)
+	}
+	if status.DeadmanRemaining < 8*time.Hour || status.DeadmanRemaining > 9*time.Hour {
+		t.Errorf("DeadmanRemaining = %s, want ~9h", status.DeadmanRemaining)
+	}
+}
+
+func TestClient_Stop(t *testing.T) {
+	lambda := mock.New(mock.WithName("lambda"))
+	lambda.AddInstance(&provider.Instance{ID: "inst-1", Provider: "lambda", Status: provider.InstanceStatusRunning})
+
+	events := make(chan Event, 32)
+	c, sm := newTestClient(t, withProviders(lambda), WithEvents(events))
+
+	if _, err := c.Stop(context.Background()); !errors.Is(err, ErrNoActiveSession) {
+		t.Errorf("Stop() error = %v, want ErrNoActiveSession", err)
+	}
+
+	saveTestState(t, sm, "inst-1")
+
+	result, err := c.Stop(context.Background())
+	if err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if result.InstanceID != "inst-1" || result.Provider != "lambda" {
+		t.Errorf("Stop() = %+v", result)
+	}
+	if len(lambda.TerminateInstanceCalls) != 1 {
+		t.Errorf("TerminateInstanceCalls = %d, want 1", len(lambda.TerminateInstanceCalls))
+	}
+
+	state, _ := sm.LoadState()
+	if state != nil {
+		t.Error("state should be cleared after Stop()")
+	}
+
+	close(events)
+	var stops int
+	for e := range events {
+		if e.Kind != EventStop || e.Session != "sdk" || e.TotalSteps == 0 {
+			t.Errorf("unexpected event %+v", e)
+		}
+		stops++
+	}
+	if stops == 0 {
+		t.Error("no stop events")
+	}
+}
+
+func TestClient_Deploy_SessionActive(t *testing.T) {
+	lambda := mock.New(mock.WithName("lambda"))
+	c, sm := newTestClient(t, withProviders(lambda))
+	saveTestState(t, sm, "inst-1")
+
+	_, err := c.Deploy(context.Background(), DeploySpec{Model: "qwen2.5-coder:7b"})
+	if !errors.Is(err, ErrSessionActive) {
+		t.Errorf("Deploy() error = %v, want ErrSessionActive", err)
+	}
+	if len(lambda.CreateInstanceCalls) != 0 {
+		t.Error("CreateInstance called for an active session")
+	}
+}
+
+func TestClient_Deploy_ReportsFailure(t *testing.T) {
+	lambda := mock.New(
+		mock.WithName("lambda"),
+		mock.WithOffers([]provider.Offer{testOffer("lambda", "l-80", 80, 1.0, nil)}),
+		mock.WithCreateInstanceError(provider.ErrInsufficientCapacity),
+	)
+
+	events := make(chan Event, 64)
+	c, sm := newTestClient(t, withProviders(lambda), WithEvents(events))
+
+	_, err := c.Deploy(context.Background(), DeploySpec{Model: "qwen2.5-coder:7b", OnDemand: true, MaxAttempts: 1})
+	if err == nil {
+		t.Fatal("Deploy() error = nil, want error")
+	}
+	if len(lambda.CreateInstanceCalls) != 1 {
+		t.Errorf("CreateInstanceCalls = %d, want 1", len(lambda.CreateInstanceCalls))
+	}
+	if state, _ := sm.LoadState(); state != nil {
+		t.Error("no state should be saved for a failed deployment")
+	}
+
+	close(events)
+	var failed bool
+	for e := range events {
+		if e.Kind != EventDeploy {
+			t.Errorf("event kind = %s, want deploy", e.Kind)
+		}
+		if e.Err != nil {
+			failed = true
+		}
+	}
+	if !failed {
+		t.Error("no event reported the failure")
+	}
+}
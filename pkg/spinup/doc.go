@@ -0,0 +1,34 @@
+// Package spinup is the Go API for embedding spinup: deploying a model on the
+// cheapest GPU instance, watching the deployment, checking on the session and
+// stopping it again, without shelling out to the spinup binary.
+//
+// A Client works on one session of a state directory, the same state the
+// spinup command line uses, so sessions started through either can be
+// inspected and stopped through the other:
+//
+//	client, err := spinup.New(spinup.WithStateDir("/var/lib/spinup"))
+//	if err != nil {
+//		return err
+//	}
+//	session, err := client.Deploy(ctx, spinup.DeploySpec{Model: "qwen2.5-coder:32b"})
+//
+// Provider API keys and preferences are loaded like the command line loads
+// them: from the global config file (~/.config/spinup/config.toml) and the
+// profile selected with WithProfile or SPINUP_PROFILE, the .env file in the
+// current directory or the one given with WithEnvFile, and the environment
+// variables documented in the README. The default state directory is the
+// command line's too, ~/.local/state/spinup unless STATE_DIR says otherwise.
+//
+// The deadman switch terminates an instance whose heartbeat stops. The
+// spinup command line keeps it alive from a background process; programs
+// that deploy through this package call Client.Supervise for as long as the
+// session should live.
+//
+// # Stability
+//
+// This package follows semantic versioning. Within a major version, exported
+// identifiers are not removed and their behavior doesn't change
+// incompatibly; new functions, options, struct fields and event kinds may be
+// added. The packages under internal/ have no such guarantee and are not
+// importable anyway.
+package spinup
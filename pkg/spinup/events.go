@@ -0,0 +1,118 @@
+package spinup
+
+import (
+	"time"
+
+	"github.com/tmeurs/spinup/internal/deploy"
+)
+
+// EventKind tells which operation an Event belongs to.
+type EventKind string
+
+const (
+	// EventDeploy is progress of Client.Deploy.
+	EventDeploy EventKind = "deploy"
+
+	// EventStop is progress of Client.Stop.
+	EventStop EventKind = "stop"
+)
+
+// Event reports progress of a deployment or stop. Each step is reported when
+// it starts and again when it completes. An operation that fails ends with
+// an event that has Err set.
+type Event struct {
+	// Kind is the operation the event belongs to.
+	Kind EventKind
+
+	// Session is the session the operation runs on.
+	Session string
+
+	// Step is the 1-based step of the operation.
+	Step int
+
+	// TotalSteps is the number of steps of the operation.
+	TotalSteps int
+
+	// StepName describes the step, e.g. "Waiting for instance boot".
+	StepName string
+
+	// Message is a human-readable progress message.
+	Message string
+
+	// Detail is additional detail, e.g. the provider or a retry count.
+	Detail string
+
+	// Completed is set when the step is done.
+	Completed bool
+
+	// Warning is set for problems that don't fail the operation, e.g. billing
+	// that has to be verified manually.
+	Warning bool
+
+	// Attempt is the 1-based offer being tried while creating and booting an
+	// instance, zero otherwise.
+	Attempt int
+
+	// Err is set if the operation failed.
+	Err error
+
+	// Time is when the event happened.
+	Time time.Time
+}
+
+// deployEvent converts deployment progress to an Event.
+func deployEvent(session string, p deploy.DeployProgress) Event {
+	return Event{
+		Kind:       EventDeploy,
+		Session:    session,
+		Step:       int(p.Step),
+		TotalSteps: p.TotalSteps,
+		StepName:   p.Step.String(),
+		Message:    p.Message,
+		Detail:     p.Detail,
+		Completed:  p.Completed,
+		Attempt:    p.Attempt,
+		Err:        p.Error,
+		Time:       time.Now(),
+	}
+}
+
+// stopEvent converts stop progress to an Event.
+func stopEvent(session string, p deploy.StopProgress) Event {
+	return Event{
+		Kind:       EventStop,
+		Session:    session,
+		Step:       int(p.Step),
+		TotalSteps: p.TotalSteps,
+		StepName:   p.Step.String(),
+		Message:    p.Message,
+		Detail:     p.Detail,
+		Completed:  p.Completed,
+		Warning:    p.Warning,
+		Err:        p.Error,
+		Time:       time.Now(),
+	}
+}
+
+// failedEvent returns the last event of a failed operation.
+func failedEvent(kind EventKind, session, message string, err error) Event {
+	return Event{
+		Kind:    kind,
+		Session: session,
+		Message: message,
+		Err:     err,
+		Time:    time.Now(),
+	}
+}
+
+// emit sends an event without blocking. Events are dropped if the channel
+// is full.
+func (c *Client) emit(e Event) {
+	if c.events == nil {
+		return
+	}
+	select {
+	case c.events <- e:
+	default:
+	}
+}
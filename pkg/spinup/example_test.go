@@ -0,0 +1,85 @@
+package spinup_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/signal"
+	"syscall"
+
+	"github.com/tmeurs/spinup/pkg/spinup"
+)
+
+func Example() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	events := make(chan spinup.Event, 32)
+	client, err := spinup.New(spinup.WithSession("ci"), spinup.WithEvents(events))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	go func() {
+		for e := range events {
+			fmt.Printf("[%d/%d] %s\n", e.Step, e.TotalSteps, e.Message)
+		}
+	}()
+
+	session, err := client.Deploy(ctx, spinup.DeploySpec{Model: "qwen2.5-coder:32b"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s on %s (%s), €%.2f/h\n", session.Model, session.GPU, session.Provider, session.HourlyRate)
+	fmt.Println("Ollama API:", session.Endpoint)
+
+	// Keep the deadman switch fed until interrupted, then stop the instance
+	if err := client.Supervise(ctx); err != nil {
+		log.Print(err)
+	}
+	result, err := client.Stop(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Session cost: €%.2f\n", result.SessionCost)
+}
+
+func ExampleClient_ListOffers() {
+	client, err := spinup.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	list, err := client.ListOffers(context.Background(), spinup.OfferQuery{
+		Model:          "qwen2.5-coder:32b",
+		MaxHourlyPrice: 2.0,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for name, err := range list.Errors {
+		log.Printf("%s: %v", name, err)
+	}
+	for _, o := range list.Offers {
+		fmt.Printf("%-10s %-12s %-10s €%.2f/h\n", o.Provider, o.GPU, o.Region, o.LowestPrice())
+	}
+}
+
+func ExampleClient_Status() {
+	client, err := spinup.New(spinup.WithSession("ci"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	status, err := client.Status(context.Background())
+	if errors.Is(err, spinup.ErrNoActiveSession) {
+		fmt.Println("Nothing running")
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s: %s, running %s, €%.2f so far, deadman in %s\n",
+		status.InstanceID, status.ModelStatus, status.Running, status.AccumulatedCost, status.DeadmanRemaining)
+}
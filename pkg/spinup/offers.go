@@ -0,0 +1,209 @@
+package spinup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/models"
+	"github.com/tmeurs/spinup/internal/offerexpr"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/registry"
+)
+
+// OfferQuery filters offers. The zero value matches every available offer.
+type OfferQuery struct {
+	// Model limits offers to GPUs with enough VRAM for the model.
+	Model string
+
+	// MinVRAMGB limits offers to GPUs with at least this much VRAM.
+	MinVRAMGB int
+
+	// GPU limits offers to one GPU type, e.g. "a100-80".
+	GPU string
+
+	// Region limits offers to a region, e.g. "eu-west".
+	Region string
+
+	// Provider limits offers to one provider, e.g. "vast".
+	Provider string
+
+	// MaxHourlyPrice limits offers to this hourly price. Zero means no limit.
+	MaxHourlyPrice float64
+
+	// SpotOnly limits offers to ones with spot pricing.
+	SpotOnly bool
+
+	// OnDemandOnly excludes spot offers.
+	OnDemandOnly bool
+
+	// Filter is an offer filter expression such as
+	// `vram >= 48 && provider != "paperspace"`. Empty uses OFFER_FILTER
+	// from the configuration.
+	Filter string
+}
+
+// Offer is a GPU instance on offer by a provider. Prices are per hour in EUR.
+type Offer struct {
+	// ID is the provider's ID of the offer.
+	ID string
+
+	// Provider is the provider name.
+	Provider string
+
+	// GPU is the GPU type, e.g. "A100 80GB".
+	GPU string
+
+	// VRAMGB is the GPU memory.
+	VRAMGB int
+
+	// Region is the offer's region.
+	Region string
+
+	// OnDemandPrice is the on-demand price.
+	OnDemandPrice float64
+
+	// SpotPrice is the spot price, zero if there is no spot pricing.
+	SpotPrice float64
+
+	// Reliability is the host reliability (0-1), zero if the provider
+	// doesn't report it.
+	Reliability float64
+}
+
+// LowestPrice returns the spot price if there is one, the on-demand price
+// otherwise.
+func (o Offer) LowestPrice() float64 {
+	if o.SpotPrice > 0 && o.SpotPrice < o.OnDemandPrice {
+		return o.SpotPrice
+	}
+	return o.OnDemandPrice
+}
+
+// OfferList is the result of ListOffers.
+type OfferList struct {
+	// Offers are sorted by lowest price.
+	Offers []Offer
+
+	// Errors holds the error of each provider that failed, by provider name.
+	Errors map[string]error
+}
+
+// ListOffers returns the available offers of the configured providers.
+// Providers are queried in parallel; a provider that fails is reported in
+// OfferList.Errors instead of failing the call, unless all of them fail.
+func (c *Client) ListOffers(ctx context.Context, query OfferQuery) (*OfferList, error) {
+	providers, err := c.offerProviders(query.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := provider.OfferFilter{
+		GPUType:        query.GPU,
+		MinVRAM:        query.MinVRAMGB,
+		Region:         query.Region,
+		SpotOnly:       query.SpotOnly,
+		OnDemandOnly:   query.OnDemandOnly,
+		MaxHourlyPrice: query.MaxHourlyPrice,
+	}
+	if query.Model != "" {
+		m, err := models.GetModelByName(query.Model)
+		if err != nil {
+			return nil, err
+		}
+		if m.VRAM > filter.MinVRAM {
+			filter.MinVRAM = m.VRAM
+		}
+	}
+
+	expr, err := offerFilter(c.cfg, query.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	results := deploy.FetchOffers(ctx, providers, filter, deploy.DefaultProviderFetchTimeout)
+
+	list := &OfferList{}
+	for _, r := range results {
+		if !r.Report.OK() {
+			if list.Errors == nil {
+				list.Errors = make(map[string]error)
+			}
+			list.Errors[r.Report.Provider] = r.Report.Err
+			continue
+		}
+		for _, o := range expr.Apply(r.Offers) {
+			list.Offers = append(list.Offers, offerFromProvider(o))
+		}
+	}
+	if len(list.Errors) == len(results) {
+		return nil, fmt.Errorf("all providers failed: %s", deploy.SummarizeFetchErrors(deploy.FetchReports(results)))
+	}
+
+	sort.SliceStable(list.Offers, func(i, j int) bool {
+		return list.Offers[i].LowestPrice() < list.Offers[j].LowestPrice()
+	})
+	return list, nil
+}
+
+// offerProviders returns the providers to query, all configured ones if name
+// is empty.
+// offerFilter parses a filter expression, falling back to the configured
+// default filter if it is empty.
+func offerFilter(cfg *config.Config, expr string) (*offerexpr.Filter, error) {
+	if expr == "" {
+		return cfg.DefaultFilter, nil
+	}
+	filter, err := offerexpr.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	return filter, nil
+}
+
+func (c *Client) offerProviders(name string) ([]provider.Provider, error) {
+	if c.providers != nil {
+		if name == "" {
+			return c.providers, nil
+		}
+		for _, p := range c.providers {
+			if p.Name() == name {
+				return []provider.Provider{p}, nil
+			}
+		}
+		return nil, fmt.Errorf("provider %q is not configured", name)
+	}
+
+	if name != "" {
+		p, err := registry.GetProviderByName(name, c.cfg)
+		if err != nil {
+			return nil, err
+		}
+		return []provider.Provider{p}, nil
+	}
+	providers, err := registry.GetConfiguredProviders(c.cfg)
+	if errors.Is(err, config.ErrNoProviderConfigured) {
+		return nil, ErrNoProviders
+	}
+	return providers, err
+}
+
+// offerFromProvider converts a provider offer.
+func offerFromProvider(o provider.Offer) Offer {
+	offer := Offer{
+		ID:            o.OfferID,
+		Provider:      o.Provider,
+		GPU:           o.GPU,
+		VRAMGB:        o.VRAM,
+		Region:        o.Region,
+		OnDemandPrice: o.OnDemandPrice,
+		Reliability:   o.Reliability,
+	}
+	if o.SpotPrice != nil {
+		offer.SpotPrice = *o.SpotPrice
+	}
+	return offer
+}
@@ -0,0 +1,153 @@
+package spinup
+
+import (
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/deploy"
+)
+
+// Session describes a deployed instance and the model it serves.
+type Session struct {
+	// Name is the session name.
+	Name string
+
+	// InstanceID is the provider's ID of the instance.
+	InstanceID string
+
+	// Provider is the provider name, e.g. "vast".
+	Provider string
+
+	// GPU is the instance's GPU, e.g. "A100 80GB".
+	GPU string
+
+	// Region is the instance's region.
+	Region string
+
+	// Spot is set for spot instances, which the provider may interrupt.
+	Spot bool
+
+	// Model is the deployed model.
+	Model string
+
+	// Endpoint is the Ollama API URL, reachable through the WireGuard tunnel.
+	Endpoint string
+
+	// HourlyRate is the instance's price per hour.
+	HourlyRate float64
+
+	// Currency is the currency of HourlyRate, e.g. "EUR".
+	Currency string
+
+	// CreatedAt is when the instance was created.
+	CreatedAt time.Time
+
+	// DeadmanTimeout is how long the instance lives without a heartbeat.
+	DeadmanTimeout time.Duration
+}
+
+// Status is a snapshot of the active session.
+type Status struct {
+	Session
+
+	// ModelStatus is "loading", "ready" or "error".
+	ModelStatus string
+
+	// Running is how long the instance has been running.
+	Running time.Duration
+
+	// AccumulatedCost is the cost of the session so far, in Currency.
+	AccumulatedCost float64
+
+	// LastHeartbeat is when the deadman switch was last refreshed.
+	LastHeartbeat time.Time
+
+	// DeadmanRemaining is the time left before the deadman switch terminates
+	// the instance, zero if it has expired.
+	DeadmanRemaining time.Duration
+}
+
+// StopResult describes a stopped session.
+type StopResult struct {
+	// InstanceID is the provider's ID of the terminated instance.
+	InstanceID string
+
+	// Provider is the provider name.
+	Provider string
+
+	// BillingVerified is set if the provider confirmed that billing stopped.
+	BillingVerified bool
+
+	// ManualVerificationRequired is set if billing must be checked in the
+	// provider's console at ConsoleURL.
+	ManualVerificationRequired bool
+
+	// ConsoleURL is the provider's console.
+	ConsoleURL string
+
+	// SessionCost is the total cost of the session.
+	SessionCost float64
+
+	// SessionDuration is how long the instance ran.
+	SessionDuration time.Duration
+}
+
+// sessionFromState converts a stored session.
+func sessionFromState(name string, state *config.State) Session {
+	s := Session{Name: name}
+	if inst := state.Instance; inst != nil {
+		s.InstanceID = inst.ID
+		s.Provider = inst.Provider
+		s.GPU = inst.GPU
+		s.Region = inst.Region
+		s.Spot = inst.IsSpot()
+		s.CreatedAt = inst.CreatedAt
+		if inst.WireGuardIP != "" {
+			s.Endpoint = "http://" + inst.WireGuardIP + ":11434"
+		}
+	}
+	if state.Model != nil {
+		s.Model = state.Model.Name
+	}
+	if state.Cost != nil {
+		s.HourlyRate = state.Cost.HourlyRate
+		s.Currency = state.Cost.Currency
+	}
+	if state.Deadman != nil {
+		s.DeadmanTimeout = time.Duration(state.Deadman.TimeoutHours) * time.Hour
+	}
+	return s
+}
+
+// statusFromState converts a stored session to its status at now.
+func statusFromState(name string, state *config.State, now time.Time) *Status {
+	status := &Status{
+		Session:         sessionFromState(name, state),
+		Running:         state.Instance.DurationAt(now),
+		AccumulatedCost: state.AccumulatedCostAt(now),
+	}
+	if state.Model != nil {
+		status.ModelStatus = state.Model.Status
+	}
+	if state.Deadman != nil {
+		status.LastHeartbeat = state.Deadman.LastHeartbeat
+		status.DeadmanRemaining = state.Deadman.RemainingAt(now)
+	}
+	return status
+}
+
+// stopResult converts the result of a stop.
+func stopResult(r *deploy.StopResult) *StopResult {
+	if r == nil {
+		return nil
+	}
+	return &StopResult{
+		InstanceID:                 r.InstanceID,
+		Provider:                   r.Provider,
+		BillingVerified:            r.BillingVerified,
+		ManualVerificationRequired: r.ManualVerificationRequired,
+		ConsoleURL:                 r.ConsoleURL,
+		SessionCost:                r.SessionCost,
+		SessionDuration:            r.SessionDuration,
+	}
+}
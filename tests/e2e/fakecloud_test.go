@@ -0,0 +1,130 @@
+package e2e
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/fakecloud"
+	"github.com/tmeurs/spinup/internal/provider/registry"
+	"github.com/tmeurs/spinup/tests/integration"
+)
+
+// TestFakeCloud_Stop stops an instance of every provider through the real
+// provider clients, talking to a fake cloud.
+func TestFakeCloud_Stop(t *testing.T) {
+	integration.SkipIfShort(t)
+
+	for _, name := range registry.AllProviderNames() {
+		t.Run(name, func(t *testing.T) {
+			fc := fakecloud.NewTestServer(t)
+			cfg := &config.Config{}
+			fc.Configure(cfg)
+
+			offer := fc.Offers(name)[0]
+			id := fc.AddInstance(name, fakecloud.Instance{
+				OfferID:    offer.ID,
+				GPU:        offer.GPU,
+				Region:     offer.Region,
+				HourlyRate: offer.OnDemandPrice,
+				CreatedAt:  time.Now().Add(-time.Hour),
+			})
+
+			sm, err := config.NewStateManager(t.TempDir())
+			if err != nil {
+				t.Fatalf("NewStateManager() error = %v", err)
+			}
+			state := config.NewState(
+				&config.InstanceState{ID: id, Provider: name, GPU: offer.GPU, CreatedAt: time.Now().Add(-time.Hour)},
+				nil,
+				// An interface that doesn't exist, so the tunnel teardown is a no-op.
+				&config.WireGuardState{InterfaceName: "wgfake0"},
+				&config.CostState{HourlyRate: offer.OnDemandPrice, Currency: "USD"},
+				nil,
+			)
+			if err := sm.SaveState(state); err != nil {
+				t.Fatalf("SaveState() error = %v", err)
+			}
+
+			stopCfg := deploy.DefaultStopConfig()
+			stopCfg.BaseRetryDelay = time.Second
+			stopper, err := deploy.NewStopper(cfg, stopCfg, deploy.WithStopStateManager(sm))
+			if err != nil {
+				t.Fatalf("NewStopper() error = %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			result, err := stopper.Stop(ctx)
+			if err != nil {
+				t.Fatalf("Stop() error = %v", err)
+			}
+
+			if inst, _ := fc.Instance(name, id); inst.Status != fakecloud.StatusTerminated {
+				t.Errorf("instance status = %v, want terminated", inst.Status)
+			}
+			if name == registry.ProviderPaperspace {
+				if !result.ManualVerificationRequired {
+					t.Error("ManualVerificationRequired = false, want true for Paperspace")
+				}
+			} else if !result.BillingVerified {
+				t.Error("BillingVerified = false, want true")
+			}
+			if state, _ := sm.LoadState(); state != nil {
+				t.Error("state not cleared after stop")
+			}
+		})
+	}
+}
+
+// TestFakeCloud_DeployFailover deploys against a fake cloud where the first
+// offer has no capacity and the next one fails to boot, and checks that the
+// Deployer gives up without leaving an instance running.
+func TestFakeCloud_DeployFailover(t *testing.T) {
+	integration.SkipIfShort(t)
+
+	name := registry.ProviderLambda
+	fc := fakecloud.NewTestServer(t)
+	fc.FailNext(name, fakecloud.OpCreate, http.StatusConflict, "Not enough capacity to fulfill launch request.")
+	fc.SetLifecycle(name, fakecloud.Lifecycle{FailBoot: true})
+
+	cfg := &config.Config{}
+	fc.Configure(cfg)
+	sm, err := config.NewStateManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStateManager() error = %v", err)
+	}
+
+	deployCfg := deploy.DefaultDeployConfig()
+	deployCfg.Model = "qwen2.5-coder:7b"
+	deployCfg.ProviderName = name
+	deployCfg.PreferSpot = false
+	deployCfg.MaxAttempts = 2
+	deployer, err := deploy.NewDeployer(cfg, deployCfg, deploy.WithStateManager(sm))
+	if err != nil {
+		t.Fatalf("NewDeployer() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	if _, err := deployer.Deploy(ctx); err == nil {
+		t.Fatal("Deploy() error = nil, want all attempts failed")
+	}
+
+	if n := fc.Calls(name, fakecloud.OpCreate); n != 2 {
+		t.Errorf("create calls = %d, want 2", n)
+	}
+	instances := fc.Instances(name)
+	if len(instances) != 1 {
+		t.Fatalf("fake cloud has %d instances, want 1 (the one that failed to boot)", len(instances))
+	}
+	if instances[0].Status != fakecloud.StatusTerminated {
+		t.Errorf("failed instance status = %v, want terminated", instances[0].Status)
+	}
+	if state, _ := sm.LoadState(); state != nil {
+		t.Error("state saved for a failed deployment")
+	}
+}
@@ -103,6 +103,15 @@ func WithMockOffers(offers []provider.Offer) Option {
 	}
 }
 
+// WithMockScenario scripts the mock provider with a scenario.
+func WithMockScenario(sc *mock.Scenario) Option {
+	return func(e *TestEnv) {
+		if e.MockProvider != nil {
+			e.MockProvider.SetScenario(sc)
+		}
+	}
+}
+
 // WithRealProviders forces use of real providers instead of mock.
 func WithRealProviders() Option {
 	return func(e *TestEnv) {
@@ -226,6 +235,21 @@ func (e *TestEnv) SetupMockDelay(operation string, delay time.Duration) {
 	e.MockProvider.SetDelay(operation, delay)
 }
 
+// LoadMockScenario loads a scenario file and scripts the mock provider
+// with it.
+func (e *TestEnv) LoadMockScenario(path string) *mock.Scenario {
+	e.T.Helper()
+	if e.MockProvider == nil {
+		e.T.Fatal("LoadMockScenario called but mock provider is nil")
+	}
+	sc, err := mock.LoadScenario(path)
+	if err != nil {
+		e.T.Fatalf("Failed to load scenario: %v", err)
+	}
+	e.MockProvider.SetScenario(sc)
+	return sc
+}
+
 // ResetMock resets the mock provider to its default state.
 func (e *TestEnv) ResetMock() {
 	e.T.Helper()
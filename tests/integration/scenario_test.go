@@ -0,0 +1,278 @@
+package integration
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tmeurs/spinup/internal/config"
+	"github.com/tmeurs/spinup/internal/deploy"
+	"github.com/tmeurs/spinup/internal/provider"
+	"github.com/tmeurs/spinup/internal/provider/mock"
+)
+
+// scenarioPath returns the path of a scenario in testdata/scenarios.
+func scenarioPath(name string) string {
+	return filepath.Join("testdata", "scenarios", name+".yaml")
+}
+
+// createInstance creates an instance at the mock provider and saves it as
+// the active session.
+func createInstance(env *TestEnv) *provider.Instance {
+	env.T.Helper()
+
+	ctx, cancel := env.Context()
+	defer cancel()
+	inst, err := env.MockProvider.CreateInstance(ctx, provider.CreateRequest{OfferID: "mock-offer-2"})
+	if err != nil {
+		env.T.Fatalf("CreateInstance() error = %v", err)
+	}
+
+	state := config.NewState(
+		&config.InstanceState{ID: inst.ID, Provider: inst.Provider, GPU: inst.GPU, CreatedAt: inst.CreatedAt},
+		nil,
+		// An interface that doesn't exist, so the tunnel teardown is a no-op.
+		&config.WireGuardState{InterfaceName: "wgmock0"},
+		&config.CostState{HourlyRate: inst.HourlyRate, Currency: "USD"},
+		nil,
+	)
+	if err := env.StateManager.SaveState(state); err != nil {
+		env.T.Fatalf("SaveState() error = %v", err)
+	}
+	return inst
+}
+
+// newStopper returns a Stopper for the mock provider with the shortest retry
+// delay allowed.
+func newStopper(env *TestEnv, maxRetries int, opts ...deploy.StopperOption) *deploy.Stopper {
+	env.T.Helper()
+
+	stopCfg := deploy.DefaultStopConfig()
+	stopCfg.BaseRetryDelay = time.Second
+	stopCfg.MaxRetries = maxRetries
+	opts = append([]deploy.StopperOption{
+		deploy.WithStopStateManager(env.StateManager),
+		deploy.WithStopProvider(env.MockProvider),
+	}, opts...)
+
+	s, err := deploy.NewStopper(env.Config, stopCfg, opts...)
+	if err != nil {
+		env.T.Fatalf("NewStopper() error = %v", err)
+	}
+	return s
+}
+
+func TestScenario_StopRetriesTerminateAndBilling(t *testing.T) {
+	SkipIfShort(t)
+	env := NewTestEnv(t)
+	defer env.Cleanup()
+	env.RequireMock()
+	env.LoadMockScenario(scenarioPath("flaky-stop"))
+
+	inst := createInstance(env)
+	ctx, cancel := env.Context()
+	defer cancel()
+
+	result, err := newStopper(env, 5).Stop(ctx)
+	if err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+
+	env.AssertEqual(result.TerminateAttempts, 3, "terminate succeeds on the third attempt")
+	env.AssertEqual(result.BillingCheckAttempts, 2, "billing is stopped on the second check")
+	env.AssertTrue(result.BillingVerified, "billing should be verified")
+	env.AssertEqual(len(env.MockProvider.TerminateInstanceCalls), 3, "terminate calls")
+
+	// The instance is stopping for one poll, then terminated
+	for _, want := range []provider.InstanceStatus{provider.InstanceStatusStopping, provider.InstanceStatusTerminated} {
+		got, err := env.MockProvider.GetInstance(ctx, inst.ID)
+		env.AssertNoError(err, "GetInstance")
+		env.AssertEqual(got.Status, want, "instance status")
+	}
+
+	state, _ := env.StateManager.LoadState()
+	env.AssertTrue(state == nil, "state should be cleared")
+}
+
+func TestScenario_StopTerminateExhaustsRetries(t *testing.T) {
+	SkipIfShort(t)
+	env := NewTestEnv(t)
+	defer env.Cleanup()
+	env.RequireMock()
+	env.LoadMockScenario(scenarioPath("terminate-down"))
+
+	createInstance(env)
+	ctx, cancel := env.Context()
+	defer cancel()
+
+	var alerted bool
+	stopper := newStopper(env, 2, deploy.WithCriticalAlertCallback(func(string, error, map[string]interface{}) {
+		alerted = true
+	}))
+
+	_, err := stopper.Stop(ctx)
+	if !errors.Is(err, deploy.ErrTerminateFailed) {
+		t.Fatalf("Stop() error = %v, want ErrTerminateFailed", err)
+	}
+	env.AssertTrue(strings.Contains(err.Error(), "504 Gateway Timeout"), "error should carry the last provider error")
+	env.AssertTrue(alerted, "critical alert should be raised")
+	env.AssertEqual(len(env.MockProvider.TerminateInstanceCalls), 2, "terminate calls")
+
+	state, _ := env.StateManager.LoadState()
+	env.AssertTrue(state != nil, "state should be kept when terminate fails")
+}
+
+func TestScenario_StopBillingNeverStops(t *testing.T) {
+	SkipIfShort(t)
+	env := NewTestEnv(t)
+	defer env.Cleanup()
+	env.RequireMock()
+	env.LoadMockScenario(scenarioPath("billing-stuck"))
+
+	createInstance(env)
+	ctx, cancel := env.Context()
+	defer cancel()
+
+	_, err := newStopper(env, 2).Stop(ctx)
+	if !errors.Is(err, deploy.ErrBillingNotVerified) {
+		t.Fatalf("Stop() error = %v, want ErrBillingNotVerified", err)
+	}
+	env.AssertEqual(len(env.MockProvider.GetBillingStatusCalls), 2, "billing checks")
+}
+
+func TestScenario_Reconcile(t *testing.T) {
+	tests := []struct {
+		name        string
+		yaml        string
+		pollFirst   bool
+		wantValid   bool
+		wantCleaned bool
+		wantDetails string
+	}{
+		{
+			name:        "instance gone",
+			yaml:        "methods: {GetInstance: {errors: [{error: instance_not_found}]}}",
+			wantCleaned: true,
+			wantDetails: deploy.MismatchInstanceNotFound.String(),
+		},
+		{
+			name:        "instance reaped after boot",
+			pollFirst:   true,
+			wantCleaned: true,
+			wantDetails: deploy.MismatchInstanceTerminated.String(),
+		},
+		{
+			name:        "transient error keeps state",
+			yaml:        "methods: {GetInstance: {errors: [{error: rate_limited}]}}",
+			wantValid:   true,
+			wantDetails: "provider API error, state kept",
+		},
+		{
+			name:        "instance still booting",
+			yaml:        "instance: {boot: [{status: creating}]}",
+			wantValid:   true,
+			wantDetails: "is creating",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := NewTestEnv(t)
+			defer env.Cleanup()
+			env.RequireMock()
+			if tt.yaml == "" {
+				env.LoadMockScenario(scenarioPath("reaped"))
+			} else {
+				sc, err := mock.ParseScenario([]byte(tt.yaml))
+				if err != nil {
+					t.Fatalf("ParseScenario() error = %v", err)
+				}
+				env.MockProvider.SetScenario(sc)
+			}
+
+			inst := createInstance(env)
+			ctx, cancel := env.Context()
+			defer cancel()
+			if tt.pollFirst {
+				got, _ := env.MockProvider.GetInstance(ctx, inst.ID)
+				env.AssertEqual(got.Status, provider.InstanceStatusRunning, "status on the first poll")
+			}
+
+			r, err := deploy.NewReconciler(env.Config, env.StateManager, &deploy.ReconcileOptions{
+				Timeout:     5 * time.Second,
+				AutoCleanup: true,
+				Provider:    env.MockProvider,
+			})
+			if err != nil {
+				t.Fatalf("NewReconciler() error = %v", err)
+			}
+			result, err := r.ReconcileState(ctx)
+			if err != nil {
+				t.Fatalf("ReconcileState() error = %v", err)
+			}
+
+			env.AssertEqual(result.StateValid, tt.wantValid, "StateValid")
+			env.AssertEqual(result.StateCleaned, tt.wantCleaned, "StateCleaned")
+			env.AssertTrue(strings.Contains(result.Details, tt.wantDetails), "Details "+result.Details+" should contain "+tt.wantDetails)
+
+			state, _ := env.StateManager.LoadState()
+			env.AssertEqual(state == nil, tt.wantCleaned, "state removed")
+		})
+	}
+}
+
+func TestScenario_DeployBootFailures(t *testing.T) {
+	tests := []struct {
+		scenario string
+		wantErr  string
+	}{
+		{"boot-error", "terminal state"},
+		{"boot-hang", "timeout waiting for instance to boot"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scenario, func(t *testing.T) {
+			env := NewTestEnv(t)
+			defer env.Cleanup()
+			env.RequireMock()
+			sc, err := mock.LoadScenario(scenarioPath(tt.scenario))
+			if err != nil {
+				t.Fatalf("LoadScenario() error = %v", err)
+			}
+			// Cloud-init is rendered per provider, so pose as a real one.
+			p := mock.New(mock.WithName("lambda"), mock.WithOffers(DefaultOffers()), mock.WithScenario(sc))
+
+			deployCfg := deploy.DefaultDeployConfig()
+			deployCfg.Model = "qwen2.5-coder:7b"
+			deployCfg.BootTimeout = 200 * time.Millisecond
+			deployCfg.BootPollInterval = 10 * time.Millisecond
+			deployCfg.MaxAttempts = 2
+			d, err := deploy.NewDeployer(&config.Config{}, deployCfg,
+				deploy.WithStateManager(env.StateManager),
+				deploy.WithProviders(p),
+			)
+			if err != nil {
+				t.Fatalf("NewDeployer() error = %v", err)
+			}
+
+			ctx, cancel := env.Context()
+			defer cancel()
+			_, err = d.Deploy(ctx)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Deploy() error = %v, want containing %q", err, tt.wantErr)
+			}
+
+			// Both attempts created an instance that failed to boot and was
+			// cleaned up again.
+			env.AssertEqual(len(p.CreateInstanceCalls), 2, "create calls")
+			instances, _ := p.ListInstances(ctx)
+			for _, inst := range instances {
+				env.AssertEqual(inst.Status, provider.InstanceStatusTerminated, "status of "+inst.ID)
+			}
+			state, _ := env.StateManager.LoadState()
+			env.AssertTrue(state == nil, "no state should be saved for a failed deployment")
+		})
+	}
+}